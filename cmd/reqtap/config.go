@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedConfigKeys are the configuration keys whose values config validate hides
+var redactedConfigKeys = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"password_hash": true,
+	"secret":        true,
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the ReqTap configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration and print the effective settings",
+	Long: `Load the configuration the way the server does - defaults, then the config file, then REQTAP_*
+environment variables, then command line flags - validate it and print the result as YAML.
+
+Besides the checks run at startup (forward URLs parse, regex rewrite rules compile, web paths do not
+conflict with each other or the capture path), nothing is started. The command exits non-zero when
+the configuration is invalid, so it fits CI and deployment scripts:
+
+  reqtap config validate -c config.yaml
+  reqtap config validate -c config.yaml --json | jq .forward
+
+Passwords, tokens and secrets are shown as REDACTED unless --show-secrets is set.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         validateConfig,
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("show-secrets", false, "Print passwords, tokens and secrets instead of redacting them")
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func validateConfig(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if showSecrets, _ := cmd.Flags().GetBool("show-secrets"); !showSecrets {
+		redactConfigNode(&doc)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		var resolved map[string]interface{}
+		if err := doc.Decode(&resolved); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(resolved); err != nil {
+			return err
+		}
+	} else {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Configuration is valid")
+	return nil
+}
+
+// redactConfigNode hides the values of secret keys and the passwords embedded in URLs
+func redactConfigNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if redactedConfigKeys[key.Value] && value.Kind == yaml.ScalarNode && value.Value != "" {
+				value.Value = "REDACTED"
+				continue
+			}
+			redactConfigNode(value)
+		}
+	case yaml.ScalarNode:
+		if strings.Contains(node.Value, "://") && strings.Contains(node.Value, "@") {
+			if parsed, err := url.Parse(node.Value); err == nil {
+				if _, hasPassword := parsed.User.Password(); hasPassword {
+					node.Value = parsed.Redacted()
+				}
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			redactConfigNode(child)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/internal/web"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export captured requests from the database",
+	Long: `Stream the captured requests in storage.path to stdout or a file. The default ndjson format
+writes one JSON object per line, so the output can be piped into jq or a log loader:
+
+  reqtap export --method POST --since 1h | jq -r .path
+  reqtap export --format har --since 1h --method POST --out hooks.har
+
+--aggregate exports per-interval statistics (requests, errors, average body size, forwards and
+average forward latency) instead of raw requests, as csv or parquet:
+
+  reqtap export --aggregate 1h --since 168h --format parquet -o traffic.parquet`,
+	RunE: exportRequests,
+}
+
+func init() {
+	exportCmd.Flags().String("format", "ndjson", "Export format: ndjson, json, csv, txt or har; csv or parquet with --aggregate (default csv)")
+	exportCmd.Flags().StringP("output", "o", "-", "Output file, - for stdout (also --out)")
+	exportCmd.Flags().String("search", "", "Only export requests matching this search text")
+	exportCmd.Flags().String("method", "", "Only export requests with this HTTP method")
+	exportCmd.Flags().StringSlice("tag", nil, "Only export requests carrying every listed tag")
+	exportCmd.Flags().Bool("pinned", false, "Only export pinned requests")
+	exportCmd.Flags().String("content-type", "", "Only export requests whose Content-Type starts with this, e.g. application/json")
+	exportCmd.Flags().String("path-prefix", "", "Only export requests whose path starts with this")
+	exportCmd.Flags().Duration("since", 0, "Only export requests captured within this duration, e.g. 24h")
+	exportCmd.Flags().Duration("aggregate", 0, "Export statistics per interval of this length, e.g. 1h, instead of raw requests")
+	exportCmd.Flags().SetNormalizeFunc(exportFlagAliases)
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exportFlagAliases accepts --out for --output
+func exportFlagAliases(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "out" {
+		name = "output"
+	}
+	return pflag.NormalizedName(name)
+}
+
+func exportRequests(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver == "plugin" {
+		return fmt.Errorf("export requires the sqlite or bolt storage driver")
+	}
+	store, err := storage.New(&cfg.Storage, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	format, _ := cmd.Flags().GetString("format")
+	opts := storage.ListOptions{}
+	opts.Search, _ = cmd.Flags().GetString("search")
+	opts.Method, _ = cmd.Flags().GetString("method")
+	opts.Tags, _ = cmd.Flags().GetStringSlice("tag")
+	opts.Pinned, _ = cmd.Flags().GetBool("pinned")
+	opts.ContentType, _ = cmd.Flags().GetString("content-type")
+	opts.PathPrefix, _ = cmd.Flags().GetString("path-prefix")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		opts.Since = time.Now().Add(-since)
+	}
+
+	var out io.Writer = os.Stdout
+	if path, _ := cmd.Flags().GetString("output"); path != "-" && path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if interval, _ := cmd.Flags().GetDuration("aggregate"); interval > 0 {
+		if !cmd.Flags().Changed("format") {
+			format = "csv"
+		}
+		rows, err := web.CollectAggregates(store, opts, interval)
+		if err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		if _, _, err := web.ExportAggregates(out, rows, format); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		return nil
+	}
+
+	iter := func(yield func(*storage.StoredRequest) bool) error {
+		return store.Iterate(opts, yield)
+	}
+	if _, _, err := web.StreamExport(out, iter, format, store.GetForwards); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestExportHAROut(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	dbPath := filepath.Join(dir, "reqtap.db")
+	if err := os.WriteFile(configPath, []byte("storage:\n  driver: sqlite\n  path: "+dbPath+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(configPath, nil)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	store, err := storage.New(&cfg.Storage, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	now := time.Now()
+	for _, data := range []*request.RequestData{
+		{ID: "hook", Method: "POST", Path: "/hook", Timestamp: now, Headers: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"event":"push"}`)},
+		{ID: "page", Method: "GET", Path: "/page", Timestamp: now},
+		{ID: "old", Method: "POST", Path: "/old", Timestamp: now.Add(-2 * time.Hour)},
+	} {
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("failed to record %s: %v", data.ID, err)
+		}
+	}
+	store.Close()
+
+	out := filepath.Join(dir, "hooks.har")
+	rootCmd.SetArgs([]string{"export", "-c", configPath, "--format", "har", "--since", "1h", "--method", "POST", "--out", out})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("expected --out to write the export: %v", err)
+	}
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method   string `json:"method"`
+					URL      string `json:"url"`
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(raw, &har); err != nil {
+		t.Fatalf("expected a HAR file, got %q: %v", raw, err)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("expected only the recent POST, got %d entries", len(har.Log.Entries))
+	}
+	entry := har.Log.Entries[0].Request
+	if entry.Method != "POST" || !strings.HasSuffix(entry.URL, "/hook") || entry.PostData.Text != `{"event":"push"}` {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/importer"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file.har|file.json|file.ndjson|->",
+	Short: "Import HAR captures or exported requests into the database",
+	Long: `Load the requests of a HAR archive, an ngrok inspector export, or a json/ndjson file written by
+reqtap export into storage.path, so they show up in the web console and can be replayed or
+re-forwarded. Use - to read from stdin. Imported requests get new IDs and keep their capture time:
+
+  reqtap export --tag repro -o repro.ndjson     # on one machine
+  reqtap import repro.ndjson --scenario repro   # on another`,
+	Args: cobra.ExactArgs(1),
+	RunE: importRequests,
+}
+
+func init() {
+	importCmd.Flags().String("format", importer.FormatAuto, "Import format: auto, har, ngrok, json or ndjson")
+	importCmd.Flags().String("scenario", "", "Tag every imported request with this scenario (X-ReqTap-Scenario header)")
+	rootCmd.AddCommand(importCmd)
+}
+
+func importRequests(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver == "plugin" {
+		return fmt.Errorf("import requires the sqlite or bolt storage driver")
+	}
+
+	var data []byte
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read import: %w", err)
+	}
+	format, _ := cmd.Flags().GetString("format")
+	scenario, _ := cmd.Flags().GetString("scenario")
+	items, format, err := importer.Parse(data, format, scenario)
+	if err != nil {
+		return fmt.Errorf("invalid import: %w", err)
+	}
+
+	store, err := storage.New(&cfg.Storage, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	for i, item := range items {
+		if _, err := store.Record(item); err != nil {
+			return fmt.Errorf("failed to store request %d of %d: %w", i+1, len(items), err)
+		}
+	}
+	fmt.Printf("Imported %d requests (%s) into %s\n", len(items), format, cfg.Storage.Path)
+	return nil
+}
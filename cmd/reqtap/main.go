@@ -1,21 +1,24 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
 	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/password"
 	"github.com/funnyzak/reqtap/internal/server"
 	"github.com/funnyzak/reqtap/internal/static"
 	"github.com/funnyzak/reqtap/pkg/i18n"
 	runewidth "github.com/mattn/go-runewidth"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var (
@@ -62,252 +65,95 @@ var localesCmd = &cobra.Command{
 	RunE:  showLocales,
 }
 
+var hashPasswordCmd = &cobra.Command{
+	Use:   "hash-password [password]",
+	Short: "Hash a web console password for web.auth.users[].password_hash",
+	Long: `Hash a password with bcrypt (default) or argon2id and print the result for web.auth.users[].password_hash.
+
+Without an argument the password is prompted for, or read from stdin when it is not a terminal,
+so it does not end up in the shell history.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: hashPassword,
+}
+
 func init() {
 	// Add global flags
 	rootCmd.PersistentFlags().StringP("config", "c", "", "Configuration file path")
-	rootCmd.PersistentFlags().IntP("port", "p", 0, "Listen port")
-	rootCmd.PersistentFlags().String("path", "", "URL path prefix to listen")
-	rootCmd.PersistentFlags().Int64("max-body-bytes", 0, "Maximum request body size in bytes (0 for unlimited)")
-	rootCmd.PersistentFlags().StringP("log-level", "l", "", "Log level (trace, debug, info, warn, error, fatal, panic)")
-	rootCmd.PersistentFlags().Bool("log-file-enable", false, "Enable file logging")
-	rootCmd.PersistentFlags().String("log-file-path", "", "Log file path")
-	rootCmd.PersistentFlags().Int("log-file-max-size", 0, "Maximum size of a single log file (MB)")
-	rootCmd.PersistentFlags().Int("log-file-max-backups", 0, "Maximum number of old log files to retain")
-	rootCmd.PersistentFlags().Int("log-file-max-age", 0, "Maximum retention days for old log files")
-	rootCmd.PersistentFlags().Bool("log-file-compress", false, "Whether to compress old log files")
-	rootCmd.PersistentFlags().StringSliceP("forward-url", "f", []string{}, "Target URLs to forward")
-	rootCmd.PersistentFlags().Bool("silence", false, "Suppress interactive console output")
-	rootCmd.PersistentFlags().Bool("json", false, "Emit structured JSON output")
-	rootCmd.PersistentFlags().String("locale", "", "Output locale (e.g. en, zh-CN)")
-	rootCmd.PersistentFlags().Bool("body-view", false, "Enable structured body formatting in console mode")
-	rootCmd.PersistentFlags().Int("body-preview-bytes", 0, "Maximum bytes to preview before truncating console body output")
-	rootCmd.PersistentFlags().Bool("full-body", false, "Always print full request bodies, ignoring preview limits")
-	rootCmd.PersistentFlags().Bool("body-hex-preview", false, "Enable hexadecimal preview for binary bodies")
-	rootCmd.PersistentFlags().Int("body-hex-preview-bytes", 0, "Limit for hexadecimal preview bytes (0 keeps config value)")
-	rootCmd.PersistentFlags().Bool("body-save-binary", false, "Persist binary bodies to disk when enabled")
-	rootCmd.PersistentFlags().String("body-save-directory", "", "Directory to persist binary bodies (requires --body-save-binary)")
-
-	rootCmd.PersistentFlags().String("storage-driver", "", "Storage driver (only sqlite supported)")
-	rootCmd.PersistentFlags().String("storage-path", "", "Storage database file path")
-	rootCmd.PersistentFlags().Int("storage-max-records", 0, "Maximum records persisted (0 keeps config value)")
-	rootCmd.PersistentFlags().String("storage-retention", "", "Retention duration (e.g. 168h); empty disables")
-
-	// Web console configuration flags
-	rootCmd.PersistentFlags().Bool("web-enable", false, "Enable/disable web console")
-	rootCmd.PersistentFlags().String("web-path", "", "Web UI access path")
-	rootCmd.PersistentFlags().String("web-admin-path", "", "Web admin API path")
-	rootCmd.PersistentFlags().Int("web-max-requests", 0, "Maximum number of requests to retain in memory")
-	rootCmd.PersistentFlags().Bool("web-auth-enable", false, "Enable/disable web console authentication")
-	rootCmd.PersistentFlags().String("web-auth-session-timeout", "", "Web console session timeout duration")
-	rootCmd.PersistentFlags().Bool("web-export-enable", false, "Enable/disable web console data export")
-	rootCmd.PersistentFlags().StringSlice("web-export-formats", []string{}, "Supported export formats for web console")
-
-	bindFlags(rootCmd)
+	config.RegisterFlags(rootCmd.PersistentFlags())
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(examplesCmd)
 	rootCmd.AddCommand(localesCmd)
-}
 
-func bindFlags(cmd *cobra.Command) {
-	viper.BindPFlag("server.port", cmd.Flags().Lookup("port"))
-	viper.BindPFlag("server.path", cmd.Flags().Lookup("path"))
-	viper.BindPFlag("server.max_body_bytes", cmd.Flags().Lookup("max-body-bytes"))
-	viper.BindPFlag("log.level", cmd.Flags().Lookup("log-level"))
-	viper.BindPFlag("log.file_logging.enable", cmd.Flags().Lookup("log-file-enable"))
-	viper.BindPFlag("log.file_logging.path", cmd.Flags().Lookup("log-file-path"))
-	viper.BindPFlag("log.file_logging.max_size_mb", cmd.Flags().Lookup("log-file-max-size"))
-	viper.BindPFlag("log.file_logging.max_backups", cmd.Flags().Lookup("log-file-max-backups"))
-	viper.BindPFlag("log.file_logging.max_age_days", cmd.Flags().Lookup("log-file-max-age"))
-	viper.BindPFlag("log.file_logging.compress", cmd.Flags().Lookup("log-file-compress"))
-	viper.BindPFlag("forward.urls", cmd.Flags().Lookup("forward-url"))
-	viper.BindPFlag("output.locale", cmd.Flags().Lookup("locale"))
-
-	// Web console configuration bindings
-	viper.BindPFlag("web.enable", cmd.Flags().Lookup("web-enable"))
-	viper.BindPFlag("web.path", cmd.Flags().Lookup("web-path"))
-	viper.BindPFlag("web.admin_path", cmd.Flags().Lookup("web-admin-path"))
-	viper.BindPFlag("web.max_requests", cmd.Flags().Lookup("web-max-requests"))
-	viper.BindPFlag("web.auth.enable", cmd.Flags().Lookup("web-auth-enable"))
-	viper.BindPFlag("web.auth.session_timeout", cmd.Flags().Lookup("web-auth-session-timeout"))
-	viper.BindPFlag("web.export.enable", cmd.Flags().Lookup("web-export-enable"))
-	viper.BindPFlag("web.export.formats", cmd.Flags().Lookup("web-export-formats"))
-	viper.BindPFlag("output.silence", cmd.Flags().Lookup("silence"))
-	viper.BindPFlag("output.body_view.enable", cmd.Flags().Lookup("body-view"))
-	viper.BindPFlag("output.body_view.max_preview_bytes", cmd.Flags().Lookup("body-preview-bytes"))
-	viper.BindPFlag("output.body_view.full_body", cmd.Flags().Lookup("full-body"))
-	viper.BindPFlag("output.body_view.binary.hex_preview_enable", cmd.Flags().Lookup("body-hex-preview"))
-	viper.BindPFlag("output.body_view.binary.hex_preview_bytes", cmd.Flags().Lookup("body-hex-preview-bytes"))
-	viper.BindPFlag("output.body_view.binary.save_to_file", cmd.Flags().Lookup("body-save-binary"))
-	viper.BindPFlag("output.body_view.binary.save_directory", cmd.Flags().Lookup("body-save-directory"))
-	viper.BindPFlag("storage.driver", cmd.Flags().Lookup("storage-driver"))
-	viper.BindPFlag("storage.path", cmd.Flags().Lookup("storage-path"))
-	viper.BindPFlag("storage.max_records", cmd.Flags().Lookup("storage-max-records"))
-	viper.BindPFlag("storage.retention", cmd.Flags().Lookup("storage-retention"))
+	hashPasswordCmd.Flags().String("algorithm", password.Bcrypt, "Hash algorithm (bcrypt, argon2id)")
+	rootCmd.AddCommand(hashPasswordCmd)
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
-	// Get configuration file path
-	configPath, _ := cmd.Flags().GetString("config")
-
-	// Load configuration using global viper
-	cfg, err := config.LoadConfig(configPath, viper.GetViper())
+	cfg, err := loadServerConfig(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	// Override with command line arguments (command line has highest priority)
-	// This ensures command line flags override config file values
-	if port, err := cmd.Flags().GetInt("port"); err == nil && port != 0 {
-		cfg.Server.Port = port
-	}
-	if path, err := cmd.Flags().GetString("path"); err == nil && path != "" {
-		cfg.Server.Path = path
-	}
-	if cmd.Flags().Changed("max-body-bytes") {
-		if maxBodyBytes, err := cmd.Flags().GetInt64("max-body-bytes"); err == nil {
-			cfg.Server.MaxBodyBytes = maxBodyBytes
-		}
-	}
-	if logLevel, err := cmd.Flags().GetString("log-level"); err == nil && logLevel != "" {
-		cfg.Log.Level = logLevel
-	}
-	if logFileEnable, err := cmd.Flags().GetBool("log-file-enable"); err == nil && cmd.Flags().Changed("log-file-enable") {
-		cfg.Log.FileLogging.Enable = logFileEnable
-	}
-	if logFilePath, err := cmd.Flags().GetString("log-file-path"); err == nil && logFilePath != "" {
-		cfg.Log.FileLogging.Path = logFilePath
-	}
-	if logFileSize, err := cmd.Flags().GetInt("log-file-max-size"); err == nil && logFileSize != 0 {
-		cfg.Log.FileLogging.MaxSizeMB = logFileSize
-	}
-	if logFileBackups, err := cmd.Flags().GetInt("log-file-max-backups"); err == nil && logFileBackups != 0 {
-		cfg.Log.FileLogging.MaxBackups = logFileBackups
-	}
-	if logFileAge, err := cmd.Flags().GetInt("log-file-max-age"); err == nil && logFileAge != 0 {
-		cfg.Log.FileLogging.MaxAgeDays = logFileAge
-	}
-	if logFileCompress, err := cmd.Flags().GetBool("log-file-compress"); err == nil && cmd.Flags().Changed("log-file-compress") {
-		cfg.Log.FileLogging.Compress = logFileCompress
-	}
-	if forwardURLs, err := cmd.Flags().GetStringSlice("forward-url"); err == nil && len(forwardURLs) > 0 {
-		cfg.Forward.URLs = forwardURLs
-	}
-	if locale, err := cmd.Flags().GetString("locale"); err == nil && strings.TrimSpace(locale) != "" {
-		cfg.Output.Locale = strings.TrimSpace(locale)
-	}
+	// Create logger
+	log := logger.NewLogger(&cfg.Log, cfg.Output.Mode)
 
-	// Override with web console command line arguments (command line has highest priority)
-	if webEnable, err := cmd.Flags().GetBool("web-enable"); err == nil && cmd.Flags().Changed("web-enable") {
-		cfg.Web.Enable = webEnable
-	}
-	if webPath, err := cmd.Flags().GetString("web-path"); err == nil && webPath != "" {
-		cfg.Web.Path = webPath
-	}
-	if webAdminPath, err := cmd.Flags().GetString("web-admin-path"); err == nil && webAdminPath != "" {
-		cfg.Web.AdminPath = webAdminPath
-	}
-	if webMaxRequests, err := cmd.Flags().GetInt("web-max-requests"); err == nil && webMaxRequests != 0 {
-		cfg.Web.MaxRequests = webMaxRequests
-	}
-	if webAuthEnable, err := cmd.Flags().GetBool("web-auth-enable"); err == nil && cmd.Flags().Changed("web-auth-enable") {
-		cfg.Web.Auth.Enable = webAuthEnable
-	}
-	if webAuthSessionTimeout, err := cmd.Flags().GetString("web-auth-session-timeout"); err == nil && webAuthSessionTimeout != "" {
-		if timeout, err := time.ParseDuration(webAuthSessionTimeout); err == nil {
-			cfg.Web.Auth.SessionTimeout = timeout
-		}
-	}
-	if webExportEnable, err := cmd.Flags().GetBool("web-export-enable"); err == nil && cmd.Flags().Changed("web-export-enable") {
-		cfg.Web.Export.Enable = webExportEnable
-	}
-	if webExportFormats, err := cmd.Flags().GetStringSlice("web-export-formats"); err == nil && len(webExportFormats) > 0 {
-		cfg.Web.Export.Formats = webExportFormats
+	// Create and start server
+	srv, err := server.New(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to initialize server: %w", err)
 	}
 
-	if cmd.Flags().Changed("silence") {
-		if silence, err := cmd.Flags().GetBool("silence"); err == nil {
-			cfg.Output.Silence = silence
-		}
-	}
-	if jsonOutput, err := cmd.Flags().GetBool("json"); err == nil && jsonOutput {
-		cfg.Output.Mode = "json"
-	}
-	if cmd.Flags().Changed("body-view") {
-		if bodyView, err := cmd.Flags().GetBool("body-view"); err == nil {
-			cfg.Output.BodyView.Enable = bodyView
-		}
-	}
-	if cmd.Flags().Changed("body-preview-bytes") {
-		if preview, err := cmd.Flags().GetInt("body-preview-bytes"); err == nil {
-			cfg.Output.BodyView.MaxPreviewBytes = preview
-		}
-	}
-	if cmd.Flags().Changed("full-body") {
-		if fullBody, err := cmd.Flags().GetBool("full-body"); err == nil {
-			cfg.Output.BodyView.FullBody = fullBody
-		}
-	}
-	if cmd.Flags().Changed("body-hex-preview") {
-		if hexPreview, err := cmd.Flags().GetBool("body-hex-preview"); err == nil {
-			cfg.Output.BodyView.Binary.HexPreviewEnable = hexPreview
-		}
-	}
-	if cmd.Flags().Changed("body-hex-preview-bytes") {
-		if bytes, err := cmd.Flags().GetInt("body-hex-preview-bytes"); err == nil {
-			cfg.Output.BodyView.Binary.HexPreviewBytes = bytes
-		}
-	}
-	if cmd.Flags().Changed("body-save-binary") {
-		if save, err := cmd.Flags().GetBool("body-save-binary"); err == nil {
-			cfg.Output.BodyView.Binary.SaveToFile = save
-		}
-	}
-	if cmd.Flags().Changed("body-save-directory") {
-		if dir, err := cmd.Flags().GetString("body-save-directory"); err == nil {
-			cfg.Output.BodyView.Binary.SaveDirectory = dir
-		}
-	}
-	if storageDriver, err := cmd.Flags().GetString("storage-driver"); err == nil && storageDriver != "" {
-		cfg.Storage.Driver = storageDriver
+	// Display startup information
+	mode := strings.ToLower(cfg.Output.Mode)
+	showBanner := !cfg.Output.Silence && mode != "json" && mode != "tui"
+	openLink := cfg.Web.Auth.LoginLink.OpenBrowser
+	var loginLink string
+	if showBanner || openLink {
+		loginLink = startupLoginLink(cfg, srv, log)
 	}
-	if storagePath, err := cmd.Flags().GetString("storage-path"); err == nil && storagePath != "" {
-		cfg.Storage.Path = storagePath
+	if showBanner {
+		printStartupBanner(cfg, log, loginLink)
 	}
-	if cmd.Flags().Changed("storage-max-records") {
-		if maxRecords, err := cmd.Flags().GetInt("storage-max-records"); err == nil {
-			cfg.Storage.MaxRecords = maxRecords
-		}
+	logStartupSummary(cfg, log)
+	if openLink && loginLink != "" {
+		srv.OnReady(func() {
+			if err := openBrowser(loginLink); err != nil {
+				log.Warn("Failed to open the web console in a browser", "error", err)
+			}
+		})
 	}
-	if storageRetention, err := cmd.Flags().GetString("storage-retention"); err == nil && storageRetention != "" {
-		if retention, err := time.ParseDuration(storageRetention); err == nil {
-			cfg.Storage.Retention = retention
-		}
+	// SIGHUP and the admin reload API re-run the same load, override and validation steps
+	srv.SetConfigLoader(func() (*config.Config, error) {
+		return loadServerConfig(cmd)
+	})
+	return srv.Start()
+}
+
+// loadServerConfig reads the configuration, applies command line overrides and validates the result
+func loadServerConfig(cmd *cobra.Command) (*config.Config, error) {
+	// Get configuration file path
+	configPath, _ := cmd.Flags().GetString("config")
+
+	cfg, err := config.LoadConfig(configPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	cfg.Apply(config.FromFlags(cmd.Flags()))
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 	if err := validateWebPathConflicts(cfg); err != nil {
-		return err
+		return nil, err
 	}
-
-	// Create logger
-	log := logger.NewLogger(&cfg.Log, cfg.Output.Mode)
-
-	// Display startup information
-	if !cfg.Output.Silence && strings.ToLower(cfg.Output.Mode) != "json" {
-		printStartupBanner(cfg, log)
+	// NO_COLOR and a stdout that is not a terminal already turn the colors off
+	if cfg.Output.NoColor && !color.NoColor {
+		color.NoColor = true
 	}
-	logStartupSummary(cfg, log)
 
-	// Create and start server
-	srv, err := server.New(cfg, log)
-	if err != nil {
-		return fmt.Errorf("failed to initialize server: %w", err)
-	}
-	return srv.Start()
+	return cfg, nil
 }
 
 func showVersion(cmd *cobra.Command, args []string) {
@@ -444,6 +290,56 @@ func showLocales(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func hashPassword(cmd *cobra.Command, args []string) error {
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	var secret string
+	if len(args) > 0 {
+		secret = args[0]
+	} else {
+		var err error
+		if secret, err = readPassword(); err != nil {
+			return err
+		}
+	}
+	if secret == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+	hash, err := password.Hash(secret, algorithm)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+// readPassword prompts twice on a terminal, otherwise reads the first line of stdin
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Confirm password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(first), nil
+}
+
 func listCLILocales() ([]string, error) {
 	tr, err := i18n.NewTranslator("en")
 	if err != nil {
@@ -485,7 +381,20 @@ func printLocaleLines(locales []string) {
 	}
 }
 
-func printStartupBanner(cfg *config.Config, log logger.Logger) {
+// startupLoginLink returns the absolute one-time login URL, or "" when no link applies
+func startupLoginLink(cfg *config.Config, srv *server.Server, log logger.Logger) string {
+	path, _, err := srv.LoginLink()
+	if err != nil {
+		log.Warn("Failed to issue web console login link", "error", err)
+		return ""
+	}
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s://localhost:%d%s", cfg.Server.Scheme(), cfg.Server.Port, path)
+}
+
+func printStartupBanner(cfg *config.Config, log logger.Logger, loginLink string) {
 	// Collect all content lines to display
 	var lines []string
 
@@ -497,11 +406,15 @@ func printStartupBanner(cfg *config.Config, log logger.Logger) {
 	lines = append(lines, titleLine, subtitleLine, "")
 
 	// Listening information
-	watchPath := cfg.Server.Path
+	watchPath := strings.Join(cfg.Server.CapturePrefixes(), ", ")
 	if watchPath == "/" {
 		watchPath = "/ (All Paths)"
 	}
-	lines = append(lines, fmt.Sprintf("🚀 Listening on:   http://0.0.0.0:%d%s", cfg.Server.Port, cfg.Server.Path))
+	listenPath := cfg.Server.Path
+	if len(cfg.Server.Paths) > 0 {
+		listenPath = ""
+	}
+	lines = append(lines, fmt.Sprintf("🚀 Listening on:   %s://0.0.0.0:%d%s", cfg.Server.Scheme(), cfg.Server.Port, listenPath))
 	lines = append(lines, fmt.Sprintf("🎯 Watching Path:   %s", watchPath))
 	lines = append(lines, fmt.Sprintf("📊 Log Level:       %s", cfg.Log.Level))
 	if cfg.Web.Enable {
@@ -510,12 +423,13 @@ func printStartupBanner(cfg *config.Config, log logger.Logger) {
 		lines = append(lines, fmt.Sprintf("   └─ API Path:     %s", cfg.Web.AdminPath))
 		if cfg.Web.Auth.Enable {
 			lines = append(lines, fmt.Sprintf("   └─ Auth:         Enabled (%d user(s))", len(cfg.Web.Auth.Users)))
-			// Add user details
-			for _, user := range cfg.Web.Auth.Users {
-				lines = append(lines, fmt.Sprintf("      └─ User:      %s (%s) - %s", user.Username, user.Role, user.Password))
-			}
 			// Add session timeout info
 			lines = append(lines, fmt.Sprintf("      └─ Session:   %v timeout", cfg.Web.Auth.SessionTimeout))
+			// A one-time link replaces listing accounts and their credentials
+			if loginLink != "" {
+				lines = append(lines, fmt.Sprintf("      └─ Login:     %s", loginLink))
+				lines = append(lines, fmt.Sprintf("         (one-time link, expires in %v)", cfg.Web.Auth.LoginLink.TTL))
+			}
 		} else {
 			lines = append(lines, "   └─ Auth:         Disabled")
 		}
@@ -530,10 +444,21 @@ func printStartupBanner(cfg *config.Config, log logger.Logger) {
 
 	// Forward target information
 	lines = append(lines, "")
-	if len(cfg.Forward.URLs) > 0 {
-		lines = append(lines, fmt.Sprintf("🔀 Forward Targets:  %d Target(s)", len(cfg.Forward.URLs)))
-		for _, url := range cfg.Forward.URLs {
-			lines = append(lines, fmt.Sprintf("   └─ %s", url))
+	if targets := cfg.Forward.ResolvedTargets(); len(targets) > 0 {
+		lines = append(lines, fmt.Sprintf("🔀 Forward Targets:  %d Target(s)", len(targets)))
+		for _, target := range targets {
+			var details []string
+			if len(target.Expect.Status) > 0 || len(target.Expect.JSON) > 0 {
+				details = append(details, fmt.Sprintf("expect status=%v, %d json check(s)", target.Expect.Status, len(target.Expect.JSON)))
+			}
+			if target.LatencyBudget > 0 {
+				details = append(details, fmt.Sprintf("budget %s", target.LatencyBudget))
+			}
+			if len(details) > 0 {
+				lines = append(lines, fmt.Sprintf("   └─ %s (%s)", target.URL, strings.Join(details, "; ")))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("   └─ %s", target.URL))
 		}
 	} else {
 		lines = append(lines, "🔀 Forward Targets:  None")
@@ -653,7 +578,7 @@ func printStartupBanner(cfg *config.Config, log logger.Logger) {
 	log.Info("ReqTap starting",
 		"version", version,
 		"port", cfg.Server.Port,
-		"path", cfg.Server.Path,
+		"paths", cfg.Server.CapturePrefixes(),
 		"log_level", cfg.Log.Level,
 		"forward_urls", cfg.Forward.URLs,
 		"web_enable", cfg.Web.Enable,
@@ -694,12 +619,13 @@ func printBoxSeparator(width int) {
 }
 
 func formatMockResponseSummary(cfg *config.Config) string {
-	count := len(cfg.Server.Responses)
+	rules := cfg.Server.ResponseRules()
+	count := len(rules)
 	if count == 0 {
 		return "None configured"
 	}
 	var names []string
-	for _, rule := range cfg.Server.Responses {
+	for _, rule := range rules {
 		names = append(names, rule.Name)
 	}
 	return fmt.Sprintf("%d rule(s): %s", count, strings.Join(names, ", "))
@@ -713,7 +639,9 @@ func formatPathStrategySummary(cfg *config.Config) string {
 	switch mode {
 	case "strip_prefix":
 		prefix := cfg.Forward.PathStrategy.StripPrefix
-		if prefix == "" {
+		if prefix == "" && len(cfg.Server.Paths) > 0 {
+			prefix = "per server.paths entry"
+		} else if prefix == "" {
 			prefix = cfg.Server.Path
 		}
 		return fmt.Sprintf("strip_prefix (prefix=%s)", prefix)
@@ -754,12 +682,12 @@ func formatLocaleList(locales []string) string {
 func logStartupSummary(cfg *config.Config, log logger.Logger) {
 	mode := strings.ToLower(cfg.Output.Mode)
 	var responseNames []string
-	for _, rule := range cfg.Server.Responses {
+	for _, rule := range cfg.Server.ResponseRules() {
 		responseNames = append(responseNames, rule.Name)
 	}
 	log.Info("Startup configuration",
 		"port", cfg.Server.Port,
-		"path", cfg.Server.Path,
+		"paths", cfg.Server.CapturePrefixes(),
 		"responses", responseNames,
 		"forward_urls", cfg.Forward.URLs,
 		"path_strategy", formatPathStrategySummary(cfg),
@@ -808,15 +736,21 @@ func validateWebPathConflicts(cfg *config.Config) error {
 		return nil
 	}
 
-	serverPath := normalizeConfigPath(cfg.Server.Path)
+	key := "server.path"
+	if len(cfg.Server.Paths) > 0 {
+		key = "server.paths"
+	}
 	webPath := normalizeConfigPath(cfg.Web.Path)
 	adminPath := normalizeConfigPath(cfg.Web.AdminPath)
 
-	if pathsOverlap(serverPath, webPath) {
-		return fmt.Errorf("web.path (%s) conflicts with server.path (%s); please configure different values", cfg.Web.Path, cfg.Server.Path)
-	}
-	if pathsOverlap(serverPath, adminPath) {
-		return fmt.Errorf("web.admin_path (%s) conflicts with server.path (%s); please configure different values", cfg.Web.AdminPath, cfg.Server.Path)
+	for _, prefix := range cfg.Server.CapturePrefixes() {
+		serverPath := normalizeConfigPath(prefix)
+		if pathsOverlap(serverPath, webPath) {
+			return fmt.Errorf("web.path (%s) conflicts with %s (%s); please configure different values", cfg.Web.Path, key, prefix)
+		}
+		if pathsOverlap(serverPath, adminPath) {
+			return fmt.Errorf("web.admin_path (%s) conflicts with %s (%s); please configure different values", cfg.Web.AdminPath, key, prefix)
+		}
 	}
 
 	return nil
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Manage the mock response rules of a running ReqTap instance",
+	Long: `List, add and remove server.responses rules through the admin API of a running instance, without a
+restart. Rules added this way are matched before the rules of the config file, replace a config
+rule with the same name and are kept in the sqlite storage across restarts.
+
+  reqtap mock add --name outage --match-prefix /reqtap/payments --status 503
+  reqtap mock rm outage
+  reqtap mock export -o rules.yaml              # on one instance
+  reqtap mock import rules.yaml --replace       # on another
+  reqtap mock import --preset slack             # built-in rules, see reqtap mock presets`,
+}
+
+var mockListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the mock response rules in match order",
+	RunE:  listMockRules,
+}
+
+var mockAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a mock response rule, or replace one with --replace",
+	Long: `Add a rule built from the flags, or from --file, a YAML or JSON document with the keys of a
+server.responses entry; flags override the keys of the file. --replace changes an existing rule,
+including one of the config file, instead of adding a new one.`,
+	RunE: addMockRule,
+}
+
+var mockRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove a mock response rule added through the API",
+	Args:  cobra.ExactArgs(1),
+	RunE:  removeMockRule,
+}
+
+var mockExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the mock response rules as a YAML list of server.responses entries",
+	RunE:  exportMockRules,
+}
+
+var mockImportCmd = &cobra.Command{
+	Use:   "import [file|-]",
+	Short: "Add the rules of a mock export, a server.responses list or a preset",
+	Long: `Add every rule of a YAML or JSON file (- reads stdin) holding a list of server.responses entries,
+as written by reqtap mock export, or the rules of a built-in preset with --preset. Existing rules
+are left alone unless --replace is given; nothing is changed when a name is taken.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: importMockRules,
+}
+
+var mockPresetsCmd = &cobra.Command{
+	Use:   "presets",
+	Short: "List the built-in mock rule presets",
+	Long: `List the presets that can be enabled with --mock-preset or server.mock_presets when starting
+ReqTap, or added to a running instance with reqtap mock import --preset.`,
+	Args: cobra.NoArgs,
+	RunE: listMockPresets,
+}
+
+func init() {
+	mockCmd.PersistentFlags().String("url", "", "ReqTap base URL, defaults to the local instance of the configuration")
+	mockCmd.PersistentFlags().String("token", "", "API token with the admin scope; not needed when web auth is off")
+	mockCmd.PersistentFlags().String("api-path", "", "web.admin_path of the instance, defaults to the local web.admin_path")
+
+	mockAddCmd.Flags().String("name", "", "Rule name (required unless set in --file)")
+	mockAddCmd.Flags().StringSlice("method", nil, "HTTP methods the rule matches (default any)")
+	mockAddCmd.Flags().String("match-path", "", "Exact request path the rule matches")
+	mockAddCmd.Flags().String("match-prefix", "", "Request path prefix the rule matches")
+	mockAddCmd.Flags().Int("status", 0, "Response status code (default 200)")
+	mockAddCmd.Flags().String("body", "", "Response body")
+	mockAddCmd.Flags().Bool("template", false, "Render the body and headers as mock templates")
+	mockAddCmd.Flags().StringArray("header", nil, `Response header as "Name: value", repeatable`)
+	mockAddCmd.Flags().Duration("delay", 0, "Hold the response back for this long")
+	mockAddCmd.Flags().String("file", "", "YAML or JSON file with the rule")
+	mockAddCmd.Flags().Bool("replace", false, "Replace the existing rule of that name")
+
+	mockExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	mockExportCmd.Flags().String("source", "all", "Rules to export: all, api (added through the API) or config")
+
+	mockImportCmd.Flags().String("preset", "", "Import the rules of this built-in preset instead of a file")
+	mockImportCmd.Flags().Bool("replace", false, "Replace existing rules with the same names")
+
+	mockCmd.AddCommand(mockListCmd)
+	mockCmd.AddCommand(mockAddCmd)
+	mockCmd.AddCommand(mockRmCmd)
+	mockCmd.AddCommand(mockExportCmd)
+	mockCmd.AddCommand(mockImportCmd)
+	mockCmd.AddCommand(mockPresetsCmd)
+	rootCmd.AddCommand(mockCmd)
+}
+
+// mockRulesClient calls the /mock-rules endpoints of an instance
+type mockRulesClient struct {
+	base  string
+	token string
+}
+
+func newMockRulesClient(cmd *cobra.Command) (*mockRulesClient, error) {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	remote, _ := cmd.Flags().GetString("url")
+	if remote == "" {
+		remote = fmt.Sprintf("%s://127.0.0.1:%d", cfg.Server.Scheme(), cfg.Server.Port)
+	}
+	endpoint, err := url.Parse(strings.TrimSpace(remote))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid URL: %s", remote)
+	}
+	apiPath, _ := cmd.Flags().GetString("api-path")
+	if apiPath == "" {
+		apiPath = cfg.Web.AdminPath
+	}
+	endpoint.Path = strings.TrimRight(endpoint.Path, "/") + "/" + strings.Trim(apiPath, "/") + "/mock-rules"
+	token, _ := cmd.Flags().GetString("token")
+	return &mockRulesClient{base: endpoint.String(), token: token}, nil
+}
+
+// do sends a request and decodes a JSON answer into out; error statuses are returned with the
+// message of the server.
+func (c *mockRulesClient) do(method, name string, body interface{}, out interface{}) error {
+	target := c.base
+	if name != "" {
+		target += "/" + url.PathEscape(name)
+	}
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, target, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s", method, target, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type mockRuleEntry struct {
+	Source    string                 `json:"source"`
+	Rule      map[string]interface{} `json:"rule"`
+	UpdatedBy string                 `json:"updated_by,omitempty"`
+}
+
+// fetchMockRules lists the rules of the instance in match order
+func (c *mockRulesClient) fetchMockRules() ([]mockRuleEntry, error) {
+	var result struct {
+		Rules []mockRuleEntry `json:"rules"`
+	}
+	if err := c.do(http.MethodGet, "", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Rules, nil
+}
+
+func listMockRules(cmd *cobra.Command, args []string) error {
+	client, err := newMockRulesClient(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := client.fetchMockRules()
+	if err != nil {
+		return err
+	}
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tMETHODS\tPATH\tSTATUS")
+	for _, entry := range entries {
+		path := "*"
+		if value, ok := entry.Rule["path"]; ok {
+			path = fmt.Sprint(value)
+		} else if value, ok := entry.Rule["path_prefix"]; ok {
+			path = fmt.Sprint(value) + "*"
+		}
+		methods := "*"
+		if value, ok := entry.Rule["methods"].([]interface{}); ok {
+			names := make([]string, 0, len(value))
+			for _, method := range value {
+				names = append(names, fmt.Sprint(method))
+			}
+			methods = strings.Join(names, ",")
+		}
+		fmt.Fprintf(w, "%v\t%s\t%s\t%s\t%v\n", entry.Rule["name"], entry.Source, methods, path, entry.Rule["status"])
+	}
+	return w.Flush()
+}
+
+func addMockRule(cmd *cobra.Command, args []string) error {
+	rule := map[string]interface{}{}
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(raw, &rule); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+	}
+	flags := cmd.Flags()
+	for flag, key := range map[string]string{"name": "name", "match-path": "path", "match-prefix": "path_prefix", "body": "body"} {
+		if flags.Changed(flag) {
+			rule[key], _ = flags.GetString(flag)
+		}
+	}
+	if flags.Changed("method") {
+		rule["methods"], _ = flags.GetStringSlice("method")
+	}
+	if flags.Changed("status") {
+		rule["status"], _ = flags.GetInt("status")
+	}
+	if flags.Changed("template") {
+		rule["template"], _ = flags.GetBool("template")
+	}
+	if flags.Changed("delay") {
+		delay, _ := flags.GetDuration("delay")
+		rule["delay"] = delay.String()
+	}
+	if flags.Changed("header") {
+		headers, _ := rule["headers"].(map[string]interface{})
+		if headers == nil {
+			headers = map[string]interface{}{}
+		}
+		values, _ := flags.GetStringArray("header")
+		for _, value := range values {
+			key, val, ok := strings.Cut(value, ":")
+			if !ok {
+				return fmt.Errorf(`--header must be "Name: value": %s`, value)
+			}
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		rule["headers"] = headers
+	}
+	name, _ := rule["name"].(string)
+	if name == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	client, err := newMockRulesClient(cmd)
+	if err != nil {
+		return err
+	}
+	if replace, _ := flags.GetBool("replace"); replace {
+		err = client.do(http.MethodPut, name, rule, nil)
+	} else {
+		err = client.do(http.MethodPost, "", rule, nil)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Mock rule %s saved\n", name)
+	return nil
+}
+
+func removeMockRule(cmd *cobra.Command, args []string) error {
+	client, err := newMockRulesClient(cmd)
+	if err != nil {
+		return err
+	}
+	if err := client.do(http.MethodDelete, args[0], nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("Mock rule %s removed\n", args[0])
+	return nil
+}
+
+func exportMockRules(cmd *cobra.Command, args []string) error {
+	source, _ := cmd.Flags().GetString("source")
+	source = strings.ToLower(strings.TrimSpace(source))
+	if source != "all" && source != "api" && source != "config" {
+		return fmt.Errorf("--source must be all, api or config")
+	}
+	client, err := newMockRulesClient(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := client.fetchMockRules()
+	if err != nil {
+		return err
+	}
+
+	rules := []config.ImmediateResponseConfig{}
+	for _, entry := range entries {
+		if source != "all" && entry.Source != source {
+			continue
+		}
+		// Round trip through the config struct so keys come out in the order of server.responses
+		raw, err := yaml.Marshal(entry.Rule)
+		if err != nil {
+			return err
+		}
+		var rule config.ImmediateResponseConfig
+		if err := yaml.Unmarshal(raw, &rule); err != nil {
+			return fmt.Errorf("failed to decode rule %v: %w", entry.Rule["name"], err)
+		}
+		rules = append(rules, rule)
+	}
+	data, err := yaml.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d mock rules to %s\n", len(rules), output)
+	return nil
+}
+
+func importMockRules(cmd *cobra.Command, args []string) error {
+	presetName, _ := cmd.Flags().GetString("preset")
+	var rules []map[string]interface{}
+	switch {
+	case presetName != "" && len(args) > 0:
+		return fmt.Errorf("give either a file or --preset")
+	case presetName != "":
+		preset, ok := config.LookupMockPreset(presetName)
+		if !ok {
+			return fmt.Errorf("unknown preset %q (available: %s)", presetName, strings.Join(config.MockPresetNames(), ", "))
+		}
+		// Encode the rules as YAML documents, e.g. a delay of "500ms", which the API expects
+		raw, err := yaml.Marshal(preset.Rules)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(raw, &rules); err != nil {
+			return err
+		}
+	case len(args) == 1:
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read rules: %w", err)
+		}
+		if rules, err = parseMockRuleList(data); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", args[0], err)
+		}
+	default:
+		return fmt.Errorf("a file or --preset is required")
+	}
+
+	client, err := newMockRulesClient(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := client.fetchMockRules()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		existing[fmt.Sprint(entry.Rule["name"])] = true
+	}
+	replace, _ := cmd.Flags().GetBool("replace")
+	var taken []string
+	for i, rule := range rules {
+		name, _ := rule["name"].(string)
+		if name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if existing[name] && !replace {
+			taken = append(taken, name)
+		}
+	}
+	if len(taken) > 0 {
+		return fmt.Errorf("mock rules already exist: %s (use --replace)", strings.Join(taken, ", "))
+	}
+
+	for _, rule := range rules {
+		name := rule["name"].(string)
+		if existing[name] {
+			err = client.do(http.MethodPut, name, rule, nil)
+		} else {
+			err = client.do(http.MethodPost, "", rule, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Imported %d mock rules\n", len(rules))
+	return nil
+}
+
+// parseMockRuleList reads a list of server.responses entries, the entries of reqtap mock list
+// --json, or a document with a responses or server.responses list.
+func parseMockRuleList(data []byte) ([]map[string]interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if mapping, ok := doc.(map[string]interface{}); ok {
+		if server, ok := mapping["server"].(map[string]interface{}); ok {
+			mapping = server
+		}
+		doc = mapping["responses"]
+	}
+	items, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of rules")
+	}
+	rules := make([]map[string]interface{}, 0, len(items))
+	for i, item := range items {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rule %d is not a mapping", i+1)
+		}
+		if nested, ok := rule["rule"].(map[string]interface{}); ok {
+			rule = nested
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func listMockPresets(cmd *cobra.Command, args []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRESET\tRULES\tDESCRIPTION")
+	for _, preset := range config.MockPresets() {
+		names := make([]string, 0, len(preset.Rules))
+		for _, rule := range preset.Rules {
+			names = append(names, rule.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", preset.Name, strings.Join(names, ","), preset.Description)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/server"
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Inspect and retry the persisted forward queue",
+	Long: `Deliveries that failed every attempt are kept in the forward_queue table when forward.queue.enable
+is set, and retried in the background with backoff, also after a restart.`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued forward deliveries",
+	RunE:  listQueue,
+}
+
+var queueFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Retry every queued forward delivery now",
+	Long: `Retry every queued delivery once, regardless of its schedule. Delivered entries leave the queue,
+failed ones are rescheduled with backoff (or dropped after forward.queue.max_attempts).`,
+	RunE: flushQueue,
+}
+
+func init() {
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueFlushCmd)
+	rootCmd.AddCommand(queueCmd)
+}
+
+func openForwardQueue(cmd *cobra.Command) (*server.ForwardQueue, error) {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return server.OpenForwardQueue(cfg, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+}
+
+func listQueue(cmd *cobra.Command, args []string) error {
+	queue, err := openForwardQueue(cmd)
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+
+	items, err := queue.List()
+	if err != nil {
+		return fmt.Errorf("failed to read forward queue: %w", err)
+	}
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+	if len(items) == 0 {
+		fmt.Println("Forward queue is empty")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREQUEST\tTARGET\tATTEMPTS\tNEXT ATTEMPT\tLAST ERROR")
+	for _, item := range items {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			item.ID, item.RequestID, item.TargetURL, item.Attempts,
+			item.NextAttempt.Local().Format(time.RFC3339), item.LastError)
+	}
+	return w.Flush()
+}
+
+func flushQueue(cmd *cobra.Command, args []string) error {
+	queue, err := openForwardQueue(cmd)
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := queue.Flush(ctx)
+	fmt.Printf("Delivered: %d, rescheduled: %d, dropped: %d\n", report.Delivered, report.Rescheduled, report.Dropped)
+	return err
+}
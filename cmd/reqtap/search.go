@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Search captured requests, including their bodies",
+	Long: `List the stored requests whose path, query, headers, note or text body contain the search text,
+ignoring case, newest first. Body matches are shown as a snippet with the match highlighted:
+
+  reqtap search order_id=12345 --since 24h
+  reqtap search "invoice.paid" --json | jq -r .id`,
+	Args: cobra.ExactArgs(1),
+	RunE: searchRequests,
+}
+
+func init() {
+	searchCmd.Flags().String("method", "", "Only search requests with this HTTP method")
+	searchCmd.Flags().Duration("since", 0, "Only search requests captured within this duration, e.g. 24h")
+	searchCmd.Flags().Int("limit", 20, "Maximum number of requests to list")
+	rootCmd.AddCommand(searchCmd)
+}
+
+func searchRequests(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver == "plugin" {
+		return fmt.Errorf("search requires the sqlite or bolt storage driver")
+	}
+	store, err := storage.New(&cfg.Storage, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := storage.ListOptions{Search: args[0]}
+	opts.Method, _ = cmd.Flags().GetString("method")
+	opts.Limit, _ = cmd.Flags().GetInt("limit")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		opts.Since = time.Now().Add(-since)
+	}
+	items, total, err := store.List(opts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(items) == 0 {
+		fmt.Println("No matching requests")
+		return nil
+	}
+	highlight := color.New(color.FgBlack, color.BgYellow)
+	for _, item := range items {
+		fmt.Printf("%s  %-6s %s  %s\n", item.Timestamp.Local().Format("2006-01-02 15:04:05"), item.Method, item.Path, item.ID)
+		if match := item.BodyMatch; match != nil {
+			fmt.Printf("    %s  (%d in body)\n", highlightSnippet(match, highlight), match.Matches)
+		}
+	}
+	if total > len(items) {
+		fmt.Printf("Showing %d of %d matching requests; raise --limit to see more\n", len(items), total)
+	}
+	return nil
+}
+
+// highlightSnippet colors the matches of a snippet and keeps it on one line
+func highlightSnippet(match *storage.SearchMatch, highlight *color.Color) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range match.Highlights {
+		b.WriteString(match.Snippet[last:span[0]])
+		b.WriteString(highlight.Sprint(match.Snippet[span[0]:span[1]]))
+		last = span[1]
+	}
+	b.WriteString(match.Snippet[last:])
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the captured requests",
+	Long: `Print the summary the server prints on shutdown for the stored requests: total requests, counts per
+method, body size p50/p95, forward success rate and latency p50/p95, and the most requested paths:
+
+  reqtap stats --since 24h
+  reqtap stats --top 20 --json | jq .top_paths`,
+	Args: cobra.NoArgs,
+	RunE: showStats,
+}
+
+func init() {
+	statsCmd.Flags().Duration("since", 0, "Only summarize requests captured within this duration, e.g. 24h")
+	statsCmd.Flags().Int("top", 10, "Number of most requested paths to list")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func showStats(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver == "plugin" {
+		return fmt.Errorf("stats requires the sqlite or bolt storage driver")
+	}
+	store, err := storage.New(&cfg.Storage, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var since time.Time
+	if duration, _ := cmd.Flags().GetDuration("since"); duration > 0 {
+		since = time.Now().Add(-duration)
+	}
+	top, _ := cmd.Flags().GetInt("top")
+	summary, err := storage.Summarize(store, since, time.Time{}, top)
+	if err != nil {
+		return fmt.Errorf("failed to summarize requests: %w", err)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	}
+	return printer.WriteSummary(os.Stdout, summary)
+}
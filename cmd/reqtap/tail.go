@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/tail"
+	"github.com/funnyzak/reqtap/pkg/i18n"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Follow the requests captured by a remote ReqTap instance",
+	Long: `Connect to the web console WebSocket of a remote ReqTap instance and print every request it
+captures with the local console printer, as if it had been received here. The output settings
+(--json, --body-view, locale, ...) come from the local configuration. The connection is
+re-established with backoff when it drops.
+
+  reqtap tail --url http://remote:38888 --token <api token>`,
+	RunE: runTail,
+}
+
+func init() {
+	tailCmd.Flags().String("url", "", "Remote ReqTap base URL or WebSocket endpoint (required)")
+	tailCmd.Flags().String("token", "", "API token of the remote web console; not needed when its auth is off")
+	tailCmd.Flags().String("api-path", "", "Remote web.admin_path, defaults to the local web.admin_path")
+	tailCmd.Flags().Int("history", 0, "Print the latest N stored requests before following")
+	rootCmd.AddCommand(tailCmd)
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	remote, _ := cmd.Flags().GetString("url")
+	if remote == "" {
+		return fmt.Errorf("--url is required")
+	}
+	token, _ := cmd.Flags().GetString("token")
+	apiPath, _ := cmd.Flags().GetString("api-path")
+	if apiPath == "" {
+		apiPath = cfg.Web.AdminPath
+	}
+	history, _ := cmd.Flags().GetInt("history")
+
+	log := logger.NewLogger(&cfg.Log, cfg.Output.Mode)
+	client, err := tail.NewClient(tail.Options{URL: remote, Token: token, APIPath: apiPath, History: history}, log)
+	if err != nil {
+		return err
+	}
+	translator, err := i18n.NewTranslator("en")
+	if err != nil {
+		return err
+	}
+	outputFile := printer.OpenOutputFile(&cfg.Output)
+	if outputFile != nil {
+		defer outputFile.Close()
+	}
+	p := printer.New(strings.ToLower(cfg.Output.Mode), log, &cfg.Output, translator, cfg.Output.Locale, printer.Writer(outputFile, false))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return client.Run(ctx, func(data *request.RequestData) {
+		if err := p.PrintRequest(data); err != nil {
+			log.Error("Failed to print request", "error", err, "request_id", data.ID)
+		}
+	})
+}
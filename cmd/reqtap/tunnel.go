@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/tunnel"
+)
+
+var tunnelCmd = &cobra.Command{
+	Use:   "tunnel",
+	Short: "Receive the requests of a public ReqTap instance on a local one",
+	Long: `Connect to a public ReqTap instance with tunnel.enable set and relay every request it captures
+to a locally running ReqTap, whose answer is returned to the original sender. The connection is
+kept open and re-established with backoff when it drops.
+
+  reqtap tunnel --server https://hooks.example.com --secret <tunnel.secret>`,
+	RunE: runTunnel,
+}
+
+func init() {
+	tunnelCmd.Flags().String("server", "", "Public ReqTap base URL or tunnel endpoint (required)")
+	tunnelCmd.Flags().String("secret", "", "Tunnel secret, defaults to tunnel.secret")
+	tunnelCmd.Flags().String("target", "", "Local ReqTap URL, defaults to http://127.0.0.1:<server.port>")
+	rootCmd.AddCommand(tunnelCmd)
+}
+
+func runTunnel(cmd *cobra.Command, args []string) error {
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	serverURL, _ := cmd.Flags().GetString("server")
+	if serverURL == "" {
+		return fmt.Errorf("--server is required")
+	}
+	// A bare base URL connects to the configured tunnel path
+	if parsed, err := url.Parse(serverURL); err == nil && (parsed.Path == "" || parsed.Path == "/") {
+		parsed.Path = cfg.Tunnel.Path
+		if parsed.Path == "" {
+			parsed.Path = "/reqtap-tunnel"
+		}
+		serverURL = parsed.String()
+	}
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = cfg.Tunnel.Secret
+	}
+	target, _ := cmd.Flags().GetString("target")
+	if target == "" {
+		target = fmt.Sprintf("http://127.0.0.1:%d", cfg.Server.Port)
+	}
+
+	client, err := tunnel.NewClient(tunnel.ClientOptions{
+		ServerURL: serverURL,
+		Secret:    secret,
+		TargetURL: target,
+		Timeout:   cfg.Tunnel.Timeout,
+	}, logger.NewLogger(&cfg.Log, cfg.Output.Mode))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return client.Run(ctx)
+}
@@ -0,0 +1,275 @@
+// Package anomaly flags captured traffic that deviates from its own recent history.
+//
+// Requests are aggregated into fixed windows. When a window closes, its request
+// count, error rate and mean body size are compared with a rolling baseline made
+// of the previous windows; a value more than Threshold standard deviations away
+// from the baseline mean raises an Event.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Metric names reported in events.
+const (
+	MetricRequestRate = "request_rate"
+	MetricErrorRate   = "error_rate"
+	MetricBodySize    = "body_size"
+)
+
+// Event directions.
+const (
+	DirectionSpike = "spike"
+	DirectionDrop  = "drop"
+)
+
+// Event describes a window whose metric deviated from the rolling baseline.
+type Event struct {
+	Metric      string    `json:"metric"`
+	Direction   string    `json:"direction"`
+	Value       float64   `json:"value"`
+	Baseline    float64   `json:"baseline"`
+	StdDev      float64   `json:"stddev"`
+	Score       float64   `json:"score"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Message     string    `json:"message"`
+}
+
+// Options tunes the detector; zero values fall back to the config defaults.
+type Options struct {
+	Window          time.Duration
+	BaselineWindows int
+	Threshold       float64
+	MinRequests     int
+	Cooldown        time.Duration
+}
+
+// Detector keeps the rolling baselines; it is safe for concurrent use.
+type Detector struct {
+	mu        sync.Mutex
+	opts      Options
+	notify    func(Event)
+	current   window
+	series    map[string]*series
+	lastFired map[string]time.Time
+}
+
+type window struct {
+	start  time.Time
+	count  int
+	errors int
+	bytes  int64
+}
+
+// New creates a detector that hands every event to notify.
+func New(opts Options, notify func(Event)) *Detector {
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.BaselineWindows < 3 {
+		opts.BaselineWindows = 30
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = 3
+	}
+	if notify == nil {
+		notify = func(Event) {}
+	}
+	return &Detector{
+		opts:   opts,
+		notify: notify,
+		series: map[string]*series{
+			MetricRequestRate: newSeries(opts.BaselineWindows),
+			MetricErrorRate:   newSeries(opts.BaselineWindows),
+			MetricBodySize:    newSeries(opts.BaselineWindows),
+		},
+		lastFired: make(map[string]time.Time),
+	}
+}
+
+// Observe records one captured request.
+func (d *Detector) Observe(ts time.Time, size int64, failed bool) {
+	d.mu.Lock()
+	events := d.advance(ts)
+	d.current.count++
+	d.current.bytes += size
+	if failed {
+		d.current.errors++
+	}
+	d.mu.Unlock()
+	d.emit(events)
+}
+
+// Tick closes windows that ended before now, so that silence is noticed too.
+func (d *Detector) Tick(now time.Time) {
+	d.mu.Lock()
+	events := d.advance(now)
+	d.mu.Unlock()
+	d.emit(events)
+}
+
+// Run ticks until ctx is done.
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.Window / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.Tick(now)
+		}
+	}
+}
+
+func (d *Detector) emit(events []Event) {
+	for _, ev := range events {
+		d.notify(ev)
+	}
+}
+
+// advance closes every window that ended at or before now; callers hold d.mu.
+func (d *Detector) advance(now time.Time) []Event {
+	if d.current.start.IsZero() {
+		d.current.start = now.Truncate(d.opts.Window)
+		return nil
+	}
+	var events []Event
+	closed := 0
+	for !now.Before(d.current.start.Add(d.opts.Window)) {
+		// After a long idle gap only the most recent windows matter for the baseline
+		if closed > d.opts.BaselineWindows {
+			d.current = window{start: now.Truncate(d.opts.Window)}
+			break
+		}
+		events = append(events, d.closeWindow()...)
+		d.current = window{start: d.current.start.Add(d.opts.Window)}
+		closed++
+	}
+	return events
+}
+
+func (d *Detector) closeWindow() []Event {
+	w := d.current
+	var events []Event
+	check := func(metric string, value, floor float64, judge bool) {
+		s := d.series[metric]
+		if judge {
+			if ev, ok := d.evaluate(metric, s, value, floor, w); ok {
+				events = append(events, ev)
+			}
+		}
+		s.push(value)
+	}
+
+	rate := float64(w.count)
+	// Spikes need some volume, drops need a baseline worth losing
+	judgeRate := rate >= float64(d.opts.MinRequests) || d.series[MetricRequestRate].mean() >= float64(d.opts.MinRequests)
+	check(MetricRequestRate, rate, 1, judgeRate)
+	if w.count > 0 && w.count >= d.opts.MinRequests {
+		check(MetricErrorRate, float64(w.errors)/float64(w.count), 0.05, true)
+		check(MetricBodySize, float64(w.bytes)/float64(w.count), 16, true)
+	}
+	return events
+}
+
+func (d *Detector) evaluate(metric string, s *series, value, floor float64, w window) (Event, bool) {
+	// Wait for half a baseline before judging anything
+	if s.len() < (d.opts.BaselineWindows+1)/2 {
+		return Event{}, false
+	}
+	mean, stddev := s.mean(), s.stddev()
+	spread := math.Max(stddev, math.Max(floor, math.Abs(mean)*0.1))
+	score := (value - mean) / spread
+	if math.Abs(score) < d.opts.Threshold {
+		return Event{}, false
+	}
+	end := w.start.Add(d.opts.Window)
+	if last, ok := d.lastFired[metric]; ok && d.opts.Cooldown > 0 && end.Sub(last) < d.opts.Cooldown {
+		return Event{}, false
+	}
+	d.lastFired[metric] = end
+
+	direction := DirectionSpike
+	if score < 0 {
+		direction = DirectionDrop
+	}
+	return Event{
+		Metric:      metric,
+		Direction:   direction,
+		Value:       value,
+		Baseline:    mean,
+		StdDev:      stddev,
+		Score:       score,
+		WindowStart: w.start,
+		WindowEnd:   end,
+		Message:     describe(metric, direction, value, mean, d.opts.Window),
+	}, true
+}
+
+func describe(metric, direction string, value, baseline float64, win time.Duration) string {
+	switch metric {
+	case MetricRequestRate:
+		return fmt.Sprintf("request rate %s: %.0f per %s (baseline %.1f)", direction, value, win, baseline)
+	case MetricErrorRate:
+		return fmt.Sprintf("error rate %s: %.1f%% (baseline %.1f%%)", direction, value*100, baseline*100)
+	default:
+		return fmt.Sprintf("body size %s: %.0f bytes on average (baseline %.0f)", direction, value, baseline)
+	}
+}
+
+// series is a fixed-size ring of the most recent window values.
+type series struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newSeries(size int) *series {
+	return &series{values: make([]float64, size)}
+}
+
+func (s *series) push(v float64) {
+	s.values[s.next] = v
+	s.next = (s.next + 1) % len(s.values)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+func (s *series) len() int {
+	if s.full {
+		return len(s.values)
+	}
+	return s.next
+}
+
+func (s *series) mean() float64 {
+	n := s.len()
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range s.values[:n] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+func (s *series) stddev() float64 {
+	n := s.len()
+	if n < 2 {
+		return 0
+	}
+	mean := s.mean()
+	var sum float64
+	for _, v := range s.values[:n] {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(n-1))
+}
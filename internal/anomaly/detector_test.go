@@ -0,0 +1,121 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) notify(ev Event) {
+	r.events = append(r.events, ev)
+}
+
+func (r *recorder) byMetric(metric string) []Event {
+	var out []Event
+	for _, ev := range r.events {
+		if ev.Metric == metric {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+var epoch = time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+// feed sends count requests spread over window i.
+func feed(d *Detector, i, count int, size int64, failed int) {
+	start := epoch.Add(time.Duration(i) * time.Minute)
+	for n := 0; n < count; n++ {
+		d.Observe(start.Add(time.Duration(n)*time.Millisecond), size, n < failed)
+	}
+}
+
+func newTestDetector(r *recorder) *Detector {
+	return New(Options{Window: time.Minute, BaselineWindows: 10, Threshold: 3, MinRequests: 5, Cooldown: 5 * time.Minute}, r.notify)
+}
+
+func TestDetectorFlagsRateSpike(t *testing.T) {
+	r := &recorder{}
+	d := newTestDetector(r)
+	for i := 0; i < 10; i++ {
+		feed(d, i, 20+i%3, 100, 0)
+	}
+	if len(r.events) != 0 {
+		t.Fatalf("expected a quiet baseline, got %+v", r.events)
+	}
+
+	feed(d, 10, 80, 100, 0)
+	d.Tick(epoch.Add(11 * time.Minute))
+	spikes := r.byMetric(MetricRequestRate)
+	if len(spikes) != 1 || spikes[0].Direction != DirectionSpike || spikes[0].Value != 80 {
+		t.Fatalf("expected one rate spike, got %+v", spikes)
+	}
+
+	// The next spike falls within the cooldown
+	feed(d, 11, 90, 100, 0)
+	d.Tick(epoch.Add(12 * time.Minute))
+	if spikes := r.byMetric(MetricRequestRate); len(spikes) != 1 {
+		t.Fatalf("expected the cooldown to suppress a repeat, got %+v", spikes)
+	}
+}
+
+func TestDetectorFlagsSilenceAsDrop(t *testing.T) {
+	r := &recorder{}
+	d := newTestDetector(r)
+	for i := 0; i < 10; i++ {
+		feed(d, i, 20+i%3, 100, 0)
+	}
+	d.Tick(epoch.Add(11 * time.Minute))
+
+	rate := r.byMetric(MetricRequestRate)
+	if len(rate) != 1 || rate[0].Direction != DirectionDrop || rate[0].Value != 0 {
+		t.Fatalf("expected a rate drop for an empty window, got %+v", rate)
+	}
+}
+
+func TestDetectorErrorRateAndBodySize(t *testing.T) {
+	r := &recorder{}
+	d := newTestDetector(r)
+	for i := 0; i < 10; i++ {
+		feed(d, i, 20, 100, 1)
+	}
+	feed(d, 10, 20, 5000, 15)
+	d.Tick(epoch.Add(11 * time.Minute))
+
+	if errs := r.byMetric(MetricErrorRate); len(errs) != 1 || errs[0].Direction != DirectionSpike {
+		t.Fatalf("expected an error rate spike, got %+v", errs)
+	}
+	if sizes := r.byMetric(MetricBodySize); len(sizes) != 1 || sizes[0].Value != 5000 {
+		t.Fatalf("expected a body size spike, got %+v", sizes)
+	}
+	if rate := r.byMetric(MetricRequestRate); len(rate) != 0 {
+		t.Fatalf("steady volume must not be flagged, got %+v", rate)
+	}
+}
+
+func TestDetectorNeedsBaselineAndVolume(t *testing.T) {
+	r := &recorder{}
+	d := newTestDetector(r)
+	// Too little history
+	feed(d, 0, 10, 100, 0)
+	feed(d, 1, 10, 100, 0)
+	feed(d, 2, 500, 100, 0)
+	d.Tick(epoch.Add(3 * time.Minute))
+	if len(r.events) != 0 {
+		t.Fatalf("expected no events before the baseline warms up, got %+v", r.events)
+	}
+
+	// Quiet traffic below min_requests is never judged
+	r2 := &recorder{}
+	d2 := newTestDetector(r2)
+	for i := 0; i < 12; i++ {
+		feed(d2, i, i%2, 100, i%2)
+	}
+	d2.Tick(epoch.Add(12 * time.Minute))
+	if len(r2.events) != 0 {
+		t.Fatalf("expected low-volume traffic to be ignored, got %+v", r2.events)
+	}
+}
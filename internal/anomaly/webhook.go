@@ -0,0 +1,72 @@
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+)
+
+// webhookTimeout bounds a single notification delivery.
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier posts events as JSON to a fixed set of URLs.
+type WebhookNotifier struct {
+	urls   []string
+	client *http.Client
+	log    logger.Logger
+}
+
+// NewWebhookNotifier returns nil when there is nothing to notify.
+func NewWebhookNotifier(urls []string, log logger.Logger) *WebhookNotifier {
+	if len(urls) == 0 {
+		return nil
+	}
+	return &WebhookNotifier{
+		urls:   urls,
+		client: &http.Client{Timeout: webhookTimeout},
+		log:    log,
+	}
+}
+
+// Notify delivers the event to every URL; failures are logged, not retried.
+func (n *WebhookNotifier) Notify(ctx context.Context, ev Event) {
+	if n == nil {
+		return
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":  "anomaly",
+		"event": ev,
+	})
+	if err != nil {
+		n.log.Error("Failed to encode anomaly event", "error", err)
+		return
+	}
+	for _, url := range n.urls {
+		if err := n.post(ctx, url, payload); err != nil {
+			n.log.Warn("Failed to deliver anomaly notification", "url", url, "error", err)
+		}
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ReqTap/1.0")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// Package archive moves old captured requests to S3 or GCS, one gzipped ndjson object per UTC
+// day in the format of `reqtap export --format ndjson`, and then deletes them locally.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+const day = 24 * time.Hour
+
+// Archiver uploads the unpinned requests of every UTC day older than After, then deletes them
+// with their forwards, replays, comments and spilled bodies. Pinned requests stay local.
+type Archiver struct {
+	store    storage.Store
+	bucket   *bucket
+	prefix   string
+	after    time.Duration
+	interval time.Duration
+	// keepPreviews archives spilled bodies as their stored preview, as the spill files hold the
+	// bodies unmasked while redaction rules mask bodies
+	keepPreviews bool
+	log          logger.Logger
+	now          func() time.Time
+}
+
+// New returns nil when archiving is disabled. maskedBodies reports whether redaction rules mask
+// request bodies; spilled bodies are then archived as their masked preview.
+func New(cfg config.StorageArchiveConfig, maskedBodies bool, store storage.Store, log logger.Logger) (*Archiver, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	b, prefix, err := newBucket(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Archiver{
+		store:        store,
+		bucket:       b,
+		prefix:       prefix,
+		after:        cfg.After,
+		interval:     cfg.Interval,
+		keepPreviews: maskedBodies,
+		log:          log,
+		now:          time.Now,
+	}, nil
+}
+
+// Start archives on start-up and then every interval until ctx is cancelled; wg tracks the loop so
+// the store is not closed under a running pass.
+func (a *Archiver) Start(ctx context.Context, wg *sync.WaitGroup) {
+	if a == nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := a.Run(ctx); err != nil && ctx.Err() == nil {
+				a.log.Error("Failed to archive requests", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run archives every complete day older than After and returns how many requests it moved. A day
+// that fails stays local and is retried on the next run.
+func (a *Archiver) Run(ctx context.Context) (int64, error) {
+	cutoff := a.now().Add(-a.after).UTC().Truncate(day)
+	pending := make(map[time.Time]struct{})
+	err := a.store.Iterate(storage.ListOptions{Until: cutoff}, func(item *storage.StoredRequest) bool {
+		if !item.Pinned {
+			pending[item.Timestamp.UTC().Truncate(day)] = struct{}{}
+		}
+		return ctx.Err() == nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list requests to archive: %w", err)
+	}
+	days := make([]time.Time, 0, len(pending))
+	for d := range pending {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	var archived int64
+	for _, d := range days {
+		if err := ctx.Err(); err != nil {
+			return archived, err
+		}
+		n, err := a.archiveDay(ctx, d)
+		if err != nil {
+			return archived, fmt.Errorf("archive %s: %w", d.Format(time.DateOnly), err)
+		}
+		archived += n
+	}
+	return archived, nil
+}
+
+// archiveDay uploads the unpinned requests captured on the UTC day starting at start and deletes
+// them once the object is stored
+func (a *Archiver) archiveDay(ctx context.Context, start time.Time) (int64, error) {
+	f, err := os.CreateTemp("", "reqtap-archive-*.ndjson.gz")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(f, hash))
+	bw := bufio.NewWriter(gz)
+	var ids []string
+	var writeErr error
+	err = a.store.Iterate(storage.ListOptions{Since: start, Until: start.Add(day)}, func(item *storage.StoredRequest) bool {
+		if item.Pinned {
+			return true
+		}
+		if writeErr = ctx.Err(); writeErr != nil {
+			return false
+		}
+		if writeErr = a.writeRecord(bw, item); writeErr != nil {
+			return false
+		}
+		ids = append(ids, item.ID)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	key, err := a.objectKey(ctx, start)
+	if err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := a.bucket.put(ctx, key, f, size, hex.EncodeToString(hash.Sum(nil)), "application/gzip"); err != nil {
+		return 0, err
+	}
+	deleted, err := a.store.Delete(ids)
+	if err != nil {
+		return 0, fmt.Errorf("delete archived requests: %w", err)
+	}
+	a.log.Info("Archived requests", "day", start.Format(time.DateOnly), "object", a.bucket.location(key), "requests", deleted, "bytes", size)
+	return deleted, nil
+}
+
+// objectKey names the object of the day starting at start; requests added to an archived day
+// later, e.g. by `reqtap import`, go to a numbered object next to it instead of replacing it
+func (a *Archiver) objectKey(ctx context.Context, start time.Time) (string, error) {
+	base := a.prefix + start.Format(time.DateOnly)
+	key := base + ".ndjson.gz"
+	for n := 2; ; n++ {
+		exists, err := a.bucket.exists(ctx, key)
+		if err != nil || !exists {
+			return key, err
+		}
+		key = fmt.Sprintf("%s-%d.ndjson.gz", base, n)
+	}
+}
+
+// streamedRequest leaves out the body of a request, which writeRecord streams after it
+type streamedRequest struct {
+	*storage.StoredRequest
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// writeRecord writes item as one ndjson line like `reqtap export`. A spilled body is streamed from
+// its file into the line, base64-encoded like every body, as the file is deleted with the request
+// and may be too large to read into memory. A spill file that is already gone, or one that holds
+// a body redaction rules would mask, leaves the stored preview.
+func (a *Archiver) writeRecord(w io.Writer, item *storage.StoredRequest) error {
+	if item.RequestData == nil || item.BodyFile == "" {
+		return json.NewEncoder(w).Encode(item)
+	}
+	data := *item.RequestData
+	data.BodyFile = ""
+	copied := *item
+	copied.RequestData = &data
+	if a.keepPreviews {
+		return json.NewEncoder(w).Encode(&copied)
+	}
+	reader, _, err := item.OpenBody()
+	if errors.Is(err, fs.ErrNotExist) {
+		return json.NewEncoder(w).Encode(&copied)
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	head, err := json.Marshal(streamedRequest{StoredRequest: &copied})
+	if err != nil {
+		return err
+	}
+	// head is a JSON object; the body goes in as its last member
+	if _, err := w.Write(head[:len(head)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"body":"`); err != nil {
+		return err
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, reader); err != nil {
+		return fmt.Errorf("read spilled body: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\"}\n")
+	return err
+}
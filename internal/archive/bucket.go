@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// uploadTimeout bounds a single object request, so a stalled connection cannot hold the archiver
+const uploadTimeout = 10 * time.Minute
+
+// bucket writes objects through the S3 API, signed with AWS Signature Version 4. GCS accepts the
+// same requests on storage.googleapis.com when signed with HMAC keys.
+type bucket struct {
+	client *http.Client
+	// scheme is s3 or gs, as in the configured URL
+	scheme string
+	name   string
+	// endpoint is the base URL of path-style requests; empty addresses the bucket as a subdomain of
+	// the regional AWS endpoint
+	endpoint     string
+	region       string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+// newBucket returns the bucket of cfg.URL and the key prefix of the archived objects
+func newBucket(cfg config.StorageArchiveConfig) (*bucket, string, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse archive url: %w", err)
+	}
+	b := &bucket{
+		client:       &http.Client{Timeout: uploadTimeout},
+		scheme:       u.Scheme,
+		name:         u.Host,
+		endpoint:     strings.TrimRight(cfg.Endpoint, "/"),
+		region:       firstNonEmpty(cfg.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		accessKeyID:  firstNonEmpty(cfg.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID")),
+		secretKey:    firstNonEmpty(cfg.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		now:          time.Now,
+	}
+	if u.Scheme == "gs" {
+		b.region = firstNonEmpty(cfg.Region, "auto")
+		b.sessionToken = ""
+		if b.endpoint == "" {
+			b.endpoint = "https://storage.googleapis.com"
+		}
+	}
+	if b.accessKeyID == "" || b.secretKey == "" {
+		return nil, "", fmt.Errorf("archive credentials missing: set storage.archive.access_key_id and secret_access_key or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return b, prefix, nil
+}
+
+// location is the configured-style URL of key, for logs
+func (b *bucket) location(key string) string {
+	return b.scheme + "://" + b.name + "/" + key
+}
+
+// objectURL is where key is read and written
+func (b *bucket) objectURL(key string) string {
+	if b.endpoint == "" {
+		return "https://" + b.name + ".s3." + b.region + ".amazonaws.com/" + escapeKey(key)
+	}
+	return b.endpoint + "/" + b.name + "/" + escapeKey(key)
+}
+
+// exists reports whether key is already stored
+func (b *bucket) exists(ctx context.Context, key string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, b.objectURL(key), nil)
+	if err != nil {
+		return false, err
+	}
+	b.sign(req, emptyPayloadHash)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("check %s: %w", b.location(key), err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode < 300:
+		return true, nil
+	default:
+		return false, fmt.Errorf("check %s: unexpected status %d", b.location(key), resp.StatusCode)
+	}
+}
+
+// put uploads size bytes of body to key; payloadHash is their hex SHA-256
+func (b *bucket) put(ctx context.Context, key string, body io.Reader, size int64, payloadHash, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.objectURL(key), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	b.sign(req, payloadHash)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload %s: %w", b.location(key), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload %s: unexpected status %d: %s", b.location(key), resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds the AWS Signature Version 4 Authorization header to req
+func (b *bucket) sign(req *http.Request, payloadHash string) {
+	now := b.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.sessionToken)
+	}
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if b.sessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+b.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapeKey escapes every segment of key as signed by SigV4, keeping the slashes
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
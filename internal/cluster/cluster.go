@@ -0,0 +1,127 @@
+// Package cluster shares captured requests between ReqTap instances so every web console
+// shows the merged, instance-labeled stream of the whole deployment.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+const (
+	// RequestsPath is where peers accept requests, relative to the admin API base URL.
+	RequestsPath = "/cluster/requests"
+	// SecretHeader carries the shared cluster secret.
+	SecretHeader = "X-ReqTap-Cluster-Secret"
+)
+
+// Authorized reports whether the request presents the shared secret.
+func Authorized(r *http.Request, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), []byte(secret)) == 1
+}
+
+// Gossip pushes the requests captured locally to every peer. Requests received from peers are
+// never pushed again, so each request travels exactly one hop.
+type Gossip struct {
+	instance string
+	secret   string
+	client   *http.Client
+	log      logger.Logger
+	peers    []*peer
+}
+
+type peer struct {
+	url   string
+	queue chan *request.RequestData
+}
+
+// NewGossip returns nil when clustering is disabled.
+func NewGossip(cfg config.ClusterConfig, log logger.Logger) *Gossip {
+	if !cfg.Enable {
+		return nil
+	}
+	g := &Gossip{
+		instance: cfg.InstanceID,
+		secret:   cfg.Secret,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		log:      log,
+	}
+	for _, url := range cfg.Peers {
+		g.peers = append(g.peers, &peer{url: url + RequestsPath, queue: make(chan *request.RequestData, cfg.QueueSize)})
+	}
+	return g
+}
+
+// Instance is the label stamped on requests captured by this instance.
+func (g *Gossip) Instance() string {
+	return g.instance
+}
+
+// Start runs one delivery loop per peer until ctx is cancelled.
+func (g *Gossip) Start(ctx context.Context) {
+	for _, p := range g.peers {
+		go g.deliver(ctx, p)
+	}
+}
+
+// Publish queues a request for every peer; when a peer falls behind its overflow is dropped. A
+// body spilled to disk is shared as its in-memory preview, as the file is local to this instance.
+func (g *Gossip) Publish(data *request.RequestData) {
+	if data.BodyFile != "" {
+		shared := *data
+		shared.BodyFile = ""
+		data = &shared
+	}
+	for _, p := range g.peers {
+		select {
+		case p.queue <- data:
+		default:
+			g.log.Warn("Cluster peer queue full, dropping request", "peer", p.url, "request_id", data.ID)
+		}
+	}
+}
+
+func (g *Gossip) deliver(ctx context.Context, p *peer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-p.queue:
+			if err := g.push(ctx, p.url, data); err != nil {
+				g.log.Warn("Failed to share request with cluster peer", "peer", p.url, "request_id", data.ID, "error", err)
+			}
+		}
+	}
+}
+
+func (g *Gossip) push(ctx context.Context, url string, data *request.RequestData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SecretHeader, g.secret)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestGossipPushesToPeers(t *testing.T) {
+	received := make(chan *request.RequestData, 1)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api"+RequestsPath || !Authorized(r, "s3cret") {
+			http.Error(w, "unexpected", http.StatusUnauthorized)
+			return
+		}
+		var data request.RequestData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- &data
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer peer.Close()
+
+	gossip := NewGossip(config.ClusterConfig{
+		Enable:     true,
+		InstanceID: "edge-1",
+		Peers:      []string{peer.URL + "/api"},
+		Secret:     "s3cret",
+		QueueSize:  4,
+		Timeout:    time.Second,
+	}, noopLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gossip.Start(ctx)
+
+	gossip.Publish(&request.RequestData{ID: "REQ-1", Method: http.MethodPost, Path: "/hook", Instance: gossip.Instance()})
+
+	select {
+	case data := <-received:
+		if data.ID != "REQ-1" || data.Instance != "edge-1" || data.Path != "/hook" {
+			t.Fatalf("unexpected request shared: %+v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("peer did not receive the request")
+	}
+
+	// A spilled body is shared as its preview, not as a path on this instance
+	spilled := &request.RequestData{ID: "REQ-2", Body: []byte("prev"), Size: 1 << 20, BodyFile: "/var/lib/reqtap/bodies/body-1.bin", Instance: gossip.Instance()}
+	gossip.Publish(spilled)
+	select {
+	case data := <-received:
+		if data.ID != "REQ-2" || data.BodyFile != "" || string(data.Body) != "prev" || data.Size != 1<<20 {
+			t.Fatalf("expected the preview without the body file, got %+v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("peer did not receive the spilled request")
+	}
+	if spilled.BodyFile == "" {
+		t.Fatal("expected the local record to keep its body file")
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, RequestsPath, nil)
+	if Authorized(r, "s3cret") {
+		t.Fatal("a request without the secret must be rejected")
+	}
+	r.Header.Set(SecretHeader, "s3cret")
+	if !Authorized(r, "s3cret") {
+		t.Fatal("expected the shared secret to be accepted")
+	}
+	if Authorized(r, "") {
+		t.Fatal("an empty secret must never authorize")
+	}
+}
+
+func TestNewGossipDisabled(t *testing.T) {
+	if NewGossip(config.ClusterConfig{}, noopLogger{}) != nil {
+		t.Fatal("expected no gossip when clustering is disabled")
+	}
+}
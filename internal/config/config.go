@@ -1,23 +1,57 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
+	"github.com/funnyzak/reqtap/internal/password"
 )
 
 // Config application configuration structure
 type Config struct {
-	Server  ServerConfig  `yaml:"server" mapstructure:"server"`
-	Log     LogConfig     `yaml:"log" mapstructure:"log"`
-	Forward ForwardConfig `yaml:"forward" mapstructure:"forward"`
-	Web     WebConfig     `yaml:"web" mapstructure:"web"`
-	Output  OutputConfig  `yaml:"output" mapstructure:"output"`
-	Storage StorageConfig `yaml:"storage" mapstructure:"storage"`
+	Server  ServerConfig   `yaml:"server" mapstructure:"server"`
+	Log     LogConfig      `yaml:"log" mapstructure:"log"`
+	Forward ForwardConfig  `yaml:"forward" mapstructure:"forward"`
+	Web     WebConfig      `yaml:"web" mapstructure:"web"`
+	Output  OutputConfig   `yaml:"output" mapstructure:"output"`
+	Storage StorageConfig  `yaml:"storage" mapstructure:"storage"`
+	Plugins []PluginConfig `yaml:"plugins" mapstructure:"plugins"`
+	// WasmTransforms run sandboxed WebAssembly modules over each captured request
+	WasmTransforms []WasmTransformConfig `yaml:"wasm_transforms" mapstructure:"wasm_transforms"`
+	Anomaly        AnomalyConfig         `yaml:"anomaly" mapstructure:"anomaly"`
+	Dedup          DedupConfig           `yaml:"dedup" mapstructure:"dedup"`
+	Cluster        ClusterConfig         `yaml:"cluster" mapstructure:"cluster"`
+	Telemetry      TelemetryConfig       `yaml:"telemetry" mapstructure:"telemetry"`
+	Tunnel         TunnelConfig          `yaml:"tunnel" mapstructure:"tunnel"`
+	Debug          DebugConfig           `yaml:"debug" mapstructure:"debug"`
+
+	// Hooks redact, tag or drop matching requests at the hook points of the capture pipeline
+	Hooks []HookConfig `yaml:"hooks" mapstructure:"hooks"`
+
+	// Scripts run JavaScript over each captured request to respond, tag, drop or rewrite its forward
+	Scripts []ScriptConfig `yaml:"scripts" mapstructure:"scripts"`
+
+	// Privacy keeps sensitive values out of storage, console output and exports
+	Privacy PrivacyConfig `yaml:"privacy" mapstructure:"privacy"`
+
+	// Health serves liveness and readiness probes for orchestrators such as Kubernetes
+	Health HealthConfig `yaml:"health" mapstructure:"health"`
 }
 
 // ServerConfig HTTP server configuration
@@ -25,25 +59,261 @@ type ServerConfig struct {
 	Port int    `yaml:"port" mapstructure:"port"`
 	Path string `yaml:"path" mapstructure:"path"`
 	// MaxBodyBytes limits the size of accepted request bodies (0 = unlimited)
-	MaxBodyBytes int64                     `yaml:"max_body_bytes" mapstructure:"max_body_bytes"`
-	Responses    []ImmediateResponseConfig `yaml:"responses" mapstructure:"responses"`
+	MaxBodyBytes int64 `yaml:"max_body_bytes" mapstructure:"max_body_bytes"`
+	// Spill streams large bodies to files instead of buffering them in memory
+	Spill     BodySpillConfig           `yaml:"spill" mapstructure:"spill"`
+	Responses []ImmediateResponseConfig `yaml:"responses" mapstructure:"responses"`
+	WebSocket WebSocketCaptureConfig    `yaml:"websocket" mapstructure:"websocket"`
+	GRPC      GRPCCaptureConfig         `yaml:"grpc" mapstructure:"grpc"`
+	Identity  IdentityConfig            `yaml:"identity" mapstructure:"identity"`
+	// Auth requires credentials on the capture path; web console users are configured under web.auth
+	Auth CaptureAuthConfig `yaml:"auth" mapstructure:"auth"`
+	// TrustedProxies are the peers (CIDRs or single addresses) whose X-Forwarded-For and X-Real-IP
+	// headers name the client; requests from any other peer are recorded with their own address
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+	// AccessControl limits which client addresses reach the capture path
+	AccessControl AccessControlConfig `yaml:"access_control" mapstructure:"access_control"`
+	// CORS answers browser preflight requests and adds CORS headers to capture responses
+	CORS CORSConfig `yaml:"cors" mapstructure:"cors"`
+	// HTTP2 accepts HTTP/2 next to HTTP/1.1: h2c with prior knowledge in cleartext, ALPN over TLS
+	HTTP2 HTTP2Config `yaml:"http2" mapstructure:"http2"`
+	// TLS serves the listener over HTTPS when a certificate and key are configured
+	TLS ServerTLSConfig `yaml:"tls" mapstructure:"tls"`
+	// Paths replaces Path with several capture prefixes, each with its own mock rules and forward targets
+	Paths []CapturePathConfig `yaml:"paths" mapstructure:"paths"`
+
+	// MockPresets adds the rules of built-in presets (github, slack, stripe) before Responses
+	MockPresets []string `yaml:"mock_presets" mapstructure:"mock_presets"`
+	// Listener hands the listening socket over between processes so restarts do not refuse connections
+	Listener ListenerConfig `yaml:"listener" mapstructure:"listener"`
+}
+
+// BodySpillConfig streams bodies above a threshold to disk; memory then only holds a preview
+type BodySpillConfig struct {
+	// ThresholdBytes is the body size kept in memory; larger bodies go to a file (0 disables spilling)
+	ThresholdBytes int64 `yaml:"threshold_bytes" mapstructure:"threshold_bytes"`
+	// Dir holds the spilled bodies; empty uses a "bodies" directory next to the sqlite database
+	Dir string `yaml:"dir" mapstructure:"dir"`
+}
+
+// CapturePathConfig is one capture prefix of server.paths; requests go to the longest matching prefix
+type CapturePathConfig struct {
+	Path string `yaml:"path" mapstructure:"path"`
+	// Responses replace server.responses for this prefix when set
+	Responses []ImmediateResponseConfig `yaml:"responses" mapstructure:"responses"`
+	// Forward replaces forward.urls and forward.targets for this prefix when set; the other forward
+	// settings are shared
+	Forward CapturePathForwardConfig `yaml:"forward" mapstructure:"forward"`
+}
+
+// CapturePathForwardConfig lists the forward targets of one capture prefix
+type CapturePathForwardConfig struct {
+	URLs    []string              `yaml:"urls" mapstructure:"urls"`
+	Targets []ForwardTargetConfig `yaml:"targets" mapstructure:"targets"`
+}
+
+// CapturePrefixes lists the prefixes the listener captures: every server.paths entry, or server.path
+func (c ServerConfig) CapturePrefixes() []string {
+	if len(c.Paths) == 0 {
+		return []string{c.Path}
+	}
+	prefixes := make([]string, 0, len(c.Paths))
+	for _, route := range c.Paths {
+		prefixes = append(prefixes, route.Path)
+	}
+	return prefixes
+}
+
+// HTTP2Config toggles HTTP/2 on the capture listener
+type HTTP2Config struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+}
+
+// ListenerConfig controls how the listening socket is obtained
+type ListenerConfig struct {
+	// ReusePort sets SO_REUSEPORT, so a new process can bind server.port while the old one drains
+	ReusePort bool `yaml:"reuse_port" mapstructure:"reuse_port"`
+	// SocketActivation serves the socket passed by systemd (LISTEN_FDS) instead of binding server.port
+	SocketActivation bool `yaml:"socket_activation" mapstructure:"socket_activation"`
+}
+
+// ServerTLSConfig points at a PEM certificate chain and its private key
+type ServerTLSConfig struct {
+	CertFile string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `yaml:"key_file" mapstructure:"key_file"`
+}
+
+// Enabled reports whether the listener serves HTTPS
+func (c ServerTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// Scheme is the URL scheme clients use to reach the listener
+func (c ServerConfig) Scheme() string {
+	if c.TLS.Enabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// CaptureAuthConfig lists the credentials accepted on the capture path
+type CaptureAuthConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// Realm is announced in the WWW-Authenticate challenge of rejected requests
+	Realm       string                    `yaml:"realm" mapstructure:"realm"`
+	Credentials []CaptureCredentialConfig `yaml:"credentials" mapstructure:"credentials"`
+}
+
+// CaptureCredentialConfig is a bearer Token or a Username/Password pair for basic auth
+type CaptureCredentialConfig struct {
+	// Name is recorded on every request captured with the credential; it defaults to the username
+	Name     string `yaml:"name" mapstructure:"name"`
+	Token    string `yaml:"token" mapstructure:"token"`
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+}
+
+// AccessControlConfig filters capture requests by client IP; entries are CIDRs or single addresses
+type AccessControlConfig struct {
+	// Allow lists the only addresses accepted when set
+	Allow []string `yaml:"allow" mapstructure:"allow"`
+	// Deny rejects matching addresses, even when they are allowed
+	Deny []string `yaml:"deny" mapstructure:"deny"`
+	// TrustedProxies is kept for older configs and added to server.trusted_proxies
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+}
+
+// CORSConfig lets browser pages call the capture path from other origins
+type CORSConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// AllowedOrigins lists the accepted Origin values; "*" accepts any origin
+	AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods" mapstructure:"allowed_methods"`
+	// AllowedHeaders lists the request headers a preflight may ask for; empty allows whatever it asks
+	AllowedHeaders []string `yaml:"allowed_headers" mapstructure:"allowed_headers"`
+	// AllowCredentials lets pages send cookies and credentials; the origin is then echoed instead of "*"
+	AllowCredentials bool `yaml:"allow_credentials" mapstructure:"allow_credentials"`
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge time.Duration `yaml:"max_age" mapstructure:"max_age"`
+}
+
+// IdentityConfig controls how capture responses identify the server
+type IdentityConfig struct {
+	// ServerHeader overrides the Server header (ReqTap/1.0, or the stealth profile's banner)
+	ServerHeader string `yaml:"server_header" mapstructure:"server_header"`
+	// HideServerHeader omits the Server header entirely
+	HideServerHeader bool `yaml:"hide_server_header" mapstructure:"hide_server_header"`
+	// Stealth mimics Profile: its banner and its error pages replace ReqTap's
+	Stealth bool   `yaml:"stealth" mapstructure:"stealth"`
+	Profile string `yaml:"profile" mapstructure:"profile"`
+}
+
+// WebSocketCaptureConfig controls how WebSocket upgrades on the capture path are handled
+type WebSocketCaptureConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// ProxyURL relays frames to an upstream ws:// or wss:// endpoint; empty terminates the socket in ReqTap
+	ProxyURL string `yaml:"proxy_url" mapstructure:"proxy_url"`
+	// PreviewBytes caps the payload preview logged for each frame
+	PreviewBytes int `yaml:"preview_bytes" mapstructure:"preview_bytes"`
+}
+
+// GRPCCaptureConfig controls how gRPC calls (HTTP/2, application/grpc) are captured
+type GRPCCaptureConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// DescriptorSets are FileDescriptorSet files (protoc --include_imports --descriptor_set_out) used to decode messages
+	DescriptorSets []string `yaml:"descriptor_sets" mapstructure:"descriptor_sets"`
+	// Reflection fetches unknown schemas from the backend's server reflection service
+	Reflection bool `yaml:"reflection" mapstructure:"reflection"`
+	// BackendURL relays calls to an upstream gRPC server (http:// for h2c, https:// for TLS); empty answers with an empty message
+	BackendURL string        `yaml:"backend_url" mapstructure:"backend_url"`
+	Timeout    time.Duration `yaml:"timeout" mapstructure:"timeout"`
 }
 
-// ImmediateResponseConfig describes an inline response rule for incoming requests
+// ImmediateResponseConfig describes an inline response rule for incoming requests.
+// With Template set, body and header values may use Go-template placeholders such as {{.Method}}
+// or {{.JSONBody "user.id"}}.
 type ImmediateResponseConfig struct {
-	Name       string            `yaml:"name" mapstructure:"name"`
-	Methods    []string          `yaml:"methods" mapstructure:"methods"`
-	Path       string            `yaml:"path" mapstructure:"path"`
-	PathPrefix string            `yaml:"path_prefix" mapstructure:"path_prefix"`
-	Status     int               `yaml:"status" mapstructure:"status"`
-	Body       string            `yaml:"body" mapstructure:"body"`
-	Headers    map[string]string `yaml:"headers" mapstructure:"headers"`
+	Name       string            `yaml:"name,omitempty" mapstructure:"name"`
+	Methods    []string          `yaml:"methods,omitempty" mapstructure:"methods"`
+	Path       string            `yaml:"path,omitempty" mapstructure:"path"`
+	PathPrefix string            `yaml:"path_prefix,omitempty" mapstructure:"path_prefix"`
+	Status     int               `yaml:"status,omitempty" mapstructure:"status"`
+	Body       string            `yaml:"body,omitempty" mapstructure:"body"`
+	Headers    map[string]string `yaml:"headers,omitempty" mapstructure:"headers"`
+	// Template renders Body and Headers, and those of the Sequence steps, as templates; without it
+	// they are sent verbatim, so literal "{{" text needs no escaping
+	Template bool `yaml:"template,omitempty" mapstructure:"template"`
+	// StatusText replaces the standard reason phrase of the status line, e.g. "200 Everything Fine"
+	StatusText string `yaml:"status_text,omitempty" mapstructure:"status_text"`
+	// HTTP10 answers with an HTTP/1.0 status line, a Content-Length body and Connection: close
+	HTTP10 bool `yaml:"http10,omitempty" mapstructure:"http10"`
+	// Compression gzips the body for clients that accept it ("gzip"); empty sends bodies as-is
+	Compression string `yaml:"compression,omitempty" mapstructure:"compression"`
+	// CompressionMinBytes leaves smaller bodies uncompressed
+	CompressionMinBytes int `yaml:"compression_min_bytes,omitempty" mapstructure:"compression_min_bytes"`
+	// BodyFile serves the body from disk with Range, ETag and Last-Modified support instead of Body
+	BodyFile string `yaml:"body_file,omitempty" mapstructure:"body_file"`
+	// BodyURL serves the body fetched from an http(s) URL on first use, with the same semantics as
+	// BodyFile; BodyURLTTL refetches it once expired (0 keeps it until the next reload)
+	BodyURL    string        `yaml:"body_url,omitempty" mapstructure:"body_url"`
+	BodyURLTTL time.Duration `yaml:"body_url_ttl,omitempty" mapstructure:"body_url_ttl"`
+	// Delay holds the response back; DelayJitter adds a random extra delay of up to its value
+	Delay       time.Duration `yaml:"delay,omitempty" mapstructure:"delay"`
+	DelayJitter time.Duration `yaml:"delay_jitter,omitempty" mapstructure:"delay_jitter"`
+	// TimeoutChance is the probability (0-1) that no response is sent at all and the connection
+	// hangs until the client gives up
+	TimeoutChance float64 `yaml:"timeout_chance,omitempty" mapstructure:"timeout_chance"`
+	// Sequence answers successive matching calls with these steps in order, e.g. 500 twice and then
+	// 200; the last step keeps answering unless SequenceLoop starts over
+	Sequence     []ResponseStepConfig `yaml:"sequence,omitempty" mapstructure:"sequence"`
+	SequenceLoop bool                 `yaml:"sequence_loop,omitempty" mapstructure:"sequence_loop"`
+	// HeaderMatch, QueryMatch and BodyMatch narrow the rule to requests satisfying every condition,
+	// so different payloads on the same path can get different responses
+	HeaderMatch []MatchConditionConfig `yaml:"header_match,omitempty" mapstructure:"header_match"`
+	QueryMatch  []MatchConditionConfig `yaml:"query_match,omitempty" mapstructure:"query_match"`
+	BodyMatch   []MatchConditionConfig `yaml:"body_match,omitempty" mapstructure:"body_match"`
+}
+
+// MatchConditionConfig tests a header or query parameter (Name), a JSON body field (JSONPath) or,
+// for body_match without a json_path, the whole body. At most one of Equals, Contains and Regex may
+// be set; when none is, the header, parameter or field only has to exist.
+type MatchConditionConfig struct {
+	Name     string `yaml:"name,omitempty" mapstructure:"name"`
+	JSONPath string `yaml:"json_path,omitempty" mapstructure:"json_path"`
+	Equals   string `yaml:"equals,omitempty" mapstructure:"equals"`
+	Contains string `yaml:"contains,omitempty" mapstructure:"contains"`
+	Regex    string `yaml:"regex,omitempty" mapstructure:"regex"`
+}
+
+// ResponseStepConfig is one step of a response sequence; unset fields keep the rule's values and
+// headers are merged over the rule's headers
+type ResponseStepConfig struct {
+	Status  int               `yaml:"status,omitempty" mapstructure:"status"`
+	Body    string            `yaml:"body,omitempty" mapstructure:"body"`
+	Headers map[string]string `yaml:"headers,omitempty" mapstructure:"headers"`
+	// Times is how many calls the step answers before the next one takes over (default 1)
+	Times int `yaml:"times,omitempty" mapstructure:"times"`
 }
 
 // LogConfig log configuration
 type LogConfig struct {
 	Level       string        `yaml:"level"`
 	FileLogging FileLogConfig `yaml:"file_logging"`
+	// Outputs send logs to syslog or journald in addition to stdout and the log file
+	Outputs []LogOutputConfig `yaml:"outputs" mapstructure:"outputs"`
+}
+
+// LogOutputConfig is one extra log destination of log.outputs
+type LogOutputConfig struct {
+	// Type is "syslog" (RFC 5424) or "journald"
+	Type string `yaml:"type" mapstructure:"type"`
+	// Network is "udp" (default) or "tcp"; Address is the syslog server as host:port
+	Network string `yaml:"network" mapstructure:"network"`
+	Address string `yaml:"address" mapstructure:"address"`
+	// Facility is the syslog facility, e.g. "local0" (default "user")
+	Facility string `yaml:"facility" mapstructure:"facility"`
+	// Tag is the syslog APP-NAME and the journald SYSLOG_IDENTIFIER (default "reqtap")
+	Tag string `yaml:"tag" mapstructure:"tag"`
 }
 
 // FileLogConfig file log configuration
@@ -73,6 +343,228 @@ type ForwardConfig struct {
 	PathStrategy          ForwardPathStrategyConfig `yaml:"path_strategy" mapstructure:"path_strategy"`
 	HeaderBlacklist       []string                  `yaml:"header_blacklist" mapstructure:"header_blacklist"`
 	HeaderWhitelist       []string                  `yaml:"header_whitelist" mapstructure:"header_whitelist"`
+	Targets               []ForwardTargetConfig     `yaml:"targets" mapstructure:"targets"`
+	// LatencyBudget is the provider timeout forwards are measured against; 0 disables the check
+	LatencyBudget time.Duration `yaml:"latency_budget" mapstructure:"latency_budget"`
+	// Expectations is the response contract of every HTTP target without an expect of its own
+	Expectations ForwardExpectConfig `yaml:"expectations" mapstructure:"expectations"`
+	// Filters decide per target which requests are forwarded
+	Filters []ForwardFilterConfig `yaml:"filters" mapstructure:"filters"`
+	// Transforms rewrite the body and headers of each request before it is sent to a target
+	Transforms []ForwardTransformConfig `yaml:"transforms" mapstructure:"transforms"`
+	// CircuitBreaker stops forwarding to a target after consecutive failures
+	CircuitBreaker ForwardCircuitBreakerConfig `yaml:"circuit_breaker" mapstructure:"circuit_breaker"`
+	// HealthCheck probes every target in the background
+	HealthCheck ForwardHealthCheckConfig `yaml:"health_check" mapstructure:"health_check"`
+	// Queue persists failed deliveries and retries them, also after a restart
+	Queue ForwardQueueConfig `yaml:"queue" mapstructure:"queue"`
+	// Ordering forwards the requests sharing a key in the order they arrived
+	Ordering ForwardOrderingConfig `yaml:"ordering" mapstructure:"ordering"`
+	// HTTP2 selects the protocol towards targets: auto (HTTP/2 when https:// targets offer it via
+	// ALPN), always (HTTP/2 only, h2c prior knowledge for http:// targets) or off (HTTP/1.1 only)
+	HTTP2 string `yaml:"http2" mapstructure:"http2"`
+	// HostOverrides connect to fixed addresses for target hostnames instead of resolving them
+	HostOverrides []ForwardHostOverrideConfig `yaml:"host_overrides" mapstructure:"host_overrides"`
+}
+
+// ForwardHostOverrideConfig maps a target hostname to the address forwards connect to, like an
+// /etc/hosts entry that also picks the port. A list rather than a map, as hostnames contain the
+// dots that separate config keys.
+type ForwardHostOverrideConfig struct {
+	// Host is a hostname such as api.internal, or host:port to override that port only
+	Host string `yaml:"host" mapstructure:"host"`
+	// Address is IP:port or host:port, or an IP alone to keep the port of the target URL
+	Address string `yaml:"address" mapstructure:"address"`
+}
+
+// ForwardQueueConfig stores deliveries that failed every attempt in the forward_queue table; a
+// background worker retries them every PollInterval with a backoff doubling from Backoff up to
+// MaxBackoff, and drops them after MaxAttempts retries (0 retries forever)
+type ForwardQueueConfig struct {
+	Enable       bool          `yaml:"enable" mapstructure:"enable"`
+	PollInterval time.Duration `yaml:"poll_interval" mapstructure:"poll_interval"`
+	Backoff      time.Duration `yaml:"backoff" mapstructure:"backoff"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+	MaxAttempts  int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+}
+
+// ForwardOrderingConfig forwards the requests sharing a key to each target one at a time, in the
+// order they arrived; requests with different keys, or without one, are forwarded in parallel
+type ForwardOrderingConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// Header names the header holding the key, e.g. X-Account-Id
+	Header string `yaml:"header" mapstructure:"header"`
+	// JSONPath reads the key from the JSON body when the header is not set or not sent
+	JSONPath string `yaml:"json_path" mapstructure:"json_path"`
+}
+
+// ForwardCircuitBreakerConfig opens a target's circuit after FailureThreshold consecutive failed
+// attempts; while open, requests skip the target until Cooldown elapses and one trial is let through.
+type ForwardCircuitBreakerConfig struct {
+	Enable           bool          `yaml:"enable" mapstructure:"enable"`
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `yaml:"cooldown" mapstructure:"cooldown"`
+}
+
+// ForwardHealthCheckConfig sends GET <target url><path> every Interval; a response below 400 is healthy
+type ForwardHealthCheckConfig struct {
+	Enable   bool          `yaml:"enable" mapstructure:"enable"`
+	Path     string        `yaml:"path" mapstructure:"path"`
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	Timeout  time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// ForwardFilterConfig allows or denies forwarding requests that match all of its conditions.
+// For each target the first matching filter wins; when none matches, the request is forwarded
+// unless an allow filter governs that target.
+type ForwardFilterConfig struct {
+	Name   string `yaml:"name" mapstructure:"name"`
+	Action string `yaml:"action" mapstructure:"action"`
+	// Targets lists the target URLs the filter governs; empty governs every target
+	Targets []string `yaml:"targets" mapstructure:"targets"`
+	Methods []string `yaml:"methods" mapstructure:"methods"`
+	// PathRegex, Headers values and BodyContains must all match for the filter to apply
+	PathRegex    string            `yaml:"path_regex" mapstructure:"path_regex"`
+	Headers      map[string]string `yaml:"headers" mapstructure:"headers"`
+	BodyContains string            `yaml:"body_contains" mapstructure:"body_contains"`
+}
+
+// ForwardTransformConfig rewrites requests before they are delivered to the targets it governs.
+// Transforms apply in order; within one, the JSON operations run first (rename, remove, then set),
+// followed by body and headers. Values and body are Go templates like mock responses.
+type ForwardTransformConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	// Targets lists the target URLs the transform applies to; empty applies to every target
+	Targets []string                   `yaml:"targets" mapstructure:"targets"`
+	JSON    ForwardJSONTransformConfig `yaml:"json" mapstructure:"json"`
+	// Body replaces the request body; empty keeps it
+	Body    string                       `yaml:"body" mapstructure:"body"`
+	Headers ForwardHeaderTransformConfig `yaml:"headers" mapstructure:"headers"`
+}
+
+// ForwardJSONTransformConfig edits JSON bodies by dotted path; other bodies are left unchanged.
+// Paths are lists rather than map keys so their case survives config loading.
+type ForwardJSONTransformConfig struct {
+	Rename []ForwardJSONRenameConfig `yaml:"rename" mapstructure:"rename"`
+	Remove []string                  `yaml:"remove" mapstructure:"remove"`
+	Set    []ForwardJSONSetConfig    `yaml:"set" mapstructure:"set"`
+}
+
+// ForwardJSONRenameConfig moves the value at From to To
+type ForwardJSONRenameConfig struct {
+	From string `yaml:"from" mapstructure:"from"`
+	To   string `yaml:"to" mapstructure:"to"`
+}
+
+// ForwardJSONSetConfig stores Value at Path as a string, or, with Raw, decoded as JSON
+type ForwardJSONSetConfig struct {
+	Path  string `yaml:"path" mapstructure:"path"`
+	Value string `yaml:"value" mapstructure:"value"`
+	Raw   bool   `yaml:"raw" mapstructure:"raw"`
+}
+
+// ForwardHeaderTransformConfig removes headers, then sets headers; set headers are sent even when
+// the header blacklist or whitelist would drop them
+type ForwardHeaderTransformConfig struct {
+	Set    map[string]string `yaml:"set" mapstructure:"set"`
+	Remove []string          `yaml:"remove" mapstructure:"remove"`
+}
+
+// ForwardTargetConfig describes a forward destination with optional per-target behavior
+type ForwardTargetConfig struct {
+	// URL is an HTTP endpoint or a message broker sink: kafka://broker1:9092,broker2:9092/topic,
+	// amqp(s)://user:pass@host/vhost?exchange=events&routing_key=hooks or mqtt(s)://host:1883/topic
+	URL    string              `yaml:"url" mapstructure:"url"`
+	Expect ForwardExpectConfig `yaml:"expect" mapstructure:"expect"`
+	// LatencyBudget overrides forward.latency_budget for this target
+	LatencyBudget time.Duration `yaml:"latency_budget" mapstructure:"latency_budget"`
+	// Format serializes messages published to sinks: json (the request record, as exported) or
+	// body (the raw request body); empty means json
+	Format string `yaml:"format" mapstructure:"format"`
+	// SamplePercent forwards only this share of requests (0-100) to the target; 0 forwards all
+	SamplePercent float64 `yaml:"sample_percent" mapstructure:"sample_percent"`
+	// Weight puts the target in the weighted group: each request goes to one weighted target,
+	// picked in proportion to the weights; 0 sends every request
+	Weight int `yaml:"weight" mapstructure:"weight"`
+	// Sign replaces the provider signature of the request with one computed over the forwarded body
+	Sign ForwardSignConfig `yaml:"sign" mapstructure:"sign"`
+	// MaxConcurrent caps the deliveries in flight to this target on top of forward.max_concurrent;
+	// 0 leaves only the global limit
+	MaxConcurrent int `yaml:"max_concurrent" mapstructure:"max_concurrent"`
+	// RateLimit caps the attempts sent to this target per second, retries included; 0 is unlimited
+	RateLimit float64 `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// Retry replaces forward.max_retries and the retry backoff for this target
+	Retry ForwardRetryConfig `yaml:"retry" mapstructure:"retry"`
+}
+
+// ForwardRetryConfig is the retry policy of a target. MaxRetries is only applied when set, so a
+// target can turn retries off with 0; the backoff doubles from Backoff (default 1s) up to
+// MaxBackoff (default 30s) between attempts.
+type ForwardRetryConfig struct {
+	MaxRetries *int          `yaml:"max_retries" mapstructure:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff" mapstructure:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+}
+
+// ForwardSignConfig re-signs forwarded requests, so that receivers verifying webhook signatures
+// accept them after path rewriting or body transforms
+type ForwardSignConfig struct {
+	// Scheme is github (X-Hub-Signature-256), stripe (Stripe-Signature), slack (X-Slack-Signature)
+	// or hmac; empty disables signing
+	Scheme string `yaml:"scheme" mapstructure:"scheme"`
+	Secret string `yaml:"secret" mapstructure:"secret"`
+	// Header, Algorithm (sha256, sha1 or sha512), Encoding (hex or base64) and Prefix shape the
+	// signature of the hmac scheme; they default to X-Signature, sha256, hex and no prefix
+	Header    string `yaml:"header" mapstructure:"header"`
+	Algorithm string `yaml:"algorithm" mapstructure:"algorithm"`
+	Encoding  string `yaml:"encoding" mapstructure:"encoding"`
+	Prefix    string `yaml:"prefix" mapstructure:"prefix"`
+}
+
+// Signature schemes of ForwardSignConfig.Scheme
+const (
+	SignSchemeGitHub = "github"
+	SignSchemeStripe = "stripe"
+	SignSchemeSlack  = "slack"
+	SignSchemeHMAC   = "hmac"
+)
+
+// Message formats of ForwardTargetConfig.Format
+const (
+	SinkFormatJSON = "json"
+	SinkFormatBody = "body"
+)
+
+// sinkSchemes are the forward URL schemes published to message brokers instead of sent over HTTP
+var sinkSchemes = map[string]bool{"kafka": true, "amqp": true, "amqps": true, "mqtt": true, "mqtts": true}
+
+// IsSinkURL reports whether a forward URL points at a message broker
+func IsSinkURL(raw string) bool {
+	scheme, _, found := strings.Cut(strings.TrimSpace(raw), "://")
+	return found && sinkSchemes[strings.ToLower(scheme)]
+}
+
+// ForwardExpectConfig declares the response contract a forward target must satisfy
+type ForwardExpectConfig struct {
+	// Status lists accepted response codes; empty means any status below 400
+	Status []int                 `yaml:"status" mapstructure:"status"`
+	JSON   []JSONAssertionConfig `yaml:"json" mapstructure:"json"`
+	// MaxLatency is the longest an attempt may take until the response is read; 0 disables the check
+	MaxLatency time.Duration `yaml:"max_latency" mapstructure:"max_latency"`
+	// BodyContains lists substrings the response body must include
+	BodyContains []string `yaml:"body_contains" mapstructure:"body_contains"`
+}
+
+// Empty reports whether no assertion is configured
+func (e ForwardExpectConfig) Empty() bool {
+	return len(e.Status) == 0 && len(e.JSON) == 0 && e.MaxLatency == 0 && len(e.BodyContains) == 0
+}
+
+// JSONAssertionConfig checks a field of the target's JSON response body
+type JSONAssertionConfig struct {
+	Path string `yaml:"path" mapstructure:"path"`
+	// Equals compares the rendered field value; when empty the field only has to exist
+	Equals string `yaml:"equals" mapstructure:"equals"`
 }
 
 // ForwardPathStrategyConfig configures how target paths are constructed
@@ -100,6 +592,25 @@ type WebConfig struct {
 	SupportedLocales []string        `yaml:"supported_locales" mapstructure:"supported_locales"`
 	Auth             WebAuthConfig   `yaml:"auth" mapstructure:"auth"`
 	Export           WebExportConfig `yaml:"export" mapstructure:"export"`
+	// WebSocket controls the live /ws feed used by the console
+	WebSocket WebSocketFeedConfig `yaml:"websocket" mapstructure:"websocket"`
+	// Audit records logins, exports, replays, deletions and configuration changes
+	Audit WebAuditConfig `yaml:"audit" mapstructure:"audit"`
+}
+
+// WebAuditConfig configures the append-only audit log of console and API actions
+type WebAuditConfig struct {
+	// Enable stores the entries in the storage database, listed by GET /api/audit
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// File also appends every entry as a JSON line to this file; empty writes no file
+	File string `yaml:"file" mapstructure:"file"`
+}
+
+// WebSocketFeedConfig configures the console's live event feed
+type WebSocketFeedConfig struct {
+	// History is how many stored requests are replayed to a client right after it connects; 0 disables the backfill.
+	// Clients may ask for a different count with ?history=N.
+	History int `yaml:"history" mapstructure:"history"`
 }
 
 // WebAuthConfig authentication configuration
@@ -107,13 +618,37 @@ type WebAuthConfig struct {
 	Enable         bool            `yaml:"enable" mapstructure:"enable"`
 	SessionTimeout time.Duration   `yaml:"session_timeout" mapstructure:"session_timeout"`
 	Users          []WebUserConfig `yaml:"users" mapstructure:"users"`
+	// LoginLink prints a one-time console login URL at startup
+	LoginLink WebLoginLinkConfig `yaml:"login_link" mapstructure:"login_link"`
+	// Tokens are long-lived admin API tokens sent as "Authorization: Bearer <token>"
+	Tokens []WebTokenConfig `yaml:"tokens" mapstructure:"tokens"`
+}
+
+// WebTokenConfig is an admin API token with its scopes: read, write, export, replay or admin
+type WebTokenConfig struct {
+	Name   string   `yaml:"name" mapstructure:"name"`
+	Token  string   `yaml:"token" mapstructure:"token"`
+	Scopes []string `yaml:"scopes" mapstructure:"scopes"`
+}
+
+// WebLoginLinkConfig configures the one-time login link shown in the startup banner
+type WebLoginLinkConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// User is the account the link signs in as; empty picks the first admin user
+	User string `yaml:"user" mapstructure:"user"`
+	// TTL is how long the link can be used; it works only once either way
+	TTL time.Duration `yaml:"ttl" mapstructure:"ttl"`
+	// OpenBrowser opens the link in the default browser once the server is up
+	OpenBrowser bool `yaml:"open_browser" mapstructure:"open_browser"`
 }
 
 // WebUserConfig user credential configuration
 type WebUserConfig struct {
 	Username string `yaml:"username" mapstructure:"username"`
 	Password string `yaml:"password" mapstructure:"password"`
-	Role     string `yaml:"role" mapstructure:"role"`
+	// PasswordHash is a bcrypt or argon2id hash (see `reqtap hash-password`); it takes precedence over Password
+	PasswordHash string `yaml:"password_hash" mapstructure:"password_hash"`
+	Role         string `yaml:"role" mapstructure:"role"`
 }
 
 // WebExportConfig export configuration
@@ -128,6 +663,28 @@ type OutputConfig struct {
 	Silence  bool           `yaml:"silence" mapstructure:"silence"`
 	Locale   string         `yaml:"locale" mapstructure:"locale"`
 	BodyView BodyViewConfig `yaml:"body_view" mapstructure:"body_view"`
+	// BodyFilter prints only the fragment of JSON bodies at this path (".data.id", "$.items[0]")
+	BodyFilter string `yaml:"body_filter" mapstructure:"body_filter"`
+	// File tees the console text or JSON lines to this file, without terminal colors; empty disables it
+	File string `yaml:"file" mapstructure:"file"`
+	// FileRotation rotates File like log.file_logging
+	FileRotation OutputFileRotationConfig `yaml:"file_rotation" mapstructure:"file_rotation"`
+	// Theme colors the console output: "default", "solarized" (light and dark terminals) or "mono"
+	Theme string `yaml:"theme" mapstructure:"theme"`
+	// NoColor prints without terminal colors; NO_COLOR and output that is not a terminal also turn
+	// them off
+	NoColor bool `yaml:"no_color" mapstructure:"no_color"`
+	// StatusLine keeps uptime, captures, req/s and forward errors on the last terminal row in
+	// console mode
+	StatusLine bool `yaml:"status_line" mapstructure:"status_line"`
+}
+
+// OutputFileRotationConfig bounds the size, count and age of the output.file transcripts
+type OutputFileRotationConfig struct {
+	MaxSizeMB  int  `yaml:"max_size_mb" mapstructure:"max_size_mb"`
+	MaxBackups int  `yaml:"max_backups" mapstructure:"max_backups"`
+	MaxAgeDays int  `yaml:"max_age_days" mapstructure:"max_age_days"`
+	Compress   bool `yaml:"compress" mapstructure:"compress"`
 }
 
 // StorageConfig 持久化存储参数
@@ -136,18 +693,216 @@ type StorageConfig struct {
 	Path       string        `yaml:"path" mapstructure:"path"`
 	MaxRecords int           `yaml:"max_records" mapstructure:"max_records"`
 	Retention  time.Duration `yaml:"retention" mapstructure:"retention"`
+	// MaintenanceInterval is how often the sqlite store prunes, vacuums and checkpoints in the background; 0 disables it
+	MaintenanceInterval time.Duration `yaml:"maintenance_interval" mapstructure:"maintenance_interval"`
+	// WriteBatch caps how many requests the sqlite writer commits in one transaction
+	WriteBatch int `yaml:"write_batch" mapstructure:"write_batch"`
+	// WriteQueue bounds the requests waiting for the sqlite writer; capture waits while it is full
+	WriteQueue int `yaml:"write_queue" mapstructure:"write_queue"`
+	// Plugin names the plugin serving the "storage" hook when driver is plugin
+	Plugin string `yaml:"plugin" mapstructure:"plugin"`
+	// Archive uploads old requests to object storage before deleting them locally
+	Archive StorageArchiveConfig `yaml:"archive" mapstructure:"archive"`
+}
+
+// StorageArchiveConfig moves requests older than After to an S3 or GCS bucket, one ndjson.gz
+// object per UTC day, so they are kept long-term without growing the local database
+type StorageArchiveConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// URL is the bucket and key prefix, s3://bucket/prefix or gs://bucket/prefix
+	URL string `yaml:"url" mapstructure:"url"`
+	// After is the age at which requests are archived; a day is archived once all of it is older
+	After time.Duration `yaml:"after" mapstructure:"after"`
+	// Interval is how often the archiver looks for days to archive
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	// Endpoint replaces the S3 or GCS endpoint, e.g. for MinIO; buckets are then addressed by path
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	// Region signs S3 requests; defaults to AWS_REGION, then us-east-1
+	Region string `yaml:"region" mapstructure:"region"`
+	// AccessKeyID and SecretAccessKey sign the uploads, HMAC keys for GCS; they default to
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	AccessKeyID     string `yaml:"access_key_id" mapstructure:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" mapstructure:"secret_access_key"`
+}
+
+// AnomalyConfig configures detection of traffic that deviates from its rolling baseline
+type AnomalyConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// Window is the aggregation interval each metric is measured over
+	Window time.Duration `yaml:"window" mapstructure:"window"`
+	// BaselineWindows is how many past windows form the rolling baseline
+	BaselineWindows int `yaml:"baseline_windows" mapstructure:"baseline_windows"`
+	// Threshold is the deviation, in standard deviations, that raises an event
+	Threshold float64 `yaml:"threshold" mapstructure:"threshold"`
+	// MinRequests ignores windows too quiet to judge error rate and body size
+	MinRequests int `yaml:"min_requests" mapstructure:"min_requests"`
+	// Cooldown suppresses repeated events for the same metric
+	Cooldown time.Duration `yaml:"cooldown" mapstructure:"cooldown"`
+	// Webhooks receive every event as a JSON POST
+	Webhooks []string `yaml:"webhooks" mapstructure:"webhooks"`
+}
+
+// DedupConfig flags re-deliveries of a request that was already captured
+type DedupConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// Header names an idempotency header, e.g. Idempotency-Key; requests carrying it are identified
+	// by its value, the others by method, path and body
+	Header string `yaml:"header" mapstructure:"header"`
+	// Window is how long after the first request a copy still counts as a duplicate
+	Window time.Duration `yaml:"window" mapstructure:"window"`
+	// SuppressForward stops duplicates from being forwarded; they are still answered and stored
+	SuppressForward bool `yaml:"suppress_forward" mapstructure:"suppress_forward"`
+}
+
+// ClusterConfig shares captured requests between ReqTap instances running behind a load balancer
+type ClusterConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// InstanceID labels the requests this instance captures; defaults to the hostname
+	InstanceID string `yaml:"instance_id" mapstructure:"instance_id"`
+	// Peers are the admin API base URLs of the other instances, e.g. http://reqtap-2:38888/api
+	Peers []string `yaml:"peers" mapstructure:"peers"`
+	// Secret authenticates requests exchanged between peers
+	Secret string `yaml:"secret" mapstructure:"secret"`
+	// QueueSize bounds the requests waiting to be pushed to each peer; overflow is dropped
+	QueueSize int `yaml:"queue_size" mapstructure:"queue_size"`
+	// Timeout bounds a single push to a peer
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// TunnelConfig lets `reqtap tunnel` clients connect to this instance and answer the requests it
+// captures, so a locally running ReqTap can receive traffic sent to a public one
+type TunnelConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// Path is where tunnel clients connect; requests to it are never captured
+	Path string `yaml:"path" mapstructure:"path"`
+	// Secret authenticates tunnel clients
+	Secret string `yaml:"secret" mapstructure:"secret"`
+	// Timeout bounds how long a relayed request waits for the tunnel client's answer
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// HealthConfig serves liveness and readiness probes on the capture listener; requests to them are
+// never captured
+type HealthConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// LivenessPath answers 200 while the process serves requests
+	LivenessPath string `yaml:"liveness_path" mapstructure:"liveness_path"`
+	// ReadinessPath answers 200 when storage is writable, the listener is bound and the host of every
+	// HTTP forward target resolves, and 503 otherwise
+	ReadinessPath string `yaml:"readiness_path" mapstructure:"readiness_path"`
+	// Timeout bounds the readiness checks
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// PrivacyConfig holds the privacy settings
+type PrivacyConfig struct {
+	Redact RedactConfig `yaml:"redact" mapstructure:"redact"`
+}
+
+// RedactConfig masks sensitive values of every captured request before it is stored, printed,
+// broadcast or exported
+type RedactConfig struct {
+	// Headers are header names whose values are masked
+	Headers []string `yaml:"headers" mapstructure:"headers"`
+	// Fields are JSON paths masked in JSON bodies, e.g. "data.card.number"
+	Fields []string `yaml:"fields" mapstructure:"fields"`
+	// Patterns are regular expressions whose matches are masked in header values, the query string and text bodies
+	Patterns []string `yaml:"patterns" mapstructure:"patterns"`
+	// PreserveForward forwards the request as received; only what ReqTap keeps and shows is masked
+	PreserveForward bool `yaml:"preserve_forward" mapstructure:"preserve_forward"`
+}
+
+// DebugConfig exposes diagnostics of the running process on the admin API, for admins only
+type DebugConfig struct {
+	// Pprof mounts net/http/pprof at debug/pprof/ and runtime statistics at debug/runtime
+	Pprof bool `yaml:"pprof" mapstructure:"pprof"`
+}
+
+// TelemetryConfig exports OpenTelemetry traces of received, stored and forwarded requests over OTLP
+type TelemetryConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string `yaml:"service_name" mapstructure:"service_name"`
+	// Endpoint is the collector address, host:port or a full URL such as https://otel.example.com/v1/traces
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	// Protocol is "http" (OTLP/HTTP protobuf) or "grpc"
+	Protocol string `yaml:"protocol" mapstructure:"protocol"`
+	// Insecure disables TLS towards the collector
+	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+	// Headers are sent with every export, e.g. collector API keys
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"`
+	// SampleRatio is the fraction of new traces recorded; requests arriving with a traceparent follow the caller's decision
+	SampleRatio float64 `yaml:"sample_ratio" mapstructure:"sample_ratio"`
+}
+
+// Hook points and built-in hook actions
+var (
+	HookPoints  = []string{"on_receive", "before_store", "before_forward", "after_forward"}
+	HookActions = []string{"redact", "tag", "drop"}
+)
+
+// HookConfig runs a built-in action at a hook point for the requests matching all of its
+// conditions; the conditions are those of forward filters.
+type HookConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	// Point is on_receive, before_store, before_forward or after_forward
+	Point string `yaml:"point" mapstructure:"point"`
+	// Action is redact, tag or drop
+	Action       string            `yaml:"action" mapstructure:"action"`
+	Methods      []string          `yaml:"methods" mapstructure:"methods"`
+	PathRegex    string            `yaml:"path_regex" mapstructure:"path_regex"`
+	Headers      map[string]string `yaml:"headers" mapstructure:"headers"`
+	BodyContains string            `yaml:"body_contains" mapstructure:"body_contains"`
+	// RedactHeaders and RedactFields (JSON paths) are masked by the redact action
+	RedactHeaders []string `yaml:"redact_headers" mapstructure:"redact_headers"`
+	RedactFields  []string `yaml:"redact_fields" mapstructure:"redact_fields"`
+	// Tags are added to the request by the tag action
+	Tags []string `yaml:"tags" mapstructure:"tags"`
+}
+
+// PluginConfig declares an external plugin process speaking JSON-RPC over stdio
+type PluginConfig struct {
+	Name    string   `yaml:"name" mapstructure:"name"`
+	Command string   `yaml:"command" mapstructure:"command"`
+	Args    []string `yaml:"args" mapstructure:"args"`
+	Env     []string `yaml:"env" mapstructure:"env"`
+	// Hooks restricts the hooks used (transform, export, storage); empty uses all the plugin offers
+	Hooks   []string      `yaml:"hooks" mapstructure:"hooks"`
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// WasmTransformConfig loads a WebAssembly module implementing the request transform ABI
+type WasmTransformConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	Path string `yaml:"path" mapstructure:"path"`
+	// MemoryLimitMB caps the module's linear memory
+	MemoryLimitMB int `yaml:"memory_limit_mb" mapstructure:"memory_limit_mb"`
+	// Timeout bounds the CPU time of a single invocation
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// ScriptConfig loads a JavaScript file defining handle(req), see the script package
+type ScriptConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	Path string `yaml:"path" mapstructure:"path"`
+	// Timeout bounds a single call; the client waits for it, so it should stay short
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
 }
 
 // BodyViewConfig 控制正文格式化与分段
 type BodyViewConfig struct {
-	Enable          bool             `yaml:"enable" mapstructure:"enable"`
-	MaxPreviewBytes int              `yaml:"max_preview_bytes" mapstructure:"max_preview_bytes"`
-	FullBody        bool             `yaml:"full_body" mapstructure:"full_body"`
-	Json            JSONViewConfig   `yaml:"json" mapstructure:"json"`
-	Form            FormViewConfig   `yaml:"form" mapstructure:"form"`
-	XML             XMLViewConfig    `yaml:"xml" mapstructure:"xml"`
-	HTML            HTMLViewConfig   `yaml:"html" mapstructure:"html"`
-	Binary          BinaryViewConfig `yaml:"binary" mapstructure:"binary"`
+	Enable          bool                `yaml:"enable" mapstructure:"enable"`
+	MaxPreviewBytes int                 `yaml:"max_preview_bytes" mapstructure:"max_preview_bytes"`
+	FullBody        bool                `yaml:"full_body" mapstructure:"full_body"`
+	Json            JSONViewConfig      `yaml:"json" mapstructure:"json"`
+	Form            FormViewConfig      `yaml:"form" mapstructure:"form"`
+	XML             XMLViewConfig       `yaml:"xml" mapstructure:"xml"`
+	HTML            HTMLViewConfig      `yaml:"html" mapstructure:"html"`
+	Multipart       MultipartViewConfig `yaml:"multipart" mapstructure:"multipart"`
+	GraphQL         GraphQLViewConfig   `yaml:"graphql" mapstructure:"graphql"`
+	JWT             JWTViewConfig       `yaml:"jwt" mapstructure:"jwt"`
+	Protobuf        ProtobufViewConfig  `yaml:"protobuf" mapstructure:"protobuf"`
+	Binary          BinaryViewConfig    `yaml:"binary" mapstructure:"binary"`
 }
 
 // JSONViewConfig JSON 展示参数
@@ -176,6 +931,43 @@ type HTMLViewConfig struct {
 	StripControl bool `yaml:"strip_control" mapstructure:"strip_control"`
 }
 
+// MultipartViewConfig multipart/form-data 展示参数
+type MultipartViewConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// PreviewBytes 文本分段预览的最大字节数
+	PreviewBytes int `yaml:"preview_bytes" mapstructure:"preview_bytes"`
+	// SaveFiles 将文件分段保存到 binary.save_directory
+	SaveFiles bool `yaml:"save_files" mapstructure:"save_files"`
+}
+
+// GraphQLViewConfig GraphQL 展示参数：识别 application/graphql 与含 query/variables 的 JSON 请求体
+type GraphQLViewConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+}
+
+// JWTViewConfig JWT 展示参数：解码 Authorization: Bearer 头与请求体字段中的 JWT，展示头部与声明
+type JWTViewConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+}
+
+// ProtobufViewConfig Protobuf 展示参数：按描述符集解码 Protobuf 请求体为 JSON，供控制台、Web 与导出使用
+type ProtobufViewConfig struct {
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+	// DescriptorSets 为 FileDescriptorSet 文件（protoc --include_imports --descriptor_set_out）；
+	// .proto 源文件需先用 protoc 或 buf build 编译
+	DescriptorSets []string `yaml:"descriptor_sets" mapstructure:"descriptor_sets"`
+	// Messages 按 Content-Type 与路径前缀选择消息类型，第一个匹配项生效；未匹配的 Protobuf 请求体按字段编号解码
+	Messages []ProtobufMessageConfig `yaml:"messages" mapstructure:"messages"`
+}
+
+// ProtobufMessageConfig 将请求映射到消息类型；content_type 与 path_prefix 至少设置一个，均设置时需同时匹配
+type ProtobufMessageConfig struct {
+	ContentType string `yaml:"content_type" mapstructure:"content_type"`
+	PathPrefix  string `yaml:"path_prefix" mapstructure:"path_prefix"`
+	// Message 为完整消息名，例如 acme.events.v1.OrderCreated
+	Message string `yaml:"message" mapstructure:"message"`
+}
+
 // BinaryViewConfig 二进制展示参数
 type BinaryViewConfig struct {
 	HexPreviewEnable bool   `yaml:"hex_preview_enable" mapstructure:"hex_preview_enable"`
@@ -194,8 +986,9 @@ func LoadConfig(configPath string, v *viper.Viper) (*Config, error) {
 	// Set default values
 	setDefaults(v)
 
-	// Set environment variable prefix
+	// Set environment variable prefix; nested keys read as REQTAP_SERVER_PORT
 	v.SetEnvPrefix("REQTAP")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
 	// Set configuration file
@@ -231,17 +1024,62 @@ func LoadConfig(configPath string, v *viper.Viper) (*Config, error) {
 	}
 
 	// Ensure zero-value fields use default values (Unmarshal doesn't apply defaults to zero-value fields)
-	// Also override with command line flags if they are set (command line flags have highest priority)
+	// Command line flags are applied afterwards by the caller, see FromFlags
 	applyDefaults(&config, v)
+	applyBoolDefaults(&config, v)
+
+	return &config, nil
+}
 
+// Defaults returns the built-in configuration without reading a config file or the environment.
+func Defaults() (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("unable to decode config: %w", err)
+	}
+	applyDefaults(&config, v)
+	applyBoolDefaults(&config, v)
 	return &config, nil
 }
 
+// ApplyDefaults fills the zero-valued fields of c with the built-in defaults, without reading a
+// config file or the environment. Bool fields are left alone since false cannot be told apart
+// from unset; build the configuration with New to start from the default switches.
+func (c *Config) ApplyDefaults() {
+	v := viper.New()
+	setDefaults(v)
+	applyDefaults(c, v)
+	// applyDefaults only covers the fields a config file may leave empty after Unmarshal; the
+	// sections Unmarshal fills on its own are copied over from the defaults
+	if defaults, err := Defaults(); err == nil {
+		fillZero(reflect.ValueOf(c).Elem(), reflect.ValueOf(defaults).Elem())
+	}
+}
+
+// fillZero copies src into the zero-valued fields of dst, walking nested structs and skipping bools
+func fillZero(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch {
+		case field.Kind() == reflect.Struct:
+			fillZero(field, src.Field(i))
+		case field.Kind() == reflect.Bool:
+		case field.IsZero():
+			field.Set(src.Field(i))
+		}
+	}
+}
+
 // applyDefaults apply default values to zero-value fields in the struct
-// This only applies defaults for fields that don't have command line flags.
-// Command line flags are handled separately in main.go to ensure highest priority.
+// Bool fields are handled by applyBoolDefaults, and command line flags are applied
+// afterwards by FromFlags to ensure highest priority.
 func applyDefaults(cfg *Config, v *viper.Viper) {
-	// Server configuration - only apply defaults if zero (command line handled in main.go)
+	// Server configuration - only apply defaults if zero (command line handled by FromFlags)
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = v.GetInt("server.port")
 	}
@@ -251,27 +1089,44 @@ func applyDefaults(cfg *Config, v *viper.Viper) {
 	if cfg.Server.MaxBodyBytes == 0 {
 		cfg.Server.MaxBodyBytes = v.GetInt64("server.max_body_bytes")
 	}
+	if cfg.Server.Spill.ThresholdBytes == 0 {
+		cfg.Server.Spill.ThresholdBytes = v.GetInt64("server.spill.threshold_bytes")
+	}
+	if cfg.Server.Spill.Dir == "" {
+		cfg.Server.Spill.Dir = v.GetString("server.spill.dir")
+	}
 	if len(cfg.Server.Responses) == 0 {
 		var defaults []ImmediateResponseConfig
 		if err := v.UnmarshalKey("server.responses", &defaults); err == nil {
 			cfg.Server.Responses = defaults
 		}
 	}
-	for i := range cfg.Server.Responses {
-		cfg.Server.Responses[i].Headers = canonicalizeHeaders(cfg.Server.Responses[i].Headers)
+	canonicalizeResponseHeaders(cfg.Server.Responses)
+	for i := range cfg.Server.Paths {
+		canonicalizeResponseHeaders(cfg.Server.Paths[i].Responses)
+	}
+	if cfg.Server.TLS.CertFile == "" {
+		cfg.Server.TLS.CertFile = v.GetString("server.tls.cert_file")
+	}
+	if cfg.Server.TLS.KeyFile == "" {
+		cfg.Server.TLS.KeyFile = v.GetString("server.tls.key_file")
+	}
+	if cfg.Server.Auth.Realm == "" {
+		cfg.Server.Auth.Realm = v.GetString("server.auth.realm")
+	}
+	if cfg.Server.Identity.Profile == "" {
+		cfg.Server.Identity.Profile = v.GetString("server.identity.profile")
+	}
+	if cfg.Server.WebSocket.PreviewBytes == 0 {
+		cfg.Server.WebSocket.PreviewBytes = v.GetInt("server.websocket.preview_bytes")
 	}
 
-	// Log configuration - only apply defaults if zero (command line handled in main.go)
+	// Log configuration - only apply defaults if zero (command line handled by FromFlags)
 	if cfg.Log.Level == "" {
 		cfg.Log.Level = v.GetString("log.level")
 	}
 
-	// File logging configuration - only apply defaults if zero (command line handled in main.go)
-	// Note: For bool fields, we always use viper's value since it correctly handles
-	// both config file values and defaults. Viper.GetBool() will return the value from
-	// config file if set, otherwise the default value.
-	cfg.Log.FileLogging.Enable = v.GetBool("log.file_logging.enable")
-	cfg.Log.FileLogging.Compress = v.GetBool("log.file_logging.compress")
+	// File logging configuration - only apply defaults if zero (command line handled by FromFlags)
 	if cfg.Log.FileLogging.Path == "" {
 		cfg.Log.FileLogging.Path = v.GetString("log.file_logging.path")
 	}
@@ -289,34 +1144,41 @@ func applyDefaults(cfg *Config, v *viper.Viper) {
 	if cfg.Output.Mode == "" {
 		cfg.Output.Mode = v.GetString("output.mode")
 	}
-	cfg.Output.Silence = v.GetBool("output.silence")
-	cfg.Output.BodyView.Enable = v.GetBool("output.body_view.enable")
+	if cfg.Output.BodyFilter == "" {
+		cfg.Output.BodyFilter = v.GetString("output.body_filter")
+	}
+	if cfg.Output.File == "" {
+		cfg.Output.File = v.GetString("output.file")
+	}
+	if cfg.Output.Theme == "" {
+		cfg.Output.Theme = v.GetString("output.theme")
+	}
+	if cfg.Output.FileRotation.MaxSizeMB == 0 {
+		cfg.Output.FileRotation.MaxSizeMB = v.GetInt("output.file_rotation.max_size_mb")
+	}
+	if cfg.Output.FileRotation.MaxBackups == 0 {
+		cfg.Output.FileRotation.MaxBackups = v.GetInt("output.file_rotation.max_backups")
+	}
+	if cfg.Output.FileRotation.MaxAgeDays == 0 {
+		cfg.Output.FileRotation.MaxAgeDays = v.GetInt("output.file_rotation.max_age_days")
+	}
 	if cfg.Output.BodyView.MaxPreviewBytes == 0 {
 		cfg.Output.BodyView.MaxPreviewBytes = v.GetInt("output.body_view.max_preview_bytes")
 	}
-	cfg.Output.BodyView.FullBody = v.GetBool("output.body_view.full_body")
-	cfg.Output.BodyView.Json.Enable = v.GetBool("output.body_view.json.enable")
-	cfg.Output.BodyView.Json.Pretty = v.GetBool("output.body_view.json.pretty")
 	if cfg.Output.BodyView.Json.MaxIndentBytes == 0 {
 		cfg.Output.BodyView.Json.MaxIndentBytes = v.GetInt("output.body_view.json.max_indent_bytes")
 	}
-	cfg.Output.BodyView.Form.Enable = v.GetBool("output.body_view.form.enable")
-	cfg.Output.BodyView.XML.Enable = v.GetBool("output.body_view.xml.enable")
-	cfg.Output.BodyView.XML.Pretty = v.GetBool("output.body_view.xml.pretty")
-	cfg.Output.BodyView.XML.StripControl = v.GetBool("output.body_view.xml.strip_control")
-	cfg.Output.BodyView.HTML.Enable = v.GetBool("output.body_view.html.enable")
-	cfg.Output.BodyView.HTML.Pretty = v.GetBool("output.body_view.html.pretty")
-	cfg.Output.BodyView.HTML.StripControl = v.GetBool("output.body_view.html.strip_control")
-	cfg.Output.BodyView.Binary.HexPreviewEnable = v.GetBool("output.body_view.binary.hex_preview_enable")
+	if cfg.Output.BodyView.Multipart.PreviewBytes == 0 {
+		cfg.Output.BodyView.Multipart.PreviewBytes = v.GetInt("output.body_view.multipart.preview_bytes")
+	}
 	if cfg.Output.BodyView.Binary.HexPreviewBytes == 0 {
 		cfg.Output.BodyView.Binary.HexPreviewBytes = v.GetInt("output.body_view.binary.hex_preview_bytes")
 	}
-	cfg.Output.BodyView.Binary.SaveToFile = v.GetBool("output.body_view.binary.save_to_file")
 	if cfg.Output.BodyView.Binary.SaveDirectory == "" {
 		cfg.Output.BodyView.Binary.SaveDirectory = v.GetString("output.body_view.binary.save_directory")
 	}
 
-	// Forward configuration - command line handled in main.go for URLs
+	// Forward configuration - command line handled by FromFlags for URLs
 	// These don't have command line flags, so only apply defaults if zero
 	if cfg.Forward.Timeout == 0 {
 		cfg.Forward.Timeout = v.GetInt("forward.timeout")
@@ -367,12 +1229,22 @@ func applyDefaults(cfg *Config, v *viper.Viper) {
 	if len(cfg.Forward.HeaderWhitelist) == 0 {
 		cfg.Forward.HeaderWhitelist = v.GetStringSlice("forward.header_whitelist")
 	}
+	if len(cfg.Forward.Targets) == 0 {
+		var targets []ForwardTargetConfig
+		if err := v.UnmarshalKey("forward.targets", &targets); err == nil {
+			cfg.Forward.Targets = targets
+		}
+	}
 	cfg.Forward.HeaderBlacklist = normalizeHeaderList(cfg.Forward.HeaderBlacklist)
 	cfg.Forward.HeaderWhitelist = normalizeHeaderList(cfg.Forward.HeaderWhitelist)
-	cfg.Forward.TLSInsecureSkipVerify = v.GetBool("forward.tls_insecure_skip_verify")
+	if cfg.Forward.HTTP2 == "" {
+		cfg.Forward.HTTP2 = v.GetString("forward.http2")
+	}
+	if cfg.Forward.LatencyBudget == 0 {
+		cfg.Forward.LatencyBudget = v.GetDuration("forward.latency_budget")
+	}
 
 	// Web configuration defaults
-	cfg.Web.Enable = v.GetBool("web.enable")
 	if cfg.Web.Path == "" {
 		cfg.Web.Path = v.GetString("web.path")
 	}
@@ -384,7 +1256,6 @@ func applyDefaults(cfg *Config, v *viper.Viper) {
 	}
 
 	// Auth defaults
-	cfg.Web.Auth.Enable = v.GetBool("web.auth.enable")
 	if cfg.Web.Auth.SessionTimeout == 0 {
 		timeoutStr := v.GetString("web.auth.session_timeout")
 		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
@@ -399,20 +1270,90 @@ func applyDefaults(cfg *Config, v *viper.Viper) {
 			cfg.Web.Auth.Users = users
 		}
 	}
+	if cfg.Web.Auth.LoginLink.TTL == 0 {
+		cfg.Web.Auth.LoginLink.TTL = v.GetDuration("web.auth.login_link.ttl")
+	}
+
+	if cfg.Web.WebSocket.History == 0 {
+		cfg.Web.WebSocket.History = v.GetInt("web.websocket.history")
+	}
 
 	// Export defaults
-	cfg.Web.Export.Enable = v.GetBool("web.export.enable")
 	if len(cfg.Web.Export.Formats) == 0 {
 		cfg.Web.Export.Formats = v.GetStringSlice("web.export.formats")
 	}
 }
 
+// applyBoolDefaults sets every bool field from viper: a false bool cannot tell "unset" from
+// "disabled", while viper returns the config file value if set and the default otherwise.
+func applyBoolDefaults(cfg *Config, v *viper.Viper) {
+	cfg.Server.WebSocket.Enable = v.GetBool("server.websocket.enable")
+	cfg.Server.GRPC.Enable = v.GetBool("server.grpc.enable")
+	cfg.Server.GRPC.Reflection = v.GetBool("server.grpc.reflection")
+	cfg.Server.Identity.HideServerHeader = v.GetBool("server.identity.hide_server_header")
+	cfg.Server.Identity.Stealth = v.GetBool("server.identity.stealth")
+	cfg.Server.Auth.Enable = v.GetBool("server.auth.enable")
+	cfg.Server.HTTP2.Enable = v.GetBool("server.http2.enable")
+	cfg.Server.Listener.ReusePort = v.GetBool("server.listener.reuse_port")
+	cfg.Server.Listener.SocketActivation = v.GetBool("server.listener.socket_activation")
+	cfg.Server.CORS.Enable = v.GetBool("server.cors.enable")
+	cfg.Server.CORS.AllowCredentials = v.GetBool("server.cors.allow_credentials")
+	cfg.Log.FileLogging.Enable = v.GetBool("log.file_logging.enable")
+	cfg.Log.FileLogging.Compress = v.GetBool("log.file_logging.compress")
+	cfg.Output.Silence = v.GetBool("output.silence")
+	cfg.Output.NoColor = v.GetBool("output.no_color")
+	cfg.Output.StatusLine = v.GetBool("output.status_line")
+	cfg.Output.FileRotation.Compress = v.GetBool("output.file_rotation.compress")
+	cfg.Output.BodyView.Enable = v.GetBool("output.body_view.enable")
+	cfg.Output.BodyView.FullBody = v.GetBool("output.body_view.full_body")
+	cfg.Output.BodyView.Json.Enable = v.GetBool("output.body_view.json.enable")
+	cfg.Output.BodyView.Json.Pretty = v.GetBool("output.body_view.json.pretty")
+	cfg.Output.BodyView.Form.Enable = v.GetBool("output.body_view.form.enable")
+	cfg.Output.BodyView.XML.Enable = v.GetBool("output.body_view.xml.enable")
+	cfg.Output.BodyView.XML.Pretty = v.GetBool("output.body_view.xml.pretty")
+	cfg.Output.BodyView.XML.StripControl = v.GetBool("output.body_view.xml.strip_control")
+	cfg.Output.BodyView.HTML.Enable = v.GetBool("output.body_view.html.enable")
+	cfg.Output.BodyView.HTML.Pretty = v.GetBool("output.body_view.html.pretty")
+	cfg.Output.BodyView.HTML.StripControl = v.GetBool("output.body_view.html.strip_control")
+	cfg.Output.BodyView.Multipart.Enable = v.GetBool("output.body_view.multipart.enable")
+	cfg.Output.BodyView.Multipart.SaveFiles = v.GetBool("output.body_view.multipart.save_files")
+	cfg.Output.BodyView.GraphQL.Enable = v.GetBool("output.body_view.graphql.enable")
+	cfg.Output.BodyView.JWT.Enable = v.GetBool("output.body_view.jwt.enable")
+	cfg.Output.BodyView.Protobuf.Enable = v.GetBool("output.body_view.protobuf.enable")
+	cfg.Output.BodyView.Binary.HexPreviewEnable = v.GetBool("output.body_view.binary.hex_preview_enable")
+	cfg.Output.BodyView.Binary.SaveToFile = v.GetBool("output.body_view.binary.save_to_file")
+	cfg.Forward.TLSInsecureSkipVerify = v.GetBool("forward.tls_insecure_skip_verify")
+	cfg.Forward.CircuitBreaker.Enable = v.GetBool("forward.circuit_breaker.enable")
+	cfg.Forward.HealthCheck.Enable = v.GetBool("forward.health_check.enable")
+	cfg.Forward.Queue.Enable = v.GetBool("forward.queue.enable")
+	cfg.Forward.Ordering.Enable = v.GetBool("forward.ordering.enable")
+	cfg.Web.Enable = v.GetBool("web.enable")
+	cfg.Web.Auth.Enable = v.GetBool("web.auth.enable")
+	cfg.Web.Auth.LoginLink.Enable = v.GetBool("web.auth.login_link.enable")
+	cfg.Web.Auth.LoginLink.OpenBrowser = v.GetBool("web.auth.login_link.open_browser")
+	cfg.Web.Export.Enable = v.GetBool("web.export.enable")
+	cfg.Web.Audit.Enable = v.GetBool("web.audit.enable")
+	cfg.Anomaly.Enable = v.GetBool("anomaly.enable")
+	cfg.Dedup.Enable = v.GetBool("dedup.enable")
+	cfg.Dedup.SuppressForward = v.GetBool("dedup.suppress_forward")
+	cfg.Cluster.Enable = v.GetBool("cluster.enable")
+	cfg.Tunnel.Enable = v.GetBool("tunnel.enable")
+	cfg.Storage.Archive.Enable = v.GetBool("storage.archive.enable")
+	cfg.Health.Enable = v.GetBool("health.enable")
+	cfg.Telemetry.Enable = v.GetBool("telemetry.enable")
+	cfg.Telemetry.Insecure = v.GetBool("telemetry.insecure")
+	cfg.Debug.Pprof = v.GetBool("debug.pprof")
+	cfg.Privacy.Redact.PreserveForward = v.GetBool("privacy.redact.preserve_forward")
+}
+
 // setDefaults set default configuration values
 func setDefaults(v *viper.Viper) {
 	// Server default configuration
 	v.SetDefault("server.port", 38888)
 	v.SetDefault("server.path", "/reqtap")
 	v.SetDefault("server.max_body_bytes", int64(10*1024*1024))
+	v.SetDefault("server.spill.threshold_bytes", int64(0))
+	v.SetDefault("server.spill.dir", "")
 	v.SetDefault("server.responses", []map[string]interface{}{
 		{
 			"name":   "default-ok",
@@ -423,6 +1364,38 @@ func setDefaults(v *viper.Viper) {
 			},
 		},
 	})
+	v.SetDefault("server.mock_presets", []string{})
+
+	v.SetDefault("server.websocket.enable", false)
+	v.SetDefault("server.websocket.proxy_url", "")
+	v.SetDefault("server.websocket.preview_bytes", 256)
+	v.SetDefault("server.grpc.enable", false)
+	v.SetDefault("server.grpc.descriptor_sets", []string{})
+	v.SetDefault("server.grpc.reflection", false)
+	v.SetDefault("server.grpc.backend_url", "")
+	v.SetDefault("server.grpc.timeout", "30s")
+	v.SetDefault("server.identity.server_header", "")
+	v.SetDefault("server.identity.hide_server_header", false)
+	v.SetDefault("server.identity.stealth", false)
+	v.SetDefault("server.identity.profile", "nginx")
+	v.SetDefault("server.http2.enable", false)
+	v.SetDefault("server.tls.cert_file", "")
+	v.SetDefault("server.tls.key_file", "")
+	v.SetDefault("server.listener.reuse_port", false)
+	v.SetDefault("server.listener.socket_activation", true)
+	v.SetDefault("server.auth.enable", false)
+	v.SetDefault("server.auth.realm", "reqtap")
+	v.SetDefault("server.auth.credentials", []map[string]interface{}{})
+	v.SetDefault("server.access_control.allow", []string{})
+	v.SetDefault("server.access_control.deny", []string{})
+	v.SetDefault("server.trusted_proxies", []string{})
+	v.SetDefault("server.access_control.trusted_proxies", []string{})
+	v.SetDefault("server.cors.enable", false)
+	v.SetDefault("server.cors.allowed_origins", []string{"*"})
+	v.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"})
+	v.SetDefault("server.cors.allowed_headers", []string{})
+	v.SetDefault("server.cors.allow_credentials", false)
+	v.SetDefault("server.cors.max_age", "10m")
 
 	// Log default configuration
 	v.SetDefault("log.level", "info")
@@ -446,6 +1419,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("forward.tls_handshake_timeout", 10)
 	v.SetDefault("forward.expect_continue_timeout", 1)
 	v.SetDefault("forward.tls_insecure_skip_verify", false)
+	v.SetDefault("forward.http2", "auto")
+	v.SetDefault("forward.latency_budget", "0s")
 	v.SetDefault("forward.path_strategy.mode", "append")
 	v.SetDefault("forward.path_strategy.strip_prefix", "")
 	v.SetDefault("forward.path_strategy.rules", []map[string]string{})
@@ -462,6 +1437,22 @@ func setDefaults(v *viper.Viper) {
 		"content-length",
 	})
 	v.SetDefault("forward.header_whitelist", []string{})
+	v.SetDefault("forward.targets", []map[string]interface{}{})
+	v.SetDefault("forward.circuit_breaker.enable", false)
+	v.SetDefault("forward.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("forward.circuit_breaker.cooldown", "30s")
+	v.SetDefault("forward.health_check.enable", false)
+	v.SetDefault("forward.health_check.path", "/")
+	v.SetDefault("forward.health_check.interval", "10s")
+	v.SetDefault("forward.health_check.timeout", "5s")
+	v.SetDefault("forward.queue.enable", false)
+	v.SetDefault("forward.queue.poll_interval", "5s")
+	v.SetDefault("forward.queue.backoff", "30s")
+	v.SetDefault("forward.queue.max_backoff", "30m")
+	v.SetDefault("forward.queue.max_attempts", 20)
+	v.SetDefault("forward.ordering.enable", false)
+	v.SetDefault("forward.ordering.header", "")
+	v.SetDefault("forward.ordering.json_path", "")
 
 	// Web console defaults
 	v.SetDefault("web.enable", true)
@@ -476,13 +1467,28 @@ func setDefaults(v *viper.Viper) {
 		{"username": "admin", "password": "admin123", "role": "admin"},
 		{"username": "user", "password": "user123", "role": "viewer"},
 	})
+	v.SetDefault("web.auth.login_link.enable", true)
+	v.SetDefault("web.auth.login_link.ttl", "5m")
+	v.SetDefault("web.auth.login_link.open_browser", false)
+	v.SetDefault("web.websocket.history", 0)
 	v.SetDefault("web.export.enable", true)
-	v.SetDefault("web.export.formats", []string{"json", "csv", "txt"})
+	v.SetDefault("web.export.formats", []string{"json", "ndjson", "csv", "txt", "har"})
+	v.SetDefault("web.audit.enable", true)
+	v.SetDefault("web.audit.file", "")
 
 	// Output defaults
 	v.SetDefault("output.mode", "console")
 	v.SetDefault("output.silence", false)
 	v.SetDefault("output.locale", "en")
+	v.SetDefault("output.body_filter", "")
+	v.SetDefault("output.file", "")
+	v.SetDefault("output.theme", "default")
+	v.SetDefault("output.no_color", false)
+	v.SetDefault("output.status_line", false)
+	v.SetDefault("output.file_rotation.max_size_mb", 10)
+	v.SetDefault("output.file_rotation.max_backups", 5)
+	v.SetDefault("output.file_rotation.max_age_days", 30)
+	v.SetDefault("output.file_rotation.compress", false)
 	v.SetDefault("output.body_view.enable", false)
 	v.SetDefault("output.body_view.max_preview_bytes", int(32*1024))
 	v.SetDefault("output.body_view.full_body", false)
@@ -496,6 +1502,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("output.body_view.html.enable", true)
 	v.SetDefault("output.body_view.html.pretty", false)
 	v.SetDefault("output.body_view.html.strip_control", true)
+	v.SetDefault("output.body_view.multipart.enable", true)
+	v.SetDefault("output.body_view.multipart.preview_bytes", 512)
+	v.SetDefault("output.body_view.multipart.save_files", false)
+	v.SetDefault("output.body_view.graphql.enable", true)
+	v.SetDefault("output.body_view.jwt.enable", true)
+	v.SetDefault("output.body_view.protobuf.enable", false)
 	v.SetDefault("output.body_view.binary.hex_preview_enable", false)
 	v.SetDefault("output.body_view.binary.hex_preview_bytes", 256)
 	v.SetDefault("output.body_view.binary.save_to_file", false)
@@ -506,12 +1518,85 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("storage.path", "./data/reqtap.db")
 	v.SetDefault("storage.max_records", 100000)
 	v.SetDefault("storage.retention", "0s")
+	v.SetDefault("storage.maintenance_interval", "1h")
+	v.SetDefault("storage.write_batch", 256)
+	v.SetDefault("storage.write_queue", 4096)
+	v.SetDefault("storage.plugin", "")
+	v.SetDefault("storage.archive.enable", false)
+	v.SetDefault("storage.archive.url", "")
+	v.SetDefault("storage.archive.after", "720h")
+	v.SetDefault("storage.archive.interval", "1h")
+	v.SetDefault("storage.archive.endpoint", "")
+	v.SetDefault("storage.archive.region", "")
+	v.SetDefault("storage.archive.access_key_id", "")
+	v.SetDefault("storage.archive.secret_access_key", "")
+
+	// Plugin defaults
+	v.SetDefault("plugins", []map[string]interface{}{})
+	v.SetDefault("wasm_transforms", []map[string]interface{}{})
+	v.SetDefault("scripts", []map[string]interface{}{})
+
+	// Anomaly detection defaults
+	v.SetDefault("anomaly.enable", false)
+	v.SetDefault("anomaly.window", "1m")
+	v.SetDefault("anomaly.baseline_windows", 30)
+	v.SetDefault("anomaly.threshold", 3.0)
+	v.SetDefault("anomaly.min_requests", 5)
+	v.SetDefault("anomaly.cooldown", "10m")
+	v.SetDefault("anomaly.webhooks", []string{})
+
+	// De-duplication defaults
+	v.SetDefault("dedup.enable", false)
+	v.SetDefault("dedup.header", "")
+	v.SetDefault("dedup.window", "10m")
+	v.SetDefault("dedup.suppress_forward", false)
+
+	v.SetDefault("hooks", []map[string]interface{}{})
+
+	// Cluster defaults
+	v.SetDefault("cluster.enable", false)
+	v.SetDefault("cluster.instance_id", "")
+	v.SetDefault("cluster.peers", []string{})
+	v.SetDefault("cluster.secret", "")
+	v.SetDefault("cluster.queue_size", 1000)
+	v.SetDefault("cluster.timeout", "5s")
+
+	// Tunnel defaults
+	v.SetDefault("tunnel.enable", false)
+	v.SetDefault("tunnel.path", "/reqtap-tunnel")
+	v.SetDefault("tunnel.secret", "")
+	v.SetDefault("tunnel.timeout", "30s")
+
+	// Health probe defaults
+	v.SetDefault("health.enable", false)
+	v.SetDefault("health.liveness_path", "/healthz")
+	v.SetDefault("health.readiness_path", "/readyz")
+	v.SetDefault("health.timeout", "2s")
+
+	// Telemetry defaults
+	v.SetDefault("telemetry.enable", false)
+	v.SetDefault("telemetry.service_name", "reqtap")
+	v.SetDefault("telemetry.endpoint", "localhost:4318")
+	v.SetDefault("telemetry.protocol", "http")
+	v.SetDefault("telemetry.insecure", true)
+	v.SetDefault("telemetry.headers", map[string]string{})
+	v.SetDefault("telemetry.sample_ratio", 1.0)
+
+	// Debug defaults
+	v.SetDefault("debug.pprof", false)
+
+	// Privacy defaults
+	v.SetDefault("privacy.redact.headers", []string{})
+	v.SetDefault("privacy.redact.fields", []string{})
+	v.SetDefault("privacy.redact.patterns", []string{})
+	v.SetDefault("privacy.redact.preserve_forward", false)
 }
 
 // validate configuration
 func (c *Config) Validate() error {
 	// Validate port
-	if c.Server.Port < 1 || c.Server.Port > 65535 {
+	// Port 0 only survives applyDefaults when set in code, e.g. by pkg/reqtap; it picks a free port
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
 		return fmt.Errorf("invalid port: %d (must be 1-65535)", c.Server.Port)
 	}
 
@@ -522,48 +1607,134 @@ func (c *Config) Validate() error {
 	if c.Server.MaxBodyBytes < 0 {
 		return fmt.Errorf("server max body bytes cannot be negative")
 	}
+	if c.Server.Spill.ThresholdBytes < 0 {
+		return fmt.Errorf("server spill threshold_bytes cannot be negative")
+	}
 	if len(c.Server.Responses) == 0 {
 		return fmt.Errorf("server responses configuration cannot be empty")
 	}
-	for i, resp := range c.Server.Responses {
-		if resp.Status < 100 || resp.Status > 599 {
-			return fmt.Errorf("server response %d status must be between 100 and 599", i+1)
-		}
-		if resp.Path != "" && !strings.HasPrefix(resp.Path, "/") {
-			return fmt.Errorf("server response %d path must start with '/'", i+1)
-		}
-		if resp.PathPrefix != "" && !strings.HasPrefix(resp.PathPrefix, "/") {
-			return fmt.Errorf("server response %d path_prefix must start with '/'", i+1)
-		}
-		for _, method := range resp.Methods {
-			if method == "" {
-				return fmt.Errorf("server response %d contains empty method", i+1)
-			}
-		}
+	if err := validateImmediateResponses("server response", c.Server.Responses); err != nil {
+		return err
 	}
-
-	switch strings.ToLower(c.Output.Mode) {
-	case "", "console", "json":
-		if c.Output.Mode == "" {
-			c.Output.Mode = "console"
-		}
-	default:
-		return fmt.Errorf("output mode must be 'console' or 'json'")
+	if err := validateMockPresets(c.Server.MockPresets); err != nil {
+		return err
+	}
+	if err := c.validateCapturePaths(); err != nil {
+		return err
+	}
+	if err := validateWebSocketCaptureConfig(&c.Server.WebSocket); err != nil {
+		return err
+	}
+	if err := validateGRPCCaptureConfig(&c.Server.GRPC); err != nil {
+		return err
+	}
+	if strings.ContainsAny(c.Server.Identity.ServerHeader, "\r\n") {
+		return fmt.Errorf("server identity server_header cannot contain line breaks")
+	}
+	switch strings.ToLower(c.Server.Identity.Profile) {
+	case "", "nginx", "apache", "caddy":
+		c.Server.Identity.Profile = strings.ToLower(c.Server.Identity.Profile)
+	default:
+		return fmt.Errorf("server identity profile must be nginx, apache or caddy")
+	}
+	if err := validateCaptureAuthConfig(&c.Server.Auth); err != nil {
+		return err
+	}
+	for i, entry := range c.Server.TrustedProxies {
+		prefix, err := ParseAddressRange(entry)
+		if err != nil {
+			return fmt.Errorf("server trusted_proxies entry %q is not an IP address or CIDR", entry)
+		}
+		c.Server.TrustedProxies[i] = prefix.String()
+	}
+	if err := validateAccessControlConfig(&c.Server.AccessControl); err != nil {
+		return err
+	}
+	if err := validateCORSConfig(&c.Server.CORS); err != nil {
+		return err
+	}
+	c.Server.TLS.CertFile = strings.TrimSpace(c.Server.TLS.CertFile)
+	c.Server.TLS.KeyFile = strings.TrimSpace(c.Server.TLS.KeyFile)
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server tls requires both cert_file and key_file")
+	}
+
+	switch strings.ToLower(c.Output.Mode) {
+	case "", "console", "json", "tui":
+		if c.Output.Mode == "" {
+			c.Output.Mode = "console"
+		}
+	default:
+		return fmt.Errorf("output mode must be 'console', 'json' or 'tui'")
+	}
+	switch c.Output.Theme = strings.ToLower(strings.TrimSpace(c.Output.Theme)); c.Output.Theme {
+	case "", "default", "solarized", "mono":
+		if c.Output.Theme == "" {
+			c.Output.Theme = "default"
+		}
+	default:
+		return fmt.Errorf("output theme must be 'default', 'solarized' or 'mono'")
+	}
+	c.Output.File = strings.TrimSpace(c.Output.File)
+	if rotation := c.Output.FileRotation; rotation.MaxSizeMB < 0 || rotation.MaxBackups < 0 || rotation.MaxAgeDays < 0 {
+		return fmt.Errorf("output file rotation values cannot be negative")
 	}
 	if err := validateBodyViewConfig(&c.Output.BodyView); err != nil {
 		return err
 	}
 
+	if err := c.validatePlugins(); err != nil {
+		return err
+	}
+	if err := c.validateWasmTransforms(); err != nil {
+		return err
+	}
+	if err := c.validateScripts(); err != nil {
+		return err
+	}
+	if err := validateAnomalyConfig(&c.Anomaly); err != nil {
+		return err
+	}
+	if err := validateDedupConfig(&c.Dedup); err != nil {
+		return err
+	}
+	if err := validateRedactConfig(&c.Privacy.Redact); err != nil {
+		return err
+	}
+	if err := validateTelemetryConfig(&c.Telemetry); err != nil {
+		return err
+	}
+	if err := c.validateCluster(); err != nil {
+		return err
+	}
+	if err := validateTunnelConfig(&c.Tunnel); err != nil {
+		return err
+	}
+	if err := validateHealthConfig(&c.Health); err != nil {
+		return err
+	}
+
 	switch strings.ToLower(strings.TrimSpace(c.Storage.Driver)) {
 	case "", "sqlite", "sqlite3":
 		if strings.TrimSpace(c.Storage.Driver) == "" {
 			c.Storage.Driver = "sqlite"
 		}
+		if strings.TrimSpace(c.Storage.Path) == "" {
+			return fmt.Errorf("storage path cannot be empty")
+		}
+	case "bolt", "bbolt":
+		if strings.TrimSpace(c.Storage.Path) == "" {
+			return fmt.Errorf("storage path cannot be empty")
+		}
+	case "plugin":
+		if !c.hasPluginHook(c.Storage.Plugin, "storage") {
+			return fmt.Errorf("storage plugin %q must name a configured plugin with the storage hook", c.Storage.Plugin)
+		}
+		if c.Forward.Queue.Enable {
+			return fmt.Errorf("forward queue requires the sqlite or bolt storage driver")
+		}
 	default:
-		return fmt.Errorf("storage driver must be sqlite")
-	}
-	if strings.TrimSpace(c.Storage.Path) == "" {
-		return fmt.Errorf("storage path cannot be empty")
+		return fmt.Errorf("storage driver must be sqlite, bolt or plugin")
 	}
 	if c.Storage.MaxRecords < 0 {
 		return fmt.Errorf("storage max_records cannot be negative")
@@ -571,6 +1742,15 @@ func (c *Config) Validate() error {
 	if c.Storage.Retention < 0 {
 		return fmt.Errorf("storage retention cannot be negative")
 	}
+	if c.Storage.MaintenanceInterval < 0 {
+		return fmt.Errorf("storage maintenance_interval cannot be negative")
+	}
+	if c.Storage.WriteBatch < 0 || c.Storage.WriteQueue < 0 {
+		return fmt.Errorf("storage write_batch and write_queue cannot be negative")
+	}
+	if err := validateStorageArchiveConfig(&c.Storage); err != nil {
+		return err
+	}
 
 	if strings.TrimSpace(c.Output.Locale) == "" {
 		c.Output.Locale = "en"
@@ -600,12 +1780,46 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("log file max age cannot be negative")
 		}
 	}
+	if err := validateLogOutputs(c.Log.Outputs); err != nil {
+		return err
+	}
 
 	// Validate forward URLs
 	for i, url := range c.Forward.URLs {
 		if url == "" {
 			return fmt.Errorf("forward URL %d cannot be empty", i+1)
 		}
+		if err := validateForwardURL(url); err != nil {
+			return fmt.Errorf("forward URL %d: %w", i+1, err)
+		}
+	}
+
+	if err := validateForwardTargets("forward target", c.Forward.Targets); err != nil {
+		return err
+	}
+	if c.Forward.LatencyBudget < 0 {
+		return fmt.Errorf("forward latency budget cannot be negative")
+	}
+	if err := validateForwardExpect(&c.Forward.Expectations); err != nil {
+		return fmt.Errorf("forward expectations %w", err)
+	}
+	if err := c.validateHooks(); err != nil {
+		return err
+	}
+	if err := c.validateForwardFilters(); err != nil {
+		return err
+	}
+	if err := c.validateForwardTransforms(); err != nil {
+		return err
+	}
+	if err := validateForwardHealthConfig(&c.Forward); err != nil {
+		return err
+	}
+	if err := validateForwardOrdering(&c.Forward.Ordering); err != nil {
+		return err
+	}
+	if err := validateForwardHostOverrides(c.Forward.HostOverrides); err != nil {
+		return err
 	}
 
 	// Validate forward configuration
@@ -615,6 +1829,14 @@ func (c *Config) Validate() error {
 	if c.Forward.MaxRetries < 0 {
 		return fmt.Errorf("forward max retries cannot be negative")
 	}
+	switch strings.ToLower(strings.TrimSpace(c.Forward.HTTP2)) {
+	case "", "auto":
+		c.Forward.HTTP2 = "auto"
+	case "always", "off":
+		c.Forward.HTTP2 = strings.ToLower(strings.TrimSpace(c.Forward.HTTP2))
+	default:
+		return fmt.Errorf("forward http2 must be auto, always or off")
+	}
 	if c.Forward.MaxConcurrent < 1 {
 		return fmt.Errorf("forward max concurrent must be at least 1")
 	}
@@ -634,6 +1856,11 @@ func (c *Config) Validate() error {
 			if rule.Match == "" {
 				return fmt.Errorf("forward path rule %d match cannot be empty", i+1)
 			}
+			if rule.Regex {
+				if _, err := regexp.Compile(strings.TrimSpace(rule.Match)); err != nil {
+					return fmt.Errorf("forward path rule %d regex is invalid: %w", i+1, err)
+				}
+			}
 		}
 	}
 
@@ -665,6 +1892,9 @@ func (c *Config) Validate() error {
 		if c.Web.MaxRequests < 1 {
 			return fmt.Errorf("web max requests must be at least 1")
 		}
+		if c.Web.WebSocket.History < 0 {
+			return fmt.Errorf("web websocket history cannot be negative")
+		}
 
 		if c.Web.Auth.Enable {
 			if c.Web.Auth.SessionTimeout <= 0 {
@@ -678,8 +1908,12 @@ func (c *Config) Validate() error {
 				if user.Username == "" {
 					return fmt.Errorf("web auth user %d username cannot be empty", i+1)
 				}
-				if user.Password == "" {
-					return fmt.Errorf("web auth user %d password cannot be empty", i+1)
+				if user.PasswordHash != "" {
+					if err := password.Check(user.PasswordHash); err != nil {
+						return fmt.Errorf("web auth user %d password_hash: %w", i+1, err)
+					}
+				} else if user.Password == "" {
+					return fmt.Errorf("web auth user %d password or password_hash cannot be empty", i+1)
 				}
 				if user.Role == "" {
 					return fmt.Errorf("web auth user %d role cannot be empty", i+1)
@@ -688,6 +1922,12 @@ func (c *Config) Validate() error {
 					return fmt.Errorf("web auth user %d role must be admin or viewer", i+1)
 				}
 			}
+			if err := validateLoginLinkConfig(&c.Web.Auth); err != nil {
+				return err
+			}
+			if err := validateWebTokens(c.Web.Auth.Tokens); err != nil {
+				return err
+			}
 		}
 
 		if c.Web.Export.Enable {
@@ -711,80 +1951,1367 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-func validateBodyViewConfig(cfg *BodyViewConfig) error {
-	if cfg.MaxPreviewBytes < 0 {
-		return fmt.Errorf("output.body_view.max_preview_bytes cannot be negative")
+// validateLogOutputs checks log.outputs and fills in the syslog defaults
+func validateLogOutputs(outputs []LogOutputConfig) error {
+	for i := range outputs {
+		output := &outputs[i]
+		output.Type = strings.ToLower(strings.TrimSpace(output.Type))
+		output.Network = strings.ToLower(strings.TrimSpace(output.Network))
+		output.Facility = strings.ToLower(strings.TrimSpace(output.Facility))
+		if output.Tag == "" {
+			output.Tag = "reqtap"
+		}
+		switch output.Type {
+		case "syslog":
+			if output.Network == "" {
+				output.Network = "udp"
+			}
+			if output.Network != "udp" && output.Network != "tcp" {
+				return fmt.Errorf("log output %d network must be 'udp' or 'tcp'", i+1)
+			}
+			if _, _, err := net.SplitHostPort(output.Address); err != nil {
+				return fmt.Errorf("log output %d address must be host:port: %w", i+1, err)
+			}
+			if output.Facility == "" {
+				output.Facility = "user"
+			}
+			if _, ok := SyslogFacilities[output.Facility]; !ok {
+				return fmt.Errorf("log output %d facility %q is not a syslog facility", i+1, output.Facility)
+			}
+		case "journald":
+		default:
+			return fmt.Errorf("log output %d type must be 'syslog' or 'journald'", i+1)
+		}
+		if strings.ContainsAny(output.Tag, " \t\r\n") {
+			return fmt.Errorf("log output %d tag cannot contain whitespace", i+1)
+		}
 	}
-	if cfg.Json.MaxIndentBytes < 0 {
-		return fmt.Errorf("output.body_view.json.max_indent_bytes cannot be negative")
+	return nil
+}
+
+// SyslogFacilities maps the syslog facility names to their RFC 5424 codes
+var SyslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// validateImmediateResponses checks mock rules; label prefixes errors, e.g. "server response"
+func validateImmediateResponses(label string, responses []ImmediateResponseConfig) error {
+	for i := range responses {
+		if err := validateImmediateResponse(fmt.Sprintf("%s %d", label, i+1), &responses[i]); err != nil {
+			return err
+		}
 	}
-	if cfg.Binary.HexPreviewBytes < 0 {
-		return fmt.Errorf("output.body_view.binary.hex_preview_bytes cannot be negative")
+	return nil
+}
+
+// ValidateMockRule checks a server.responses rule managed through the admin API and canonicalizes
+// its headers; the rule must be named, as the API addresses rules by name. body_file and body_url
+// are refused, as they would let API clients read local files and make the server fetch URLs; they
+// can only be set in the config file.
+func ValidateMockRule(rule *ImmediateResponseConfig) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("mock rule name is required")
+	}
+	if rule.BodyFile != "" || rule.BodyURL != "" {
+		return fmt.Errorf("mock rule %s cannot set body_file or body_url; define the rule in the config file", rule.Name)
+	}
+	rules := []ImmediateResponseConfig{*rule}
+	canonicalizeResponseHeaders(rules)
+	*rule = rules[0]
+	return validateImmediateResponse("mock rule "+rule.Name, rule)
+}
+
+// validateImmediateResponse checks one mock rule; label names the rule in errors
+func validateImmediateResponse(label string, resp *ImmediateResponseConfig) error {
+	if resp.Status < 100 || resp.Status > 599 {
+		return fmt.Errorf("%s status must be between 100 and 599", label)
 	}
-	if cfg.Binary.SaveToFile && strings.TrimSpace(cfg.Binary.SaveDirectory) == "" {
-		return fmt.Errorf("output.body_view.binary.save_directory cannot be empty when save_to_file is enabled")
+	if resp.Path != "" && !strings.HasPrefix(resp.Path, "/") {
+		return fmt.Errorf("%s path must start with '/'", label)
+	}
+	if resp.PathPrefix != "" && !strings.HasPrefix(resp.PathPrefix, "/") {
+		return fmt.Errorf("%s path_prefix must start with '/'", label)
+	}
+	if strings.ContainsAny(resp.StatusText, "\r\n") {
+		return fmt.Errorf("%s status_text cannot contain line breaks", label)
+	}
+	switch strings.ToLower(resp.Compression) {
+	case "", "gzip":
+		resp.Compression = strings.ToLower(resp.Compression)
+	default:
+		return fmt.Errorf("%s compression must be 'gzip' or empty", label)
+	}
+	if resp.CompressionMinBytes < 0 {
+		return fmt.Errorf("%s compression_min_bytes cannot be negative", label)
+	}
+	if resp.Delay < 0 || resp.DelayJitter < 0 {
+		return fmt.Errorf("%s delay and delay_jitter cannot be negative", label)
+	}
+	if resp.TimeoutChance < 0 || resp.TimeoutChance > 1 {
+		return fmt.Errorf("%s timeout_chance must be between 0 and 1", label)
+	}
+	if resp.BodyFile != "" {
+		if resp.Body != "" {
+			return fmt.Errorf("%s cannot set both body and body_file", label)
+		}
+		if resp.StatusText != "" || resp.HTTP10 || resp.Compression != "" {
+			return fmt.Errorf("%s body_file cannot be combined with status_text, http10 or compression", label)
+		}
+		info, err := os.Stat(resp.BodyFile)
+		if err != nil {
+			return fmt.Errorf("%s body_file: %w", label, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s body_file %s is not a regular file", label, resp.BodyFile)
+		}
+	}
+	if resp.BodyURL != "" {
+		if resp.Body != "" || resp.BodyFile != "" {
+			return fmt.Errorf("%s body_url cannot be combined with body or body_file", label)
+		}
+		if resp.StatusText != "" || resp.HTTP10 || resp.Compression != "" {
+			return fmt.Errorf("%s body_url cannot be combined with status_text, http10 or compression", label)
+		}
+		parsed, err := url.Parse(resp.BodyURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%s body_url %q must be an absolute http or https URL", label, resp.BodyURL)
+		}
+	}
+	if resp.BodyURLTTL < 0 {
+		return fmt.Errorf("%s body_url_ttl cannot be negative", label)
+	}
+	for _, method := range resp.Methods {
+		if method == "" {
+			return fmt.Errorf("%s contains empty method", label)
+		}
+	}
+	if err := validateResponseSequence(label, *resp); err != nil {
+		return err
+	}
+	if err := validateMatchConditions(label, *resp); err != nil {
+		return err
+	}
+	if !resp.Template {
+		return nil
+	}
+	if _, err := mocktemplate.Parse("body", resp.Body); err != nil {
+		return fmt.Errorf("%s body template: %w", label, err)
+	}
+	for key, value := range resp.Headers {
+		if _, err := mocktemplate.Parse(key, value); err != nil {
+			return fmt.Errorf("%s header %s template: %w", label, key, err)
+		}
 	}
 	return nil
 }
 
-func canonicalizeHeaders(headers map[string]string) map[string]string {
-	if len(headers) == 0 {
-		return headers
-	}
-	canonical := make(map[string]string, len(headers))
-	for key, value := range headers {
-		canonical[http.CanonicalHeaderKey(key)] = value
+// validateMatchConditions checks the header, query and body conditions of a rule; label names the
+// rule in errors
+func validateMatchConditions(label string, resp ImmediateResponseConfig) error {
+	groups := []struct {
+		field      string
+		conditions []MatchConditionConfig
+	}{
+		{"header_match", resp.HeaderMatch},
+		{"query_match", resp.QueryMatch},
+		{"body_match", resp.BodyMatch},
+	}
+	for _, group := range groups {
+		for j, cond := range group.conditions {
+			prefix := fmt.Sprintf("%s %s %d", label, group.field, j+1)
+			operators := 0
+			for _, value := range []string{cond.Equals, cond.Contains, cond.Regex} {
+				if value != "" {
+					operators++
+				}
+			}
+			if operators > 1 {
+				return fmt.Errorf("%s can set only one of equals, contains and regex", prefix)
+			}
+			if cond.Regex != "" {
+				if _, err := regexp.Compile(cond.Regex); err != nil {
+					return fmt.Errorf("%s regex: %w", prefix, err)
+				}
+			}
+			if group.field == "body_match" {
+				if cond.Name != "" {
+					return fmt.Errorf("%s cannot set name; use json_path to select a field", prefix)
+				}
+				if cond.JSONPath == "" && operators == 0 {
+					return fmt.Errorf("%s needs equals, contains or regex when json_path is empty", prefix)
+				}
+				continue
+			}
+			if strings.TrimSpace(cond.Name) == "" {
+				return fmt.Errorf("%s name cannot be empty", prefix)
+			}
+			if cond.JSONPath != "" {
+				return fmt.Errorf("%s json_path is only supported in body_match", prefix)
+			}
+		}
 	}
-	return canonical
+	return nil
 }
 
-func normalizeHeaderList(list []string) []string {
-	if len(list) == 0 {
-		return list
+// validateResponseSequence checks the steps of a sequenced rule; label names the rule in errors
+func validateResponseSequence(label string, resp ImmediateResponseConfig) error {
+	if len(resp.Sequence) == 0 {
+		if resp.SequenceLoop {
+			return fmt.Errorf("%s sequence_loop requires a sequence", label)
+		}
+		return nil
 	}
-	set := make(map[string]struct{}, len(list))
-	result := make([]string, 0, len(list))
-	for _, h := range list {
-		norm := strings.ToLower(strings.TrimSpace(h))
-		if norm == "" {
-			continue
+	if resp.BodyFile != "" || resp.BodyURL != "" {
+		return fmt.Errorf("%s sequence cannot be combined with body_file or body_url", label)
+	}
+	for j, step := range resp.Sequence {
+		if step.Status != 0 && (step.Status < 100 || step.Status > 599) {
+			return fmt.Errorf("%s sequence step %d status must be between 100 and 599", label, j+1)
 		}
-		if _, exists := set[norm]; exists {
+		if step.Times < 0 {
+			return fmt.Errorf("%s sequence step %d times cannot be negative", label, j+1)
+		}
+		if !resp.Template {
 			continue
 		}
-		set[norm] = struct{}{}
-		result = append(result, norm)
+		if _, err := mocktemplate.Parse("body", step.Body); err != nil {
+			return fmt.Errorf("%s sequence step %d body template: %w", label, j+1, err)
+		}
+		for key, value := range step.Headers {
+			if _, err := mocktemplate.Parse(key, value); err != nil {
+				return fmt.Errorf("%s sequence step %d header %s template: %w", label, j+1, key, err)
+			}
+		}
 	}
-	return result
+	return nil
 }
 
-func normalizeLocaleList(locales []string) []string {
-	if len(locales) == 0 {
-		return locales
+// validateForwardExpect checks a response contract
+func validateForwardExpect(expect *ForwardExpectConfig) error {
+	for _, status := range expect.Status {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("expected status %d must be between 100 and 599", status)
+		}
 	}
-	set := make(map[string]struct{}, len(locales))
-	result := make([]string, 0, len(locales))
-	for _, loc := range locales {
-		norm := strings.TrimSpace(loc)
-		if norm == "" {
-			continue
+	for j, assertion := range expect.JSON {
+		if strings.TrimSpace(assertion.Path) == "" {
+			return fmt.Errorf("json assertion %d path cannot be empty", j+1)
 		}
-		key := strings.ToLower(norm)
-		if _, exists := set[key]; exists {
+	}
+	if expect.MaxLatency < 0 {
+		return fmt.Errorf("max_latency cannot be negative")
+	}
+	for j, needle := range expect.BodyContains {
+		if needle == "" {
+			return fmt.Errorf("body_contains entry %d cannot be empty", j+1)
+		}
+	}
+	return nil
+}
+
+// validateForwardTargets checks detailed forward targets; label prefixes errors, e.g. "forward target"
+func validateForwardTargets(label string, targets []ForwardTargetConfig) error {
+	for i, target := range targets {
+		if strings.TrimSpace(target.URL) == "" {
+			return fmt.Errorf("%s %d url cannot be empty", label, i+1)
+		}
+		if err := validateForwardExpect(&target.Expect); err != nil {
+			return fmt.Errorf("%s %d %w", label, i+1, err)
+		}
+		if target.LatencyBudget < 0 {
+			return fmt.Errorf("%s %d latency budget cannot be negative", label, i+1)
+		}
+		if target.SamplePercent < 0 || target.SamplePercent > 100 {
+			return fmt.Errorf("%s %d sample_percent must be between 0 and 100", label, i+1)
+		}
+		if target.Weight < 0 {
+			return fmt.Errorf("%s %d weight cannot be negative", label, i+1)
+		}
+		if target.MaxConcurrent < 0 {
+			return fmt.Errorf("%s %d max_concurrent cannot be negative", label, i+1)
+		}
+		if target.RateLimit < 0 {
+			return fmt.Errorf("%s %d rate_limit cannot be negative", label, i+1)
+		}
+		if retry := target.Retry; (retry.MaxRetries != nil && *retry.MaxRetries < 0) || retry.Backoff < 0 || retry.MaxBackoff < 0 {
+			return fmt.Errorf("%s %d retry values cannot be negative", label, i+1)
+		}
+		if retry := target.Retry; retry.Backoff > 0 && retry.MaxBackoff > 0 && retry.MaxBackoff < retry.Backoff {
+			return fmt.Errorf("%s %d retry max_backoff must be at least backoff", label, i+1)
+		}
+		switch strings.ToLower(target.Format) {
+		case "", SinkFormatJSON, SinkFormatBody:
+		default:
+			return fmt.Errorf("%s %d format must be json or body", label, i+1)
+		}
+		if err := validateForwardSign(&targets[i].Sign); err != nil {
+			return fmt.Errorf("%s %d sign: %w", label, i+1, err)
+		}
+		if !IsSinkURL(target.URL) {
+			if err := validateForwardURL(target.URL); err != nil {
+				return fmt.Errorf("%s %d: %w", label, i+1, err)
+			}
 			continue
 		}
-		set[key] = struct{}{}
-		result = append(result, norm)
+		if target.Sign.Scheme != "" {
+			return fmt.Errorf("%s %d publishes to a message broker and cannot sign requests", label, i+1)
+		}
+		if !target.Expect.Empty() {
+			return fmt.Errorf("%s %d publishes to a message broker and cannot expect a response", label, i+1)
+		}
+		if err := validateSinkURL(target.URL); err != nil {
+			return fmt.Errorf("%s %d: %w", label, i+1, err)
+		}
 	}
-	return result
+	return nil
 }
 
-func containsLocale(locales []string, target string) bool {
-	targetKey := strings.ToLower(strings.TrimSpace(target))
-	for _, loc := range locales {
-		if strings.ToLower(strings.TrimSpace(loc)) == targetKey {
-			return true
+// validateForwardSign normalizes a signing config and fills in the hmac defaults
+func validateForwardSign(sign *ForwardSignConfig) error {
+	sign.Scheme = strings.ToLower(strings.TrimSpace(sign.Scheme))
+	if sign.Scheme == "" {
+		return nil
+	}
+	if sign.Secret == "" {
+		return fmt.Errorf("secret cannot be empty")
+	}
+	switch sign.Scheme {
+	case SignSchemeGitHub, SignSchemeStripe, SignSchemeSlack:
+		if sign.Header != "" || sign.Algorithm != "" || sign.Encoding != "" || sign.Prefix != "" {
+			return fmt.Errorf("header, algorithm, encoding and prefix only apply to the hmac scheme")
 		}
+		return nil
+	case SignSchemeHMAC:
+	default:
+		return fmt.Errorf("unknown scheme %q, expected github, stripe, slack or hmac", sign.Scheme)
 	}
-	return false
+	if sign.Header == "" {
+		sign.Header = "X-Signature"
+	}
+	sign.Algorithm = strings.ToLower(sign.Algorithm)
+	switch sign.Algorithm {
+	case "":
+		sign.Algorithm = "sha256"
+	case "sha256", "sha1", "sha512":
+	default:
+		return fmt.Errorf("algorithm must be sha256, sha1 or sha512")
+	}
+	sign.Encoding = strings.ToLower(sign.Encoding)
+	switch sign.Encoding {
+	case "":
+		sign.Encoding = "hex"
+	case "hex", "base64":
+	default:
+		return fmt.Errorf("encoding must be hex or base64")
+	}
+	return nil
+}
+
+// validateSinkURL checks that a message broker URL names its broker and destination; HTTP URLs pass
+// validateForwardURL checks that a forward destination is a sink URL or an absolute http(s) URL
+func validateForwardURL(raw string) error {
+	if IsSinkURL(raw) {
+		return validateSinkURL(raw)
+	}
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if scheme := strings.ToLower(parsed.Scheme); (scheme != "http" && scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+func validateSinkURL(raw string) error {
+	if !IsSinkURL(raw) {
+		return nil
+	}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(raw), "://")
+	scheme = strings.ToLower(scheme)
+	if scheme == "kafka" {
+		// Several brokers are separated by commas, which url.Parse rejects in the host
+		brokers, topic, _ := strings.Cut(rest, "/")
+		if i := strings.LastIndex(brokers, "@"); i >= 0 {
+			brokers = brokers[i+1:]
+		}
+		topic, _, _ = strings.Cut(topic, "?")
+		if brokers == "" || topic == "" {
+			return fmt.Errorf("kafka sink must look like kafka://broker:9092/topic")
+		}
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%s sink URL is invalid", scheme)
+	}
+	query := parsed.Query()
+	switch scheme {
+	case "amqp", "amqps":
+		if query.Get("exchange") == "" && query.Get("routing_key") == "" {
+			return fmt.Errorf("amqp sink needs an exchange or a routing_key query parameter")
+		}
+	case "mqtt", "mqtts":
+		if strings.Trim(parsed.Path, "/") == "" {
+			return fmt.Errorf("mqtt sink must look like mqtt://broker:1883/topic")
+		}
+		switch query.Get("qos") {
+		case "", "0", "1", "2":
+		default:
+			return fmt.Errorf("mqtt sink qos must be 0, 1 or 2")
+		}
+	}
+	return nil
+}
+
+// ResolvedTargets merges plain forward URLs with detailed target definitions.
+// Detailed definitions win when both reference the same URL; targets without
+// their own latency budget inherit forward.latency_budget, and HTTP targets
+// without an expect inherit forward.expectations.
+func (f *ForwardConfig) ResolvedTargets() []ForwardTargetConfig {
+	detailed := make(map[string]struct{}, len(f.Targets))
+	for _, target := range f.Targets {
+		detailed[strings.TrimSpace(target.URL)] = struct{}{}
+	}
+	targets := make([]ForwardTargetConfig, 0, len(f.URLs)+len(f.Targets))
+	for _, url := range f.URLs {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		if _, ok := detailed[url]; ok {
+			continue
+		}
+		targets = append(targets, ForwardTargetConfig{URL: url, LatencyBudget: f.LatencyBudget})
+	}
+	for _, target := range f.Targets {
+		target.URL = strings.TrimSpace(target.URL)
+		if target.LatencyBudget == 0 {
+			target.LatencyBudget = f.LatencyBudget
+		}
+		targets = append(targets, target)
+	}
+	for i := range targets {
+		if targets[i].Expect.Empty() && !IsSinkURL(targets[i].URL) {
+			targets[i].Expect = f.Expectations
+		}
+	}
+	return targets
+}
+
+// validateCapturePaths checks server.paths; prefixes are trimmed and must be unique
+func (c *Config) validateCapturePaths() error {
+	seen := make(map[string]struct{}, len(c.Server.Paths))
+	for i := range c.Server.Paths {
+		route := &c.Server.Paths[i]
+		route.Path = strings.TrimSpace(route.Path)
+		if route.Path == "" {
+			return fmt.Errorf("server path %d cannot be empty", i+1)
+		}
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("server path %d must start with '/'", i+1)
+		}
+		if _, dup := seen[route.Path]; dup {
+			return fmt.Errorf("server path %s is configured more than once", route.Path)
+		}
+		seen[route.Path] = struct{}{}
+		label := "server path " + route.Path
+		if err := validateImmediateResponses(label+" response", route.Responses); err != nil {
+			return err
+		}
+		for j, url := range route.Forward.URLs {
+			if strings.TrimSpace(url) == "" {
+				return fmt.Errorf("%s forward URL %d cannot be empty", label, j+1)
+			}
+			if err := validateSinkURL(url); err != nil {
+				return fmt.Errorf("%s forward URL %d: %w", label, j+1, err)
+			}
+		}
+		if err := validateForwardTargets(label+" forward target", route.Forward.Targets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateHooks() error {
+	for i := range c.Hooks {
+		hook := &c.Hooks[i]
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		hook.Point = strings.ToLower(strings.TrimSpace(hook.Point))
+		if !slices.Contains(HookPoints, hook.Point) {
+			return fmt.Errorf("hook %s point must be one of %s", hook.Name, strings.Join(HookPoints, ", "))
+		}
+		hook.Action = strings.ToLower(strings.TrimSpace(hook.Action))
+		switch hook.Action {
+		case "redact":
+			if len(hook.RedactHeaders) == 0 && len(hook.RedactFields) == 0 {
+				return fmt.Errorf("hook %s redact requires redact_headers or redact_fields", hook.Name)
+			}
+		case "tag":
+			if len(hook.Tags) == 0 {
+				return fmt.Errorf("hook %s tag requires tags", hook.Name)
+			}
+		case "drop":
+			if hook.Point == "after_forward" {
+				return fmt.Errorf("hook %s cannot drop after_forward, the request was already delivered", hook.Name)
+			}
+		default:
+			return fmt.Errorf("hook %s action must be one of %s", hook.Name, strings.Join(HookActions, ", "))
+		}
+		for j, method := range hook.Methods {
+			hook.Methods[j] = strings.ToUpper(strings.TrimSpace(method))
+		}
+		if hook.PathRegex != "" {
+			if _, err := regexp.Compile(hook.PathRegex); err != nil {
+				return fmt.Errorf("hook %s path_regex: %w", hook.Name, err)
+			}
+		}
+		for name, pattern := range hook.Headers {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("hook %s header %s: %w", hook.Name, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateForwardFilters() error {
+	for i := range c.Forward.Filters {
+		filter := &c.Forward.Filters[i]
+		filter.Action = strings.ToLower(strings.TrimSpace(filter.Action))
+		switch filter.Action {
+		case "":
+			filter.Action = "allow"
+		case "allow", "deny":
+		default:
+			return fmt.Errorf("forward filter %d action must be 'allow' or 'deny'", i+1)
+		}
+		for j, method := range filter.Methods {
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if method == "" {
+				return fmt.Errorf("forward filter %d contains empty method", i+1)
+			}
+			filter.Methods[j] = method
+		}
+		for j, target := range filter.Targets {
+			filter.Targets[j] = strings.TrimSpace(target)
+		}
+		if filter.PathRegex != "" {
+			if _, err := regexp.Compile(filter.PathRegex); err != nil {
+				return fmt.Errorf("forward filter %d path_regex: %w", i+1, err)
+			}
+		}
+		for name, pattern := range filter.Headers {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("forward filter %d header %s: %w", i+1, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateForwardHostOverrides(overrides []ForwardHostOverrideConfig) error {
+	seen := make(map[string]bool, len(overrides))
+	for i := range overrides {
+		override := &overrides[i]
+		override.Host = strings.ToLower(strings.TrimSpace(override.Host))
+		override.Address = strings.TrimSpace(override.Address)
+		if override.Host == "" || strings.Contains(override.Host, "/") {
+			return fmt.Errorf("forward host override %d host must be a hostname or host:port", i+1)
+		}
+		if host, port, err := net.SplitHostPort(override.Host); err == nil {
+			if host == "" || !validPort(port) {
+				return fmt.Errorf("forward host override %s host must be a hostname or host:port", override.Host)
+			}
+		}
+		if seen[override.Host] {
+			return fmt.Errorf("forward host override %s is defined twice", override.Host)
+		}
+		seen[override.Host] = true
+		if override.Address == "" {
+			return fmt.Errorf("forward host override %s address cannot be empty", override.Host)
+		}
+		if host, port, err := net.SplitHostPort(override.Address); err == nil {
+			if host == "" || !validPort(port) {
+				return fmt.Errorf("forward host override %s address must be IP:port, host:port or an IP", override.Host)
+			}
+		} else if strings.ContainsAny(override.Address, "/ ") {
+			return fmt.Errorf("forward host override %s address must be IP:port, host:port or an IP", override.Host)
+		}
+	}
+	return nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func (c *Config) validateForwardTransforms() error {
+	for i := range c.Forward.Transforms {
+		transform := &c.Forward.Transforms[i]
+		for j, target := range transform.Targets {
+			transform.Targets[j] = strings.TrimSpace(target)
+		}
+		for j := range transform.JSON.Rename {
+			rename := &transform.JSON.Rename[j]
+			rename.From, rename.To = strings.TrimSpace(rename.From), strings.TrimSpace(rename.To)
+			if len(jsonpath.Split(rename.From)) == 0 || len(jsonpath.Split(rename.To)) == 0 {
+				return fmt.Errorf("forward transform %d json rename %d requires from and to", i+1, j+1)
+			}
+		}
+		for j, path := range transform.JSON.Remove {
+			if len(jsonpath.Split(path)) == 0 {
+				return fmt.Errorf("forward transform %d json remove %d is empty", i+1, j+1)
+			}
+		}
+		for j := range transform.JSON.Set {
+			op := &transform.JSON.Set[j]
+			if len(jsonpath.Split(op.Path)) == 0 {
+				return fmt.Errorf("forward transform %d json set %d requires a path", i+1, j+1)
+			}
+			if _, err := mocktemplate.Parse(op.Path, op.Value); err != nil {
+				return fmt.Errorf("forward transform %d json set %s: %w", i+1, op.Path, err)
+			}
+			if op.Raw && !mocktemplate.IsTemplate(op.Value) && !json.Valid([]byte(op.Value)) {
+				return fmt.Errorf("forward transform %d json set %s: raw value is not valid JSON", i+1, op.Path)
+			}
+		}
+		if _, err := mocktemplate.Parse("body", transform.Body); err != nil {
+			return fmt.Errorf("forward transform %d body: %w", i+1, err)
+		}
+		transform.Headers.Set = canonicalizeHeaders(transform.Headers.Set)
+		for name, value := range transform.Headers.Set {
+			if _, err := mocktemplate.Parse(name, value); err != nil {
+				return fmt.Errorf("forward transform %d header %s: %w", i+1, name, err)
+			}
+		}
+		transform.Headers.Remove = normalizeHeaderList(transform.Headers.Remove)
+	}
+	return nil
+}
+
+func validateForwardHealthConfig(cfg *ForwardConfig) error {
+	if cfg.CircuitBreaker.Enable {
+		if cfg.CircuitBreaker.FailureThreshold < 1 {
+			return fmt.Errorf("forward circuit_breaker failure_threshold must be at least 1")
+		}
+		if cfg.CircuitBreaker.Cooldown <= 0 {
+			return fmt.Errorf("forward circuit_breaker cooldown must be positive")
+		}
+	}
+	if cfg.HealthCheck.Enable {
+		cfg.HealthCheck.Path = strings.TrimSpace(cfg.HealthCheck.Path)
+		if cfg.HealthCheck.Path == "" {
+			cfg.HealthCheck.Path = "/"
+		}
+		if !strings.HasPrefix(cfg.HealthCheck.Path, "/") {
+			return fmt.Errorf("forward health_check path must start with /")
+		}
+		if cfg.HealthCheck.Interval <= 0 {
+			return fmt.Errorf("forward health_check interval must be positive")
+		}
+		if cfg.HealthCheck.Timeout <= 0 {
+			return fmt.Errorf("forward health_check timeout must be positive")
+		}
+	}
+	if cfg.Queue.Enable {
+		if cfg.Queue.PollInterval <= 0 {
+			return fmt.Errorf("forward queue poll_interval must be positive")
+		}
+		if cfg.Queue.Backoff <= 0 {
+			return fmt.Errorf("forward queue backoff must be positive")
+		}
+		if cfg.Queue.MaxBackoff < cfg.Queue.Backoff {
+			return fmt.Errorf("forward queue max_backoff must be at least backoff")
+		}
+		if cfg.Queue.MaxAttempts < 0 {
+			return fmt.Errorf("forward queue max_attempts cannot be negative")
+		}
+	}
+	return nil
+}
+
+var pluginHooks = map[string]bool{"transform": true, "export": true, "storage": true}
+
+func (c *Config) validatePlugins() error {
+	seen := make(map[string]struct{}, len(c.Plugins))
+	for i := range c.Plugins {
+		p := &c.Plugins[i]
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			return fmt.Errorf("plugin %d name cannot be empty", i+1)
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("plugin %q is declared more than once", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		if strings.TrimSpace(p.Command) == "" {
+			return fmt.Errorf("plugin %q command cannot be empty", p.Name)
+		}
+		for j, hook := range p.Hooks {
+			hook = strings.ToLower(strings.TrimSpace(hook))
+			if !pluginHooks[hook] {
+				return fmt.Errorf("plugin %q hook %q must be transform, export, or storage", p.Name, p.Hooks[j])
+			}
+			p.Hooks[j] = hook
+		}
+		if p.Timeout < 0 {
+			return fmt.Errorf("plugin %q timeout cannot be negative", p.Name)
+		}
+		if p.Timeout == 0 {
+			p.Timeout = 5 * time.Second
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateWasmTransforms() error {
+	for i := range c.WasmTransforms {
+		t := &c.WasmTransforms[i]
+		if strings.TrimSpace(t.Path) == "" {
+			return fmt.Errorf("wasm transform %d path cannot be empty", i+1)
+		}
+		if strings.TrimSpace(t.Name) == "" {
+			t.Name = fmt.Sprintf("wasm-%d", i+1)
+		}
+		if t.MemoryLimitMB < 0 {
+			return fmt.Errorf("wasm transform %q memory_limit_mb cannot be negative", t.Name)
+		}
+		if t.MemoryLimitMB == 0 {
+			t.MemoryLimitMB = 16
+		}
+		if t.Timeout < 0 {
+			return fmt.Errorf("wasm transform %q timeout cannot be negative", t.Name)
+		}
+		if t.Timeout == 0 {
+			t.Timeout = 100 * time.Millisecond
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateScripts() error {
+	for i := range c.Scripts {
+		s := &c.Scripts[i]
+		if strings.TrimSpace(s.Path) == "" {
+			return fmt.Errorf("script %d path cannot be empty", i+1)
+		}
+		if strings.TrimSpace(s.Name) == "" {
+			s.Name = fmt.Sprintf("script-%d", i+1)
+		}
+		if s.Timeout < 0 {
+			return fmt.Errorf("script %q timeout cannot be negative", s.Name)
+		}
+		if s.Timeout == 0 {
+			s.Timeout = 100 * time.Millisecond
+		}
+	}
+	return nil
+}
+
+// hasPluginHook reports whether the named plugin may serve the hook; plugins without an explicit hook list may serve any
+func (c *Config) hasPluginHook(name, hook string) bool {
+	for _, p := range c.Plugins {
+		if p.Name != strings.TrimSpace(name) {
+			continue
+		}
+		if len(p.Hooks) == 0 {
+			return true
+		}
+		for _, h := range p.Hooks {
+			if h == hook {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validateAnomalyConfig(cfg *AnomalyConfig) error {
+	if cfg.Window < 0 || cfg.Cooldown < 0 || cfg.BaselineWindows < 0 || cfg.Threshold < 0 || cfg.MinRequests < 0 {
+		return fmt.Errorf("anomaly settings cannot be negative")
+	}
+	if cfg.Window == 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.BaselineWindows == 0 {
+		cfg.BaselineWindows = 30
+	}
+	if cfg.BaselineWindows < 3 {
+		return fmt.Errorf("anomaly baseline_windows must be at least 3")
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = 3
+	}
+	for i, hook := range cfg.Webhooks {
+		parsed, err := url.Parse(strings.TrimSpace(hook))
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("anomaly webhook %d must be an http(s) URL", i+1)
+		}
+	}
+	return nil
+}
+
+func validateForwardOrdering(cfg *ForwardOrderingConfig) error {
+	cfg.Header = strings.TrimSpace(cfg.Header)
+	cfg.JSONPath = strings.TrimSpace(cfg.JSONPath)
+	if strings.ContainsAny(cfg.Header, " \t\r\n:") {
+		return fmt.Errorf("forward ordering header %q is not a valid header name", cfg.Header)
+	}
+	if cfg.Enable && cfg.Header == "" && cfg.JSONPath == "" {
+		return fmt.Errorf("forward ordering requires a header or a json_path")
+	}
+	return nil
+}
+
+func validateDedupConfig(cfg *DedupConfig) error {
+	if cfg.Window < 0 {
+		return fmt.Errorf("dedup window cannot be negative")
+	}
+	if cfg.Window == 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	cfg.Header = strings.TrimSpace(cfg.Header)
+	if strings.ContainsAny(cfg.Header, " \t\r\n:") {
+		return fmt.Errorf("dedup header %q is not a valid header name", cfg.Header)
+	}
+	if cfg.SuppressForward && !cfg.Enable {
+		return fmt.Errorf("dedup suppress_forward requires dedup.enable")
+	}
+	return nil
+}
+
+func validateRedactConfig(cfg *RedactConfig) error {
+	for i, name := range cfg.Headers {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("privacy redact header %q is not a valid header name", cfg.Headers[i])
+		}
+		cfg.Headers[i] = name
+	}
+	for i, field := range cfg.Fields {
+		if len(jsonpath.Split(field)) == 0 {
+			return fmt.Errorf("privacy redact field %d is empty", i+1)
+		}
+	}
+	for _, pattern := range cfg.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("privacy redact pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.PreserveForward && len(cfg.Headers)+len(cfg.Fields)+len(cfg.Patterns) == 0 {
+		return fmt.Errorf("privacy redact preserve_forward requires headers, fields or patterns")
+	}
+	return nil
+}
+
+func (c *Config) validateCluster() error {
+	cfg := &c.Cluster
+	if !cfg.Enable {
+		return nil
+	}
+	if !c.Web.Enable {
+		return fmt.Errorf("cluster requires web.enable, peers exchange requests over the admin API")
+	}
+	if strings.TrimSpace(cfg.Secret) == "" {
+		return fmt.Errorf("cluster secret cannot be empty")
+	}
+	if cfg.QueueSize < 0 || cfg.Timeout < 0 {
+		return fmt.Errorf("cluster settings cannot be negative")
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.InstanceID = strings.TrimSpace(cfg.InstanceID)
+	if cfg.InstanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			return fmt.Errorf("cluster instance_id cannot be empty")
+		}
+		cfg.InstanceID = hostname
+	}
+	for i, peer := range cfg.Peers {
+		peer = strings.TrimRight(strings.TrimSpace(peer), "/")
+		parsed, err := url.Parse(peer)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("cluster peer %d must be an http(s) URL", i+1)
+		}
+		cfg.Peers[i] = peer
+	}
+	return nil
+}
+
+func validateStorageArchiveConfig(cfg *StorageConfig) error {
+	archive := &cfg.Archive
+	if !archive.Enable {
+		return nil
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Driver), "plugin") {
+		return fmt.Errorf("storage archive requires the sqlite or bolt storage driver")
+	}
+	archive.URL = strings.TrimSpace(archive.URL)
+	u, err := url.Parse(archive.URL)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "gs") || u.Host == "" {
+		return fmt.Errorf("storage archive url must be s3://bucket/prefix or gs://bucket/prefix")
+	}
+	if archive.After <= 0 {
+		return fmt.Errorf("storage archive after must be positive")
+	}
+	if archive.Interval < 0 {
+		return fmt.Errorf("storage archive interval cannot be negative")
+	}
+	if archive.Interval == 0 {
+		archive.Interval = time.Hour
+	}
+	// Only whole UTC days older than after are archived, on the next pass, so a request can wait
+	// for after plus a day plus the interval
+	if wait := archive.After + 24*time.Hour + archive.Interval; cfg.Retention > 0 && cfg.Retention <= wait {
+		return fmt.Errorf("storage retention must be longer than archive after plus 24h and the archive interval (%s), or requests are pruned before they are archived", wait)
+	}
+	if archive.Endpoint != "" {
+		if e, err := url.Parse(archive.Endpoint); err != nil || (e.Scheme != "http" && e.Scheme != "https") || e.Host == "" {
+			return fmt.Errorf("storage archive endpoint must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+func validateTunnelConfig(cfg *TunnelConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	if strings.TrimSpace(cfg.Secret) == "" {
+		return fmt.Errorf("tunnel secret cannot be empty")
+	}
+	cfg.Path = strings.TrimSpace(cfg.Path)
+	if cfg.Path == "" {
+		cfg.Path = "/reqtap-tunnel"
+	}
+	if !strings.HasPrefix(cfg.Path, "/") || cfg.Path == "/" {
+		return fmt.Errorf("tunnel path must start with '/' and cannot be '/'")
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("tunnel timeout cannot be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return nil
+}
+
+func validateHealthConfig(cfg *HealthConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	cfg.LivenessPath = strings.TrimSpace(cfg.LivenessPath)
+	if cfg.LivenessPath == "" {
+		cfg.LivenessPath = "/healthz"
+	}
+	cfg.ReadinessPath = strings.TrimSpace(cfg.ReadinessPath)
+	if cfg.ReadinessPath == "" {
+		cfg.ReadinessPath = "/readyz"
+	}
+	for _, path := range []string{cfg.LivenessPath, cfg.ReadinessPath} {
+		if !strings.HasPrefix(path, "/") || path == "/" {
+			return fmt.Errorf("health path %q must start with '/' and cannot be '/'", path)
+		}
+	}
+	if cfg.LivenessPath == cfg.ReadinessPath {
+		return fmt.Errorf("health liveness_path and readiness_path must differ")
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("health timeout cannot be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	return nil
+}
+
+func validateTelemetryConfig(cfg *TelemetryConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	cfg.Protocol = strings.ToLower(strings.TrimSpace(cfg.Protocol))
+	switch cfg.Protocol {
+	case "":
+		cfg.Protocol = "http"
+	case "http", "grpc":
+	default:
+		return fmt.Errorf("telemetry protocol must be http or grpc")
+	}
+	cfg.Endpoint = strings.TrimSpace(cfg.Endpoint)
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("telemetry endpoint cannot be empty")
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return fmt.Errorf("telemetry sample_ratio must be between 0 and 1")
+	}
+	if cfg.SampleRatio == 0 {
+		cfg.SampleRatio = 1
+	}
+	if strings.TrimSpace(cfg.ServiceName) == "" {
+		cfg.ServiceName = "reqtap"
+	}
+	return nil
+}
+
+func validateWebSocketCaptureConfig(cfg *WebSocketCaptureConfig) error {
+	if cfg.PreviewBytes < 0 {
+		return fmt.Errorf("server websocket preview_bytes cannot be negative")
+	}
+	if cfg.PreviewBytes == 0 {
+		cfg.PreviewBytes = 256
+	}
+	cfg.ProxyURL = strings.TrimSpace(cfg.ProxyURL)
+	if cfg.ProxyURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(cfg.ProxyURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("server websocket proxy_url %q is not a valid URL", cfg.ProxyURL)
+	}
+	switch parsed.Scheme {
+	case "ws", "wss":
+	default:
+		return fmt.Errorf("server websocket proxy_url must use ws:// or wss://")
+	}
+	return nil
+}
+
+func validateCaptureAuthConfig(cfg *CaptureAuthConfig) error {
+	if cfg.Realm == "" {
+		cfg.Realm = "reqtap"
+	}
+	if strings.ContainsAny(cfg.Realm, "\"\r\n") {
+		return fmt.Errorf("server auth realm cannot contain quotes or line breaks")
+	}
+	if !cfg.Enable {
+		return nil
+	}
+	if len(cfg.Credentials) == 0 {
+		return fmt.Errorf("server auth requires at least one credential when enabled")
+	}
+	names := make(map[string]bool, len(cfg.Credentials))
+	for i := range cfg.Credentials {
+		cred := &cfg.Credentials[i]
+		cred.Name = strings.TrimSpace(cred.Name)
+		switch {
+		case cred.Token != "" && cred.Username != "":
+			return fmt.Errorf("server auth credential %d must set either token or username, not both", i+1)
+		case cred.Token != "":
+			if cred.Name == "" {
+				return fmt.Errorf("server auth credential %d needs a name for its token", i+1)
+			}
+		case cred.Username != "":
+			if strings.Contains(cred.Username, ":") {
+				return fmt.Errorf("server auth credential %d username cannot contain ':'", i+1)
+			}
+			if cred.Password == "" {
+				return fmt.Errorf("server auth credential %d requires a password", i+1)
+			}
+			if cred.Name == "" {
+				cred.Name = cred.Username
+			}
+		default:
+			return fmt.Errorf("server auth credential %d must set a token or a username", i+1)
+		}
+		if names[cred.Name] {
+			return fmt.Errorf("server auth credential name %q is used more than once", cred.Name)
+		}
+		names[cred.Name] = true
+	}
+	return nil
+}
+
+// validateAccessControlConfig turns every entry into a CIDR, single addresses becoming /32 or /128
+func validateAccessControlConfig(cfg *AccessControlConfig) error {
+	lists := []struct {
+		name    string
+		entries []string
+	}{
+		{"allow", cfg.Allow},
+		{"deny", cfg.Deny},
+		{"trusted_proxies", cfg.TrustedProxies},
+	}
+	for _, list := range lists {
+		for i, entry := range list.entries {
+			prefix, err := ParseAddressRange(entry)
+			if err != nil {
+				return fmt.Errorf("server access_control %s entry %q is not an IP address or CIDR", list.name, entry)
+			}
+			list.entries[i] = prefix.String()
+		}
+	}
+	return nil
+}
+
+// validateCORSConfig normalizes origins and methods so they can be compared as they are sent
+func validateCORSConfig(cfg *CORSConfig) error {
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("server cors max_age cannot be negative")
+	}
+	if cfg.Enable && len(cfg.AllowedOrigins) == 0 {
+		return fmt.Errorf("server cors requires allowed_origins")
+	}
+	for i, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "*" {
+			parsed, err := url.Parse(origin)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+				return fmt.Errorf("server cors origin %q must be \"*\" or scheme://host[:port]", cfg.AllowedOrigins[i])
+			}
+			origin = strings.ToLower(origin)
+		}
+		cfg.AllowedOrigins[i] = origin
+	}
+	for i, method := range cfg.AllowedMethods {
+		cfg.AllowedMethods[i] = strings.ToUpper(strings.TrimSpace(method))
+	}
+	return nil
+}
+
+// ParseAddressRange parses a CIDR or a single IP address, which matches only itself
+func ParseAddressRange(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func validateGRPCCaptureConfig(cfg *GRPCCaptureConfig) error {
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("server grpc timeout cannot be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	for i, path := range cfg.DescriptorSets {
+		cfg.DescriptorSets[i] = strings.TrimSpace(path)
+		if cfg.DescriptorSets[i] == "" {
+			return fmt.Errorf("server grpc descriptor_sets entry %d is empty", i+1)
+		}
+	}
+	cfg.BackendURL = strings.TrimSpace(cfg.BackendURL)
+	if cfg.BackendURL == "" {
+		if cfg.Reflection {
+			return fmt.Errorf("server grpc reflection requires backend_url")
+		}
+		return nil
+	}
+	parsed, err := url.Parse(cfg.BackendURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("server grpc backend_url %q is not a valid URL", cfg.BackendURL)
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("server grpc backend_url must use http:// (h2c) or https://")
+	}
+	return nil
+}
+
+func validateBodyViewConfig(cfg *BodyViewConfig) error {
+	if cfg.MaxPreviewBytes < 0 {
+		return fmt.Errorf("output.body_view.max_preview_bytes cannot be negative")
+	}
+	if cfg.Json.MaxIndentBytes < 0 {
+		return fmt.Errorf("output.body_view.json.max_indent_bytes cannot be negative")
+	}
+	if cfg.Binary.HexPreviewBytes < 0 {
+		return fmt.Errorf("output.body_view.binary.hex_preview_bytes cannot be negative")
+	}
+	if cfg.Binary.SaveToFile && strings.TrimSpace(cfg.Binary.SaveDirectory) == "" {
+		return fmt.Errorf("output.body_view.binary.save_directory cannot be empty when save_to_file is enabled")
+	}
+	if cfg.Multipart.PreviewBytes < 0 {
+		return fmt.Errorf("output.body_view.multipart.preview_bytes cannot be negative")
+	}
+	if cfg.Multipart.SaveFiles && strings.TrimSpace(cfg.Binary.SaveDirectory) == "" {
+		return fmt.Errorf("output.body_view.binary.save_directory cannot be empty when multipart.save_files is enabled")
+	}
+	return validateProtobufViewConfig(&cfg.Protobuf)
+}
+
+func validateProtobufViewConfig(cfg *ProtobufViewConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	for i, path := range cfg.DescriptorSets {
+		cfg.DescriptorSets[i] = strings.TrimSpace(path)
+		if cfg.DescriptorSets[i] == "" {
+			return fmt.Errorf("output.body_view.protobuf descriptor_sets entry %d is empty", i+1)
+		}
+		if strings.HasSuffix(strings.ToLower(cfg.DescriptorSets[i]), ".proto") {
+			return fmt.Errorf("output.body_view.protobuf descriptor_sets entry %d is a .proto source; compile it with protoc --include_imports --descriptor_set_out", i+1)
+		}
+	}
+	for i := range cfg.Messages {
+		msg := &cfg.Messages[i]
+		msg.ContentType = strings.ToLower(strings.TrimSpace(msg.ContentType))
+		msg.PathPrefix = strings.TrimSpace(msg.PathPrefix)
+		msg.Message = strings.TrimPrefix(strings.TrimSpace(msg.Message), ".")
+		if msg.Message == "" {
+			return fmt.Errorf("output.body_view.protobuf message %d must name a message type", i+1)
+		}
+		if msg.ContentType == "" && msg.PathPrefix == "" {
+			return fmt.Errorf("output.body_view.protobuf message %d needs a content_type or path_prefix", i+1)
+		}
+		if len(cfg.DescriptorSets) == 0 {
+			return fmt.Errorf("output.body_view.protobuf message %d needs descriptor_sets to resolve %s", i+1, msg.Message)
+		}
+	}
+	return nil
+}
+
+// canonicalizeResponseHeaders canonicalizes the header names of rules and their sequence steps
+func canonicalizeResponseHeaders(responses []ImmediateResponseConfig) {
+	for i := range responses {
+		responses[i].Headers = canonicalizeHeaders(responses[i].Headers)
+		for j := range responses[i].Sequence {
+			responses[i].Sequence[j].Headers = canonicalizeHeaders(responses[i].Sequence[j].Headers)
+		}
+	}
+}
+
+func canonicalizeHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	canonical := make(map[string]string, len(headers))
+	for key, value := range headers {
+		canonical[http.CanonicalHeaderKey(key)] = value
+	}
+	return canonical
+}
+
+func normalizeHeaderList(list []string) []string {
+	if len(list) == 0 {
+		return list
+	}
+	set := make(map[string]struct{}, len(list))
+	result := make([]string, 0, len(list))
+	for _, h := range list {
+		norm := strings.ToLower(strings.TrimSpace(h))
+		if norm == "" {
+			continue
+		}
+		if _, exists := set[norm]; exists {
+			continue
+		}
+		set[norm] = struct{}{}
+		result = append(result, norm)
+	}
+	return result
+}
+
+func normalizeLocaleList(locales []string) []string {
+	if len(locales) == 0 {
+		return locales
+	}
+	set := make(map[string]struct{}, len(locales))
+	result := make([]string, 0, len(locales))
+	for _, loc := range locales {
+		norm := strings.TrimSpace(loc)
+		if norm == "" {
+			continue
+		}
+		key := strings.ToLower(norm)
+		if _, exists := set[key]; exists {
+			continue
+		}
+		set[key] = struct{}{}
+		result = append(result, norm)
+	}
+	return result
+}
+
+func containsLocale(locales []string, target string) bool {
+	targetKey := strings.ToLower(strings.TrimSpace(target))
+	for _, loc := range locales {
+		if strings.ToLower(strings.TrimSpace(loc)) == targetKey {
+			return true
+		}
+	}
+	return false
+}
+
+// minTokenLength keeps configured API tokens from being guessable
+const minTokenLength = 16
+
+// ValidTokenScope reports whether scope is one of the admin API token scopes.
+func ValidTokenScope(scope string) bool {
+	switch scope {
+	case "read", "write", "export", "replay", "admin":
+		return true
+	}
+	return false
+}
+
+func validateWebTokens(tokens []WebTokenConfig) error {
+	seen := make(map[string]bool, len(tokens))
+	for i := range tokens {
+		token := &tokens[i]
+		token.Name = strings.TrimSpace(token.Name)
+		if token.Name == "" {
+			return fmt.Errorf("web auth token %d name cannot be empty", i+1)
+		}
+		if seen[token.Name] {
+			return fmt.Errorf("web auth token %s is configured more than once", token.Name)
+		}
+		seen[token.Name] = true
+		if len(strings.TrimSpace(token.Token)) < minTokenLength {
+			return fmt.Errorf("web auth token %s must be at least %d characters", token.Name, minTokenLength)
+		}
+		if len(token.Scopes) == 0 {
+			return fmt.Errorf("web auth token %s needs at least one scope", token.Name)
+		}
+		for j, scope := range token.Scopes {
+			scope = strings.ToLower(strings.TrimSpace(scope))
+			if !ValidTokenScope(scope) {
+				return fmt.Errorf("web auth token %s scope %q must be read, write, export, replay or admin", token.Name, scope)
+			}
+			token.Scopes[j] = scope
+		}
+	}
+	return nil
+}
+
+func validateLoginLinkConfig(cfg *WebAuthConfig) error {
+	link := cfg.LoginLink
+	if !link.Enable {
+		return nil
+	}
+	if link.TTL <= 0 {
+		return fmt.Errorf("web auth login_link ttl must be greater than zero")
+	}
+	if link.User == "" {
+		return nil
+	}
+	for _, user := range cfg.Users {
+		if strings.EqualFold(strings.TrimSpace(user.Username), strings.TrimSpace(link.User)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("web auth login_link user %q is not a configured user", link.User)
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -90,6 +91,10 @@ func TestLoadConfig(t *testing.T) {
 			t.Fatalf("Expected default auth users to be populated")
 		}
 
+		if !cfg.Web.Auth.LoginLink.Enable || cfg.Web.Auth.LoginLink.TTL != 5*time.Minute || cfg.Web.Auth.LoginLink.OpenBrowser {
+			t.Errorf("Expected login link enabled with a 5m ttl by default, got %+v", cfg.Web.Auth.LoginLink)
+		}
+
 		if !cfg.Web.Export.Enable {
 			t.Errorf("Expected export enabled by default")
 		}
@@ -118,6 +123,18 @@ func TestLoadConfig(t *testing.T) {
 		if cfg.Storage.Retention != 0 {
 			t.Errorf("Expected default storage retention 0, got %s", cfg.Storage.Retention)
 		}
+		if cfg.Storage.MaintenanceInterval != time.Hour {
+			t.Errorf("Expected default storage maintenance interval 1h, got %s", cfg.Storage.MaintenanceInterval)
+		}
+		if cfg.Forward.CircuitBreaker.Enable || cfg.Forward.CircuitBreaker.FailureThreshold != 5 {
+			t.Errorf("Expected circuit breaker disabled with threshold 5, got %+v", cfg.Forward.CircuitBreaker)
+		}
+		if cfg.Forward.HealthCheck.Enable || cfg.Forward.HealthCheck.Interval != 10*time.Second {
+			t.Errorf("Expected health check disabled with interval 10s, got %+v", cfg.Forward.HealthCheck)
+		}
+		if cfg.Forward.Queue.Enable || cfg.Forward.Queue.Backoff != 30*time.Second || cfg.Forward.Queue.MaxAttempts != 20 {
+			t.Errorf("Expected forward queue disabled with 30s backoff and 20 attempts, got %+v", cfg.Forward.Queue)
+		}
 	})
 }
 
@@ -251,6 +268,30 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "forward timeout cannot be negative",
 		},
+		{
+			name: "Target retry backoff above max_backoff",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{
+					Level: "info",
+				},
+				Forward: ForwardConfig{
+					MaxConcurrent: 10,
+					Targets: []ForwardTargetConfig{{
+						URL:           "http://localhost:3000",
+						MaxConcurrent: 2,
+						RateLimit:     5,
+						Retry:         ForwardRetryConfig{Backoff: time.Minute, MaxBackoff: time.Second},
+					}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward target 1 retry max_backoff must be at least backoff",
+		},
 		{
 			name: "Zero max concurrent",
 			config: &Config{
@@ -340,6 +381,78 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "web auth requires at least one user",
 		},
+		{
+			name: "Web auth user with malformed password hash",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Web: WebConfig{
+					Enable:      true,
+					Path:        "/web",
+					AdminPath:   "/api",
+					MaxRequests: 100,
+					Auth: WebAuthConfig{
+						Enable:         true,
+						SessionTimeout: time.Hour,
+						Users:          []WebUserConfig{{Username: "admin", PasswordHash: "admin123", Role: "admin"}},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "web auth user 1 password_hash",
+		},
+		{
+			name: "Negative websocket history",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Web: WebConfig{
+					Enable:      true,
+					Path:        "/web",
+					AdminPath:   "/api",
+					MaxRequests: 100,
+					WebSocket:   WebSocketFeedConfig{History: -1},
+				},
+			},
+			expectError: true,
+			errorMsg:    "web websocket history cannot be negative",
+		},
+		{
+			name: "Login link for an unknown user",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Web: WebConfig{
+					Enable:      true,
+					Path:        "/web",
+					AdminPath:   "/api",
+					MaxRequests: 100,
+					Auth: WebAuthConfig{
+						Enable:         true,
+						SessionTimeout: time.Hour,
+						Users:          []WebUserConfig{{Username: "admin", Password: "secret", Role: "admin"}},
+						LoginLink:      WebLoginLinkConfig{Enable: true, User: "ghost", TTL: time.Minute},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    `web auth login_link user "ghost" is not a configured user`,
+		},
 		{
 			name: "Invalid output mode",
 			config: &Config{
@@ -355,6 +468,51 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "output mode",
 		},
+		{
+			name: "Invalid output theme",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Output:  OutputConfig{Mode: "console", Theme: "neon"},
+			},
+			expectError: true,
+			errorMsg:    "output theme",
+		},
+		{
+			name: "Invalid forward host override address",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, HostOverrides: []ForwardHostOverrideConfig{{Host: "api.internal", Address: "http://127.0.0.1:8080"}}},
+				Output:  OutputConfig{Mode: "console"},
+			},
+			expectError: true,
+			errorMsg:    "address must be",
+		},
+		{
+			name: "Duplicate forward host override",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, HostOverrides: []ForwardHostOverrideConfig{{Host: "api.internal", Address: "127.0.0.1:8080"}, {Host: "API.internal", Address: "127.0.0.1:9090"}}},
+				Output:  OutputConfig{Mode: "console"},
+			},
+			expectError: true,
+			errorMsg:    "defined twice",
+		},
 		{
 			name: "Invalid path strategy mode",
 			config: &Config{
@@ -408,6 +566,35 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "storage driver must be sqlite",
 		},
+		{
+			name: "Bolt storage driver",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Queue: ForwardQueueConfig{Enable: true, PollInterval: time.Second, Backoff: time.Second, MaxBackoff: time.Minute}},
+				Storage: StorageConfig{Driver: "bolt", Path: "./data/reqtap.bolt"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Mock presets",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses(), MockPresets: []string{"Slack", "github"}},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: false,
+		},
+		{
+			name: "Unknown mock preset",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses(), MockPresets: []string{"discord"}},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    `unknown server mock preset "discord" (available: github, slack, stripe)`,
+		},
 		{
 			name: "Empty storage path",
 			config: &Config{
@@ -427,101 +614,1059 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "storage path cannot be empty",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if tt.config != nil && tt.config.Storage.Driver == "" && tt.config.Storage.Path == "" && tt.config.Storage.MaxRecords == 0 && tt.config.Storage.Retention == 0 {
-				tt.config.Storage = StorageConfig{
+		{
+			name: "Negative storage maintenance interval",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Storage: StorageConfig{
+					Driver:              "sqlite",
+					Path:                "./data/reqtap.db",
+					MaintenanceInterval: -time.Minute,
+				},
+			},
+			expectError: true,
+			errorMsg:    "storage maintenance_interval cannot be negative",
+		},
+		{
+			name: "Negative storage write queue",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Storage: StorageConfig{
 					Driver:     "sqlite",
-					Path:       "./reqtap.db",
-					MaxRecords: 1000,
-				}
-			}
-			err := tt.config.Validate()
-
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Expected error containing '%s', but got no error", tt.errorMsg)
-				} else if tt.errorMsg != "" && err.Error() != tt.errorMsg && !contains(err.Error(), tt.errorMsg) {
-					t.Errorf("Expected error containing '%s', got '%s'", tt.errorMsg, err.Error())
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Expected no error, but got: %v", err)
-				}
-			}
-		})
-	}
-}
-
-func TestLoadConfigWithFile(t *testing.T) {
-	// Create temporary configuration file
-	configContent := `
-server:
-  port: 9999
-  path: "/test"
-  responses:
-    - name: "custom"
-      methods: ["POST"]
-      path: "/test"
-      status: 202
-      body: '{"status":"queued"}'
-      headers:
-        Content-Type: application/json
-
-log:
-  level: "debug"
-  file_logging:
-    enable: true
-    path: "/tmp/test.log"
-    max_size_mb: 5
-    max_backups: 3
-    max_age_days: 7
-    compress: false
-
-forward:
-  urls:
-    - "http://localhost:4000"
-    - "https://api.example.com"
-  timeout: 60
-  max_retries: 5
-  max_concurrent: 20
-  path_strategy:
-    mode: "strip_prefix"
-    strip_prefix: "/test"
-
-output:
-  mode: json
-  silence: true
-`
-
-	// Create temporary file
-	tmpFile, err := os.CreateTemp("", "reqtap_test_config_*.yaml")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-
-	// Write configuration content
-	if _, err := tmpFile.WriteString(configContent); err != nil {
-		t.Fatalf("Failed to write config content: %v", err)
-	}
-	tmpFile.Close()
-
-	// Load configuration
-	cfg, err := LoadConfig(tmpFile.Name(), nil)
-	if err != nil {
-		t.Fatalf("Failed to load config: %v", err)
-	}
-
-	// Verify configuration values
-	if cfg.Server.Port != 9999 {
-		t.Errorf("Expected port 9999, got %d", cfg.Server.Port)
-	}
-
-	if cfg.Server.Path != "/test" {
-		t.Errorf("Expected path '/test', got %s", cfg.Server.Path)
+					Path:       "./data/reqtap.db",
+					WriteQueue: -1,
+				},
+			},
+			expectError: true,
+			errorMsg:    "storage write_batch and write_queue cannot be negative",
+		},
+		{
+			name: "Invalid storage archive url",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Storage: StorageConfig{
+					Driver:  "sqlite",
+					Path:    "./data/reqtap.db",
+					Archive: StorageArchiveConfig{Enable: true, URL: "https://bucket/prefix", After: time.Hour},
+				},
+			},
+			expectError: true,
+			errorMsg:    "storage archive url must be s3://bucket/prefix or gs://bucket/prefix",
+		},
+		{
+			name: "Storage retention shorter than archive after",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Storage: StorageConfig{
+					Driver:    "sqlite",
+					Path:      "./data/reqtap.db",
+					Retention: 24 * time.Hour,
+					Archive:   StorageArchiveConfig{Enable: true, URL: "s3://bucket/reqtap", After: 48 * time.Hour},
+				},
+			},
+			expectError: true,
+			errorMsg:    "storage retention must be longer than archive after",
+		},
+		{
+			name: "Storage retention within a day of archive after",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Storage: StorageConfig{
+					Driver:    "sqlite",
+					Path:      "./data/reqtap.db",
+					Retention: 60 * time.Hour,
+					Archive:   StorageArchiveConfig{Enable: true, URL: "s3://bucket/reqtap", After: 48 * time.Hour},
+				},
+			},
+			expectError: true,
+			errorMsg:    "storage retention must be longer than archive after plus 24h and the archive interval (73h0m0s)",
+		},
+		{
+			name: "Invalid telemetry protocol",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:       LogConfig{Level: "info"},
+				Forward:   ForwardConfig{MaxConcurrent: 1},
+				Telemetry: TelemetryConfig{Enable: true, Endpoint: "localhost:4317", Protocol: "zipkin"},
+			},
+			expectError: true,
+			errorMsg:    "telemetry protocol must be http or grpc",
+		},
+		{
+			name: "Invalid circuit breaker threshold",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{
+					MaxConcurrent:  1,
+					CircuitBreaker: ForwardCircuitBreakerConfig{Enable: true, Cooldown: time.Second},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward circuit_breaker failure_threshold must be at least 1",
+		},
+		{
+			name: "Invalid health check path",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{
+					MaxConcurrent: 1,
+					HealthCheck:   ForwardHealthCheckConfig{Enable: true, Path: "healthz", Interval: time.Second, Timeout: time.Second},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward health_check path must start with /",
+		},
+		{
+			name: "Forward queue backoff above max_backoff",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{
+					MaxConcurrent: 1,
+					Queue:         ForwardQueueConfig{Enable: true, PollInterval: time.Second, Backoff: time.Minute, MaxBackoff: time.Second},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward queue max_backoff must be at least backoff",
+		},
+		{
+			name: "Invalid response template",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Body: `{"id":"{{.Header "X-Id"}"}`, Template: true},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 body template",
+		},
+		{
+			name: "Response braces without template are literal",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Body: `{{ mustache }}`, Headers: map[string]string{"X-Hint": "{{user}"}, Sequence: []ResponseStepConfig{{Body: "{{"}}},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: false,
+		},
+		{
+			name: "Response status text with line break",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, StatusText: "OK\r\nX-Injected: 1"},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 status_text cannot contain line breaks",
+		},
+		{
+			name: "Response header match without a name",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, HeaderMatch: []MatchConditionConfig{{Equals: "ping"}}},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 header_match 1 name cannot be empty",
+		},
+		{
+			name: "Response body match with two operators",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 400, BodyMatch: []MatchConditionConfig{{JSONPath: "event", Equals: "ping", Regex: "^p"}}},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 body_match 1 can set only one of equals, contains and regex",
+		},
+		{
+			name: "Unsupported response compression",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Compression: "br"},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 compression must be 'gzip' or empty",
+		},
+		{
+			name: "Response timeout chance above 1",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Delay: 2 * time.Second, TimeoutChance: 1.5},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 timeout_chance must be between 0 and 1",
+		},
+		{
+			name: "Response with both body and body_file",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Body: "inline", BodyFile: "fixture.bin"},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 cannot set both body and body_file",
+		},
+		{
+			name: "Response body_url must be absolute",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, BodyURL: "fixtures.example.com/large.json"},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 body_url \"fixtures.example.com/large.json\" must be an absolute http or https URL",
+		},
+		{
+			name: "Unknown stealth profile",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					Identity: IdentityConfig{Stealth: true, Profile: "iis"},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server identity profile must be nginx, apache or caddy",
+		},
+		{
+			name: "Capture auth token without name",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					Auth: CaptureAuthConfig{Enable: true, Credentials: []CaptureCredentialConfig{{Token: "secret"}}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server auth credential 1 needs a name for its token",
+		},
+		{
+			name: "Capture auth duplicate credential names",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					Auth: CaptureAuthConfig{Enable: true, Credentials: []CaptureCredentialConfig{
+						{Username: "ci", Password: "pw"},
+						{Name: "ci", Token: "secret"},
+					}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server auth credential name \"ci\" is used more than once",
+		},
+		{
+			name: "TLS certificate without key",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					TLS: ServerTLSConfig{CertFile: "cert.pem"},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server tls requires both cert_file and key_file",
+		},
+		{
+			name: "Unknown forward http2 mode",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, HTTP2: "h3"},
+			},
+			expectError: true,
+			errorMsg:    "forward http2 must be auto, always or off",
+		},
+		{
+			name: "Duplicate capture path",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					Paths: []CapturePathConfig{{Path: "/github"}, {Path: " /github "}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server path /github is configured more than once",
+		},
+		{
+			name: "Invalid capture path response",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					Paths: []CapturePathConfig{{Path: "/stripe", Responses: []ImmediateResponseConfig{{Status: 700}}}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server path /stripe response 1 status must be between 100 and 599",
+		},
+		{
+			name: "Sequence step with invalid status",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200, Sequence: []ResponseStepConfig{{Status: 500}, {Status: 1000}}},
+					},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server response 1 sequence step 2 status must be between 100 and 599",
+		},
+		{
+			name: "gRPC reflection without backend",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Path: "/",
+					Responses: []ImmediateResponseConfig{
+						{Status: 200},
+					},
+					GRPC: GRPCCaptureConfig{Enable: true, Reflection: true},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server grpc reflection requires backend_url",
+		},
+		{
+			name: "Invalid forward filter action",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{
+					MaxConcurrent: 1,
+					Filters:       []ForwardFilterConfig{{Action: "drop", PathRegex: "^/stripe"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward filter 1 action must be 'allow' or 'deny'",
+		},
+		{
+			name: "Invalid forward filter regex",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{
+					MaxConcurrent: 1,
+					Filters:       []ForwardFilterConfig{{Action: "deny", PathRegex: "^/stripe("}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "forward filter 1 path_regex",
+		},
+		{
+			name: "Anomaly webhook must be http",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Anomaly: AnomalyConfig{Enable: true, Webhooks: []string{"ftp://alerts.example.com"}},
+			},
+			expectError: true,
+			errorMsg:    "anomaly webhook 1 must be an http(s) URL",
+		},
+		{
+			name: "Anomaly baseline too short",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Anomaly: AnomalyConfig{Enable: true, BaselineWindows: 2},
+			},
+			expectError: true,
+			errorMsg:    "anomaly baseline_windows must be at least 3",
+		},
+		{
+			name: "Dedup header must be a header name",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Dedup:   DedupConfig{Enable: true, Header: "Idempotency Key"},
+			},
+			expectError: true,
+			errorMsg:    "is not a valid header name",
+		},
+		{
+			name: "Dedup suppress_forward requires enable",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Dedup:   DedupConfig{SuppressForward: true},
+			},
+			expectError: true,
+			errorMsg:    "dedup suppress_forward requires dedup.enable",
+		},
+		{
+			name: "Cluster requires web console",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Cluster: ClusterConfig{Enable: true, Secret: "s3cret"},
+			},
+			expectError: true,
+			errorMsg:    "cluster requires web.enable",
+		},
+		{
+			name: "Cluster peer must be http",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Web:     WebConfig{Enable: true},
+				Cluster: ClusterConfig{Enable: true, Secret: "s3cret", Peers: []string{"reqtap-2:38888"}},
+			},
+			expectError: true,
+			errorMsg:    "cluster peer 1 must be an http(s) URL",
+		},
+		{
+			name: "Web auth token too short",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Web: WebConfig{
+					Enable:      true,
+					Path:        "/web",
+					AdminPath:   "/api",
+					MaxRequests: 100,
+					Auth: WebAuthConfig{
+						Enable:         true,
+						SessionTimeout: time.Hour,
+						Users:          []WebUserConfig{{Username: "admin", Password: "admin", Role: "admin"}},
+						Tokens:         []WebTokenConfig{{Name: "ci", Token: "short", Scopes: []string{"read"}}},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "web auth token ci must be at least 16 characters",
+		},
+		{
+			name: "Access control rejects invalid ranges",
+			config: &Config{
+				Server: ServerConfig{
+					Port:          8080,
+					Path:          "/",
+					Responses:     defaultResponses(),
+					AccessControl: AccessControlConfig{Allow: []string{"192.30.252.0/22", "office"}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server access_control allow entry \"office\" is not an IP address or CIDR",
+		},
+		{
+			name: "Trusted proxies must be ranges",
+			config: &Config{
+				Server: ServerConfig{
+					Port:           8080,
+					Path:           "/",
+					Responses:      defaultResponses(),
+					TrustedProxies: []string{"10.0.0.0/8", "load-balancer"},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server trusted_proxies entry \"load-balancer\" is not an IP address or CIDR",
+		},
+		{
+			name: "CORS origins must be bare origins",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+					CORS:      CORSConfig{Enable: true, AllowedOrigins: []string{"https://app.example.com/", "app.example.com"}},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server cors origin \"app.example.com\" must be \"*\" or scheme://host[:port]",
+		},
+		{
+			name: "CORS requires allowed origins",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+					CORS:      CORSConfig{Enable: true},
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "server cors requires allowed_origins",
+		},
+		{
+			name: "Hooks require a known point",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Hooks:   []HookConfig{{Name: "tokens", Point: "before_print", Action: "redact", RedactHeaders: []string{"Authorization"}}},
+			},
+			expectError: true,
+			errorMsg:    "hook tokens point must be one of on_receive, before_store, before_forward, after_forward",
+		},
+		{
+			name: "Hooks cannot drop delivered requests",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Hooks:   []HookConfig{{Point: "after_forward", Action: "drop"}},
+			},
+			expectError: true,
+			errorMsg:    "hook hook-1 cannot drop after_forward",
+		},
+		{
+			name: "Tag hooks require tags",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Hooks:   []HookConfig{{Name: "label", Point: "before_store", Action: "tag"}},
+			},
+			expectError: true,
+			errorMsg:    "hook label tag requires tags",
+		},
+		{
+			name: "Redact patterns must compile",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Privacy: PrivacyConfig{Redact: RedactConfig{Patterns: []string{"(card"}}},
+			},
+			expectError: true,
+			errorMsg:    "privacy redact pattern \"(card\"",
+		},
+		{
+			name: "Preserving forwards requires redact rules",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Path: "/", Responses: defaultResponses()},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Privacy: PrivacyConfig{Redact: RedactConfig{PreserveForward: true}},
+			},
+			expectError: true,
+			errorMsg:    "privacy redact preserve_forward requires headers, fields or patterns",
+		},
+		{
+			name: "Kafka sink requires a topic",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, URLs: []string{"kafka://broker-1:9092,broker-2:9092"}},
+			},
+			expectError: true,
+			errorMsg:    "forward URL 1: kafka sink must look like kafka://broker:9092/topic",
+		},
+		{
+			name: "Forward URL without a scheme",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, URLs: []string{"localhost:3000/hook"}},
+			},
+			expectError: true,
+			errorMsg:    `forward URL 1: "localhost:3000/hook" must be an absolute http or https URL`,
+		},
+		{
+			name: "Forward target URL that does not parse",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Targets: []ForwardTargetConfig{
+					{URL: "http://[::1/hook"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward target 1: invalid URL",
+		},
+		{
+			name: "Regex rewrite rule that does not compile",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, PathStrategy: ForwardPathStrategyConfig{
+					Mode:  "rewrite",
+					Rules: []ForwardRewriteRuleConfig{{Match: "^/api/(v[0-9]+", Replace: "/$1", Regex: true}},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward path rule 1 regex is invalid",
+		},
+		{
+			name: "Forward ordering without a key",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Ordering: ForwardOrderingConfig{Enable: true}},
+			},
+			expectError: true,
+			errorMsg:    "forward ordering requires a header or a json_path",
+		},
+		{
+			name: "Sink targets cannot expect a response",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Targets: []ForwardTargetConfig{
+					{URL: "mqtt://broker:1883/reqtap/hooks", Format: "body", Expect: ForwardExpectConfig{Status: []int{200}}},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward target 1 publishes to a message broker and cannot expect a response",
+		},
+		{
+			name: "Forward signing without secret",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Targets: []ForwardTargetConfig{
+					{URL: "http://localhost:9000", Sign: ForwardSignConfig{Scheme: "GitHub"}},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward target 1 sign: secret cannot be empty",
+		},
+		{
+			name: "Protobuf view with a .proto source",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Output: OutputConfig{BodyView: BodyViewConfig{Protobuf: ProtobufViewConfig{
+					Enable:         true,
+					DescriptorSets: []string{"api/events.proto"},
+				}}},
+			},
+			expectError: true,
+			errorMsg:    "descriptor_sets entry 1 is a .proto source",
+		},
+		{
+			name: "Syslog log output without address",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info", Outputs: []LogOutputConfig{
+					{Type: "journald"},
+					{Type: "syslog", Network: "tcp"},
+				}},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+			},
+			expectError: true,
+			errorMsg:    "log output 2 address must be host:port",
+		},
+		{
+			name: "Forward target sample percent above 100",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Targets: []ForwardTargetConfig{
+					{URL: "http://canary:9000", Weight: 1},
+					{URL: "http://mirror:9000", SamplePercent: 150},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward target 2 sample_percent must be between 0 and 100",
+		},
+		{
+			name: "Forward transform raw value must be JSON",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Transforms: []ForwardTransformConfig{
+					{JSON: ForwardJSONTransformConfig{Set: []ForwardJSONSetConfig{{Path: "meta", Value: "{version", Raw: true}}}},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward transform 1 json set meta: raw value is not valid JSON",
+		},
+		{
+			name: "Forward transform body must be a valid template",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log: LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1, Transforms: []ForwardTransformConfig{
+					{Body: `{"id":"{{.JSONBody "id"}"}`},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "forward transform 1 body: template: body:1: unexpected \"}\" in operand",
+		},
+		{
+			name: "Tunnel requires a secret",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Tunnel:  TunnelConfig{Enable: true},
+			},
+			expectError: true,
+			errorMsg:    "tunnel secret cannot be empty",
+		},
+		{
+			name: "Health probes need distinct paths",
+			config: &Config{
+				Server: ServerConfig{
+					Port:      8080,
+					Path:      "/",
+					Responses: defaultResponses(),
+				},
+				Log:     LogConfig{Level: "info"},
+				Forward: ForwardConfig{MaxConcurrent: 1},
+				Health:  HealthConfig{Enable: true, LivenessPath: "/health", ReadinessPath: "/health"},
+			},
+			expectError: true,
+			errorMsg:    "health liveness_path and readiness_path must differ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config != nil && tt.config.Storage.Driver == "" && tt.config.Storage.Path == "" && tt.config.Storage.MaxRecords == 0 && tt.config.Storage.Retention == 0 {
+				tt.config.Storage = StorageConfig{
+					Driver:     "sqlite",
+					Path:       "./reqtap.db",
+					MaxRecords: 1000,
+				}
+			}
+			err := tt.config.Validate()
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error containing '%s', but got no error", tt.errorMsg)
+				} else if tt.errorMsg != "" && err.Error() != tt.errorMsg && !contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.errorMsg, err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error, but got: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithFile(t *testing.T) {
+	// Create temporary configuration file
+	configContent := `
+server:
+  port: 9999
+  path: "/test"
+  responses:
+    - name: "custom"
+      methods: ["POST"]
+      path: "/test"
+      status: 202
+      body: '{"status":"queued"}'
+      headers:
+        Content-Type: application/json
+
+log:
+  level: "debug"
+  file_logging:
+    enable: true
+    path: "/tmp/test.log"
+    max_size_mb: 5
+    max_backups: 3
+    max_age_days: 7
+    compress: false
+
+forward:
+  urls:
+    - "http://localhost:4000"
+    - "https://api.example.com"
+  timeout: 60
+  max_retries: 5
+  max_concurrent: 20
+  latency_budget: 20s
+  expectations:
+    max_latency: 2s
+    body_contains: ["accepted"]
+  path_strategy:
+    mode: "strip_prefix"
+    strip_prefix: "/test"
+  targets:
+    - url: "https://api.example.com"
+      latency_budget: 1500ms
+      expect:
+        status: [200, 202]
+        json:
+          - path: "status"
+            equals: "ok"
+
+output:
+  mode: json
+  silence: true
+`
+
+	// Create temporary file
+	tmpFile, err := os.CreateTemp("", "reqtap_test_config_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	// Write configuration content
+	if _, err := tmpFile.WriteString(configContent); err != nil {
+		t.Fatalf("Failed to write config content: %v", err)
+	}
+	tmpFile.Close()
+
+	// Load configuration
+	cfg, err := LoadConfig(tmpFile.Name(), nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Verify configuration values
+	if cfg.Server.Port != 9999 {
+		t.Errorf("Expected port 9999, got %d", cfg.Server.Port)
+	}
+
+	if cfg.Server.Path != "/test" {
+		t.Errorf("Expected path '/test', got %s", cfg.Server.Path)
 	}
 
 	if len(cfg.Server.Responses) != 1 {
@@ -552,6 +1697,20 @@ output:
 		t.Errorf("Expected 2 forward URLs, got %d", len(cfg.Forward.URLs))
 	}
 
+	targets := cfg.Forward.ResolvedTargets()
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 resolved forward targets, got %d", len(targets))
+	}
+	if targets[1].URL != "https://api.example.com" || len(targets[1].Expect.Status) != 2 || len(targets[1].Expect.JSON) != 1 {
+		t.Errorf("Unexpected detailed forward target: %+v", targets[1])
+	}
+	if targets[0].LatencyBudget != 20*time.Second || targets[1].LatencyBudget != 1500*time.Millisecond {
+		t.Errorf("Unexpected latency budgets: %v, %v", targets[0].LatencyBudget, targets[1].LatencyBudget)
+	}
+	if targets[0].Expect.MaxLatency != 2*time.Second || len(targets[0].Expect.BodyContains) != 1 || targets[1].Expect.MaxLatency != 0 {
+		t.Errorf("Expected forward.expectations only for targets without an expect: %+v, %+v", targets[0].Expect, targets[1].Expect)
+	}
+
 	if cfg.Forward.Timeout != 60 {
 		t.Errorf("Expected forward timeout 60, got %d", cfg.Forward.Timeout)
 	}
@@ -569,6 +1728,22 @@ output:
 	}
 }
 
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: 9999\nlog:\n  level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REQTAP_SERVER_PORT", "7000")
+
+	cfg, err := LoadConfig(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Server.Port != 7000 || cfg.Log.Level != "debug" {
+		t.Errorf("Expected REQTAP_SERVER_PORT to override the file, got port=%d level=%s", cfg.Server.Port, cfg.Log.Level)
+	}
+}
+
 func TestLoadConfigInvalidFile(t *testing.T) {
 	// Test that non-existent configuration file should return error
 	cfg, err := LoadConfig("/nonexistent/path/config.yaml", nil)
@@ -580,6 +1755,23 @@ func TestLoadConfigInvalidFile(t *testing.T) {
 	}
 }
 
+func TestValidateMockRuleRejectsBodySources(t *testing.T) {
+	rule := ImmediateResponseConfig{Name: " outage ", Status: 503, Headers: map[string]string{"retry-after": "30"}}
+	if err := ValidateMockRule(&rule); err != nil || rule.Name != "outage" || rule.Headers["Retry-After"] != "30" {
+		t.Fatalf("expected a valid rule, got %#v (%v)", rule, err)
+	}
+
+	dir := t.TempDir()
+	for _, rule := range []ImmediateResponseConfig{
+		{Name: "file", Status: 200, BodyFile: filepath.Join(dir, "body.json")},
+		{Name: "url", Status: 200, BodyURL: "http://169.254.169.254/latest/meta-data/"},
+	} {
+		if err := ValidateMockRule(&rule); err == nil || !contains(err.Error(), "body_file or body_url") {
+			t.Fatalf("expected %s to be rejected, got %v", rule.Name, err)
+		}
+	}
+}
+
 // Helper function: check if string contains substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (len(substr) == 0 || indexOf(s, substr) >= 0)
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// RegisterFlags adds the command line flags that override configuration values to fs; FromFlags
+// applies the ones that were set.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.IntP("port", "p", 0, "Listen port")
+	fs.String("path", "", "URL path prefix to listen")
+	fs.Int64("max-body-bytes", 0, "Maximum request body size in bytes (0 for unlimited)")
+	fs.StringP("log-level", "l", "", "Log level (trace, debug, info, warn, error, fatal, panic)")
+	fs.Bool("log-file-enable", false, "Enable file logging")
+	fs.String("log-file-path", "", "Log file path")
+	fs.Int("log-file-max-size", 0, "Maximum size of a single log file (MB)")
+	fs.Int("log-file-max-backups", 0, "Maximum number of old log files to retain")
+	fs.Int("log-file-max-age", 0, "Maximum retention days for old log files")
+	fs.Bool("log-file-compress", false, "Whether to compress old log files")
+	fs.StringSliceP("forward-url", "f", []string{}, "Target URLs to forward")
+	fs.StringSlice("host-override", []string{}, "Connect to a fixed address for a forward target host, as host=IP:port (repeatable)")
+	fs.StringSlice("mock-preset", []string{}, "Answer a provider's webhook handshake with built-in mock rules (github, slack, stripe)")
+	fs.Bool("silence", false, "Suppress interactive console output")
+	fs.Bool("json", false, "Emit structured JSON output")
+	fs.Bool("tui", false, "Browse captured requests in an interactive terminal UI (logs go to the log file only)")
+	fs.String("locale", "", "Output locale (e.g. en, zh-CN)")
+	fs.String("theme", "", "Console color theme (default, solarized, mono)")
+	fs.Bool("no-color", false, "Print without terminal colors")
+	fs.Bool("status-line", false, "Show uptime, captures, req/s and forward errors on the last terminal row")
+	fs.String("output-file", "", "Also write the printed requests (console text or JSON lines) to this rotating file")
+	fs.Bool("body-view", false, "Enable structured body formatting in console mode")
+	fs.Int("body-preview-bytes", 0, "Maximum bytes to preview before truncating console body output")
+	fs.Bool("full-body", false, "Always print full request bodies, ignoring preview limits")
+	fs.String("body-filter", "", "Print only this path of JSON bodies (e.g. .data.id or $.items[0])")
+	fs.Bool("body-hex-preview", false, "Enable hexadecimal preview for binary bodies")
+	fs.Int("body-hex-preview-bytes", 0, "Limit for hexadecimal preview bytes (0 keeps config value)")
+	fs.Bool("body-save-binary", false, "Persist binary bodies to disk when enabled")
+	fs.String("body-save-directory", "", "Directory to persist binary bodies (requires --body-save-binary)")
+
+	fs.String("storage-driver", "", "Storage driver (sqlite, bolt or plugin)")
+	fs.String("storage-path", "", "Storage database file path")
+	fs.Int("storage-max-records", 0, "Maximum records persisted (0 keeps config value)")
+	fs.String("storage-retention", "", "Retention duration (e.g. 168h); empty disables")
+
+	// Web console configuration flags
+	fs.Bool("web-enable", false, "Enable/disable web console")
+	fs.String("web-path", "", "Web UI access path")
+	fs.String("web-admin-path", "", "Web admin API path")
+	fs.Int("web-max-requests", 0, "Maximum number of requests to retain in memory")
+	fs.Bool("web-auth-enable", false, "Enable/disable web console authentication")
+	fs.String("web-auth-session-timeout", "", "Web console session timeout duration")
+	fs.Bool("web-open", false, "Open the one-time web console login link in the default browser")
+	fs.Bool("web-export-enable", false, "Enable/disable web console data export")
+	fs.StringSlice("web-export-formats", []string{}, "Supported export formats for web console")
+}
+
+// FromFlags returns an Option applying the flags of RegisterFlags that were set on the command
+// line, which take precedence over the config file and the environment.
+func FromFlags(fs *pflag.FlagSet) Option {
+	return func(cfg *Config) {
+		// Override with command line arguments (command line has highest priority)
+		// This ensures command line flags override config file values
+		if port, err := fs.GetInt("port"); err == nil && port != 0 {
+			cfg.Server.Port = port
+		}
+		if path, err := fs.GetString("path"); err == nil && path != "" {
+			cfg.Server.Path = path
+		}
+		if fs.Changed("max-body-bytes") {
+			if maxBodyBytes, err := fs.GetInt64("max-body-bytes"); err == nil {
+				cfg.Server.MaxBodyBytes = maxBodyBytes
+			}
+		}
+		if logLevel, err := fs.GetString("log-level"); err == nil && logLevel != "" {
+			cfg.Log.Level = logLevel
+		}
+		if logFileEnable, err := fs.GetBool("log-file-enable"); err == nil && fs.Changed("log-file-enable") {
+			cfg.Log.FileLogging.Enable = logFileEnable
+		}
+		if logFilePath, err := fs.GetString("log-file-path"); err == nil && logFilePath != "" {
+			cfg.Log.FileLogging.Path = logFilePath
+		}
+		if logFileSize, err := fs.GetInt("log-file-max-size"); err == nil && logFileSize != 0 {
+			cfg.Log.FileLogging.MaxSizeMB = logFileSize
+		}
+		if logFileBackups, err := fs.GetInt("log-file-max-backups"); err == nil && logFileBackups != 0 {
+			cfg.Log.FileLogging.MaxBackups = logFileBackups
+		}
+		if logFileAge, err := fs.GetInt("log-file-max-age"); err == nil && logFileAge != 0 {
+			cfg.Log.FileLogging.MaxAgeDays = logFileAge
+		}
+		if logFileCompress, err := fs.GetBool("log-file-compress"); err == nil && fs.Changed("log-file-compress") {
+			cfg.Log.FileLogging.Compress = logFileCompress
+		}
+		if forwardURLs, err := fs.GetStringSlice("forward-url"); err == nil && len(forwardURLs) > 0 {
+			cfg.Forward.URLs = forwardURLs
+		}
+		if overrides, err := fs.GetStringSlice("host-override"); err == nil && len(overrides) > 0 {
+			cfg.Forward.HostOverrides = make([]ForwardHostOverrideConfig, 0, len(overrides))
+			for _, override := range overrides {
+				host, address, _ := strings.Cut(override, "=")
+				cfg.Forward.HostOverrides = append(cfg.Forward.HostOverrides, ForwardHostOverrideConfig{Host: host, Address: address})
+			}
+		}
+		if presets, err := fs.GetStringSlice("mock-preset"); err == nil && len(presets) > 0 {
+			cfg.Server.MockPresets = presets
+		}
+		if locale, err := fs.GetString("locale"); err == nil && strings.TrimSpace(locale) != "" {
+			cfg.Output.Locale = strings.TrimSpace(locale)
+		}
+
+		// Override with web console command line arguments (command line has highest priority)
+		if webEnable, err := fs.GetBool("web-enable"); err == nil && fs.Changed("web-enable") {
+			cfg.Web.Enable = webEnable
+		}
+		if webPath, err := fs.GetString("web-path"); err == nil && webPath != "" {
+			cfg.Web.Path = webPath
+		}
+		if webAdminPath, err := fs.GetString("web-admin-path"); err == nil && webAdminPath != "" {
+			cfg.Web.AdminPath = webAdminPath
+		}
+		if webMaxRequests, err := fs.GetInt("web-max-requests"); err == nil && webMaxRequests != 0 {
+			cfg.Web.MaxRequests = webMaxRequests
+		}
+		if webAuthEnable, err := fs.GetBool("web-auth-enable"); err == nil && fs.Changed("web-auth-enable") {
+			cfg.Web.Auth.Enable = webAuthEnable
+		}
+		if webAuthSessionTimeout, err := fs.GetString("web-auth-session-timeout"); err == nil && webAuthSessionTimeout != "" {
+			if timeout, err := time.ParseDuration(webAuthSessionTimeout); err == nil {
+				cfg.Web.Auth.SessionTimeout = timeout
+			}
+		}
+		if webOpen, err := fs.GetBool("web-open"); err == nil && fs.Changed("web-open") {
+			cfg.Web.Auth.LoginLink.OpenBrowser = webOpen
+		}
+		if webExportEnable, err := fs.GetBool("web-export-enable"); err == nil && fs.Changed("web-export-enable") {
+			cfg.Web.Export.Enable = webExportEnable
+		}
+		if webExportFormats, err := fs.GetStringSlice("web-export-formats"); err == nil && len(webExportFormats) > 0 {
+			cfg.Web.Export.Formats = webExportFormats
+		}
+
+		if fs.Changed("silence") {
+			if silence, err := fs.GetBool("silence"); err == nil {
+				cfg.Output.Silence = silence
+			}
+		}
+		if jsonOutput, err := fs.GetBool("json"); err == nil && jsonOutput {
+			cfg.Output.Mode = "json"
+		}
+		if tuiOutput, err := fs.GetBool("tui"); err == nil && tuiOutput {
+			cfg.Output.Mode = "tui"
+		}
+		if theme, err := fs.GetString("theme"); err == nil && theme != "" {
+			cfg.Output.Theme = theme
+		}
+		if fs.Changed("no-color") {
+			if noColor, err := fs.GetBool("no-color"); err == nil {
+				cfg.Output.NoColor = noColor
+			}
+		}
+		if fs.Changed("status-line") {
+			if statusLine, err := fs.GetBool("status-line"); err == nil {
+				cfg.Output.StatusLine = statusLine
+			}
+		}
+		if fs.Changed("output-file") {
+			if file, err := fs.GetString("output-file"); err == nil {
+				cfg.Output.File = file
+			}
+		}
+		if fs.Changed("body-view") {
+			if bodyView, err := fs.GetBool("body-view"); err == nil {
+				cfg.Output.BodyView.Enable = bodyView
+			}
+		}
+		if fs.Changed("body-preview-bytes") {
+			if preview, err := fs.GetInt("body-preview-bytes"); err == nil {
+				cfg.Output.BodyView.MaxPreviewBytes = preview
+			}
+		}
+		if fs.Changed("full-body") {
+			if fullBody, err := fs.GetBool("full-body"); err == nil {
+				cfg.Output.BodyView.FullBody = fullBody
+			}
+		}
+		if fs.Changed("body-filter") {
+			if filter, err := fs.GetString("body-filter"); err == nil {
+				cfg.Output.BodyFilter = filter
+			}
+		}
+		if fs.Changed("body-hex-preview") {
+			if hexPreview, err := fs.GetBool("body-hex-preview"); err == nil {
+				cfg.Output.BodyView.Binary.HexPreviewEnable = hexPreview
+			}
+		}
+		if fs.Changed("body-hex-preview-bytes") {
+			if bytes, err := fs.GetInt("body-hex-preview-bytes"); err == nil {
+				cfg.Output.BodyView.Binary.HexPreviewBytes = bytes
+			}
+		}
+		if fs.Changed("body-save-binary") {
+			if save, err := fs.GetBool("body-save-binary"); err == nil {
+				cfg.Output.BodyView.Binary.SaveToFile = save
+			}
+		}
+		if fs.Changed("body-save-directory") {
+			if dir, err := fs.GetString("body-save-directory"); err == nil {
+				cfg.Output.BodyView.Binary.SaveDirectory = dir
+			}
+		}
+		if storageDriver, err := fs.GetString("storage-driver"); err == nil && storageDriver != "" {
+			cfg.Storage.Driver = storageDriver
+		}
+		if storagePath, err := fs.GetString("storage-path"); err == nil && storagePath != "" {
+			cfg.Storage.Path = storagePath
+		}
+		if fs.Changed("storage-max-records") {
+			if maxRecords, err := fs.GetInt("storage-max-records"); err == nil {
+				cfg.Storage.MaxRecords = maxRecords
+			}
+		}
+		if storageRetention, err := fs.GetString("storage-retention"); err == nil && storageRetention != "" {
+			if retention, err := time.ParseDuration(storageRetention); err == nil {
+				cfg.Storage.Retention = retention
+			}
+		}
+	}
+}
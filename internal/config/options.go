@@ -0,0 +1,97 @@
+package config
+
+import "time"
+
+// Option adjusts a configuration built in code, see New.
+type Option func(*Config)
+
+// New returns the built-in configuration with opts applied in order, without reading a config
+// file, the environment or the global viper instance. The result is not validated; call
+// Validate before using it.
+func New(opts ...Option) (*Config, error) {
+	cfg, err := Defaults()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Apply(opts...)
+	return cfg, nil
+}
+
+// Apply applies opts to c in order.
+func (c *Config) Apply(opts ...Option) {
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+}
+
+// WithPort sets the listen port; 0 picks a free port.
+func WithPort(port int) Option {
+	return func(c *Config) {
+		c.Server.Port = port
+	}
+}
+
+// WithPath sets the capture path prefix.
+func WithPath(path string) Option {
+	return func(c *Config) {
+		c.Server.Path = path
+	}
+}
+
+// WithMaxBodyBytes limits the captured body size; 0 is unlimited.
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *Config) {
+		c.Server.MaxBodyBytes = n
+	}
+}
+
+// WithLogLevel sets the log level.
+func WithLogLevel(level string) Option {
+	return func(c *Config) {
+		c.Log.Level = level
+	}
+}
+
+// WithForwardURLs relays every captured request to urls.
+func WithForwardURLs(urls ...string) Option {
+	return func(c *Config) {
+		c.Forward.URLs = urls
+	}
+}
+
+// WithOutputMode sets the output mode (console, json or tui).
+func WithOutputMode(mode string) Option {
+	return func(c *Config) {
+		c.Output.Mode = mode
+	}
+}
+
+// WithSilence turns the interactive console output off or on.
+func WithSilence(silence bool) Option {
+	return func(c *Config) {
+		c.Output.Silence = silence
+	}
+}
+
+// WithWeb turns the web console on or off.
+func WithWeb(enable bool) Option {
+	return func(c *Config) {
+		c.Web.Enable = enable
+	}
+}
+
+// WithStoragePath sets the sqlite database file.
+func WithStoragePath(path string) Option {
+	return func(c *Config) {
+		c.Storage.Path = path
+	}
+}
+
+// WithStorageRetention drops stored requests older than d; 0 keeps them.
+func WithStorageRetention(d time.Duration) Option {
+	return func(c *Config) {
+		c.Storage.Retention = d
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestNew(t *testing.T) {
+	cfg, err := New(WithPort(9090), WithForwardURLs("http://localhost:4000"), WithWeb(false), WithStorageRetention(time.Hour))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Server.Port != 9090 || len(cfg.Forward.URLs) != 1 || cfg.Web.Enable || cfg.Storage.Retention != time.Hour {
+		t.Fatalf("expected the options to be applied, got %+v", cfg.Server)
+	}
+	// Everything else keeps the built-in defaults, switches included
+	if cfg.Log.Level != "info" || cfg.Forward.MaxConcurrent != 10 || !cfg.Output.BodyView.Json.Enable || !cfg.Web.Export.Enable {
+		t.Fatal("expected the remaining settings to keep their defaults")
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{Port: 7070},
+		Web:    WebConfig{Enable: true, Path: "/console"},
+	}
+	cfg.ApplyDefaults()
+	if cfg.Server.Port != 7070 || cfg.Web.Path != "/console" || !cfg.Web.Enable {
+		t.Fatal("expected the values set in code to be kept")
+	}
+	if cfg.Server.Path != "/reqtap" || cfg.Web.AdminPath != "/api" || cfg.Storage.Driver != "sqlite" || cfg.Forward.Timeout != 30 {
+		t.Fatalf("expected zero values to be filled in, got %+v", cfg.Server)
+	}
+	if cfg.Web.Auth.SessionTimeout != 24*time.Hour || len(cfg.Forward.HeaderBlacklist) == 0 {
+		t.Fatal("expected nested zero values to be filled in")
+	}
+	if cfg.Web.Export.Enable {
+		t.Fatal("expected bool fields to be left alone")
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+}
+
+func TestFromFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("reqtap", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	err := fs.Parse([]string{"-p", "9000", "--web-enable=false", "--body-filter", ".data.id", "--storage-retention", "48h", "-f", "http://a", "-f", "http://b"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	cfg, err := New(WithPath("/hooks"), FromFlags(fs))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Server.Port != 9000 || cfg.Web.Enable || cfg.Output.BodyFilter != ".data.id" || cfg.Storage.Retention != 48*time.Hour {
+		t.Fatal("expected the flags on the command line to override the config")
+	}
+	if len(cfg.Forward.URLs) != 2 || cfg.Forward.URLs[1] != "http://b" {
+		t.Fatalf("unexpected forward urls: %v", cfg.Forward.URLs)
+	}
+	// Flags left out keep the configured values
+	if cfg.Server.Path != "/hooks" || !cfg.Web.Auth.Enable || cfg.Log.Level != "info" {
+		t.Fatal("expected unset flags to keep the configured values")
+	}
+}
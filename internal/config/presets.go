@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MockPreset is a ready-made set of server.responses rules answering the verification and delivery
+// handshake of a webhook provider
+type MockPreset struct {
+	Name        string
+	Description string
+	Rules       []ImmediateResponseConfig
+}
+
+// MockPresets lists the preset library ordered by name; every call returns fresh rules, so callers
+// may change them
+func MockPresets() []MockPreset {
+	jsonHeaders := func() map[string]string {
+		return map[string]string{"Content-Type": "application/json"}
+	}
+	return []MockPreset{
+		{
+			Name:        "github",
+			Description: "Answer the ping sent when a GitHub webhook is created and acknowledge other events",
+			Rules: []ImmediateResponseConfig{
+				{
+					Name:        "preset-github-ping",
+					Methods:     []string{http.MethodPost},
+					Status:      http.StatusOK,
+					Body:        `{"msg":"pong"}`,
+					Headers:     jsonHeaders(),
+					HeaderMatch: []MatchConditionConfig{{Name: "X-GitHub-Event", Equals: "ping"}},
+				},
+				{
+					Name:        "preset-github-event",
+					Methods:     []string{http.MethodPost},
+					Status:      http.StatusOK,
+					Body:        `{"received":true}`,
+					Headers:     jsonHeaders(),
+					HeaderMatch: []MatchConditionConfig{{Name: "X-GitHub-Event"}},
+				},
+			},
+		},
+		{
+			Name:        "slack",
+			Description: "Echo the challenge of Slack's url_verification request and acknowledge other events",
+			Rules: []ImmediateResponseConfig{
+				{
+					Name:      "preset-slack-url-verification",
+					Methods:   []string{http.MethodPost},
+					Status:    http.StatusOK,
+					Body:      `{{.JSONBody "challenge"}}`,
+					Template:  true,
+					Headers:   map[string]string{"Content-Type": "text/plain"},
+					BodyMatch: []MatchConditionConfig{{JSONPath: "type", Equals: "url_verification"}},
+				},
+				{
+					Name:        "preset-slack-event",
+					Methods:     []string{http.MethodPost},
+					Status:      http.StatusOK,
+					HeaderMatch: []MatchConditionConfig{{Name: "X-Slack-Signature"}},
+				},
+			},
+		},
+		{
+			Name:        "stripe",
+			Description: "Acknowledge signed Stripe events with 200 and a JSON body",
+			Rules: []ImmediateResponseConfig{
+				{
+					Name:        "preset-stripe-event",
+					Methods:     []string{http.MethodPost},
+					Status:      http.StatusOK,
+					Body:        `{"received":true}`,
+					Headers:     jsonHeaders(),
+					HeaderMatch: []MatchConditionConfig{{Name: "Stripe-Signature"}},
+				},
+			},
+		},
+	}
+}
+
+// LookupMockPreset returns the preset of that name, ignoring case.
+func LookupMockPreset(name string) (MockPreset, bool) {
+	for _, preset := range MockPresets() {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return preset, true
+		}
+	}
+	return MockPreset{}, false
+}
+
+// MockPresetNames lists the names of the preset library.
+func MockPresetNames() []string {
+	presets := MockPresets()
+	names := make([]string, 0, len(presets))
+	for _, preset := range presets {
+		names = append(names, preset.Name)
+	}
+	return names
+}
+
+// ResponseRules lists the rules of the mock presets followed by server.responses, in match order.
+// Preset rules only match their provider's requests, so they go before a catch-all response.
+func (c ServerConfig) ResponseRules() []ImmediateResponseConfig {
+	if len(c.MockPresets) == 0 {
+		return c.Responses
+	}
+	var rules []ImmediateResponseConfig
+	for _, name := range c.MockPresets {
+		if preset, ok := LookupMockPreset(name); ok {
+			rules = append(rules, preset.Rules...)
+		}
+	}
+	return append(rules, c.Responses...)
+}
+
+// validateMockPresets checks that every server.mock_presets entry names a preset of the library
+func validateMockPresets(names []string) error {
+	for _, name := range names {
+		if _, ok := LookupMockPreset(name); !ok {
+			return fmt.Errorf("unknown server mock preset %q (available: %s)", name, strings.Join(MockPresetNames(), ", "))
+		}
+	}
+	return nil
+}
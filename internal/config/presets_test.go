@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMockPresets(t *testing.T) {
+	for _, preset := range MockPresets() {
+		for i := range preset.Rules {
+			if err := ValidateMockRule(&preset.Rules[i]); err != nil {
+				t.Fatalf("preset %s rule %s is invalid: %v", preset.Name, preset.Rules[i].Name, err)
+			}
+		}
+	}
+
+	server := ServerConfig{Responses: defaultResponses(), MockPresets: []string{"stripe", "slack"}}
+	var names []string
+	for _, rule := range server.ResponseRules() {
+		names = append(names, rule.Name)
+	}
+	want := []string{"preset-stripe-event", "preset-slack-url-verification", "preset-slack-event", "default"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected rules %v, got %v", want, names)
+	}
+
+	// Changing the rules of one lookup must not leak into the library
+	preset, _ := LookupMockPreset("stripe")
+	preset.Rules[0].Headers["Content-Type"] = "text/plain"
+	if again, _ := LookupMockPreset("stripe"); again.Rules[0].Headers["Content-Type"] != "application/json" {
+		t.Fatal("preset rules are shared between lookups")
+	}
+}
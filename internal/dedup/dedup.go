@@ -0,0 +1,116 @@
+// Package dedup recognizes re-deliveries of a request that was already captured.
+//
+// Every request gets a fingerprint: the value of the configured idempotency header when the
+// request carries it, otherwise a hash of its method, path and body. A request whose fingerprint
+// was first seen less than Window ago is a duplicate of that first request.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Options tunes the detector; zero values fall back to the config defaults.
+type Options struct {
+	// Header is the idempotency header; empty fingerprints every request by method, path and body
+	Header string
+	Window time.Duration
+}
+
+// Detector remembers the first request of each fingerprint; it is safe for concurrent use.
+type Detector struct {
+	mu        sync.Mutex
+	header    string
+	window    time.Duration
+	seen      map[string]first
+	lastSweep time.Time
+}
+
+type first struct {
+	id string
+	at time.Time
+}
+
+// New creates a detector.
+func New(opts Options) *Detector {
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Minute
+	}
+	return &Detector{
+		header: http.CanonicalHeaderKey(opts.Header),
+		window: opts.Window,
+		seen:   make(map[string]first),
+	}
+}
+
+// Fingerprint identifies data: "key:" and the hashed idempotency header value when data carries
+// the header, otherwise "body:" and a hash of the method, path and body as received.
+func (d *Detector) Fingerprint(data *request.RequestData) (string, error) {
+	hash := sha256.New()
+	if d.header != "" {
+		if key := data.Headers.Get(d.header); key != "" {
+			io.WriteString(hash, key)
+			return "key:" + hex.EncodeToString(hash.Sum(nil)[:16]), nil
+		}
+	}
+	io.WriteString(hash, data.Method+"\n"+data.Path+"\n")
+	body, _, err := data.OpenBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", err
+	}
+	return "body:" + hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
+
+// Check fingerprints data and sets data.Fingerprint, plus data.DuplicateOf when the fingerprint
+// was first seen within the window. It reports whether data is a duplicate.
+func (d *Detector) Check(data *request.RequestData) (bool, error) {
+	fingerprint, err := d.Fingerprint(data)
+	if err != nil {
+		return false, err
+	}
+	data.Fingerprint = fingerprint
+	at := data.Timestamp
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep(at)
+	if prev, ok := d.seen[fingerprint]; ok && at.Sub(prev.at) < d.window {
+		data.DuplicateOf = prev.id
+		return true, nil
+	}
+	d.seen[fingerprint] = first{id: data.ID, at: at}
+	return false, nil
+}
+
+// Forget drops fingerprint when id is the request it was first seen with, so that a request that
+// was never recorded does not make its next delivery a duplicate.
+func (d *Detector) Forget(fingerprint, id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if prev, ok := d.seen[fingerprint]; ok && prev.id == id {
+		delete(d.seen, fingerprint)
+	}
+}
+
+// sweep forgets the fingerprints whose window has passed, at most once per window.
+func (d *Detector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for fingerprint, prev := range d.seen {
+		if now.Sub(prev.at) >= d.window {
+			delete(d.seen, fingerprint)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package dedup
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func newRequest(id, path, body string, at time.Time, headers http.Header) *request.RequestData {
+	return &request.RequestData{ID: id, Timestamp: at, Method: http.MethodPost, Path: path, Body: []byte(body), Headers: headers}
+}
+
+func TestDetectorBodyFingerprint(t *testing.T) {
+	d := New(Options{Window: time.Minute})
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newRequest("a", "/hook", `{"id":1}`, base, http.Header{})
+	if dup, err := d.Check(first); err != nil || dup || first.Fingerprint == "" {
+		t.Fatalf("expected the first request to be fingerprinted and kept, got %v %v", dup, err)
+	}
+	again := newRequest("b", "/hook", `{"id":1}`, base.Add(30*time.Second), http.Header{})
+	if dup, _ := d.Check(again); !dup || again.DuplicateOf != "a" || again.Fingerprint != first.Fingerprint {
+		t.Fatalf("expected a re-delivery to be a duplicate of a, got %q", again.DuplicateOf)
+	}
+	other := newRequest("c", "/other", `{"id":1}`, base.Add(40*time.Second), http.Header{})
+	if dup, _ := d.Check(other); dup {
+		t.Fatal("expected a different path to change the fingerprint")
+	}
+	late := newRequest("d", "/hook", `{"id":1}`, base.Add(2*time.Minute), http.Header{})
+	if dup, _ := d.Check(late); dup {
+		t.Fatal("expected a copy after the window to start over")
+	}
+	if dup, _ := d.Check(newRequest("e", "/hook", `{"id":1}`, base.Add(150*time.Second), http.Header{})); !dup {
+		t.Fatal("expected the late copy to become the new first request")
+	}
+}
+
+func TestDetectorIdempotencyHeader(t *testing.T) {
+	d := New(Options{Header: "idempotency-key", Window: time.Hour})
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newRequest("a", "/hook", `{"attempt":1}`, base, http.Header{"Idempotency-Key": {"evt_1"}})
+	d.Check(first)
+	retry := newRequest("b", "/hook", `{"attempt":2}`, base.Add(time.Minute), http.Header{"Idempotency-Key": {"evt_1"}})
+	if dup, _ := d.Check(retry); !dup || retry.DuplicateOf != "a" {
+		t.Fatal("expected the same idempotency key to mark a duplicate despite a different body")
+	}
+	next := newRequest("c", "/hook", `{"attempt":1}`, base.Add(2*time.Minute), http.Header{"Idempotency-Key": {"evt_2"}})
+	if dup, _ := d.Check(next); dup {
+		t.Fatal("expected a new idempotency key to be a new request")
+	}
+	// Requests without the header fall back to the body
+	plain := newRequest("d", "/hook", `{"attempt":1}`, base.Add(3*time.Minute), http.Header{})
+	if dup, _ := d.Check(plain); dup || plain.Fingerprint[:5] != "body:" {
+		t.Fatalf("expected a body fingerprint, got %q", plain.Fingerprint)
+	}
+}
+
+func TestDetectorForget(t *testing.T) {
+	d := New(Options{Window: time.Minute})
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newRequest("a", "/hook", `{"id":1}`, base, http.Header{})
+	d.Check(first)
+	d.Forget(first.Fingerprint, "b")
+	if dup, _ := d.Check(newRequest("b", "/hook", `{"id":1}`, base.Add(time.Second), http.Header{})); !dup {
+		t.Fatal("expected forgetting another request to keep the first one")
+	}
+	d.Forget(first.Fingerprint, "a")
+	again := newRequest("c", "/hook", `{"id":1}`, base.Add(2*time.Second), http.Header{})
+	if dup, _ := d.Check(again); dup {
+		t.Fatalf("expected a forgotten request not to count, got a duplicate of %q", again.DuplicateOf)
+	}
+}
@@ -0,0 +1,136 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttleDelay returns how long deliveries to the target are held back after a failed attempt:
+// the Retry-After of a 429 or 503, or the next backoff for a 429 without one. A 503 without
+// Retry-After only delays the retry of its own request.
+func throttleDelay(outcome forwardOutcome, policy RetryPolicy, attempt int) (time.Duration, bool) {
+	if outcome.statusCode != http.StatusTooManyRequests && outcome.statusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	if delay, ok := parseRetryAfter(outcome.headers.Get("Retry-After"), time.Now()); ok {
+		return delay, true
+	}
+	if outcome.statusCode == http.StatusTooManyRequests {
+		return policy.backoff(attempt + 1), true
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a Retry-After header, either delay seconds or an HTTP date. A date in the
+// past means retry now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// backoffRegistry holds off every delivery to a throttled target, not only the retries of the
+// request that got the answer
+type backoffRegistry struct {
+	mu      sync.Mutex
+	targets map[string]*targetBackoff
+}
+
+type targetBackoff struct {
+	until     time.Time
+	throttled uint64
+}
+
+func newBackoffRegistry() *backoffRegistry {
+	return &backoffRegistry{targets: make(map[string]*targetBackoff)}
+}
+
+// hold records a throttled response of url and keeps deliveries to it back for delay; an earlier
+// longer hold is kept. It returns when deliveries resume.
+func (r *backoffRegistry) hold(url string, delay time.Duration) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.targets[url]
+	if entry == nil {
+		entry = &targetBackoff{}
+		r.targets[url] = entry
+	}
+	entry.throttled++
+	if until := time.Now().Add(delay); until.After(entry.until) {
+		entry.until = until
+	}
+	return entry.until
+}
+
+// wait blocks while url is held back. It fails at once when the hold outlasts the deadline of ctx,
+// returning the remaining hold so the delivery can be queued for later instead.
+func (r *backoffRegistry) wait(ctx context.Context, url string) (time.Duration, error) {
+	r.mu.Lock()
+	var until time.Time
+	if entry := r.targets[url]; entry != nil {
+		until = entry.until
+	}
+	r.mu.Unlock()
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && until.After(deadline) {
+		return remaining, fmt.Errorf("target asked to back off until %s, past the forward deadline", until.Format(time.RFC3339))
+	}
+
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return 0, nil
+	case <-ctx.Done():
+		return remaining, ctx.Err()
+	}
+}
+
+// annotate adds the throttled response counts and current holds to stats
+func (r *backoffRegistry) annotate(stats []TargetStats) []TargetStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	index := make(map[string]bool, len(stats))
+	for _, entry := range stats {
+		index[entry.URL] = true
+	}
+	for url := range r.targets {
+		if !index[url] {
+			stats = append(stats, TargetStats{URL: url})
+		}
+	}
+	sortTargetStats(stats)
+	now := time.Now()
+	for i := range stats {
+		entry := r.targets[stats[i].URL]
+		if entry == nil {
+			continue
+		}
+		stats[i].Throttled = entry.throttled
+		if entry.until.After(now) {
+			until := entry.until.UTC()
+			stats[i].BackoffUntil = &until
+		}
+	}
+	return stats
+}
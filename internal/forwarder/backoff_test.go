@@ -0,0 +1,122 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestForwardHonorsRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var hits []time.Time
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, time.Now())
+		first := len(hits) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer limited.Close()
+
+	// The default backoff would retry after 10ms; the target asked for a second
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 2, Retries: 2, Timeout: time.Second})
+	defer f.Close()
+	target := Target{URL: limited.URL, Retry: &RetryPolicy{MaxRetries: -1, Backoff: 10 * time.Millisecond}}
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/"}
+	var wg sync.WaitGroup
+	var first []Result
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first, _ = f.Forward(context.Background(), data, []Target{target})
+	}()
+	// A delivery of another request waits for the hold as well
+	time.Sleep(100 * time.Millisecond)
+	if stats := f.Stats(); len(stats) != 1 || stats[0].BackoffUntil == nil || stats[0].Throttled != 1 {
+		t.Fatalf("expected the hold in the target stats, got %+v", stats)
+	}
+	second, _ := f.Forward(context.Background(), data, []Target{target})
+	wg.Wait()
+
+	if !first[0].Success || first[0].Attempts != 2 || !second[0].Success || second[0].Attempts != 1 {
+		t.Fatalf("expected both deliveries to succeed after the hold, got %+v and %+v", first, second)
+	}
+	if len(hits) != 3 {
+		t.Fatalf("expected 3 hits, got %d", len(hits))
+	}
+	for _, hit := range hits[1:] {
+		if gap := hit.Sub(hits[0]); gap < 900*time.Millisecond {
+			t.Fatalf("expected deliveries to wait for Retry-After, one came after %s", gap)
+		}
+	}
+	stats := f.Stats()
+	if len(stats) != 1 || stats[0].Throttled != 1 || stats[0].BackoffUntil != nil {
+		t.Fatalf("expected one throttled answer and no hold left, got %+v", stats)
+	}
+
+	// A hold past the forward deadline fails at once and reports what is left of it
+	f.backoffs.hold(limited.URL, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	started := time.Now()
+	results, _ := f.Forward(ctx, data, []Target{target})
+	if results[0].Success || results[0].RetryAfter < 50*time.Second || time.Since(started) > 500*time.Millisecond {
+		t.Fatalf("expected the delivery to give up on the hold, got %+v after %s", results[0], time.Since(started))
+	}
+}
+
+func TestForwardRetryAfterReplacesBackoff(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		first := hits == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer limited.Close()
+
+	// Retry-After: 0 asks for an immediate retry, so the 2s backoff must not apply
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1, Timeout: 5 * time.Second})
+	defer f.Close()
+	target := Target{URL: limited.URL, Retry: &RetryPolicy{MaxRetries: 1, Backoff: 2 * time.Second, MaxBackoff: 2 * time.Second}}
+	started := time.Now()
+	results, _ := f.Forward(context.Background(), &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/"}, []Target{target})
+	if len(results) != 1 || !results[0].Success || results[0].Attempts != 2 {
+		t.Fatalf("expected the retry to succeed, got %+v", results)
+	}
+	if took := time.Since(started); took > time.Second {
+		t.Fatalf("expected the retry without the backoff, took %s", took)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"0":                             0,
+		"Fri, 02 Jan 2026 15:04:35 GMT": 30 * time.Second,
+		"Fri, 02 Jan 2026 15:00:00 GMT": 0,
+	} {
+		if got, ok := parseRetryAfter(value, now); !ok || got != want {
+			t.Fatalf("%q: expected %s, got %s (%v)", value, want, got, ok)
+		}
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(value, now); ok {
+			t.Fatalf("%q should not parse", value)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package forwarder
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+)
+
+// Expectation describes the response contract a forward target must honor.
+type Expectation struct {
+	// Status lists accepted status codes; empty accepts anything below 400.
+	Status []int
+	JSON   []JSONAssertion
+	// MaxLatency bounds how long an attempt may take until the response is read; 0 disables it.
+	MaxLatency time.Duration
+	// BodyContains lists substrings the response body must include.
+	BodyContains []string
+}
+
+// JSONAssertion checks a single field of the JSON response body.
+type JSONAssertion struct {
+	Path string
+	// Equals compares the rendered value; empty only requires the field to exist.
+	Equals string
+}
+
+// Check evaluates the contract against a response received after latency and returns human
+// readable violations.
+func (e *Expectation) Check(status int, body []byte, latency time.Duration) []string {
+	if e == nil {
+		return nil
+	}
+	var violations []string
+	if len(e.Status) > 0 {
+		if !containsStatus(e.Status, status) {
+			violations = append(violations, fmt.Sprintf("status %d not in %v", status, e.Status))
+		}
+	} else if status >= 400 {
+		violations = append(violations, fmt.Sprintf("target returned status %d", status))
+	}
+	if e.MaxLatency > 0 && latency > e.MaxLatency {
+		violations = append(violations, fmt.Sprintf("latency %s exceeds %s", latency.Round(time.Millisecond), e.MaxLatency))
+	}
+	for _, needle := range e.BodyContains {
+		if !bytes.Contains(body, []byte(needle)) {
+			violations = append(violations, fmt.Sprintf("response body does not contain %q", needle))
+		}
+	}
+
+	if len(e.JSON) == 0 {
+		return violations
+	}
+	doc, err := jsonpath.Decode(body)
+	if err != nil {
+		return append(violations, "response body is not valid JSON")
+	}
+	for _, assertion := range e.JSON {
+		value, ok := jsonpath.Lookup(doc, assertion.Path)
+		if !ok {
+			violations = append(violations, fmt.Sprintf("json path %s missing", assertion.Path))
+			continue
+		}
+		if assertion.Equals == "" {
+			continue
+		}
+		if got := jsonpath.Stringify(value); got != assertion.Equals {
+			violations = append(violations, fmt.Sprintf("json path %s = %s, expected %s", assertion.Path, got, assertion.Equals))
+		}
+	}
+	return violations
+}
+
+func containsStatus(list []int, status int) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestExpectationCheck(t *testing.T) {
+	expect := &Expectation{
+		Status: []int{200, 202},
+		JSON: []JSONAssertion{
+			{Path: "status", Equals: "queued"},
+			{Path: "id"},
+		},
+	}
+
+	if v := expect.Check(202, []byte(`{"status":"queued","id":1}`), 0); len(v) != 0 {
+		t.Fatalf("expected no violations, got %v", v)
+	}
+	if v := expect.Check(500, []byte(`{"status":"queued","id":1}`), 0); len(v) != 1 {
+		t.Fatalf("expected status violation, got %v", v)
+	}
+	if v := expect.Check(200, []byte(`{"status":"failed"}`), 0); len(v) != 2 {
+		t.Fatalf("expected equals and missing violations, got %v", v)
+	}
+	if v := expect.Check(200, []byte(`oops`), 0); len(v) != 1 {
+		t.Fatalf("expected invalid json violation, got %v", v)
+	}
+
+	monitor := &Expectation{MaxLatency: 100 * time.Millisecond, BodyContains: []string{"accepted"}}
+	if v := monitor.Check(200, []byte(`accepted`), 50*time.Millisecond); len(v) != 0 {
+		t.Fatalf("expected no violations, got %v", v)
+	}
+	if v := monitor.Check(200, []byte(`rejected`), 250*time.Millisecond); len(v) != 2 || v[0] != "latency 250ms exceeds 100ms" {
+		t.Fatalf("expected latency and body violations, got %v", v)
+	}
+}
+
+func TestForwardReportsContractViolations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"rejected"}`))
+	}))
+	defer srv.Close()
+
+	f := NewForwarder(noopLogger{}, Options{Retries: 0, MaxConcurrent: 2})
+	defer f.Close()
+
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/hook", Headers: http.Header{}}
+	results, err := f.Forward(context.Background(), data, []Target{
+		{URL: srv.URL},
+		{URL: srv.URL + "/strict", Expect: &Expectation{JSON: []JSONAssertion{{Path: "status", Equals: "ok"}}}},
+	})
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].Success || results[0].StatusCode != http.StatusOK {
+		t.Fatalf("expected plain target success, got %#v", results[0])
+	}
+	if results[0].Headers.Get("Content-Type") != "application/json" || string(results[0].Body) != `{"status":"rejected"}` {
+		t.Fatalf("expected target response to be captured, got %#v", results[0])
+	}
+	if results[1].Success || len(results[1].Violations) != 1 {
+		t.Fatalf("expected contract violation, got %#v", results[1])
+	}
+
+	stats := f.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 targets, got %d", len(stats))
+	}
+	for _, entry := range stats {
+		if entry.URL == srv.URL+"/strict" && entry.ContractViolations != 1 {
+			t.Fatalf("expected violation counted, got %#v", entry)
+		}
+	}
+}
+
+func TestForwardFlagsLatencyBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow") {
+			time.Sleep(60 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	f := NewForwarder(noopLogger{}, Options{Retries: 0, MaxConcurrent: 2})
+	defer f.Close()
+
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/hook", Headers: http.Header{}}
+	results, err := f.Forward(context.Background(), data, []Target{
+		{URL: srv.URL, LatencyBudget: time.Second},
+		{URL: srv.URL + "/slow", LatencyBudget: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if results[0].OverBudget || results[0].LatencyBudget != time.Second || results[0].Latency <= 0 {
+		t.Fatalf("expected fast target within budget, got %#v", results[0])
+	}
+	if !results[1].OverBudget || !results[1].Success || results[1].Latency < 60*time.Millisecond || results[1].Duration < results[1].Latency {
+		t.Fatalf("expected slow target to be flagged but delivered, got %#v", results[1])
+	}
+	for _, entry := range f.Stats() {
+		if entry.URL == srv.URL+"/slow" && entry.OverBudget != 1 {
+			t.Fatalf("expected over-budget delivery counted, got %#v", entry)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package forwarder
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Filter allows or denies forwarding requests that match all of its conditions.
+type Filter struct {
+	Name string
+	Deny bool
+	// Targets lists the target URLs the filter governs; empty governs every target.
+	Targets []string
+	Methods []string
+	Path    *regexp.Regexp
+	// Headers maps a header name to a pattern one of its values must match.
+	Headers      map[string]*regexp.Regexp
+	BodyContains []byte
+}
+
+// Match reports whether the request satisfies every condition of the filter.
+func (f *Filter) Match(data *request.RequestData) bool {
+	if len(f.Methods) > 0 && !containsFold(f.Methods, data.Method) {
+		return false
+	}
+	if f.Path != nil && !f.Path.MatchString(data.Path) {
+		return false
+	}
+	for name, pattern := range f.Headers {
+		matched := false
+		for _, value := range data.Headers.Values(name) {
+			if pattern.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.BodyContains) > 0 && !bytes.Contains(data.Body, f.BodyContains) {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) governs(url string) bool {
+	return len(f.Targets) == 0 || containsFold(f.Targets, url)
+}
+
+// ErrNoTargets reports that no configured target accepts the request.
+var ErrNoTargets = errors.New("no forward target matches the request")
+
+// SelectTargets applies the filters to every target and returns the targets the request may be
+// forwarded to, plus the URLs that were filtered out. For each target the first matching filter
+// decides; when none matches, the request is forwarded unless an allow filter governs the target.
+func SelectTargets(filters []Filter, data *request.RequestData, targets []Target) ([]Target, []string) {
+	if len(filters) == 0 {
+		return targets, nil
+	}
+	selected := make([]Target, 0, len(targets))
+	var skipped []string
+	for _, target := range targets {
+		if allowed(filters, data, target.URL) {
+			selected = append(selected, target)
+		} else {
+			skipped = append(skipped, target.URL)
+		}
+	}
+	return selected, skipped
+}
+
+func allowed(filters []Filter, data *request.RequestData, url string) bool {
+	allowListed := false
+	for i := range filters {
+		filter := &filters[i]
+		if !filter.governs(url) {
+			continue
+		}
+		if filter.Match(data) {
+			return !filter.Deny
+		}
+		if !filter.Deny {
+			allowListed = true
+		}
+	}
+	return !allowListed
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package forwarder
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func targetURLs(targets []Target) []string {
+	urls := make([]string, 0, len(targets))
+	for _, target := range targets {
+		urls = append(urls, target.URL)
+	}
+	return urls
+}
+
+func TestSelectTargetsAllowList(t *testing.T) {
+	local, archive := "http://localhost:3000", "http://archive.example"
+	targets := []Target{{URL: local}, {URL: archive}}
+	filters := []Filter{{
+		Name:    "stripe-only",
+		Targets: []string{local},
+		Methods: []string{"POST"},
+		Path:    regexp.MustCompile(`^/stripe/`),
+	}}
+
+	stripe := &request.RequestData{Method: "POST", Path: "/stripe/events", Headers: http.Header{}}
+	selected, skipped := SelectTargets(filters, stripe, targets)
+	if len(selected) != 2 || len(skipped) != 0 {
+		t.Fatalf("expected stripe post to reach both targets, got %v skipped %v", targetURLs(selected), skipped)
+	}
+
+	health := &request.RequestData{Method: "GET", Path: "/health", Headers: http.Header{}}
+	selected, skipped = SelectTargets(filters, health, targets)
+	if len(selected) != 1 || selected[0].URL != archive || len(skipped) != 1 || skipped[0] != local {
+		t.Fatalf("expected health check to skip the local target, got %v skipped %v", targetURLs(selected), skipped)
+	}
+}
+
+func TestSelectTargetsDenyAndConditions(t *testing.T) {
+	targets := []Target{{URL: "http://a.example"}}
+	filters := []Filter{
+		{Name: "no-pings", Deny: true, Headers: map[string]*regexp.Regexp{"X-Event": regexp.MustCompile(`^ping$`)}},
+		{Name: "no-tests", Deny: true, BodyContains: []byte(`"livemode":false`)},
+	}
+
+	ping := &request.RequestData{Method: "POST", Path: "/hook", Headers: http.Header{"X-Event": {"ping"}}}
+	if selected, _ := SelectTargets(filters, ping, targets); len(selected) != 0 {
+		t.Fatalf("expected ping to be denied")
+	}
+	test := &request.RequestData{Method: "POST", Path: "/hook", Headers: http.Header{}, Body: []byte(`{"livemode":false}`)}
+	if selected, _ := SelectTargets(filters, test, targets); len(selected) != 0 {
+		t.Fatalf("expected test-mode body to be denied")
+	}
+	live := &request.RequestData{Method: "POST", Path: "/hook", Headers: http.Header{"X-Event": {"charge"}}, Body: []byte(`{"livemode":true}`)}
+	if selected, _ := SelectTargets(filters, live, targets); len(selected) != 1 {
+		t.Fatalf("expected live event to be forwarded")
+	}
+}
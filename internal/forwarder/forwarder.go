@@ -1,13 +1,11 @@
 package forwarder
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"path"
 	"regexp"
@@ -15,7 +13,13 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/telemetry"
 	"github.com/funnyzak/reqtap/pkg/request"
 )
 
@@ -34,14 +38,79 @@ type Forwarder struct {
 	pathStrategy    *pathStrategy
 	headerBlacklist map[string]struct{}
 	headerWhitelist map[string]struct{}
+	stats           *statsRegistry
+	health          *healthMonitor
+	healthCheck     HealthCheckOptions
+	healthStop      chan struct{}
+	healthDone      chan struct{}
+	sinks           *sinkRegistry
+	limits          *limitRegistry
+	backoffs        *backoffRegistry
 }
 
 // Client 抽象转发接口，便于注入 mock 或替换实现。
 type Client interface {
-	Forward(ctx context.Context, data *request.RequestData, urls []string) error
+	Forward(ctx context.Context, data *request.RequestData, targets []Target) ([]Result, error)
+	Stats() []TargetStats
 	Close()
 }
 
+// Target describes a single forward destination.
+type Target struct {
+	URL    string
+	Expect *Expectation
+	// LatencyBudget is the provider timeout the first attempt is measured against; 0 disables the check.
+	LatencyBudget time.Duration
+	// StripPrefix removes this prefix from forwarded paths in place of the configured path strategy,
+	// e.g. the server.paths prefix the request was captured on.
+	StripPrefix string
+	// Format serializes messages for message broker URLs, see config.ForwardTargetConfig.Format
+	Format string
+	// Transforms rewrite the request for this target, see AttachTransforms
+	Transforms []*Transform
+	// SamplePercent and Weight limit the requests the target receives, see SampleTargets
+	SamplePercent float64
+	Weight        int
+	// Sign re-signs the forwarded body; nil forwards the request headers unchanged
+	Sign *Signer
+	// MaxConcurrent and RateLimit (attempts per second) throttle deliveries to this target on top
+	// of Options.MaxConcurrent; 0 leaves them unlimited
+	MaxConcurrent int
+	RateLimit     float64
+	// Retry replaces Options.Retries and the default backoff; nil keeps them
+	Retry *RetryPolicy
+}
+
+// maxResponseBodyBytes bounds how much of a target response is buffered for assertions and persistence.
+const maxResponseBodyBytes = 1 << 20
+
+// Result captures the delivery outcome for one target.
+type Result struct {
+	URL        string        `json:"url"`
+	StatusCode int           `json:"status_code"`
+	Attempts   int           `json:"attempts"`
+	Duration   time.Duration `json:"duration_ns"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	Violations []string      `json:"violations,omitempty"`
+	// StartedAt is when the first attempt began, after waiting for the target's limits and a
+	// worker; zero when the delivery was skipped.
+	StartedAt time.Time `json:"started_at,omitzero"`
+	// Latency is how long the first attempt took, i.e. what the provider would have waited for.
+	Latency       time.Duration `json:"latency_ns"`
+	LatencyBudget time.Duration `json:"latency_budget_ns,omitempty"`
+	OverBudget    bool          `json:"over_budget,omitempty"`
+	// Headers and Body hold the last response received from the target.
+	Headers       http.Header `json:"headers,omitempty"`
+	Body          []byte      `json:"-"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+	// CircuitOpen marks a delivery that was skipped because the target's circuit is open.
+	CircuitOpen bool `json:"circuit_open,omitempty"`
+	// RetryAfter is how long the target still asked to be left alone (429 or 503) when the
+	// delivery gave up
+	RetryAfter time.Duration `json:"retry_after_ns,omitempty"`
+}
+
 type pathStrategyMode string
 
 const (
@@ -63,9 +132,15 @@ type Options struct {
 	TLSHandshakeTimeout   time.Duration
 	ExpectContinueTimeout time.Duration
 	TLSInsecureSkipVerify bool
-	PathStrategy          PathStrategyOptions
-	HeaderBlacklist       []string
-	HeaderWhitelist       []string
+	// HTTP2 is HTTP2Auto, HTTP2Always or HTTP2Off; empty means HTTP2Auto
+	HTTP2           string
+	PathStrategy    PathStrategyOptions
+	HeaderBlacklist []string
+	HeaderWhitelist []string
+	CircuitBreaker  CircuitBreakerOptions
+	HealthCheck     HealthCheckOptions
+	// HostOverrides dial fixed addresses for target hostnames instead of resolving them
+	HostOverrides []HostOverride
 }
 
 // PathStrategyOptions configures how request paths are rewritten before forwarding
@@ -83,6 +158,29 @@ type RewriteRuleOption struct {
 	Regex   bool
 }
 
+// Protocol modes for Options.HTTP2
+const (
+	// HTTP2Auto negotiates HTTP/2 with https:// targets through ALPN and uses HTTP/1.1 otherwise
+	HTTP2Auto = "auto"
+	// HTTP2Always speaks HTTP/2 only, with prior knowledge (h2c) towards http:// targets
+	HTTP2Always = "always"
+	// HTTP2Off keeps every delivery on HTTP/1.1
+	HTTP2Off = "off"
+)
+
+// hopByHopHeaders apply to a single connection and are never forwarded; HTTP/2 rejects most of them
+var hopByHopHeaders = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-connection":    true,
+	"proxy-authenticate":  true,
+	"proxy-authorization": true,
+	"te":                  true,
+	"trailer":             true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+}
+
 // ErrForwarderClosed indicates the forwarder has been shut down.
 var ErrForwarderClosed = errors.New("forwarder is closed")
 
@@ -106,6 +204,10 @@ func NewForwarder(logger logger.Logger, opts Options) *Forwarder {
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: opts.TLSInsecureSkipVerify,
 		},
+		Protocols: transportProtocols(opts.HTTP2),
+	}
+	if len(opts.HostOverrides) > 0 {
+		transport.DialContext = newHostDialer(opts.HostOverrides).DialContext
 	}
 
 	f := &Forwarder{
@@ -121,21 +223,41 @@ func NewForwarder(logger logger.Logger, opts Options) *Forwarder {
 		pathStrategy:    newPathStrategy(opts.PathStrategy, logger),
 		headerBlacklist: toHeaderSet(normalizeHeaders(opts.HeaderBlacklist)),
 		headerWhitelist: toHeaderSet(normalizeHeaders(opts.HeaderWhitelist)),
+		stats:           newStatsRegistry(),
+		health:          newHealthMonitor(logger, opts.CircuitBreaker),
+		healthCheck:     opts.HealthCheck,
+		sinks:           newSinkRegistry(opts.Timeout),
+		limits:          newLimitRegistry(),
+		backoffs:        newBackoffRegistry(),
 	}
 	f.cond = sync.NewCond(&f.mu)
 	return f
 }
 
-// Forward forwards request to all configured URLs
-func (f *Forwarder) Forward(ctx context.Context, data *request.RequestData, urls []string) error {
-	if len(urls) == 0 {
-		return nil
+// SetPathStrategy replaces the path rewrite strategy used by subsequent forwards
+func (f *Forwarder) SetPathStrategy(opts PathStrategyOptions) {
+	strategy := newPathStrategy(opts, f.logger)
+	f.mu.Lock()
+	f.pathStrategy = strategy
+	f.mu.Unlock()
+}
+
+func (f *Forwarder) currentPathStrategy() *pathStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pathStrategy
+}
+
+// Forward forwards request to all given targets and reports per-target outcomes
+func (f *Forwarder) Forward(ctx context.Context, data *request.RequestData, targets []Target) ([]Result, error) {
+	if len(targets) == 0 {
+		return nil, nil
 	}
 
 	f.mu.Lock()
 	if f.closed {
 		f.mu.Unlock()
-		return ErrForwarderClosed
+		return nil, ErrForwarderClosed
 	}
 	f.activeCalls++
 	f.mu.Unlock()
@@ -148,88 +270,265 @@ func (f *Forwarder) Forward(ctx context.Context, data *request.RequestData, urls
 		f.mu.Unlock()
 	}()
 
-	// Concurrently forward to all target URLs
+	// Concurrently forward to all targets
+	results := make([]Result, len(targets))
 	var wg sync.WaitGroup
-	for _, url := range urls {
+	for i, target := range targets {
 		wg.Add(1)
-		go func(targetURL string) {
+		go func(idx int, target Target) {
 			defer wg.Done()
 
+			// Wait for the target's own limits first, so a throttled target does not hold the
+			// worker tokens other targets need
+			limiter := f.limits.limiter(target)
+			release, err := limiter.acquire(ctx)
+			if err == nil {
+				defer release()
+				err = limiter.wait(ctx)
+			}
+			if err != nil {
+				results[idx] = Result{URL: target.URL, Error: err.Error()}
+				return
+			}
+			// A target that answered 429 or 503 is left alone for as long as it asked
+			if remaining, err := f.backoffs.wait(ctx, target.URL); err != nil {
+				results[idx] = Result{URL: target.URL, Error: err.Error(), RetryAfter: remaining}
+				return
+			}
+
 			// Get worker token (control concurrent count)
 			f.workerPool <- struct{}{}
 			defer func() { <-f.workerPool }()
 
-			f.forwardToURL(ctx, data, targetURL)
-		}(url)
+			if !f.health.allow(target.URL) {
+				f.logger.Debug("Forward skipped, target circuit is open",
+					"request_id", data.ID,
+					"url", target.URL,
+				)
+				results[idx] = Result{URL: target.URL, Error: ErrCircuitOpen.Error(), CircuitOpen: true}
+				return
+			}
+			results[idx] = f.forwardToTarget(ctx, data, target)
+			f.stats.record(results[idx])
+		}(i, target)
 	}
 
 	wg.Wait()
-	return nil
+	return results, nil
+}
+
+// Stats returns delivery counters, circuit state and health for every target seen so far
+func (f *Forwarder) Stats() []TargetStats {
+	return f.backoffs.annotate(f.health.annotate(f.stats.snapshot()))
 }
 
-// forwardToURL forwards request to single URL (with retry)
-func (f *Forwarder) forwardToURL(ctx context.Context, data *request.RequestData, targetURL string) {
+// forwardToTarget forwards request to single target (with retry)
+func (f *Forwarder) forwardToTarget(ctx context.Context, data *request.RequestData, target Target) (result Result) {
 	var lastErr error
+	started := time.Now()
+	result = Result{URL: target.URL, StartedAt: started}
+	ctx, span := telemetry.Tracer().Start(ctx, "reqtap.forward",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("reqtap.request_id", data.ID),
+			attribute.String("reqtap.forward.target", target.URL),
+		),
+	)
+	defer func() {
+		result.Duration = time.Since(started)
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", result.StatusCode),
+			attribute.Int("reqtap.forward.attempts", result.Attempts),
+		)
+		if !result.Success {
+			span.SetStatus(codes.Error, result.Error)
+		}
+		span.End()
+	}()
+
+	// Transform once so templated values such as {{uuid}} stay the same across retries
+	transformed, err := transformRequest(data, target.Transforms)
+	if err != nil {
+		result.Error = err.Error()
+		f.logger.Error("Forward transform failed",
+			"request_id", data.ID,
+			"url", target.URL,
+			"error", err.Error(),
+		)
+		return result
+	}
+	data = transformed
 
-	for attempt := 0; attempt <= f.retries; attempt++ {
+	policy := f.retryPolicy(target)
+	// held is set when the last attempt was throttled; the retry then waits for the target's hold
+	// instead of the backoff
+	held := false
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 && f.health.tripped(target.URL) {
+			f.logger.Warn("Forward retries abandoned, target circuit is open",
+				"request_id", data.ID,
+				"url", target.URL,
+				"attempts", attempt,
+			)
+			return result
+		}
 		if attempt > 0 {
-			// Exponential backoff
-			backoff := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second // Maximum backoff time
+			// Exponential backoff, then wait for the target's rate limit like the first attempt did
+			delay := policy.backoff(attempt)
+			if held {
+				delay = 0
 			}
-
 			select {
 			case <-ctx.Done():
 				f.logger.Info("Forward cancelled by context",
 					"request_id", data.ID,
-					"url", targetURL,
+					"url", target.URL,
 					"attempt", attempt+1,
 				)
-				return
-			case <-time.After(backoff):
+				result.Error = ctx.Err().Error()
+				return result
+			case <-time.After(delay):
 				// Continue retry
 			}
+			if remaining, err := f.backoffs.wait(ctx, target.URL); err != nil {
+				f.logger.Warn("Forward retries abandoned, target asked to back off",
+					"request_id", data.ID,
+					"url", target.URL,
+					"attempts", attempt,
+					"retry_after", remaining.String(),
+				)
+				result.Error = err.Error()
+				result.RetryAfter = remaining
+				return result
+			}
+			if err := f.limits.limiter(target).wait(ctx); err != nil {
+				result.Error = err.Error()
+				return result
+			}
 		}
 
-		err := f.doForward(ctx, data, targetURL, attempt)
+		result.Attempts = attempt + 1
+		attemptStarted := time.Now()
+		outcome, err := f.doForward(ctx, data, target, attempt)
+		f.health.observe(target.URL, err, "forward")
+		if attempt == 0 {
+			f.checkLatencyBudget(data, target, &result, time.Since(attemptStarted))
+		}
+		result.StatusCode = outcome.statusCode
+		result.Headers = outcome.headers
+		result.Body = outcome.body
+		result.BodyTruncated = outcome.truncated
+		result.Violations = outcome.violations
 		if err == nil {
 			f.logger.Info("Request forwarded successfully",
 				"request_id", data.ID,
-				"url", targetURL,
+				"url", target.URL,
 				"method", data.Method,
 				"path", data.Path,
+				"status", outcome.statusCode,
 				"attempt", attempt+1,
 			)
-			return
+			result.Success = true
+			result.Error = ""
+			return result
 		}
 
 		lastErr = err
+		result.Error = err.Error()
 		f.logger.Warn("Forward attempt failed",
 			"request_id", data.ID,
-			"url", targetURL,
+			"url", target.URL,
 			"error", err.Error(),
 			"attempt", attempt+1,
 		)
+		var delay time.Duration
+		delay, held = throttleDelay(outcome, policy, attempt)
+		result.RetryAfter = 0
+		if held {
+			until := f.backoffs.hold(target.URL, delay)
+			result.RetryAfter = max(time.Until(until), 0)
+			f.logger.Warn("Forward target asked to back off",
+				"request_id", data.ID,
+				"url", target.URL,
+				"status", outcome.statusCode,
+				"retry_after", delay.String(),
+				"until", until,
+			)
+		}
 	}
 
+	if len(result.Violations) > 0 {
+		f.logger.Error("Forward target violated response contract",
+			"request_id", data.ID,
+			"url", target.URL,
+			"status", result.StatusCode,
+			"violations", result.Violations,
+		)
+	}
 	f.logger.Error("All forward attempts failed",
 		"request_id", data.ID,
-		"url", targetURL,
+		"url", target.URL,
 		"final_error", lastErr.Error(),
-		"total_attempts", f.retries+1,
+		"total_attempts", result.Attempts,
 	)
+	return result
+}
+
+// signRequest signs the body on a reader of its own, so spilled bodies are streamed from disk
+// once more instead of being buffered. Timestamped schemes are signed again on every attempt.
+func (f *Forwarder) signRequest(data *request.RequestData, signer *Signer, header http.Header) error {
+	body, _, err := data.OpenBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return signer.Sign(header, body)
+}
+
+// checkLatencyBudget flags deliveries the provider would have given up on
+func (f *Forwarder) checkLatencyBudget(data *request.RequestData, target Target, result *Result, latency time.Duration) {
+	result.Latency = latency
+	if target.LatencyBudget <= 0 {
+		return
+	}
+	result.LatencyBudget = target.LatencyBudget
+	if latency <= target.LatencyBudget {
+		return
+	}
+	result.OverBudget = true
+	f.logger.Warn("Forward exceeded provider latency budget",
+		"request_id", data.ID,
+		"url", target.URL,
+		"latency", latency.String(),
+		"budget", target.LatencyBudget.String(),
+	)
+}
+
+type forwardOutcome struct {
+	statusCode int
+	headers    http.Header
+	body       []byte
+	truncated  bool
+	violations []string
 }
 
 // doForward executes single forward
-func (f *Forwarder) doForward(ctx context.Context, data *request.RequestData, targetURL string, attempt int) error {
+func (f *Forwarder) doForward(ctx context.Context, data *request.RequestData, target Target, attempt int) (forwardOutcome, error) {
+	var outcome forwardOutcome
+	if config.IsSinkURL(target.URL) {
+		return outcome, f.publish(ctx, data, target)
+	}
 	resolvedPath := data.Path
 	var appliedRule string
-	if f.pathStrategy != nil {
-		resolvedPath, appliedRule = f.pathStrategy.resolve(data.Path)
+	strategy := f.currentPathStrategy()
+	if prefix := normalizeStripPrefix(target.StripPrefix); prefix != "" {
+		strategy = &pathStrategy{mode: pathModeStripPrefix, stripPrefix: prefix}
+	}
+	if strategy != nil {
+		resolvedPath, appliedRule = strategy.resolve(data.Path)
 	}
 	// Build target URL
-	targetURL = strings.TrimSuffix(targetURL, "/") + resolvedPath
+	targetURL := strings.TrimSuffix(target.URL, "/") + resolvedPath
 	if data.Query != "" {
 		targetURL += "?" + data.Query
 	}
@@ -242,15 +541,25 @@ func (f *Forwarder) doForward(ctx context.Context, data *request.RequestData, ta
 		)
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, data.Method, targetURL, bytes.NewReader(data.Body))
+	// Create request; spilled bodies are streamed from disk on every attempt
+	body, size, err := data.OpenBody()
 	if err != nil {
-		return fmt.Errorf("create request failed: %w", err)
+		return outcome, err
+	}
+	req, err := http.NewRequestWithContext(ctx, data.Method, targetURL, body)
+	if err != nil {
+		body.Close()
+		return outcome, fmt.Errorf("create request failed: %w", err)
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+		body.Close()
 	}
 
 	// Copy Headers (filter some headers that should not be forwarded)
 	for key, values := range data.Headers {
-		if f.shouldForwardHeader(key) {
+		if f.shouldForwardHeader(key) || target.setsHeader(key) {
 			for _, value := range values {
 				req.Header.Add(key, value)
 			}
@@ -262,35 +571,77 @@ func (f *Forwarder) doForward(ctx context.Context, data *request.RequestData, ta
 	req.Header.Set("X-Forwarded-Proto", "http")
 	req.Header.Set("X-ReqTap-Original-Host", data.Headers.Get("Host"))
 	req.Header.Set("X-ReqTap-Forward-Attempt", fmt.Sprintf("%d", attempt+1))
+	if target.Sign != nil {
+		if err := f.signRequest(data, target.Sign, req.Header); err != nil {
+			if req.Body != http.NoBody {
+				req.Body.Close()
+			}
+			return outcome, fmt.Errorf("sign request failed: %w", err)
+		}
+	}
+	telemetry.Inject(ctx, req.Header)
 
 	// Send request
+	sent := time.Now()
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return outcome, fmt.Errorf("request failed: %w", err)
 	}
 	defer func() {
 		if cerr := resp.Body.Close(); cerr != nil {
 			f.logger.Warn("Failed to close response body", "error", cerr)
 		}
 	}()
+	outcome.statusCode = resp.StatusCode
+	outcome.headers = resp.Header.Clone()
 
-	// Read response (avoid connection pool issues)
-	_, err = io.Copy(io.Discard, resp.Body)
+	// Read response (avoid connection pool issues); keep a bounded copy for contract checks and history
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
 	if err != nil {
 		f.logger.Warn("Failed to read response body", "error", err)
 	}
+	outcome.body = respBody
+	discarded, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		f.logger.Warn("Failed to read response body", "error", err)
+	}
+	outcome.truncated = discarded > 0
+
+	if target.Expect != nil {
+		outcome.violations = target.Expect.Check(resp.StatusCode, respBody, time.Since(sent))
+		if len(outcome.violations) > 0 {
+			return outcome, fmt.Errorf("response contract violated: %s", strings.Join(outcome.violations, "; "))
+		}
+		return outcome, nil
+	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("target returned status %d", resp.StatusCode)
+		return outcome, fmt.Errorf("target returned status %d", resp.StatusCode)
 	}
 
-	return nil
+	return outcome, nil
+}
+
+// transportProtocols maps an HTTP2 mode to the protocols the transport may use
+func transportProtocols(mode string) *http.Protocols {
+	protocols := new(http.Protocols)
+	switch mode {
+	case HTTP2Always:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	case HTTP2Off:
+		protocols.SetHTTP1(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+	return protocols
 }
 
 // shouldForwardHeader determines if specified header should be forwarded
 func (f *Forwarder) shouldForwardHeader(key string) bool {
 	lowerKey := strings.ToLower(strings.TrimSpace(key))
-	if lowerKey == "" {
+	if lowerKey == "" || hopByHopHeaders[lowerKey] {
 		return false
 	}
 
@@ -376,9 +727,11 @@ func (f *Forwarder) Close() {
 	for f.activeCalls > 0 {
 		f.cond.Wait()
 	}
+	f.stopHealthChecksLocked()
 	f.mu.Unlock()
 
 	close(f.workerPool)
+	f.sinks.close()
 
 	// Close idle connections of HTTP client
 	if transport, ok := f.client.Transport.(*http.Transport); ok {
@@ -0,0 +1,92 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// protoServer answers with the protocol the request arrived on and whether hop-by-hop headers made it through.
+func protoServer(t *testing.T, tls bool, protocols *http.Protocols) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if r.Header.Get("Keep-Alive") != "" || r.Header.Get("Upgrade") != "" {
+			w.Header().Set("X-Hop-By-Hop", "forwarded")
+		}
+	}))
+	srv.Config.Protocols = protocols
+	if tls {
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestForwardNegotiatesHTTP2(t *testing.T) {
+	h2c := new(http.Protocols)
+	h2c.SetHTTP1(true)
+	h2c.SetUnencryptedHTTP2(true)
+	cleartext := protoServer(t, false, h2c)
+	secure := protoServer(t, true, nil)
+
+	data := &request.RequestData{
+		ID:     "REQ",
+		Method: http.MethodPost,
+		Path:   "/hook",
+		Headers: http.Header{
+			"Connection": {"keep-alive, Upgrade"},
+			"Keep-Alive": {"timeout=5"},
+			"Upgrade":    {"websocket"},
+		},
+	}
+	cases := []struct {
+		mode   string
+		target string
+		want   string
+	}{
+		{HTTP2Auto, secure.URL, "HTTP/2.0"},
+		{HTTP2Auto, cleartext.URL, "HTTP/1.1"},
+		{HTTP2Always, cleartext.URL, "HTTP/2.0"},
+		{HTTP2Off, secure.URL, "HTTP/1.1"},
+	}
+	for _, tc := range cases {
+		f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1, TLSInsecureSkipVerify: true, HTTP2: tc.mode})
+		results, _ := f.Forward(context.Background(), data, []Target{{URL: tc.target}})
+		f.Close()
+		if !results[0].Success {
+			t.Fatalf("%s to %s: delivery failed: %s", tc.mode, tc.target, results[0].Error)
+		}
+		if got := results[0].Headers.Get("X-Proto"); got != tc.want {
+			t.Errorf("%s to %s: expected %s, got %s", tc.mode, tc.target, tc.want, got)
+		}
+		if results[0].Headers.Get("X-Hop-By-Hop") != "" {
+			t.Errorf("%s to %s: hop-by-hop headers were forwarded", tc.mode, tc.target)
+		}
+	}
+}
+
+func TestForwardTargetStripPrefix(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+	t.Cleanup(srv.Close)
+
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1})
+	defer f.Close()
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/github/push", Headers: http.Header{}}
+	results, _ := f.Forward(context.Background(), data, []Target{{URL: srv.URL + "/hooks", StripPrefix: "/github"}})
+	if !results[0].Success {
+		t.Fatalf("delivery failed: %s", results[0].Error)
+	}
+	if got != "/hooks/push" {
+		t.Errorf("expected the route prefix to be stripped, got %s", got)
+	}
+}
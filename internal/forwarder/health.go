@@ -0,0 +1,332 @@
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+)
+
+// Circuit states reported in TargetStats.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen marks deliveries skipped because the target's circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreakerOptions configures the per-target circuit breaker; a FailureThreshold of 0 disables it.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed attempts that opens the circuit
+	FailureThreshold int
+	// Cooldown is how long an open circuit waits before letting one trial request through
+	Cooldown time.Duration
+}
+
+// HealthCheckOptions configures active probing of targets; an Interval of 0 disables it.
+type HealthCheckOptions struct {
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+type targetHealth struct {
+	state          string
+	failures       int
+	openedAt       time.Time
+	trialInFlight  bool
+	shortCircuited uint64
+	healthy        *bool
+	lastCheckAt    time.Time
+	lastCheckError string
+}
+
+// healthMonitor tracks circuit state and health check results per target URL.
+type healthMonitor struct {
+	mu      sync.Mutex
+	logger  logger.Logger
+	breaker CircuitBreakerOptions
+	targets map[string]*targetHealth
+	now     func() time.Time
+}
+
+func newHealthMonitor(log logger.Logger, breaker CircuitBreakerOptions) *healthMonitor {
+	return &healthMonitor{
+		logger:  log,
+		breaker: breaker,
+		targets: make(map[string]*targetHealth),
+		now:     time.Now,
+	}
+}
+
+func (m *healthMonitor) enabled() bool {
+	return m.breaker.FailureThreshold > 0
+}
+
+func (m *healthMonitor) entry(url string) *targetHealth {
+	entry, ok := m.targets[url]
+	if !ok {
+		entry = &targetHealth{state: CircuitClosed}
+		m.targets[url] = entry
+	}
+	return entry
+}
+
+// allow reports whether a delivery to url may proceed. Once the cooldown of an open circuit has
+// elapsed a single trial is let through; its outcome closes or re-opens the circuit.
+func (m *healthMonitor) allow(url string) bool {
+	if !m.enabled() {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(url)
+	switch entry.state {
+	case CircuitOpen:
+		if m.now().Sub(entry.openedAt) < m.breaker.Cooldown {
+			entry.shortCircuited++
+			return false
+		}
+		m.transition(url, entry, CircuitHalfOpen, "cooldown elapsed")
+		entry.trialInFlight = true
+		return true
+	case CircuitHalfOpen:
+		if entry.trialInFlight {
+			entry.shortCircuited++
+			return false
+		}
+		entry.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// tripped reports whether the circuit of url is open, so pending retries can be abandoned.
+func (m *healthMonitor) tripped(url string) bool {
+	if !m.enabled() {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entry(url).state == CircuitOpen
+}
+
+// observe feeds the outcome of one delivery attempt or health check into the breaker.
+func (m *healthMonitor) observe(url string, err error, source string) {
+	if !m.enabled() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(url)
+	if err == nil {
+		entry.failures = 0
+		entry.trialInFlight = false
+		if entry.state != CircuitClosed {
+			m.transition(url, entry, CircuitClosed, source+" succeeded")
+		}
+		return
+	}
+	entry.failures++
+	switch {
+	case entry.state == CircuitHalfOpen:
+		entry.trialInFlight = false
+		m.transition(url, entry, CircuitOpen, source+" failed: "+err.Error())
+	case entry.state == CircuitClosed && entry.failures >= m.breaker.FailureThreshold:
+		m.transition(url, entry, CircuitOpen, source+" failed: "+err.Error())
+	}
+}
+
+func (m *healthMonitor) transition(url string, entry *targetHealth, state, reason string) {
+	entry.state = state
+	if state == CircuitOpen {
+		entry.openedAt = m.now()
+	}
+	switch state {
+	case CircuitOpen:
+		m.logger.Warn("Forward target circuit opened",
+			"url", url,
+			"consecutive_failures", entry.failures,
+			"cooldown", m.breaker.Cooldown.String(),
+			"reason", reason,
+		)
+	default:
+		m.logger.Info("Forward target circuit state changed",
+			"url", url,
+			"state", state,
+			"reason", reason,
+		)
+	}
+}
+
+// recordCheck stores a health check result and lets it drive the breaker.
+func (m *healthMonitor) recordCheck(url string, err error) {
+	m.mu.Lock()
+	entry := m.entry(url)
+	healthy := err == nil
+	if entry.healthy != nil && *entry.healthy != healthy {
+		if healthy {
+			m.logger.Info("Forward target is healthy again", "url", url)
+		} else {
+			m.logger.Warn("Forward target health check failing", "url", url, "error", err.Error())
+		}
+	}
+	entry.healthy = &healthy
+	entry.lastCheckAt = m.now().UTC()
+	entry.lastCheckError = ""
+	if err != nil {
+		entry.lastCheckError = err.Error()
+	}
+	m.mu.Unlock()
+
+	m.observe(url, err, "health check")
+}
+
+// track makes sure url is reported even before any request was forwarded to it.
+func (m *healthMonitor) track(url string) {
+	m.mu.Lock()
+	m.entry(url)
+	m.mu.Unlock()
+}
+
+// annotate merges circuit and health state into delivery stats, adding tracked targets without deliveries.
+func (m *healthMonitor) annotate(stats []TargetStats) []TargetStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := make(map[string]int, len(stats))
+	for i := range stats {
+		index[stats[i].URL] = i
+	}
+	for url := range m.targets {
+		if _, ok := index[url]; !ok {
+			index[url] = len(stats)
+			stats = append(stats, TargetStats{URL: url})
+		}
+	}
+	for i := range stats {
+		entry, ok := m.targets[stats[i].URL]
+		if !ok {
+			continue
+		}
+		if m.enabled() {
+			stats[i].Circuit = entry.state
+		}
+		stats[i].ConsecutiveFailures = entry.failures
+		stats[i].ShortCircuited = entry.shortCircuited
+		if entry.healthy != nil {
+			healthy := *entry.healthy
+			stats[i].Healthy = &healthy
+		}
+		stats[i].LastCheckAt = entry.lastCheckAt
+		stats[i].LastCheckError = entry.lastCheckError
+	}
+	sortTargetStats(stats)
+	return stats
+}
+
+// SetHealthCheckTargets registers the targets reported by Stats and, when health checks are
+// enabled, restarts background probing with exactly these URLs.
+func (f *Forwarder) SetHealthCheckTargets(urls []string) {
+	for _, url := range urls {
+		f.health.track(url)
+	}
+	if f.healthCheck.Interval <= 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.stopHealthChecksLocked()
+	stop, done := make(chan struct{}), make(chan struct{})
+	f.healthStop, f.healthDone = stop, done
+	// Message brokers are not probed over HTTP; failed publishes still trip their circuit
+	var targets []string
+	for _, url := range urls {
+		if !config.IsSinkURL(url) {
+			targets = append(targets, url)
+		}
+	}
+	go f.runHealthChecks(targets, stop, done)
+}
+
+func (f *Forwarder) stopHealthChecksLocked() {
+	if f.healthStop == nil {
+		return
+	}
+	close(f.healthStop)
+	<-f.healthDone
+	f.healthStop, f.healthDone = nil, nil
+}
+
+func (f *Forwarder) runHealthChecks(urls []string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(f.healthCheck.Interval)
+	defer ticker.Stop()
+	for {
+		for _, url := range urls {
+			err := f.checkTarget(ctx, url)
+			if ctx.Err() != nil {
+				return
+			}
+			f.health.recordCheck(url, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkTarget probes GET <url><path>; any response below 400 counts as healthy.
+func (f *Forwarder) checkTarget(ctx context.Context, url string) error {
+	timeout := durationOrDefault(f.healthCheck.Timeout, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checkPath := f.healthCheck.Path
+	if checkPath == "" {
+		checkPath = "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+checkPath, nil)
+	if err != nil {
+		return fmt.Errorf("create health check request: %w", err)
+	}
+	req.Header.Set("User-Agent", "reqtap-health-check")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodyBytes))
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	var failing atomic.Bool
+	var hits atomic.Int32
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewForwarder(noopLogger{}, Options{
+		MaxConcurrent:  1,
+		CircuitBreaker: CircuitBreakerOptions{FailureThreshold: 2, Cooldown: time.Minute},
+	})
+	defer f.Close()
+	now := time.Now()
+	f.health.now = func() time.Time { return now }
+
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/hook", Headers: http.Header{}}
+	targets := []Target{{URL: srv.URL}}
+	for i := 0; i < 2; i++ {
+		f.Forward(context.Background(), data, targets)
+	}
+	results, _ := f.Forward(context.Background(), data, targets)
+	if !results[0].CircuitOpen || results[0].Error != ErrCircuitOpen.Error() {
+		t.Fatalf("expected the third delivery to be short-circuited, got %+v", results[0])
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected the open circuit to spare the target, got %d hits", hits.Load())
+	}
+	stats := f.Stats()
+	if stats[0].Circuit != CircuitOpen || stats[0].ShortCircuited != 1 || stats[0].Failed != 2 {
+		t.Fatalf("unexpected stats while open: %+v", stats[0])
+	}
+
+	failing.Store(false)
+	now = now.Add(time.Minute)
+	results, _ = f.Forward(context.Background(), data, targets)
+	if !results[0].Success {
+		t.Fatalf("expected the half-open trial to be delivered, got %+v", results[0])
+	}
+	if stats := f.Stats(); stats[0].Circuit != CircuitClosed || stats[0].ConsecutiveFailures != 0 {
+		t.Fatalf("expected the circuit to close after a successful trial, got %+v", stats[0])
+	}
+}
+
+func TestHealthChecksReportTargetHealth(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dead.Close()
+
+	f := NewForwarder(noopLogger{}, Options{
+		MaxConcurrent:  1,
+		CircuitBreaker: CircuitBreakerOptions{FailureThreshold: 1, Cooldown: time.Minute},
+		HealthCheck:    HealthCheckOptions{Path: "/healthz", Interval: 10 * time.Millisecond, Timeout: time.Second},
+	})
+	defer f.Close()
+	f.SetHealthCheckTargets([]string{healthy.URL, dead.URL})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats := f.Stats()
+		if len(stats) == 2 && stats[0].Healthy != nil && stats[1].Healthy != nil {
+			byURL := map[string]TargetStats{stats[0].URL: stats[0], stats[1].URL: stats[1]}
+			if !*byURL[healthy.URL].Healthy || byURL[healthy.URL].Circuit != CircuitClosed {
+				t.Fatalf("expected %s to be healthy, got %+v", healthy.URL, byURL[healthy.URL])
+			}
+			if *byURL[dead.URL].Healthy || byURL[dead.URL].Circuit != CircuitOpen || byURL[dead.URL].LastCheckError == "" {
+				t.Fatalf("expected %s to be unhealthy with an open circuit, got %+v", dead.URL, byURL[dead.URL])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health checks did not complete: %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
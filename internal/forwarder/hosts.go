@@ -0,0 +1,58 @@
+package forwarder
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// HostOverride sends the connections for Host to Address instead of resolving Host, like an
+// /etc/hosts entry. Host is a hostname, or host:port to override a single port; Address is
+// host:port, or a host alone to keep the port of the target URL.
+type HostOverride struct {
+	Host    string
+	Address string
+}
+
+// hostDialer dials the overridden address of a host. The request keeps its URL, so the Host
+// header, TLS server name and certificate check still use the original hostname.
+type hostDialer struct {
+	dialer *net.Dialer
+	hosts  map[string]string
+}
+
+func newHostDialer(overrides []HostOverride) *hostDialer {
+	hosts := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		host := strings.ToLower(strings.TrimSpace(override.Host))
+		if host == "" || strings.TrimSpace(override.Address) == "" {
+			continue
+		}
+		hosts[host] = strings.TrimSpace(override.Address)
+	}
+	return &hostDialer{dialer: &net.Dialer{}, hosts: hosts}
+}
+
+// DialContext connects to the override of addr, or to addr itself when it has none
+func (d *hostDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx, network, d.resolve(addr))
+}
+
+// resolve returns the address to dial for addr; host:port overrides win over hostname ones
+func (d *hostDialer) resolve(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.ToLower(host)
+	target, ok := d.hosts[net.JoinHostPort(host, port)]
+	if !ok {
+		if target, ok = d.hosts[host]; !ok {
+			return addr
+		}
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return net.JoinHostPort(strings.Trim(target, "[]"), port)
+	}
+	return target
+}
@@ -0,0 +1,56 @@
+package forwarder
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestForwardHostOverrides(t *testing.T) {
+	var gotHost string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer stub.Close()
+
+	f := NewForwarder(noopLogger{}, Options{
+		MaxConcurrent: 1,
+		Timeout:       time.Second,
+		HostOverrides: []HostOverride{{Host: "API.internal", Address: stub.Listener.Addr().String()}},
+	})
+	defer f.Close()
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/hook"}
+	results, err := f.Forward(context.Background(), data, []Target{{URL: "http://api.internal/"}})
+	if err != nil || len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected the override to reach the stub, got %+v (%v)", results, err)
+	}
+	if gotHost != "api.internal" {
+		t.Fatalf("expected the original Host header, got %q", gotHost)
+	}
+}
+
+func TestHostDialerResolve(t *testing.T) {
+	d := newHostDialer([]HostOverride{
+		{Host: "api.internal", Address: "127.0.0.1:9000"},
+		{Host: "api.internal:8443", Address: "127.0.0.1:9443"},
+		{Host: "billing.internal", Address: "10.0.0.5"},
+		{Host: "v6.internal", Address: "::1"},
+	})
+	cases := map[string]string{
+		"api.internal:80":      "127.0.0.1:9000",
+		"API.internal:8443":    "127.0.0.1:9443",
+		"billing.internal:443": "10.0.0.5:443",
+		"v6.internal:80":       net.JoinHostPort("::1", "80"),
+		"example.com:443":      "example.com:443",
+	}
+	for addr, want := range cases {
+		if got := d.resolve(addr); got != want {
+			t.Errorf("resolve(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package forwarder
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RetryPolicy replaces the forwarder's retry count and backoff for one target
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; negative keeps Options.Retries
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubling up to MaxBackoff; zero values fall
+	// back to 1s and 30s
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+const (
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// retryPolicy returns the policy deliveries to target follow
+func (f *Forwarder) retryPolicy(target Target) RetryPolicy {
+	policy := RetryPolicy{MaxRetries: f.retries}
+	if target.Retry != nil {
+		policy = *target.Retry
+		if policy.MaxRetries < 0 {
+			policy.MaxRetries = f.retries
+		}
+	}
+	policy.Backoff = durationOrDefault(policy.Backoff, defaultRetryBackoff)
+	policy.MaxBackoff = durationOrDefault(policy.MaxBackoff, defaultRetryMaxBackoff)
+	return policy
+}
+
+// backoff is the wait before the given attempt (1 being the first retry)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := time.Duration(float64(p.Backoff) * math.Pow(2, float64(attempt-1)))
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// limitRegistry keeps the concurrency and rate limiter of every throttled target URL
+type limitRegistry struct {
+	mu      sync.Mutex
+	targets map[string]*targetLimiter
+}
+
+// targetLimiter holds the in-flight slots and the pacing of one target. Attempts are spaced
+// 1/rate apart, so a burst is spread out instead of sent at the start of every second.
+type targetLimiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newLimitRegistry() *limitRegistry {
+	return &limitRegistry{targets: make(map[string]*targetLimiter)}
+}
+
+// limiter returns the limiter of target, or nil when it is not throttled. A limiter is replaced
+// when the target's limits change, e.g. after a config reload.
+func (l *limitRegistry) limiter(target Target) *targetLimiter {
+	if target.MaxConcurrent <= 0 && target.RateLimit <= 0 {
+		return nil
+	}
+	var interval time.Duration
+	if target.RateLimit > 0 {
+		interval = time.Duration(float64(time.Second) / target.RateLimit)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter := l.targets[target.URL]
+	if limiter != nil && cap(limiter.slots) == max(target.MaxConcurrent, 0) && limiter.interval == interval {
+		return limiter
+	}
+	limiter = &targetLimiter{interval: interval}
+	if target.MaxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, target.MaxConcurrent)
+	}
+	l.targets[target.URL] = limiter
+	return limiter
+}
+
+// acquire takes a delivery slot of the limiter; the returned func gives it back
+func (t *targetLimiter) acquire(ctx context.Context) (func(), error) {
+	if t == nil || t.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return func() { <-t.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// wait blocks until the rate limit lets the next attempt through
+func (t *targetLimiter) wait(ctx context.Context) error {
+	if t == nil || t.interval <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
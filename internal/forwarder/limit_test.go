@@ -0,0 +1,104 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestForwardPerTargetConcurrencyAndRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	var fragileHits []time.Time
+	fragile := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		fragileHits = append(fragileHits, time.Now())
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer fragile.Close()
+	var mirrorHits atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits.Add(1)
+	}))
+	defer mirror.Close()
+
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 10, Timeout: time.Second})
+	defer f.Close()
+	targets := []Target{
+		{URL: fragile.URL, MaxConcurrent: 1, RateLimit: 20},
+		{URL: mirror.URL},
+	}
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := f.Forward(context.Background(), &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/"}, targets)
+			if err != nil || !results[0].Success || !results[1].Success {
+				t.Errorf("expected every delivery to succeed, got %+v (%v)", results, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if mirrorHits.Load() != 5 || len(fragileHits) != 5 {
+		t.Fatalf("expected 5 deliveries per target, got mirror=%d fragile=%d", mirrorHits.Load(), len(fragileHits))
+	}
+	if peak != 1 {
+		t.Fatalf("expected at most 1 delivery in flight to the throttled target, got %d", peak)
+	}
+	// 5 attempts at 20 per second are spaced at least 50ms apart
+	if elapsed := fragileHits[4].Sub(started); elapsed < 190*time.Millisecond {
+		t.Fatalf("expected the rate limit to spread the attempts, the last one came after %s", elapsed)
+	}
+}
+
+func TestForwardPerTargetRetryPolicy(t *testing.T) {
+	var hits atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1, Retries: 3, Timeout: time.Second})
+	defer f.Close()
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/"}
+
+	results, _ := f.Forward(context.Background(), data, []Target{{URL: failing.URL, Retry: &RetryPolicy{MaxRetries: 0}}})
+	if results[0].Attempts != 1 || hits.Load() != 1 {
+		t.Fatalf("expected retries to be off for the target, got %d attempts", results[0].Attempts)
+	}
+
+	hits.Store(0)
+	started := time.Now()
+	results, _ = f.Forward(context.Background(), data, []Target{{URL: failing.URL, Retry: &RetryPolicy{MaxRetries: -1, Backoff: 10 * time.Millisecond}}})
+	if results[0].Attempts != 4 || hits.Load() != 4 {
+		t.Fatalf("expected the global retry count with a target backoff, got %d attempts", results[0].Attempts)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected the short target backoff to apply, took %s", elapsed)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 80: time.Second} {
+		if got := policy.backoff(attempt); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package forwarder
+
+import "hash/fnv"
+
+// sampleBuckets is the resolution of SamplePercent: hundredths of a percent.
+const sampleBuckets = 10000
+
+// SampleTargets keeps the targets a request is mirrored to. A target with a SamplePercent only
+// receives that share of requests, and the targets with a Weight split requests between them so
+// that each request reaches exactly one of them. Decisions hash the request ID, so a request is
+// always routed the same way, also when it is re-forwarded. It returns the kept targets plus the
+// URLs that were sampled out.
+func SampleTargets(requestID string, targets []Target) ([]Target, []string) {
+	totalWeight := 0
+	for _, target := range targets {
+		totalWeight += target.Weight
+	}
+	weighted := ""
+	if totalWeight > 0 {
+		point := int(requestHash(requestID, "") % uint64(totalWeight))
+		for _, target := range targets {
+			if point < target.Weight {
+				weighted = target.URL
+				break
+			}
+			point -= target.Weight
+		}
+	}
+
+	selected := make([]Target, 0, len(targets))
+	var skipped []string
+	for _, target := range targets {
+		keep := target.Weight == 0 || target.URL == weighted
+		if keep && target.SamplePercent > 0 && target.SamplePercent < 100 {
+			keep = requestHash(requestID, target.URL)%sampleBuckets < uint64(target.SamplePercent*sampleBuckets/100)
+		}
+		if keep {
+			selected = append(selected, target)
+		} else {
+			skipped = append(skipped, target.URL)
+		}
+	}
+	return selected, skipped
+}
+
+// requestHash maps a request ID, salted per target, to a stable number
+func requestHash(requestID, salt string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(requestID))
+	h.Write([]byte{0})
+	h.Write([]byte(salt))
+	return h.Sum64()
+}
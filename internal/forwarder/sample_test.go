@@ -0,0 +1,50 @@
+package forwarder
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSampleTargetsPercent(t *testing.T) {
+	prod, canary := "http://prod", "http://canary"
+	targets := []Target{{URL: prod}, {URL: canary, SamplePercent: 10}}
+
+	mirrored := 0
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("REQ-%d", i)
+		selected, skipped := SampleTargets(id, targets)
+		if selected[0].URL != prod {
+			t.Fatalf("%s: expected the unsampled target to receive every request, got %v", id, targetURLs(selected))
+		}
+		if len(selected) == 2 {
+			mirrored++
+		} else if len(skipped) != 1 || skipped[0] != canary {
+			t.Fatalf("%s: expected the canary to be sampled out, got %v", id, skipped)
+		}
+		// The decision is stable for a request ID
+		again, _ := SampleTargets(id, targets)
+		if len(again) != len(selected) {
+			t.Fatalf("%s: expected a deterministic decision", id)
+		}
+	}
+	if mirrored < 850 || mirrored > 1150 {
+		t.Fatalf("expected about 10%% of requests mirrored, got %d of 10000", mirrored)
+	}
+}
+
+func TestSampleTargetsWeights(t *testing.T) {
+	archive := "http://archive"
+	targets := []Target{{URL: "http://blue", Weight: 3}, {URL: "http://green", Weight: 1}, {URL: archive}}
+
+	counts := map[string]int{}
+	for i := 0; i < 8000; i++ {
+		selected, _ := SampleTargets(fmt.Sprintf("REQ-%d", i), targets)
+		if len(selected) != 2 || selected[1].URL != archive {
+			t.Fatalf("expected one weighted target plus the archive, got %v", targetURLs(selected))
+		}
+		counts[selected[0].URL]++
+	}
+	if counts["http://blue"] < 5600 || counts["http://blue"] > 6400 {
+		t.Fatalf("expected a 3:1 split, got %v", counts)
+	}
+}
@@ -0,0 +1,100 @@
+package forwarder
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// providerSignatureHeaders are the signature headers of the known providers; a signer removes all
+// of them so that a stale signature of the original request is never forwarded.
+var providerSignatureHeaders = []string{
+	"X-Hub-Signature",
+	"X-Hub-Signature-256",
+	"Stripe-Signature",
+	"X-Slack-Signature",
+	"X-Slack-Request-Timestamp",
+}
+
+// Signer computes a fresh webhook signature over the forwarded body.
+type Signer struct {
+	cfg config.ForwardSignConfig
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// NewSigner returns a signer for a validated config, or nil when signing is disabled.
+func NewSigner(cfg config.ForwardSignConfig) *Signer {
+	if cfg.Scheme == "" {
+		return nil
+	}
+	return &Signer{cfg: cfg, now: time.Now}
+}
+
+// Sign reads the body and sets the signature headers on header. The body is consumed, so callers
+// pass a reader of their own.
+func (s *Signer) Sign(header http.Header, body io.Reader) error {
+	for _, name := range providerSignatureHeaders {
+		header.Del(name)
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	switch s.cfg.Scheme {
+	case config.SignSchemeGitHub:
+		sum, err := s.sum(sha256.New, "", body)
+		if err != nil {
+			return err
+		}
+		header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(sum))
+	case config.SignSchemeStripe:
+		sum, err := s.sum(sha256.New, timestamp+".", body)
+		if err != nil {
+			return err
+		}
+		header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(sum))
+	case config.SignSchemeSlack:
+		sum, err := s.sum(sha256.New, "v0:"+timestamp+":", body)
+		if err != nil {
+			return err
+		}
+		header.Set("X-Slack-Request-Timestamp", timestamp)
+		header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(sum))
+	default:
+		algorithm := sha256.New
+		switch s.cfg.Algorithm {
+		case "sha1":
+			algorithm = sha1.New
+		case "sha512":
+			algorithm = sha512.New
+		}
+		sum, err := s.sum(algorithm, "", body)
+		if err != nil {
+			return err
+		}
+		encoded := hex.EncodeToString(sum)
+		if s.cfg.Encoding == "base64" {
+			encoded = base64.StdEncoding.EncodeToString(sum)
+		}
+		header.Set(s.cfg.Header, s.cfg.Prefix+encoded)
+	}
+	return nil
+}
+
+// sum computes the HMAC of prefix followed by the body
+func (s *Signer) sum(algorithm func() hash.Hash, prefix string, body io.Reader) ([]byte, error) {
+	mac := hmac.New(algorithm, []byte(s.cfg.Secret))
+	mac.Write([]byte(prefix))
+	if _, err := io.Copy(mac, body); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
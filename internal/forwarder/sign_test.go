@@ -0,0 +1,102 @@
+package forwarder
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func hexHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignerSchemes(t *testing.T) {
+	body := `{"id":7}`
+	cases := []struct {
+		cfg  config.ForwardSignConfig
+		want http.Header
+	}{
+		{
+			cfg:  config.ForwardSignConfig{Scheme: "github", Secret: "s3cret"},
+			want: http.Header{"X-Hub-Signature-256": {"sha256=" + hexHMAC("s3cret", body)}},
+		},
+		{
+			cfg:  config.ForwardSignConfig{Scheme: "stripe", Secret: "whsec"},
+			want: http.Header{"Stripe-Signature": {"t=1700000000,v1=" + hexHMAC("whsec", "1700000000."+body)}},
+		},
+		{
+			cfg: config.ForwardSignConfig{Scheme: "slack", Secret: "xoxb"},
+			want: http.Header{
+				"X-Slack-Request-Timestamp": {"1700000000"},
+				"X-Slack-Signature":         {"v0=" + hexHMAC("xoxb", "v0:1700000000:"+body)},
+			},
+		},
+		{
+			cfg:  config.ForwardSignConfig{Scheme: "hmac", Secret: "key", Header: "X-Webhook-Signature", Algorithm: "sha1", Encoding: "base64", Prefix: "sha1="},
+			want: http.Header{"X-Webhook-Signature": {"sha1=sGlKpbShUuWavdG5sESZaiUeKF0="}},
+		},
+	}
+	for _, tc := range cases {
+		signer := NewSigner(tc.cfg)
+		signer.now = func() time.Time { return time.Unix(1700000000, 0) }
+		header := http.Header{"X-Hub-Signature": {"sha1=stale"}, "Content-Type": {"application/json"}}
+		if err := signer.Sign(header, strings.NewReader(body)); err != nil {
+			t.Fatalf("%s: %v", tc.cfg.Scheme, err)
+		}
+		tc.want.Set("Content-Type", "application/json")
+		if len(header) != len(tc.want) {
+			t.Errorf("%s: expected headers %v, got %v", tc.cfg.Scheme, tc.want, header)
+		}
+		for name := range tc.want {
+			if header.Get(name) != tc.want.Get(name) {
+				t.Errorf("%s: expected %s %q, got %q", tc.cfg.Scheme, name, tc.want.Get(name), header.Get(name))
+			}
+		}
+	}
+	if NewSigner(config.ForwardSignConfig{}) != nil {
+		t.Error("expected no signer without a scheme")
+	}
+}
+
+func TestForwardSignsTransformedBody(t *testing.T) {
+	var gotBody, gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotSignature = r.Header.Get("X-Hub-Signature-256")
+	}))
+	t.Cleanup(srv.Close)
+
+	targets := AttachTransforms(
+		[]Transform{{Name: "replace", Body: &Value{Text: `{"rewritten":true}`}}},
+		[]Target{{URL: srv.URL, Sign: NewSigner(config.ForwardSignConfig{Scheme: "github", Secret: "s3cret"})}},
+	)
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1})
+	defer f.Close()
+	data := &request.RequestData{
+		ID:      "REQ",
+		Method:  http.MethodPost,
+		Path:    "/hook",
+		Headers: http.Header{"X-Hub-Signature-256": {"sha256=original"}},
+		Body:    []byte(`{"original":true}`),
+	}
+	results, _ := f.Forward(context.Background(), data, targets)
+	if !results[0].Success {
+		t.Fatalf("delivery failed: %s", results[0].Error)
+	}
+	if want := "sha256=" + hexHMAC("s3cret", gotBody); gotBody != `{"rewritten":true}` || gotSignature != want {
+		t.Fatalf("expected the transformed body to be signed, got body %s signature %s", gotBody, gotSignature)
+	}
+}
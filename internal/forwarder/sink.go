@@ -0,0 +1,371 @@
+package forwarder
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// sink publishes captured requests to a message broker; implementations are safe for concurrent use
+type sink interface {
+	publish(ctx context.Context, msg *sinkMessage) error
+	close()
+}
+
+// sinkMessage is a captured request serialized for a broker. Key is the request ID, used as the
+// Kafka message key and the AMQP message ID; MQTT 3.1.1 has no room for headers.
+type sinkMessage struct {
+	key         string
+	contentType string
+	headers     map[string]string
+	payload     []byte
+	timestamp   time.Time
+}
+
+// buildSinkMessage serializes data in format: the request record as JSON, with the complete body,
+// or the raw body alone
+func buildSinkMessage(data *request.RequestData, format string) (*sinkMessage, error) {
+	body := data.Body
+	if data.BodyFile != "" {
+		reader, _, err := data.OpenBody()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if body, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("read spilled body: %w", err)
+		}
+	}
+	msg := &sinkMessage{
+		key:       data.ID,
+		timestamp: data.Timestamp,
+		headers: map[string]string{
+			"reqtap-request-id": data.ID,
+			"reqtap-method":     data.Method,
+			"reqtap-path":       data.Path,
+		},
+	}
+	if strings.EqualFold(format, config.SinkFormatBody) {
+		msg.contentType = data.ContentType
+		msg.payload = body
+		return msg, nil
+	}
+	record := *data
+	record.Body = body
+	record.BodyFile = ""
+	payload, err := json.Marshal(&record)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	msg.contentType = "application/json"
+	msg.payload = payload
+	return msg, nil
+}
+
+// sinkRegistry keeps one connected sink per target URL; a sink that failed to publish is dropped
+// and dialed again on the next attempt.
+type sinkRegistry struct {
+	timeout time.Duration
+	mu      sync.Mutex
+	sinks   map[string]sink
+}
+
+func newSinkRegistry(timeout time.Duration) *sinkRegistry {
+	return &sinkRegistry{timeout: durationOrDefault(timeout, 10*time.Second), sinks: make(map[string]sink)}
+}
+
+func (r *sinkRegistry) get(rawURL string) (sink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sinks[rawURL]; ok {
+		return s, nil
+	}
+	s, err := dialSink(rawURL, r.timeout)
+	if err != nil {
+		return nil, err
+	}
+	r.sinks[rawURL] = s
+	return s, nil
+}
+
+func (r *sinkRegistry) drop(rawURL string, s sink) {
+	r.mu.Lock()
+	if r.sinks[rawURL] == s {
+		delete(r.sinks, rawURL)
+	}
+	r.mu.Unlock()
+	s.close()
+}
+
+func (r *sinkRegistry) close() {
+	r.mu.Lock()
+	sinks := r.sinks
+	r.sinks = make(map[string]sink)
+	r.mu.Unlock()
+	for _, s := range sinks {
+		s.close()
+	}
+}
+
+// publish delivers data to the broker behind target.URL
+func (f *Forwarder) publish(ctx context.Context, data *request.RequestData, target Target) error {
+	msg, err := buildSinkMessage(data, target.Format)
+	if err != nil {
+		return err
+	}
+	s, err := f.sinks.get(target.URL)
+	if err != nil {
+		return fmt.Errorf("connect sink: %w", err)
+	}
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	if err := s.publish(ctx, msg); err != nil {
+		f.sinks.drop(target.URL, s)
+		return fmt.Errorf("publish failed: %w", err)
+	}
+	return nil
+}
+
+func dialSink(rawURL string, timeout time.Duration) (sink, error) {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	switch strings.ToLower(scheme) {
+	case "kafka":
+		return newKafkaSink(rawURL)
+	case "amqp", "amqps":
+		return newAMQPSink(rawURL, timeout)
+	case "mqtt", "mqtts":
+		return newMQTTSink(rawURL, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported sink scheme %q", scheme)
+	}
+}
+
+// kafkaSink writes to kafka://[user:pass@]broker1:9092,broker2:9092/topic[?tls=true]; credentials
+// use SASL/PLAIN
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink(rawURL string) (*kafkaSink, error) {
+	rest := rawURL[len("kafka://"):]
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	hosts, topic, _ := strings.Cut(rest, "/")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka sink query: %w", err)
+	}
+
+	transport := &kafka.Transport{}
+	if i := strings.LastIndex(hosts, "@"); i >= 0 {
+		userinfo := hosts[:i]
+		hosts = hosts[i+1:]
+		username, password, _ := strings.Cut(userinfo, ":")
+		if username, err = url.PathUnescape(username); err != nil {
+			return nil, fmt.Errorf("invalid kafka sink username: %w", err)
+		}
+		if password, err = url.PathUnescape(password); err != nil {
+			return nil, fmt.Errorf("invalid kafka sink password: %w", err)
+		}
+		transport.SASL = plain.Mechanism{Username: username, Password: password}
+	}
+	if enabled, _ := strconv.ParseBool(query.Get("tls")); enabled {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if hosts == "" || topic == "" {
+		return nil, errors.New("kafka sink must look like kafka://broker:9092/topic")
+	}
+	return &kafkaSink{writer: &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(hosts, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		Transport:    transport,
+		RequiredAcks: kafka.RequireOne,
+		// The forwarder retries failed deliveries itself, and waits for each message
+		MaxAttempts:  1,
+		BatchTimeout: 10 * time.Millisecond,
+	}}, nil
+}
+
+func (s *kafkaSink) publish(ctx context.Context, msg *sinkMessage) error {
+	headers := make([]kafka.Header, 0, len(msg.headers)+1)
+	for key, value := range msg.headers {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	if msg.contentType != "" {
+		headers = append(headers, kafka.Header{Key: "content-type", Value: []byte(msg.contentType)})
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(msg.key),
+		Value:   msg.payload,
+		Headers: headers,
+		Time:    msg.timestamp,
+	})
+}
+
+func (s *kafkaSink) close() {
+	_ = s.writer.Close()
+}
+
+// amqpSink publishes to amqp(s)://user:pass@host:5672/vhost?exchange=events&routing_key=hooks with
+// publisher confirms, so a delivery only succeeds once the broker took the message
+type amqpSink struct {
+	exchange   string
+	routingKey string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func newAMQPSink(rawURL string, timeout time.Duration) (*amqpSink, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amqp sink URL: %w", err)
+	}
+	query := parsed.Query()
+	s := &amqpSink{exchange: query.Get("exchange"), routingKey: query.Get("routing_key")}
+	query.Del("exchange")
+	query.Del("routing_key")
+	parsed.RawQuery = query.Encode()
+
+	s.conn, err = amqp.DialConfig(parsed.String(), amqp.Config{Dial: amqp.DefaultDial(timeout)})
+	if err != nil {
+		return nil, err
+	}
+	if s.channel, err = s.conn.Channel(); err != nil {
+		s.conn.Close()
+		return nil, err
+	}
+	if err := s.channel.Confirm(false); err != nil {
+		s.conn.Close()
+		return nil, fmt.Errorf("enable publisher confirms: %w", err)
+	}
+	return s, nil
+}
+
+func (s *amqpSink) publish(ctx context.Context, msg *sinkMessage) error {
+	headers := amqp.Table{}
+	for key, value := range msg.headers {
+		headers[key] = value
+	}
+	s.mu.Lock()
+	confirm, err := s.channel.PublishWithDeferredConfirmWithContext(ctx, s.exchange, s.routingKey, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.contentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.key,
+		Timestamp:    msg.timestamp,
+		Body:         msg.payload,
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("broker rejected the message")
+	}
+	return nil
+}
+
+func (s *amqpSink) close() {
+	_ = s.conn.Close()
+}
+
+// mqttSink publishes to mqtt(s)://user:pass@host:1883/topic?qos=1&retain=true
+type mqttSink struct {
+	client  mqtt.Client
+	topic   string
+	qos     byte
+	retain  bool
+	timeout time.Duration
+}
+
+func newMQTTSink(rawURL string, timeout time.Duration) (*mqttSink, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mqtt sink URL: %w", err)
+	}
+	query := parsed.Query()
+	broker := "tcp://" + hostWithDefaultPort(parsed, "1883")
+	if parsed.Scheme == "mqtts" {
+		broker = "ssl://" + hostWithDefaultPort(parsed, "8883")
+	}
+	qos, _ := strconv.Atoi(query.Get("qos"))
+	retain, _ := strconv.ParseBool(query.Get("retain"))
+	clientID := query.Get("client_id")
+	if clientID == "" {
+		clientID = "reqtap-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(true)
+	if parsed.User != nil {
+		opts.SetUsername(parsed.User.Username())
+		if password, ok := parsed.User.Password(); ok {
+			opts.SetPassword(password)
+		}
+	}
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(timeout) {
+		client.Disconnect(0)
+		return nil, errors.New("mqtt connect timed out")
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return &mqttSink{
+		client:  client,
+		topic:   strings.TrimPrefix(parsed.Path, "/"),
+		qos:     byte(qos),
+		retain:  retain,
+		timeout: timeout,
+	}, nil
+}
+
+func (s *mqttSink) publish(ctx context.Context, msg *sinkMessage) error {
+	token := s.client.Publish(s.topic, s.qos, s.retain, msg.payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *mqttSink) close() {
+	s.client.Disconnect(250)
+}
+
+func hostWithDefaultPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return u.Hostname() + ":" + port
+}
@@ -0,0 +1,84 @@
+package forwarder
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TargetStats aggregates delivery outcomes for a forward target.
+type TargetStats struct {
+	URL                string    `json:"url"`
+	Delivered          uint64    `json:"delivered"`
+	Failed             uint64    `json:"failed"`
+	ContractViolations uint64    `json:"contract_violations"`
+	OverBudget         uint64    `json:"over_budget"`
+	LastStatus         int       `json:"last_status"`
+	LastError          string    `json:"last_error,omitempty"`
+	LastAttemptAt      time.Time `json:"last_attempt_at"`
+	// Circuit is closed, open or half_open; empty when the circuit breaker is disabled
+	Circuit             string `json:"circuit,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	// ShortCircuited counts deliveries skipped while the circuit was open
+	ShortCircuited uint64 `json:"short_circuited"`
+	// Healthy is the latest health check result; nil until the target has been checked
+	Healthy        *bool     `json:"healthy,omitempty"`
+	LastCheckAt    time.Time `json:"last_check_at"`
+	LastCheckError string    `json:"last_check_error,omitempty"`
+	// Throttled counts the 429 and 503 answers that held the target back; BackoffUntil is set
+	// while deliveries wait for the hold to end
+	Throttled    uint64     `json:"throttled"`
+	BackoffUntil *time.Time `json:"backoff_until,omitempty"`
+}
+
+type statsRegistry struct {
+	mu      sync.Mutex
+	targets map[string]*TargetStats
+}
+
+func newStatsRegistry() *statsRegistry {
+	return &statsRegistry{targets: make(map[string]*TargetStats)}
+}
+
+func (r *statsRegistry) record(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.targets[res.URL]
+	if !ok {
+		entry = &TargetStats{URL: res.URL}
+		r.targets[res.URL] = entry
+	}
+	if res.Success {
+		entry.Delivered++
+	} else {
+		entry.Failed++
+	}
+	if len(res.Violations) > 0 {
+		entry.ContractViolations++
+	}
+	if res.OverBudget {
+		entry.OverBudget++
+	}
+	entry.LastStatus = res.StatusCode
+	entry.LastError = res.Error
+	entry.LastAttemptAt = time.Now().UTC()
+}
+
+func (r *statsRegistry) snapshot() []TargetStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]TargetStats, 0, len(r.targets))
+	for _, entry := range r.targets {
+		result = append(result, *entry)
+	}
+	sortTargetStats(result)
+	return result
+}
+
+func sortTargetStats(stats []TargetStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].URL < stats[j].URL
+	})
+}
@@ -0,0 +1,230 @@
+package forwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Transform rewrites requests before they are delivered to the targets it governs. JSON
+// operations run first (rename, remove, then set), followed by the body template and the headers.
+type Transform struct {
+	Name string
+	// Targets lists the target URLs the transform applies to; empty applies to every target.
+	Targets    []string
+	JSONRename []JSONRename
+	JSONRemove []string
+	JSONSet    []JSONSet
+	// Body replaces the request body; nil keeps it.
+	Body *Value
+	// RemoveHeaders are dropped before SetHeaders are applied; set headers bypass the header
+	// blacklist and whitelist.
+	RemoveHeaders []string
+	SetHeaders    map[string]Value
+}
+
+// JSONRename moves the value at From to To.
+type JSONRename struct {
+	From string
+	To   string
+}
+
+// JSONSet stores Value at Path; Raw values are decoded as JSON instead of inserted as strings.
+type JSONSet struct {
+	Path  string
+	Value Value
+	Raw   bool
+}
+
+// Value is static text or a template rendered against the request being transformed.
+type Value struct {
+	Text     string
+	Template *mocktemplate.Template
+}
+
+func (v Value) render(data *mocktemplate.Data) (string, error) {
+	if v.Template == nil {
+		return v.Text, nil
+	}
+	return v.Template.Render(data)
+}
+
+func (t *Transform) governs(url string) bool {
+	return len(t.Targets) == 0 || containsFold(t.Targets, url)
+}
+
+func (t *Transform) rewritesBody() bool {
+	return len(t.JSONRename) > 0 || len(t.JSONRemove) > 0 || len(t.JSONSet) > 0 || t.Body != nil
+}
+
+// AttachTransforms returns a copy of targets carrying the transforms that apply to each of them,
+// in configuration order.
+func AttachTransforms(transforms []Transform, targets []Target) []Target {
+	if len(transforms) == 0 {
+		return targets
+	}
+	attached := make([]Target, len(targets))
+	for i, target := range targets {
+		target.Transforms = nil
+		for j := range transforms {
+			if transforms[j].governs(target.URL) {
+				target.Transforms = append(target.Transforms, &transforms[j])
+			}
+		}
+		attached[i] = target
+	}
+	return attached
+}
+
+// setsHeader reports whether one of the target's transforms injects the header
+func (t Target) setsHeader(name string) bool {
+	for _, transform := range t.Transforms {
+		if _, ok := transform.SetHeaders[http.CanonicalHeaderKey(name)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// transformRequest applies transforms to a copy of data; data itself is left untouched.
+func transformRequest(data *request.RequestData, transforms []*Transform) (*request.RequestData, error) {
+	if len(transforms) == 0 {
+		return data, nil
+	}
+	out := *data
+	out.Headers = data.Headers.Clone()
+	if out.Headers == nil {
+		out.Headers = http.Header{}
+	}
+	for i, transform := range transforms {
+		if err := transform.apply(&out); err != nil {
+			name := transform.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("transform %s: %w", name, err)
+		}
+	}
+	return &out, nil
+}
+
+func (t *Transform) apply(data *request.RequestData) error {
+	if t.rewritesBody() {
+		body, err := fullBody(data)
+		if err != nil {
+			return err
+		}
+		if body, err = t.applyJSON(data, body); err != nil {
+			return err
+		}
+		if t.Body != nil {
+			rendered, err := t.Body.render(templateData(data, body))
+			if err != nil {
+				return fmt.Errorf("body: %w", err)
+			}
+			body = []byte(rendered)
+		}
+		replaceBody(data, body)
+	}
+
+	for _, name := range t.RemoveHeaders {
+		data.Headers.Del(name)
+	}
+	if len(t.SetHeaders) > 0 {
+		tdata := templateData(data, data.Body)
+		for name, value := range t.SetHeaders {
+			rendered, err := value.render(tdata)
+			if err != nil {
+				return fmt.Errorf("header %s: %w", name, err)
+			}
+			data.Headers.Set(name, rendered)
+		}
+	}
+	return nil
+}
+
+// applyJSON runs the JSON operations; bodies that are not JSON are returned unchanged
+func (t *Transform) applyJSON(data *request.RequestData, body []byte) ([]byte, error) {
+	if len(t.JSONRename) == 0 && len(t.JSONRemove) == 0 && len(t.JSONSet) == 0 {
+		return body, nil
+	}
+	doc, err := jsonpath.Decode(body)
+	if err != nil {
+		return body, nil
+	}
+	var ok bool
+	for _, rename := range t.JSONRename {
+		value, found := jsonpath.Lookup(doc, rename.From)
+		if !found {
+			continue
+		}
+		doc, _ = jsonpath.Delete(doc, rename.From)
+		if doc, ok = jsonpath.Set(doc, rename.To, value); !ok {
+			return nil, fmt.Errorf("json rename %s: cannot set %s", rename.From, rename.To)
+		}
+	}
+	for _, path := range t.JSONRemove {
+		doc, _ = jsonpath.Delete(doc, path)
+	}
+	tdata := templateData(data, body)
+	for _, op := range t.JSONSet {
+		rendered, err := op.Value.render(tdata)
+		if err != nil {
+			return nil, fmt.Errorf("json set %s: %w", op.Path, err)
+		}
+		var value interface{} = rendered
+		if op.Raw {
+			if value, err = jsonpath.Decode([]byte(rendered)); err != nil {
+				return nil, fmt.Errorf("json set %s: value is not valid JSON: %w", op.Path, err)
+			}
+		}
+		if doc, ok = jsonpath.Set(doc, op.Path, value); !ok {
+			return nil, fmt.Errorf("json set %s: path crosses a non-object value", op.Path)
+		}
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encode json: %w", err)
+	}
+	return encoded, nil
+}
+
+// fullBody returns the decoded body, reading spilled bodies from disk
+func fullBody(data *request.RequestData) ([]byte, error) {
+	if data.BodyFile == "" {
+		return data.Body, nil
+	}
+	reader, _, err := data.OpenBody()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read spilled body: %w", err)
+	}
+	return body, nil
+}
+
+// replaceBody makes body the request body sent on the wire, dropping the original encoding
+func replaceBody(data *request.RequestData, body []byte) {
+	data.Body = body
+	data.BodyFile = ""
+	data.Size = int64(len(body))
+	data.ContentLength = int64(len(body))
+	data.WireBody = nil
+	data.WireSize = 0
+	if data.ContentEncoding != "" {
+		data.Headers.Del("Content-Encoding")
+		data.ContentEncoding = ""
+	}
+}
+
+func templateData(data *request.RequestData, body []byte) *mocktemplate.Data {
+	return mocktemplate.NewData(data.ID, data.Method, data.Path, data.Query, data.RemoteAddr, data.Headers, body)
+}
@@ -0,0 +1,107 @@
+package forwarder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func templateValue(t *testing.T, text string) Value {
+	t.Helper()
+	tmpl, err := mocktemplate.Parse("test", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Value{Text: text, Template: tmpl}
+}
+
+func TestForwardAppliesTransforms(t *testing.T) {
+	var gotBody, gotSource, gotSignature, gotEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotSource = r.Header.Get("X-Source")
+		gotSignature = r.Header.Get("X-Signature")
+		gotEncoding = r.Header.Get("Content-Encoding")
+	}))
+	t.Cleanup(srv.Close)
+	other := "http://other.example"
+
+	transforms := []Transform{
+		{
+			Name:       "envelope",
+			Targets:    []string{srv.URL},
+			JSONRename: []JSONRename{{From: "eventType", To: "event.type"}},
+			JSONRemove: []string{"signature"},
+			JSONSet: []JSONSet{
+				{Path: "event.method", Value: templateValue(t, "{{.Method}}")},
+				{Path: "meta", Value: Value{Text: `{"version":2}`}, Raw: true},
+			},
+			RemoveHeaders: []string{"X-Signature"},
+			SetHeaders:    map[string]Value{"X-Source": templateValue(t, `{{.JSONBody "event.type"}}`)},
+		},
+		{Name: "other only", Targets: []string{other}, Body: &Value{Text: "replaced"}},
+	}
+	targets := AttachTransforms(transforms, []Target{{URL: srv.URL}, {URL: other}})
+	if len(targets[0].Transforms) != 1 || len(targets[1].Transforms) != 1 || targets[1].Transforms[0].Name != "other only" {
+		t.Fatalf("expected each target to get its own transform, got %+v", targets)
+	}
+
+	// The whitelist would drop X-Source; headers set by transforms are sent anyway
+	f := NewForwarder(noopLogger{}, Options{MaxConcurrent: 1, HeaderWhitelist: []string{"content-type"}})
+	defer f.Close()
+	data := &request.RequestData{
+		ID:              "REQ",
+		Method:          http.MethodPost,
+		Path:            "/hook",
+		Headers:         http.Header{"X-Signature": {"sha256=abc"}, "Content-Encoding": {"gzip"}},
+		Body:            []byte(`{"eventType":"push","signature":"abc","id":7}`),
+		WireBody:        []byte("compressed"),
+		ContentEncoding: "gzip",
+	}
+	results, _ := f.Forward(context.Background(), data, targets[:1])
+	if !results[0].Success {
+		t.Fatalf("delivery failed: %s", results[0].Error)
+	}
+	if want := `{"event":{"method":"POST","type":"push"},"id":7,"meta":{"version":2}}`; gotBody != want {
+		t.Errorf("expected body %s, got %s", want, gotBody)
+	}
+	if gotSource != "push" || gotSignature != "" || gotEncoding != "" {
+		t.Errorf("unexpected headers: source=%q signature=%q encoding=%q", gotSource, gotSignature, gotEncoding)
+	}
+	if string(data.Body) != `{"eventType":"push","signature":"abc","id":7}` || data.Headers.Get("X-Signature") == "" {
+		t.Error("transforms must not modify the captured request")
+	}
+}
+
+func TestTransformBodyTemplateAndErrors(t *testing.T) {
+	data := &request.RequestData{ID: "REQ", Method: http.MethodPost, Body: []byte(`{"user":{"id":"u1"}}`)}
+
+	body := templateValue(t, `{"uid":"{{.JSONBody "user.id"}}","via":"{{.Header "X-Via"}}"}`)
+	out, err := transformRequest(data, []*Transform{
+		{SetHeaders: map[string]Value{"X-Via": {Text: "reqtap"}}},
+		{Body: &body},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"uid":"u1","via":"reqtap"}`; string(out.Body) != want || out.Size != int64(len(want)) {
+		t.Errorf("expected body %s, got %s (size %d)", want, out.Body, out.Size)
+	}
+
+	plain := &request.RequestData{ID: "REQ", Body: []byte("not json")}
+	out, err = transformRequest(plain, []*Transform{{JSONRemove: []string{"a"}}})
+	if err != nil || string(out.Body) != "not json" {
+		t.Errorf("expected non-JSON bodies to pass through, got %q, %v", out.Body, err)
+	}
+
+	_, err = transformRequest(data, []*Transform{{Name: "bad", JSONSet: []JSONSet{{Path: "user.id.x", Value: Value{Text: "1"}}}}})
+	if err == nil {
+		t.Fatal("expected setting a field below a string to fail")
+	}
+}
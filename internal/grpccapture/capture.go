@@ -0,0 +1,126 @@
+package grpccapture
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Capturer answers captured gRPC calls, either with an empty message or by relaying them to the
+// backend, and decodes both directions.
+type Capturer struct {
+	schemas    *Schemas
+	backend    *Backend
+	reflection bool
+
+	mu        sync.Mutex
+	reflected map[string]bool
+}
+
+// New loads the configured descriptor sets and prepares the backend relay.
+func New(cfg config.GRPCCaptureConfig) (*Capturer, error) {
+	c := &Capturer{
+		schemas:    NewSchemas(),
+		reflection: cfg.Reflection,
+		reflected:  make(map[string]bool),
+	}
+	for _, path := range cfg.DescriptorSets {
+		if err := c.schemas.LoadDescriptorSet(path); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.BackendURL != "" {
+		backend, err := NewBackend(cfg.BackendURL, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.backend = backend
+	}
+	return c, nil
+}
+
+// Serve answers the call and returns what was exchanged. The returned error describes a failed
+// relay or schema lookup; the client has been answered either way.
+func (c *Capturer) Serve(w http.ResponseWriter, r *http.Request, body []byte) (*request.GRPCCall, error) {
+	service, method, ok := ParseMethod(r.URL.Path)
+	if !ok {
+		writeStatus(w, "12", "malformed method name")
+		return &request.GRPCCall{Status: "12", StatusMessage: "malformed method name"}, nil
+	}
+	call := &request.GRPCCall{Service: service, Method: method}
+	desc, lookupErr := c.method(r.Context(), service, method)
+
+	var input, output protoreflect.MessageDescriptor
+	if desc != nil {
+		input, output = desc.Input(), desc.Output()
+	}
+	call.Requests = decodeStream(body, r.Header.Get("Grpc-Encoding"), input)
+
+	if c.backend == nil {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(EncodeFrame(nil))
+		w.Header().Set("Grpc-Status", "0")
+		call.Status = "0"
+		call.Responses = []request.GRPCMessage{{Size: 0, JSON: []byte("{}"), Raw: output == nil}}
+		return call, lookupErr
+	}
+
+	result, err := c.backend.relay(w, r, service, method, body)
+	if err != nil {
+		call.Status, call.StatusMessage = "14", err.Error()
+		writeStatus(w, call.Status, "upstream unavailable")
+		return call, fmt.Errorf("relay %s/%s: %w", service, method, err)
+	}
+	call.Status, call.StatusMessage = result.status, result.statusMessage
+	call.Responses = decodeStream(result.body, result.encoding, output)
+	if result.truncated {
+		call.Responses = append(call.Responses, request.GRPCMessage{Error: "response stream truncated for capture"})
+	}
+	return call, lookupErr
+}
+
+// method finds the schema of service/method, asking the backend's reflection service once per service.
+func (c *Capturer) method(ctx context.Context, service, method string) (protoreflect.MethodDescriptor, error) {
+	if desc := c.schemas.Method(service, method); desc != nil || !c.reflection || c.backend == nil {
+		return desc, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reflected[service] {
+		return c.schemas.Method(service, method), nil
+	}
+	c.reflected[service] = true
+	files, err := c.backend.reflect(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("reflect %s: %w", service, err)
+	}
+	if err := c.schemas.Register(files); err != nil {
+		return nil, fmt.Errorf("reflect %s: %w", service, err)
+	}
+	return c.schemas.Method(service, method), nil
+}
+
+func decodeStream(body []byte, encoding string, desc protoreflect.MessageDescriptor) []request.GRPCMessage {
+	frames, err := SplitFrames(body)
+	messages := DecodeMessages(frames, encoding, desc)
+	if err != nil {
+		messages = append(messages, request.GRPCMessage{Error: err.Error()})
+	}
+	return messages
+}
+
+// writeStatus sends a trailers-only gRPC error response.
+func writeStatus(w http.ResponseWriter, status, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", status)
+	w.Header().Set("Grpc-Message", message)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,258 @@
+package grpccapture
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Well-known types referenced by user descriptors resolve from the global registry
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Schemas resolves gRPC methods to their message descriptors. It is safe for concurrent use.
+type Schemas struct {
+	mu    sync.RWMutex
+	files *protoregistry.Files
+}
+
+// NewSchemas returns an empty registry.
+func NewSchemas() *Schemas {
+	return &Schemas{files: &protoregistry.Files{}}
+}
+
+// LoadDescriptorSet registers every file of a FileDescriptorSet, as written by
+// `protoc --include_imports --descriptor_set_out=FILE` or `buf build -o FILE`.
+func (s *Schemas) LoadDescriptorSet(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parse descriptor set %s: %w", path, err)
+	}
+	return s.Register(set.GetFile())
+}
+
+// Register adds file descriptors; files may arrive in any order as long as their imports are included.
+func (s *Schemas) Register(files []*descriptorpb.FileDescriptorProto) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := files
+	for len(pending) > 0 {
+		var retry []*descriptorpb.FileDescriptorProto
+		var lastErr error
+		for _, fd := range pending {
+			if _, err := s.files.FindFileByPath(fd.GetName()); err == nil {
+				continue
+			}
+			file, err := protodesc.NewFile(fd, resolver{s.files})
+			if err != nil {
+				retry = append(retry, fd)
+				lastErr = err
+				continue
+			}
+			if err := s.files.RegisterFile(file); err != nil {
+				return err
+			}
+		}
+		if len(retry) == len(pending) {
+			return lastErr
+		}
+		pending = retry
+	}
+	return nil
+}
+
+// Method returns the descriptor of service/method, or nil when it is unknown.
+func (s *Schemas) Method(service, method string) protoreflect.MethodDescriptor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	return svc.Methods().ByName(protoreflect.Name(method))
+}
+
+// Message returns the descriptor of a fully qualified message name, or nil when it is unknown.
+func (s *Schemas) Message(name string) protoreflect.MessageDescriptor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+	msg, _ := desc.(protoreflect.MessageDescriptor)
+	return msg
+}
+
+// resolver looks up imports in the registry being built first, then among the well-known types.
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// DecodeMessages turns the frames of one direction into JSON. With a nil descriptor the messages
+// are decoded schemaless, keyed by field number.
+func DecodeMessages(frames []Frame, encoding string, desc protoreflect.MessageDescriptor) []request.GRPCMessage {
+	messages := make([]request.GRPCMessage, 0, len(frames))
+	for _, frame := range frames {
+		msg := request.GRPCMessage{Size: len(frame.Data), Compressed: frame.Compressed}
+		payload, err := frame.Decompress(encoding)
+		if err != nil {
+			msg.Error = err.Error()
+			messages = append(messages, msg)
+			continue
+		}
+		if out, err := DecodeMessage(payload, desc); err != nil {
+			msg.Error = err.Error()
+		} else {
+			msg.JSON = out
+			msg.Raw = desc == nil
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// DecodeMessage turns one serialized message into JSON. With a nil descriptor it is decoded
+// schemaless, keyed by field number.
+func DecodeMessage(payload []byte, desc protoreflect.MessageDescriptor) ([]byte, error) {
+	if desc != nil {
+		dyn := dynamicpb.NewMessage(desc)
+		if err := proto.Unmarshal(payload, dyn); err != nil {
+			return nil, err
+		}
+		return protojson.Marshal(dyn)
+	}
+	fields, err := decodeRaw(payload, 0)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// maxRawDepth bounds how deep length-delimited fields are probed for nested messages.
+const maxRawDepth = 8
+
+// decodeRaw decodes the wire format without a schema. Length-delimited fields are shown as text
+// when printable, otherwise as nested messages when they parse as such, otherwise as base64.
+func decodeRaw(payload []byte, depth int) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	add := func(num protowire.Number, value interface{}) {
+		key := strconv.Itoa(int(num))
+		if existing, ok := fields[key]; ok {
+			if list, ok := existing.([]interface{}); ok {
+				fields[key] = append(list, value)
+			} else {
+				fields[key] = []interface{}{existing, value}
+			}
+			return
+		}
+		fields[key] = value
+	}
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		var value interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, m := protowire.ConsumeVarint(payload)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			value, n = v, m
+		case protowire.Fixed32Type:
+			v, m := protowire.ConsumeFixed32(payload)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			value, n = v, m
+		case protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(payload)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			value, n = v, m
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(payload)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			value, n = rawBytesValue(v, depth), m
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", typ)
+		}
+		add(num, value)
+		payload = payload[n:]
+	}
+	return fields, nil
+}
+
+func rawBytesValue(v []byte, depth int) interface{} {
+	if isPrintable(v) {
+		return string(v)
+	}
+	if depth < maxRawDepth {
+		if nested, err := decodeRaw(v, depth+1); err == nil {
+			return nested
+		}
+	}
+	return base64.StdEncoding.EncodeToString(v)
+}
+
+func isPrintable(v []byte) bool {
+	if !utf8.Valid(v) {
+		return false
+	}
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
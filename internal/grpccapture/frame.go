@@ -0,0 +1,91 @@
+// Package grpccapture decodes gRPC calls received on the capture path and relays them to a gRPC backend.
+package grpccapture
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// frameHeaderLen is the compressed flag plus the big-endian message length.
+const frameHeaderLen = 5
+
+// Frame is one length-prefixed gRPC message.
+type Frame struct {
+	Compressed bool
+	Data       []byte
+}
+
+// IsGRPC reports whether the request is a gRPC call (HTTP/2 with an application/grpc content type).
+func IsGRPC(r *http.Request) bool {
+	contentType := strings.ToLower(r.Header.Get("Content-Type"))
+	return r.ProtoMajor == 2 && (contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+"))
+}
+
+// ParseMethod splits a gRPC path such as /pkg.Service/Method.
+func ParseMethod(path string) (service, method string, ok bool) {
+	path = strings.TrimPrefix(path, "/")
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 || idx == len(path)-1 {
+		return "", "", false
+	}
+	// Capture path prefixes are not part of the gRPC method name
+	service = path[:idx]
+	if slash := strings.LastIndex(service, "/"); slash >= 0 {
+		service = service[slash+1:]
+	}
+	return service, path[idx+1:], service != ""
+}
+
+// SplitFrames cuts a gRPC stream body into its messages; a truncated trailing message is an error.
+func SplitFrames(body []byte) ([]Frame, error) {
+	var frames []Frame
+	for len(body) > 0 {
+		if len(body) < frameHeaderLen {
+			return frames, errors.New("truncated gRPC message header")
+		}
+		size := binary.BigEndian.Uint32(body[1:frameHeaderLen])
+		if uint64(len(body)-frameHeaderLen) < uint64(size) {
+			return frames, fmt.Errorf("truncated gRPC message: want %d bytes, have %d", size, len(body)-frameHeaderLen)
+		}
+		frames = append(frames, Frame{
+			Compressed: body[0]&1 == 1,
+			Data:       body[frameHeaderLen : frameHeaderLen+int(size)],
+		})
+		body = body[frameHeaderLen+int(size):]
+	}
+	return frames, nil
+}
+
+// EncodeFrame prefixes an uncompressed message with its gRPC header.
+func EncodeFrame(message []byte) []byte {
+	frame := make([]byte, frameHeaderLen+len(message))
+	binary.BigEndian.PutUint32(frame[1:frameHeaderLen], uint32(len(message)))
+	copy(frame[frameHeaderLen:], message)
+	return frame
+}
+
+// Decompress returns the message payload, inflating it with the stream's grpc-encoding when flagged.
+func (f Frame) Decompress(encoding string) ([]byte, error) {
+	if !f.Compressed {
+		return f.Data, nil
+	}
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(f.Data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case "", "identity":
+		return nil, errors.New("compressed message without grpc-encoding")
+	default:
+		return nil, fmt.Errorf("unsupported grpc-encoding %q", encoding)
+	}
+}
@@ -0,0 +1,160 @@
+package grpccapture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func echoFile() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("echo.proto"),
+		Package: proto.String("demo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("EchoRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("text"), JsonName: proto.String("text"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("count"), JsonName: proto.String("count"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Say"),
+				InputType:  proto.String(".demo.EchoRequest"),
+				OutputType: proto.String(".demo.EchoRequest"),
+			}},
+		}},
+	}
+}
+
+// echoMessage encodes EchoRequest{text, count} by hand.
+func echoMessage(text string, count uint64) []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendString(b, text)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	return protowire.AppendVarint(b, count)
+}
+
+func TestSplitFrames(t *testing.T) {
+	body := append(EncodeFrame([]byte("one")), EncodeFrame(nil)...)
+	frames, err := SplitFrames(body)
+	if err != nil {
+		t.Fatalf("split frames: %v", err)
+	}
+	if len(frames) != 2 || string(frames[0].Data) != "one" || len(frames[1].Data) != 0 {
+		t.Fatalf("unexpected frames: %+v", frames)
+	}
+	if _, err := SplitFrames(body[:len(body)-3]); err == nil {
+		t.Fatal("expected an error for a truncated stream")
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	cases := []struct {
+		path            string
+		service, method string
+		ok              bool
+	}{
+		{"/demo.Echo/Say", "demo.Echo", "Say", true},
+		{"/reqtap/demo.Echo/Say", "demo.Echo", "Say", true},
+		{"/demo.Echo/", "", "", false},
+		{"/Say", "", "", false},
+	}
+	for _, tc := range cases {
+		service, method, ok := ParseMethod(tc.path)
+		if service != tc.service || method != tc.method || ok != tc.ok {
+			t.Errorf("ParseMethod(%q) = %q, %q, %v", tc.path, service, method, ok)
+		}
+	}
+}
+
+func TestDecodeMessages(t *testing.T) {
+	schemas := NewSchemas()
+	if err := schemas.Register([]*descriptorpb.FileDescriptorProto{echoFile()}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	desc := schemas.Method("demo.Echo", "Say")
+	if desc == nil {
+		t.Fatal("expected the method to resolve")
+	}
+	frames := []Frame{{Data: echoMessage("hi", 3)}}
+
+	decoded := DecodeMessages(frames, "", desc.Input())
+	var got map[string]interface{}
+	if err := json.Unmarshal(decoded[0].JSON, &got); err != nil || got["text"] != "hi" || got["count"] != float64(3) || decoded[0].Raw {
+		t.Fatalf("unexpected schema decode: %s (%v)", decoded[0].JSON, err)
+	}
+
+	raw := DecodeMessages(frames, "", nil)
+	if err := json.Unmarshal(raw[0].JSON, &got); err != nil || got["1"] != "hi" || got["2"] != float64(3) || !raw[0].Raw {
+		t.Fatalf("unexpected raw decode: %s (%v)", raw[0].JSON, err)
+	}
+}
+
+func TestCapturerRelaysWithReflection(t *testing.T) {
+	fileBytes, err := proto.Marshal(echoFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		switch r.URL.Path {
+		case "/" + reflectionService + "/" + reflectionMethod:
+			files := protowire.AppendTag(nil, fieldFileDescriptorProto, protowire.BytesType)
+			files = protowire.AppendBytes(files, fileBytes)
+			response := protowire.AppendTag(nil, fieldFileDescriptorResponse, protowire.BytesType)
+			response = protowire.AppendBytes(response, files)
+			_, _ = w.Write(EncodeFrame(response))
+		case "/demo.Echo/Say":
+			frames, _ := SplitFrames(body)
+			_, _ = w.Write(EncodeFrame(frames[0].Data))
+		default:
+			w.Header().Set("Grpc-Status", "12")
+			return
+		}
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	backend.Config.Protocols = new(http.Protocols)
+	backend.Config.Protocols.SetUnencryptedHTTP2(true)
+	backend.Start()
+	defer backend.Close()
+
+	capturer, err := New(config.GRPCCaptureConfig{Enable: true, Reflection: true, BackendURL: backend.URL})
+	if err != nil {
+		t.Fatalf("new capturer: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/demo.Echo/Say", nil)
+	r.ProtoMajor = 2
+	r.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+
+	call, err := capturer.Serve(w, r, EncodeFrame(echoMessage("ping", 1)))
+	if err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if call.Service != "demo.Echo" || call.Method != "Say" || call.Status != "0" {
+		t.Fatalf("unexpected call: %+v", call)
+	}
+	if len(call.Requests) != 1 || call.Requests[0].Raw || !bytes.Contains(call.Requests[0].JSON, []byte(`"ping"`)) {
+		t.Fatalf("request not decoded with the reflected schema: %+v", call.Requests)
+	}
+	if len(call.Responses) != 1 || !bytes.Contains(call.Responses[0].JSON, []byte(`"ping"`)) {
+		t.Fatalf("response not decoded: %+v", call.Responses)
+	}
+	if w.Body.Len() == 0 || w.Result().Trailer.Get("Grpc-Status") != "0" {
+		t.Fatalf("client did not receive the relayed answer: %q %v", w.Body.Bytes(), w.Result().Trailer)
+	}
+}
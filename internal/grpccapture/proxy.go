@@ -0,0 +1,209 @@
+package grpccapture
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxCapturedResponse caps how much of a relayed response stream is kept for decoding.
+const maxCapturedResponse = 4 << 20
+
+// hopHeaders are connection-specific and never relayed.
+var hopHeaders = map[string]bool{
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"Content-Length":    true,
+}
+
+// Backend relays gRPC calls to an upstream server over HTTP/2.
+type Backend struct {
+	base    *url.URL
+	client  *http.Client
+	timeout time.Duration
+}
+
+// relayResult is what the backend answered, as seen by the client.
+type relayResult struct {
+	body          []byte
+	truncated     bool
+	encoding      string
+	status        string
+	statusMessage string
+}
+
+// NewBackend builds a relay for http:// (h2c prior knowledge) or https:// upstreams.
+func NewBackend(rawURL string, timeout time.Duration) (*Backend, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	protocols := new(http.Protocols)
+	switch base.Scheme {
+	case "http":
+		protocols.SetUnencryptedHTTP2(true)
+	case "https":
+		protocols.SetHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unsupported gRPC backend scheme %q", base.Scheme)
+	}
+	transport := &http.Transport{
+		Protocols:       protocols,
+		TLSClientConfig: &tls.Config{NextProtos: []string{"h2"}},
+	}
+	return &Backend{
+		base:    base,
+		client:  &http.Client{Transport: transport},
+		timeout: timeout,
+	}, nil
+}
+
+// target resolves the gRPC method path against the backend base URL.
+func (b *Backend) target(service, method string) string {
+	target := *b.base
+	target.Path = strings.TrimSuffix(b.base.Path, "/") + "/" + service + "/" + method
+	target.RawQuery = ""
+	return target.String()
+}
+
+// relay sends the buffered call upstream and streams the answer back to w, trailers included.
+func (b *Backend) relay(w http.ResponseWriter, r *http.Request, service, method string, body []byte) (*relayResult, error) {
+	ctx := r.Context()
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.target(service, method), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		if hopHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		req.Header[key] = append([]string(nil), values...)
+	}
+	req.Header.Set("Te", "trailers")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		if hopHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	result := &relayResult{encoding: resp.Header.Get("Grpc-Encoding")}
+	captured := &limitedBuffer{limit: maxCapturedResponse}
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			captured.Write(buf[:n])
+			if _, err := w.Write(buf[:n]); err != nil {
+				return nil, err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	result.body = captured.Bytes()
+	result.truncated = captured.truncated
+
+	for key, values := range resp.Trailer {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+key, value)
+		}
+	}
+	// Trailers-only responses carry the status in the headers
+	result.status, result.statusMessage = resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if result.status == "" {
+		result.status, result.statusMessage = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	return result, nil
+}
+
+// call performs a unary call and returns the first response message.
+func (b *Backend) call(ctx context.Context, service, method string, message []byte) ([]byte, error) {
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.target(service, method), bytes.NewReader(EncodeFrame(message)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCapturedResponse))
+	if err != nil {
+		return nil, err
+	}
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if resp.StatusCode != http.StatusOK || (status != "" && status != "0") {
+		message := resp.Trailer.Get("Grpc-Message")
+		if message == "" {
+			message = resp.Header.Get("Grpc-Message")
+		}
+		return nil, fmt.Errorf("%s/%s failed: http %d, grpc-status %s %s", service, method, resp.StatusCode, status, message)
+	}
+	frames, err := SplitFrames(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s/%s returned no message", service, method)
+	}
+	return frames[0].Decompress(resp.Header.Get("Grpc-Encoding"))
+}
+
+// limitedBuffer keeps the first limit bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,116 @@
+package grpccapture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The reflection protocol is tiny, so its messages are encoded by hand instead of pulling in grpc-go.
+const (
+	reflectionService        = "grpc.reflection.v1.ServerReflection"
+	reflectionServiceV1Alpha = "grpc.reflection.v1alpha.ServerReflection"
+	reflectionMethod         = "ServerReflectionInfo"
+
+	// ServerReflectionRequest.file_containing_symbol
+	fieldFileContainingSymbol = 4
+	// ServerReflectionResponse.file_descriptor_response and error_response
+	fieldFileDescriptorResponse = 4
+	fieldErrorResponse          = 7
+	// FileDescriptorResponse.file_descriptor_proto
+	fieldFileDescriptorProto = 1
+	// ErrorResponse.error_message
+	fieldErrorMessage = 2
+)
+
+// reflect asks the backend for the file defining symbol and its dependencies, trying the
+// v1 service first and falling back to v1alpha for older servers.
+func (b *Backend) reflect(ctx context.Context, symbol string) ([]*descriptorpb.FileDescriptorProto, error) {
+	request := protowire.AppendTag(nil, fieldFileContainingSymbol, protowire.BytesType)
+	request = protowire.AppendString(request, symbol)
+
+	response, err := b.call(ctx, reflectionService, reflectionMethod, request)
+	if err != nil {
+		var alphaErr error
+		if response, alphaErr = b.call(ctx, reflectionServiceV1Alpha, reflectionMethod, request); alphaErr != nil {
+			return nil, err
+		}
+	}
+	return parseReflectionResponse(response)
+}
+
+func parseReflectionResponse(response []byte) ([]*descriptorpb.FileDescriptorProto, error) {
+	var files []*descriptorpb.FileDescriptorProto
+	for len(response) > 0 {
+		num, typ, n := protowire.ConsumeTag(response)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		response = response[n:]
+		if typ != protowire.BytesType || (num != fieldFileDescriptorResponse && num != fieldErrorResponse) {
+			n = protowire.ConsumeFieldValue(num, typ, response)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			response = response[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(response)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		response = response[n:]
+		if num == fieldErrorResponse {
+			return nil, fmt.Errorf("reflection: %s", nestedString(value, fieldErrorMessage))
+		}
+		for _, raw := range nestedBytes(value, fieldFileDescriptorProto) {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return nil, err
+			}
+			files = append(files, fd)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("reflection returned no file descriptors")
+	}
+	return files, nil
+}
+
+// nestedBytes collects every length-delimited value of field num in message.
+func nestedBytes(message []byte, num protowire.Number) [][]byte {
+	var values [][]byte
+	for len(message) > 0 {
+		fieldNum, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return values
+		}
+		message = message[n:]
+		if fieldNum == num && typ == protowire.BytesType {
+			value, m := protowire.ConsumeBytes(message)
+			if m < 0 {
+				return values
+			}
+			values = append(values, value)
+			message = message[m:]
+			continue
+		}
+		m := protowire.ConsumeFieldValue(fieldNum, typ, message)
+		if m < 0 {
+			return values
+		}
+		message = message[m:]
+	}
+	return values
+}
+
+func nestedString(message []byte, num protowire.Number) string {
+	if values := nestedBytes(message, num); len(values) > 0 {
+		return string(values[0])
+	}
+	return "unknown error"
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harDocument struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	ServerIPAddress string `json:"serverIPAddress"`
+	Request         struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		PostData    *struct {
+			MimeType string         `json:"mimeType"`
+			Params   []harNameValue `json:"params"`
+			Text     string         `json:"text"`
+			Encoding string         `json:"encoding"`
+			// ReqTap's own HAR export marks base64 request bodies with _encoding
+			ReqTapEncoding string `json:"_encoding"`
+		} `json:"postData"`
+	} `json:"request"`
+	Kind string `json:"_reqtapKind"`
+}
+
+// parseHAR converts HAR 1.2 entries; the forward entries ReqTap adds to its own exports are skipped.
+func parseHAR(data []byte) ([]*request.RequestData, error) {
+	var doc harDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid HAR document: %w", err)
+	}
+	items := make([]*request.RequestData, 0, len(doc.Log.Entries))
+	for i, entry := range doc.Log.Entries {
+		if entry.Kind == "forward" {
+			continue
+		}
+		item, err := harRequestData(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("HAR entry %d: %w", i, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func harRequestData(entry *harEntry) (*request.RequestData, error) {
+	body, contentType, err := harBody(entry)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(entry.Request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	r, err := http.NewRequest(method, entry.Request.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(entry.Request.HTTPVersion, "HTTP/") {
+		r.Proto = entry.Request.HTTPVersion
+	}
+	for _, header := range entry.Request.Headers {
+		switch {
+		case strings.HasPrefix(header.Name, ":"):
+			// HTTP/2 pseudo headers are implied by the method and URL
+		case strings.EqualFold(header.Name, "Host"):
+			r.Host = header.Value
+		default:
+			r.Header.Add(header.Name, header.Value)
+		}
+	}
+	if contentType != "" && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	var started time.Time
+	if entry.StartedDateTime != "" {
+		if started, err = time.Parse(time.RFC3339Nano, entry.StartedDateTime); err != nil {
+			return nil, fmt.Errorf("invalid startedDateTime: %w", err)
+		}
+	}
+	return build(r, body, started, ""), nil
+}
+
+// harBody returns the request body, rebuilding form bodies that were exported as params only.
+func harBody(entry *harEntry) ([]byte, string, error) {
+	post := entry.Request.PostData
+	if post == nil {
+		return nil, "", nil
+	}
+	if post.Text == "" && len(post.Params) > 0 {
+		form := url.Values{}
+		for _, param := range post.Params {
+			form.Add(param.Name, param.Value)
+		}
+		return []byte(form.Encode()), post.MimeType, nil
+	}
+	if post.Encoding == "base64" || post.ReqTapEncoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(post.Text)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid base64 body: %w", err)
+		}
+		return body, post.MimeType, nil
+	}
+	return []byte(post.Text), post.MimeType, nil
+}
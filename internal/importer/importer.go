@@ -0,0 +1,117 @@
+// Package importer converts traffic captured by other tools (HAR archives, ngrok inspect exports)
+// and the json/ndjson exports of ReqTap into requests that can be browsed and replayed like live
+// captures.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// ScenarioHeader tags every imported request with the scenario it belongs to, so a batch can be found with search.
+const ScenarioHeader = "X-ReqTap-Scenario"
+
+// Supported formats.
+const (
+	FormatAuto  = "auto"
+	FormatHAR   = "har"
+	FormatNgrok = "ngrok"
+	// FormatJSON and FormatNDJSON are the json and ndjson exports of ReqTap itself
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// ErrUnknownFormat is returned when auto-detection cannot tell the format apart.
+var ErrUnknownFormat = errors.New("unrecognized import format")
+
+// Parse converts the document into requests in capture order. An empty scenario leaves requests untagged.
+func Parse(data []byte, format, scenario string) ([]*request.RequestData, string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || format == FormatAuto {
+		format = Detect(data)
+		if format == "" {
+			return nil, "", ErrUnknownFormat
+		}
+	}
+
+	var (
+		items []*request.RequestData
+		err   error
+	)
+	switch format {
+	case FormatHAR:
+		items, err = parseHAR(data)
+	case FormatNgrok:
+		items, err = parseNgrok(data)
+	case FormatJSON, FormatNDJSON, "jsonl":
+		items, err = parseExport(data)
+	default:
+		return nil, "", fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+	if scenario = strings.TrimSpace(scenario); scenario != "" {
+		for _, item := range items {
+			item.Headers.Set(ScenarioHeader, scenario)
+		}
+	}
+	return items, format, nil
+}
+
+// Detect guesses the format from the top-level JSON shape; it returns "" when unsure.
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var first []json.RawMessage
+		if err := json.NewDecoder(bytes.NewReader(trimmed)).Decode(&first); err == nil && len(first) > 0 && isExportRecord(first[0]) {
+			return FormatJSON
+		}
+		return FormatNgrok
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &probe); err != nil {
+		// More than one object is an ndjson export
+		line, _, _ := bytes.Cut(trimmed, []byte("\n"))
+		if isExportRecord(line) {
+			return FormatNDJSON
+		}
+		return ""
+	}
+	if _, ok := probe["log"]; ok {
+		return FormatHAR
+	}
+	if _, ok := probe["requests"]; ok {
+		return FormatNgrok
+	}
+	if _, ok := probe["request"]; ok {
+		return FormatNgrok
+	}
+	if isExportRecord(trimmed) {
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// build wraps an http.Request the same way the capture handler does, then restores the original metadata.
+func build(r *http.Request, body []byte, timestamp time.Time, remoteAddr string) *request.RequestData {
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.RemoteAddr = remoteAddr
+	data := request.NewRequestData(r, body)
+	if !timestamp.IsZero() {
+		data.Timestamp = timestamp
+	}
+	if data.ContentLength < 0 {
+		data.ContentLength = int64(len(body))
+	}
+	return data
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+const sampleHAR = `{"log":{"version":"1.2","entries":[
+ {"startedDateTime":"2025-06-01T10:00:00.5Z","request":{"method":"post","url":"https://hooks.example.com/stripe/events?live=1","httpVersion":"HTTP/2",
+  "headers":[{"name":":authority","value":"hooks.example.com"},{"name":"Host","value":"hooks.example.com"},{"name":"Stripe-Signature","value":"t=1,v1=abc"}],
+  "postData":{"mimeType":"application/json","text":"{\"type\":\"charge.succeeded\"}"}},"response":{"status":200},"_reqtapKind":"capture"},
+ {"startedDateTime":"2025-06-01T10:00:00.6Z","request":{"method":"POST","url":"http://localhost:3000/webhook","headers":[]},"response":{"status":200},"_reqtapKind":"forward"},
+ {"startedDateTime":"2025-06-01T10:00:01Z","request":{"method":"POST","url":"https://hooks.example.com/form","headers":[],
+  "postData":{"mimeType":"application/x-www-form-urlencoded","params":[{"name":"a","value":"1"},{"name":"b","value":"x y"}]}}},
+ {"startedDateTime":"2025-06-01T10:00:02Z","request":{"method":"PUT","url":"https://hooks.example.com/blob","headers":[],
+  "postData":{"mimeType":"application/octet-stream","text":"AAEC","_encoding":"base64"}}}
+]}}`
+
+func TestParseHAR(t *testing.T) {
+	items, format, err := Parse([]byte(sampleHAR), "", "stripe-repro")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if format != FormatHAR {
+		t.Fatalf("expected har detection, got %q", format)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected forward entries to be skipped, got %d items", len(items))
+	}
+
+	first := items[0]
+	if first.Method != "POST" || first.Path != "/stripe/events" || first.Query != "live=1" || first.Proto != "HTTP/2" {
+		t.Fatalf("unexpected request line: %+v", first)
+	}
+	if string(first.Body) != `{"type":"charge.succeeded"}` || first.ContentType != "application/json" {
+		t.Fatalf("unexpected body %q (%s)", first.Body, first.ContentType)
+	}
+	if first.Headers.Get("Stripe-Signature") != "t=1,v1=abc" || first.Headers.Get(":authority") != "" || first.Headers.Get("Host") != "" {
+		t.Fatalf("unexpected headers: %v", first.Headers)
+	}
+	if first.Headers.Get(ScenarioHeader) != "stripe-repro" {
+		t.Fatalf("expected scenario tag, got %v", first.Headers)
+	}
+	if want := time.Date(2025, time.June, 1, 10, 0, 0, 5e8, time.UTC); !first.Timestamp.Equal(want) {
+		t.Fatalf("expected original timestamp, got %v", first.Timestamp)
+	}
+
+	if form := string(items[1].Body); form != "a=1&b=x+y" {
+		t.Fatalf("expected form params to be re-encoded, got %q", form)
+	}
+	if blob := items[2].Body; len(blob) != 3 || blob[2] != 2 || !items[2].IsBinary {
+		t.Fatalf("expected base64 body to be decoded, got %v", blob)
+	}
+}
+
+func TestParseNgrok(t *testing.T) {
+	raw := base64.StdEncoding.EncodeToString([]byte("POST /github?x=1 HTTP/1.1\r\nHost: abc.ngrok.app\r\nX-Github-Event: push\r\nContent-Type: application/json\r\nContent-Length: 11\r\n\r\n{\"ref\":\"m\"}"))
+	doc := `{"requests":[
+ {"id":"airt_2","remote_addr":"203.0.113.9","start":"2025-06-01T10:00:05Z","request":{"method":"GET","proto":"HTTP/1.1","uri":"/health","headers":{"Accept":["*/*"]}}},
+ {"id":"airt_1","remote_addr":"203.0.113.8","start":"2025-06-01T10:00:00Z","request":{"method":"POST","proto":"HTTP/1.1","uri":"/github?x=1","raw":"` + raw + `"}}
+]}`
+	items, format, err := Parse([]byte(doc), FormatAuto, "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if format != FormatNgrok || len(items) != 2 {
+		t.Fatalf("expected two ngrok requests, got %q/%d", format, len(items))
+	}
+
+	first := items[0]
+	if first.Path != "/github" || first.Query != "x=1" || string(first.Body) != `{"ref":"m"}` {
+		t.Fatalf("expected the raw capture oldest first, got %+v", first)
+	}
+	if first.Headers.Get("X-Github-Event") != "push" || first.RemoteAddr != "203.0.113.8" {
+		t.Fatalf("unexpected metadata: %v %s", first.Headers, first.RemoteAddr)
+	}
+	if first.Headers.Get(ScenarioHeader) != "" {
+		t.Fatalf("expected no scenario tag without a name")
+	}
+
+	second := items[1]
+	if second.Method != "GET" || second.Path != "/health" || len(second.Body) != 0 || second.Headers.Get("Accept") != "*/*" {
+		t.Fatalf("expected the header-only fallback, got %+v", second)
+	}
+}
+
+func TestParseReqTapExport(t *testing.T) {
+	newer := `{"id":"B","timestamp":"2025-06-01T10:00:05Z","method":"POST","proto":"HTTP/1.1","path":"/stripe","query":"live=1",` +
+		`"remote_addr":"203.0.113.9","headers":{"Content-Type":["application/json"],"Content-Encoding":["gzip"]},"body":"eyJhIjoxfQ==",` +
+		`"content_type":"application/json","content_length":20,"is_binary":false,"size":7,"mock_response":{"rule":"ok","status":202},` +
+		`"content_encoding":"gzip","wire_size":20,"tags":["repro"]}`
+	older := `{"id":"A","timestamp":"2025-06-01T10:00:00Z","method":"GET","path":"/health","headers":{"Accept":["*/*"]},"body":null,"mock_response":{"rule":"","status":0}}`
+
+	for name, doc := range map[string]string{
+		FormatJSON:   "[" + newer + "," + older + "]",
+		FormatNDJSON: newer + "\n" + older + "\n",
+	} {
+		items, format, err := Parse([]byte(doc), "", "moved")
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		if format != name || len(items) != 2 {
+			t.Fatalf("%s: expected two requests, got %q/%d", name, format, len(items))
+		}
+		if items[0].Path != "/health" || items[0].Method != "GET" {
+			t.Fatalf("%s: expected the oldest request first, got %+v", name, items[0])
+		}
+		second := items[1]
+		if second.ID == "B" || second.ID == "" {
+			t.Fatalf("%s: expected a new ID, got %q", name, second.ID)
+		}
+		if second.Path != "/stripe" || second.Query != "live=1" || string(second.Body) != `{"a":1}` || second.RemoteAddr != "203.0.113.9" {
+			t.Fatalf("%s: unexpected request: %+v", name, second)
+		}
+		if second.MockResponse.Status != 202 || second.ContentEncoding != "gzip" || second.ContentLength != 20 {
+			t.Fatalf("%s: expected the capture metadata to be kept, got %+v", name, second)
+		}
+		if second.Headers.Get("Content-Encoding") != "" || second.Headers.Get(ScenarioHeader) != "moved" {
+			t.Fatalf("%s: unexpected headers: %v", name, second.Headers)
+		}
+		if want := time.Date(2025, time.June, 1, 10, 0, 5, 0, time.UTC); !second.Timestamp.Equal(want) {
+			t.Fatalf("%s: expected the capture time to be kept, got %v", name, second.Timestamp)
+		}
+	}
+}
+
+func TestParseRejectsUnknownDocuments(t *testing.T) {
+	if _, _, err := Parse([]byte(`{"foo":1}`), "", ""); err != ErrUnknownFormat {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, _, err := Parse([]byte(`{}`), "curl", ""); err == nil {
+		t.Fatalf("expected unsupported format error")
+	}
+}
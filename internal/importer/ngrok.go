@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// ngrokRequest is one item of the ngrok agent API (GET /api/requests/http) as saved from the inspector.
+type ngrokRequest struct {
+	ID         string    `json:"id"`
+	RemoteAddr string    `json:"remote_addr"`
+	Start      time.Time `json:"start"`
+	Request    struct {
+		Method  string      `json:"method"`
+		Proto   string      `json:"proto"`
+		Headers http.Header `json:"headers"`
+		URI     string      `json:"uri"`
+		// Raw is the base64-encoded request as received on the wire
+		Raw []byte `json:"raw"`
+	} `json:"request"`
+}
+
+// parseNgrok accepts the {"requests": [...]} listing, a bare array, or a single request; ngrok lists newest first.
+func parseNgrok(data []byte) ([]*request.RequestData, error) {
+	var captured []ngrokRequest
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &captured); err != nil {
+			return nil, fmt.Errorf("invalid ngrok export: %w", err)
+		}
+	default:
+		var doc struct {
+			Requests []ngrokRequest `json:"requests"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("invalid ngrok export: %w", err)
+		}
+		captured = doc.Requests
+		if captured == nil {
+			var single ngrokRequest
+			if err := json.Unmarshal(trimmed, &single); err != nil {
+				return nil, fmt.Errorf("invalid ngrok export: %w", err)
+			}
+			captured = []ngrokRequest{single}
+		}
+	}
+
+	items := make([]*request.RequestData, 0, len(captured))
+	for i := len(captured) - 1; i >= 0; i-- {
+		item, err := ngrokRequestData(&captured[i])
+		if err != nil {
+			return nil, fmt.Errorf("ngrok request %s: %w", captured[i].ID, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func ngrokRequestData(captured *ngrokRequest) (*request.RequestData, error) {
+	if len(captured.Request.Raw) > 0 {
+		if r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(captured.Request.Raw))); err == nil {
+			// The inspector caps raw captures, so keep whatever part of the body was recorded
+			body, _ := io.ReadAll(r.Body)
+			r.Body.Close()
+			return build(r, body, captured.Start, captured.RemoteAddr), nil
+		}
+	}
+
+	// Without a usable raw capture only the request line and headers can be restored
+	uri := captured.Request.URI
+	if uri == "" {
+		uri = "/"
+	}
+	method := strings.ToUpper(captured.Request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	r, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if captured.Request.Proto != "" {
+		r.Proto = captured.Request.Proto
+	}
+	r.Header = captured.Request.Headers.Clone()
+	r.ContentLength = 0
+	return build(r, nil, captured.Start, captured.RemoteAddr), nil
+}
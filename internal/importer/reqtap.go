@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// parseExport converts the json (an array) or ndjson (one object per line) export of ReqTap. The
+// requests get new IDs, so a set can be imported next to the requests it was exported from.
+func parseExport(data []byte) ([]*request.RequestData, error) {
+	var exported []*request.RequestData
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &exported); err != nil {
+			return nil, fmt.Errorf("invalid ReqTap export: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		for line := 1; ; line++ {
+			var item request.RequestData
+			if err := decoder.Decode(&item); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("invalid ReqTap export record %d: %w", line, err)
+			}
+			exported = append(exported, &item)
+		}
+	}
+
+	items := make([]*request.RequestData, 0, len(exported))
+	for i, original := range exported {
+		if original == nil {
+			continue
+		}
+		item, err := exportedRequestData(original)
+		if err != nil {
+			return nil, fmt.Errorf("ReqTap export record %d: %w", i+1, err)
+		}
+		items = append(items, item)
+	}
+	// Exports list the newest request first
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Timestamp.Before(items[j].Timestamp) })
+	return items, nil
+}
+
+func exportedRequestData(original *request.RequestData) (*request.RequestData, error) {
+	method := strings.ToUpper(original.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	target := &url.URL{Path: original.Path, RawQuery: original.Query}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+	r, err := http.NewRequest(method, target.String(), bytes.NewReader(original.Body))
+	if err != nil {
+		return nil, err
+	}
+	if original.Proto != "" {
+		r.Proto = original.Proto
+	}
+	r.Header = original.Headers.Clone()
+	if original.ContentEncoding != "" {
+		// Exports carry the decoded body, so it is no longer encoded as the header says
+		r.Header.Del("Content-Encoding")
+	}
+
+	item := build(r, original.Body, original.Timestamp, original.RemoteAddr)
+	// A spilled body is exported as its preview, which is all that is imported; the declared
+	// length still tells the original size
+	item.ContentLength = original.ContentLength
+	item.MockResponse = original.MockResponse
+	item.Instance = original.Instance
+	item.Credential = original.Credential
+	item.GRPC = original.GRPC
+	item.Protobuf = original.Protobuf
+	item.ContentEncoding = original.ContentEncoding
+	item.WireSize = original.WireSize
+	item.Fingerprint = original.Fingerprint
+	return item, nil
+}
+
+// isExportRecord reports whether raw is a JSON object shaped like an exported request.
+func isExportRecord(raw []byte) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return false
+	}
+	_, hasMethod := probe["method"]
+	_, hasPath := probe["path"]
+	return hasMethod && hasPath
+}
@@ -0,0 +1,172 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup resolves a dotted path (e.g. "data.items.0.id" or "$.data.items[0].id")
+// against a decoded JSON document.
+func Lookup(doc interface{}, path string) (interface{}, bool) {
+	segments := Split(path)
+	current := doc
+	for _, seg := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// LookupBytes decodes raw JSON and resolves the path against it.
+func LookupBytes(body []byte, path string) (interface{}, bool) {
+	doc, err := Decode(body)
+	if err != nil {
+		return nil, false
+	}
+	return Lookup(doc, path)
+}
+
+// Decode parses raw JSON preserving numbers as json.Number so they render verbatim.
+func Decode(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Split breaks a path expression into segments, tolerating a leading "$" and bracket indexes.
+func Split(path string) []string {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	parts := strings.Split(path, ".")
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.Trim(strings.TrimSpace(part), `"'`)
+		if part == "" {
+			continue
+		}
+		segments = append(segments, part)
+	}
+	return segments
+}
+
+// Stringify renders a resolved value for comparisons and templating.
+// Strings are returned verbatim, null becomes "null" and composite values are JSON encoded.
+func Stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// Set stores value at path inside doc, creating missing objects along the way, and returns the
+// updated document. It fails when an intermediate segment is not an object or an array index is
+// out of range; an empty path replaces the whole document.
+func Set(doc interface{}, path string, value interface{}) (interface{}, bool) {
+	return set(doc, Split(path), value)
+}
+
+func set(node interface{}, segments []string, value interface{}) (interface{}, bool) {
+	if len(segments) == 0 {
+		return value, true
+	}
+	seg, rest := segments[0], segments[1:]
+	switch current := node.(type) {
+	case map[string]interface{}:
+		child, ok := set(current[seg], rest, value)
+		if !ok {
+			return node, false
+		}
+		current[seg] = child
+		return current, true
+	case []interface{}:
+		idx, err := strconv.Atoi(seg)
+		if err != nil || idx < 0 || idx >= len(current) {
+			return node, false
+		}
+		child, ok := set(current[idx], rest, value)
+		if !ok {
+			return node, false
+		}
+		current[idx] = child
+		return current, true
+	case nil:
+		child, ok := set(nil, rest, value)
+		if !ok {
+			return node, false
+		}
+		return map[string]interface{}{seg: child}, true
+	default:
+		return node, false
+	}
+}
+
+// Delete removes the value at path from doc and reports whether it existed. Array elements are
+// removed and the following elements shift down.
+func Delete(doc interface{}, path string) (interface{}, bool) {
+	segments := Split(path)
+	if len(segments) == 0 {
+		return doc, false
+	}
+	parentPath, last := segments[:len(segments)-1], segments[len(segments)-1]
+	parent := doc
+	for _, seg := range parentPath {
+		var ok bool
+		if parent, ok = Lookup(parent, seg); !ok {
+			return doc, false
+		}
+	}
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return doc, false
+		}
+		delete(node, last)
+		return doc, true
+	case []interface{}:
+		idx, err := strconv.Atoi(last)
+		if err != nil || idx < 0 || idx >= len(node) {
+			return doc, false
+		}
+		trimmed := append(node[:idx:idx], node[idx+1:]...)
+		return set(doc, parentPath, trimmed)
+	default:
+		return doc, false
+	}
+}
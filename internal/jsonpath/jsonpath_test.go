@@ -0,0 +1,67 @@
+package jsonpath
+
+import "testing"
+
+func TestLookupBytes(t *testing.T) {
+	body := []byte(`{"data":{"items":[{"id":42,"ok":true}],"name":"demo"}}`)
+
+	tests := []struct {
+		path   string
+		want   string
+		exists bool
+	}{
+		{"data.name", "demo", true},
+		{"$.data.items[0].id", "42", true},
+		{"data.items.0.ok", "true", true},
+		{"data.items.1.id", "", false},
+		{"data.missing", "", false},
+		{"data.items", `[{"id":42,"ok":true}]`, true},
+	}
+
+	for _, tt := range tests {
+		value, ok := LookupBytes(body, tt.path)
+		if ok != tt.exists {
+			t.Fatalf("path %s: expected exists=%v, got %v", tt.path, tt.exists, ok)
+		}
+		if ok && Stringify(value) != tt.want {
+			t.Fatalf("path %s: expected %s, got %s", tt.path, tt.want, Stringify(value))
+		}
+	}
+}
+
+func TestLookupBytesInvalidJSON(t *testing.T) {
+	if _, ok := LookupBytes([]byte("not json"), "a"); ok {
+		t.Fatal("expected lookup on invalid JSON to fail")
+	}
+}
+
+func TestSetAndDelete(t *testing.T) {
+	doc, err := Decode([]byte(`{"event":{"type":"push","tags":["a","b","c"]},"sig":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ok bool
+	if doc, ok = Set(doc, "meta.source", "reqtap"); !ok {
+		t.Fatal("expected set to create missing objects")
+	}
+	if doc, ok = Set(doc, "event.tags[0]", "z"); !ok {
+		t.Fatal("expected set to replace an array element")
+	}
+	if _, ok = Set(doc, "sig.nested", 1); ok {
+		t.Fatal("expected set below a string to fail")
+	}
+	if doc, ok = Delete(doc, "event.tags.1"); !ok {
+		t.Fatal("expected delete of an array element")
+	}
+	if doc, ok = Delete(doc, "sig"); !ok {
+		t.Fatal("expected delete of a field")
+	}
+	if _, ok = Delete(doc, "missing.field"); ok {
+		t.Fatal("expected delete of a missing path to report false")
+	}
+
+	want := `{"event":{"tags":["z","c"],"type":"push"},"meta":{"source":"reqtap"}}`
+	if got := Stringify(doc); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
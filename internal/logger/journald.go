@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// journalSocket is where systemd-journald accepts its native protocol
+var journalSocket = "/run/systemd/journal/socket"
+
+// journaldWriter sends each log event to journald with the message in MESSAGE and every event
+// field as a journal field of its own, e.g. request_id becomes REQUEST_ID.
+type journaldWriter struct {
+	mu   sync.Mutex
+	tag  string
+	pid  string
+	conn net.Conn
+	// retryAt holds off connecting after a failed attempt
+	retryAt time.Time
+}
+
+func newJournaldWriter(cfg config.LogOutputConfig) *journaldWriter {
+	return &journaldWriter{tag: cfg.Tag, pid: strconv.Itoa(os.Getpid())}
+}
+
+// Write implements io.Writer for events without a level
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (w *journaldWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	fields := map[string]string{
+		"PRIORITY":          strconv.Itoa(syslogSeverity(level)),
+		"SYSLOG_IDENTIFIER": w.tag,
+		"SYSLOG_PID":        w.pid,
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(p, &event); err != nil {
+		fields["MESSAGE"] = string(bytes.TrimRight(p, "\n"))
+	}
+	for key, value := range event {
+		switch key {
+		case zerolog.LevelFieldName, zerolog.TimestampFieldName:
+			continue
+		case zerolog.MessageFieldName:
+			fields["MESSAGE"] = fmt.Sprint(value)
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			raw, _ := json.Marshal(value)
+			text = string(raw)
+		}
+		fields[journalFieldName(key)] = text
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if time.Now().Before(w.retryAt) {
+			return len(p), nil
+		}
+		conn, err := net.Dial("unixgram", journalSocket)
+		if err != nil {
+			w.retryAt = time.Now().Add(redialDelay)
+			return 0, fmt.Errorf("journald: %w", err)
+		}
+		w.conn = conn
+	}
+	if _, err := w.conn.Write(encodeJournalFields(fields)); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, fmt.Errorf("journald: %w", err)
+	}
+	return len(p), nil
+}
+
+// journalFieldName turns an event key into a journal field name: upper case letters, digits and
+// underscores, not starting with an underscore or digit
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "F" + name
+	}
+	return name
+}
+
+// encodeJournalFields serializes fields in the journal native protocol; values with a line break
+// use the length-prefixed binary form
+func encodeJournalFields(fields map[string]string) []byte {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		value := fields[key]
+		buf.WriteString(key)
+		if strings.Contains(value, "\n") {
+			buf.WriteByte('\n')
+			binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		} else {
+			buf.WriteByte('=')
+		}
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/fatih/color"
 	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/rs/zerolog"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -115,12 +116,16 @@ func NewLogger(cfg *config.LogConfig, outputMode string) Logger {
 	var writers []io.Writer
 	structured := strings.ToLower(outputMode) == "json"
 
-	if structured {
+	switch {
+	case structured:
 		writers = append(writers, os.Stdout)
-	} else {
+	case strings.ToLower(outputMode) == "tui":
+		// The terminal UI owns the screen; logs only go to the log file
+	default:
 		consoleWriter := zerolog.ConsoleWriter{
 			Out:        os.Stdout,
 			TimeFormat: "2006-01-02 15:04:05",
+			NoColor:    color.NoColor,
 		}
 		writers = append(writers, consoleWriter)
 	}
@@ -138,8 +143,17 @@ func NewLogger(cfg *config.LogConfig, outputMode string) Logger {
 		writers = append(writers, fileWriter)
 	}
 
-	// Create multi-output writer
-	multiWriter := io.MultiWriter(writers...)
+	for _, output := range cfg.Outputs {
+		switch output.Type {
+		case "syslog":
+			writers = append(writers, newSyslogWriter(output))
+		case "journald":
+			writers = append(writers, newJournaldWriter(output))
+		}
+	}
+
+	// Create multi-output writer; syslog and journald take the level of each event
+	multiWriter := zerolog.MultiLevelWriter(writers...)
 
 	// Create logger
 	logger := zerolog.New(multiWriter).Level(logLevel).With().Timestamp().Logger()
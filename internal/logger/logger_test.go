@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestSyslogOutput(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	log := NewLogger(&config.LogConfig{Level: "info", Outputs: []config.LogOutputConfig{
+		{Type: "syslog", Network: "udp", Address: udp.LocalAddr().String(), Facility: "local0", Tag: "reqtap"},
+		{Type: "syslog", Network: "tcp", Address: tcp.Addr().String(), Facility: "user", Tag: "reqtap"},
+	}}, "tui")
+	log.Warn("Forward failed", "target", "http://hook")
+
+	udp.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0 (16) * 8 + warning (4)
+	pattern := regexp.MustCompile(`^<132>1 \S+ \S+ reqtap \d+ - - \{.*"target":"http://hook".*"message":"Forward failed"\}$`)
+	if !pattern.Match(buf[:n]) {
+		t.Fatalf("unexpected syslog datagram: %s", buf[:n])
+	}
+
+	conn, err := tcp.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('}')
+	if err != nil {
+		t.Fatal(err)
+	}
+	length, msg, _ := strings.Cut(line, " ")
+	if !strings.HasPrefix(msg, "<12>1 ") || length != strconv.Itoa(len(msg)) {
+		t.Fatalf("expected an octet-counted frame, got %q", line)
+	}
+}
+
+func TestJournaldOutput(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+	previous := journalSocket
+	journalSocket = socket
+	defer func() { journalSocket = previous }()
+
+	log := NewLogger(&config.LogConfig{Level: "info", Outputs: []config.LogOutputConfig{{Type: "journald", Tag: "reqtap"}}}, "tui")
+	log.Error("Request failed", "request_id", "abc", "error", "line one\nline two")
+
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datagram := string(buf[:n])
+	for _, want := range []string{"MESSAGE=Request failed\n", "PRIORITY=3\n", "REQUEST_ID=abc\n", "SYSLOG_IDENTIFIER=reqtap\n"} {
+		if !strings.Contains(datagram, want) {
+			t.Fatalf("expected %q in %q", want, datagram)
+		}
+	}
+	value := "line one\nline two"
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	if !strings.Contains(datagram, "ERROR\n"+string(size[:])+value+"\n") {
+		t.Fatalf("expected a multi-line value in binary form, got %q", datagram)
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// rfc5424Time is the TIMESTAMP of RFC 5424, which allows at most microseconds
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// redialDelay is how long events are dropped after a log server could not be reached, so that an
+// unreachable server neither blocks every log call nor floods stderr with write errors
+const redialDelay = 10 * time.Second
+
+// syslogWriter sends each log event as an RFC 5424 message whose MSG is the JSON event. Over TCP
+// messages are framed by octet counting (RFC 6587); a broken connection is re-dialled on the next
+// event.
+type syslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	pid      int
+	conn     net.Conn
+	// retryAt holds off dialling after a failed attempt
+	retryAt time.Time
+}
+
+func newSyslogWriter(cfg config.LogOutputConfig) *syslogWriter {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogWriter{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: config.SyslogFacilities[cfg.Facility],
+		tag:      cfg.Tag,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+}
+
+// Write implements io.Writer for events without a level
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (w *syslogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", w.facility*8+syslogSeverity(level),
+		time.Now().Format(rfc5424Time), w.hostname, w.tag, w.pid, bytes.TrimRight(p, "\n"))
+	if w.network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// One retry covers a server that closed an idle TCP connection
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if time.Now().Before(w.retryAt) {
+				return len(p), nil
+			}
+			if w.conn, err = net.DialTimeout(w.network, w.address, 5*time.Second); err != nil {
+				w.conn = nil
+				w.retryAt = time.Now().Add(redialDelay)
+				return 0, fmt.Errorf("syslog %s: %w", w.address, err)
+			}
+		}
+		if _, err = w.conn.Write([]byte(msg)); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, fmt.Errorf("syslog %s: %w", w.address, err)
+}
+
+// syslogSeverity maps a zerolog level to an RFC 5424 severity
+func syslogSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return 7
+	case zerolog.InfoLevel:
+		return 6
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.PanicLevel:
+		return 0
+	}
+	return 5
+}
@@ -0,0 +1,125 @@
+// Package mocktemplate renders mock response bodies and headers with Go-template placeholders
+// filled in from the captured request, e.g. {{.Method}}, {{.Header "X-Id"}} or {{.JSONBody "user.id"}}.
+package mocktemplate
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+)
+
+// funcs are available in every template.
+var funcs = template.FuncMap{
+	"uuid": uuid.NewString,
+	// now renders the current UTC time as RFC 3339, or with the given Go layout
+	"now": func(layout ...string) string {
+		ts := time.Now().UTC()
+		if len(layout) > 0 && layout[0] != "" {
+			return ts.Format(layout[0])
+		}
+		return ts.Format(time.RFC3339)
+	},
+	"unix": func() int64 {
+		return time.Now().Unix()
+	},
+}
+
+// Template is a parsed placeholder template.
+type Template struct {
+	tmpl *template.Template
+}
+
+// IsTemplate reports whether text contains placeholders; static text is served verbatim.
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// Parse compiles text; it returns nil without error when text holds no placeholders.
+func Parse(name, text string) (*Template, error) {
+	if !IsTemplate(text) {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render executes the template against the request data.
+func (t *Template) Render(data *Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Data exposes the captured request to templates.
+type Data struct {
+	ID         string
+	Method     string
+	Path       string
+	Query      string
+	RemoteAddr string
+	Body       string
+
+	headers http.Header
+	rawBody []byte
+
+	queryOnce sync.Once
+	query     url.Values
+	jsonOnce  sync.Once
+	json      interface{}
+	jsonErr   error
+}
+
+// NewData wraps the request fields used by templates.
+func NewData(id, method, path, rawQuery, remoteAddr string, headers http.Header, body []byte) *Data {
+	return &Data{
+		ID:         id,
+		Method:     method,
+		Path:       path,
+		Query:      rawQuery,
+		RemoteAddr: remoteAddr,
+		Body:       string(body),
+		headers:    headers,
+		rawBody:    body,
+	}
+}
+
+// Header returns the first value of the named request header.
+func (d *Data) Header(name string) string {
+	return d.headers.Get(name)
+}
+
+// QueryParam returns the first value of the named query parameter.
+func (d *Data) QueryParam(name string) string {
+	d.queryOnce.Do(func() {
+		d.query, _ = url.ParseQuery(d.Query)
+	})
+	return d.query.Get(name)
+}
+
+// JSONBody resolves a dotted path against the JSON request body; missing values render empty.
+func (d *Data) JSONBody(path string) string {
+	d.jsonOnce.Do(func() {
+		d.json, d.jsonErr = jsonpath.Decode(d.rawBody)
+	})
+	if d.jsonErr != nil {
+		return ""
+	}
+	value, ok := jsonpath.Lookup(d.json, path)
+	if !ok {
+		return ""
+	}
+	return jsonpath.Stringify(value)
+}
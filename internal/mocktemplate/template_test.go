@@ -0,0 +1,47 @@
+package mocktemplate
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestRenderRequestPlaceholders(t *testing.T) {
+	tmpl, err := Parse("body", `{"method":"{{.Method}}","path":"{{.Path}}","id":"{{.Header "X-Id"}}","user":{{.JSONBody "user.id"}},"page":"{{.QueryParam "page"}}","missing":"{{.JSONBody "nope"}}"}`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	data := NewData("REQ1", "POST", "/users", "page=2", "127.0.0.1", http.Header{"X-Id": {"abc"}}, []byte(`{"user":{"id":42}}`))
+	got, err := tmpl.Render(data)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	want := `{"method":"POST","path":"/users","id":"abc","user":42,"page":"2","missing":""}`
+	if got != want {
+		t.Fatalf("unexpected render:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRenderFunctions(t *testing.T) {
+	tmpl, err := Parse("body", `{{uuid}} {{now "2006"}} {{now}}`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	got, err := tmpl.Render(NewData("", "GET", "/", "", "", nil, nil))
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12} \d{4} \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+	if !pattern.MatchString(got) {
+		t.Fatalf("unexpected render %q", got)
+	}
+}
+
+func TestParseStaticAndInvalid(t *testing.T) {
+	if tmpl, err := Parse("body", `{"ok":true}`); tmpl != nil || err != nil {
+		t.Fatalf("static text should not be compiled: %v %v", tmpl, err)
+	}
+	if _, err := Parse("body", `{{.Method`); err == nil {
+		t.Fatalf("expected a parse error")
+	}
+}
@@ -0,0 +1,294 @@
+// Package parquet writes small, flat Apache Parquet files: one row group of required INT64,
+// DOUBLE and timestamp columns, PLAIN encoded and uncompressed. It covers the aggregate exports
+// and leaves nested schemas, nulls and compression to full Parquet libraries.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Kind is the type of a column.
+type Kind int
+
+const (
+	// Int64 columns hold Column.Int64
+	Int64 Kind = iota
+	// Double columns hold Column.Double
+	Double
+	// TimestampMillis columns hold Unix milliseconds in Column.Int64, annotated as UTC timestamps
+	TimestampMillis
+)
+
+// Column is one column of the file; every column must hold the same number of values.
+type Column struct {
+	Name   string
+	Kind   Kind
+	Int64  []int64
+	Double []float64
+}
+
+func (c *Column) len() int {
+	if c.Kind == Double {
+		return len(c.Double)
+	}
+	return len(c.Int64)
+}
+
+// physicalType returns the parquet.thrift Type of the column
+func (c *Column) physicalType() int32 {
+	if c.Kind == Double {
+		return typeDouble
+	}
+	return typeInt64
+}
+
+// plain encodes the values of the column with the PLAIN encoding
+func (c *Column) plain() []byte {
+	buf := make([]byte, 0, 8*c.len())
+	if c.Kind == Double {
+		for _, v := range c.Double {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+		return buf
+	}
+	for _, v := range c.Int64 {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+	}
+	return buf
+}
+
+// Constants of parquet.thrift
+const (
+	magic = "PAR1"
+
+	typeInt64  = 2
+	typeDouble = 5
+
+	repetitionRequired = 0
+
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+
+	pageTypeData = 0
+)
+
+// Write writes the columns as a Parquet file with a single row group.
+func Write(w io.Writer, columns []Column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("parquet: no columns")
+	}
+	rows := columns[0].len()
+	for i := range columns {
+		if columns[i].len() != rows {
+			return fmt.Errorf("parquet: column %s has %d values, expected %d", columns[i].Name, columns[i].len(), rows)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+	chunks := make([]chunk, len(columns))
+	if rows > 0 {
+		for i := range columns {
+			data := columns[i].plain()
+			header := newEncoder()
+			header.i32Field(1, pageTypeData)
+			header.i32Field(2, int32(len(data)))
+			header.i32Field(3, int32(len(data)))
+			header.structField(5)
+			header.i32Field(1, int32(rows))
+			header.i32Field(2, encodingPlain)
+			header.i32Field(3, encodingRLE)
+			header.i32Field(4, encodingRLE)
+			header.endStruct()
+			header.endStruct()
+
+			chunks[i].offset = int64(file.Len())
+			file.Write(header.bytes())
+			file.Write(data)
+			chunks[i].size = int64(file.Len()) - chunks[i].offset
+		}
+	}
+
+	meta := newEncoder()
+	meta.i32Field(1, 1)
+	meta.listField(2, compactStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(columns)))
+	meta.endStruct()
+	for i := range columns {
+		meta.beginStruct()
+		meta.i32Field(1, columns[i].physicalType())
+		meta.i32Field(3, repetitionRequired)
+		meta.stringField(4, columns[i].Name)
+		if columns[i].Kind == TimestampMillis {
+			meta.i32Field(6, convertedTimestampMillis)
+			// LogicalType{TIMESTAMP: TimestampType{isAdjustedToUTC: true, unit: TimeUnit{MILLIS}}}
+			meta.structField(10)
+			meta.structField(8)
+			meta.boolField(1, true)
+			meta.structField(2)
+			meta.structField(1)
+			meta.endStruct()
+			meta.endStruct()
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.endStruct()
+	}
+	meta.i64Field(3, int64(rows))
+	if rows == 0 {
+		meta.listField(4, compactStruct, 0)
+	} else {
+		var total int64
+		for _, c := range chunks {
+			total += c.size
+		}
+		meta.listField(4, compactStruct, 1)
+		meta.beginStruct()
+		meta.listField(1, compactStruct, len(columns))
+		for i := range columns {
+			meta.beginStruct()
+			meta.i64Field(2, chunks[i].offset)
+			meta.structField(3)
+			meta.i32Field(1, columns[i].physicalType())
+			meta.listField(2, compactI32, 2)
+			meta.varint(zigzag(encodingPlain))
+			meta.varint(zigzag(encodingRLE))
+			meta.listField(3, compactBinary, 1)
+			meta.binary(columns[i].Name)
+			meta.i32Field(4, codecUncompressed)
+			meta.i64Field(5, int64(rows))
+			meta.i64Field(6, chunks[i].size)
+			meta.i64Field(7, chunks[i].size)
+			meta.i64Field(9, chunks[i].offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64Field(2, total)
+		meta.i64Field(3, int64(rows))
+		meta.endStruct()
+	}
+	meta.stringField(6, "reqtap")
+	meta.endStruct()
+
+	footer := meta.bytes()
+	file.Write(footer)
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	file.WriteString(magic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// Type ids of the Thrift compact protocol
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// encoder writes Thrift compact protocol structs. The top-level struct is open from the start and
+// is closed by the last endStruct.
+type encoder struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func newEncoder() *encoder {
+	return &encoder{last: []int16{0}}
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func (e *encoder) fieldHeader(id int16, typ byte) {
+	last := &e.last[len(e.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		e.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		e.buf.WriteByte(typ)
+		e.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (e *encoder) varint(v uint64) {
+	e.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (e *encoder) binary(s string) {
+	e.varint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) i32Field(id int16, v int32) {
+	e.fieldHeader(id, compactI32)
+	e.varint(zigzag(int64(v)))
+}
+
+func (e *encoder) i64Field(id int16, v int64) {
+	e.fieldHeader(id, compactI64)
+	e.varint(zigzag(v))
+}
+
+func (e *encoder) boolField(id int16, v bool) {
+	if v {
+		e.fieldHeader(id, compactTrue)
+	} else {
+		e.fieldHeader(id, compactFalse)
+	}
+}
+
+func (e *encoder) stringField(id int16, s string) {
+	e.fieldHeader(id, compactBinary)
+	e.binary(s)
+}
+
+// listField starts a list; the caller writes its size elements right after.
+func (e *encoder) listField(id int16, elem byte, size int) {
+	e.fieldHeader(id, compactList)
+	if size < 15 {
+		e.buf.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	e.buf.WriteByte(0xf0 | elem)
+	e.varint(uint64(size))
+}
+
+// structField starts a struct-typed field; close it with endStruct.
+func (e *encoder) structField(id int16) {
+	e.fieldHeader(id, compactStruct)
+	e.beginStruct()
+}
+
+// beginStruct starts a struct element of a list; close it with endStruct.
+func (e *encoder) beginStruct() {
+	e.last = append(e.last, 0)
+}
+
+func (e *encoder) endStruct() {
+	e.buf.WriteByte(0)
+	e.last = e.last[:len(e.last)-1]
+}
@@ -0,0 +1,141 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// decoder reads Thrift compact structs into maps keyed by field id, enough to check the output.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) byte() byte {
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		panic("bad varint")
+	}
+	d.pos += n
+	return v
+}
+
+func (d *decoder) int() int64 {
+	u := d.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (d *decoder) value(typ byte) interface{} {
+	switch typ {
+	case compactTrue:
+		return true
+	case compactFalse:
+		return false
+	case compactI32, compactI64:
+		return d.int()
+	case compactBinary:
+		n := int(d.uvarint())
+		s := string(d.data[d.pos : d.pos+n])
+		d.pos += n
+		return s
+	case compactList:
+		header := d.byte()
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = d.value(elem)
+		}
+		return list
+	case compactStruct:
+		fields := map[int64]interface{}{}
+		var last int64
+		for {
+			header := d.byte()
+			if header == 0 {
+				return fields
+			}
+			typ := header & 0x0f
+			if delta := int64(header >> 4); delta > 0 {
+				last += delta
+			} else {
+				last = d.int()
+			}
+			fields[last] = d.value(typ)
+		}
+	}
+	panic(fmt.Sprintf("unexpected type %d", typ))
+}
+
+func TestWrite(t *testing.T) {
+	columns := []Column{
+		{Name: "interval_start", Kind: TimestampMillis, Int64: []int64{1700000000000, 1700003600000}},
+		{Name: "requests", Kind: Int64, Int64: []int64{12, 0}},
+		{Name: "avg_size_bytes", Kind: Double, Double: []float64{40.5, 0}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, columns); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	file := buf.Bytes()
+	if string(file[:4]) != magic || string(file[len(file)-4:]) != magic {
+		t.Fatal("expected PAR1 magic at both ends")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &decoder{data: file[len(file)-8-footerLen : len(file)-8]}
+	meta := footer.value(compactStruct).(map[int64]interface{})
+	if footer.pos != footerLen {
+		t.Fatalf("footer decoded %d of %d bytes", footer.pos, footerLen)
+	}
+	if meta[3] != int64(2) {
+		t.Fatalf("expected 2 rows, got %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 4 || schema[0].(map[int64]interface{})[5] != int64(3) {
+		t.Fatalf("unexpected schema: %v", schema)
+	}
+	if ts := schema[1].(map[int64]interface{}); ts[4] != "interval_start" || ts[6] != int64(convertedTimestampMillis) {
+		t.Fatalf("unexpected timestamp column: %v", ts)
+	}
+
+	chunks := meta[4].([]interface{})[0].(map[int64]interface{})[1].([]interface{})
+	for i, raw := range chunks {
+		colMeta := raw.(map[int64]interface{})[3].(map[int64]interface{})
+		offset := int(colMeta[9].(int64))
+		page := &decoder{data: file, pos: offset}
+		header := page.value(compactStruct).(map[int64]interface{})
+		size := int(header[2].(int64))
+		if header[5].(map[int64]interface{})[1] != int64(2) || size != 16 {
+			t.Fatalf("column %d: unexpected page header %v", i, header)
+		}
+		if int64(page.pos-offset+size) != colMeta[6].(int64) {
+			t.Fatalf("column %d: chunk size %v does not cover the page", i, colMeta[6])
+		}
+		first := binary.LittleEndian.Uint64(file[page.pos:])
+		switch columns[i].Kind {
+		case Double:
+			if got := math.Float64frombits(first); got != columns[i].Double[0] {
+				t.Fatalf("column %d: expected %v, got %v", i, columns[i].Double[0], got)
+			}
+		default:
+			if got := int64(first); got != columns[i].Int64[0] {
+				t.Fatalf("column %d: expected %v, got %v", i, columns[i].Int64[0], got)
+			}
+		}
+	}
+
+	if err := Write(&buf, []Column{{Name: "a", Int64: []int64{1}}, {Name: "b", Int64: nil}}); err == nil {
+		t.Fatal("expected columns of different lengths to be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+// Package password hashes and verifies web console passwords with bcrypt or argon2id.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported algorithms.
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+// argon2id parameters follow the OWASP recommendation for interactive logins.
+const (
+	argonMemory  = 64 * 1024
+	argonTime    = 3
+	argonThreads = 2
+	argonSaltLen = 16
+	argonKeyLen  = 32
+)
+
+// ErrUnknownHash is returned for hashes that are neither bcrypt nor argon2id PHC strings.
+var ErrUnknownHash = errors.New("unsupported password hash, expected bcrypt ($2a$/$2b$/$2y$) or argon2id ($argon2id$)")
+
+// Hash derives a storable hash of the password with the given algorithm.
+func Hash(password, algorithm string) (string, error) {
+	switch strings.ToLower(algorithm) {
+	case "", Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	case Argon2id:
+		salt := make([]byte, argonSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q (use bcrypt or argon2id)", algorithm)
+	}
+}
+
+// Check reports whether hash is a well-formed supported hash, without the cost of verifying a password.
+func Check(hash string) error {
+	switch {
+	case isBcrypt(hash):
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, err := parseArgon2id(hash)
+		return err
+	default:
+		return ErrUnknownHash
+	}
+}
+
+// Verify reports whether password matches hash; err is non-nil only for malformed hashes.
+func Verify(hash, password string) (bool, error) {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, err := parseArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		derived := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.threads, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(derived, params.key) == 1, nil
+	default:
+		return false, ErrUnknownHash
+	}
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+type argon2Params struct {
+	memory     uint32
+	iterations uint32
+	threads    uint8
+	salt       []byte
+	key        []byte
+}
+
+// parseArgon2id decodes the PHC string $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func parseArgon2id(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+	if params.iterations == 0 || params.threads == 0 {
+		return nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, fmt.Errorf("invalid argon2id key")
+	}
+	return params, nil
+}
@@ -0,0 +1,48 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{Bcrypt, Argon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			hash, err := Hash("s3cret!", algorithm)
+			if err != nil {
+				t.Fatalf("hash: %v", err)
+			}
+			if strings.Contains(hash, "s3cret!") {
+				t.Fatalf("hash leaks the password: %s", hash)
+			}
+			if err := Check(hash); err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if ok, err := Verify(hash, "s3cret!"); err != nil || !ok {
+				t.Fatalf("expected the password to verify, got %v, %v", ok, err)
+			}
+			if ok, err := Verify(hash, "wrong"); err != nil || ok {
+				t.Fatalf("expected a mismatch, got %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"admin123",
+		"$argon2id$v=19$m=65536,t=3,p=2$bm90LWJhc2U2NA",
+		"$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=0,p=2$c2FsdA$a2V5",
+	} {
+		if err := Check(hash); err == nil {
+			t.Errorf("expected %q to be rejected", hash)
+		}
+		if ok, err := Verify(hash, "admin123"); err == nil || ok {
+			t.Errorf("expected %q to fail verification with an error, got %v, %v", hash, ok, err)
+		}
+	}
+	if _, err := Hash("x", "md5"); err == nil {
+		t.Fatal("expected unknown algorithms to be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	sdk "github.com/funnyzak/reqtap/pkg/plugin"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Client is a running plugin process.
+type Client struct {
+	name    string
+	hooks   map[string]struct{}
+	timeout time.Duration
+	cmd     *exec.Cmd
+	rpc     *rpc.Client
+	log     logger.Logger
+}
+
+// Start launches the plugin process and performs the protocol handshake.
+func Start(cfg config.PluginConfig, log logger.Logger) (*Client, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start plugin %s: %w", cfg.Name, err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	c := &Client{
+		name:    cfg.Name,
+		hooks:   make(map[string]struct{}),
+		timeout: timeout,
+		cmd:     cmd,
+		rpc:     jsonrpc.NewClient(pipeConn{reader: stdout, writer: stdin}),
+		log:     log,
+	}
+
+	var reply sdk.HandshakeReply
+	if err := c.call(context.Background(), "Plugin.Handshake", &sdk.HandshakeArgs{ProtocolVersion: sdk.ProtocolVersion}, &reply); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("plugin %s handshake failed: %w", cfg.Name, err)
+	}
+
+	offered := make(map[string]struct{}, len(reply.Hooks))
+	for _, hook := range reply.Hooks {
+		offered[hook] = struct{}{}
+	}
+	wanted := cfg.Hooks
+	if len(wanted) == 0 {
+		wanted = reply.Hooks
+	}
+	for _, hook := range wanted {
+		if _, ok := offered[hook]; !ok {
+			c.Close()
+			return nil, fmt.Errorf("plugin %s does not implement the %s hook", cfg.Name, hook)
+		}
+		c.hooks[hook] = struct{}{}
+	}
+	return c, nil
+}
+
+// Name returns the configured plugin name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Has reports whether the plugin serves the hook.
+func (c *Client) Has(hook string) bool {
+	_, ok := c.hooks[hook]
+	return ok
+}
+
+// Transform lets the plugin rewrite a request; drop reports that the request should be discarded.
+func (c *Client) Transform(ctx context.Context, data *request.RequestData) (result *request.RequestData, drop bool, err error) {
+	var reply sdk.TransformReply
+	if err := c.call(ctx, "Plugin.Transform", &sdk.TransformArgs{Request: data}, &reply); err != nil {
+		return nil, false, err
+	}
+	if reply.Drop || reply.Request == nil {
+		return nil, true, nil
+	}
+	return reply.Request, false, nil
+}
+
+// Export hands a stored request to the plugin.
+func (c *Client) Export(ctx context.Context, data *request.RequestData) error {
+	return c.call(ctx, "Plugin.Export", &sdk.ExportArgs{Request: data}, &sdk.Empty{})
+}
+
+// Close stops the plugin: closing stdin asks it to exit, and it is killed if it does not.
+func (c *Client) Close() error {
+	err := c.rpc.Close()
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(c.timeout):
+		c.log.Warn("Plugin did not exit in time, killing it", "plugin", c.name)
+		_ = c.cmd.Process.Kill()
+		<-done
+	}
+	return err
+}
+
+func (c *Client) call(ctx context.Context, method string, args, reply interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	call := c.rpc.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-ctx.Done():
+		return fmt.Errorf("plugin %s %s: %w", c.name, method, ctx.Err())
+	}
+}
+
+// pipeConn joins the plugin's stdout and stdin into a single connection.
+type pipeConn struct {
+	reader io.ReadCloser
+	writer io.WriteCloser
+}
+
+func (p pipeConn) Read(b []byte) (int, error)  { return p.reader.Read(b) }
+func (p pipeConn) Write(b []byte) (int, error) { return p.writer.Write(b) }
+
+func (p pipeConn) Close() error {
+	werr := p.writer.Close()
+	rerr := p.reader.Close()
+	if werr != nil {
+		return werr
+	}
+	return rerr
+}
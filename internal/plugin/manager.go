@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+)
+
+// Manager owns the configured plugin processes.
+type Manager struct {
+	clients []*Client
+	log     logger.Logger
+}
+
+// NewManager starts every configured plugin; on failure the already started ones are stopped.
+func NewManager(cfgs []config.PluginConfig, log logger.Logger) (*Manager, error) {
+	m := &Manager{log: log}
+	for _, cfg := range cfgs {
+		client, err := Start(cfg, log)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		log.Info("Plugin started", "plugin", cfg.Name, "command", cfg.Command)
+		m.clients = append(m.clients, client)
+	}
+	return m, nil
+}
+
+// WithHook lists plugins serving the hook, in configuration order.
+func (m *Manager) WithHook(hook string) []*Client {
+	if m == nil {
+		return nil
+	}
+	var result []*Client
+	for _, c := range m.clients {
+		if c.Has(hook) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Get returns the named plugin or nil.
+func (m *Manager) Get(name string) *Client {
+	if m == nil {
+		return nil
+	}
+	for _, c := range m.clients {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Close stops all plugins.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	for _, c := range m.clients {
+		if err := c.Close(); err != nil {
+			m.log.Warn("Failed to stop plugin", "plugin", c.Name(), "error", err)
+		}
+	}
+	m.clients = nil
+}
@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+	sdk "github.com/funnyzak/reqtap/pkg/plugin"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// The test binary doubles as a plugin process when REQTAP_TEST_PLUGIN is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("REQTAP_TEST_PLUGIN") == "1" {
+		if err := sdk.Serve("memory", newMemoryPlugin()); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+type memoryPlugin struct {
+	mu       sync.Mutex
+	requests []*request.RequestData
+	forwards map[string][]*sdk.ForwardRecord
+}
+
+func newMemoryPlugin() *memoryPlugin {
+	return &memoryPlugin{forwards: make(map[string][]*sdk.ForwardRecord)}
+}
+
+func (p *memoryPlugin) Transform(data *request.RequestData) (*request.RequestData, error) {
+	if data.Path == "/drop" {
+		return nil, nil
+	}
+	data.Headers.Set("X-Plugin", "memory")
+	return data, nil
+}
+
+func (p *memoryPlugin) Record(data *request.RequestData) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append([]*request.RequestData{data}, p.requests...)
+	return nil
+}
+
+func (p *memoryPlugin) List(args sdk.ListArgs) ([]*request.RequestData, int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	items := p.requests[min(args.Offset, len(p.requests)):]
+	if args.Limit > 0 && len(items) > args.Limit {
+		items = items[:args.Limit]
+	}
+	return items, len(p.requests), nil
+}
+
+func (p *memoryPlugin) Get(id string) (*request.RequestData, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, item := range p.requests {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *memoryPlugin) RecordReplay(*request.ReplayData) error { return nil }
+
+func (p *memoryPlugin) GetReplays(string) ([]*request.ReplayData, error) { return nil, nil }
+
+func (p *memoryPlugin) RecordForwards(requestID string, records []*sdk.ForwardRecord) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forwards[requestID] = append(p.forwards[requestID], records...)
+	return nil
+}
+
+func (p *memoryPlugin) GetForwards(requestID string) ([]*sdk.ForwardRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.forwards[requestID], nil
+}
+
+func startTestPlugin(t *testing.T, hooks ...string) *Manager {
+	t.Helper()
+	manager, err := NewManager([]config.PluginConfig{{
+		Name:    "memory",
+		Command: os.Args[0],
+		Env:     []string{"REQTAP_TEST_PLUGIN=1"},
+		Hooks:   hooks,
+		Timeout: 5 * time.Second,
+	}}, noopLogger{})
+	if err != nil {
+		t.Fatalf("start plugin failed: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	return manager
+}
+
+func TestPluginTransform(t *testing.T) {
+	manager := startTestPlugin(t, sdk.HookTransform)
+	if got := manager.WithHook(sdk.HookStorage); len(got) != 0 {
+		t.Fatalf("hooks not requested in config must stay disabled, got %d", len(got))
+	}
+	client := manager.WithHook(sdk.HookTransform)[0]
+
+	data := &request.RequestData{ID: "REQ1", Method: http.MethodPost, Path: "/hook", Headers: http.Header{}}
+	result, drop, err := client.Transform(context.Background(), data)
+	if err != nil || drop {
+		t.Fatalf("unexpected transform outcome: drop=%v err=%v", drop, err)
+	}
+	if result.Headers.Get("X-Plugin") != "memory" {
+		t.Fatalf("expected header added by plugin, got %v", result.Headers)
+	}
+
+	data.Path = "/drop"
+	if _, drop, err := client.Transform(context.Background(), data); err != nil || !drop {
+		t.Fatalf("expected request to be dropped, drop=%v err=%v", drop, err)
+	}
+}
+
+func TestPluginStore(t *testing.T) {
+	manager := startTestPlugin(t)
+	store, err := NewStore(manager.Get("memory"))
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+
+	for _, id := range []string{"REQ1", "REQ2"} {
+		if _, err := store.Record(&request.RequestData{ID: id, Method: http.MethodGet, Path: "/" + id}); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	items, total, err := store.List(storage.ListOptions{Limit: 1})
+	if err != nil || total != 2 || len(items) != 1 || items[0].ID != "REQ2" {
+		t.Fatalf("unexpected list result: total=%d items=%v err=%v", total, items, err)
+	}
+	snapshot, err := store.Snapshot()
+	if err != nil || len(snapshot) != 2 {
+		t.Fatalf("unexpected snapshot: %d err=%v", len(snapshot), err)
+	}
+	if got, err := store.Get("missing"); err != nil || got != nil {
+		t.Fatalf("expected missing record to be nil, got %v err=%v", got, err)
+	}
+
+	err = store.RecordForwards("REQ1", []*storage.ForwardRecord{{TargetURL: "http://a.example", StatusCode: 201, Success: true}})
+	if err != nil {
+		t.Fatalf("record forwards failed: %v", err)
+	}
+	forwards, err := store.GetForwards("REQ1")
+	if err != nil || len(forwards) != 1 || forwards[0].StatusCode != 201 {
+		t.Fatalf("unexpected forwards: %v err=%v", forwards, err)
+	}
+}
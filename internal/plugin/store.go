@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+	sdk "github.com/funnyzak/reqtap/pkg/plugin"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// iteratePageSize is the page size used to walk a plugin backend.
+const iteratePageSize = 200
+
+// pluginStore adapts a storage plugin to storage.Store.
+type pluginStore struct {
+	client *Client
+}
+
+// NewStore exposes a plugin serving the storage hook as a storage.Store.
+func NewStore(client *Client) (storage.Store, error) {
+	if client == nil || !client.Has(sdk.HookStorage) {
+		return nil, fmt.Errorf("plugin does not serve the storage hook")
+	}
+	return &pluginStore{client: client}, nil
+}
+
+func (s *pluginStore) Record(data *request.RequestData) (*storage.StoredRequest, error) {
+	if data == nil {
+		return nil, fmt.Errorf("request data is nil")
+	}
+	if err := s.client.call(context.Background(), "Storage.Record", &sdk.RecordArgs{Request: data}, &sdk.Empty{}); err != nil {
+		return nil, err
+	}
+	return &storage.StoredRequest{ID: data.ID, RequestData: data}, nil
+}
+
+func (s *pluginStore) List(opts storage.ListOptions) ([]*storage.StoredRequest, int, error) {
+	var reply sdk.ListReply
+	args := &sdk.ListArgs{
+		Search: opts.Search,
+		Method: opts.Method,
+		Since:  opts.Since,
+		Until:  opts.Until,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}
+	if err := s.client.call(context.Background(), "Storage.List", args, &reply); err != nil {
+		return nil, 0, err
+	}
+	items := make([]*storage.StoredRequest, 0, len(reply.Items))
+	for _, item := range reply.Items {
+		if item != nil {
+			items = append(items, &storage.StoredRequest{ID: item.ID, RequestData: item})
+		}
+	}
+	return items, reply.Total, nil
+}
+
+func (s *pluginStore) Iterate(opts storage.ListOptions, fn func(*storage.StoredRequest) bool) error {
+	page := opts
+	page.Limit = iteratePageSize
+	page.Offset = 0
+	for {
+		items, _, err := s.List(page)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if !fn(item) {
+				return nil
+			}
+		}
+		if len(items) < iteratePageSize {
+			return nil
+		}
+		page.Offset += len(items)
+	}
+}
+
+func (s *pluginStore) Snapshot() ([]*storage.StoredRequest, error) {
+	var records []*storage.StoredRequest
+	err := s.Iterate(storage.ListOptions{}, func(item *storage.StoredRequest) bool {
+		records = append(records, item)
+		return true
+	})
+	return records, err
+}
+
+func (s *pluginStore) Get(id string) (*storage.StoredRequest, error) {
+	var reply sdk.GetReply
+	if err := s.client.call(context.Background(), "Storage.Get", &sdk.GetArgs{ID: id}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Request == nil {
+		return nil, nil
+	}
+	return &storage.StoredRequest{ID: reply.Request.ID, RequestData: reply.Request}, nil
+}
+
+func (s *pluginStore) RecordReplay(data *request.ReplayData) (*storage.StoredReplay, error) {
+	if data == nil {
+		return nil, fmt.Errorf("replay data is nil")
+	}
+	if err := s.client.call(context.Background(), "Storage.RecordReplay", &sdk.ReplayArgs{Replay: data}, &sdk.Empty{}); err != nil {
+		return nil, err
+	}
+	return &storage.StoredReplay{ReplayData: data}, nil
+}
+
+func (s *pluginStore) GetReplays(originalRequestID string) ([]*storage.StoredReplay, error) {
+	var reply sdk.ReplaysReply
+	if err := s.client.call(context.Background(), "Storage.GetReplays", &sdk.GetArgs{ID: originalRequestID}, &reply); err != nil {
+		return nil, err
+	}
+	result := make([]*storage.StoredReplay, 0, len(reply.Replays))
+	for _, replay := range reply.Replays {
+		result = append(result, &storage.StoredReplay{ReplayData: replay})
+	}
+	return result, nil
+}
+
+func (s *pluginStore) RecordForwards(requestID string, records []*storage.ForwardRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	wire := make([]*sdk.ForwardRecord, 0, len(records))
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		converted := sdk.ForwardRecord(*record)
+		wire = append(wire, &converted)
+	}
+	return s.client.call(context.Background(), "Storage.RecordForwards", &sdk.ForwardsArgs{RequestID: requestID, Records: wire}, &sdk.Empty{})
+}
+
+func (s *pluginStore) GetForwards(requestID string) ([]*storage.ForwardRecord, error) {
+	var reply sdk.ForwardsReply
+	if err := s.client.call(context.Background(), "Storage.GetForwards", &sdk.GetArgs{ID: requestID}, &reply); err != nil {
+		return nil, err
+	}
+	result := make([]*storage.ForwardRecord, 0, len(reply.Records))
+	for _, record := range reply.Records {
+		if record == nil {
+			continue
+		}
+		converted := storage.ForwardRecord(*record)
+		result = append(result, &converted)
+	}
+	return result, nil
+}
+
+// Close is a no-op; the plugin process is owned by the Manager.
+func (s *pluginStore) Close() error {
+	return nil
+}
+
+// Claim is not part of the plugin storage protocol.
+func (s *pluginStore) Claim(string, string, bool) (*storage.Claim, error) {
+	return nil, storage.ErrUnsupported
+}
+
+// ReleaseClaim is not part of the plugin storage protocol.
+func (s *pluginStore) ReleaseClaim(string, string, bool) error {
+	return storage.ErrUnsupported
+}
+
+// AddComment is not part of the plugin storage protocol.
+func (s *pluginStore) AddComment(*storage.Comment) error {
+	return storage.ErrUnsupported
+}
+
+// GetComments reports no comments since plugins cannot store them.
+func (s *pluginStore) GetComments(string) ([]*storage.Comment, error) {
+	return nil, nil
+}
+
+// Annotate is not part of the plugin storage protocol.
+func (s *pluginStore) Annotate(string, []string, *string) error {
+	return storage.ErrUnsupported
+}
+
+// Pin is not part of the plugin storage protocol.
+func (s *pluginStore) Pin(string, bool) error {
+	return storage.ErrUnsupported
+}
+
+// Delete is not part of the plugin storage protocol.
+func (s *pluginStore) Delete([]string) (int64, error) {
+	return 0, storage.ErrUnsupported
+}
+
+// EnqueueForward is not part of the plugin storage protocol.
+func (s *pluginStore) EnqueueForward(*storage.QueuedForward) error {
+	return storage.ErrUnsupported
+}
+
+// QueuedForwards reports an empty queue since plugins cannot store one.
+func (s *pluginStore) QueuedForwards(time.Time, int) ([]*storage.QueuedForward, error) {
+	return nil, nil
+}
+
+// RescheduleForward is not part of the plugin storage protocol.
+func (s *pluginStore) RescheduleForward(*storage.QueuedForward) error {
+	return storage.ErrUnsupported
+}
+
+// DequeueForward is not part of the plugin storage protocol.
+func (s *pluginStore) DequeueForward(int64) error {
+	return storage.ErrUnsupported
+}
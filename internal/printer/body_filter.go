@@ -0,0 +1,41 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// filterBody selects the fragment of a JSON body addressed by expr (".data.id", "$.data.items[0]"
+// or "data.id"), indented when pretty is set. ok is false when the body is not JSON, so callers
+// print it unchanged; found reports whether the path exists.
+func filterBody(data *request.RequestData, expr string, pretty bool) (fragment string, found bool, ok bool) {
+	if expr == "" || data == nil || data.IsBinary {
+		return "", false, false
+	}
+	trimmed := bytes.TrimSpace(data.Body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return "", false, false
+	}
+	doc, err := jsonpath.Decode(trimmed)
+	if err != nil {
+		return "", false, false
+	}
+	value, found := jsonpath.Lookup(doc, expr)
+	if !found {
+		return "", false, true
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return jsonpath.Stringify(value), true, true
+	}
+	return strings.TrimRight(buf.String(), "\n"), true, true
+}
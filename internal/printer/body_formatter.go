@@ -63,13 +63,22 @@ func (f *bodyFormatter) Format(data *request.RequestData) formattedBody {
 	if !f.cfg.Enable {
 		return formattedBody{Text: string(body)}
 	}
+	if res, ok := f.formatProtobuf(data); ok {
+		return res
+	}
 	mediaType := normalizeMediaType(data.ContentType)
+	if res, ok := f.formatGraphQL(mediaType, body); ok {
+		return res
+	}
 	if res, ok := f.formatJSON(mediaType, body); ok {
 		return res
 	}
 	if res, ok := f.formatForm(mediaType, body); ok {
 		return res
 	}
+	if res, ok := f.formatMultipart(data); ok {
+		return res
+	}
 	if res, ok := f.formatXML(mediaType, body); ok {
 		return res
 	}
@@ -129,27 +138,32 @@ func (f *bodyFormatter) formatForm(mediaType string, body []byte) (formattedBody
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
-	keyHeader := f.t(keyFormKeyHeader)
-	valueHeader := f.t(keyFormValueHeader)
-	maxKeyWidth := utf8.RuneCountInString(keyHeader)
-	for _, key := range keys {
-		if w := utf8.RuneCountInString(key); w > maxKeyWidth {
-			maxKeyWidth = w
-		}
-	}
 	var builder strings.Builder
 	title := f.t(keyFormTitle)
 	if title == "" {
 		title = "Form data:"
 	}
 	builder.WriteString(title + "\n")
-	fmt.Fprintf(&builder, "%-*s │ %s\n", maxKeyWidth, keyHeader, valueHeader)
+	writeKeyValueTable(&builder, f.t(keyFormKeyHeader), f.t(keyFormValueHeader), keys, func(key string) string {
+		return strings.Join(values[key], ", ")
+	})
+	return formattedBody{Text: builder.String()}, true
+}
+
+// writeKeyValueTable 以对齐的两列表格输出键值对
+func writeKeyValueTable(builder *strings.Builder, keyHeader, valueHeader string, keys []string, value func(string) string) {
+	maxKeyWidth := utf8.RuneCountInString(keyHeader)
+	for _, key := range keys {
+		if w := utf8.RuneCountInString(key); w > maxKeyWidth {
+			maxKeyWidth = w
+		}
+	}
+	fmt.Fprintf(builder, "%-*s │ %s\n", maxKeyWidth, keyHeader, valueHeader)
 	divider := strings.Repeat("─", maxKeyWidth)
 	builder.WriteString(divider + "─┼" + strings.Repeat("─", 40) + "\n")
 	for _, key := range keys {
-		fmt.Fprintf(&builder, "%-*s │ %s\n", maxKeyWidth, key, strings.Join(values[key], ", "))
+		fmt.Fprintf(builder, "%-*s │ %s\n", maxKeyWidth, key, value(key))
 	}
-	return formattedBody{Text: builder.String()}, true
 }
 
 func (f *bodyFormatter) formatXML(mediaType string, body []byte) (formattedBody, bool) {
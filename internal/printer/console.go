@@ -23,44 +23,6 @@ import (
 	"golang.org/x/term"
 )
 
-// ColorScheme color scheme
-type ColorScheme struct {
-	MethodGET      *color.Color
-	MethodPOST     *color.Color
-	MethodPUT      *color.Color
-	MethodDELETE   *color.Color
-	MethodPATCH    *color.Color
-	HeaderKey      *color.Color
-	HeaderValue    *color.Color
-	Separator      *color.Color
-	Timestamp      *color.Color
-	BodyContent    *color.Color
-	BinaryNotice   *color.Color
-	TruncateNotice *color.Color
-	RemoteAddr     *color.Color
-	Query          *color.Color
-}
-
-// NewColorScheme creates a new color scheme
-func NewColorScheme() *ColorScheme {
-	return &ColorScheme{
-		MethodGET:      color.New(color.FgBlue, color.Bold),
-		MethodPOST:     color.New(color.FgGreen, color.Bold),
-		MethodPUT:      color.New(color.FgYellow, color.Bold),
-		MethodDELETE:   color.New(color.FgRed, color.Bold),
-		MethodPATCH:    color.New(color.FgMagenta, color.Bold),
-		HeaderKey:      color.New(color.FgCyan),
-		HeaderValue:    color.New(color.FgWhite),
-		Separator:      color.New(color.FgYellow, color.Bold),
-		Timestamp:      color.New(color.FgHiBlack),
-		BodyContent:    color.New(color.FgWhite),
-		BinaryNotice:   color.New(color.FgHiRed, color.Bold),
-		TruncateNotice: color.New(color.FgHiYellow, color.Bold),
-		RemoteAddr:     color.New(color.FgHiBlue),
-		Query:          color.New(color.FgHiMagenta),
-	}
-}
-
 // ConsolePrinter console printer
 type ConsolePrinter struct {
 	colorScheme *ColorScheme
@@ -68,6 +30,7 @@ type ConsolePrinter struct {
 	out         io.Writer
 	formatter   *bodyFormatter
 	bodyView    config.BodyViewConfig
+	bodyFilter  string
 	promptMu    sync.Mutex
 	translator  *i18n.Translator
 	locale      string
@@ -165,6 +128,28 @@ func NewConsolePrinter(log logger.Logger, viewCfg *config.BodyViewConfig, transl
 	}
 }
 
+// SetOutput replaces where requests are printed; nil restores stdout
+func (p *ConsolePrinter) SetOutput(w io.Writer) {
+	if w == nil {
+		w = os.Stdout
+	}
+	p.out = w
+}
+
+// SetColors switches to the named theme (see ThemeColorScheme); disabled prints without terminal
+// colors whatever the theme
+func (p *ConsolePrinter) SetColors(theme string, enabled bool) {
+	p.colorScheme = ThemeColorScheme(theme)
+	if !enabled {
+		p.colorScheme.disable()
+	}
+}
+
+// SetBodyFilter prints only the fragment of JSON bodies addressed by expr; empty prints whole bodies
+func (p *ConsolePrinter) SetBodyFilter(expr string) {
+	p.bodyFilter = strings.TrimSpace(expr)
+}
+
 func (p *ConsolePrinter) t(key string) string {
 	if p == nil || p.translator == nil {
 		return key
@@ -184,12 +169,25 @@ func (p *ConsolePrinter) PrintRequest(data *request.RequestData) error {
 	p.printHeaders(&builder, data.Headers, width)
 	builder.WriteString("\n")
 	p.printBody(&builder, data)
+	p.printJWTs(&builder, data)
 	builder.WriteString("\n\n")
 
 	_, err := fmt.Fprint(p.out, builder.String())
 	return err
 }
 
+// SetPaused prints a banner when capture is paused or resumed, so a quiet console is not mistaken
+// for missing traffic
+func (p *ConsolePrinter) SetPaused(paused bool, skipped int64) {
+	banner := p.t(keyCapturePaused)
+	if !paused {
+		banner = fmt.Sprintf(p.t(keyCaptureResumed), skipped)
+	}
+	p.promptMu.Lock()
+	defer p.promptMu.Unlock()
+	fmt.Fprintln(p.out, p.colorScheme.TruncateNotice.Sprint(banner))
+}
+
 func (p *ConsolePrinter) printSummary(builder *strings.Builder, requestNum uint64, timestamp string, data *request.RequestData, width int) {
 	separator := p.buildSeparator(width)
 	builder.WriteString(p.colorScheme.Separator.Sprint(separator))
@@ -244,7 +242,21 @@ func (p *ConsolePrinter) printMetadataLine(builder *strings.Builder, data *reque
 	addSep()
 	builder.WriteString(p.t(keyMetadataSize))
 	builder.WriteString(": ")
-	builder.WriteString(p.colorScheme.BodyContent.Sprint(humanize.Bytes(uint64(len(data.Body)))))
+	if data.BodyFile != "" {
+		builder.WriteString(p.colorScheme.BodyContent.Sprint(humanize.Bytes(uint64(data.Size))))
+		spilled := fmt.Sprintf(p.t(keyMetadataSpilled), data.BodyFile)
+		builder.WriteString(p.colorScheme.TruncateNotice.Sprint(" (" + spilled + ")"))
+	} else {
+		builder.WriteString(p.colorScheme.BodyContent.Sprint(humanize.Bytes(uint64(len(data.Body)))))
+	}
+	if data.ContentEncoding != "" {
+		wire := fmt.Sprintf(p.t(keyMetadataWireSize), data.ContentEncoding, humanize.Bytes(uint64(data.WireSize)))
+		builder.WriteString(p.colorScheme.TruncateNotice.Sprint(" (" + wire + ")"))
+	}
+	if data.DuplicateOf != "" {
+		addSep()
+		builder.WriteString(p.colorScheme.TruncateNotice.Sprint(fmt.Sprintf(p.t(keyMetadataDuplicateOf), data.DuplicateOf)))
+	}
 	builder.WriteString("\n")
 }
 
@@ -341,14 +353,23 @@ func (p *ConsolePrinter) printBody(builder *strings.Builder, data *request.Reque
 		return
 	}
 
-	if data.IsBinary {
+	// multipart 上传常含二进制文件分段，交由格式化器逐段展示
+	if data.IsBinary && !p.formatter.rendersMultipart(data) && !p.formatter.rendersProtobuf(data) {
 		p.printBinaryBody(builder, data, bodySize)
 		return
 	}
 
 	text := string(data.Body)
 	notices := []string{}
-	if p.formatter != nil {
+	if fragment, found, ok := filterBody(data, p.bodyFilter, true); ok {
+		if !found {
+			builder.WriteString(p.colorScheme.TruncateNotice.Sprintf(p.t(keyBodyFilterMissed), p.bodyFilter, bodySize))
+			builder.WriteString("\n")
+			return
+		}
+		text = fragment
+		notices = append(notices, fmt.Sprintf(p.t(keyBodyFiltered), p.bodyFilter, bodySize))
+	} else if p.formatter != nil {
 		formatted := p.formatter.Format(data)
 		if formatted.Text != "" {
 			text = formatted.Text
@@ -458,7 +479,7 @@ func (p *ConsolePrinter) getMethodColor(method string) *color.Color {
 	case "PATCH":
 		return p.colorScheme.MethodPATCH
 	default:
-		return color.New(color.FgWhite, color.Bold)
+		return p.colorScheme.MethodOther
 	}
 }
 
@@ -2,8 +2,13 @@ package printer
 
 import (
 	"bytes"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -70,6 +75,15 @@ func TestConsolePrinter_PrintRequest(t *testing.T) {
 	if !bytes.Contains(buf.Bytes(), []byte("GET")) {
 		t.Fatalf("method line missing")
 	}
+
+	buf.Reset()
+	req.DuplicateOf = "REQ-1"
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Duplicate of REQ-1")) {
+		t.Fatalf("duplicate marker missing: %s", buf.String())
+	}
 }
 
 func TestConsolePrinter_PrintRequestChinese(t *testing.T) {
@@ -122,6 +136,39 @@ func TestConsolePrinter_JSONPretty(t *testing.T) {
 	}
 }
 
+func TestConsolePrinter_BodyFilter(t *testing.T) {
+	p := newTestPrinter(t, nil, "en")
+	p.SetBodyFilter("$.user")
+	buf := &bytes.Buffer{}
+	p.out = buf
+	req := &request.RequestData{
+		Method:      "POST",
+		Path:        "/hook",
+		Body:        []byte(`{"user":{"id":7},"payload":"` + strings.Repeat("x", 64) + `"}`),
+		Timestamp:   time.Now(),
+		ContentType: "application/json",
+	}
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, `"id": 7`) || strings.Contains(output, "xxxx") {
+		t.Fatalf("expected only the filtered fragment, got %s", output)
+	}
+	if !strings.Contains(output, "[Filtered by $.user") {
+		t.Fatalf("expected a filter notice, got %s", output)
+	}
+
+	buf.Reset()
+	p.SetBodyFilter(".missing")
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "matched nothing") {
+		t.Fatalf("expected a no-match notice, got %s", buf.String())
+	}
+}
+
 func TestConsolePrinter_FormTable(t *testing.T) {
 	cfg := config.BodyViewConfig{
 		Enable: true,
@@ -226,3 +273,212 @@ func TestConsolePrinter_BinaryPreviewAndSave(t *testing.T) {
 		t.Fatalf("saved binary content mismatch")
 	}
 }
+
+func TestConsolePrinter_MultipartParts(t *testing.T) {
+	tdir := t.TempDir()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("comment", "hello multipart"); err != nil {
+		t.Fatalf("write field failed: %v", err)
+	}
+	fileHeader := textproto.MIMEHeader{}
+	fileHeader.Set("Content-Disposition", `form-data; name="upload"; filename="../logo.png"`)
+	fileHeader.Set("Content-Type", "image/png")
+	part, err := writer.CreatePart(fileHeader)
+	if err != nil {
+		t.Fatalf("create part failed: %v", err)
+	}
+	fileContent := []byte{0x89, 'P', 'N', 'G', 0x00, 0x01}
+	part.Write(fileContent)
+	writer.Close()
+
+	cfg := config.BodyViewConfig{
+		Enable:    true,
+		Multipart: config.MultipartViewConfig{Enable: true, PreviewBytes: 5, SaveFiles: true},
+		Binary:    config.BinaryViewConfig{HexPreviewEnable: true, HexPreviewBytes: 4, SaveDirectory: tdir},
+	}
+	p := newTestPrinter(t, &cfg, "en")
+	buf := &bytes.Buffer{}
+	p.out = buf
+	req := &request.RequestData{
+		ID:          "MULTI",
+		Method:      "POST",
+		Path:        "/upload",
+		Body:        body.Bytes(),
+		Timestamp:   time.Now(),
+		ContentType: writer.FormDataContentType(),
+		IsBinary:    true,
+	}
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"Multipart form data (2 parts):",
+		`[1] name="comment"  text/plain`,
+		"    hello",
+		"[Preview shows first 5 B of 15 B]",
+		`[2] name="upload" filename="logo.png"  image/png  6 B`,
+		"[File saved to ",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Hex preview") {
+		t.Fatalf("expected multipart body not to fall back to the binary view")
+	}
+	entries, err := os.ReadDir(tdir)
+	if err != nil {
+		t.Fatalf("read dir failed: %v", err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), "-part2-logo.png") {
+		t.Fatalf("expected one saved part file, got %v", entries)
+	}
+	saved, err := os.ReadFile(filepath.Join(tdir, entries[0].Name()))
+	if err != nil || !bytes.Equal(saved, fileContent) {
+		t.Fatalf("saved part content mismatch: %v", err)
+	}
+}
+
+func TestConsolePrinter_GraphQL(t *testing.T) {
+	cfg := config.BodyViewConfig{
+		Enable:  true,
+		Json:    config.JSONViewConfig{Enable: true, Pretty: true},
+		GraphQL: config.GraphQLViewConfig{Enable: true},
+	}
+	p := newTestPrinter(t, &cfg, "en")
+	buf := &bytes.Buffer{}
+	p.out = buf
+	req := &request.RequestData{
+		ID:          "GQL",
+		Method:      "POST",
+		Path:        "/graphql",
+		Body:        []byte(`{"operationName":"GetUser","query":"query GetUser($id: ID!) { user(id: $id) { id name ... on Admin { level } } }","variables":{"id":"42","filter":{"active":true}}}`),
+		Timestamp:   time.Now(),
+		ContentType: "application/json",
+	}
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"GraphQL operation GetUser:",
+		"query GetUser($id: ID!) {\n  user(id: $id) {\n    id\n    name\n    ... on Admin {\n      level\n    }\n  }\n}\n",
+		"Variables:",
+		"filter.active │ true",
+		"id            │ 42",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// JSON payloads that merely carry a "query" field keep the JSON view
+	buf.Reset()
+	req.Body = []byte(`{"query":"shoes","page":2}`)
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "GraphQL") || !strings.Contains(buf.String(), `"page": 2`) {
+		t.Fatalf("expected a plain JSON view:\n%s", buf.String())
+	}
+}
+
+func TestConsolePrinter_JWT(t *testing.T) {
+	segment := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	header := segment(`{"alg":"HS256","typ":"JWT"}`)
+	expired := header + "." + segment(`{"sub":"42","exp":1700000000}`) + ".c2ln"
+	valid := header + "." + segment(`{"sub":"7","scope":"read","exp":4102444800}`) + ".c2ln"
+
+	cfg := config.BodyViewConfig{
+		Enable: true,
+		Json:   config.JSONViewConfig{Enable: true, Pretty: true},
+		JWT:    config.JWTViewConfig{Enable: true},
+	}
+	p := newTestPrinter(t, &cfg, "en")
+	buf := &bytes.Buffer{}
+	p.out = buf
+	req := &request.RequestData{
+		ID:          "JWT",
+		Method:      "POST",
+		Path:        "/token",
+		Headers:     http.Header{"Authorization": {"Bearer " + expired}},
+		Body:        []byte(`{"grant":{"id_token":"` + valid + `"},"note":"a.b.c"}`),
+		Timestamp:   time.Now(),
+		ContentType: "application/json",
+	}
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"Authorization: [REDACTED]",
+		"JWT from the Authorization header:",
+		`Header: {"alg":"HS256","typ":"JWT"}`,
+		`"sub": "42"`,
+		"Expired 2023-11-14T22:13:20Z",
+		"JWT in body field .grant.id_token:",
+		`"scope": "read"`,
+		"Expires 2100-01-01T00:00:00Z",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "JWT in body field .note") {
+		t.Fatalf("expected non-JWT strings to be ignored:\n%s", output)
+	}
+
+	buf.Reset()
+	p.bodyView.JWT.Enable = false
+	if err := p.PrintRequest(req); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "JWT") {
+		t.Fatalf("expected no JWT section when disabled:\n%s", buf.String())
+	}
+}
+
+func TestConsolePrinter_Themes(t *testing.T) {
+	sequences := func(scheme *ColorScheme) []string {
+		var codes []string
+		fields := reflect.ValueOf(scheme).Elem()
+		for i := 0; i < fields.NumField(); i++ {
+			c := fields.Field(i).Interface().(*color.Color)
+			c.EnableColor()
+			colored := c.Sprint("x")
+			codes = append(codes, strings.Split(colored[2:strings.Index(colored, "m")], ";")...)
+		}
+		return codes
+	}
+	// Mono sets no colors; solarized avoids the bright colors that are background tones in its palette
+	for _, code := range sequences(ThemeColorScheme(ThemeMono)) {
+		if len(code) == 2 && (code[0] == '3' || code[0] == '9') {
+			t.Fatalf("mono theme uses color %s", code)
+		}
+	}
+	for _, code := range sequences(ThemeColorScheme(ThemeSolarized)) {
+		switch code {
+		case "37", "90", "92", "93", "94", "96", "97":
+			t.Fatalf("solarized theme uses base tone %s", code)
+		}
+	}
+	if ThemeColorScheme("unknown").Separator.Sprint("x") != NewColorScheme().Separator.Sprint("x") {
+		t.Fatal("unknown themes should fall back to the default theme")
+	}
+
+	// Disabled colors win over a color terminal
+	color.NoColor = false
+	defer func() { color.NoColor = true }()
+	p := newTestPrinter(t, nil, "en")
+	buf := &bytes.Buffer{}
+	p.out = buf
+	p.SetColors(ThemeSolarized, false)
+	if err := p.PrintRequest(&request.RequestData{Method: "POST", Path: "/hook", Body: []byte("hi"), Timestamp: time.Now()}); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("\x1b[")) {
+		t.Fatalf("expected no color sequences, got %q", buf.String())
+	}
+}
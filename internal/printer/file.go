@@ -0,0 +1,64 @@
+package printer
+
+import (
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// ansiEscape matches the color sequences of the console printer
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// OutputFile is the rotating transcript of output.file. Terminal colors are stripped, so the file
+// reads the same whether or not stdout is a terminal.
+type OutputFile struct {
+	file *lumberjack.Logger
+}
+
+// OpenOutputFile returns the transcript configured by cfg, or nil when output.file is empty. The
+// file is created on the first write.
+func OpenOutputFile(cfg *config.OutputConfig) *OutputFile {
+	if cfg == nil || cfg.File == "" {
+		return nil
+	}
+	return &OutputFile{file: &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.FileRotation.MaxSizeMB,
+		MaxBackups: cfg.FileRotation.MaxBackups,
+		MaxAge:     cfg.FileRotation.MaxAgeDays,
+		Compress:   cfg.FileRotation.Compress,
+	}}
+}
+
+// Write appends p without color sequences; printers write whole records, so a sequence is never
+// split across writes
+func (f *OutputFile) Write(p []byte) (int, error) {
+	if _, err := f.file.Write(ansiEscape.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the current file
+func (f *OutputFile) Close() error {
+	return f.file.Close()
+}
+
+// Writer returns where a printer writes: stdout, teed to file when set, or only file when the
+// console is silenced. It returns nil when there is nothing to write to.
+func Writer(file *OutputFile, silence bool) io.Writer {
+	switch {
+	case file == nil && silence:
+		return nil
+	case file == nil:
+		return os.Stdout
+	case silence:
+		return file
+	default:
+		return io.MultiWriter(os.Stdout, file)
+	}
+}
@@ -0,0 +1,49 @@
+package printer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestOutputFile(t *testing.T) {
+	if OpenOutputFile(&config.OutputConfig{}) != nil || Writer(nil, true) != nil {
+		t.Fatal("expected no transcript without output.file")
+	}
+	if Writer(nil, false) != os.Stdout {
+		t.Fatal("expected stdout without output.file")
+	}
+
+	path := filepath.Join(t.TempDir(), "requests.log")
+	file := OpenOutputFile(&config.OutputConfig{File: path, FileRotation: config.OutputFileRotationConfig{MaxSizeMB: 1}})
+	defer file.Close()
+	colored := "\x1b[32;1mPOST\x1b[0m /hook\n"
+	if n, err := file.Write([]byte(colored)); err != nil || n != len(colored) {
+		t.Fatalf("expected the whole write to be reported, got %d (%v)", n, err)
+	}
+
+	// Silenced, the printer writes only to the transcript
+	p := New("json", noopLogger{}, &config.OutputConfig{}, testTranslator(t), "en", Writer(file, true))
+	if err := p.PrintRequest(&request.RequestData{ID: "REQ", Method: "POST", Path: "/hook", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || lines[0] != "POST /hook" {
+		t.Fatalf("expected the colors to be stripped, got %q", data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record["type"] != "request" {
+		t.Fatalf("expected a JSON line in the transcript, got %q (%v)", lines[1], err)
+	}
+}
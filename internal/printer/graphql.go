@@ -0,0 +1,260 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+)
+
+const mediaTypeGraphQL = "application/graphql"
+
+// graphQLRequest is the standard GraphQL-over-HTTP JSON envelope
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLKeys are the only fields a JSON body may carry to be treated as a GraphQL request, so
+// that unrelated payloads with a "query" field keep the JSON view
+var graphQLKeys = map[string]bool{"query": true, "operationName": true, "variables": true, "extensions": true}
+
+// formatGraphQL 缩进展示 GraphQL 查询，并以表格列出变量
+func (f *bodyFormatter) formatGraphQL(mediaType string, body []byte) (formattedBody, bool) {
+	if !f.cfg.GraphQL.Enable {
+		return formattedBody{}, false
+	}
+	req, ok := parseGraphQLRequest(mediaType, body)
+	if !ok {
+		return formattedBody{}, false
+	}
+
+	var builder strings.Builder
+	if req.OperationName != "" {
+		fmt.Fprintf(&builder, f.t(keyGraphQLOperation)+"\n", req.OperationName)
+	} else {
+		builder.WriteString(f.t(keyGraphQLTitle) + "\n")
+	}
+	builder.WriteString(prettyGraphQL(req.Query))
+	if len(req.Variables) > 0 {
+		rows := make(map[string]string)
+		flattenGraphQLVariables("", req.Variables, rows)
+		keys := make([]string, 0, len(rows))
+		for key := range rows {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		builder.WriteString("\n" + f.t(keyGraphQLVariables) + "\n")
+		writeKeyValueTable(&builder, f.t(keyGraphQLVariableHeader), f.t(keyGraphQLValueHeader), keys, func(key string) string {
+			return rows[key]
+		})
+	}
+	return formattedBody{Text: builder.String()}, true
+}
+
+// parseGraphQLRequest accepts application/graphql bodies and JSON objects with a query and optional
+// variables, operationName and extensions
+func parseGraphQLRequest(mediaType string, body []byte) (graphQLRequest, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return graphQLRequest{}, false
+	}
+	if mediaType == mediaTypeGraphQL {
+		return graphQLRequest{Query: string(trimmed)}, true
+	}
+	if !looksLikeJSON(mediaType, trimmed) || trimmed[0] != '{' {
+		return graphQLRequest{}, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return graphQLRequest{}, false
+	}
+	for key := range fields {
+		if !graphQLKeys[key] {
+			return graphQLRequest{}, false
+		}
+	}
+	var req graphQLRequest
+	if err := json.Unmarshal(trimmed, &req); err != nil {
+		return graphQLRequest{}, false
+	}
+	doc, err := jsonpath.Decode(fields["variables"])
+	if variables, ok := doc.(map[string]interface{}); err == nil && ok {
+		req.Variables = variables
+	}
+	if !strings.Contains(req.Query, "{") {
+		return graphQLRequest{}, false
+	}
+	return req, true
+}
+
+// flattenGraphQLVariables lists nested input objects as dotted paths; lists stay JSON encoded
+func flattenGraphQLVariables(prefix string, values map[string]interface{}, rows map[string]string) {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenGraphQLVariables(path, nested, rows)
+			continue
+		}
+		rows[path] = jsonpath.Stringify(value)
+	}
+}
+
+// prettyGraphQL re-indents a GraphQL document: one selection per line, two spaces per selection
+// set, arguments and input objects kept on the line of their field
+func prettyGraphQL(query string) string {
+	tokens := lexGraphQL(query)
+	var builder strings.Builder
+	depth, parens := 0, 0
+	newline := false
+	prev, prevPrev := "", ""
+	write := func(token string, breakBefore bool) {
+		switch {
+		case builder.Len() == 0:
+		case newline || breakBefore:
+			builder.WriteString("\n" + strings.Repeat("  ", depth))
+		case graphQLNeedsSpace(prev, token):
+			builder.WriteString(" ")
+		}
+		builder.WriteString(token)
+		newline = false
+		prevPrev, prev = prev, token
+	}
+
+	for _, token := range tokens {
+		selection := depth > 0 && parens == 0
+		switch {
+		case strings.HasPrefix(token, "#"):
+			write(token, true)
+			newline = true
+			continue
+		case parens > 0:
+			switch token {
+			case "(":
+				parens++
+			case ")":
+				parens--
+			}
+			write(token, false)
+			continue
+		}
+
+		switch token {
+		case "{":
+			if builder.Len() > 0 && !newline {
+				builder.WriteString(" ")
+			}
+			builder.WriteString("{")
+			depth++
+			newline = true
+			prevPrev, prev = prev, token
+		case "}":
+			if depth > 0 {
+				depth--
+			}
+			write(token, true)
+			newline = true
+			if depth == 0 {
+				builder.WriteString("\n")
+			}
+		case "(":
+			parens++
+			write(token, false)
+		case ",":
+			// commas are insignificant between selections
+		default:
+			startsSelection := selection && (token == "..." || isGraphQLName(token)) &&
+				prev != ":" && prev != "@" && prev != "..." && !(prev == "on" && prevPrev == "...")
+			write(token, startsSelection)
+		}
+	}
+	return strings.TrimRight(builder.String(), "\n") + "\n"
+}
+
+// graphQLNeedsSpace decides whether two tokens on the same line are separated by a space
+func graphQLNeedsSpace(prev, token string) bool {
+	switch prev {
+	case "", "(", "[", "{", "$", "@":
+		return false
+	case "...":
+		return token == "on"
+	}
+	switch token {
+	case ")", "]", "}", ":", ",", "!", "(":
+		return false
+	}
+	return true
+}
+
+func isGraphQLName(token string) bool {
+	if token == "" {
+		return false
+	}
+	c := token[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// lexGraphQL splits a document into names, numbers, strings, comments and punctuators; whitespace
+// is dropped
+func lexGraphQL(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			end := strings.IndexAny(query[i:], "\r\n")
+			if end < 0 {
+				end = len(query) - i
+			}
+			tokens = append(tokens, strings.TrimRight(query[i:i+end], " \t"))
+			i += end
+		case strings.HasPrefix(query[i:], `"""`):
+			end := i + 3
+			for end < len(query) && !strings.HasPrefix(query[end:], `"""`) {
+				if strings.HasPrefix(query[end:], `\"""`) {
+					end += 4
+					continue
+				}
+				end++
+			}
+			end = min(end+3, len(query))
+			tokens = append(tokens, query[i:end])
+			i = end
+		case c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != '"' && query[end] != '\n' {
+				if query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+			tokens = append(tokens, query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.ContainsRune("{}()[]:!$@=|&,", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			end := i + 1
+			for end < len(query) && !strings.ContainsRune(" \t\r\n,{}()[]:!$@=|&\"#", rune(query[end])) &&
+				!strings.HasPrefix(query[end:], "...") {
+				end++
+			}
+			tokens = append(tokens, query[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
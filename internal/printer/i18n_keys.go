@@ -1,20 +1,46 @@
 package printer
 
 const (
-	keySummaryTitle        = "cli.summary.title"
-	keyMetadataRemote      = "cli.metadata.remote"
-	keyMetadataUserAgent   = "cli.metadata.user_agent"
-	keyMetadataContentType = "cli.metadata.content_type"
-	keyMetadataSize        = "cli.metadata.size"
-	keyHeadersRedacted     = "cli.headers.redacted"
-	keyBodyEmpty           = "cli.body.empty"
-	keyBodyTruncate        = "cli.body.truncate_hint"
-	keyBodyBinarySummary   = "cli.body.binary_summary"
-	keyBodyHexTitle        = "cli.body.hex_preview_title"
-	keyBodyHexTruncate     = "cli.body.hex_preview_truncate"
-	keyBodyBinarySaved     = "cli.body.binary_saved"
-	keyJSONIndentSkipped   = "cli.json.indent_skipped"
-	keyFormTitle           = "cli.form.title"
-	keyFormKeyHeader       = "cli.form.key_header"
-	keyFormValueHeader     = "cli.form.value_header"
+	keySummaryTitle          = "cli.summary.title"
+	keyMetadataRemote        = "cli.metadata.remote"
+	keyMetadataUserAgent     = "cli.metadata.user_agent"
+	keyMetadataContentType   = "cli.metadata.content_type"
+	keyMetadataSize          = "cli.metadata.size"
+	keyMetadataWireSize      = "cli.metadata.wire_size"
+	keyMetadataSpilled       = "cli.metadata.spilled"
+	keyMetadataDuplicateOf   = "cli.metadata.duplicate_of"
+	keyHeadersRedacted       = "cli.headers.redacted"
+	keyBodyEmpty             = "cli.body.empty"
+	keyBodyTruncate          = "cli.body.truncate_hint"
+	keyBodyBinarySummary     = "cli.body.binary_summary"
+	keyBodyHexTitle          = "cli.body.hex_preview_title"
+	keyBodyHexTruncate       = "cli.body.hex_preview_truncate"
+	keyBodyBinarySaved       = "cli.body.binary_saved"
+	keyBodyFiltered          = "cli.body.filtered"
+	keyBodyFilterMissed      = "cli.body.filter_missed"
+	keyJSONIndentSkipped     = "cli.json.indent_skipped"
+	keyFormTitle             = "cli.form.title"
+	keyFormKeyHeader         = "cli.form.key_header"
+	keyFormValueHeader       = "cli.form.value_header"
+	keyMultipartTitle        = "cli.multipart.title"
+	keyMultipartTruncated    = "cli.multipart.preview_truncated"
+	keyMultipartSaved        = "cli.multipart.file_saved"
+	keyMultipartParseError   = "cli.multipart.parse_error"
+	keyGraphQLTitle          = "cli.graphql.title"
+	keyGraphQLOperation      = "cli.graphql.operation"
+	keyGraphQLVariables      = "cli.graphql.variables"
+	keyGraphQLVariableHeader = "cli.graphql.variable_header"
+	keyGraphQLValueHeader    = "cli.graphql.value_header"
+	keyJWTFromHeader         = "cli.jwt.from_header"
+	keyJWTFromBody           = "cli.jwt.from_body"
+	keyJWTHeader             = "cli.jwt.header"
+	keyJWTClaims             = "cli.jwt.claims"
+	keyJWTExpires            = "cli.jwt.expires"
+	keyJWTExpired            = "cli.jwt.expired"
+	keyJWTNoExpiry           = "cli.jwt.no_expiry"
+	keyProtobufMessage       = "cli.protobuf.message"
+	keyProtobufRaw           = "cli.protobuf.raw"
+	keyCapturePaused         = "cli.capture.paused"
+	keyCaptureResumed        = "cli.capture.resumed"
+	keyStatusLine            = "cli.status.line"
 )
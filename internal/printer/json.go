@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/funnyzak/reqtap/internal/logger"
 	"github.com/funnyzak/reqtap/pkg/request"
@@ -14,6 +15,8 @@ type JSONPrinter struct {
 	encoder *json.Encoder
 	logger  logger.Logger
 	out     io.Writer
+	// bodyFilter replaces JSON bodies with the fragment it addresses, see SetBodyFilter
+	bodyFilter string
 }
 
 // NewJSONPrinter 创建 JSON 输出器
@@ -35,21 +38,51 @@ func (p *JSONPrinter) SetOutput(w io.Writer) {
 	p.encoder = encoder
 }
 
+// SetBodyFilter emits only the fragment of JSON bodies addressed by expr as body_text, dropping the
+// raw body from the record; empty emits whole bodies
+func (p *JSONPrinter) SetBodyFilter(expr string) {
+	p.bodyFilter = strings.TrimSpace(expr)
+}
+
+// jsonRequestEnvelope 是每个请求的完整记录，下游日志管道无需再关联多行日志
 type jsonRequestEnvelope struct {
-	Type     string               `json:"type"`
-	ID       uint64               `json:"id"`
-	Request  *request.RequestData `json:"request"`
-	BodyText string               `json:"body_text,omitempty"`
+	Type      string               `json:"type"`
+	ID        uint64               `json:"id"`
+	StorageID string               `json:"storage_id,omitempty"`
+	Rule      string               `json:"rule,omitempty"`
+	Status    int                  `json:"status,omitempty"`
+	Request   *request.RequestData `json:"request"`
+	BodyText  string               `json:"body_text,omitempty"`
+	// BodyFilter is set when BodyText holds a filtered fragment; BodyMatched is false when the path was missing
+	BodyFilter  string           `json:"body_filter,omitempty"`
+	BodyMatched *bool            `json:"body_matched,omitempty"`
+	Forwards    []ForwardOutcome `json:"forwards,omitempty"`
 }
 
-// PrintRequest 输出请求 JSON
+// PrintRequest 输出请求 JSON（不含转发结果）
 func (p *JSONPrinter) PrintRequest(data *request.RequestData) error {
+	return p.PrintOutcome(data, Outcome{})
+}
+
+// PrintOutcome 输出包含存储 ID、命中规则、响应状态与转发结果的单条 JSON
+func (p *JSONPrinter) PrintOutcome(data *request.RequestData, outcome Outcome) error {
 	env := jsonRequestEnvelope{
-		Type:    "request",
-		ID:      nextRequestNumber(),
-		Request: data,
+		Type:      "request",
+		ID:        nextRequestNumber(),
+		StorageID: outcome.StorageID,
+		Rule:      data.MockResponse.Rule,
+		Status:    data.MockResponse.Status,
+		Request:   data,
+		Forwards:  outcome.Forwards,
 	}
-	if !data.IsBinary && len(data.Body) > 0 {
+	if fragment, found, ok := filterBody(data, p.bodyFilter, false); ok {
+		filtered := *data
+		filtered.Body = nil
+		env.Request = &filtered
+		env.BodyText = fragment
+		env.BodyFilter = p.bodyFilter
+		env.BodyMatched = &found
+	} else if !data.IsBinary && len(data.Body) > 0 {
 		env.BodyText = string(data.Body)
 	}
 	if err := p.encoder.Encode(env); err != nil {
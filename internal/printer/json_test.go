@@ -35,3 +35,39 @@ func TestJSONPrinter_PrintRequest(t *testing.T) {
 		t.Fatalf("unexpected type: %v", decoded["type"])
 	}
 }
+
+func TestJSONPrinter_BodyFilter(t *testing.T) {
+	p := NewJSONPrinter(noopLogger{})
+	buf := &bytes.Buffer{}
+	p.SetOutput(buf)
+	p.SetBodyFilter(".data.items[1]")
+
+	data := &request.RequestData{
+		Method:    "POST",
+		Path:      "/demo",
+		Timestamp: time.Now(),
+		Body:      []byte(`{"data":{"items":[{"id":1},{"id":2,"name":"<b>"}]},"noise":"x"}`),
+	}
+	if err := p.PrintRequest(data); err != nil {
+		t.Fatalf("print request failed: %v", err)
+	}
+
+	var decoded struct {
+		Request     map[string]interface{} `json:"request"`
+		BodyText    string                 `json:"body_text"`
+		BodyFilter  string                 `json:"body_filter"`
+		BodyMatched bool                   `json:"body_matched"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if decoded.BodyText != `{"id":2,"name":"<b>"}` || !decoded.BodyMatched || decoded.BodyFilter != ".data.items[1]" {
+		t.Fatalf("expected the filtered fragment, got %+v", decoded)
+	}
+	if decoded.Request["body"] != nil {
+		t.Fatalf("expected the raw body to be dropped, got %v", decoded.Request["body"])
+	}
+	if len(data.Body) == 0 {
+		t.Fatal("expected the original record to keep its body")
+	}
+}
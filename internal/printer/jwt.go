@@ -0,0 +1,187 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// jwtPattern matches the compact serialization: base64url header, claims and an optional signature
+var jwtPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]*$`)
+
+// decodedJWT is a token found in a request; the signature is neither shown nor verified
+type decodedJWT struct {
+	// Source is the header name or the body field path the token came from
+	Source   string
+	FromBody bool
+	Header   map[string]interface{}
+	Claims   map[string]interface{}
+}
+
+// decodeJWT decodes the header and claims of a compact JWT; the header must name an alg
+func decodeJWT(token string) (header, claims map[string]interface{}, ok bool) {
+	token = strings.TrimSpace(token)
+	if !jwtPattern.MatchString(token) {
+		return nil, nil, false
+	}
+	parts := strings.Split(token, ".")
+	if decodeJWTSegment(parts[0], &header) != nil || decodeJWTSegment(parts[1], &claims) != nil {
+		return nil, nil, false
+	}
+	if _, hasAlg := header["alg"]; !hasAlg || claims == nil {
+		return nil, nil, false
+	}
+	return header, claims, true
+}
+
+func decodeJWTSegment(segment string, out *map[string]interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+// findJWTs collects the tokens of the Authorization: Bearer header and of JSON or form body fields
+func findJWTs(data *request.RequestData) []decodedJWT {
+	var found []decodedJWT
+	seen := make(map[string]bool)
+	add := func(source string, fromBody bool, token string) {
+		token = strings.TrimSpace(token)
+		if seen[token] {
+			return
+		}
+		header, claims, ok := decodeJWT(token)
+		if !ok {
+			return
+		}
+		seen[token] = true
+		found = append(found, decodedJWT{Source: source, FromBody: fromBody, Header: header, Claims: claims})
+	}
+
+	for _, value := range data.Headers.Values("Authorization") {
+		if scheme, token, ok := strings.Cut(strings.TrimSpace(value), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			add("Authorization", false, token)
+		}
+	}
+
+	if data.IsBinary || len(data.Body) == 0 {
+		return found
+	}
+	body := bytes.TrimSpace(data.Body)
+	if jwtPattern.Match(body) {
+		add(".", true, string(body))
+		return found
+	}
+	mediaType := normalizeMediaType(data.ContentType)
+	if looksLikeJSON(mediaType, body) {
+		if doc, err := jsonpath.Decode(body); err == nil {
+			walkJWTFields("", doc, func(path, value string) { add(path, true, value) })
+		}
+		return found
+	}
+	if mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				for _, value := range values[key] {
+					add(key, true, value)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// walkJWTFields visits the string values of a JSON document with their jq-style path; a
+// "Bearer " prefix is dropped so that tokens copied from headers are recognised too
+func walkJWTFields(path string, node interface{}, visit func(path, value string)) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkJWTFields(path+"."+key, value[key], visit)
+		}
+	case []interface{}:
+		for i, item := range value {
+			walkJWTFields(path+"["+strconv.Itoa(i)+"]", item, visit)
+		}
+	case string:
+		if len(value) > 7 && strings.EqualFold(value[:7], "Bearer ") {
+			value = value[7:]
+		}
+		visit(path, value)
+	}
+}
+
+// jwtExpiry reads the exp claim, a NumericDate in seconds
+func jwtExpiry(claims map[string]interface{}) (time.Time, bool) {
+	var seconds float64
+	switch exp := claims["exp"].(type) {
+	case json.Number:
+		value, err := exp.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = value
+	case float64:
+		seconds = exp
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0).UTC(), true
+}
+
+// printJWTs lists the header and claims of every JWT carried by the request and highlights whether
+// it has expired
+func (p *ConsolePrinter) printJWTs(builder *strings.Builder, data *request.RequestData) {
+	if !p.bodyView.Enable || !p.bodyView.JWT.Enable {
+		return
+	}
+	for _, token := range findJWTs(data) {
+		title := fmt.Sprintf(p.t(keyJWTFromHeader), token.Source)
+		if token.FromBody {
+			title = fmt.Sprintf(p.t(keyJWTFromBody), token.Source)
+		}
+		builder.WriteString("\n")
+		builder.WriteString(p.colorScheme.HeaderKey.Sprintln(title))
+
+		header, _ := json.Marshal(token.Header)
+		builder.WriteString(p.colorScheme.BodyContent.Sprintf(p.t(keyJWTHeader)+"\n", header))
+		builder.WriteString(p.colorScheme.BodyContent.Sprintln(p.t(keyJWTClaims)))
+		claims, _ := json.MarshalIndent(token.Claims, "", "  ")
+		p.printBodyContent(builder, string(claims))
+
+		expiry, ok := jwtExpiry(token.Claims)
+		switch {
+		case !ok:
+			builder.WriteString(p.colorScheme.TruncateNotice.Sprintln(p.t(keyJWTNoExpiry)))
+		case expiry.Before(time.Now()):
+			builder.WriteString(p.colorScheme.JWTExpired.Sprintf(p.t(keyJWTExpired)+"\n", expiry.Format(time.RFC3339), humanize.Time(expiry)))
+		default:
+			builder.WriteString(p.colorScheme.JWTValid.Sprintf(p.t(keyJWTExpires)+"\n", expiry.Format(time.RFC3339), humanize.Time(expiry)))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+const mediaTypeMultipartForm = "multipart/form-data"
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// rendersMultipart 判断是否由 formatMultipart 处理（即使请求体被判定为二进制）
+func (f *bodyFormatter) rendersMultipart(data *request.RequestData) bool {
+	return f != nil && f.cfg.Enable && f.cfg.Multipart.Enable &&
+		normalizeMediaType(data.ContentType) == mediaTypeMultipartForm
+}
+
+// formatMultipart 逐段列出名称、文件名、类型与大小，预览文本分段，并可将文件分段落盘
+func (f *bodyFormatter) formatMultipart(data *request.RequestData) (formattedBody, bool) {
+	if !f.rendersMultipart(data) {
+		return formattedBody{}, false
+	}
+	_, params, err := mime.ParseMediaType(data.ContentType)
+	if err != nil || params["boundary"] == "" {
+		return formattedBody{}, false
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(data.Body), params["boundary"])
+	var parts strings.Builder
+	var notices []string
+	count := 0
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if count == 0 {
+				return formattedBody{}, false
+			}
+			notices = append(notices, fmt.Sprintf(f.t(keyMultipartParseError), err))
+			break
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			notices = append(notices, fmt.Sprintf(f.t(keyMultipartParseError), err))
+			break
+		}
+		count++
+		f.writeMultipartPart(&parts, data, count, part, content)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, f.t(keyMultipartTitle)+"\n", count)
+	builder.WriteString(parts.String())
+	return formattedBody{Text: builder.String(), Notices: notices}, true
+}
+
+func (f *bodyFormatter) writeMultipartPart(builder *strings.Builder, data *request.RequestData, index int, part *multipart.Part, content []byte) {
+	contentType := part.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	fmt.Fprintf(builder, "[%d] name=%q", index, part.FormName())
+	if filename := part.FileName(); filename != "" {
+		fmt.Fprintf(builder, " filename=%q", filename)
+	}
+	fmt.Fprintf(builder, "  %s  %s\n", contentType, humanize.Bytes(uint64(len(content))))
+
+	if isTextPart(contentType, content) {
+		preview := content
+		limit := f.cfg.Multipart.PreviewBytes
+		if limit > 0 && len(preview) > limit {
+			preview = preview[:limit]
+			for len(preview) > 0 && !utf8.Valid(preview) {
+				preview = preview[:len(preview)-1]
+			}
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(preview), "\r\n"), "\n") {
+			builder.WriteString("    " + strings.TrimRight(line, "\r") + "\n")
+		}
+		if len(preview) < len(content) {
+			fmt.Fprintf(builder, "    "+f.t(keyMultipartTruncated)+"\n", humanize.Bytes(uint64(len(preview))), humanize.Bytes(uint64(len(content))))
+		}
+	}
+
+	if part.FileName() != "" && f.cfg.Multipart.SaveFiles && len(content) > 0 {
+		path, err := f.saveMultipartFile(data, index, part.FileName(), content)
+		if err != nil {
+			if f.logger != nil {
+				f.logger.Warn("failed to persist multipart file", "error", err, "request_id", data.ID, "part", index)
+			}
+			return
+		}
+		fmt.Fprintf(builder, "    "+f.t(keyMultipartSaved)+"\n", path)
+	}
+}
+
+func (f *bodyFormatter) saveMultipartFile(data *request.RequestData, index int, filename string, content []byte) (string, error) {
+	dir := strings.TrimSpace(f.cfg.Binary.SaveDirectory)
+	if dir == "" {
+		return "", fmt.Errorf("binary save directory is empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	safeName := unsafeFilenameChars.ReplaceAllString(filepath.Base(filename), "_")
+	name := fmt.Sprintf("reqtap-%s-%s-part%d-%s", time.Now().Format("20060102-150405"), strings.ToLower(data.ID), index, safeName)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// isTextPart 仅预览声明为文本类型且内容为无空字节 UTF-8 的分段
+func isTextPart(contentType string, content []byte) bool {
+	if bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content) {
+		return false
+	}
+	mediaType := normalizeMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") || strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") || mediaType == "application/x-www-form-urlencoded"
+}
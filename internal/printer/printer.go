@@ -1,6 +1,7 @@
 package printer
 
 import (
+	"io"
 	"sync/atomic"
 
 	"github.com/funnyzak/reqtap/internal/config"
@@ -14,21 +15,56 @@ type Printer interface {
 	PrintRequest(*request.RequestData) error
 }
 
+// OutcomePrinter 在请求处理完毕（含转发）后输出一条完整记录，而不是在捕获时立即输出
+type OutcomePrinter interface {
+	Printer
+	PrintOutcome(*request.RequestData, Outcome) error
+}
+
+// PausePrinter 在捕获暂停或恢复时显示状态；skipped 为暂停期间未记录的请求数
+type PausePrinter interface {
+	Printer
+	SetPaused(paused bool, skipped int64)
+}
+
+// Outcome 汇总请求被捕获之后的处理结果
+type Outcome struct {
+	StorageID string
+	Forwards  []ForwardOutcome
+}
+
+// ForwardOutcome 描述单个转发目标的结果
+type ForwardOutcome struct {
+	URL       string `json:"url"`
+	Status    int    `json:"status,omitempty"`
+	Success   bool   `json:"success"`
+	Attempts  int    `json:"attempts,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
 var globalRequestCounter uint64
 
 func nextRequestNumber() uint64 {
 	return atomic.AddUint64(&globalRequestCounter, 1)
 }
 
-// New 创建指定模式的 Printer
-func New(mode string, log logger.Logger, cfg *config.OutputConfig, translator *i18n.Translator, locale string) Printer {
+// New 创建指定模式的 Printer，输出到 out（nil 表示 stdout，见 Writer）
+func New(mode string, log logger.Logger, cfg *config.OutputConfig, translator *i18n.Translator, locale string, out io.Writer) Printer {
 	if cfg == nil {
 		cfg = &config.OutputConfig{}
 	}
 	switch mode {
 	case "json":
-		return NewJSONPrinter(log)
+		p := NewJSONPrinter(log)
+		p.SetOutput(out)
+		p.SetBodyFilter(cfg.BodyFilter)
+		return p
 	default:
-		return NewConsolePrinter(log, &cfg.BodyView, translator, locale)
+		p := NewConsolePrinter(log, &cfg.BodyView, translator, locale)
+		p.SetOutput(out)
+		p.SetColors(cfg.Theme, !cfg.NoColor)
+		p.SetBodyFilter(cfg.BodyFilter)
+		return p
 	}
 }
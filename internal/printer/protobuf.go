@@ -0,0 +1,30 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// rendersProtobuf 判断请求体是否已按 output.body_view.protobuf 解码，可替代二进制摘要展示
+func (f *bodyFormatter) rendersProtobuf(data *request.RequestData) bool {
+	return f != nil && f.cfg.Enable && f.cfg.Protobuf.Enable && data.Protobuf != nil && len(data.Protobuf.JSON) > 0
+}
+
+// formatProtobuf 缩进展示解码后的 Protobuf 消息
+func (f *bodyFormatter) formatProtobuf(data *request.RequestData) (formattedBody, bool) {
+	if !f.rendersProtobuf(data) {
+		return formattedBody{}, false
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, data.Protobuf.JSON, "", "  "); err != nil {
+		return formattedBody{}, false
+	}
+	title := f.t(keyProtobufRaw)
+	if data.Protobuf.Message != "" {
+		title = fmt.Sprintf(f.t(keyProtobufMessage), data.Protobuf.Message)
+	}
+	return formattedBody{Text: title + "\n" + pretty.String()}, true
+}
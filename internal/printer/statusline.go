@@ -0,0 +1,143 @@
+package printer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/funnyzak/reqtap/pkg/i18n"
+	runewidth "github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// statusLineInterval is how often the status line is redrawn
+const statusLineInterval = time.Second
+
+// StatusSnapshot is what the status line shows
+type StatusSnapshot struct {
+	Uptime        time.Duration
+	Captured      int64
+	RatePerSecond float64
+	ForwardErrors int64
+}
+
+// StatusLine keeps a status line on the bottom row of the terminal. The row is left out of the
+// scrolling region, so request output and log lines scroll above it without going through the
+// status line, and every redraw is a single write that restores the cursor.
+type StatusLine struct {
+	out        *os.File
+	snapshot   func() StatusSnapshot
+	translator *i18n.Translator
+	locale     string
+
+	mu   sync.Mutex
+	rows int
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStatusLine returns a status line for out that shows what snapshot returns
+func NewStatusLine(out *os.File, translator *i18n.Translator, locale string, snapshot func() StatusSnapshot) *StatusLine {
+	return &StatusLine{out: out, snapshot: snapshot, translator: translator, locale: locale}
+}
+
+// Start reserves the bottom row and redraws it every second until Stop. It does nothing and
+// returns false when out is not a terminal.
+func (s *StatusLine) Start() bool {
+	if s == nil || !term.IsTerminal(int(s.out.Fd())) || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return true
+	}
+	_, rows, err := term.GetSize(int(s.out.Fd()))
+	if err != nil || rows < 2 {
+		return false
+	}
+	// The new line keeps the cursor off the reserved row
+	fmt.Fprint(s.out, "\n\x1b[1A")
+	s.reserve(rows)
+	s.draw()
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+	return true
+}
+
+// Stop clears the status line and gives the row back to the scrolling output
+func (s *StatusLine) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, "\x1b7\x1b[r\x1b[%d;1H\x1b[2K\x1b8", s.rows)
+}
+
+func (s *StatusLine) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(statusLineInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			// The scrolling region follows the terminal when it is resized
+			if _, rows, err := term.GetSize(int(s.out.Fd())); err == nil && rows >= 2 && rows != s.rows {
+				s.reserve(rows)
+			}
+			s.draw()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// reserve limits scrolling to the rows above the last one; the cursor is saved and restored
+// because setting the region moves it to the top
+func (s *StatusLine) reserve(rows int) {
+	s.rows = rows
+	fmt.Fprintf(s.out, "\x1b7\x1b[1;%dr\x1b8", rows-1)
+}
+
+func (s *StatusLine) draw() {
+	text := s.text(s.snapshot())
+	if width, _, err := term.GetSize(int(s.out.Fd())); err == nil && width > 0 {
+		text = runewidth.Truncate(text, width, "")
+	}
+	fmt.Fprintf(s.out, "\x1b7\x1b[%d;1H\x1b[2K%s\x1b8", s.rows, color.New(color.ReverseVideo).Sprint(text))
+}
+
+func (s *StatusLine) text(snap StatusSnapshot) string {
+	format := keyStatusLine
+	if s.translator != nil {
+		format = s.translator.Text(s.locale, keyStatusLine)
+	}
+	return fmt.Sprintf(format,
+		formatUptime(snap.Uptime),
+		humanize.Comma(snap.Captured),
+		snap.RatePerSecond,
+		humanize.Comma(snap.ForwardErrors),
+	)
+}
+
+// formatUptime prints whole seconds, e.g. 1h2m3s
+func formatUptime(d time.Duration) string {
+	return d.Truncate(time.Second).String()
+}
@@ -0,0 +1,53 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// WriteSummary prints summary as plain text: totals, methods, body sizes, forwards and top paths.
+func WriteSummary(w io.Writer, summary *storage.Summary) error {
+	var b strings.Builder
+	b.WriteString("Session summary")
+	if !summary.From.IsZero() {
+		fmt.Fprintf(&b, " (%s - %s)", summary.From.Local().Format("2006-01-02 15:04:05"), summary.To.Local().Format("2006-01-02 15:04:05"))
+	}
+	b.WriteString("\n")
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  Requests\t%d\n", summary.Total)
+	if summary.Total > 0 {
+		methods := make([]string, 0, len(summary.Methods))
+		for _, method := range summary.Methods {
+			methods = append(methods, fmt.Sprintf("%s %d", method.Name, method.Count))
+		}
+		fmt.Fprintf(tw, "  Methods\t%s\n", strings.Join(methods, ", "))
+		fmt.Fprintf(tw, "  Body size\tp50 %s, p95 %s\n", humanize.Bytes(uint64(summary.BodySizeP50)), humanize.Bytes(uint64(summary.BodySizeP95)))
+	}
+	if summary.Forwards > 0 {
+		fmt.Fprintf(tw, "  Forwards\t%d, %.1f%% succeeded, latency p50 %dms, p95 %dms\n", summary.Forwards,
+			summary.ForwardSuccessRate*100, summary.ForwardLatencyP50Ms, summary.ForwardLatencyP95Ms)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(summary.TopPaths) > 0 {
+		b.WriteString("  Top paths\n")
+		tw = tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, path := range summary.TopPaths {
+			fmt.Fprintf(tw, "    %d\t  %s\n", path.Count, path.Name)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
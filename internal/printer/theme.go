@@ -0,0 +1,123 @@
+package printer
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Console color themes of output.theme
+const (
+	// ThemeDefault suits dark terminals
+	ThemeDefault = "default"
+	// ThemeSolarized only uses the accent colors of the Solarized palette, so it reads on both its
+	// light and dark variants
+	ThemeSolarized = "solarized"
+	// ThemeMono sets no colors, only bold, faint and underline
+	ThemeMono = "mono"
+)
+
+// ColorScheme color scheme
+type ColorScheme struct {
+	MethodGET      *color.Color
+	MethodPOST     *color.Color
+	MethodPUT      *color.Color
+	MethodDELETE   *color.Color
+	MethodPATCH    *color.Color
+	MethodOther    *color.Color
+	HeaderKey      *color.Color
+	HeaderValue    *color.Color
+	Separator      *color.Color
+	Timestamp      *color.Color
+	BodyContent    *color.Color
+	BinaryNotice   *color.Color
+	TruncateNotice *color.Color
+	RemoteAddr     *color.Color
+	Query          *color.Color
+	JWTValid       *color.Color
+	JWTExpired     *color.Color
+}
+
+// NewColorScheme creates the color scheme of the default theme
+func NewColorScheme() *ColorScheme {
+	return &ColorScheme{
+		MethodGET:      color.New(color.FgBlue, color.Bold),
+		MethodPOST:     color.New(color.FgGreen, color.Bold),
+		MethodPUT:      color.New(color.FgYellow, color.Bold),
+		MethodDELETE:   color.New(color.FgRed, color.Bold),
+		MethodPATCH:    color.New(color.FgMagenta, color.Bold),
+		MethodOther:    color.New(color.Bold),
+		HeaderKey:      color.New(color.FgCyan),
+		HeaderValue:    color.New(color.Reset),
+		Separator:      color.New(color.FgYellow, color.Bold),
+		Timestamp:      color.New(color.FgHiBlack),
+		BodyContent:    color.New(color.Reset),
+		BinaryNotice:   color.New(color.FgHiRed, color.Bold),
+		TruncateNotice: color.New(color.FgHiYellow, color.Bold),
+		RemoteAddr:     color.New(color.FgHiBlue),
+		Query:          color.New(color.FgHiMagenta),
+		JWTValid:       color.New(color.FgGreen, color.Bold),
+		JWTExpired:     color.New(color.FgHiRed, color.Bold),
+	}
+}
+
+// ThemeColorScheme creates the color scheme of a theme; unknown names get the default theme
+func ThemeColorScheme(theme string) *ColorScheme {
+	switch strings.ToLower(strings.TrimSpace(theme)) {
+	case ThemeSolarized:
+		// Solarized maps the bright colors to its background tones, except red (orange) and
+		// magenta (violet)
+		return &ColorScheme{
+			MethodGET:      color.New(color.FgBlue, color.Bold),
+			MethodPOST:     color.New(color.FgGreen, color.Bold),
+			MethodPUT:      color.New(color.FgYellow, color.Bold),
+			MethodDELETE:   color.New(color.FgRed, color.Bold),
+			MethodPATCH:    color.New(color.FgMagenta, color.Bold),
+			MethodOther:    color.New(color.Bold),
+			HeaderKey:      color.New(color.FgCyan),
+			HeaderValue:    color.New(color.Reset),
+			Separator:      color.New(color.FgBlue),
+			Timestamp:      color.New(color.Faint),
+			BodyContent:    color.New(color.Reset),
+			BinaryNotice:   color.New(color.FgRed, color.Bold),
+			TruncateNotice: color.New(color.FgHiRed),
+			RemoteAddr:     color.New(color.FgBlue),
+			Query:          color.New(color.FgHiMagenta),
+			JWTValid:       color.New(color.FgGreen, color.Bold),
+			JWTExpired:     color.New(color.FgRed, color.Bold),
+		}
+	case ThemeMono:
+		return &ColorScheme{
+			MethodGET:      color.New(color.Bold),
+			MethodPOST:     color.New(color.Bold),
+			MethodPUT:      color.New(color.Bold),
+			MethodDELETE:   color.New(color.Bold),
+			MethodPATCH:    color.New(color.Bold),
+			MethodOther:    color.New(color.Bold),
+			HeaderKey:      color.New(color.Bold),
+			HeaderValue:    color.New(color.Reset),
+			Separator:      color.New(color.Bold),
+			Timestamp:      color.New(color.Faint),
+			BodyContent:    color.New(color.Reset),
+			BinaryNotice:   color.New(color.Bold),
+			TruncateNotice: color.New(color.Bold),
+			RemoteAddr:     color.New(color.Reset),
+			Query:          color.New(color.Underline),
+			JWTValid:       color.New(color.Bold),
+			JWTExpired:     color.New(color.Bold, color.Underline),
+		}
+	default:
+		return NewColorScheme()
+	}
+}
+
+// disable makes every color of the scheme print plain text
+func (s *ColorScheme) disable() {
+	for _, c := range []*color.Color{
+		s.MethodGET, s.MethodPOST, s.MethodPUT, s.MethodDELETE, s.MethodPATCH, s.MethodOther,
+		s.HeaderKey, s.HeaderValue, s.Separator, s.Timestamp, s.BodyContent, s.BinaryNotice,
+		s.TruncateNotice, s.RemoteAddr, s.Query, s.JWTValid, s.JWTExpired,
+	} {
+		c.DisableColor()
+	}
+}
@@ -0,0 +1,145 @@
+// Package redact masks sensitive header values, JSON body fields and text patterns of a captured
+// request.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Mask replaces every redacted value.
+const Mask = "[REDACTED]"
+
+// Rules lists what to mask.
+type Rules struct {
+	// Headers are header names, matched case-insensitively
+	Headers []string
+	// Fields are JSON paths such as "card.number" or "$.items[0].token" into JSON bodies
+	Fields []string
+	// Patterns mask every match in header values, the query string and text bodies
+	Patterns []*regexp.Regexp
+}
+
+// Empty reports whether the rules mask nothing.
+func (r Rules) Empty() bool {
+	return len(r.Headers) == 0 && len(r.Fields) == 0 && len(r.Patterns) == 0
+}
+
+// Apply masks the matching values of data in place and reports whether anything was masked.
+// Of a spilled body only the preview is masked, the file keeps the body as received, so the server
+// does not spill bodies while rules mask them. When a masked body had been decoded from a
+// Content-Encoding, the encoded copy is dropped so that it cannot be stored or forwarded, and the
+// request is delivered decoded from then on.
+func (r Rules) Apply(data *request.RequestData) bool {
+	changed := r.applyHeaders(data.Headers)
+	if query, ok := r.maskPatterns([]byte(data.Query)); ok {
+		data.Query = string(query)
+		changed = true
+	}
+	if data.Protobuf != nil && len(data.Protobuf.JSON) > 0 {
+		if decoded, ok := r.maskBody(data.Protobuf.JSON); ok {
+			data.Protobuf.JSON = decoded
+			changed = true
+		}
+	}
+	if len(data.Body) == 0 || data.IsBinary {
+		return changed
+	}
+	body, masked := r.maskBody(data.Body)
+	if !masked {
+		return changed
+	}
+	data.Body = body
+	if data.BodyFile == "" {
+		data.Size = int64(len(body))
+	}
+	if data.WireBody != nil {
+		data.WireBody = nil
+		data.WireSize = 0
+		data.Headers.Del("Content-Encoding")
+	}
+	return true
+}
+
+func (r Rules) applyHeaders(headers http.Header) bool {
+	changed := false
+	for _, name := range r.Headers {
+		values := headers[http.CanonicalHeaderKey(name)]
+		for i := range values {
+			values[i] = Mask
+			changed = true
+		}
+	}
+	if len(r.Patterns) == 0 {
+		return changed
+	}
+	for _, values := range headers {
+		for i, value := range values {
+			if masked, ok := r.maskPatterns([]byte(value)); ok {
+				values[i] = string(masked)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// maskBody masks the fields and patterns of a text body; ok is false when nothing matched.
+func (r Rules) maskBody(body []byte) ([]byte, bool) {
+	masked := false
+	if len(r.Fields) > 0 {
+		if fields, ok := r.maskFields(body); ok {
+			body, masked = fields, true
+		}
+	}
+	if patterns, ok := r.maskPatterns(body); ok {
+		body, masked = patterns, true
+	}
+	return body, masked
+}
+
+// maskPatterns returns text with every pattern match masked; ok is false when nothing matched.
+func (r Rules) maskPatterns(text []byte) ([]byte, bool) {
+	matched := false
+	for _, pattern := range r.Patterns {
+		if pattern.Match(text) {
+			text = pattern.ReplaceAllLiteral(text, []byte(Mask))
+			matched = true
+		}
+	}
+	return text, matched
+}
+
+// maskFields returns body with the configured fields masked; ok is false when body is not JSON
+// or none of the fields is present.
+func (r Rules) maskFields(body []byte) ([]byte, bool) {
+	doc, err := jsonpath.Decode(body)
+	if err != nil {
+		return nil, false
+	}
+	masked := false
+	for _, field := range r.Fields {
+		if _, ok := jsonpath.Lookup(doc, field); !ok {
+			continue
+		}
+		if updated, ok := jsonpath.Set(doc, field, Mask); ok {
+			doc = updated
+			masked = true
+		}
+	}
+	if !masked {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, false
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), true
+}
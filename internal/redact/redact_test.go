@@ -0,0 +1,58 @@
+package redact
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestRulesApply(t *testing.T) {
+	rules := Rules{Headers: []string{"authorization"}, Fields: []string{"card.number", "$.items[1].token", "missing"}}
+	data := &request.RequestData{
+		Headers:  http.Header{"Authorization": {"Bearer secret"}, "Content-Encoding": {"gzip"}, "X-Event": {"paid"}},
+		Body:     []byte(`{"card":{"number":"4242424242424242","brand":"visa"},"items":[{"token":"a"},{"token":"b"}],"amount":12.50}`),
+		WireBody: []byte("gzipped"),
+	}
+	if !rules.Apply(data) {
+		t.Fatal("expected the request to be changed")
+	}
+	if data.Headers.Get("Authorization") != Mask || data.Headers.Get("X-Event") != "paid" {
+		t.Fatalf("unexpected headers: %v", data.Headers)
+	}
+	want := `{"amount":12.50,"card":{"brand":"visa","number":"[REDACTED]"},"items":[{"token":"a"},{"token":"[REDACTED]"}]}`
+	if string(data.Body) != want || data.Size != int64(len(want)) {
+		t.Fatalf("unexpected body %s", data.Body)
+	}
+	// The encoded copy still holds the original values
+	if data.WireBody != nil || data.Headers.Get("Content-Encoding") != "" {
+		t.Fatal("expected the encoded body to be dropped")
+	}
+
+	plain := &request.RequestData{Headers: http.Header{}, Body: []byte("card=4242")}
+	if rules.Apply(plain) || string(plain.Body) != "card=4242" {
+		t.Fatal("expected a non-JSON body to be left alone")
+	}
+}
+
+func TestRulesApplyPatterns(t *testing.T) {
+	rules := Rules{Patterns: []*regexp.Regexp{
+		regexp.MustCompile(`\b\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{4}\b`),
+		regexp.MustCompile(`(?i)bearer\s+[a-z0-9._-]+`),
+	}}
+	data := &request.RequestData{
+		Query:   "card=4242 4242 4242 4242&amount=10",
+		Headers: http.Header{"X-Forwarded-Auth": {"Bearer abc.def"}},
+		Body:    []byte("name=Jane&card=4242-4242-4242-4242"),
+	}
+	if !rules.Apply(data) {
+		t.Fatal("expected the request to be changed")
+	}
+	if data.Query != "card=[REDACTED]&amount=10" || data.Headers.Get("X-Forwarded-Auth") != Mask {
+		t.Fatalf("unexpected query %q or headers %v", data.Query, data.Headers)
+	}
+	if string(data.Body) != "name=Jane&card=[REDACTED]" {
+		t.Fatalf("unexpected body %s", data.Body)
+	}
+}
@@ -0,0 +1,322 @@
+// Package script runs user-provided JavaScript over captured requests.
+//
+// A script defines a global function handle(req) that is called once per request. req holds
+// id, method, path, query, params, headers, remote_addr, content_type, body and, when the body
+// is JSON, json; req.header(name) reads a header regardless of its case. handle returns nothing
+// to leave the request alone, or a decision object:
+//
+//	{
+//	  respond: {status: 202, headers: {...}, body: "text" or an object sent as JSON},
+//	  forward: false, or {body: ..., headers: {"X-Drop-Me": null, ...}},
+//	  tags: ["vip"],
+//	  drop: true
+//	}
+//
+// console.log, console.warn and console.error write to the ReqTap log. Every call is aborted
+// once its timeout expires. Global variables live in one of several runtimes, so they must not
+// be relied on to carry state between requests.
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// handlerFunction is the global function a script must define
+const handlerFunction = "handle"
+
+// maxIdleRuntimes bounds the runtimes kept for reuse between calls
+const maxIdleRuntimes = 8
+
+// Decision is what a script decided for a request; the zero value leaves it alone.
+type Decision struct {
+	// Respond answers the client in place of the mock rules
+	Respond *Response
+	// Forward changes the request as it is forwarded; nil forwards it unchanged
+	Forward *ForwardChange
+	// SkipForward keeps the request from being forwarded
+	SkipForward bool
+	Tags        []string
+	// Drop answers the client but records, prints and forwards nothing
+	Drop bool
+}
+
+// Response is a response written by a script.
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// ForwardChange rewrites the forwarded copy of a request.
+type ForwardChange struct {
+	// Body replaces the forwarded body when not nil
+	Body []byte
+	// Headers sets headers; a nil value removes the header
+	Headers map[string]*string
+}
+
+// Script is a compiled script; calls run concurrently, each on a runtime of its own.
+type Script struct {
+	name    string
+	timeout time.Duration
+	program *goja.Program
+	log     logger.Logger
+	idle    chan *runtime
+}
+
+type runtime struct {
+	vm     *goja.Runtime
+	handle goja.Callable
+}
+
+// Load compiles the script at cfg.Path.
+func Load(cfg config.ScriptConfig, log logger.Logger) (*Script, error) {
+	source, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read script %s: %w", cfg.Name, err)
+	}
+	return Compile(cfg, string(source), log)
+}
+
+// Compile builds a Script from source.
+func Compile(cfg config.ScriptConfig, source string, log logger.Logger) (*Script, error) {
+	program, err := goja.Compile(cfg.Path, source, false)
+	if err != nil {
+		return nil, fmt.Errorf("compile script %s: %w", cfg.Name, err)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+	s := &Script{name: cfg.Name, timeout: timeout, program: program, log: log, idle: make(chan *runtime, maxIdleRuntimes)}
+	// Running the top level once surfaces errors and a missing handle function at startup
+	rt, err := s.newRuntime()
+	if err != nil {
+		return nil, err
+	}
+	s.release(rt)
+	return s, nil
+}
+
+// Name returns the configured script name.
+func (s *Script) Name() string {
+	return s.name
+}
+
+// Run calls handle with data and returns the script's decision.
+func (s *Script) Run(ctx context.Context, data *request.RequestData) (*Decision, error) {
+	rt, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	interruptSent := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		rt.vm.Interrupt(ctx.Err())
+		close(interruptSent)
+	})
+	result, err := rt.handle(goja.Undefined(), rt.vm.ToValue(requestObject(data)))
+	if !stop() {
+		// The interrupt may land after handle returned; a runtime is only reused once it is cleared
+		<-interruptSent
+	}
+	rt.vm.ClearInterrupt()
+	s.release(rt)
+
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("script %s exceeded its %s time limit", s.name, s.timeout)
+		}
+		return nil, fmt.Errorf("script %s: %w", s.name, err)
+	}
+	decision, err := decodeDecision(result)
+	if err != nil {
+		return nil, fmt.Errorf("script %s returned an invalid decision: %w", s.name, err)
+	}
+	return decision, nil
+}
+
+func (s *Script) acquire() (*runtime, error) {
+	select {
+	case rt := <-s.idle:
+		return rt, nil
+	default:
+		return s.newRuntime()
+	}
+}
+
+func (s *Script) release(rt *runtime) {
+	select {
+	case s.idle <- rt:
+	default:
+	}
+}
+
+func (s *Script) newRuntime() (*runtime, error) {
+	vm := goja.New()
+	console := vm.NewObject()
+	console.Set("log", s.logFunc(s.log.Info))
+	console.Set("info", s.logFunc(s.log.Info))
+	console.Set("warn", s.logFunc(s.log.Warn))
+	console.Set("error", s.logFunc(s.log.Error))
+	vm.Set("console", console)
+
+	timer := time.AfterFunc(s.timeout, func() {
+		vm.Interrupt("timeout")
+	})
+	_, err := vm.RunProgram(s.program)
+	timer.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", s.name, err)
+	}
+	vm.ClearInterrupt()
+	handle, ok := goja.AssertFunction(vm.Get(handlerFunction))
+	if !ok {
+		return nil, fmt.Errorf("script %s must define a %s(req) function", s.name, handlerFunction)
+	}
+	return &runtime{vm: vm, handle: handle}, nil
+}
+
+func (s *Script) logFunc(write func(msg string, fields ...interface{})) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		parts := make([]string, 0, len(call.Arguments))
+		for _, arg := range call.Arguments {
+			parts = append(parts, arg.String())
+		}
+		write(strings.Join(parts, " "), "script", s.name)
+		return goja.Undefined()
+	}
+}
+
+// requestObject is the req argument of handle
+func requestObject(data *request.RequestData) map[string]interface{} {
+	headers := make(map[string]interface{}, len(data.Headers))
+	for name, values := range data.Headers {
+		headers[name] = strings.Join(values, ", ")
+	}
+	params := map[string]interface{}{}
+	if values, err := url.ParseQuery(data.Query); err == nil {
+		for name := range values {
+			params[name] = values.Get(name)
+		}
+	}
+	req := map[string]interface{}{
+		"id":           data.ID,
+		"method":       data.Method,
+		"path":         data.Path,
+		"query":        data.Query,
+		"params":       params,
+		"headers":      headers,
+		"remote_addr":  data.RemoteAddr,
+		"content_type": data.ContentType,
+		"body":         string(data.Body),
+		"header": func(name string) string {
+			return strings.Join(data.Headers.Values(name), ", ")
+		},
+	}
+	var parsed interface{}
+	if !data.IsBinary && json.Unmarshal(data.Body, &parsed) == nil {
+		req["json"] = parsed
+	}
+	return req
+}
+
+// rawDecision is the decision object as the script returned it
+type rawDecision struct {
+	Respond *struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	} `json:"respond"`
+	Forward json.RawMessage `json:"forward"`
+	Tags    []string        `json:"tags"`
+	Drop    bool            `json:"drop"`
+}
+
+func decodeDecision(value goja.Value) (*Decision, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return &Decision{}, nil
+	}
+	encoded, err := json.Marshal(value.Export())
+	if err != nil {
+		return nil, err
+	}
+	var raw rawDecision
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{Tags: raw.Tags, Drop: raw.Drop}
+	if raw.Respond != nil {
+		status := raw.Respond.Status
+		if status == 0 {
+			status = 200
+		}
+		if status < 100 || status > 999 {
+			return nil, fmt.Errorf("respond status %d is out of range", status)
+		}
+		decision.Respond = &Response{Status: status, Headers: raw.Respond.Headers, Body: bodyBytes(raw.Respond.Body)}
+		if !isJSONString(raw.Respond.Body) && len(raw.Respond.Body) > 0 && !hasHeader(decision.Respond.Headers, "Content-Type") {
+			if decision.Respond.Headers == nil {
+				decision.Respond.Headers = map[string]string{}
+			}
+			decision.Respond.Headers["Content-Type"] = "application/json"
+		}
+	}
+	switch forward := strings.TrimSpace(string(raw.Forward)); forward {
+	case "", "null", "true":
+	case "false":
+		decision.SkipForward = true
+	default:
+		var change struct {
+			Body    json.RawMessage    `json:"body"`
+			Headers map[string]*string `json:"headers"`
+		}
+		if err := json.Unmarshal(raw.Forward, &change); err != nil {
+			return nil, fmt.Errorf("forward must be false or an object")
+		}
+		decision.Forward = &ForwardChange{Body: bodyBytes(change.Body), Headers: change.Headers}
+	}
+	return decision, nil
+}
+
+// bodyBytes sends strings as they are and anything else as JSON; nil when there is no body
+func bodyBytes(raw json.RawMessage) []byte {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []byte(text)
+	}
+	return []byte(raw)
+}
+
+func isJSONString(raw json.RawMessage) bool {
+	return len(raw) > 0 && raw[0] == '"'
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package script
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+const decideScript = `
+function handle(req) {
+  if (req.path === "/health") return { drop: true };
+  if (req.params.mode === "skip") return { forward: false };
+  if (req.json && req.json.type === "ping") {
+    return { respond: { status: 202, body: { pong: req.json.id, sig: req.header("x-signature") } }, tags: ["ping"] };
+  }
+  if (req.method === "POST") {
+    return { forward: { body: req.body.toUpperCase(), headers: { "X-Script": "yes", "Authorization": null } } };
+  }
+}
+`
+
+func TestScriptDecisions(t *testing.T) {
+	s, err := Compile(config.ScriptConfig{Name: "decide", Timeout: time.Second}, decideScript, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(method, path, query, body string) *Decision {
+		t.Helper()
+		data := &request.RequestData{ID: "REQ", Method: method, Path: path, Query: query, Body: []byte(body),
+			Headers: http.Header{"X-Signature": {"abc"}, "Authorization": {"Bearer x"}}}
+		decision, err := s.Run(context.Background(), data)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return decision
+	}
+
+	if d := run("GET", "/health", "", ""); !d.Drop {
+		t.Fatalf("expected a drop, got %+v", d)
+	}
+	if d := run("GET", "/orders", "mode=skip", ""); !d.SkipForward || d.Forward != nil {
+		t.Fatalf("expected forwarding to be skipped, got %+v", d)
+	}
+	d := run("POST", "/hook", "", `{"type":"ping","id":7}`)
+	if d.Respond == nil || d.Respond.Status != 202 || d.Respond.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("expected a JSON response, got %+v", d.Respond)
+	}
+	if string(d.Respond.Body) != `{"pong":7,"sig":"abc"}` || strings.Join(d.Tags, ",") != "ping" {
+		t.Fatalf("unexpected response body or tags: %s %v", d.Respond.Body, d.Tags)
+	}
+	d = run("POST", "/hook", "", "hello")
+	if d.Forward == nil || string(d.Forward.Body) != "HELLO" || *d.Forward.Headers["X-Script"] != "yes" {
+		t.Fatalf("expected a rewritten forward, got %+v", d.Forward)
+	}
+	if value, ok := d.Forward.Headers["Authorization"]; !ok || value != nil {
+		t.Fatal("expected a null header to remove it")
+	}
+	if d := run("GET", "/other", "", ""); d.Respond != nil || d.Forward != nil || d.Drop || d.SkipForward {
+		t.Fatalf("expected no decision, got %+v", d)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	if _, err := Compile(config.ScriptConfig{Name: "broken"}, "function handle(req) {", noopLogger{}); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+	if _, err := Compile(config.ScriptConfig{Name: "empty"}, "var x = 1", noopLogger{}); err == nil || !strings.Contains(err.Error(), "handle(req)") {
+		t.Fatalf("expected a missing handle function to be reported, got %v", err)
+	}
+
+	s, err := Compile(config.ScriptConfig{Name: "spin", Timeout: 20 * time.Millisecond},
+		`function handle(req) { if (req.path === "/spin") { for (;;) {} } return { respond: { status: 5 } } }`, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(context.Background(), &request.RequestData{Path: "/spin"}); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Fatalf("expected the time limit to abort the script, got %v", err)
+	}
+	// The interrupted runtime is reused for the next call
+	if _, err := s.Run(context.Background(), &request.RequestData{Path: "/"}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected an invalid status to be rejected, got %v", err)
+	}
+}
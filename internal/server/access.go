@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/web"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// AccessControlOptions lists the address ranges allowed and denied on the capture path
+type AccessControlOptions struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+func buildAccessControlOptions(cfg config.AccessControlConfig) AccessControlOptions {
+	return AccessControlOptions{
+		Allow: parseAddressRanges(cfg.Allow),
+		Deny:  parseAddressRanges(cfg.Deny),
+	}
+}
+
+// parseAddressRanges skips invalid entries; config validation already reported them
+func parseAddressRanges(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		if prefix, err := config.ParseAddressRange(entry); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// Enabled reports whether any allow or deny range is configured
+func (o AccessControlOptions) Enabled() bool {
+	return len(o.Allow) > 0 || len(o.Deny) > 0
+}
+
+// accessStage rejects capture requests from addresses outside of server.access_control with 403
+// before anything else is done with them.
+func (h *Handler) accessStage(_ context.Context, ex *Exchange) error {
+	cfg := h.currentConfig()
+	opts := cfg.AccessControl
+	if !opts.Enabled() {
+		return nil
+	}
+	client, ok := request.ClientAddr(ex.Request, cfg.TrustedProxies)
+	denied := ok && matchesAny(opts.Deny, client)
+	notAllowed := len(opts.Allow) > 0 && !(ok && matchesAny(opts.Allow, client))
+	if !denied && !notAllowed {
+		return nil
+	}
+	if denied {
+		h.accessDenied.Add(1)
+	} else {
+		h.accessNotAllowed.Add(1)
+	}
+	h.logger.Debug("Capture request rejected by access control",
+		"method", ex.Request.Method,
+		"path", ex.Request.URL.Path,
+		"remote_addr", ex.Request.RemoteAddr,
+		"client_ip", client,
+		"denied", denied,
+	)
+	h.writeError(ex.Writer, http.StatusForbidden)
+	return ErrStopPipeline
+}
+
+// AccessStats reports how many capture requests access control rejected.
+func (h *Handler) AccessStats() web.AccessStats {
+	denied, notAllowed := h.accessDenied.Load(), h.accessNotAllowed.Load()
+	return web.AccessStats{Rejected: denied + notAllowed, Denied: denied, NotAllowed: notAllowed}
+}
+
+func matchesAny(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
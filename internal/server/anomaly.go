@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/funnyzak/reqtap/internal/anomaly"
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/web"
+)
+
+// StageAnomaly feeds every processed request to the anomaly detector.
+const StageAnomaly = "anomaly"
+
+// installAnomalyStage observes requests once forwarding has finished, so failed deliveries count as errors.
+func (h *Handler) installAnomalyStage(detector *anomaly.Detector) error {
+	if detector == nil {
+		return nil
+	}
+	return h.pipeline.InsertAfter(StageForward, Stage{Name: StageAnomaly, Phase: PhaseAsync, Run: func(_ context.Context, ex *Exchange) error {
+		failed := ex.Record.MockResponse.Status >= 400
+		for _, res := range ex.Results {
+			if !res.Success {
+				failed = true
+				break
+			}
+		}
+		detector.Observe(ex.Record.Timestamp, ex.Record.Size, failed)
+		return nil
+	}})
+}
+
+// newAnomalyDetector reports events to the log, live consoles and configured webhooks.
+func newAnomalyDetector(cfg config.AnomalyConfig, log logger.Logger, webService *web.Service, ctx context.Context, wg *sync.WaitGroup) *anomaly.Detector {
+	if !cfg.Enable {
+		return nil
+	}
+	webhooks := anomaly.NewWebhookNotifier(cfg.Webhooks, log)
+	detector := anomaly.New(anomaly.Options{
+		Window:          cfg.Window,
+		BaselineWindows: cfg.BaselineWindows,
+		Threshold:       cfg.Threshold,
+		MinRequests:     cfg.MinRequests,
+		Cooldown:        cfg.Cooldown,
+	}, func(ev anomaly.Event) {
+		log.Warn("Traffic anomaly detected",
+			"metric", ev.Metric,
+			"direction", ev.Direction,
+			"value", ev.Value,
+			"baseline", ev.Baseline,
+			"score", ev.Score,
+			"window_start", ev.WindowStart,
+		)
+		webService.NotifyAnomaly(ev)
+		if webhooks != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				webhooks.Notify(ctx, ev)
+			}()
+		}
+	})
+	go detector.Run(ctx)
+	return detector
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// CaptureAuthOptions lists the credentials a request must present on the capture path
+type CaptureAuthOptions struct {
+	Enable      bool
+	Realm       string
+	Credentials []CaptureCredential
+}
+
+// CaptureCredential is a bearer Token or a Username/Password pair; Name is recorded on captured requests
+type CaptureCredential struct {
+	Name     string
+	Token    string
+	Username string
+	Password string
+}
+
+func buildCaptureAuthOptions(cfg config.CaptureAuthConfig) CaptureAuthOptions {
+	opts := CaptureAuthOptions{Enable: cfg.Enable, Realm: cfg.Realm}
+	for _, c := range cfg.Credentials {
+		opts.Credentials = append(opts.Credentials, CaptureCredential{
+			Name:     c.Name,
+			Token:    c.Token,
+			Username: c.Username,
+			Password: c.Password,
+		})
+	}
+	return opts
+}
+
+// authStage rejects capture requests without a configured credential before their body is read.
+// The Authorization header is dropped once accepted so the secret is neither stored nor forwarded.
+func (h *Handler) authStage(_ context.Context, ex *Exchange) error {
+	opts := h.currentConfig().Auth
+	if !opts.Enable {
+		return nil
+	}
+	name, ok := opts.authenticate(ex.Request.Header.Get("Authorization"))
+	if !ok {
+		h.logger.Debug("Capture request rejected: missing or invalid credentials",
+			"method", ex.Request.Method,
+			"path", ex.Request.URL.Path,
+			"remote_addr", ex.Request.RemoteAddr,
+		)
+		ex.Writer.Header().Set("WWW-Authenticate", opts.challenge())
+		h.writeError(ex.Writer, http.StatusUnauthorized)
+		return ErrStopPipeline
+	}
+	ex.Request.Header.Del("Authorization")
+	ex.Credential = name
+	return nil
+}
+
+// authenticate returns the name of the credential matching an Authorization header value
+func (o CaptureAuthOptions) authenticate(header string) (string, bool) {
+	scheme, value, found := strings.Cut(header, " ")
+	if !found {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		for _, cred := range o.Credentials {
+			if cred.Token != "" && subtle.ConstantTimeCompare([]byte(cred.Token), []byte(value)) == 1 {
+				return cred.Name, true
+			}
+		}
+	case strings.EqualFold(scheme, "Basic"):
+		r := http.Request{Header: http.Header{"Authorization": {"Basic " + value}}}
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return "", false
+		}
+		for _, cred := range o.Credentials {
+			if cred.Username == "" {
+				continue
+			}
+			userMatch := subtle.ConstantTimeCompare([]byte(cred.Username), []byte(username))
+			passMatch := subtle.ConstantTimeCompare([]byte(cred.Password), []byte(password))
+			if userMatch&passMatch == 1 {
+				return cred.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// challenge asks for basic auth when a username is configured, for a bearer token otherwise
+func (o CaptureAuthOptions) challenge() string {
+	for _, cred := range o.Credentials {
+		if cred.Username != "" {
+			return fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, o.Realm)
+		}
+	}
+	return fmt.Sprintf(`Bearer realm=%q`, o.Realm)
+}
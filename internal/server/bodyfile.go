@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// serveBodyFile answers with the rule's body file. The ETag is derived from size and modification
+// time unless the rule sets its own.
+func (h *Handler) serveBodyFile(w http.ResponseWriter, r *http.Request, rule *ImmediateResponseRule) {
+	f, err := os.Open(rule.BodyFile)
+	if err != nil {
+		h.logger.Error("Failed to open mock body file", "rule", rule.Name, "file", rule.BodyFile, "error", err)
+		h.writeError(w, http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		h.logger.Error("Mock body file is not a regular file", "rule", rule.Name, "file", rule.BodyFile, "error", err)
+		h.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	if w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", fileETag(info))
+	}
+	h.logger.Debug("Immediate mock response applied",
+		"rule", rule.Name,
+		"status", rule.Status,
+		"file", rule.BodyFile,
+		"method", r.Method,
+		"path", r.URL.Path,
+	)
+	serveBodyContent(w, r, rule.Status, filepath.Base(rule.BodyFile), info.ModTime(), f, info.Size())
+}
+
+// serveBodyContent writes a body_file or body_url body. 200 rules go through http.ServeContent,
+// which handles Range, If-Range, If-None-Match and If-Modified-Since; other statuses get the whole
+// body. name picks the Content-Type by extension when none is set.
+func serveBodyContent(w http.ResponseWriter, r *http.Request, status int, name string, modTime time.Time, content io.ReadSeeker, size int64) {
+	if status == http.StatusOK {
+		http.ServeContent(w, r, name, modTime, content)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(size))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, content)
+	}
+}
+
+// fileETag is a strong validator that changes whenever the file is rewritten.
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	// bodyURLTimeout bounds one fetch of a body_url
+	bodyURLTimeout = 30 * time.Second
+	// maxBodyURLBytes bounds the size of a fetched body_url
+	maxBodyURLBytes = 64 << 20
+)
+
+// bodyURLCache keeps the bodies fetched for body_url rules, one entry per URL. The first fetch of a
+// URL is made by one request at a time while the others wait for its result; an expired body keeps
+// being served while it is refreshed in the background.
+type bodyURLCache struct {
+	client  *http.Client
+	mu      sync.Mutex
+	entries map[string]*bodyURLEntry
+}
+
+type bodyURLEntry struct {
+	mu      sync.Mutex
+	current *fetchedBody
+	// refreshing is set while an expired body is refetched; err is the failure of the last refetch
+	refreshing bool
+	err        error
+}
+
+// fetchedBody is one fetch of a body_url; it is never modified once cached
+type fetchedBody struct {
+	body        []byte
+	contentType string
+	etag        string
+	modTime     time.Time
+	fetchedAt   time.Time
+}
+
+func newBodyURLCache() *bodyURLCache {
+	return &bodyURLCache{
+		client:  &http.Client{Timeout: bodyURLTimeout},
+		entries: make(map[string]*bodyURLEntry),
+	}
+}
+
+// reset forgets every body, so reloaded rules fetch their URLs again.
+func (c *bodyURLCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]*bodyURLEntry)
+	c.mu.Unlock()
+}
+
+// get returns the body of rawURL, fetching it when it is not cached yet. A body older than ttl (0
+// never expires) is returned as it is and refetched in the background; while the last refetch
+// failed its error is returned with the stale body.
+func (c *bodyURLCache) get(ctx context.Context, rawURL string, ttl time.Duration) (*fetchedBody, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[rawURL]
+	if !ok {
+		entry = &bodyURLEntry{}
+		c.entries[rawURL] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	current := entry.current
+	if current != nil {
+		if ttl > 0 && time.Since(current.fetchedAt) >= ttl && !entry.refreshing {
+			entry.refreshing = true
+			go c.refresh(entry, rawURL, current)
+		}
+		return current, entry.err
+	}
+	// The fetch outlives a client that hangs up, so the requests waiting for it still get the body
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bodyURLTimeout)
+	defer cancel()
+	fetched, err := c.fetch(ctx, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	entry.current = fetched
+	return fetched, nil
+}
+
+// refresh refetches the expired body previous of entry without holding up the requests served
+// from it
+func (c *bodyURLCache) refresh(entry *bodyURLEntry, rawURL string, previous *fetchedBody) {
+	ctx, cancel := context.WithTimeout(context.Background(), bodyURLTimeout)
+	defer cancel()
+	fetched, err := c.fetch(ctx, rawURL, previous)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.refreshing = false
+	entry.err = err
+	if err == nil {
+		entry.current = fetched
+	}
+}
+
+// fetch downloads rawURL, revalidating previous with its validators when there is one
+func (c *bodyURLCache) fetch(ctx context.Context, rawURL string, previous *fetchedBody) (*fetchedBody, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		if previous.etag != "" {
+			req.Header.Set("If-None-Match", previous.etag)
+		}
+		if !previous.modTime.IsZero() {
+			req.Header.Set("If-Modified-Since", previous.modTime.UTC().Format(http.TimeFormat))
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && previous != nil {
+		revalidated := *previous
+		revalidated.fetchedAt = time.Now()
+		return &revalidated, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyURLBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodyURLBytes {
+		return nil, fmt.Errorf("body exceeds %d bytes", maxBodyURLBytes)
+	}
+
+	fetched := &fetchedBody{
+		body:        body,
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("ETag"),
+		fetchedAt:   time.Now(),
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		fetched.modTime = modTime
+	}
+	return fetched, nil
+}
+
+// serveBodyURL answers with the body fetched from the rule's body_url. The upstream Content-Type,
+// ETag and Last-Modified are passed on unless the rule sets its own headers; without an upstream
+// ETag one is derived from the content.
+func (h *Handler) serveBodyURL(w http.ResponseWriter, r *http.Request, rule *ImmediateResponseRule) {
+	fetched, err := h.bodyURLs.get(r.Context(), rule.BodyURL, rule.BodyURLTTL)
+	if fetched == nil {
+		h.logger.Error("Failed to fetch mock body URL", "rule", rule.Name, "url", rule.BodyURL, "error", err)
+		h.writeError(w, http.StatusBadGateway)
+		return
+	}
+	if err != nil {
+		h.logger.Warn("Failed to refresh mock body URL, serving the cached body", "rule", rule.Name, "url", rule.BodyURL, "error", err)
+	}
+
+	if w.Header().Get("Content-Type") == "" && fetched.contentType != "" {
+		w.Header().Set("Content-Type", fetched.contentType)
+	}
+	if w.Header().Get("ETag") == "" {
+		etag := fetched.etag
+		if etag == "" {
+			etag = fmt.Sprintf(`"%x"`, sha256.Sum256(fetched.body))
+		}
+		w.Header().Set("ETag", etag)
+	}
+	h.logger.Debug("Immediate mock response applied",
+		"rule", rule.Name,
+		"status", rule.Status,
+		"url", rule.BodyURL,
+		"method", r.Method,
+		"path", r.URL.Path,
+	)
+	name := ""
+	if parsed, err := url.Parse(rule.BodyURL); err == nil {
+		name = path.Base(parsed.Path)
+	}
+	serveBodyContent(w, r, rule.Status, name, fetched.modTime, bytes.NewReader(fetched.body), int64(len(fetched.body)))
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+
+	"github.com/funnyzak/reqtap/internal/cluster"
+)
+
+// Stage names of the cluster extension.
+const (
+	StageClusterLabel   = "cluster_label"
+	StageClusterPublish = "cluster_publish"
+)
+
+// installClusterStages labels captured requests with the instance ID and shares them with peers once stored.
+func (h *Handler) installClusterStages(gossip *cluster.Gossip) error {
+	if gossip == nil {
+		return nil
+	}
+	err := h.pipeline.InsertAfter(StageCapture, Stage{Name: StageClusterLabel, Phase: PhaseSync, Run: func(_ context.Context, ex *Exchange) error {
+		ex.Record.Instance = gossip.Instance()
+		return nil
+	}})
+	if err != nil {
+		return err
+	}
+	return h.pipeline.InsertAfter(StageStore, Stage{Name: StageClusterPublish, Phase: PhaseAsync, Run: func(_ context.Context, ex *Exchange) error {
+		gossip.Publish(ex.Record)
+		return nil
+	}})
+}
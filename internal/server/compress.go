@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressBody gzips a mock body when the rule asks for it and the client accepts gzip.
+// Vary is set whenever compression is configured because the answer depends on Accept-Encoding.
+func compressBody(w http.ResponseWriter, r *http.Request, rule *ImmediateResponseRule, body []byte) []byte {
+	if rule == nil || rule.Compression == "" {
+		return body
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if len(body) == 0 || len(body) < rule.CompressionMinBytes || r.Method == http.MethodHead {
+		return body
+	}
+	if w.Header().Get("Content-Encoding") != "" || !acceptsGzip(r.Header.Values("Accept-Encoding")) {
+		return body
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return body
+	}
+	if err := zw.Close(); err != nil {
+		return body
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	return buf.Bytes()
+}
+
+// acceptsGzip reports whether the Accept-Encoding values allow gzip, honouring q=0 and the * wildcard.
+func acceptsGzip(values []string) bool {
+	wildcard := false
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "gzip" && coding != "x-gzip" && coding != "*" {
+				continue
+			}
+			accepted := true
+			for _, param := range strings.Split(params, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if ok && strings.EqualFold(key, "q") {
+					if q, err := strconv.ParseFloat(val, 64); err == nil && q <= 0 {
+						accepted = false
+					}
+				}
+			}
+			if coding == "*" {
+				wildcard = accepted
+				continue
+			}
+			return accepted
+		}
+	}
+	return wildcard
+}
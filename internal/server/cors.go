@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// CORSOptions are the server.cors settings applied to capture requests sent by browsers
+type CORSOptions struct {
+	Enable           bool
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func buildCORSOptions(cfg config.CORSConfig) CORSOptions {
+	return CORSOptions{
+		Enable:           cfg.Enable,
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or "" when it is not allowed.
+// Credentialed responses cannot use "*", so the origin is echoed instead.
+func (o CORSOptions) allowOrigin(origin string) string {
+	normalized := strings.ToLower(strings.TrimRight(origin, "/"))
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" {
+			if o.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+		if allowed == normalized {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (o CORSOptions) allowsMethod(method string) bool {
+	if len(o.AllowedMethods) == 0 {
+		return true
+	}
+	for _, allowed := range o.AllowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// corsStage answers preflight requests on the capture path without recording them and adds the
+// CORS headers to every other response sent to an allowed origin. Headers set by a response rule
+// are applied later and win over the ones set here.
+func (h *Handler) corsStage(_ context.Context, ex *Exchange) error {
+	opts := h.currentConfig().CORS
+	origin := ex.Request.Header.Get("Origin")
+	if !opts.Enable || origin == "" {
+		return nil
+	}
+	header := ex.Writer.Header()
+	header.Add("Vary", "Origin")
+	allowOrigin := opts.allowOrigin(origin)
+
+	requestMethod := ex.Request.Header.Get("Access-Control-Request-Method")
+	if ex.Request.Method != http.MethodOptions || requestMethod == "" {
+		if allowOrigin != "" {
+			setCORSOriginHeaders(header, allowOrigin, opts.AllowCredentials)
+		}
+		return nil
+	}
+
+	// Preflights outside of the capture path fall through to the usual 404
+	if !h.shouldHandlePath(ex.Request.URL.Path) {
+		return nil
+	}
+	if allowOrigin == "" || !opts.allowsMethod(strings.ToUpper(requestMethod)) {
+		h.logger.Debug("CORS preflight rejected",
+			"path", ex.Request.URL.Path,
+			"origin", origin,
+			"method", requestMethod,
+		)
+		h.writeError(ex.Writer, http.StatusForbidden)
+		return ErrStopPipeline
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	setCORSOriginHeaders(header, allowOrigin, opts.AllowCredentials)
+	if len(opts.AllowedMethods) > 0 {
+		header.Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
+	} else {
+		header.Set("Access-Control-Allow-Methods", requestMethod)
+	}
+	if len(opts.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+	} else if requested := ex.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if opts.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge/time.Second)))
+	}
+	h.setServerHeader(header)
+	ex.Writer.WriteHeader(http.StatusNoContent)
+	return ErrStopPipeline
+}
+
+func setCORSOriginHeaders(header http.Header, origin string, credentials bool) {
+	header.Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/dedup"
+)
+
+// StageDedup fingerprints requests and marks re-deliveries as duplicates.
+const StageDedup = "dedup"
+
+// installDedupStage checks requests on the request goroutine, right after they were accepted, so
+// that the first of two concurrent copies is the one that arrived first. A first copy that is
+// never stored, e.g. answered while paused or dropped by a hook, script or transform, is
+// forgotten again once its pipeline ends.
+func (h *Handler) installDedupStage(cfg config.DedupConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	detector := dedup.New(dedup.Options{Header: cfg.Header, Window: cfg.Window})
+	return h.pipeline.InsertAfter(StageCapture, Stage{Name: StageDedup, Phase: PhaseSync, Run: func(_ context.Context, ex *Exchange) error {
+		if ex.Rejected {
+			return nil
+		}
+		duplicate, err := detector.Check(ex.Record)
+		if err != nil {
+			h.logger.Warn("Failed to fingerprint request", "request_id", ex.Record.ID, "error", err)
+			return nil
+		}
+		if !duplicate {
+			fingerprint, id := ex.Record.Fingerprint, ex.Record.ID
+			ex.OnDone(func() {
+				if ex.Stored == nil {
+					detector.Forget(fingerprint, id)
+				}
+			})
+			return nil
+		}
+		ex.SkipForward = cfg.SuppressForward
+		h.logger.Info("Duplicate request",
+			"request_id", ex.Record.ID,
+			"duplicate_of", ex.Record.DuplicateOf,
+			"fingerprint", ex.Record.Fingerprint,
+			"forward_suppressed", cfg.SuppressForward,
+		)
+		return nil
+	}})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/funnyzak/reqtap/internal/grpccapture"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// grpcRule is the mock rule name recorded for captured gRPC calls.
+const grpcRule = "grpc"
+
+// SetGRPCCapture enables gRPC capture; nil turns it off.
+func (h *Handler) SetGRPCCapture(c *grpccapture.Capturer) {
+	h.mu.Lock()
+	h.grpc = c
+	h.mu.Unlock()
+}
+
+// grpcCapturer returns the capturer when the request is a gRPC call that should be captured.
+func (h *Handler) grpcCapturer(r *http.Request) *grpccapture.Capturer {
+	h.mu.RLock()
+	c := h.grpc
+	h.mu.RUnlock()
+	if c == nil || !grpccapture.IsGRPC(r) {
+		return nil
+	}
+	return c
+}
+
+// serveGRPC answers a gRPC call and records its decoded messages; calls are relayed to the
+// gRPC backend instead of the HTTP forward targets.
+func (h *Handler) serveGRPC(c *grpccapture.Capturer, ex *Exchange) error {
+	call, err := c.Serve(ex.Writer, ex.Request, ex.Body)
+	if err != nil {
+		h.logger.Warn("gRPC capture incomplete", "error", err, "request_id", ex.Record.ID, "path", ex.Request.URL.Path)
+	}
+	ex.Record.GRPC = call
+	ex.Record.MockResponse = request.MockResponse{Rule: grpcRule, Status: http.StatusOK}
+	return nil
+}
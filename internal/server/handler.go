@@ -5,21 +5,31 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/netip"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"golang.org/x/sync/errgroup"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/grpccapture"
 	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
 	"github.com/funnyzak/reqtap/internal/printer"
 	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/internal/telemetry"
+	"github.com/funnyzak/reqtap/internal/tunnel"
 	"github.com/funnyzak/reqtap/pkg/request"
 )
 
 // Handler HTTP request handler
 type Handler struct {
+	mu        sync.RWMutex
 	printer   printer.Printer
 	forwarder forwarder.Client
 	logger    logger.Logger
@@ -28,16 +38,60 @@ type Handler struct {
 	web       RequestRecorder
 	baseCtx   context.Context
 	procWG    *sync.WaitGroup
+	pipeline  *Pipeline
+	grpc      *grpccapture.Capturer
+	tunnel    *tunnel.Hub
+	// sequenceCalls counts the calls answered by sequenced rules of the current config
+	seqMu         sync.Mutex
+	sequenceCalls map[*ImmediateResponseRule]int64
+	// accessDenied and accessNotAllowed count requests rejected by access control
+	accessDenied     atomic.Int64
+	accessNotAllowed atomic.Int64
+	// paused stops recording after the mock response; pausedSkipped counts the requests since
+	pauseMu       sync.Mutex
+	paused        atomic.Bool
+	pausedAt      time.Time
+	pausedSkipped atomic.Int64
+	// hooks are the hooks registered through AddHook
+	hooksMu sync.RWMutex
+	hooks   map[HookPoint][]namedHook
+
+	// order lines up the forwards of requests sharing a key, see forward.ordering
+	order *forwardOrder
+	// bodyURLs caches the bodies of body_url rules
+	bodyURLs *bodyURLCache
+	// stats feeds the console status line
+	stats *liveStats
+	// timelines records the processing steps of recent requests
+	timelines *timelineRecorder
 }
 
 // ServerConfig server configuration
 type ServerConfig struct {
-	Port         int
-	Path         string
-	MaxBodyBytes int64
-	ForwardURLs  []string
-	ForwardOpts  ForwardOptions
-	Responses    []ImmediateResponseRule
+	Port           int
+	Path           string
+	MaxBodyBytes   int64
+	Spill          SpillOptions
+	ForwardTargets []forwarder.Target
+	ForwardFilters []forwarder.Filter
+	// ForwardTransforms rewrite requests per target right before delivery
+	ForwardTransforms []forwarder.Transform
+	ForwardOpts       ForwardOptions
+	Responses         []ImmediateResponseRule
+	WebSocket         WebSocketOptions
+	Identity          IdentityOptions
+	ForwardQueue      ForwardQueueOptions
+	Auth              CaptureAuthOptions
+	AccessControl     AccessControlOptions
+	// TrustedProxies are the peers whose forwarding headers name the client
+	TrustedProxies []netip.Prefix
+	CORS           CORSOptions
+	// Routes replace Path when server.paths is configured
+	Routes []CaptureRoute
+	// Hooks are the built-in hooks of the hooks section
+	Hooks []HookRule
+	// Redact masks privacy.redact values before requests are stored
+	Redact RedactOptions
 }
 
 // ForwardOptions forwarding options
@@ -56,6 +110,32 @@ type ImmediateResponseRule struct {
 	Status     int
 	Body       string
 	Headers    map[string]string
+	// StatusText and HTTP10 need a hand-written status line, see writeRawResponse
+	StatusText string
+	HTTP10     bool
+	// Compression is "gzip" or empty; bodies below CompressionMinBytes stay uncompressed
+	Compression         string
+	CompressionMinBytes int
+	// BodyFile replaces Body with a file served through serveBodyFile
+	BodyFile string
+	// BodyURL replaces Body with the body fetched from a URL, see serveBodyURL
+	BodyURL    string
+	BodyURLTTL time.Duration
+	// Delay, DelayJitter and TimeoutChance inject latency, see injectLatency
+	Delay         time.Duration
+	DelayJitter   time.Duration
+	TimeoutChance float64
+	// Sequence answers successive calls step by step, see advanceSequence
+	Sequence     []ResponseStep
+	SequenceLoop bool
+	// HeaderMatch, QueryMatch and BodyMatch must all hold for the rule to answer, see matchesConditions
+	HeaderMatch []MatchCondition
+	QueryMatch  []MatchCondition
+	BodyMatch   []MatchCondition
+
+	// Compiled placeholders of Body and Headers; nil entries are served verbatim
+	bodyTemplate    *mocktemplate.Template
+	headerTemplates map[string]*mocktemplate.Template
 }
 
 // RequestRecorder 抽象存储接口，方便替换为不同的存储实现或测试桩。
@@ -64,8 +144,16 @@ type RequestRecorder interface {
 	Close()
 }
 
+// ForwardNotifier receives forward outcomes, e.g. to surface delivery failures to live consoles.
+type ForwardNotifier interface {
+	NotifyForward(requestID string, results []forwarder.Result)
+}
+
 var errRequestBodyTooLarge = errors.New("request body exceeds configured limit")
 
+// bodyTooLargeRule labels requests rejected with 413 in place of a mock rule name
+const bodyTooLargeRule = "body_too_large"
+
 // NewHandler creates a new request handler
 func NewHandler(
 	printer printer.Printer,
@@ -77,7 +165,7 @@ func NewHandler(
 	baseCtx context.Context,
 	procWG *sync.WaitGroup,
 ) *Handler {
-	return &Handler{
+	h := &Handler{
 		printer:   printer,
 		forwarder: forwarder,
 		logger:    logger,
@@ -86,54 +174,494 @@ func NewHandler(
 		web:       webService,
 		baseCtx:   baseCtx,
 		procWG:    procWG,
+		bodyURLs:  newBodyURLCache(),
+		stats:     newLiveStats(),
+		timelines: newTimelineRecorder(),
+	}
+	h.pipeline = h.defaultPipeline()
+	for _, ext := range registeredExtensions() {
+		if err := ext(h.pipeline); err != nil {
+			logger.Error("Failed to apply pipeline extension", "error", err)
+		}
+	}
+	return h
+}
+
+// UpdateConfig swaps the runtime configuration; in-flight requests keep the previous one.
+func (h *Handler) UpdateConfig(cfg *ServerConfig) {
+	if cfg == nil {
+		return
 	}
+	h.mu.Lock()
+	h.config = cfg
+	h.mu.Unlock()
+	// Rules were rebuilt, so sequences start over and body URLs are fetched again
+	h.seqMu.Lock()
+	h.sequenceCalls = nil
+	h.seqMu.Unlock()
+	h.bodyURLs.reset()
+}
+
+// SetPrinter swaps the printer; nil disables console output.
+func (h *Handler) SetPrinter(p printer.Printer) {
+	h.mu.Lock()
+	h.printer = p
+	h.mu.Unlock()
+}
+
+func (h *Handler) currentConfig() *ServerConfig {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.config
+}
+
+func (h *Handler) currentPrinter() printer.Printer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.printer
+}
+
+// Pipeline exposes the processing chain so callers can insert custom stages.
+func (h *Handler) Pipeline() *Pipeline {
+	return h.pipeline
+}
+
+// defaultPipeline builds access → cors → auth → verify → capture → hook-on-receive → scrub → respond → pause →
+// hook-before-store → store → broadcast → print → hook-before-forward → forward → hook-after-forward → report.
+// Verify runs before capture so requests outside the capture path are refused without reading their
+// body; print runs next to the following stages, see printStage.
+func (h *Handler) defaultPipeline() *Pipeline {
+	return NewPipeline(
+		Stage{Name: StageAccess, Phase: PhaseSync, Run: h.accessStage},
+		Stage{Name: StageCORS, Phase: PhaseSync, Run: h.corsStage},
+		Stage{Name: StageAuth, Phase: PhaseSync, Run: h.authStage},
+		Stage{Name: StageVerify, Phase: PhaseSync, Run: h.verifyStage},
+		Stage{Name: StageCapture, Phase: PhaseSync, Run: h.captureStage},
+		Stage{Name: StageHookOnReceive, Phase: PhaseSync, Run: h.hookStage(HookOnReceive)},
+		Stage{Name: StageScrub, Phase: PhaseSync, Run: h.scrubStage},
+		Stage{Name: StageRespond, Phase: PhaseSync, Run: h.respondStage},
+		Stage{Name: StagePause, Phase: PhaseSync, Run: h.pauseStage},
+		Stage{Name: StageHookBeforeStore, Phase: PhaseAsync, Run: h.hookStage(HookBeforeStore)},
+		Stage{Name: StageStore, Phase: PhaseAsync, Run: h.storeStage},
+		Stage{Name: StageBroadcast, Phase: PhaseAsync, Run: h.broadcastStage},
+		Stage{Name: StagePrint, Phase: PhaseAsync, Run: h.printStage},
+		Stage{Name: StageHookBeforeForward, Phase: PhaseAsync, Run: h.hookStage(HookBeforeForward)},
+		Stage{Name: StageForward, Phase: PhaseAsync, Run: h.forwardStage},
+		Stage{Name: StageHookAfterForward, Phase: PhaseAsync, Run: h.hookStage(HookAfterForward)},
+		Stage{Name: StageReport, Phase: PhaseAsync, Run: h.reportStage},
+	)
 }
 
 // ServeHTTP implements the http.Handler interface
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Read request body before sending response
-	bodyBytes, err := h.readRequestBody(r)
-	if err != nil {
-		h.handleBodyReadError(w, err)
+	ex := &Exchange{Writer: w, Request: r}
+	// The receive span covers the response; store and forward spans hang off it in the background
+	ctx, span := telemetry.Tracer().Start(telemetry.Extract(r.Context(), r.Header), "reqtap.receive",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		),
+	)
+	stopped := h.pipeline.run(ctx, PhaseSync, ex, h.stageError(ex))
+	if ex.Record != nil {
+		span.SetAttributes(attribute.String("reqtap.request_id", ex.Record.ID))
+		if ex.Record.MockResponse.Status > 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", ex.Record.MockResponse.Status))
+		}
+	}
+	span.End()
+	if stopped || ex.Dropped {
+		ex.finish()
 		return
 	}
 
-	// Send immediate response to client
-	responseRule := h.sendImmediateResponse(w, r)
-
 	// Process request asynchronously with already read body
 	h.procWG.Add(1)
 	go func() {
 		defer h.procWG.Done()
-		ctx, cancel := context.WithCancel(h.baseCtx)
+		defer ex.finish()
+		ctx, cancel := context.WithCancel(trace.ContextWithSpan(h.baseCtx, span))
 		defer cancel()
-		h.processRequest(ctx, r, bodyBytes, responseRule)
+		h.pipeline.run(ctx, PhaseAsync, ex, h.stageError(ex))
+	}()
+}
+
+func (h *Handler) stageError(ex *Exchange) func(string, error) {
+	return func(stage string, err error) {
+		requestID := ""
+		if ex.Record != nil {
+			requestID = ex.Record.ID
+		}
+		h.logger.Error("Pipeline stage failed", "stage", stage, "error", err, "request_id", requestID)
+	}
+}
+
+// captureStage reads the body before the response is sent and builds the request record; an
+// oversized body is not kept, but the request's metadata is still recorded and answered with 413
+func (h *Handler) captureStage(_ context.Context, ex *Exchange) error {
+	body, err := h.readRequestBody(ex.Writer, ex.Request)
+	if errors.Is(err, errRequestBodyTooLarge) {
+		ex.Rejected = true
+		ex.Record = h.newRecord(ex.Request, nil)
+		ex.Record.Credential = ex.Credential
+		h.logger.Warn("Request body exceeds configured limit",
+			"request_id", ex.Record.ID,
+			"limit_bytes", h.currentConfig().MaxBodyBytes,
+			"content_length", ex.Request.ContentLength,
+			"remote_addr", ex.Record.RemoteAddr,
+		)
+		return nil
+	}
+	if err != nil {
+		h.logger.Error("Failed to read request body", "error", err)
+		h.writeError(ex.Writer, http.StatusInternalServerError)
+		return ErrStopPipeline
+	}
+	ex.Body = body.Data
+	ex.Record = h.newRecord(ex.Request, body.Data)
+	ex.Record.Credential = ex.Credential
+	if body.File != "" {
+		ex.Record.BodyFile = body.File
+		ex.Record.Size = body.Size
+		h.logger.Debug("Request body spilled to disk",
+			"request_id", ex.Record.ID,
+			"size", body.Size,
+			"file", body.File,
+		)
+		// A request dropped, paused or not stored leaves nobody to delete the file
+		ex.OnDone(func() {
+			if ex.storedBodyFile != body.File {
+				os.Remove(body.File)
+			}
+		})
+	}
+	if err := ex.Record.DecodeContentEncoding(h.currentConfig().MaxBodyBytes); err != nil {
+		// The body is kept as received and still forwarded unchanged
+		h.logger.Warn("Failed to decode request body",
+			"request_id", ex.Record.ID,
+			"content_encoding", ex.Request.Header.Get("Content-Encoding"),
+			"error", err,
+		)
+	}
+	return nil
+}
+
+// newRecord builds the request record with the client address resolved through server.trusted_proxies
+func (h *Handler) newRecord(r *http.Request, body []byte) *request.RequestData {
+	record := request.NewRequestData(r, body)
+	record.RemoteAddr = request.ClientIP(r, h.currentConfig().TrustedProxies)
+	return record
+}
+
+// verifyStage rejects requests outside of the configured capture path; gRPC method paths are
+// fixed by the protocol, so captured gRPC calls are accepted anywhere
+func (h *Handler) verifyStage(_ context.Context, ex *Exchange) error {
+	if !h.shouldHandlePath(ex.Request.URL.Path) && h.grpcCapturer(ex.Request) == nil {
+		h.writeError(ex.Writer, http.StatusNotFound)
+		return ErrStopPipeline
+	}
+	return nil
+}
+
+// scrubStage is the anchor for stages that sanitize the record before it is answered and persisted
+func (h *Handler) scrubStage(context.Context, *Exchange) error {
+	return nil
+}
+
+// respondStage sends the immediate response to the client, accepts a WebSocket upgrade or answers a gRPC call
+func (h *Handler) respondStage(_ context.Context, ex *Exchange) error {
+	if ex.Responded {
+		return nil
+	}
+	if ex.Rejected {
+		// Closing the connection spares reading whatever the client is still sending
+		ex.Writer.Header().Set("Connection", "close")
+		h.writeError(ex.Writer, http.StatusRequestEntityTooLarge)
+		ex.Record.MockResponse = request.MockResponse{Rule: bodyTooLargeRule, Status: http.StatusRequestEntityTooLarge}
+		return nil
+	}
+	if h.acceptsWebSocket(ex.Request) {
+		return h.upgradeWebSocket(ex)
+	}
+	if c := h.grpcCapturer(ex.Request); c != nil {
+		return h.serveGRPC(c, ex)
+	}
+	if hub := h.tunnelHub(); hub != nil && h.relayTunnel(hub, ex) {
+		return nil
+	}
+	if rule := h.selectResponseRule(ex.Request, ex.Record); rule != nil && rule.injectsLatency() {
+		if !h.injectLatency(ex.Writer, ex.Request, rule) {
+			// Status 0 records that no response was sent
+			ex.Rule = rule
+			ex.Record.MockResponse = request.MockResponse{Rule: rule.Name}
+			return nil
+		}
+	}
+	ex.Rule = h.sendImmediateResponse(ex.Writer, ex.Request, ex.Record)
+	ex.Record.MockResponse = h.toMockResponseSummary(ex.Rule)
+	return nil
+}
+
+// storeStage persists the record
+func (h *Handler) storeStage(ctx context.Context, ex *Exchange) error {
+	record := ex.Record
+	h.timelines.received(record)
+	if h.store != nil {
+		_, span := telemetry.Tracer().Start(ctx, "reqtap.store",
+			trace.WithAttributes(attribute.String("reqtap.request_id", record.ID)))
+		started := time.Now()
+		stored, err := h.store.Record(record)
+		h.timelines.stored(record.ID, time.Since(started))
+		if err != nil {
+			h.logger.Error("Failed to persist request", "error", err, "request_id", record.ID)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			ex.storedBodyFile = record.BodyFile
+		}
+		span.End()
+		ex.Stored = stored
+	}
+	if ex.Stored == nil {
+		ex.Stored = &storage.StoredRequest{ID: record.ID, RequestData: record}
+	}
+	h.stats.observeCapture(time.Now())
+	if len(ex.Tags) > 0 {
+		// Tags added by hooks before the request was stored
+		h.tagExchange(ex, nil)
+	}
+
+	// Log request
+	h.logger.Info("Request received",
+		"request_id", record.ID,
+		"method", record.Method,
+		"path", record.Path,
+		"remote_addr", record.RemoteAddr,
+		"user_agent", record.UserAgent,
+		"content_length", record.ContentLength,
+		"content_type", record.ContentType,
+		"mock_rule", record.MockResponse.Rule,
+		"mock_status", record.MockResponse.Status,
+	)
+	return nil
+}
+
+// broadcastStage pushes the stored record to live consoles
+func (h *Handler) broadcastStage(_ context.Context, ex *Exchange) error {
+	if h.web == nil {
+		return nil
+	}
+	stored := ex.Stored
+	if stored == nil {
+		stored = &storage.StoredRequest{ID: ex.Record.ID, RequestData: ex.Record}
+	}
+	h.web.Record(stored)
+	return nil
+}
+
+// printStage renders the record to the configured printer while the later stages go on, so a slow
+// terminal does not hold forwarding back; the exchange finishes once it is printed. Outcome printers
+// wait for reportStage.
+func (h *Handler) printStage(_ context.Context, ex *Exchange) error {
+	p := h.currentPrinter()
+	if p == nil {
+		return nil
+	}
+	if _, ok := p.(printer.OutcomePrinter); ok {
+		return nil
+	}
+	// before_forward hooks may mask the record in place while it is printed, so the printer gets a
+	// copy with its own headers
+	record := *ex.Record
+	record.Headers = ex.Record.Headers.Clone()
+	if ex.Record.Protobuf != nil {
+		decoded := *ex.Record.Protobuf
+		record.Protobuf = &decoded
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		started := time.Now()
+		if err := p.PrintRequest(&record); err != nil {
+			h.logger.Error("Failed to print request", "error", err, "request_id", record.ID)
+		}
+		h.timelines.printed(record.ID, time.Since(started))
 	}()
+	ex.OnDone(func() { <-done })
+	return nil
+}
+
+// forwardStage delivers the record to the configured targets
+func (h *Handler) forwardStage(ctx context.Context, ex *Exchange) error {
+	if ex.Rejected || ex.SkipForward {
+		return nil
+	}
+	record := ex.Record
+	if ex.ForwardRecord != nil {
+		record = ex.ForwardRecord
+	}
+	results, err := h.forward(ctx, record, exchangeOrderTurn(ex))
+	if err != nil && !errors.Is(err, forwarder.ErrNoTargets) {
+		h.logger.Error("Failed to forward request", "error", err, "request_id", ex.Record.ID)
+	}
+	h.enqueueFailed(ex.Record.ID, results)
+	ex.Results = results
+	return nil
 }
 
-// sendImmediateResponse sends immediate response
-func (h *Handler) sendImmediateResponse(w http.ResponseWriter, r *http.Request) *ImmediateResponseRule {
-	responseRule := h.selectResponseRule(r)
+// Reforward sends a stored request through the configured targets again, applying the same
+// filters, path strategy and header rules as live traffic. Outcomes are persisted and broadcast
+// like those of the original delivery.
+func (h *Handler) Reforward(ctx context.Context, record *request.RequestData) ([]forwarder.Result, error) {
+	results, err := h.forward(ctx, record, nil)
+	if err == nil {
+		h.logger.Info("Request re-forwarded", "request_id", record.ID, "targets", len(results))
+	}
+	return results, err
+}
+
+// forward selects the targets for record and delivers it, after the requests ahead of it in turn's
+// line when turn is set; ErrNoTargets means nothing was sent.
+func (h *Handler) forward(ctx context.Context, record *request.RequestData, turn *orderTurn) ([]forwarder.Result, error) {
+	cfg := h.currentConfig()
+	candidates := cfg.forwardTargetsFor(record.Path)
+	if len(candidates) == 0 || h.forwarder == nil || record.GRPC != nil {
+		return nil, forwarder.ErrNoTargets
+	}
+	targets, skipped := forwarder.SelectTargets(cfg.ForwardFilters, record, candidates)
+	if len(skipped) > 0 {
+		h.logger.Debug("Forward targets skipped by filters",
+			"request_id", record.ID,
+			"method", record.Method,
+			"path", record.Path,
+			"targets", skipped,
+		)
+	}
+	targets, unsampled := forwarder.SampleTargets(record.ID, targets)
+	if len(unsampled) > 0 {
+		h.logger.Debug("Forward targets skipped by sampling",
+			"request_id", record.ID,
+			"targets", unsampled,
+		)
+	}
+	if len(targets) == 0 {
+		return nil, forwarder.ErrNoTargets
+	}
+	if turn != nil {
+		return h.deliverOrdered(ctx, record, targets, turn)
+	}
+	return h.deliver(ctx, record, targets)
+}
+
+// deliver sends record to targets, then persists and broadcasts the outcomes
+func (h *Handler) deliver(ctx context.Context, record *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	cfg := h.currentConfig()
+	fctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ForwardOpts.Timeout)*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	results, err := h.forwarder.Forward(fctx, record, forwarder.AttachTransforms(cfg.ForwardTransforms, targets))
+	h.timelines.forwarded(record.ID, started, results)
+	h.persistForwards(record.ID, results)
+	h.notifyForward(record.ID, results)
+	return results, err
+}
+
+// reportStage emits one consolidated record per request, including forward outcomes, to printers that support it
+func (h *Handler) reportStage(_ context.Context, ex *Exchange) error {
+	p, ok := h.currentPrinter().(printer.OutcomePrinter)
+	if !ok {
+		return nil
+	}
+	outcome := printer.Outcome{}
+	if ex.Stored != nil {
+		outcome.StorageID = ex.Stored.ID
+	}
+	for _, res := range ex.Results {
+		outcome.Forwards = append(outcome.Forwards, printer.ForwardOutcome{
+			URL:       res.URL,
+			Status:    res.StatusCode,
+			Success:   res.Success,
+			Attempts:  res.Attempts,
+			LatencyMs: res.Duration.Milliseconds(),
+			Error:     res.Error,
+		})
+	}
+	started := time.Now()
+	if err := p.PrintOutcome(ex.Record, outcome); err != nil {
+		h.logger.Error("Failed to print request", "error", err, "request_id", ex.Record.ID)
+	}
+	h.timelines.printed(ex.Record.ID, time.Since(started))
+	return nil
+}
+
+// persistForwards stores what each target answered so it can be inspected later
+func (h *Handler) persistForwards(requestID string, results []forwarder.Result) {
+	if h.store == nil || len(results) == 0 {
+		return
+	}
+	records := make([]*storage.ForwardRecord, 0, len(results))
+	for _, res := range results {
+		records = append(records, &storage.ForwardRecord{
+			TargetURL:       res.URL,
+			Timestamp:       time.Now().UTC(),
+			StatusCode:      res.StatusCode,
+			Headers:         res.Headers,
+			Body:            res.Body,
+			BodyTruncated:   res.BodyTruncated,
+			LatencyMs:       res.Duration.Milliseconds(),
+			Attempts:        res.Attempts,
+			Success:         res.Success,
+			Error:           res.Error,
+			Violations:      res.Violations,
+			LatencyBudgetMs: res.LatencyBudget.Milliseconds(),
+			OverBudget:      res.OverBudget,
+		})
+	}
+	if err := h.store.RecordForwards(requestID, records); err != nil {
+		h.logger.Error("Failed to persist forward responses", "error", err, "request_id", requestID)
+	}
+}
+
+// sendImmediateResponse sends immediate response; record feeds template placeholders and may be nil
+func (h *Handler) sendImmediateResponse(w http.ResponseWriter, r *http.Request, record *request.RequestData) *ImmediateResponseRule {
+	responseRule := h.advanceSequence(h.selectResponseRule(r, record))
 	statusCode := http.StatusOK
 	body := []byte("ok")
 	defaultContentType := "text/plain"
 
 	if responseRule != nil {
 		statusCode = responseRule.Status
-		body = []byte(responseRule.Body)
+		var data *mocktemplate.Data
+		if responseRule.bodyTemplate != nil || len(responseRule.headerTemplates) > 0 {
+			data = templateData(r, record)
+		}
+		body = []byte(h.renderTemplate(responseRule, "body", responseRule.bodyTemplate, responseRule.Body, data))
 		hasContentType := false
 		for key, value := range responseRule.Headers {
 			if key == "" {
 				continue
 			}
+			value = h.renderTemplate(responseRule, key, responseRule.headerTemplates[key], value, data)
 			w.Header().Set(key, value)
 			if strings.EqualFold(key, "Content-Type") {
 				hasContentType = true
 			}
 		}
+		if responseRule.BodyFile != "" {
+			h.setServerHeader(w.Header())
+			h.serveBodyFile(w, r, responseRule)
+			return responseRule
+		}
+		if responseRule.BodyURL != "" {
+			h.setServerHeader(w.Header())
+			h.serveBodyURL(w, r, responseRule)
+			return responseRule
+		}
 		if !hasContentType {
 			w.Header().Set("Content-Type", defaultContentType)
 		}
+		body = compressBody(w, r, responseRule, body)
 		h.logger.Debug("Immediate mock response applied",
 			"rule", responseRule.Name,
 			"status", responseRule.Status,
@@ -144,7 +672,14 @@ func (h *Handler) sendImmediateResponse(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", defaultContentType)
 	}
 
-	w.Header().Set("Server", "ReqTap/1.0")
+	h.setServerHeader(w.Header())
+	if responseRule != nil && (responseRule.StatusText != "" || responseRule.HTTP10) {
+		err := writeRawResponse(w, r, responseRule, statusCode, body)
+		if err == nil {
+			return responseRule
+		}
+		h.logger.Warn("Falling back to a standard response", "rule", responseRule.Name, "error", err)
+	}
 	w.WriteHeader(statusCode)
 	if len(body) > 0 {
 		w.Write(body)
@@ -157,16 +692,43 @@ func (h *Handler) sendImmediateResponse(w http.ResponseWriter, r *http.Request)
 	return responseRule
 }
 
-func (h *Handler) selectResponseRule(r *http.Request) *ImmediateResponseRule {
-	if len(h.config.Responses) == 0 {
-		return nil
+// renderTemplate fills in placeholders, falling back to the literal text when rendering fails
+func (h *Handler) renderTemplate(rule *ImmediateResponseRule, field string, tmpl *mocktemplate.Template, literal string, data *mocktemplate.Data) string {
+	if tmpl == nil {
+		return literal
 	}
+	rendered, err := tmpl.Render(data)
+	if err != nil {
+		h.logger.Warn("Failed to render mock response template", "rule", rule.Name, "field", field, "error", err)
+		return literal
+	}
+	return rendered
+}
+
+func templateData(r *http.Request, record *request.RequestData) *mocktemplate.Data {
+	if record == nil {
+		return mocktemplate.NewData("", r.Method, r.URL.Path, r.URL.RawQuery, r.RemoteAddr, r.Header, nil)
+	}
+	return mocktemplate.NewData(record.ID, record.Method, record.Path, record.Query, record.RemoteAddr, record.Headers, record.Body)
+}
 
+// selectResponseRule returns the first rule matching the request; record supplies the body for
+// body_match conditions and may be nil
+func (h *Handler) selectResponseRule(r *http.Request, record *request.RequestData) *ImmediateResponseRule {
 	path := r.URL.Path
+	rules := h.currentConfig().responsesFor(path)
+	if len(rules) == 0 {
+		return nil
+	}
+
 	method := strings.ToUpper(r.Method)
+	in := &matchInput{r: r}
+	if record != nil {
+		in.body = record.Body
+	}
 
-	for i := range h.config.Responses {
-		rule := &h.config.Responses[i]
+	for i := range rules {
+		rule := &rules[i]
 		if len(rule.Methods) > 0 {
 			matched := false
 			for _, allowed := range rule.Methods {
@@ -188,75 +750,33 @@ func (h *Handler) selectResponseRule(r *http.Request) *ImmediateResponseRule {
 			continue
 		}
 
+		if !rule.matchesConditions(in) {
+			continue
+		}
+
 		return rule
 	}
 
 	return nil
 }
 
-// processRequest processes request asynchronously
-func (h *Handler) processRequest(ctx context.Context, r *http.Request, bodyBytes []byte, responseRule *ImmediateResponseRule) {
-	// Create request record
-	record := request.NewRequestData(r, bodyBytes)
-	record.MockResponse = h.toMockResponseSummary(responseRule)
-
-	var stored *storage.StoredRequest
-	if h.store != nil {
-		var err error
-		stored, err = h.store.Record(record)
-		if err != nil {
-			h.logger.Error("Failed to persist request", "error", err, "request_id", record.ID)
-		}
-	}
-	if stored == nil {
-		stored = &storage.StoredRequest{ID: record.ID, RequestData: record}
-	}
-
-	if h.web != nil {
-		h.web.Record(stored)
-	}
-
-	// Log request
-	h.logger.Info("Request received",
-		"request_id", record.ID,
-		"method", record.Method,
-		"path", record.Path,
-		"remote_addr", record.RemoteAddr,
-		"user_agent", record.UserAgent,
-		"content_length", record.ContentLength,
-		"content_type", record.ContentType,
-		"mock_rule", record.MockResponse.Rule,
-		"mock_status", record.MockResponse.Status,
-	)
-
-	group, groupCtx := errgroup.WithContext(ctx)
-
-	// Print to console
-	if h.printer != nil {
-		group.Go(func() error {
-			if err := h.printer.PrintRequest(record); err != nil {
-				h.logger.Error("Failed to print request", "error", err, "request_id", record.ID)
-			}
-			return nil
-		})
+func (h *Handler) notifyForward(requestID string, results []forwarder.Result) {
+	if len(results) == 0 {
+		return
 	}
-
-	// Forward request
-	if len(h.config.ForwardURLs) > 0 {
-		group.Go(func() error {
-			fctx, cancel := context.WithTimeout(groupCtx,
-				time.Duration(h.config.ForwardOpts.Timeout)*time.Second)
-			defer cancel()
-
-			if err := h.forwarder.Forward(fctx, record, h.config.ForwardURLs); err != nil {
-				h.logger.Error("Failed to forward request", "error", err, "request_id", record.ID)
-			}
-			return nil
-		})
+	for _, res := range results {
+		if !res.Success {
+			h.stats.observeForwardError()
+			h.logger.Warn("Forward delivery failed",
+				"request_id", requestID,
+				"url", res.URL,
+				"status", res.StatusCode,
+				"violations", res.Violations,
+			)
+		}
 	}
-
-	if err := group.Wait(); err != nil {
-		h.logger.Warn("Request processing finished with errors", "error", err, "request_id", record.ID)
+	if notifier, ok := h.web.(ForwardNotifier); ok {
+		notifier.NotifyForward(requestID, results)
 	}
 }
 
@@ -271,42 +791,64 @@ func (h *Handler) toMockResponseSummary(rule *ImmediateResponseRule) request.Moc
 	}
 }
 
-func (h *Handler) readRequestBody(r *http.Request) ([]byte, error) {
+// readRequestBody enforces max_body_bytes and spills bodies above the spill threshold to disk
+func (h *Handler) readRequestBody(w http.ResponseWriter, r *http.Request) (capturedBody, error) {
 	defer r.Body.Close()
 
-	if h.config.MaxBodyBytes <= 0 {
-		return io.ReadAll(r.Body)
+	cfg := h.currentConfig()
+	limit := cfg.MaxBodyBytes
+	reader := io.Reader(r.Body)
+	if limit > 0 {
+		// A declared length over the limit is refused before a single byte is read
+		if r.ContentLength > limit {
+			return capturedBody{}, errRequestBodyTooLarge
+		}
+		// Chunked bodies are read up to the limit only; MaxBytesReader fails on the next byte
+		reader = http.MaxBytesReader(w, r.Body, limit)
 	}
 
-	limited := io.LimitReader(r.Body, h.config.MaxBodyBytes+1)
-	body, err := io.ReadAll(limited)
+	// gRPC calls are decoded from memory, so they are never spilled
+	threshold := cfg.Spill.Threshold
+	if threshold <= 0 || h.grpcCapturer(r) != nil {
+		threshold = 0
+	}
+	if threshold > 0 {
+		reader = io.LimitReader(reader, threshold+1)
+	}
+	body, err := io.ReadAll(reader)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return capturedBody{}, errRequestBodyTooLarge
+	}
 	if err != nil {
-		return nil, err
+		return capturedBody{}, err
 	}
-	if int64(len(body)) > h.config.MaxBodyBytes {
-		return nil, errRequestBodyTooLarge
+	if threshold == 0 || int64(len(body)) <= threshold {
+		return capturedBody{Data: body, Size: int64(len(body))}, nil
 	}
-	return body, nil
-}
 
-func (h *Handler) handleBodyReadError(w http.ResponseWriter, err error) {
-	switch {
-	case errors.Is(err, errRequestBodyTooLarge):
-		h.logger.Warn("Request body exceeds configured limit",
-			"limit_bytes", h.config.MaxBodyBytes,
-		)
-		http.Error(w, "Payload Too Large", http.StatusRequestEntityTooLarge)
-	default:
-		h.logger.Error("Failed to read request body", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// The body outgrew the threshold: stream it to disk and keep only a preview in memory
+	rest := io.Reader(r.Body)
+	if limit > 0 {
+		rest = http.MaxBytesReader(w, r.Body, limit-int64(len(body)))
 	}
+	file, size, err := spillBody(cfg.Spill.Dir, body, rest)
+	if err != nil {
+		return capturedBody{}, err
+	}
+	return capturedBody{Data: body[:threshold], File: file, Size: size}, nil
 }
 
 // shouldHandlePath checks if the path should be handled
 func (h *Handler) shouldHandlePath(path string) bool {
-	if h.config.Path == "/" {
+	cfg := h.currentConfig()
+	if len(cfg.Routes) > 0 {
+		return cfg.matchRoute(path) != nil
+	}
+	prefix := cfg.Path
+	if prefix == "/" {
 		return true
 	}
 
-	return strings.HasPrefix(path, h.config.Path)
+	return strings.HasPrefix(path, prefix)
 }
@@ -1,8 +1,29 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
 )
 
 func TestSelectResponseRule(t *testing.T) {
@@ -17,24 +38,66 @@ func TestSelectResponseRule(t *testing.T) {
 	}
 
 	req := httptest.NewRequest("GET", "http://localhost/foo", nil)
-	rule := h.selectResponseRule(req)
+	rule := h.selectResponseRule(req, nil)
 	if rule == nil || rule.Name != "exact" {
 		t.Fatalf("expected exact rule, got %#v", rule)
 	}
 
 	req = httptest.NewRequest("GET", "http://localhost/bar/baz", nil)
-	rule = h.selectResponseRule(req)
+	rule = h.selectResponseRule(req, nil)
 	if rule == nil || rule.Name != "prefix" {
 		t.Fatalf("expected prefix rule, got %#v", rule)
 	}
 
 	req = httptest.NewRequest("POST", "http://localhost/any", nil)
-	rule = h.selectResponseRule(req)
+	rule = h.selectResponseRule(req, nil)
 	if rule == nil || rule.Name != "method" {
 		t.Fatalf("expected method rule, got %#v", rule)
 	}
 }
 
+func TestSelectResponseRuleConditions(t *testing.T) {
+	h := &Handler{
+		config: &ServerConfig{
+			Responses: convertImmediateResponseConfigs([]config.ImmediateResponseConfig{
+				{Name: "ping", Path: "/hook", Status: 400, BodyMatch: []config.MatchConditionConfig{{JSONPath: "event", Equals: "ping"}}},
+				{Name: "github", Path: "/hook", Status: 202, HeaderMatch: []config.MatchConditionConfig{{Name: "X-GitHub-Event", Regex: "^(push|release)$"}}},
+				{Name: "debug", Path: "/hook", Status: 203, QueryMatch: []config.MatchConditionConfig{{Name: "debugMode"}}},
+				{Name: "xml", Path: "/hook", Status: 415, BodyMatch: []config.MatchConditionConfig{{Contains: "<?xml"}}},
+				{Name: "fallback", Path: "/hook", Status: 200},
+			}),
+		},
+	}
+
+	cases := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		body    string
+		want    string
+	}{
+		{"json field equals", "/hook", nil, `{"event":"ping","id":1}`, "ping"},
+		{"other json value", "/hook", nil, `{"event":"push"}`, "fallback"},
+		{"header regex", "/hook", map[string]string{"X-GitHub-Event": "release"}, `{}`, "github"},
+		{"header regex mismatch", "/hook", map[string]string{"X-GitHub-Event": "pull_request"}, `{}`, "fallback"},
+		{"query parameter present", "/hook?debugMode=", nil, "", "debug"},
+		{"query names are case sensitive", "/hook?debugmode=1", nil, "", "fallback"},
+		{"body substring", "/hook", nil, `<?xml version="1.0"?><event/>`, "xml"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://localhost"+tc.target, strings.NewReader(tc.body))
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rule := h.selectResponseRule(req, &request.RequestData{Body: []byte(tc.body)})
+			if rule == nil || rule.Name != tc.want {
+				t.Fatalf("expected rule %s, got %#v", tc.want, rule)
+			}
+		})
+	}
+}
+
 func TestSendImmediateResponse(t *testing.T) {
 	h := &Handler{
 		logger: noopLogger{},
@@ -55,7 +118,7 @@ func TestSendImmediateResponse(t *testing.T) {
 
 	req := httptest.NewRequest("GET", "http://localhost/json", nil)
 	rr := httptest.NewRecorder()
-	h.sendImmediateResponse(rr, req)
+	h.sendImmediateResponse(rr, req, nil)
 
 	if rr.Code != 202 {
 		t.Fatalf("expected status 202, got %d", rr.Code)
@@ -68,6 +131,59 @@ func TestSendImmediateResponse(t *testing.T) {
 	}
 }
 
+func TestSendImmediateResponseTemplate(t *testing.T) {
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Responses: convertImmediateResponseConfigs([]config.ImmediateResponseConfig{{
+				Name:     "echo",
+				Status:   201,
+				Template: true,
+				Body:     `{"method":"{{.Method}}","user":{{.JSONBody "user.id"}},"trace":"{{.Header "X-Trace"}}"}`,
+				Headers:  map[string]string{"content-type": "application/json", "x-request-id": "{{.ID}}"},
+			}}),
+		},
+	}
+
+	req := httptest.NewRequest("POST", "http://localhost/users", strings.NewReader(`{"user":{"id":7}}`))
+	req.Header.Set("X-Trace", "t-1")
+	record := request.NewRequestData(req, []byte(`{"user":{"id":7}}`))
+	rr := httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, record)
+
+	if body := rr.Body.String(); body != `{"method":"POST","user":7,"trace":"t-1"}` {
+		t.Fatalf("unexpected templated body %s", body)
+	}
+	if got := rr.Header().Get("X-Request-Id"); got != record.ID {
+		t.Fatalf("expected request id header %q, got %q", record.ID, got)
+	}
+}
+
+func TestSendImmediateResponseWithoutTemplateIsLiteral(t *testing.T) {
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Responses: convertImmediateResponseConfigs([]config.ImmediateResponseConfig{{
+				Name:    "docs",
+				Status:  200,
+				Body:    `Hello {{name}}, your order {{.Method}} shipped`,
+				Headers: map[string]string{"x-hint": "{{.ID}}"},
+			}}),
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost/docs", nil)
+	rr := httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, request.NewRequestData(req, nil))
+
+	if body := rr.Body.String(); body != `Hello {{name}}, your order {{.Method}} shipped` {
+		t.Fatalf("expected the body verbatim, got %s", body)
+	}
+	if got := rr.Header().Get("X-Hint"); got != "{{.ID}}" {
+		t.Fatalf("expected the header verbatim, got %q", got)
+	}
+}
+
 // noopLogger implements logger.Logger for tests
 type noopLogger struct{}
 
@@ -76,3 +192,671 @@ func (noopLogger) Info(string, ...interface{})  {}
 func (noopLogger) Warn(string, ...interface{})  {}
 func (noopLogger) Error(string, ...interface{}) {}
 func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestSendImmediateResponseRawStatusLine(t *testing.T) {
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Responses: []ImmediateResponseRule{{
+				Name:       "legacy",
+				Status:     200,
+				Body:       "ACK",
+				Headers:    map[string]string{},
+				StatusText: "Everything Fine",
+				HTTP10:     true,
+			}},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.sendImmediateResponse(w, r, nil)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /legacy HTTP/1.0\r\nHost: localhost\r\n\r\n")
+	raw, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	response := string(raw)
+	if !strings.HasPrefix(response, "HTTP/1.0 200 Everything Fine\r\n") {
+		t.Fatalf("unexpected status line: %q", response)
+	}
+	for _, want := range []string{"Connection: close\r\n", "Content-Length: 3\r\n", "\r\n\r\nACK"} {
+		if !strings.Contains(response, want) {
+			t.Fatalf("expected %q in response %q", want, response)
+		}
+	}
+	if strings.Contains(response, "Transfer-Encoding") {
+		t.Fatalf("HTTP/1.0 responses must not be chunked: %q", response)
+	}
+}
+
+func TestSendImmediateResponseGzip(t *testing.T) {
+	body := strings.Repeat(`{"item":"fixture"},`, 100)
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Responses: []ImmediateResponseRule{{
+				Name:                "big",
+				Status:              200,
+				Body:                body,
+				Headers:             map[string]string{"Content-Type": "application/json"},
+				Compression:         "gzip",
+				CompressionMinBytes: 1024,
+			}},
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost/big", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	rr := httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, nil)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" || rr.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected gzip with Vary, got %v", rr.Header())
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != body {
+		t.Fatalf("unexpected decompressed body %q", decoded)
+	}
+
+	req = httptest.NewRequest("GET", "http://localhost/big", nil)
+	rr = httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, nil)
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != body {
+		t.Fatalf("clients without gzip support must get the plain body, got %v", rr.Header())
+	}
+	if rr.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected Vary on uncompressed responses too, got %v", rr.Header())
+	}
+}
+
+func TestSendImmediateResponseBodyFileRangeAndETag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Responses: []ImmediateResponseRule{{Name: "download", Status: 200, BodyFile: path, Headers: map[string]string{}}},
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost/report.pdf", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rr := httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, nil)
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "2345" {
+		t.Fatalf("expected 206 with bytes 2-5, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Range") != "bytes 2-5/10" || rr.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("unexpected range headers %v", rr.Header())
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" || rr.Header().Get("Last-Modified") == "" {
+		t.Fatalf("expected ETag and Last-Modified, got %v", rr.Header())
+	}
+
+	req = httptest.NewRequest("GET", "http://localhost/report.pdf", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	h.sendImmediateResponse(rr, req, nil)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rr.Code)
+	}
+}
+
+func TestSendImmediateResponseBodyURLCached(t *testing.T) {
+	var fetches, revalidations atomic.Int32
+	var fail atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if fail.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[1,2,3]}`))
+	}))
+	defer upstream.Close()
+
+	h := &Handler{
+		logger:   noopLogger{},
+		bodyURLs: newBodyURLCache(),
+		config: &ServerConfig{
+			Responses: []ImmediateResponseRule{{Name: "fixture", Status: 200, BodyURL: upstream.URL + "/fixture.json", BodyURLTTL: time.Hour, Headers: map[string]string{}}},
+		},
+	}
+	send := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://localhost/items", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		h.sendImmediateResponse(rr, req, nil)
+		return rr
+	}
+
+	rr := send("", "")
+	if rr.Code != http.StatusOK || rr.Body.String() != `{"items":[1,2,3]}` || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected the fetched body, got %d %q %v", rr.Code, rr.Body.String(), rr.Header())
+	}
+	if rr = send("If-None-Match", `"v1"`); rr.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for the upstream ETag, got %d", rr.Code)
+	}
+	if rr = send("Range", "bytes=0-8"); rr.Code != http.StatusPartialContent || rr.Body.String() != `{"items":` {
+		t.Fatalf("expected a partial body, got %d %q", rr.Code, rr.Body.String())
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("expected the body to be fetched once, got %d fetches", n)
+	}
+
+	// Expired bodies are revalidated in the background, and kept when the upstream fails
+	h.config.Responses[0].BodyURLTTL = time.Nanosecond
+	if rr = send("", ""); rr.Code != http.StatusOK || rr.Body.Len() == 0 {
+		t.Fatalf("expected the expired body while it is revalidated, got %d", rr.Code)
+	}
+	waitForBodyURLRefresh(t, h.bodyURLs, h.config.Responses[0].BodyURL)
+	if n := revalidations.Load(); n != 1 {
+		t.Fatalf("expected one revalidation, got %d", n)
+	}
+	fail.Store(true)
+	for i := 0; i < 2; i++ {
+		if rr = send("", ""); rr.Code != http.StatusOK || rr.Body.String() != `{"items":[1,2,3]}` {
+			t.Fatalf("expected the stale body while the upstream fails, got %d", rr.Code)
+		}
+		waitForBodyURLRefresh(t, h.bodyURLs, h.config.Responses[0].BodyURL)
+	}
+
+	// Without a cached body a failing upstream is a bad gateway
+	h.UpdateConfig(h.config)
+	if rr = send("", ""); rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 without a cached body, got %d", rr.Code)
+	}
+}
+
+func TestSendImmediateResponseBodyURLServesStaleWhileUpstreamHangs(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+			w.Write([]byte("v2"))
+			return
+		}
+		w.Write([]byte("v1"))
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	h := &Handler{
+		logger:   noopLogger{},
+		bodyURLs: newBodyURLCache(),
+		config: &ServerConfig{
+			Responses: []ImmediateResponseRule{{Name: "fixture", Status: 200, BodyURL: upstream.URL + "/fixture", BodyURLTTL: 50 * time.Millisecond, Headers: map[string]string{}}},
+		},
+	}
+	send := func() string {
+		rr := httptest.NewRecorder()
+		h.sendImmediateResponse(rr, httptest.NewRequest("GET", "http://localhost/items", nil), nil)
+		return rr.Body.String()
+	}
+
+	if body := send(); body != "v1" {
+		t.Fatalf("expected the fetched body, got %q", body)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// The refetch hangs, but every request is answered with the expired body right away
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if body := send(); body != "v1" {
+			t.Fatalf("expected the stale body, got %q", body)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the stale body without waiting for the upstream, took %s", elapsed)
+	}
+	for deadline := time.Now().Add(2 * time.Second); fetches.Load() < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("expected a single background refetch, got %d fetches", n)
+	}
+
+	release <- struct{}{}
+	waitForBodyURLRefresh(t, h.bodyURLs, h.config.Responses[0].BodyURL)
+	if body := send(); body != "v2" {
+		t.Fatalf("expected the refreshed body, got %q", body)
+	}
+}
+
+// waitForBodyURLRefresh waits until the background refetch of rawURL is done
+func waitForBodyURLRefresh(t *testing.T, cache *bodyURLCache, rawURL string) {
+	t.Helper()
+	cache.mu.Lock()
+	entry := cache.entries[rawURL]
+	cache.mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		entry.mu.Lock()
+		refreshing := entry.refreshing
+		entry.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the body of %s was not refreshed", rawURL)
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"deflate, GZIP":     true,
+		"gzip;q=0":          false,
+		"*":                 true,
+		"*;q=0":             false,
+		"gzip;q=0, *":       false,
+		"br;q=1.0, *;q=0.1": true,
+		"identity, deflate": false,
+	}
+	for header, want := range cases {
+		if got := acceptsGzip([]string{header}); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestBuildIdentityOptions(t *testing.T) {
+	cases := []struct {
+		cfg  config.IdentityConfig
+		want string
+	}{
+		{config.IdentityConfig{}, "ReqTap/1.0"},
+		{config.IdentityConfig{ServerHeader: "edge"}, "edge"},
+		{config.IdentityConfig{Stealth: true, Profile: "apache"}, "Apache/2.4.58 (Ubuntu)"},
+		{config.IdentityConfig{Stealth: true, ServerHeader: "nginx"}, "nginx"},
+		{config.IdentityConfig{HideServerHeader: true, Stealth: true}, ""},
+	}
+	for _, tc := range cases {
+		if got := buildIdentityOptions(tc.cfg).ServerHeader; got != tc.want {
+			t.Errorf("buildIdentityOptions(%+v) = %q, want %q", tc.cfg, got, tc.want)
+		}
+	}
+}
+
+func TestStealthModeMimicsNginx(t *testing.T) {
+	h := &Handler{
+		logger: noopLogger{},
+		config: &ServerConfig{
+			Path:      "/reqtap",
+			Responses: []ImmediateResponseRule{{Name: "ack", Status: 200, Body: "ok", Headers: map[string]string{}}},
+			Identity:  buildIdentityOptions(config.IdentityConfig{Stealth: true, Profile: "nginx"}),
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	err := h.verifyStage(context.Background(), &Exchange{Writer: rr, Request: httptest.NewRequest("GET", "http://localhost/admin", nil)})
+	if err != ErrStopPipeline || rr.Code != http.StatusNotFound {
+		t.Fatalf("expected a 404 outside the capture path, got %d (%v)", rr.Code, err)
+	}
+	if rr.Header().Get("Server") != "nginx/1.24.0" || !strings.Contains(rr.Body.String(), "<center>nginx/1.24.0</center>") {
+		t.Fatalf("expected an nginx error page, got %v %q", rr.Header(), rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.sendImmediateResponse(rr, httptest.NewRequest("GET", "http://localhost/reqtap/x", nil), nil)
+	if rr.Header().Get("Server") != "nginx/1.24.0" {
+		t.Fatalf("expected the stealth banner on mock responses, got %v", rr.Header())
+	}
+}
+
+func TestRespondStageInjectsLatency(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path: "/",
+		Responses: []ImmediateResponseRule{
+			{Name: "slow", Path: "/slow", Status: http.StatusAccepted, Body: "late", Delay: 80 * time.Millisecond, DelayJitter: 20 * time.Millisecond},
+			{Name: "hang", Path: "/hang", Status: http.StatusOK, TimeoutChance: 1},
+		},
+	}
+	h := NewHandler(p, nil, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/slow")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected a delayed 202, got %d after %s", resp.StatusCode, elapsed)
+	}
+
+	client := &http.Client{Timeout: 200 * time.Millisecond}
+	if _, err := client.Get(srv.URL + "/hang"); err == nil {
+		t.Fatal("expected the client to time out waiting for a response")
+	}
+	h.procWG.Wait()
+	var env struct {
+		Rule   string `json:"rule"`
+		Status int    `json:"status"`
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &env); err != nil {
+		t.Fatalf("expected the hung request to be recorded: %v (%q)", err, out.String())
+	}
+	if env.Rule != "hang" || env.Status != 0 {
+		t.Fatalf("expected the dropped response to be recorded with status 0, got %+v", env)
+	}
+}
+
+func TestAuthStageRequiresCredentials(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := &ServerConfig{
+		Path:      "/",
+		Responses: []ImmediateResponseRule{{Name: "ack", Status: http.StatusOK, Body: "ok", Headers: map[string]string{}}},
+		Auth: buildCaptureAuthOptions(config.CaptureAuthConfig{Enable: true, Realm: "hooks", Credentials: []config.CaptureCredentialConfig{
+			{Name: "github", Token: "s3cret"},
+			{Name: "ci", Username: "ci", Password: "pw"},
+		}}),
+	}
+	h := NewHandler(nil, nil, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := func(setAuth func(*http.Request)) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/hook", strings.NewReader("{}"))
+		setAuth(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send(func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") })
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") != `Basic realm="hooks", charset="UTF-8"` {
+		t.Fatalf("expected a basic auth challenge, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := send(func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the bearer token to be accepted, got %d", resp.StatusCode)
+	}
+	if resp := send(func(r *http.Request) { r.SetBasicAuth("ci", "pw") }); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the basic credentials to be accepted, got %d", resp.StatusCode)
+	}
+	h.procWG.Wait()
+
+	items, total, err := store.List(storage.ListOptions{})
+	if err != nil || total != 2 {
+		t.Fatalf("expected only authenticated requests to be stored, got %d (%v)", total, err)
+	}
+	names := []string{items[0].Credential, items[1].Credential}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"ci", "github"}) {
+		t.Fatalf("expected the credential names to be recorded, got %v", names)
+	}
+	for _, item := range items {
+		if item.Headers.Get("Authorization") != "" {
+			t.Fatalf("expected the Authorization header to be dropped, got %v", item.Headers)
+		}
+	}
+}
+
+func TestAccessStageFiltersClientIP(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	access := config.AccessControlConfig{
+		Allow: []string{"203.0.113.0/24", "2001:db8::/32"},
+		Deny:  []string{"203.0.113.66"},
+	}
+	cfg := &ServerConfig{
+		Path:           "/",
+		Responses:      []ImmediateResponseRule{{Name: "ack", Status: http.StatusOK, Body: "ok", Headers: map[string]string{}}},
+		AccessControl:  buildAccessControlOptions(access),
+		TrustedProxies: parseAddressRanges([]string{"10.0.0.0/8"}),
+	}
+	h := NewHandler(nil, nil, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedCode int
+	}{
+		{"allowed peer", "203.0.113.7:5000", "", http.StatusOK},
+		{"allowed ipv6 peer", "[2001:db8::1]:5000", "", http.StatusOK},
+		{"denied peer", "203.0.113.66:5000", "", http.StatusForbidden},
+		{"peer outside allow list", "198.51.100.1:5000", "", http.StatusForbidden},
+		{"forwarded header from untrusted peer is ignored", "198.51.100.1:5000", "203.0.113.7", http.StatusForbidden},
+		{"client behind trusted proxies", "10.0.0.1:5000", "198.51.100.9, 203.0.113.7, 10.1.2.3", http.StatusOK},
+		{"spoofed leftmost hop is ignored", "10.0.0.1:5000", "203.0.113.7, 198.51.100.9", http.StatusForbidden},
+		{"trusted proxy without header", "10.0.0.1:5000", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("{}"))
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rec.Code)
+			}
+		})
+	}
+	h.procWG.Wait()
+
+	stats := h.AccessStats()
+	if stats.Denied != 1 || stats.NotAllowed != 4 || stats.Rejected != 5 {
+		t.Fatalf("unexpected rejection counters: %+v", stats)
+	}
+	items, total, err := store.List(storage.ListOptions{})
+	if err != nil || total != 3 {
+		t.Fatalf("expected only accepted requests to be stored, got %d (%v)", total, err)
+	}
+	// The request relayed by the trusted proxies is recorded with the client address
+	recorded := map[string]bool{}
+	for _, item := range items {
+		recorded[item.RemoteAddr] = true
+	}
+	if !recorded["203.0.113.7"] || !recorded["2001:db8::1"] || len(recorded) != 2 {
+		t.Fatalf("unexpected recorded client addresses: %v", recorded)
+	}
+}
+
+func TestCORSStageAnswersPreflight(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	cors := config.CORSConfig{
+		Enable:         true,
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		MaxAge:         10 * time.Minute,
+	}
+	cfg := &ServerConfig{
+		Path: "/hooks",
+		Responses: []ImmediateResponseRule{{Name: "ack", Status: http.StatusOK, Body: "ok", Headers: map[string]string{
+			"X-Rule": "ack",
+		}}},
+		CORS: buildCORSOptions(cors),
+	}
+	h := NewHandler(nil, nil, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/hooks/github", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	preflight.Header.Set("Access-Control-Request-Headers", "content-type, x-signature")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, preflight)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the preflight to be answered with 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("unexpected allowed origin %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST" || rec.Header().Get("Access-Control-Allow-Headers") != "content-type, x-signature" {
+		t.Fatalf("unexpected preflight headers: %v", rec.Header())
+	}
+	if rec.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected max age %q", rec.Header().Get("Access-Control-Max-Age"))
+	}
+
+	// Disallowed origins and methods are refused
+	for _, tc := range []struct{ origin, method string }{
+		{"https://evil.example.com", "POST"},
+		{"https://app.example.com", "DELETE"},
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/hooks/github", nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Fatalf("expected %s %s to be refused, got %d", tc.origin, tc.method, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader("{}"))
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Rule") != "ack" {
+		t.Fatalf("expected the mock response, got %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || rec.Header().Get("Vary") != "Origin" {
+		t.Fatalf("expected CORS headers on the mock response, got %v", rec.Header())
+	}
+	h.procWG.Wait()
+
+	if _, total, err := store.List(storage.ListOptions{}); err != nil || total != 1 {
+		t.Fatalf("expected only the actual request to be stored, got %d (%v)", total, err)
+	}
+}
+
+func TestCaptureRecordsHTTP2Proto(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:      "/",
+		Responses: []ImmediateResponseRule{{Name: "ack", Status: http.StatusOK, Body: "ok", Headers: map[string]string{}}},
+	}
+	h := NewHandler(p, nil, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.Protocols = listenerProtocols(config.ServerConfig{HTTP2: config.HTTP2Config{Enable: true}})
+	srv.Start()
+	defer srv.Close()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	resp, err := client.Post(srv.URL+"/hook", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", resp.Proto)
+	}
+	h.procWG.Wait()
+
+	var env struct {
+		Request request.RequestData `json:"request"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("expected the request to be printed: %v (%q)", err, out.String())
+	}
+	if env.Request.Proto != "HTTP/2.0" {
+		t.Fatalf("expected the negotiated protocol to be recorded, got %q", env.Request.Proto)
+	}
+}
+
+func TestPausedCaptureStillResponds(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:      "/",
+		Responses: []ImmediateResponseRule{{Name: "ack", Status: http.StatusAccepted, Body: "ok", Headers: map[string]string{}}},
+	}
+	h := NewHandler(p, nil, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	send := func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("{}")))
+		if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" {
+			t.Fatalf("expected the mock response, got %d %q", rec.Code, rec.Body.String())
+		}
+	}
+
+	state := h.SetCapturePaused(true)
+	if !state.Paused || state.PausedAt == nil {
+		t.Fatalf("expected capture to be paused, got %+v", state)
+	}
+	send()
+	send()
+	h.procWG.Wait()
+	if state := h.CaptureState(); state.Skipped != 2 {
+		t.Fatalf("expected 2 skipped requests, got %+v", state)
+	}
+	if _, total, err := store.List(storage.ListOptions{}); err != nil || total != 0 {
+		t.Fatalf("expected nothing to be stored while paused, got %d (%v)", total, err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing to be printed while paused, got %q", out.String())
+	}
+
+	if state := h.ToggleCapture(); state.Paused || state.PausedAt != nil {
+		t.Fatalf("expected capture to resume, got %+v", state)
+	}
+	send()
+	h.procWG.Wait()
+	if _, total, err := store.List(storage.ListOptions{}); err != nil || total != 1 {
+		t.Fatalf("expected the request after resuming to be stored, got %d (%v)", total, err)
+	}
+}
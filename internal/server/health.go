@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// Readiness check names
+const (
+	checkListener = "listener"
+	checkStorage  = "storage"
+	checkForward  = "forward"
+)
+
+// healthProbe answers the liveness and readiness probes of health.liveness_path and
+// health.readiness_path. It keeps its own state instead of locking the server, so probes keep
+// answering while Stop drains the listener.
+type healthProbe struct {
+	store   storage.Store
+	timeout time.Duration
+	// serving is set once the listener is bound and cleared when shutdown begins
+	serving atomic.Bool
+
+	mu sync.RWMutex
+	// hosts are the hosts of the HTTP forward targets, or of their forward.host_overrides address,
+	// without IP literals
+	hosts []string
+}
+
+// healthReport is the body of a probe response
+type healthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func newHealthProbe(cfg *config.Config, store storage.Store) *healthProbe {
+	probe := &healthProbe{store: store, timeout: cfg.Health.Timeout}
+	probe.setTargets(cfg)
+	return probe
+}
+
+// register mounts both probes on router ahead of the capture path.
+func (p *healthProbe) register(router *mux.Router, cfg config.HealthConfig) {
+	router.HandleFunc(cfg.LivenessPath, p.handleLiveness).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc(cfg.ReadinessPath, p.handleReadiness).Methods(http.MethodGet, http.MethodHead)
+}
+
+// setTargets collects the forward target hosts of cfg, including those of server.paths. A host
+// mapped by forward.host_overrides is never looked up, its override address is checked instead.
+func (p *healthProbe) setTargets(cfg *config.Config) {
+	overrides := make(map[string]string, len(cfg.Forward.HostOverrides))
+	for _, override := range cfg.Forward.HostOverrides {
+		address := strings.TrimSpace(override.Address)
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+		overrides[strings.ToLower(strings.TrimSpace(override.Host))] = strings.Trim(address, "[]")
+	}
+
+	urls := make([]string, 0, len(cfg.Forward.URLs))
+	for _, target := range cfg.Forward.ResolvedTargets() {
+		urls = append(urls, target.URL)
+	}
+	for _, path := range cfg.Server.Paths {
+		urls = append(urls, path.Forward.URLs...)
+		for _, target := range path.Forward.Targets {
+			urls = append(urls, target.URL)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, raw := range urls {
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		port := parsed.Port()
+		if port == "" {
+			port = "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+		}
+		// host:port overrides win over hostname ones, like in the forwarder
+		if address, ok := overrides[net.JoinHostPort(host, port)]; ok {
+			host = address
+		} else if address, ok := overrides[host]; ok {
+			host = address
+		}
+		if host == "" || net.ParseIP(host) != nil || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+
+	p.mu.Lock()
+	p.hosts = hosts
+	p.mu.Unlock()
+}
+
+func (p *healthProbe) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, http.StatusOK, &healthReport{Status: "ok"})
+}
+
+func (p *healthProbe) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), p.timeout)
+	defer cancel()
+
+	report := &healthReport{Status: "ready", Checks: p.check(ctx)}
+	status := http.StatusOK
+	for _, result := range report.Checks {
+		if result != "ok" {
+			report.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeHealthReport(w, status, report)
+}
+
+// check runs every readiness check and reports "ok" or why it failed
+func (p *healthProbe) check(ctx context.Context) map[string]string {
+	checks := map[string]string{
+		checkListener: "ok",
+		checkStorage:  "ok",
+		checkForward:  "ok",
+	}
+	if !p.serving.Load() {
+		checks[checkListener] = "not serving"
+	}
+	if err := p.checkStorage(ctx); err != nil {
+		checks[checkStorage] = err.Error()
+	}
+	if err := p.checkForward(ctx); err != nil {
+		checks[checkForward] = err.Error()
+	}
+	return checks
+}
+
+func (p *healthProbe) checkStorage(ctx context.Context) error {
+	if p.store == nil {
+		return nil
+	}
+	if reporter, ok := p.store.(storage.WriteQueueReporter); ok {
+		if pending, capacity := reporter.WriteQueue(); capacity > 0 && pending >= capacity {
+			return fmt.Errorf("write queue is full (%d requests)", pending)
+		}
+	}
+	if checker, ok := p.store.(storage.WriteChecker); ok {
+		if err := checker.CheckWritable(ctx); err != nil {
+			return fmt.Errorf("not writable: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkForward resolves every target host in parallel and reports those that do not resolve
+func (p *healthProbe) checkForward(ctx context.Context) error {
+	p.mu.RLock()
+	hosts := p.hosts
+	p.mu.RUnlock()
+
+	failures := make([]string, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+				failures[i] = host
+			}
+		}()
+	}
+	wg.Wait()
+
+	var unresolved []string
+	for _, host := range failures {
+		if host != "" {
+			unresolved = append(unresolved, host)
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("cannot resolve %s", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+func writeHealthReport(w http.ResponseWriter, status int, report *healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
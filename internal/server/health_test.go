@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+func TestHealthProbes(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Health = config.HealthConfig{Enable: true, LivenessPath: "/healthz", ReadinessPath: "/readyz", Timeout: 2 * time.Second}
+	cfg.Forward.URLs = []string{"http://127.0.0.1:9/hook", "http://localhost:9/hook", "kafka://broker.invalid:9092/events"}
+	cfg.Storage.Path = filepath.Join(t.TempDir(), "reqtap.db")
+	store, err := storage.New(&cfg.Storage, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	probe := newHealthProbe(cfg, store)
+	router := mux.NewRouter()
+	probe.register(router, cfg.Health)
+	get := func(path string) (int, healthReport) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		var report healthReport
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid report %q: %v", rr.Body.String(), err)
+		}
+		return rr.Code, report
+	}
+
+	if code, report := get("/healthz"); code != http.StatusOK || report.Status != "ok" {
+		t.Fatalf("expected the liveness probe to pass, got %d %+v", code, report)
+	}
+	// Only localhost needs resolving: IP literals and message brokers are skipped
+	if len(probe.hosts) != 1 || probe.hosts[0] != "localhost" {
+		t.Fatalf("unexpected target hosts: %v", probe.hosts)
+	}
+
+	code, report := get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Checks[checkListener] != "not serving" || report.Checks[checkStorage] != "ok" {
+		t.Fatalf("expected readiness to fail before the listener is bound, got %d %+v", code, report)
+	}
+	probe.serving.Store(true)
+	if code, report := get("/readyz"); code != http.StatusOK || report.Status != "ready" {
+		t.Fatalf("expected readiness to pass, got %d %+v", code, report)
+	}
+
+	next := *cfg
+	next.Forward.URLs = []string{"https://reqtap-probe.invalid/hook"}
+	probe.setTargets(&next)
+	code, report = get("/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(report.Checks[checkForward], "reqtap-probe.invalid") {
+		t.Fatalf("expected an unresolvable target to fail readiness, got %d %+v", code, report)
+	}
+
+	// Overridden hosts are not looked up; a hostname override address is resolved instead
+	next.Forward.URLs = []string{"https://reqtap-probe.invalid/hook", "http://api.invalid:8080/hook", "http://billing.invalid/hook"}
+	next.Forward.HostOverrides = []config.ForwardHostOverrideConfig{
+		{Host: "reqtap-probe.invalid", Address: "127.0.0.1:9443"},
+		{Host: "API.invalid:8080", Address: "localhost"},
+		{Host: "billing.invalid", Address: "[::1]:8080"},
+	}
+	probe.setTargets(&next)
+	if len(probe.hosts) != 1 || probe.hosts[0] != "localhost" {
+		t.Fatalf("expected only the override address to be resolved, got %v", probe.hosts)
+	}
+	if code, report := get("/readyz"); code != http.StatusOK {
+		t.Fatalf("expected overridden targets to pass readiness, got %d %+v", code, report)
+	}
+}
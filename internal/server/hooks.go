@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/redact"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// HookPoint names the place in the pipeline where a hook runs.
+type HookPoint string
+
+// Hook points, in the order a request reaches them.
+const (
+	// HookOnReceive runs on the request goroutine once the request is captured, before the client
+	// is answered; dropping keeps the answer but nothing is recorded, printed or forwarded.
+	HookOnReceive HookPoint = "on_receive"
+	// HookBeforeStore runs in the background before the request is stored; dropping skips
+	// everything that follows.
+	HookBeforeStore HookPoint = "before_store"
+	// HookBeforeForward runs before the request is forwarded; dropping only skips forwarding.
+	HookBeforeForward HookPoint = "before_forward"
+	// HookAfterForward runs once the forward results are known; the request can no longer be dropped.
+	HookAfterForward HookPoint = "after_forward"
+)
+
+// Pipeline stages running the hooks of each hook point.
+const (
+	StageHookOnReceive     = "hook-on-receive"
+	StageHookBeforeStore   = "hook-before-store"
+	StageHookBeforeForward = "hook-before-forward"
+	StageHookAfterForward  = "hook-after-forward"
+)
+
+// ErrDropRequest is returned by a hook to drop the request, see the hook points.
+var ErrDropRequest = errors.New("request dropped by hook")
+
+// Hook observes or changes a captured request at a hook point; changes to ev.Record are seen by
+// every later stage. Return ErrDropRequest to drop the request, any other error is logged.
+type Hook func(ctx context.Context, ev *HookEvent) error
+
+// HookEvent is what a hook gets to see.
+type HookEvent struct {
+	Point HookPoint
+	// Request is the original request; its body has already been read into Record
+	Request *http.Request
+	Record  *request.RequestData
+	// Results holds the forward deliveries at HookAfterForward
+	Results []forwarder.Result
+
+	handler *Handler
+	ex      *Exchange
+}
+
+// Tag adds triage tags to the request; tags added before it is stored are stored with it.
+func (ev *HookEvent) Tag(tags ...string) {
+	ev.handler.tagExchange(ev.ex, tags)
+}
+
+type namedHook struct {
+	name string
+	run  Hook
+}
+
+// HookRule is a built-in hook from the hooks section of the configuration.
+type HookRule struct {
+	Name   string
+	Point  HookPoint
+	Action string
+	Match  forwarder.Filter
+	Redact redact.Rules
+	Tags   []string
+}
+
+func buildHookRules(cfgs []config.HookConfig) []HookRule {
+	rules := make([]HookRule, 0, len(cfgs))
+	for _, c := range cfgs {
+		rule := HookRule{
+			Name:   c.Name,
+			Point:  HookPoint(c.Point),
+			Action: c.Action,
+			Match: forwarder.Filter{
+				Name:         c.Name,
+				Methods:      normalizeMethods(c.Methods),
+				BodyContains: []byte(c.BodyContains),
+			},
+			Redact: redact.Rules{Headers: c.RedactHeaders, Fields: c.RedactFields},
+			Tags:   append([]string(nil), c.Tags...),
+		}
+		if c.PathRegex != "" {
+			rule.Match.Path = regexp.MustCompile(c.PathRegex)
+		}
+		for name, pattern := range c.Headers {
+			if rule.Match.Headers == nil {
+				rule.Match.Headers = make(map[string]*regexp.Regexp, len(c.Headers))
+			}
+			rule.Match.Headers[name] = regexp.MustCompile(pattern)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// run applies the built-in action to a matching request.
+func (r *HookRule) run(_ context.Context, ev *HookEvent) error {
+	if !r.Match.Match(ev.Record) {
+		return nil
+	}
+	switch r.Action {
+	case "redact":
+		r.Redact.Apply(ev.Record)
+	case "tag":
+		ev.Tag(r.Tags...)
+	case "drop":
+		return ErrDropRequest
+	}
+	return nil
+}
+
+// AddHook registers fn to run at point after the configured hooks and the hooks added before it.
+func (h *Handler) AddHook(point HookPoint, name string, fn Hook) error {
+	switch point {
+	case HookOnReceive, HookBeforeStore, HookBeforeForward, HookAfterForward:
+	default:
+		return fmt.Errorf("unknown hook point %q", point)
+	}
+	if fn == nil {
+		return fmt.Errorf("hook %s has no function", name)
+	}
+	h.hooksMu.Lock()
+	defer h.hooksMu.Unlock()
+	if h.hooks == nil {
+		h.hooks = make(map[HookPoint][]namedHook)
+	}
+	h.hooks[point] = append(h.hooks[point], namedHook{name: name, run: fn})
+	return nil
+}
+
+// hookStage returns the stage running the hooks of point.
+func (h *Handler) hookStage(point HookPoint) StageFunc {
+	return func(ctx context.Context, ex *Exchange) error {
+		if ex.Record == nil {
+			return nil
+		}
+		// At the forward points hooks see the request as it is forwarded
+		forwarding := (point == HookBeforeForward || point == HookAfterForward) && ex.ForwardRecord != nil
+		record := ex.Record
+		if forwarding {
+			record = ex.ForwardRecord
+		}
+		ev := &HookEvent{Point: point, Request: ex.Request, Record: record, Results: ex.Results, handler: h, ex: ex}
+
+		hooks := make([]namedHook, 0)
+		rules := h.currentConfig().Hooks
+		for i := range rules {
+			if rules[i].Point == point {
+				hooks = append(hooks, namedHook{name: rules[i].Name, run: rules[i].run})
+			}
+		}
+		h.hooksMu.RLock()
+		hooks = append(hooks, h.hooks[point]...)
+		h.hooksMu.RUnlock()
+
+		for _, hook := range hooks {
+			err := hook.run(ctx, ev)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrDropRequest) {
+				h.logger.Error("Hook failed", "hook", hook.name, "point", point, "error", err, "request_id", ex.Record.ID)
+				continue
+			}
+			h.logger.Debug("Request dropped by hook", "hook", hook.name, "point", point, "request_id", ex.Record.ID)
+			switch point {
+			case HookOnReceive:
+				ex.Dropped = true
+				return nil
+			case HookBeforeStore:
+				return ErrStopPipeline
+			case HookBeforeForward:
+				ex.SkipForward = true
+				return nil
+			}
+		}
+		// A hook may have replaced the record
+		if forwarding {
+			ex.ForwardRecord = ev.Record
+		} else {
+			ex.Record = ev.Record
+		}
+		return nil
+	}
+}
+
+// tagExchange adds tags to the request, annotating it right away when it is already stored.
+func (h *Handler) tagExchange(ex *Exchange, tags []string) {
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(ex.Tags, tag) {
+			ex.Tags = append(ex.Tags, tag)
+		}
+	}
+	if ex.Stored == nil {
+		return
+	}
+	ex.Stored.Tags = ex.Tags
+	if h.store != nil && ex.Record != nil {
+		if err := h.store.Annotate(ex.Record.ID, ex.Tags, nil); err != nil {
+			h.logger.Error("Failed to tag request", "error", err, "request_id", ex.Record.ID)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+func TestHookStages(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	hooks := []config.HookConfig{
+		{Name: "no-health", Point: "on_receive", Action: "drop", PathRegex: "^/health$"},
+		{Name: "cards", Point: "before_store", Action: "redact", RedactHeaders: []string{"Authorization"}, RedactFields: []string{"card.number"}},
+		{Name: "stripe", Point: "before_store", Action: "tag", Headers: map[string]string{"Stripe-Signature": "."}, Tags: []string{"stripe"}},
+		{Name: "no-tests", Point: "before_forward", Action: "drop", BodyContains: `"livemode":false`},
+	}
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK, Body: "ok"}},
+		Hooks:          buildHookRules(hooks),
+	}
+	h := NewHandler(nil, stubForwarder{}, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	var mu sync.Mutex
+	delivered := map[string]int{}
+	err = h.AddHook(HookAfterForward, "count", func(_ context.Context, ev *HookEvent) error {
+		mu.Lock()
+		delivered[ev.Record.Path] = len(ev.Results)
+		mu.Unlock()
+		if len(ev.Results) > 0 {
+			ev.Tag("delivered")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("add hook: %v", err)
+	}
+	if err := h.AddHook("later", "x", func(context.Context, *HookEvent) error { return nil }); err == nil {
+		t.Fatal("expected an unknown hook point to be rejected")
+	}
+
+	send := func(path, body string, header http.Header) int {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		h.procWG.Wait()
+		return rec.Code
+	}
+	if code := send("/health", "", nil); code != http.StatusOK {
+		t.Fatalf("expected a dropped request to still be answered, got %d", code)
+	}
+	send("/charge", `{"card":{"number":"4242424242424242"},"livemode":true}`, http.Header{
+		"Authorization":    {"Bearer secret"},
+		"Stripe-Signature": {"t=1,v1=abc"},
+	})
+	send("/test-charge", `{"livemode":false}`, nil)
+
+	items, total, err := store.List(storage.ListOptions{})
+	if err != nil || total != 2 {
+		t.Fatalf("expected the dropped request not to be stored, got %d (%v)", total, err)
+	}
+	byPath := map[string]*storage.StoredRequest{}
+	for _, item := range items {
+		byPath[item.Path] = item
+	}
+	charge := byPath["/charge"]
+	if charge.Headers.Get("Authorization") != "[REDACTED]" || strings.Contains(string(charge.Body), "4242") {
+		t.Fatalf("expected the card and token to be redacted before storing, got %s", charge.Body)
+	}
+	if strings.Join(charge.Tags, ",") != "delivered,stripe" {
+		t.Fatalf("expected tags from before and after storing, got %v", charge.Tags)
+	}
+	if delivered["/charge"] != 1 || delivered["/test-charge"] != 0 || len(byPath["/test-charge"].Tags) != 0 {
+		t.Fatalf("expected only the live charge to be forwarded, got %v", delivered)
+	}
+	if _, ok := delivered["/health"]; ok {
+		t.Fatal("expected no background hook to run for the dropped request")
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// defaultServerHeader identifies ReqTap when neither an override nor stealth mode is configured.
+const defaultServerHeader = "ReqTap/1.0"
+
+// IdentityOptions controls how capture responses identify the server
+type IdentityOptions struct {
+	// ServerHeader is the resolved Server header; empty omits it
+	ServerHeader string
+	// Stealth answers errors with the error pages of Profile
+	Stealth bool
+	Profile string
+}
+
+// serverProfile is a web server whose banner and error pages stealth mode imitates.
+type serverProfile struct {
+	banner string
+	page   func(status int, banner string) string
+}
+
+var serverProfiles = map[string]serverProfile{
+	"nginx": {
+		banner: "nginx/1.24.0",
+		page: func(status int, banner string) string {
+			title := fmt.Sprintf("%d %s", status, http.StatusText(status))
+			return fmt.Sprintf("<html>\r\n<head><title>%s</title></head>\r\n<body>\r\n<center><h1>%s</h1></center>\r\n<hr><center>%s</center>\r\n</body>\r\n</html>\r\n",
+				title, title, nginxBannerName(banner))
+		},
+	},
+	"apache": {
+		banner: "Apache/2.4.58 (Ubuntu)",
+		page: func(status int, banner string) string {
+			text := http.StatusText(status)
+			message := apacheMessages[status]
+			if message == "" {
+				message = "The server encountered an error and was unable to complete your request."
+			}
+			return fmt.Sprintf("<!DOCTYPE HTML PUBLIC \"-//IETF//DTD HTML 2.0//EN\">\n<html><head>\n<title>%d %s</title>\n</head><body>\n<h1>%s</h1>\n<p>%s</p>\n<hr>\n<address>%s</address>\n</body></html>\n",
+				status, text, text, message, banner)
+		},
+	},
+	"caddy": {
+		banner: "Caddy",
+		// Caddy answers errors with an empty body
+		page: func(int, string) string { return "" },
+	},
+}
+
+var apacheMessages = map[int]string{
+	http.StatusNotFound:              "The requested URL was not found on this server.",
+	http.StatusRequestEntityTooLarge: "The requested resource does not allow request data with POST requests, or the amount of data provided in the request exceeds the capacity limit.",
+	http.StatusBadGateway:            "The proxy server received an invalid response from an upstream server.",
+}
+
+// nginxBannerName is what nginx prints under its error pages: the product name and version.
+func nginxBannerName(banner string) string {
+	if name, _, ok := strings.Cut(banner, " "); ok {
+		return name
+	}
+	return banner
+}
+
+// buildIdentityOptions resolves the Server header: an explicit override wins, then the stealth
+// profile's banner, then ReqTap's own.
+func buildIdentityOptions(cfg config.IdentityConfig) IdentityOptions {
+	opts := IdentityOptions{Stealth: cfg.Stealth, Profile: cfg.Profile}
+	if _, ok := serverProfiles[opts.Profile]; !ok {
+		opts.Profile = "nginx"
+	}
+	switch {
+	case cfg.HideServerHeader:
+	case cfg.ServerHeader != "":
+		opts.ServerHeader = cfg.ServerHeader
+	case cfg.Stealth:
+		opts.ServerHeader = serverProfiles[opts.Profile].banner
+	default:
+		opts.ServerHeader = defaultServerHeader
+	}
+	return opts
+}
+
+// setServerHeader applies the configured Server header to a capture response.
+func (h *Handler) setServerHeader(header http.Header) {
+	if value := h.currentConfig().Identity.ServerHeader; value != "" {
+		header.Set("Server", value)
+	}
+}
+
+// writeError answers a capture request with a plain error, or with the error page of the
+// mimicked server in stealth mode.
+func (h *Handler) writeError(w http.ResponseWriter, status int) {
+	identity := h.currentConfig().Identity
+	h.setServerHeader(w.Header())
+	if !identity.Stealth {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	page := serverProfiles[identity.Profile].page(status, identity.ServerHeader)
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Content-Length", fmt.Sprint(len(page)))
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(page))
+}
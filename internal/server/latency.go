@@ -0,0 +1,96 @@
+package server
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// maxHangDuration bounds how long a simulated timeout keeps the connection open
+const maxHangDuration = 2 * time.Minute
+
+// injectsLatency reports whether the rule delays its response or may drop it.
+func (rule *ImmediateResponseRule) injectsLatency() bool {
+	return rule.Delay > 0 || rule.DelayJitter > 0 || rule.TimeoutChance > 0
+}
+
+// responseDelay returns the fixed delay plus a random jitter in [0, DelayJitter].
+func (rule *ImmediateResponseRule) responseDelay() time.Duration {
+	delay := rule.Delay
+	if rule.DelayJitter > 0 {
+		delay += rand.N(rule.DelayJitter + 1)
+	}
+	return delay
+}
+
+// injectLatency applies the rule's timeout chance and delay before the response is written. It
+// returns false when no response must be sent: the timeout was simulated or the client went away.
+func (h *Handler) injectLatency(w http.ResponseWriter, r *http.Request, rule *ImmediateResponseRule) bool {
+	if rule.TimeoutChance > 0 && rand.Float64() < rule.TimeoutChance {
+		h.logger.Debug("Simulating response timeout", "rule", rule.Name, "method", r.Method, "path", r.URL.Path)
+		h.hangConnection(w, r)
+		return false
+	}
+
+	delay := rule.responseDelay()
+	if delay <= 0 {
+		return true
+	}
+	// The server's write timeout would otherwise cut long delays short
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(delay + 30*time.Second)); err != nil {
+		h.logger.Debug("Unable to extend write deadline for delayed response", "error", err)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		h.logger.Debug("Client gave up during response delay", "rule", rule.Name, "delay", delay.String())
+		return false
+	}
+}
+
+// hangConnection takes over the connection without answering and closes it once the client gives
+// up, maxHangDuration elapses or the server shuts down. The pipeline carries on meanwhile, so the
+// request is still stored and forwarded. Connections that cannot be taken over (HTTP/2) are
+// answered with 504 after the hang instead.
+func (h *Handler) hangConnection(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		h.waitForHang(r)
+		h.writeError(w, http.StatusGatewayTimeout)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		h.waitForHang(r)
+		h.writeError(w, http.StatusGatewayTimeout)
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(maxHangDuration))
+	go func() {
+		closed := make(chan struct{})
+		go func() {
+			// Reading returns once the client closes the connection or the deadline passes
+			_, _ = io.Copy(io.Discard, conn)
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-h.baseCtx.Done():
+		}
+		conn.Close()
+	}()
+}
+
+func (h *Handler) waitForHang(r *http.Request) {
+	timer := time.NewTimer(maxHangDuration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	case <-h.baseCtx.Done():
+	}
+}
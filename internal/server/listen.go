@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to an activated service.
+const listenFDsStart = 3
+
+// listen returns the socket the server accepts on: the one inherited from systemd socket
+// activation when present, otherwise a new TCP listener on addr.
+func listen(cfg config.ListenerConfig, addr string) (net.Listener, bool, error) {
+	if cfg.SocketActivation {
+		listener, err := activatedListener()
+		if err != nil {
+			return nil, false, err
+		}
+		if listener != nil {
+			return listener, true, nil
+		}
+	}
+
+	var lc net.ListenConfig
+	if cfg.ReusePort {
+		lc.Control = reusePortControl
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return listener, false, nil
+}
+
+// activatedListener takes over the first socket passed by systemd, or returns nil when the process
+// was not socket activated. The LISTEN_* variables are cleared so child processes do not claim it.
+func activatedListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	if file == nil {
+		return nil, fmt.Errorf("socket activation: file descriptor %d is not open", listenFDsStart)
+	}
+	// FileListener dups the descriptor, so the original is closed either way
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("socket activation: file descriptor %d is not a listening socket: %w", listenFDsStart, err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not available on windows")
+	}
+	cfg := config.ListenerConfig{ReusePort: true}
+	first, activated, err := listen(cfg, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer first.Close()
+	if activated {
+		t.Fatal("expected a new socket without LISTEN_FDS")
+	}
+
+	// A restarted process binds the same port while the old one still listens
+	addr := first.Addr().String()
+	second, _, err := listen(cfg, addr)
+	if err != nil {
+		t.Fatalf("second listen with reuse_port failed: %v", err)
+	}
+	second.Close()
+
+	if third, _, err := listen(config.ListenerConfig{}, addr); err == nil {
+		third.Close()
+		t.Fatal("expected the port to be busy without reuse_port")
+	}
+}
+
+func TestActivatedListenerIgnoresOtherProcess(t *testing.T) {
+	// LISTEN_FDS addressed to another process (e.g. our parent) must not be claimed
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	listener, activated, err := listen(config.ListenerConfig{SocketActivation: true}, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer listener.Close()
+	if activated {
+		t.Fatal("socket meant for another process was used")
+	}
+}
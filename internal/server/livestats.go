@@ -0,0 +1,122 @@
+package server
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/printer"
+)
+
+// rateWindow is the span req/s is averaged over in the console status line
+const rateWindow = time.Minute
+
+// liveStats counts what the console status line shows; the counters cover this run only
+type liveStats struct {
+	startedAt     time.Time
+	captured      atomic.Int64
+	forwardErrors atomic.Int64
+
+	// seconds holds the captures of each second of the last rateWindow, indexed by Unix second
+	mu      sync.Mutex
+	seconds [int(rateWindow / time.Second)]rateBucket
+}
+
+type rateBucket struct {
+	second int64
+	count  int64
+}
+
+func newLiveStats() *liveStats {
+	return &liveStats{startedAt: time.Now()}
+}
+
+// observeCapture counts one captured request
+func (s *liveStats) observeCapture(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.captured.Add(1)
+	second := now.Unix()
+	s.mu.Lock()
+	bucket := &s.seconds[second%int64(len(s.seconds))]
+	if bucket.second != second {
+		*bucket = rateBucket{second: second}
+	}
+	bucket.count++
+	s.mu.Unlock()
+}
+
+// observeForwardError counts one failed delivery
+func (s *liveStats) observeForwardError() {
+	if s == nil {
+		return
+	}
+	s.forwardErrors.Add(1)
+}
+
+// rate is the captures per second over the last rateWindow, or since the start when that is
+// shorter
+func (s *liveStats) rate(now time.Time) float64 {
+	window := now.Sub(s.startedAt)
+	if window > rateWindow {
+		window = rateWindow
+	}
+	if window < time.Second {
+		window = time.Second
+	}
+	// The window touches at most len(s.seconds) whole seconds, the first one partially
+	first := now.Unix() - int64(len(s.seconds)) + 1
+	if started := s.startedAt.Unix(); started > first {
+		first = started
+	}
+	var total int64
+	s.mu.Lock()
+	for _, bucket := range s.seconds {
+		if bucket.second >= first {
+			total += bucket.count
+		}
+	}
+	s.mu.Unlock()
+	return float64(total) / window.Seconds()
+}
+
+// snapshot returns the status line values at now
+func (s *liveStats) snapshot(now time.Time) printer.StatusSnapshot {
+	return printer.StatusSnapshot{
+		Uptime:        now.Sub(s.startedAt),
+		Captured:      s.captured.Load(),
+		RatePerSecond: s.rate(now),
+		ForwardErrors: s.forwardErrors.Load(),
+	}
+}
+
+// usesStatusLine reports whether the console printer gets the status line of output.status_line
+func usesStatusLine(cfg *config.Config) bool {
+	return cfg.Output.StatusLine && !cfg.Output.Silence && strings.EqualFold(cfg.Output.Mode, "console")
+}
+
+// startStatusLine shows the status line below the request output when stdout is a terminal
+func (s *Server) startStatusLine() {
+	if !usesStatusLine(s.config) {
+		return
+	}
+	stats := s.handler.stats
+	statusLine := printer.NewStatusLine(os.Stdout, s.translator, s.config.Output.Locale, func() printer.StatusSnapshot {
+		return stats.snapshot(time.Now())
+	})
+	if !statusLine.Start() {
+		s.logger.Info("Status line disabled, stdout is not a terminal")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		statusLine.Stop()
+		return
+	}
+	s.statusLine = statusLine
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLiveStatsRate(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	stats := &liveStats{startedAt: start}
+
+	// 10 captures in the first 5 seconds average over those 5 seconds
+	for i := 0; i < 10; i++ {
+		stats.observeCapture(start.Add(time.Duration(i/2) * time.Second))
+	}
+	if got := stats.rate(start.Add(5 * time.Second)); got != 2 {
+		t.Fatalf("expected 2 req/s since the start, got %v", got)
+	}
+
+	// Once a minute has passed, captures older than the window no longer count
+	for i := 0; i < 30; i++ {
+		stats.observeCapture(start.Add(90 * time.Second))
+	}
+	now := start.Add(90*time.Second + 500*time.Millisecond)
+	if got := stats.rate(now); got != 0.5 {
+		t.Fatalf("expected 0.5 req/s over the last minute, got %v", got)
+	}
+	stats.observeForwardError()
+	snap := stats.snapshot(now)
+	if snap.Captured != 40 || snap.ForwardErrors != 1 || snap.Uptime != 90*time.Second+500*time.Millisecond {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	// The bucket of a second is reused a minute later
+	stats.observeCapture(start.Add(150 * time.Second))
+	if got := stats.rate(start.Add(150 * time.Second)); got != float64(1)/60 {
+		t.Fatalf("expected the second to start over, got %v", got)
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+)
+
+// MatchCondition is a compiled header_match, query_match or body_match entry
+type MatchCondition struct {
+	// Name is the header or query parameter; JSONPath the body field. Both empty tests the whole body.
+	Name     string
+	JSONPath string
+	Equals   string
+	Contains string
+	Regex    *regexp.Regexp
+}
+
+// test applies the condition's operator; a condition without one accepts any value
+func (c *MatchCondition) test(value string) bool {
+	switch {
+	case c.Regex != nil:
+		return c.Regex.MatchString(value)
+	case c.Contains != "":
+		return strings.Contains(value, c.Contains)
+	case c.Equals != "":
+		return value == c.Equals
+	}
+	return true
+}
+
+// testAny reports whether one of the values satisfies the condition; no values means it is missing
+func (c *MatchCondition) testAny(values []string) bool {
+	for _, value := range values {
+		if c.test(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchInput is what mock rule conditions are evaluated against; query and JSON body are parsed
+// on first use and shared by every rule
+type matchInput struct {
+	r    *http.Request
+	body []byte
+
+	query   url.Values
+	doc     interface{}
+	decoded bool
+	isJSON  bool
+}
+
+func (in *matchInput) queryValues() url.Values {
+	if in.query == nil {
+		in.query = in.r.URL.Query()
+	}
+	return in.query
+}
+
+func (in *matchInput) jsonBody() (interface{}, bool) {
+	if !in.decoded {
+		in.decoded = true
+		doc, err := jsonpath.Decode(in.body)
+		in.doc, in.isJSON = doc, err == nil
+	}
+	return in.doc, in.isJSON
+}
+
+// matchesConditions reports whether the request satisfies every header, query and body condition
+func (rule *ImmediateResponseRule) matchesConditions(in *matchInput) bool {
+	for i := range rule.HeaderMatch {
+		cond := &rule.HeaderMatch[i]
+		if !cond.testAny(in.r.Header.Values(cond.Name)) {
+			return false
+		}
+	}
+	for i := range rule.QueryMatch {
+		cond := &rule.QueryMatch[i]
+		if !cond.testAny(in.queryValues()[cond.Name]) {
+			return false
+		}
+	}
+	for i := range rule.BodyMatch {
+		cond := &rule.BodyMatch[i]
+		if cond.JSONPath == "" {
+			if !cond.test(string(in.body)) {
+				return false
+			}
+			continue
+		}
+		doc, ok := in.jsonBody()
+		if !ok {
+			return false
+		}
+		value, found := jsonpath.Lookup(doc, cond.JSONPath)
+		if !found || !cond.test(jsonpath.Stringify(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertMatchConditions compiles the conditions, which were already checked by config validation
+func convertMatchConditions(cfgs []config.MatchConditionConfig) []MatchCondition {
+	if len(cfgs) == 0 {
+		return nil
+	}
+	conditions := make([]MatchCondition, 0, len(cfgs))
+	for _, c := range cfgs {
+		cond := MatchCondition{
+			Name:     strings.TrimSpace(c.Name),
+			JSONPath: c.JSONPath,
+			Equals:   c.Equals,
+			Contains: c.Contains,
+		}
+		if c.Regex != "" {
+			cond.Regex = regexp.MustCompile(c.Regex)
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
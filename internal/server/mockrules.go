@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/internal/web"
+)
+
+// loadStoredMockRules restores the mock rules added through the API before the last restart; a
+// rule that no longer validates, e.g. because its body_file was removed, is skipped.
+func loadStoredMockRules(store storage.Store, log logger.Logger) []web.MockRule {
+	ruleStore, ok := store.(storage.MockRuleStore)
+	if !ok {
+		return nil
+	}
+	stored, err := ruleStore.MockRules()
+	if err != nil {
+		log.Error("Failed to load mock rules", "error", err)
+		return nil
+	}
+	rules := make([]web.MockRule, 0, len(stored))
+	for _, entry := range stored {
+		var rule config.ImmediateResponseConfig
+		if err := yaml.Unmarshal([]byte(entry.Definition), &rule); err != nil {
+			log.Warn("Skipping stored mock rule", "rule", entry.Name, "error", err)
+			continue
+		}
+		rule.Name = entry.Name
+		if err := config.ValidateMockRule(&rule); err != nil {
+			log.Warn("Skipping stored mock rule", "rule", entry.Name, "error", err)
+			continue
+		}
+		updatedAt := entry.UpdatedAt
+		rules = append(rules, web.MockRule{Rule: rule, Source: web.MockRuleSourceAPI, UpdatedBy: entry.UpdatedBy, UpdatedAt: &updatedAt})
+	}
+	return rules
+}
+
+// effectiveMockRules lists the rules added through the API, then the server.responses rules of the
+// config file they do not replace. Unnamed config rules get the "rule-N" name of their position.
+func effectiveMockRules(apiRules []web.MockRule, configRules []config.ImmediateResponseConfig) []web.MockRule {
+	replaced := make(map[string]bool, len(apiRules))
+	rules := make([]web.MockRule, 0, len(apiRules)+len(configRules))
+	for _, rule := range apiRules {
+		replaced[rule.Rule.Name] = true
+		rules = append(rules, rule)
+	}
+	for i, rule := range configRules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !replaced[rule.Name] {
+			rules = append(rules, web.MockRule{Rule: rule, Source: web.MockRuleSourceConfig})
+		}
+	}
+	return rules
+}
+
+// mockRuleConfigs converts the effective rules for the handler
+func mockRuleConfigs(apiRules []web.MockRule, configRules []config.ImmediateResponseConfig) []ImmediateResponseRule {
+	effective := effectiveMockRules(apiRules, configRules)
+	cfgs := make([]config.ImmediateResponseConfig, 0, len(effective))
+	for _, rule := range effective {
+		cfgs = append(cfgs, rule.Rule)
+	}
+	return convertImmediateResponseConfigs(cfgs)
+}
+
+// MockRules lists the server.responses rules in match order.
+func (s *Server) MockRules() []web.MockRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return effectiveMockRules(s.mockRules, s.config.Server.ResponseRules())
+}
+
+// SaveMockRule adds or replaces a rule, stores it when the storage supports it and applies it to
+// the running handler.
+func (s *Server) SaveMockRule(rule config.ImmediateResponseConfig, updatedBy string, replace bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := -1
+	for i, existing := range s.mockRules {
+		if existing.Rule.Name == rule.Name {
+			index = i
+			break
+		}
+	}
+	exists := index >= 0
+	for _, existing := range effectiveMockRules(nil, s.config.Server.ResponseRules()) {
+		exists = exists || existing.Rule.Name == rule.Name
+	}
+	switch {
+	case replace && !exists:
+		return web.ErrMockRuleNotFound
+	case !replace && exists:
+		return web.ErrMockRuleExists
+	}
+
+	if ruleStore, ok := s.store.(storage.MockRuleStore); ok {
+		definition, err := yaml.Marshal(rule)
+		if err != nil {
+			return err
+		}
+		if err := ruleStore.SaveMockRule(&storage.MockRule{Name: rule.Name, Definition: string(definition), UpdatedBy: updatedBy}); err != nil {
+			return err
+		}
+	}
+	now := time.Now().UTC()
+	entry := web.MockRule{Rule: rule, Source: web.MockRuleSourceAPI, UpdatedBy: updatedBy, UpdatedAt: &now}
+	if index >= 0 {
+		s.mockRules[index] = entry
+	} else {
+		s.mockRules = append(s.mockRules, entry)
+	}
+	s.applyMockRules()
+	return nil
+}
+
+// DeleteMockRule removes a rule added through the API; a config rule it replaced applies again.
+func (s *Server) DeleteMockRule(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := -1
+	for i, existing := range s.mockRules {
+		if existing.Rule.Name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		for _, existing := range effectiveMockRules(nil, s.config.Server.ResponseRules()) {
+			if existing.Rule.Name == name {
+				return web.ErrMockRuleConfigured
+			}
+		}
+		return web.ErrMockRuleNotFound
+	}
+
+	if ruleStore, ok := s.store.(storage.MockRuleStore); ok {
+		if err := ruleStore.DeleteMockRule(name); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	s.mockRules = append(s.mockRules[:index], s.mockRules[index+1:]...)
+	s.applyMockRules()
+	return nil
+}
+
+// applyMockRules rebuilds the handler configuration with the current rules; callers hold s.mu.
+func (s *Server) applyMockRules() {
+	serverConfig := buildServerConfig(s.config)
+	serverConfig.Responses = mockRuleConfigs(s.mockRules, s.config.Server.ResponseRules())
+	s.handler.UpdateConfig(serverConfig)
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// StageForwardOrder reserves the forwarding turn of requests that carry an ordering key.
+const StageForwardOrder = "forward-order"
+
+// forwardOrderTurn is the exchange value holding the turn reserved by the forward-order stage
+const forwardOrderTurn = "forward-order-turn"
+
+// forwardOrder hands out turns so that the holders of the same key take them in the order they
+// were reserved. A request reserves a turn for its key when it arrives; when that turn comes up
+// it reserves one turn per target, passes on the key turn and delivers to every target as soon as
+// the previous request of the key is done with it, so a slow target does not hold up the others.
+type forwardOrder struct {
+	header   string
+	jsonPath string
+
+	mu    sync.Mutex
+	tails map[string]*orderTurn
+}
+
+// orderTurn is one place in the line of a key
+type orderTurn struct {
+	order *forwardOrder
+	key   string
+	// prev is closed once the previous holder of the key released its turn; nil for the first
+	prev <-chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newForwardOrder(cfg config.ForwardOrderingConfig) *forwardOrder {
+	return &forwardOrder{header: cfg.Header, jsonPath: cfg.JSONPath, tails: make(map[string]*orderTurn)}
+}
+
+// key reads the ordering key of record; requests without one are not ordered
+func (o *forwardOrder) key(record *request.RequestData) string {
+	if o.header != "" {
+		if value := record.Headers.Get(o.header); value != "" {
+			return value
+		}
+	}
+	if o.jsonPath != "" && !record.IsBinary {
+		if value, ok := jsonpath.LookupBytes(record.Body, o.jsonPath); ok && value != nil {
+			return jsonpath.Stringify(value)
+		}
+	}
+	return ""
+}
+
+// reserve queues a turn behind the last one reserved for key
+func (o *forwardOrder) reserve(key string) *orderTurn {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	turn := &orderTurn{order: o, key: key, done: make(chan struct{})}
+	if tail := o.tails[key]; tail != nil {
+		turn.prev = tail.done
+	}
+	o.tails[key] = turn
+	return turn
+}
+
+// wait blocks until the turn comes up; false means ctx ended first
+func (t *orderTurn) wait(ctx context.Context) bool {
+	if t.prev == nil {
+		return true
+	}
+	select {
+	case <-t.prev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release passes the turn on. A turn released before it came up, e.g. for a request that was
+// dropped, is passed on once the previous holder is done, so the order still holds.
+func (t *orderTurn) release() {
+	t.once.Do(func() {
+		if t.prev == nil {
+			t.finish()
+			return
+		}
+		select {
+		case <-t.prev:
+			t.finish()
+		default:
+			go func() {
+				<-t.prev
+				t.finish()
+			}()
+		}
+	})
+}
+
+func (t *orderTurn) finish() {
+	close(t.done)
+	t.order.mu.Lock()
+	if t.order.tails[t.key] == t {
+		delete(t.order.tails, t.key)
+	}
+	t.order.mu.Unlock()
+}
+
+// installForwardOrderStage reserves the turn of each keyed request as the last step on the
+// request goroutine, so turns follow the order in which requests were answered.
+func (h *Handler) installForwardOrderStage(cfg config.ForwardOrderingConfig) error {
+	if !cfg.Enable {
+		return nil
+	}
+	order := newForwardOrder(cfg)
+	h.order = order
+	return h.pipeline.InsertAfter(StagePause, Stage{Name: StageForwardOrder, Phase: PhaseSync, Run: func(_ context.Context, ex *Exchange) error {
+		if ex.Rejected || ex.Dropped || ex.SkipForward {
+			return nil
+		}
+		key := order.key(ex.Record)
+		if key == "" {
+			return nil
+		}
+		turn := order.reserve(key)
+		ex.Set(forwardOrderTurn, turn)
+		// Requests that never reach the forward stage still pass their turn on
+		ex.OnDone(turn.release)
+		return nil
+	}})
+}
+
+// exchangeOrderTurn returns the turn reserved for ex, if any
+func exchangeOrderTurn(ex *Exchange) *orderTurn {
+	value, ok := ex.Get(forwardOrderTurn)
+	if !ok {
+		return nil
+	}
+	return value.(*orderTurn)
+}
+
+// deliverOrdered delivers record to each target once the previous request of its key is done
+// with that target, then persists and broadcasts the outcomes like deliver
+func (h *Handler) deliverOrdered(ctx context.Context, record *request.RequestData, targets []forwarder.Target, turn *orderTurn) ([]forwarder.Result, error) {
+	started := time.Now()
+	if !turn.wait(ctx) {
+		turn.release()
+		return nil, ctx.Err()
+	}
+	turns := make([]*orderTurn, len(targets))
+	for i, target := range targets {
+		turns[i] = h.order.reserve(turn.key + "\x00" + target.URL)
+	}
+	turn.release()
+
+	cfg := h.currentConfig()
+	targets = forwarder.AttachTransforms(cfg.ForwardTransforms, targets)
+	perTarget := make([][]forwarder.Result, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer turns[i].release()
+			if !turns[i].wait(ctx) {
+				errs[i] = ctx.Err()
+				return
+			}
+			fctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ForwardOpts.Timeout)*time.Second)
+			defer cancel()
+			perTarget[i], errs[i] = h.forwarder.Forward(fctx, record, targets[i:i+1])
+		}(i)
+	}
+	wg.Wait()
+
+	var results []forwarder.Result
+	var err error
+	for i := range targets {
+		results = append(results, perTarget[i]...)
+		if err == nil {
+			err = errs[i]
+		}
+	}
+	h.timelines.forwarded(record.ID, started, results)
+	h.persistForwards(record.ID, results)
+	h.notifyForward(record.ID, results)
+	return results, err
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// gatedForwarder holds the deliveries of one body to one target until the gate opens
+type gatedForwarder struct {
+	stubForwarder
+	url, body string
+	gate      chan struct{}
+
+	mu   sync.Mutex
+	sent map[string][]string
+}
+
+func (f *gatedForwarder) Forward(ctx context.Context, data *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	for _, target := range targets {
+		if target.URL == f.url && string(data.Body) == f.body {
+			<-f.gate
+		}
+		f.mu.Lock()
+		f.sent[target.URL] = append(f.sent[target.URL], string(data.Body))
+		f.mu.Unlock()
+	}
+	return f.stubForwarder.Forward(ctx, data, targets)
+}
+
+func (f *gatedForwarder) delivered(url string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return strings.Join(f.sent[url], " ")
+}
+
+func TestForwardOrdering(t *testing.T) {
+	const slow, fast = "http://slow.test", "http://fast.test"
+	fwd := &gatedForwarder{url: slow, body: "a1", gate: make(chan struct{}), sent: map[string][]string{}}
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: slow}, {URL: fast}},
+		ForwardOpts:    ForwardOptions{Timeout: 5},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK, Body: "ok"}},
+	}
+	h := NewHandler(nil, fwd, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	if err := h.installForwardOrderStage(config.ForwardOrderingConfig{Enable: true, Header: "X-Account"}); err != nil {
+		t.Fatal(err)
+	}
+	send := func(account, body string) {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader(body))
+		if account != "" {
+			req.Header.Set("X-Account", account)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	waitFor := func(url string, count int) []string {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			got := strings.Fields(fwd.delivered(url))
+			if len(got) == count {
+				return got
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %s to receive %d requests, got %v", url, count, got)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	// before reports whether a was delivered ahead of b
+	before := func(got []string, a, b string) bool {
+		return slices.Index(got, a) >= 0 && slices.Index(got, a) < slices.Index(got, b)
+	}
+
+	send("a", "a1")
+	send("a", "a2")
+	send("b", "b1")
+	send("", "none")
+	// The slow target holds a1, so a2 waits for it there; other keys and targets go ahead
+	if got := waitFor(fast, 4); !before(got, "a1", "a2") {
+		t.Fatalf("expected a1 ahead of a2, got %v", got)
+	}
+	waitFor(slow, 2)
+	time.Sleep(20 * time.Millisecond)
+	if got := fwd.delivered(slow); strings.Contains(got, "a") {
+		t.Fatalf("expected a2 to wait for a1, got %q", got)
+	}
+
+	close(fwd.gate)
+	h.procWG.Wait()
+	if got := waitFor(slow, 4); !before(got, "a1", "a2") {
+		t.Fatalf("expected a1 then a2 once the gate opened, got %v", got)
+	}
+	if len(h.order.tails) != 0 {
+		t.Fatalf("expected every turn to be released, got %d lines", len(h.order.tails))
+	}
+}
+
+func TestForwardOrderTurnReleasedEarly(t *testing.T) {
+	order := newForwardOrder(config.ForwardOrderingConfig{JSONPath: "account.id"})
+	if key := order.key(&request.RequestData{Body: []byte(`{"account":{"id":42}}`)}); key != "42" {
+		t.Fatalf("expected the key from the JSON body, got %q", key)
+	}
+
+	first, skipped, last := order.reserve("k"), order.reserve("k"), order.reserve("k")
+	// A request dropped before forwarding releases its turn before it came up
+	skipped.release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if last.wait(ctx) {
+		t.Fatal("expected the last turn to wait for the first")
+	}
+	first.release()
+	if !last.wait(context.Background()) {
+		t.Fatal("expected the last turn to come up")
+	}
+	last.release()
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/web"
+)
+
+// CaptureNotifier is told when capture is paused or resumed, e.g. to update the web console.
+type CaptureNotifier interface {
+	NotifyCapture(state web.CaptureState)
+}
+
+// pauseStage ends the pipeline right after the mock response while capture is paused, so the
+// request is answered but neither stored, broadcast, printed nor forwarded.
+func (h *Handler) pauseStage(_ context.Context, ex *Exchange) error {
+	if !h.paused.Load() {
+		return nil
+	}
+	h.pausedSkipped.Add(1)
+	return ErrStopPipeline
+}
+
+// CaptureState reports whether capture is paused and how many requests the pause skipped.
+func (h *Handler) CaptureState() web.CaptureState {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	state := web.CaptureState{Paused: h.paused.Load(), Skipped: h.pausedSkipped.Load()}
+	if state.Paused {
+		pausedAt := h.pausedAt
+		state.PausedAt = &pausedAt
+	}
+	return state
+}
+
+// SetCapturePaused pauses or resumes capture and tells the printer and the web console.
+func (h *Handler) SetCapturePaused(paused bool) web.CaptureState {
+	h.pauseMu.Lock()
+	if h.paused.Load() == paused {
+		h.pauseMu.Unlock()
+		return h.CaptureState()
+	}
+	if paused {
+		h.pausedAt = time.Now().UTC()
+		h.pausedSkipped.Store(0)
+	}
+	h.paused.Store(paused)
+	h.pauseMu.Unlock()
+
+	state := h.CaptureState()
+	if paused {
+		h.logger.Info("Capture paused, requests are answered but not recorded")
+	} else {
+		h.logger.Info("Capture resumed", "skipped", state.Skipped)
+	}
+	if p, ok := h.currentPrinter().(printer.PausePrinter); ok {
+		p.SetPaused(paused, state.Skipped)
+	}
+	if notifier, ok := h.web.(CaptureNotifier); ok {
+		notifier.NotifyCapture(state)
+	}
+	return state
+}
+
+// ToggleCapture flips between paused and recording.
+func (h *Handler) ToggleCapture() web.CaptureState {
+	return h.SetCapturePaused(!h.paused.Load())
+}
+
+// watchPauseKey toggles capture when p is typed on the console followed by Enter. Stdin is only
+// read when it is a terminal and the terminal UI, which has its own p key, is not running.
+func (s *Server) watchPauseKey() {
+	if s.tui != nil || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+	s.logger.Info("Type p and press Enter to pause or resume capture")
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if strings.EqualFold(strings.TrimSpace(scanner.Text()), "p") {
+				s.handler.ToggleCapture()
+			}
+		}
+	}()
+}
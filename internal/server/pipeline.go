@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Built-in stage names, usable as anchors when inserting custom stages.
+const (
+	StageAccess    = "access"
+	StageCORS      = "cors"
+	StageAuth      = "auth"
+	StageCapture   = "capture"
+	StageVerify    = "verify"
+	StageScrub     = "scrub"
+	StageRespond   = "respond"
+	StagePause     = "pause"
+	StageStore     = "store"
+	StageBroadcast = "broadcast"
+	StagePrint     = "print"
+	StageForward   = "forward"
+	StageReport    = "report"
+)
+
+// Phase determines when a stage runs relative to the client response.
+type Phase int
+
+const (
+	// PhaseSync stages run on the request goroutine before the handler returns.
+	PhaseSync Phase = iota
+	// PhaseAsync stages run in the background after the client has been answered.
+	PhaseAsync
+)
+
+// ErrStopPipeline halts the remaining stages without being reported as a failure.
+var ErrStopPipeline = errors.New("pipeline stopped")
+
+// Exchange carries per-request state between pipeline stages.
+type Exchange struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	Body    []byte
+	Rule    *ImmediateResponseRule
+	Record  *request.RequestData
+	Stored  *storage.StoredRequest
+	Results []forwarder.Result
+	// Rejected marks a request refused for its body size; Record then holds metadata only
+	Rejected bool
+	// Credential names the server.auth credential the request presented
+	Credential string
+	// SkipForward keeps forwardStage from delivering the request, e.g. for suppressed duplicates
+	SkipForward bool
+	// Dropped skips the background stages once the client has been answered
+	Dropped bool
+	// Responded marks a client already answered by an earlier stage, e.g. a script
+	Responded bool
+	// Tags are stored with the request as its triage tags
+	Tags []string
+	// ForwardRecord, when set, is forwarded in place of Record, e.g. the request as received
+	// when Record was redacted
+	ForwardRecord *request.RequestData
+
+	valuesMu sync.Mutex
+	values   map[string]interface{}
+	onDone   []func()
+	// storedBodyFile is the spilled body the store took over with the record; it is deleted when
+	// the record is pruned
+	storedBodyFile string
+}
+
+// Set attaches extension-specific state to the exchange.
+func (e *Exchange) Set(key string, value interface{}) {
+	e.valuesMu.Lock()
+	defer e.valuesMu.Unlock()
+	if e.values == nil {
+		e.values = make(map[string]interface{})
+	}
+	e.values[key] = value
+}
+
+// Get reads extension-specific state from the exchange.
+func (e *Exchange) Get(key string) (interface{}, bool) {
+	e.valuesMu.Lock()
+	defer e.valuesMu.Unlock()
+	value, ok := e.values[key]
+	return value, ok
+}
+
+// OnDone registers fn to run once the background stages finished, were stopped or never started.
+func (e *Exchange) OnDone(fn func()) {
+	e.valuesMu.Lock()
+	defer e.valuesMu.Unlock()
+	e.onDone = append(e.onDone, fn)
+}
+
+// finish runs the functions registered with OnDone, the last registered first.
+func (e *Exchange) finish() {
+	e.valuesMu.Lock()
+	fns := e.onDone
+	e.onDone = nil
+	e.valuesMu.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// StageFunc processes an exchange; return ErrStopPipeline to skip the remaining stages.
+type StageFunc func(ctx context.Context, ex *Exchange) error
+
+// Stage is a named step of the processing pipeline.
+type Stage struct {
+	Name  string
+	Phase Phase
+	Run   StageFunc
+}
+
+// Pipeline is an ordered list of stages.
+type Pipeline struct {
+	mu     sync.RWMutex
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline from the given stages.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: append([]Stage(nil), stages...)}
+}
+
+// Use appends a stage at the end of the pipeline.
+func (p *Pipeline) Use(stage Stage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages = append(p.stages, stage)
+}
+
+// InsertBefore places a stage right before the named anchor.
+func (p *Pipeline) InsertBefore(anchor string, stage Stage) error {
+	return p.insert(anchor, stage, 0)
+}
+
+// InsertAfter places a stage right after the named anchor.
+func (p *Pipeline) InsertAfter(anchor string, stage Stage) error {
+	return p.insert(anchor, stage, 1)
+}
+
+// Remove drops the named stage; it reports whether a stage was removed.
+func (p *Pipeline) Remove(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, stage := range p.stages {
+		if stage.Name == name {
+			p.stages = append(p.stages[:i], p.stages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Names lists stage names in execution order.
+func (p *Pipeline) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, 0, len(p.stages))
+	for _, stage := range p.stages {
+		names = append(names, stage.Name)
+	}
+	return names
+}
+
+func (p *Pipeline) insert(anchor string, stage Stage, offset int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, existing := range p.stages {
+		if existing.Name != anchor {
+			continue
+		}
+		pos := i + offset
+		p.stages = append(p.stages, Stage{})
+		copy(p.stages[pos+1:], p.stages[pos:])
+		p.stages[pos] = stage
+		return nil
+	}
+	return fmt.Errorf("pipeline stage %q not found", anchor)
+}
+
+func (p *Pipeline) phase(phase Phase) []Stage {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var stages []Stage
+	for _, stage := range p.stages {
+		if stage.Phase == phase {
+			stages = append(stages, stage)
+		}
+	}
+	return stages
+}
+
+// run executes the stages of a phase and reports whether the pipeline was stopped.
+func (p *Pipeline) run(ctx context.Context, phase Phase, ex *Exchange, onError func(stage string, err error)) bool {
+	for _, stage := range p.phase(phase) {
+		if stage.Run == nil {
+			continue
+		}
+		if err := stage.Run(ctx, ex); err != nil {
+			if errors.Is(err, ErrStopPipeline) {
+				return true
+			}
+			if onError != nil {
+				onError(stage.Name, err)
+			}
+		}
+	}
+	return false
+}
+
+// Extension customizes the default pipeline of every handler, e.g. to insert stages.
+type Extension func(*Pipeline) error
+
+var (
+	extensionsMu sync.Mutex
+	extensions   []Extension
+)
+
+// RegisterExtension registers a compiled-in extension applied when handlers are built.
+// It is intended to be called from init functions.
+func RegisterExtension(ext Extension) {
+	if ext == nil {
+		return
+	}
+	extensionsMu.Lock()
+	extensions = append(extensions, ext)
+	extensionsMu.Unlock()
+}
+
+func registeredExtensions() []Extension {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	return append([]Extension(nil), extensions...)
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestPipelineInsertAndRemove(t *testing.T) {
+	noop := func(context.Context, *Exchange) error { return nil }
+	p := NewPipeline(
+		Stage{Name: "a", Run: noop},
+		Stage{Name: "c", Run: noop},
+	)
+
+	if err := p.InsertBefore("c", Stage{Name: "b", Run: noop}); err != nil {
+		t.Fatalf("insert before failed: %v", err)
+	}
+	if err := p.InsertAfter("c", Stage{Name: "d", Run: noop}); err != nil {
+		t.Fatalf("insert after failed: %v", err)
+	}
+	if err := p.InsertAfter("missing", Stage{Name: "x"}); err == nil {
+		t.Fatal("expected error for unknown anchor")
+	}
+	if !p.Remove("a") {
+		t.Fatal("expected stage a to be removed")
+	}
+
+	want := []string{"b", "c", "d"}
+	if got := p.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected stage order: %v", got)
+	}
+}
+
+func TestHandlerPipelineCustomStage(t *testing.T) {
+	h := NewHandler(nil, nil, noopLogger{}, &ServerConfig{Path: "/"}, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	var seenPath string
+	err := h.Pipeline().InsertBefore(StageRespond, Stage{
+		Name:  "reject-admin",
+		Phase: PhaseSync,
+		Run: func(_ context.Context, ex *Exchange) error {
+			seenPath = ex.Record.Path
+			if ex.Record.Path == "/admin" {
+				http.Error(ex.Writer, "forbidden", http.StatusForbidden)
+				return ErrStopPipeline
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("insert stage failed: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost/admin", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected custom stage to stop pipeline with 403, got %d", rr.Code)
+	}
+	if seenPath != "/admin" {
+		t.Fatalf("expected captured record before custom stage, got %q", seenPath)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost/ok", nil))
+	h.procWG.Wait()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected default response, got %d", rr.Code)
+	}
+}
+
+type stubForwarder struct{}
+
+func (stubForwarder) Forward(_ context.Context, _ *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	results := make([]forwarder.Result, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, forwarder.Result{URL: target.URL, StatusCode: http.StatusAccepted, Attempts: 1, Success: true})
+	}
+	return results, nil
+}
+
+func (stubForwarder) Stats() []forwarder.TargetStats { return nil }
+func (stubForwarder) Close()                         {}
+
+func TestHandlerJSONOutputIsOneEnvelope(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "created", Status: http.StatusCreated}},
+	}
+	h := NewHandler(p, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader("{}")))
+	h.procWG.Wait()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected exactly one JSON line, got %d: %q", len(lines), out.String())
+	}
+	var env struct {
+		StorageID string `json:"storage_id"`
+		Rule      string `json:"rule"`
+		Status    int    `json:"status"`
+		Forwards  []printer.ForwardOutcome
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &env); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if env.StorageID == "" || env.Rule != "created" || env.Status != http.StatusCreated {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+	if len(env.Forwards) != 1 || env.Forwards[0].Status != http.StatusAccepted || !env.Forwards[0].Success {
+		t.Fatalf("expected forward outcome in envelope, got %+v", env.Forwards)
+	}
+}
+
+func TestHandlerRejectsOversizedBodies(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:           "/",
+		MaxBodyBytes:   4,
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test"}},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(p, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	declared := httptest.NewRequest(http.MethodPost, "http://localhost/upload", strings.NewReader("0123456789"))
+	// Chunked bodies have no declared length and are cut off at the limit
+	chunked := httptest.NewRequest(http.MethodPost, "http://localhost/upload", io.MultiReader(strings.NewReader("0123456789")))
+	for _, req := range []*http.Request{declared, chunked} {
+		out.Reset()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		h.procWG.Wait()
+
+		if rr.Code != http.StatusRequestEntityTooLarge || rr.Header().Get("Connection") != "close" {
+			t.Fatalf("expected 413 with Connection: close, got %d %v", rr.Code, rr.Header())
+		}
+		var env struct {
+			Rule     string                   `json:"rule"`
+			Status   int                      `json:"status"`
+			Request  request.RequestData      `json:"request"`
+			Forwards []printer.ForwardOutcome `json:"forwards"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+			t.Fatalf("expected the rejected request to be recorded: %v (%q)", err, out.String())
+		}
+		if env.Rule != bodyTooLargeRule || env.Status != http.StatusRequestEntityTooLarge || len(env.Request.Body) != 0 {
+			t.Fatalf("unexpected record of rejected request: %+v", env)
+		}
+		if len(env.Forwards) != 0 {
+			t.Fatal("rejected requests must not be forwarded")
+		}
+	}
+}
+
+func TestHandlerRefusesPathsOutsideCaptureBeforeReadingBody(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{Path: "/reqtap", MaxBodyBytes: 4, Responses: []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}}}
+	h := NewHandler(p, nil, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	body := strings.NewReader("0123456789")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "http://localhost/other", body))
+	h.procWG.Wait()
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a path outside the capture path, got %d", rr.Code)
+	}
+	if body.Len() != 10 || out.Len() != 0 {
+		t.Fatalf("expected the body to stay unread and nothing recorded, read %d bytes, output %q", 10-body.Len(), out.String())
+	}
+}
+
+// blockingPrinter holds every print until release is closed
+type blockingPrinter struct {
+	release chan struct{}
+}
+
+func (p blockingPrinter) PrintRequest(*request.RequestData) error {
+	<-p.release
+	return nil
+}
+
+// signalForwarder reports every delivery on forwarded
+type signalForwarder struct {
+	stubForwarder
+	forwarded chan string
+}
+
+func (f signalForwarder) Forward(ctx context.Context, data *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	f.forwarded <- data.ID
+	return f.stubForwarder.Forward(ctx, data, targets)
+}
+
+func TestHandlerForwardsWhilePrinting(t *testing.T) {
+	p := blockingPrinter{release: make(chan struct{})}
+	f := signalForwarder{forwarded: make(chan string, 1)}
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(p, f, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader("{}")))
+	select {
+	case <-f.forwarded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected forwarding not to wait for a slow printer")
+	}
+	close(p.release)
+	h.procWG.Wait()
+}
+
+func TestHandlerReforwardAppliesFilters(t *testing.T) {
+	cfg := &ServerConfig{
+		Path: "/",
+		ForwardTargets: []forwarder.Target{
+			{URL: "http://primary.test"},
+			{URL: "http://audit.test"},
+		},
+		ForwardFilters: []forwarder.Filter{
+			{Deny: true, Targets: []string{"http://audit.test"}, Path: regexp.MustCompile(`^/internal`)},
+		},
+		ForwardOpts: ForwardOptions{Timeout: 1},
+	}
+	h := NewHandler(nil, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	results, err := h.Reforward(context.Background(), &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/internal/hook"})
+	if err != nil {
+		t.Fatalf("reforward failed: %v", err)
+	}
+	if len(results) != 1 || results[0].URL != "http://primary.test" || !results[0].Success {
+		t.Fatalf("expected delivery to the unfiltered target only, got %+v", results)
+	}
+
+	cfg.ForwardFilters[0].Targets = nil
+	if _, err := h.Reforward(context.Background(), &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/internal/hook"}); !errors.Is(err, forwarder.ErrNoTargets) {
+		t.Fatalf("expected ErrNoTargets when every target is filtered, got %v", err)
+	}
+}
+
+func TestDedupStageSuppressesForwardingDuplicates(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(p, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	if err := h.installDedupStage(config.DedupConfig{Enable: true, Window: time.Minute, SuppressForward: true}); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader(`{"id":1}`)))
+		h.procWG.Wait()
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two JSON lines, got %q", out.String())
+	}
+	var envs [2]struct {
+		Request  request.RequestData
+		Forwards []printer.ForwardOutcome
+	}
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &envs[i]); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+	}
+	if envs[0].Request.DuplicateOf != "" || len(envs[0].Forwards) != 1 {
+		t.Fatalf("expected the first request to be forwarded, got %+v", envs[0])
+	}
+	if envs[1].Request.DuplicateOf != envs[0].Request.ID || envs[1].Request.Fingerprint != envs[0].Request.Fingerprint {
+		t.Fatalf("expected the copy to be marked as a duplicate of %s, got %q", envs[0].Request.ID, envs[1].Request.DuplicateOf)
+	}
+	if len(envs[1].Forwards) != 0 {
+		t.Fatalf("expected the duplicate not to be forwarded, got %+v", envs[1].Forwards)
+	}
+}
+
+func TestDedupStageForgetsRequestsAnsweredWhilePaused(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(p, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	if err := h.installDedupStage(config.DedupConfig{Enable: true, Window: time.Minute, SuppressForward: true}); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	send := func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader(`{"id":1}`)))
+		h.procWG.Wait()
+	}
+
+	h.SetCapturePaused(true)
+	send()
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing captured while paused, got %q", out.String())
+	}
+	h.SetCapturePaused(false)
+	send()
+
+	var env struct {
+		Request  request.RequestData
+		Forwards []printer.ForwardOutcome
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("invalid json: %v (%q)", err, out.String())
+	}
+	if env.Request.DuplicateOf != "" || len(env.Forwards) != 1 {
+		t.Fatalf("expected the re-delivery to be captured and forwarded as the first copy, got %+v", env)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/funnyzak/reqtap/internal/plugin"
+	sdk "github.com/funnyzak/reqtap/pkg/plugin"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Pipeline stages contributed by plugins.
+const (
+	StagePluginTransform = "plugin-transform"
+	StagePluginExport    = "plugin-export"
+)
+
+// applyTransformResult swaps in a transformed record; transforms cannot reassign
+// identity, the mock response that was already sent or the spill file. The record reaches
+// transforms as JSON, which leaves out the body as received: an unchanged body keeps it, a
+// changed one is sent as it is, without the received Content-Encoding.
+func applyTransformResult(ex *Exchange, result *request.RequestData) {
+	original := ex.Record
+	result.ID = original.ID
+	result.MockResponse = original.MockResponse
+	if bytes.Equal(result.Body, original.Body) {
+		result.WireBody = original.WireBody
+		result.BodyFile = original.BodyFile
+	} else {
+		result.BodyFile = ""
+		result.Size = int64(len(result.Body))
+		result.ContentLength = result.Size
+		result.WireBody = nil
+		result.WireSize = 0
+		if result.ContentEncoding != "" {
+			result.Headers.Del("Content-Encoding")
+			result.ContentEncoding = ""
+		}
+	}
+	ex.Record = result
+}
+
+// installPluginStages wires transform plugins before persistence and export plugins after it.
+func (h *Handler) installPluginStages(manager *plugin.Manager) error {
+	if transformers := manager.WithHook(sdk.HookTransform); len(transformers) > 0 {
+		stage := Stage{Name: StagePluginTransform, Phase: PhaseAsync, Run: func(ctx context.Context, ex *Exchange) error {
+			for _, p := range transformers {
+				result, drop, err := p.Transform(ctx, ex.Record)
+				if err != nil {
+					h.logger.Error("Plugin transform failed", "plugin", p.Name(), "error", err, "request_id", ex.Record.ID)
+					continue
+				}
+				if drop {
+					h.logger.Debug("Request dropped by plugin", "plugin", p.Name(), "request_id", ex.Record.ID)
+					return ErrStopPipeline
+				}
+				applyTransformResult(ex, result)
+			}
+			return nil
+		}}
+		if err := h.pipeline.InsertBefore(StageStore, stage); err != nil {
+			return err
+		}
+	}
+
+	if exporters := manager.WithHook(sdk.HookExport); len(exporters) > 0 {
+		stage := Stage{Name: StagePluginExport, Phase: PhaseAsync, Run: func(ctx context.Context, ex *Exchange) error {
+			for _, p := range exporters {
+				if err := p.Export(ctx, ex.Record); err != nil {
+					h.logger.Error("Plugin export failed", "plugin", p.Name(), "error", err, "request_id", ex.Record.ID)
+				}
+			}
+			return nil
+		}}
+		if err := h.pipeline.InsertAfter(StageStore, stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestTransformResultOfGzipRequest(t *testing.T) {
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	fwd := &recordingForwarder{}
+	h := NewHandler(nil, fwd, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	// Plugin and WASM transforms receive and return the record as JSON
+	transform := Stage{Name: "json-transform", Phase: PhaseAsync, Run: func(_ context.Context, ex *Exchange) error {
+		raw, err := json.Marshal(ex.Record)
+		if err != nil {
+			return err
+		}
+		var result request.RequestData
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return err
+		}
+		if ex.Record.Path == "/edit" {
+			result.Body = []byte(`{"a":2}`)
+		}
+		applyTransformResult(ex, &result)
+		return nil
+	}}
+	if err := h.pipeline.InsertBefore(StageStore, transform); err != nil {
+		t.Fatal(err)
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"a":1}`))
+	gz.Close()
+	for _, path := range []string{"/same", "/edit"} {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, bytes.NewReader(compressed.Bytes()))
+		req.Header.Set("Content-Encoding", "gzip")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		h.procWG.Wait()
+	}
+
+	fwd.mu.Lock()
+	defer fwd.mu.Unlock()
+	if len(fwd.sent) != 2 {
+		t.Fatalf("expected both requests to be forwarded, got %d", len(fwd.sent))
+	}
+	same := fwd.sent[0]
+	if !bytes.Equal(same.ForwardBody(), compressed.Bytes()) || same.Headers.Get("Content-Encoding") != "gzip" || string(same.Body) != `{"a":1}` {
+		t.Fatalf("expected an unchanged body to be forwarded gzipped, got %q %v", same.ForwardBody(), same.Headers)
+	}
+	edited := fwd.sent[1]
+	if string(edited.ForwardBody()) != `{"a":2}` || edited.Headers.Get("Content-Encoding") != "" || edited.ContentEncoding != "" || edited.Size != 7 {
+		t.Fatalf("expected a changed body to be forwarded plain, got %q %v", edited.ForwardBody(), edited.Headers)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/redact"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// StageRedact masks the values of privacy.redact before the request is stored.
+const StageRedact = "redact"
+
+// RedactOptions are the privacy.redact settings
+type RedactOptions struct {
+	Rules redact.Rules
+	// PreserveForward keeps an unmasked copy of the request for forwarding
+	PreserveForward bool
+}
+
+func buildRedactOptions(cfg config.RedactConfig) RedactOptions {
+	opts := RedactOptions{
+		Rules:           redact.Rules{Headers: cfg.Headers, Fields: cfg.Fields},
+		PreserveForward: cfg.PreserveForward,
+	}
+	for _, pattern := range cfg.Patterns {
+		opts.Rules.Patterns = append(opts.Rules.Patterns, regexp.MustCompile(pattern))
+	}
+	return opts
+}
+
+// installRedactStage masks the record right before it is stored, after the stages that transform
+// or decode it, so storage, the console, live subscribers and exports only ever see masked values.
+// The rules are read per request, so reloads apply immediately.
+func (h *Handler) installRedactStage() error {
+	return h.pipeline.InsertBefore(StageStore, Stage{Name: StageRedact, Phase: PhaseAsync, Run: h.redactStage})
+}
+
+// redactStage masks the record in place; with preserve_forward the record as received is kept for forwarding.
+func (h *Handler) redactStage(_ context.Context, ex *Exchange) error {
+	opts := h.currentConfig().Redact
+	if opts.Rules.Empty() || ex.Record == nil {
+		return nil
+	}
+	var original *request.RequestData
+	if opts.PreserveForward {
+		// Masking replaces the body and header values, so a shallow copy with its own headers is enough
+		received := *ex.Record
+		received.Headers = ex.Record.Headers.Clone()
+		original = &received
+	}
+	if opts.Rules.Apply(ex.Record) && original != nil && ex.ForwardRecord == nil {
+		ex.ForwardRecord = original
+	}
+	if !opts.PreserveForward && ex.ForwardRecord != nil {
+		// A forward copy made by a script is masked like the record
+		opts.Rules.Apply(ex.ForwardRecord)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// recordingForwarder remembers the requests it was asked to deliver
+type recordingForwarder struct {
+	stubForwarder
+	mu   sync.Mutex
+	sent []*request.RequestData
+}
+
+func (f *recordingForwarder) Forward(ctx context.Context, data *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	f.mu.Lock()
+	f.sent = append(f.sent, data)
+	f.mu.Unlock()
+	return f.stubForwarder.Forward(ctx, data, targets)
+}
+
+func TestRedactStage(t *testing.T) {
+	for _, preserve := range []bool{false, true} {
+		store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		redactCfg := config.RedactConfig{
+			Headers:         []string{"Authorization"},
+			Fields:          []string{"card.number"},
+			Patterns:        []string{`sk_live_[A-Za-z0-9]+`},
+			PreserveForward: preserve,
+		}
+		cfg := &ServerConfig{
+			Path:           "/",
+			ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+			ForwardOpts:    ForwardOptions{Timeout: 1},
+			Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+			Redact:         buildRedactOptions(redactCfg),
+		}
+		fwd := &recordingForwarder{}
+		h := NewHandler(nil, fwd, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+		if err := h.installRedactStage(); err != nil {
+			t.Fatalf("install: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/charge?key=sk_live_abc123", strings.NewReader(`{"card":{"number":"4242424242424242"}}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		h.procWG.Wait()
+
+		items, _, err := store.List(storage.ListOptions{})
+		if err != nil || len(items) != 1 {
+			t.Fatalf("expected the request to be stored, got %d (%v)", len(items), err)
+		}
+		stored := items[0]
+		if stored.Headers.Get("Authorization") != "[REDACTED]" || stored.Query != "key=[REDACTED]" || strings.Contains(string(stored.Body), "4242") {
+			t.Fatalf("expected the stored request to be masked, got %v %q %s", stored.Headers, stored.Query, stored.Body)
+		}
+		if len(fwd.sent) != 1 {
+			t.Fatalf("expected one delivery, got %d", len(fwd.sent))
+		}
+		sent := fwd.sent[0]
+		if preserve && (sent.Headers.Get("Authorization") != "Bearer secret" || !strings.Contains(string(sent.Body), "4242") || sent.Query != "key=sk_live_abc123") {
+			t.Fatalf("expected the original request to be forwarded, got %v %s", sent.Headers, sent.Body)
+		}
+		if !preserve && sent.Headers.Get("Authorization") != "[REDACTED]" {
+			t.Fatalf("expected the masked request to be forwarded, got %v", sent.Headers)
+		}
+		store.Close()
+	}
+}
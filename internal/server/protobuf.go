@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/grpccapture"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// StageProtobufDecode decodes protobuf request bodies for the console, web and export views.
+const StageProtobufDecode = "protobuf-decode"
+
+// protobufMediaTypes are decoded without a schema when no configured message matches
+var protobufMediaTypes = map[string]bool{
+	"application/protobuf":            true,
+	"application/x-protobuf":          true,
+	"application/x-protobuffer":       true,
+	"application/vnd.google.protobuf": true,
+}
+
+type protobufMessage struct {
+	contentType string
+	pathPrefix  string
+	desc        protoreflect.MessageDescriptor
+}
+
+// protobufDecoder picks the message type of a request body from output.body_view.protobuf.
+type protobufDecoder struct {
+	messages []protobufMessage
+}
+
+// newProtobufDecoder loads the descriptor sets and resolves the configured message types; it
+// returns nil when protobuf decoding is disabled.
+func newProtobufDecoder(cfg config.ProtobufViewConfig) (*protobufDecoder, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	schemas := grpccapture.NewSchemas()
+	for _, path := range cfg.DescriptorSets {
+		if err := schemas.LoadDescriptorSet(path); err != nil {
+			return nil, fmt.Errorf("output.body_view.protobuf: %w", err)
+		}
+	}
+	decoder := &protobufDecoder{}
+	for _, msg := range cfg.Messages {
+		desc := schemas.Message(msg.Message)
+		if desc == nil {
+			return nil, fmt.Errorf("output.body_view.protobuf: message %s is not defined in the descriptor sets", msg.Message)
+		}
+		decoder.messages = append(decoder.messages, protobufMessage{contentType: msg.ContentType, pathPrefix: msg.PathPrefix, desc: desc})
+	}
+	return decoder, nil
+}
+
+// decode returns the decoded body, or nil when the request is not a protobuf request.
+func (d *protobufDecoder) decode(record *request.RequestData) *request.ProtobufBody {
+	if len(record.Body) == 0 {
+		return nil
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(record.ContentType))
+	if parsed, _, err := mime.ParseMediaType(record.ContentType); err == nil {
+		mediaType = parsed
+	}
+	var desc protoreflect.MessageDescriptor
+	for _, msg := range d.messages {
+		if (msg.contentType == "" || msg.contentType == mediaType) && strings.HasPrefix(record.Path, msg.pathPrefix) {
+			desc = msg.desc
+			break
+		}
+	}
+	if desc == nil && !protobufMediaTypes[mediaType] {
+		return nil
+	}
+
+	decoded := &request.ProtobufBody{Raw: desc == nil}
+	if desc != nil {
+		decoded.Message = string(desc.FullName())
+	}
+	if record.BodyFile != "" {
+		decoded.Error = "body was spilled to disk and is not decoded"
+		return decoded
+	}
+	out, err := grpccapture.DecodeMessage(record.Body, desc)
+	if err != nil {
+		decoded.Error = err.Error()
+		return decoded
+	}
+	decoded.JSON = out
+	return decoded
+}
+
+// installProtobufStage decodes protobuf bodies before the request is persisted, after any
+// WebAssembly transform rewrote them.
+func (h *Handler) installProtobufStage(decoder *protobufDecoder) error {
+	if decoder == nil {
+		return nil
+	}
+	return h.pipeline.InsertBefore(StageStore, Stage{Name: StageProtobufDecode, Phase: PhaseAsync, Run: func(_ context.Context, ex *Exchange) error {
+		if ex.Record.GRPC == nil {
+			ex.Record.Protobuf = decoder.decode(ex.Record)
+		}
+		return nil
+	}})
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// writeEventDescriptorSet writes a descriptor set with demo.Event{name, count} to a temp file.
+func writeEventDescriptorSet(t *testing.T) string {
+	t.Helper()
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("event.proto"),
+		Package: proto.String("demo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("count"), JsonName: proto.String("count"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}}}
+	raw, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "event.pb")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func eventMessage(name string, count uint64) []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	return protowire.AppendVarint(b, count)
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	decoder, err := newProtobufDecoder(config.ProtobufViewConfig{
+		Enable:         true,
+		DescriptorSets: []string{writeEventDescriptorSet(t)},
+		Messages:       []config.ProtobufMessageConfig{{PathPrefix: "/events", Message: "demo.Event"}},
+	})
+	if err != nil {
+		t.Fatalf("newProtobufDecoder: %v", err)
+	}
+	body := eventMessage("signup", 2)
+
+	decoded := decoder.decode(&request.RequestData{Path: "/events/ingest", ContentType: "application/octet-stream", Body: body})
+	var got map[string]interface{}
+	if decoded == nil || decoded.Message != "demo.Event" || decoded.Raw {
+		t.Fatalf("expected a demo.Event decode, got %+v", decoded)
+	}
+	if err := json.Unmarshal(decoded.JSON, &got); err != nil || got["name"] != "signup" || got["count"] != float64(2) {
+		t.Fatalf("unexpected decode: %s (%v)", decoded.JSON, err)
+	}
+
+	raw := decoder.decode(&request.RequestData{Path: "/other", ContentType: "application/x-protobuf; proto=demo.Event", Body: body})
+	if raw == nil || !raw.Raw || raw.Message != "" {
+		t.Fatalf("expected a schemaless decode, got %+v", raw)
+	}
+	if err := json.Unmarshal(raw.JSON, &got); err != nil || got["1"] != "signup" {
+		t.Fatalf("unexpected raw decode: %s (%v)", raw.JSON, err)
+	}
+
+	if other := decoder.decode(&request.RequestData{Path: "/other", ContentType: "application/json", Body: []byte(`{}`)}); other != nil {
+		t.Fatalf("expected non-protobuf bodies to be skipped, got %+v", other)
+	}
+
+	if _, err := newProtobufDecoder(config.ProtobufViewConfig{
+		Enable:         true,
+		DescriptorSets: []string{writeEventDescriptorSet(t)},
+		Messages:       []config.ProtobufMessageConfig{{PathPrefix: "/", Message: "demo.Missing"}},
+	}); err == nil {
+		t.Fatal("expected an unknown message type to be rejected")
+	}
+}
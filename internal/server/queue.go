@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// queueBatchSize bounds how many due deliveries one poll of the forward queue retries.
+const queueBatchSize = 100
+
+// ForwardQueueOptions controls persisting and retrying deliveries that failed every attempt
+type ForwardQueueOptions struct {
+	Enable       bool
+	PollInterval time.Duration
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	// MaxAttempts drops a delivery after that many queue retries; 0 retries forever
+	MaxAttempts int
+}
+
+// QueueReport counts the outcomes of one pass over the forward queue.
+type QueueReport struct {
+	Delivered   int `json:"delivered"`
+	Rescheduled int `json:"rescheduled"`
+	Dropped     int `json:"dropped"`
+}
+
+type queueOutcome int
+
+const (
+	queueDelivered queueOutcome = iota
+	queueRescheduled
+	queueDropped
+	queueInterrupted
+)
+
+// enqueueFailed persists deliveries that failed every attempt so the queue worker retries them
+func (h *Handler) enqueueFailed(requestID string, results []forwarder.Result) {
+	opts := h.currentConfig().ForwardQueue
+	if !opts.Enable || h.store == nil {
+		return
+	}
+	for _, res := range results {
+		if res.Success {
+			continue
+		}
+		item := &storage.QueuedForward{
+			RequestID:   requestID,
+			TargetURL:   res.URL,
+			LastError:   res.Error,
+			NextAttempt: time.Now().Add(max(opts.Backoff, res.RetryAfter)),
+		}
+		if err := h.store.EnqueueForward(item); err != nil {
+			h.logger.Error("Failed to queue forward for retry", "error", err, "request_id", requestID, "url", res.URL)
+			continue
+		}
+		h.logger.Info("Forward queued for retry",
+			"request_id", requestID,
+			"url", res.URL,
+			"queue_id", item.ID,
+			"next_attempt", item.NextAttempt,
+		)
+	}
+}
+
+// StartForwardQueue retries due queued deliveries every poll interval until the base context ends.
+// Deliveries left over from a previous run are picked up on the first poll.
+func (h *Handler) StartForwardQueue() {
+	opts := h.currentConfig().ForwardQueue
+	if !opts.Enable || h.store == nil || h.forwarder == nil {
+		return
+	}
+	h.procWG.Add(1)
+	go func() {
+		defer h.procWG.Done()
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+		for {
+			h.retryQueued(h.baseCtx, time.Now(), queueBatchSize)
+			select {
+			case <-h.baseCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// FlushForwardQueue retries every queued delivery once, regardless of its schedule.
+func (h *Handler) FlushForwardQueue(ctx context.Context) (QueueReport, error) {
+	if h.store == nil || h.forwarder == nil {
+		return QueueReport{}, fmt.Errorf("forward queue requires storage and a forwarder")
+	}
+	return h.retryQueued(ctx, time.Time{}, 0)
+}
+
+func (h *Handler) retryQueued(ctx context.Context, due time.Time, limit int) (QueueReport, error) {
+	var report QueueReport
+	items, err := h.store.QueuedForwards(due, limit)
+	if err != nil {
+		h.logger.Error("Failed to read forward queue", "error", err)
+		return report, err
+	}
+	opts := h.currentConfig().ForwardQueue
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		switch h.retryQueuedForward(ctx, item, opts) {
+		case queueDelivered:
+			report.Delivered++
+		case queueRescheduled:
+			report.Rescheduled++
+		case queueDropped:
+			report.Dropped++
+		case queueInterrupted:
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+func (h *Handler) retryQueuedForward(ctx context.Context, item *storage.QueuedForward, opts ForwardQueueOptions) queueOutcome {
+	stored, err := h.store.Get(item.RequestID)
+	if err != nil {
+		h.logger.Error("Failed to load queued request", "error", err, "request_id", item.RequestID)
+		return queueRescheduled
+	}
+	if stored == nil {
+		// Pruned by retention or max_records; nothing is left to deliver
+		h.dequeue(item)
+		return queueDropped
+	}
+
+	results, err := h.deliver(ctx, stored.RequestData, []forwarder.Target{h.queueTarget(stored.Path, item.TargetURL)})
+	if ctx.Err() != nil {
+		// Shutting down: leave the delivery due so the next run picks it up
+		return queueInterrupted
+	}
+	item.Attempts++
+	var retryAfter time.Duration
+	switch {
+	case err != nil:
+		item.LastError = err.Error()
+	case len(results) > 0 && results[0].Success:
+		h.dequeue(item)
+		h.logger.Info("Queued forward delivered",
+			"request_id", item.RequestID,
+			"url", item.TargetURL,
+			"queue_attempts", item.Attempts,
+		)
+		return queueDelivered
+	case len(results) > 0:
+		item.LastError = results[0].Error
+		retryAfter = results[0].RetryAfter
+	}
+
+	if opts.MaxAttempts > 0 && item.Attempts >= opts.MaxAttempts {
+		h.dequeue(item)
+		h.logger.Error("Queued forward dropped after max attempts",
+			"request_id", item.RequestID,
+			"url", item.TargetURL,
+			"queue_attempts", item.Attempts,
+			"last_error", item.LastError,
+		)
+		return queueDropped
+	}
+	// A target that asked for more time than the queue backoff gets it
+	item.NextAttempt = time.Now().Add(max(queueBackoff(opts, item.Attempts), retryAfter))
+	if err := h.store.RescheduleForward(item); err != nil {
+		h.logger.Error("Failed to reschedule queued forward", "error", err, "request_id", item.RequestID, "url", item.TargetURL)
+	}
+	return queueRescheduled
+}
+
+func (h *Handler) dequeue(item *storage.QueuedForward) {
+	if err := h.store.DequeueForward(item.ID); err != nil {
+		h.logger.Error("Failed to remove queued forward", "error", err, "request_id", item.RequestID, "url", item.TargetURL)
+	}
+}
+
+// queueTarget returns the target configured for url on path, or a bare one when it was removed from the config
+func (h *Handler) queueTarget(path, url string) forwarder.Target {
+	for _, target := range h.currentConfig().forwardTargetsFor(path) {
+		if target.URL == url {
+			return target
+		}
+	}
+	return forwarder.Target{URL: url}
+}
+
+// queueBackoff doubles the base backoff for every failed queue retry, up to the maximum
+func queueBackoff(opts ForwardQueueOptions, attempts int) time.Duration {
+	delay := opts.Backoff
+	for i := 0; i < attempts && delay < opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > opts.MaxBackoff {
+		delay = opts.MaxBackoff
+	}
+	return delay
+}
+
+// ForwardQueue gives the queue subcommands access to the persisted forward queue without
+// starting the listener.
+type ForwardQueue struct {
+	handler   *Handler
+	store     storage.Store
+	forwarder *forwarder.Forwarder
+}
+
+// OpenForwardQueue opens the configured storage and a forwarder for the forward queue.
+func OpenForwardQueue(cfg *config.Config, log logger.Logger) (*ForwardQueue, error) {
+	if cfg.Storage.Driver == "plugin" {
+		return nil, fmt.Errorf("forward queue requires the sqlite or bolt storage driver")
+	}
+	store, err := storage.New(&cfg.Storage, log)
+	if err != nil {
+		return nil, err
+	}
+	fwd := forwarder.NewForwarder(log, buildForwarderOptions(cfg))
+	handler := NewHandler(nil, fwd, log, buildServerConfig(cfg), store, nil, context.Background(), &sync.WaitGroup{})
+	return &ForwardQueue{handler: handler, store: store, forwarder: fwd}, nil
+}
+
+// List returns every queued delivery, soonest first.
+func (q *ForwardQueue) List() ([]*storage.QueuedForward, error) {
+	return q.store.QueuedForwards(time.Time{}, 0)
+}
+
+// Flush retries every queued delivery once, see Handler.FlushForwardQueue.
+func (q *ForwardQueue) Flush(ctx context.Context) (QueueReport, error) {
+	return q.handler.FlushForwardQueue(ctx)
+}
+
+// Close releases the forwarder and storage.
+func (q *ForwardQueue) Close() error {
+	q.forwarder.Close()
+	return q.store.Close()
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// flakyForwarder fails every delivery until up is set; retryAfter is reported with the failures.
+type flakyForwarder struct {
+	mu         sync.Mutex
+	up         bool
+	calls      int
+	retryAfter time.Duration
+}
+
+func (f *flakyForwarder) Forward(_ context.Context, _ *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	results := make([]forwarder.Result, 0, len(targets))
+	for _, target := range targets {
+		if f.up {
+			results = append(results, forwarder.Result{URL: target.URL, StatusCode: http.StatusOK, Attempts: 1, Success: true})
+		} else {
+			results = append(results, forwarder.Result{URL: target.URL, Attempts: 1, Error: "connection refused", RetryAfter: f.retryAfter})
+		}
+	}
+	return results, nil
+}
+
+func (f *flakyForwarder) Stats() []forwarder.TargetStats { return nil }
+func (f *flakyForwarder) Close()                         {}
+
+func TestForwardQueueRetriesFailedDeliveries(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	record := &request.RequestData{ID: "REQ", Method: http.MethodPost, Path: "/hook", Timestamp: time.Now()}
+	if _, err := store.Record(record); err != nil {
+		t.Fatalf("failed to record request: %v", err)
+	}
+
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test"}},
+		ForwardOpts:    ForwardOptions{Timeout: 5},
+		ForwardQueue:   ForwardQueueOptions{Enable: true, Backoff: time.Minute, MaxBackoff: time.Hour, MaxAttempts: 3},
+	}
+	fwd := &flakyForwarder{}
+	h := NewHandler(nil, fwd, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	if err := h.forwardStage(context.Background(), &Exchange{Record: record}); err != nil {
+		t.Fatalf("forward stage failed: %v", err)
+	}
+	queued, err := store.QueuedForwards(time.Time{}, 0)
+	if err != nil || len(queued) != 1 || queued[0].LastError != "connection refused" {
+		t.Fatalf("expected the failed delivery to be queued, got %+v (%v)", queued, err)
+	}
+	if due, _ := store.QueuedForwards(time.Now(), 0); len(due) != 0 {
+		t.Fatalf("expected the retry to wait for the backoff, got %+v", due)
+	}
+
+	report, err := h.FlushForwardQueue(context.Background())
+	if err != nil || report.Rescheduled != 1 {
+		t.Fatalf("expected one rescheduled delivery, got %+v (%v)", report, err)
+	}
+	queued, _ = store.QueuedForwards(time.Time{}, 0)
+	if len(queued) != 1 || queued[0].Attempts != 1 || time.Until(queued[0].NextAttempt) < time.Minute {
+		t.Fatalf("expected attempts and backoff to grow, got %+v", queued)
+	}
+
+	// A target asking for more time than the queue backoff gets it
+	fwd.mu.Lock()
+	fwd.retryAfter = 2 * time.Hour
+	fwd.mu.Unlock()
+	if _, err := h.FlushForwardQueue(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	queued, _ = store.QueuedForwards(time.Time{}, 0)
+	if len(queued) != 1 || time.Until(queued[0].NextAttempt) < 119*time.Minute {
+		t.Fatalf("expected the retry to wait for Retry-After, got %+v", queued)
+	}
+
+	fwd.mu.Lock()
+	fwd.up = true
+	fwd.mu.Unlock()
+	report, err = h.FlushForwardQueue(context.Background())
+	if err != nil || report.Delivered != 1 {
+		t.Fatalf("expected one delivered forward, got %+v (%v)", report, err)
+	}
+	if queued, _ = store.QueuedForwards(time.Time{}, 0); len(queued) != 0 {
+		t.Fatalf("expected an empty queue, got %+v", queued)
+	}
+	forwards, _ := store.GetForwards("REQ")
+	if len(forwards) != 4 || !forwards[3].Success {
+		t.Fatalf("expected every attempt in the forward history, got %d", len(forwards))
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	opts := ForwardQueueOptions{Backoff: 10 * time.Second, MaxBackoff: time.Minute}
+	for attempts, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := queueBackoff(opts, attempts); got != want {
+			t.Errorf("attempts %d: expected %s, got %s", attempts, want, got)
+		}
+	}
+}
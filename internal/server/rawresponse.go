@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// writeRawResponse takes over the connection to send a status line net/http cannot produce: a custom
+// reason phrase and/or an HTTP/1.0 version. The body is sent with Content-Length (never chunked) and
+// the connection is closed afterwards, which is what HTTP/1.0 clients expect.
+func writeRawResponse(w http.ResponseWriter, r *http.Request, rule *ImmediateResponseRule, status int, body []byte) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return errors.New("connection cannot be taken over (HTTP/2?)")
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	proto := "HTTP/1.1"
+	if rule.HTTP10 {
+		proto = "HTTP/1.0"
+	}
+	reason := rule.StatusText
+	if reason == "" {
+		reason = http.StatusText(status)
+	}
+
+	header := w.Header().Clone()
+	header.Del("Transfer-Encoding")
+	header.Set("Connection", "close")
+	if header.Get("Date") == "" {
+		header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	bodyAllowed := status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+	if bodyAllowed {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	} else {
+		header.Del("Content-Length")
+	}
+
+	// Once the connection is taken over a failed write cannot fall back to net/http, so errors end here
+	fmt.Fprintf(buf, "%s %03d %s\r\n", proto, status, reason)
+	_ = header.Write(buf)
+	buf.WriteString("\r\n")
+	if bodyAllowed && r.Method != http.MethodHead {
+		buf.Write(body)
+	}
+	_ = buf.Flush()
+	return nil
+}
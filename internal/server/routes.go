@@ -0,0 +1,96 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+)
+
+// CaptureRoute is one server.paths prefix with its own mock rules and forward targets
+type CaptureRoute struct {
+	Prefix string
+	// Responses replace ServerConfig.Responses for this prefix; nil inherits them
+	Responses []ImmediateResponseRule
+	// ForwardTargets are the route's own targets, or copies of the global ones when it has none
+	ForwardTargets []forwarder.Target
+}
+
+// buildCaptureRoutes resolves server.paths. With the strip_prefix path strategy and no explicit
+// strip_prefix, every target strips the prefix of the route it was reached through.
+func buildCaptureRoutes(cfg *config.Config) []CaptureRoute {
+	if len(cfg.Server.Paths) == 0 {
+		return nil
+	}
+	stripRoute := strings.EqualFold(cfg.Forward.PathStrategy.Mode, "strip_prefix") && cfg.Forward.PathStrategy.StripPrefix == ""
+	routes := make([]CaptureRoute, 0, len(cfg.Server.Paths))
+	for _, p := range cfg.Server.Paths {
+		route := CaptureRoute{Prefix: p.Path}
+		if len(p.Responses) > 0 {
+			route.Responses = convertImmediateResponseConfigs(p.Responses)
+		}
+		targets := cfg.Forward.ResolvedTargets()
+		if len(p.Forward.URLs) > 0 || len(p.Forward.Targets) > 0 {
+			own := config.ForwardConfig{URLs: p.Forward.URLs, Targets: p.Forward.Targets, LatencyBudget: cfg.Forward.LatencyBudget, Expectations: cfg.Forward.Expectations}
+			targets = own.ResolvedTargets()
+		}
+		route.ForwardTargets = convertForwardTargets(targets)
+		if stripRoute {
+			for i := range route.ForwardTargets {
+				route.ForwardTargets[i].StripPrefix = p.Path
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// matchRoute returns the route with the longest prefix of path, or nil
+func (c *ServerConfig) matchRoute(path string) *CaptureRoute {
+	var best *CaptureRoute
+	for i := range c.Routes {
+		route := &c.Routes[i]
+		if route.Prefix != "/" && !strings.HasPrefix(path, route.Prefix) {
+			continue
+		}
+		if best == nil || len(route.Prefix) > len(best.Prefix) {
+			best = route
+		}
+	}
+	return best
+}
+
+// responsesFor returns the mock rules that apply to path
+func (c *ServerConfig) responsesFor(path string) []ImmediateResponseRule {
+	if route := c.matchRoute(path); route != nil && route.Responses != nil {
+		return route.Responses
+	}
+	return c.Responses
+}
+
+// forwardTargetsFor returns the forward targets that apply to path
+func (c *ServerConfig) forwardTargetsFor(path string) []forwarder.Target {
+	if route := c.matchRoute(path); route != nil {
+		return route.ForwardTargets
+	}
+	return c.ForwardTargets
+}
+
+// healthCheckURLs lists every distinct forward target URL, including those of routes
+func (c *ServerConfig) healthCheckURLs() []string {
+	urls := targetURLs(c.ForwardTargets)
+	seen := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		seen[url] = struct{}{}
+	}
+	for _, route := range c.Routes {
+		for _, target := range route.ForwardTargets {
+			if _, ok := seen[target.URL]; ok {
+				continue
+			}
+			seen[target.URL] = struct{}{}
+			urls = append(urls, target.URL)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestCaptureRoutesPickLongestPrefix(t *testing.T) {
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("failed to load defaults: %v", err)
+	}
+	cfg.Forward.URLs = []string{"http://shared.test"}
+	cfg.Forward.PathStrategy.Mode = "strip_prefix"
+	cfg.Server.Paths = []config.CapturePathConfig{
+		{Path: "/github", Forward: config.CapturePathForwardConfig{URLs: []string{"http://github.test"}}},
+		{Path: "/github/app", Responses: []config.ImmediateResponseConfig{{Name: "app", Status: 202}}},
+		{Path: "/stripe"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	h := &Handler{config: buildServerConfig(cfg)}
+
+	cases := []struct {
+		path    string
+		handled bool
+		rule    string
+		target  string
+		strip   string
+	}{
+		{"/github/push", true, "default-ok", "http://github.test", "/github"},
+		{"/github/app/install", true, "app", "http://shared.test", "/github/app"},
+		{"/stripe/charge", true, "default-ok", "http://shared.test", "/stripe"},
+		{"/reqtap/other", false, "", "", ""},
+	}
+	for _, tc := range cases {
+		if got := h.shouldHandlePath(tc.path); got != tc.handled {
+			t.Errorf("%s: expected handled=%v, got %v", tc.path, tc.handled, got)
+		}
+		if !tc.handled {
+			continue
+		}
+		rule := h.selectResponseRule(httptest.NewRequest("POST", "http://localhost"+tc.path, nil), nil)
+		if rule == nil || rule.Name != tc.rule {
+			t.Errorf("%s: expected rule %s, got %#v", tc.path, tc.rule, rule)
+		}
+		targets := h.currentConfig().forwardTargetsFor(tc.path)
+		if len(targets) != 1 || targets[0].URL != tc.target || targets[0].StripPrefix != tc.strip {
+			t.Errorf("%s: expected %s stripping %s, got %+v", tc.path, tc.target, tc.strip, targets)
+		}
+	}
+	if urls := h.currentConfig().healthCheckURLs(); len(urls) != 2 {
+		t.Errorf("expected shared and route targets to be health checked, got %v", urls)
+	}
+}
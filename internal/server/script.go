@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/script"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// StageScript runs the configured scripts.
+const StageScript = "script"
+
+// scriptRulePrefix names the script that answered a request in its mock response summary
+const scriptRulePrefix = "script:"
+
+// installScriptStage runs scripts in order once the request is captured and the on_receive hooks
+// ran, before the client is answered, so a script can respond in place of the mock rules.
+func (h *Handler) installScriptStage(scripts []*script.Script) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	return h.pipeline.InsertAfter(StageHookOnReceive, Stage{Name: StageScript, Phase: PhaseSync, Run: func(ctx context.Context, ex *Exchange) error {
+		if ex.Record == nil || ex.Rejected || ex.Dropped {
+			return nil
+		}
+		for _, s := range scripts {
+			decision, err := s.Run(ctx, ex.Record)
+			if err != nil {
+				h.logger.Error("Script failed", "script", s.Name(), "error", err, "request_id", ex.Record.ID)
+				continue
+			}
+			h.applyScriptDecision(ex, s.Name(), decision)
+			if ex.Dropped {
+				h.logger.Debug("Request dropped by script", "script", s.Name(), "request_id", ex.Record.ID)
+				return nil
+			}
+		}
+		return nil
+	}})
+}
+
+// applyScriptDecision carries out a decision; only the first script to respond answers the client
+func (h *Handler) applyScriptDecision(ex *Exchange, name string, decision *script.Decision) {
+	if resp := decision.Respond; resp != nil && !ex.Responded {
+		h.writeScriptResponse(ex.Writer, resp)
+		ex.Responded = true
+		ex.Record.MockResponse = request.MockResponse{Rule: scriptRulePrefix + name, Status: resp.Status}
+	}
+	if len(decision.Tags) > 0 {
+		h.tagExchange(ex, decision.Tags)
+	}
+	if decision.SkipForward {
+		ex.SkipForward = true
+	}
+	if decision.Forward != nil {
+		ex.ForwardRecord = scriptForwardRecord(ex, decision.Forward)
+	}
+	if decision.Drop {
+		ex.Dropped = true
+	}
+}
+
+// writeScriptResponse answers the client with a response returned by a script
+func (h *Handler) writeScriptResponse(w http.ResponseWriter, resp *script.Response) {
+	hasContentType := false
+	for key, value := range resp.Headers {
+		if key == "" {
+			continue
+		}
+		w.Header().Set(key, value)
+		if strings.EqualFold(key, "Content-Type") {
+			hasContentType = true
+		}
+	}
+	if !hasContentType {
+		w.Header().Set("Content-Type", "text/plain")
+	}
+	h.setServerHeader(w.Header())
+	w.WriteHeader(resp.Status)
+	if len(resp.Body) > 0 {
+		w.Write(resp.Body)
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// scriptForwardRecord applies change to a copy of the request as it is forwarded; the stored
+// record keeps what was received
+func scriptForwardRecord(ex *Exchange, change *script.ForwardChange) *request.RequestData {
+	base := ex.ForwardRecord
+	if base == nil {
+		base = ex.Record
+	}
+	forward := *base
+	forward.Headers = base.Headers.Clone()
+	if forward.Headers == nil {
+		forward.Headers = http.Header{}
+	}
+	if change.Body != nil {
+		forward.Body = change.Body
+		forward.Size = int64(len(change.Body))
+		forward.ContentLength = forward.Size
+		// The new body is sent as it is, not from the spill file or in its received encoding
+		forward.BodyFile = ""
+		forward.WireBody = nil
+		forward.WireSize = 0
+		forward.ContentEncoding = ""
+		forward.Headers.Del("Content-Encoding")
+	}
+	for name, value := range change.Headers {
+		if value == nil {
+			forward.Headers.Del(name)
+		} else {
+			forward.Headers.Set(name, *value)
+		}
+	}
+	return &forward
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/script"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+func TestScriptStage(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	s, err := script.Compile(config.ScriptConfig{Name: "router", Timeout: time.Second}, `
+function handle(req) {
+  if (req.path === "/noise") return { drop: true };
+  if (req.path === "/quote") return { respond: { status: 201, body: { price: req.json.qty * 2 } }, tags: ["quoted"] };
+  return { forward: { body: JSON.stringify({ wrapped: req.json }), headers: { "X-Script": "router" } } };
+}`, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://upstream.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 1},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK, Body: "ok"}},
+	}
+	fwd := &recordingForwarder{}
+	h := NewHandler(nil, fwd, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+	if err := h.installScriptStage([]*script.Script{s}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://localhost"+path, strings.NewReader(body)))
+		h.procWG.Wait()
+		return rec
+	}
+	if rec := send("/quote", `{"qty":21}`); rec.Code != http.StatusCreated || rec.Body.String() != `{"price":42}` {
+		t.Fatalf("expected the script to answer, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := send("/noise", ""); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("expected a dropped request to get the mock response, got %d", rec.Code)
+	}
+	send("/order", `{"id":1}`)
+
+	items, total, err := store.List(storage.ListOptions{})
+	if err != nil || total != 2 {
+		t.Fatalf("expected the dropped request not to be stored, got %d (%v)", total, err)
+	}
+	byPath := map[string]*storage.StoredRequest{}
+	for _, item := range items {
+		byPath[item.Path] = item
+	}
+	quote := byPath["/quote"]
+	if quote.MockResponse.Rule != "script:router" || quote.MockResponse.Status != http.StatusCreated || strings.Join(quote.Tags, ",") != "quoted" {
+		t.Fatalf("expected the script response and tags to be recorded, got %+v %v", quote.MockResponse, quote.Tags)
+	}
+	if string(byPath["/order"].Body) != `{"id":1}` {
+		t.Fatalf("expected the stored body to stay as received, got %s", byPath["/order"].Body)
+	}
+
+	fwd.mu.Lock()
+	defer fwd.mu.Unlock()
+	if len(fwd.sent) != 2 {
+		t.Fatalf("expected the quote and the order to be forwarded, got %d", len(fwd.sent))
+	}
+	order := fwd.sent[1]
+	if string(order.Body) != `{"wrapped":{"id":1}}` || order.Headers.Get("X-Script") != "router" || order.ID != byPath["/order"].ID {
+		t.Fatalf("expected the rewritten body to be forwarded, got %s %v", order.Body, order.Headers)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
+	"github.com/funnyzak/reqtap/internal/web"
+)
+
+// ResponseStep is one step of a sequenced rule; zero Status and empty Body keep the rule's values
+type ResponseStep struct {
+	Status  int
+	Body    string
+	Headers map[string]string
+	// Times is how many calls the step answers before the next one takes over
+	Times int
+
+	bodyTemplate    *mocktemplate.Template
+	headerTemplates map[string]*mocktemplate.Template
+}
+
+// convertResponseSteps parses the step templates only when the rule sets template
+func convertResponseSteps(name string, cfgs []config.ResponseStepConfig, template bool) []ResponseStep {
+	if len(cfgs) == 0 {
+		return nil
+	}
+	steps := make([]ResponseStep, 0, len(cfgs))
+	for i, c := range cfgs {
+		step := ResponseStep{Status: c.Status, Body: c.Body, Times: c.Times}
+		if step.Times < 1 {
+			step.Times = 1
+		}
+		label := fmt.Sprintf("%s step %d", name, i+1)
+		if template {
+			// Templates are validated with the config, so a parse error here leaves the literal text in place
+			step.bodyTemplate, _ = mocktemplate.Parse(label, step.Body)
+		}
+		if len(c.Headers) > 0 {
+			step.Headers = make(map[string]string, len(c.Headers))
+		}
+		for k, v := range c.Headers {
+			key := http.CanonicalHeaderKey(k)
+			step.Headers[key] = v
+			if !template {
+				continue
+			}
+			if tmpl, _ := mocktemplate.Parse(label+" "+key, v); tmpl != nil {
+				if step.headerTemplates == nil {
+					step.headerTemplates = make(map[string]*mocktemplate.Template)
+				}
+				step.headerTemplates[key] = tmpl
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// stepIndex returns the step answering call (0-based); past the end the last step keeps answering
+// unless the sequence loops
+func (rule *ImmediateResponseRule) stepIndex(call int64) int {
+	var total int64
+	for _, step := range rule.Sequence {
+		total += int64(step.Times)
+	}
+	if rule.SequenceLoop && total > 0 {
+		call %= total
+	}
+	for i, step := range rule.Sequence {
+		if call < int64(step.Times) {
+			return i
+		}
+		call -= int64(step.Times)
+	}
+	return len(rule.Sequence) - 1
+}
+
+// withStep returns a copy of rule answering like step
+func (rule *ImmediateResponseRule) withStep(step *ResponseStep) *ImmediateResponseRule {
+	applied := *rule
+	applied.Sequence = nil
+	if step.Status != 0 {
+		applied.Status = step.Status
+	}
+	if step.Body != "" {
+		applied.Body = step.Body
+		applied.bodyTemplate = step.bodyTemplate
+	}
+	if len(step.Headers) > 0 {
+		applied.Headers = make(map[string]string, len(rule.Headers)+len(step.Headers))
+		applied.headerTemplates = make(map[string]*mocktemplate.Template, len(rule.headerTemplates)+len(step.headerTemplates))
+		for key, value := range rule.Headers {
+			applied.Headers[key] = value
+			if tmpl := rule.headerTemplates[key]; tmpl != nil {
+				applied.headerTemplates[key] = tmpl
+			}
+		}
+		for key, value := range step.Headers {
+			applied.Headers[key] = value
+			delete(applied.headerTemplates, key)
+			if tmpl := step.headerTemplates[key]; tmpl != nil {
+				applied.headerTemplates[key] = tmpl
+			}
+		}
+	}
+	return &applied
+}
+
+// advanceSequence counts a call to a sequenced rule and returns the rule as its current step answers
+func (h *Handler) advanceSequence(rule *ImmediateResponseRule) *ImmediateResponseRule {
+	if rule == nil || len(rule.Sequence) == 0 {
+		return rule
+	}
+	h.seqMu.Lock()
+	if h.sequenceCalls == nil {
+		h.sequenceCalls = make(map[*ImmediateResponseRule]int64)
+	}
+	call := h.sequenceCalls[rule]
+	h.sequenceCalls[rule] = call + 1
+	h.seqMu.Unlock()
+	return rule.withStep(&rule.Sequence[rule.stepIndex(call)])
+}
+
+// Sequences reports how far every sequenced rule has advanced.
+func (h *Handler) Sequences() []web.SequenceState {
+	cfg := h.currentConfig()
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+	states := []web.SequenceState{}
+	add := func(path string, rules []ImmediateResponseRule) {
+		for i := range rules {
+			rule := &rules[i]
+			if len(rule.Sequence) == 0 {
+				continue
+			}
+			calls := h.sequenceCalls[rule]
+			state := web.SequenceState{Rule: rule.Name, Path: path, Calls: calls, Steps: len(rule.Sequence), Loop: rule.SequenceLoop}
+			// Step is the 1-based step the next call gets
+			state.Step = rule.stepIndex(calls) + 1
+			states = append(states, state)
+		}
+	}
+	add("", cfg.Responses)
+	for _, route := range cfg.Routes {
+		add(route.Prefix, route.Responses)
+	}
+	return states
+}
+
+// ResetSequences restarts sequenced rules from their first step; an empty name resets all of them.
+// It returns the state after the reset.
+func (h *Handler) ResetSequences(name string) []web.SequenceState {
+	h.seqMu.Lock()
+	for rule := range h.sequenceCalls {
+		if name == "" || rule.Name == name {
+			delete(h.sequenceCalls, rule)
+		}
+	}
+	h.seqMu.Unlock()
+	return h.Sequences()
+}
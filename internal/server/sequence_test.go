@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestSequencedRuleAdvancesPerCall(t *testing.T) {
+	rules := convertImmediateResponseConfigs([]config.ImmediateResponseConfig{{
+		Name:     "flaky",
+		Status:   200,
+		Body:     "ok",
+		Headers:  map[string]string{"Content-Type": "text/plain"},
+		Template: true,
+		Sequence: []config.ResponseStepConfig{
+			{Status: 500, Body: "boom", Times: 2},
+			{Headers: map[string]string{"x-attempt": "{{.Method}}"}},
+		},
+	}})
+	h := &Handler{logger: noopLogger{}, config: &ServerConfig{Responses: rules}}
+
+	call := func() (int, string, string) {
+		rr := httptest.NewRecorder()
+		rule := h.sendImmediateResponse(rr, httptest.NewRequest("POST", "http://localhost/hook", nil), nil)
+		if rule == nil || rule.Name != "flaky" || rule.Status != rr.Code {
+			t.Fatalf("expected the flaky rule with the served status, got %#v", rule)
+		}
+		return rr.Code, rr.Body.String(), rr.Header().Get("X-Attempt")
+	}
+	want := []struct {
+		status  int
+		body    string
+		attempt string
+	}{
+		{500, "boom", ""},
+		{500, "boom", ""},
+		{200, "ok", "POST"},
+		{200, "ok", "POST"},
+	}
+	for i, w := range want {
+		status, body, attempt := call()
+		if status != w.status || body != w.body || attempt != w.attempt {
+			t.Fatalf("call %d: expected %d %q %q, got %d %q %q", i+1, w.status, w.body, w.attempt, status, body, attempt)
+		}
+	}
+
+	states := h.Sequences()
+	if len(states) != 1 || states[0].Calls != 4 || states[0].Step != 2 {
+		t.Fatalf("unexpected sequence state %+v", states)
+	}
+	if states = h.ResetSequences("other"); states[0].Calls != 4 {
+		t.Fatalf("resetting another rule changed the state: %+v", states)
+	}
+	if states = h.ResetSequences("flaky"); states[0].Calls != 0 || states[0].Step != 1 {
+		t.Fatalf("expected the sequence to start over, got %+v", states)
+	}
+	if status, _, _ := call(); status != 500 {
+		t.Fatalf("expected the first step after a reset, got %d", status)
+	}
+}
+
+func TestSequenceLoop(t *testing.T) {
+	rule := &ImmediateResponseRule{
+		Sequence:     []ResponseStep{{Times: 1}, {Times: 2}},
+		SequenceLoop: true,
+	}
+	for call, want := range []int{0, 1, 1, 0, 1, 1, 0} {
+		if got := rule.stepIndex(int64(call)); got != want {
+			t.Errorf("call %d: expected step %d, got %d", call, want, got)
+		}
+	}
+	rule.SequenceLoop = false
+	if got := rule.stepIndex(10); got != 1 {
+		t.Errorf("expected the last step to keep answering, got %d", got)
+	}
+}
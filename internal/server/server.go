@@ -3,9 +3,12 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
@@ -13,83 +16,133 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/funnyzak/reqtap/internal/archive"
+	"github.com/funnyzak/reqtap/internal/cluster"
 	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/grpccapture"
 	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/mocktemplate"
+	"github.com/funnyzak/reqtap/internal/plugin"
 	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/script"
 	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/internal/telemetry"
+	"github.com/funnyzak/reqtap/internal/tui"
+	"github.com/funnyzak/reqtap/internal/tunnel"
+	"github.com/funnyzak/reqtap/internal/wasm"
 	"github.com/funnyzak/reqtap/internal/web"
 	"github.com/funnyzak/reqtap/pkg/i18n"
 )
 
+// ConfigLoader re-reads the configuration when a reload is requested.
+type ConfigLoader func() (*config.Config, error)
+
+// sessionSummaryTopPaths is how many of the most requested paths the session summary lists
+const sessionSummaryTopPaths = 10
+
 // Server HTTP server
 type Server struct {
+	mu           sync.Mutex
 	config       *config.Config
+	loader       ConfigLoader
+	translator   *i18n.Translator
 	logger       logger.Logger
 	handler      *Handler
 	forwarder    forwarder.Client
 	printer      printer.Printer
+	outputFile   *printer.OutputFile
 	httpSrv      *http.Server
+	listener     net.Listener
+	stopped      bool
 	web          *web.Service
+	tunnel       *tunnel.Hub
+	health       *healthProbe
 	store        storage.Store
+	plugins      *plugin.Manager
+	transforms   []*wasm.Transformer
+	tui          *tui.UI
+	telemetry    func(context.Context) error
 	baseCtx      context.Context
 	cancel       context.CancelFunc
 	processingWG *sync.WaitGroup
+	// mockRules are the server.responses rules added through the admin API
+	mockRules []web.MockRule
+	// onReady runs once the listener accepts connections
+	onReady []func()
+	// startedAt is when Start began serving; Stop prints a summary of the session since then
+	startedAt time.Time
+	// statusLine is the sticky console footer of output.status_line
+	statusLine *printer.StatusLine
 }
 
 // New creates a new server instance
 func New(cfg *config.Config, log logger.Logger) (*Server, error) {
-	baseCtx, cancel := context.WithCancel(context.Background())
-	procWG := &sync.WaitGroup{}
 	translator, err := i18n.NewTranslator("en")
 	if err != nil {
 		return nil, err
 	}
 	// Create printer based on output configuration
-	var reqPrinter printer.Printer
-	if !cfg.Output.Silence {
-		reqPrinter = printer.New(strings.ToLower(cfg.Output.Mode), log, &cfg.Output, translator, cfg.Output.Locale)
+	outputFile := printer.OpenOutputFile(&cfg.Output)
+	reqPrinter := buildPrinter(cfg, log, translator, outputFile)
+	var terminalUI *tui.UI
+	var handler *Handler
+	if usesTUI(cfg) {
+		terminalUI = newTUI(cfg, translator, func() { handler.ToggleCapture() })
+		reqPrinter = terminalUI
 	}
 
 	// Create forwarder
-	forwardTimeout := time.Duration(cfg.Forward.Timeout) * time.Second
-
-	forwarder := forwarder.NewForwarder(log, forwarder.Options{
-		Timeout:               forwardTimeout,
-		Retries:               cfg.Forward.MaxRetries,
-		MaxConcurrent:         cfg.Forward.MaxConcurrent,
-		MaxIdleConns:          cfg.Forward.MaxIdleConns,
-		MaxIdleConnsPerHost:   cfg.Forward.MaxIdleConnsPerHost,
-		MaxConnsPerHost:       cfg.Forward.MaxConnsPerHost,
-		IdleConnTimeout:       time.Duration(cfg.Forward.IdleConnTimeout) * time.Second,
-		ResponseHeaderTimeout: time.Duration(cfg.Forward.ResponseHeaderTimeout) * time.Second,
-		TLSHandshakeTimeout:   time.Duration(cfg.Forward.TLSHandshakeTimeout) * time.Second,
-		ExpectContinueTimeout: time.Duration(cfg.Forward.ExpectContinueTimeout) * time.Second,
-		TLSInsecureSkipVerify: cfg.Forward.TLSInsecureSkipVerify,
-		PathStrategy:          buildForwardPathStrategyOptions(cfg),
-		HeaderBlacklist:       cfg.Forward.HeaderBlacklist,
-		HeaderWhitelist:       cfg.Forward.HeaderWhitelist,
-	})
+	forwarder := forwarder.NewForwarder(log, buildForwarderOptions(cfg))
 
 	// Create server configuration
-	serverConfig := &ServerConfig{
-		Port:         cfg.Server.Port,
-		Path:         cfg.Server.Path,
-		MaxBodyBytes: cfg.Server.MaxBodyBytes,
-		ForwardURLs:  cfg.Forward.URLs,
-		ForwardOpts: ForwardOptions{
-			Timeout:       cfg.Forward.Timeout,
-			MaxRetries:    cfg.Forward.MaxRetries,
-			MaxConcurrent: cfg.Forward.MaxConcurrent,
-		},
-		Responses: convertImmediateResponseConfigs(cfg.Server.Responses),
+	serverConfig := buildServerConfig(cfg)
+	if cfg.Server.Spill.ThresholdBytes > 0 && serverConfig.Spill.Threshold == 0 {
+		log.Warn("Request bodies are not spilled to disk while redaction rules mask bodies", "threshold_bytes", cfg.Server.Spill.ThresholdBytes)
+	}
+	forwarder.SetHealthCheckTargets(serverConfig.healthCheckURLs())
+
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry)
+	if err != nil {
+		return nil, err
+	}
+
+	transforms, err := loadWasmTransforms(cfg.WasmTransforms, log)
+	if err != nil {
+		shutdownTelemetry(context.Background())
+		return nil, err
+	}
+
+	scripts, err := loadScripts(cfg.Scripts, log)
+	if err != nil {
+		closeWasmTransforms(transforms)
+		shutdownTelemetry(context.Background())
+		return nil, err
 	}
 
-	store, err := storage.New(&cfg.Storage, log)
+	plugins, err := plugin.NewManager(cfg.Plugins, log)
 	if err != nil {
+		closeWasmTransforms(transforms)
+		shutdownTelemetry(context.Background())
 		return nil, err
 	}
 
+	var store storage.Store
+	if cfg.Storage.Driver == "plugin" {
+		store, err = plugin.NewStore(plugins.Get(cfg.Storage.Plugin))
+	} else {
+		store, err = storage.New(&cfg.Storage, log)
+	}
+	if err != nil {
+		plugins.Close()
+		closeWasmTransforms(transforms)
+		shutdownTelemetry(context.Background())
+		return nil, err
+	}
+
+	mockRules := loadStoredMockRules(store, log)
+	serverConfig.Responses = mockRuleConfigs(mockRules, cfg.Server.ResponseRules())
+
 	// Create web service if enabled
 	var webService *web.Service
 	if cfg.Web.Enable {
@@ -97,20 +150,225 @@ func New(cfg *config.Config, log logger.Logger) (*Server, error) {
 	}
 
 	// Create handler
-	handler := NewHandler(reqPrinter, forwarder, log, serverConfig, store, webService, baseCtx, procWG)
+	baseCtx, cancel := context.WithCancel(context.Background())
+	procWG := &sync.WaitGroup{}
+	handler = NewHandler(reqPrinter, forwarder, log, serverConfig, store, webService, baseCtx, procWG)
+	err = handler.installPluginStages(plugins)
+	if err == nil {
+		err = handler.installWasmStage(transforms)
+	}
+	if err == nil {
+		err = handler.installScriptStage(scripts)
+	}
+	if err == nil {
+		var decoder *protobufDecoder
+		if decoder, err = newProtobufDecoder(cfg.Output.BodyView.Protobuf); err == nil {
+			err = handler.installProtobufStage(decoder)
+		}
+	}
+	if err == nil {
+		err = handler.installRedactStage()
+	}
+	if err == nil {
+		err = handler.installDedupStage(cfg.Dedup)
+	}
+	if err == nil {
+		err = handler.installForwardOrderStage(cfg.Forward.Ordering)
+	}
+	if err == nil {
+		err = handler.installAnomalyStage(newAnomalyDetector(cfg.Anomaly, log, webService, baseCtx, procWG))
+	}
+	gossip := cluster.NewGossip(cfg.Cluster, log)
+	if err == nil {
+		err = handler.installClusterStages(gossip)
+	}
+	var tunnelHub *tunnel.Hub
+	if cfg.Tunnel.Enable {
+		tunnelHub = tunnel.NewHub(cfg.Tunnel.Secret, cfg.Tunnel.Timeout, log)
+		handler.SetTunnel(tunnelHub)
+	}
+	if err == nil && cfg.Server.GRPC.Enable {
+		var capturer *grpccapture.Capturer
+		if capturer, err = grpccapture.New(cfg.Server.GRPC); err == nil {
+			handler.SetGRPCCapture(capturer)
+		}
+	}
+	var archiver *archive.Archiver
+	if err == nil {
+		archiver, err = archive.New(cfg.Storage.Archive, redactsBodies(cfg), store, log)
+	}
+	if err != nil {
+		cancel()
+		store.Close()
+		plugins.Close()
+		closeWasmTransforms(transforms)
+		shutdownTelemetry(context.Background())
+		return nil, err
+	}
+
+	var probe *healthProbe
+	if cfg.Health.Enable {
+		probe = newHealthProbe(cfg, store)
+	}
 
-	return &Server{
+	srv := &Server{
 		config:       cfg,
+		translator:   translator,
 		logger:       log,
 		handler:      handler,
 		forwarder:    forwarder,
 		printer:      reqPrinter,
+		outputFile:   outputFile,
 		web:          webService,
+		tunnel:       tunnelHub,
+		health:       probe,
 		store:        store,
+		plugins:      plugins,
+		transforms:   transforms,
+		tui:          terminalUI,
+		telemetry:    shutdownTelemetry,
 		baseCtx:      baseCtx,
 		cancel:       cancel,
 		processingWG: procWG,
-	}, nil
+		mockRules:    mockRules,
+	}
+	if webService != nil {
+		webService.SetReloadHandler(srv.Reload)
+		webService.SetTargetStats(forwarder.Stats)
+		webService.SetReforwardHandler(handler.Reforward)
+		webService.SetSequenceHandlers(handler.Sequences, handler.ResetSequences)
+		webService.SetMockRuleManager(srv)
+		webService.SetAccessStats(handler.AccessStats)
+		webService.SetProcessingTimelines(handler.ProcessingTimeline)
+		webService.SetCaptureControl(handler.CaptureState, handler.SetCapturePaused)
+		webService.SetJWTView(cfg.Output.BodyView.JWT.Enable)
+		webService.SetDebug(cfg.Debug.Pprof)
+	}
+	if gossip != nil {
+		webService.SetClusterSecret(cfg.Cluster.Secret)
+		gossip.Start(baseCtx)
+	}
+	handler.StartForwardQueue()
+	archiver.Start(baseCtx, procWG)
+	return srv, nil
+}
+
+// buildForwarderOptions maps the forward section to forwarder options
+func buildForwarderOptions(cfg *config.Config) forwarder.Options {
+	return forwarder.Options{
+		Timeout:               time.Duration(cfg.Forward.Timeout) * time.Second,
+		Retries:               cfg.Forward.MaxRetries,
+		MaxConcurrent:         cfg.Forward.MaxConcurrent,
+		MaxIdleConns:          cfg.Forward.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.Forward.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.Forward.MaxConnsPerHost,
+		IdleConnTimeout:       time.Duration(cfg.Forward.IdleConnTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(cfg.Forward.ResponseHeaderTimeout) * time.Second,
+		TLSHandshakeTimeout:   time.Duration(cfg.Forward.TLSHandshakeTimeout) * time.Second,
+		ExpectContinueTimeout: time.Duration(cfg.Forward.ExpectContinueTimeout) * time.Second,
+		TLSInsecureSkipVerify: cfg.Forward.TLSInsecureSkipVerify,
+		HTTP2:                 cfg.Forward.HTTP2,
+		PathStrategy:          buildForwardPathStrategyOptions(cfg),
+		HeaderBlacklist:       cfg.Forward.HeaderBlacklist,
+		HeaderWhitelist:       cfg.Forward.HeaderWhitelist,
+		CircuitBreaker:        buildCircuitBreakerOptions(cfg.Forward.CircuitBreaker),
+		HealthCheck:           buildHealthCheckOptions(cfg.Forward.HealthCheck),
+		HostOverrides:         buildHostOverrides(cfg.Forward.HostOverrides),
+	}
+}
+
+func buildHostOverrides(cfgs []config.ForwardHostOverrideConfig) []forwarder.HostOverride {
+	if len(cfgs) == 0 {
+		return nil
+	}
+	overrides := make([]forwarder.HostOverride, 0, len(cfgs))
+	for _, c := range cfgs {
+		overrides = append(overrides, forwarder.HostOverride{Host: c.Host, Address: c.Address})
+	}
+	return overrides
+}
+
+func loadWasmTransforms(cfgs []config.WasmTransformConfig, log logger.Logger) ([]*wasm.Transformer, error) {
+	transforms := make([]*wasm.Transformer, 0, len(cfgs))
+	for _, c := range cfgs {
+		t, err := wasm.Load(context.Background(), c)
+		if err != nil {
+			closeWasmTransforms(transforms)
+			return nil, err
+		}
+		log.Info("WASM transform loaded", "transform", c.Name, "path", c.Path)
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+func loadScripts(cfgs []config.ScriptConfig, log logger.Logger) ([]*script.Script, error) {
+	scripts := make([]*script.Script, 0, len(cfgs))
+	for _, c := range cfgs {
+		s, err := script.Load(c, log)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Script loaded", "script", c.Name, "path", c.Path)
+		scripts = append(scripts, s)
+	}
+	return scripts, nil
+}
+
+func closeWasmTransforms(transforms []*wasm.Transformer) {
+	for _, t := range transforms {
+		t.Close(context.Background())
+	}
+}
+
+// buildPrinter returns the printer of the console or json output mode, writing to stdout and the
+// output.file transcript; it is nil when silenced without a transcript
+func buildPrinter(cfg *config.Config, log logger.Logger, translator *i18n.Translator, file *printer.OutputFile) printer.Printer {
+	out := printer.Writer(file, cfg.Output.Silence)
+	if out == nil {
+		return nil
+	}
+	return printer.New(strings.ToLower(cfg.Output.Mode), log, &cfg.Output, translator, cfg.Output.Locale, out)
+}
+
+func buildServerConfig(cfg *config.Config) *ServerConfig {
+	return &ServerConfig{
+		Port:              cfg.Server.Port,
+		Path:              cfg.Server.Path,
+		MaxBodyBytes:      cfg.Server.MaxBodyBytes,
+		Spill:             buildSpillOptions(cfg),
+		ForwardTargets:    convertForwardTargets(cfg.Forward.ResolvedTargets()),
+		ForwardFilters:    convertForwardFilters(cfg.Forward.Filters),
+		ForwardTransforms: convertForwardTransforms(cfg.Forward.Transforms),
+		ForwardOpts: ForwardOptions{
+			Timeout:       cfg.Forward.Timeout,
+			MaxRetries:    cfg.Forward.MaxRetries,
+			MaxConcurrent: cfg.Forward.MaxConcurrent,
+		},
+		Responses: convertImmediateResponseConfigs(cfg.Server.ResponseRules()),
+		WebSocket: WebSocketOptions{
+			Enable:       cfg.Server.WebSocket.Enable,
+			ProxyURL:     cfg.Server.WebSocket.ProxyURL,
+			PreviewBytes: cfg.Server.WebSocket.PreviewBytes,
+		},
+		Identity: buildIdentityOptions(cfg.Server.Identity),
+		Auth:     buildCaptureAuthOptions(cfg.Server.Auth),
+		// Access control is applied per request, so reloads take effect immediately
+		AccessControl: buildAccessControlOptions(cfg.Server.AccessControl),
+		// access_control.trusted_proxies predates server.trusted_proxies and still counts
+		TrustedProxies: parseAddressRanges(append(append([]string(nil), cfg.Server.TrustedProxies...), cfg.Server.AccessControl.TrustedProxies...)),
+		CORS:           buildCORSOptions(cfg.Server.CORS),
+		ForwardQueue: ForwardQueueOptions{
+			Enable:       cfg.Forward.Queue.Enable,
+			PollInterval: cfg.Forward.Queue.PollInterval,
+			Backoff:      cfg.Forward.Queue.Backoff,
+			MaxBackoff:   cfg.Forward.Queue.MaxBackoff,
+			MaxAttempts:  cfg.Forward.Queue.MaxAttempts,
+		},
+		Routes: buildCaptureRoutes(cfg),
+		Hooks:  buildHookRules(cfg.Hooks),
+		Redact: buildRedactOptions(cfg.Privacy.Redact),
+	}
 }
 
 func convertImmediateResponseConfigs(cfgs []config.ImmediateResponseConfig) []ImmediateResponseRule {
@@ -129,6 +387,23 @@ func convertImmediateResponseConfigs(cfgs []config.ImmediateResponseConfig) []Im
 			Status:     c.Status,
 			Body:       c.Body,
 			Headers:    headers,
+			StatusText: c.StatusText,
+			HTTP10:     c.HTTP10,
+
+			Compression:         c.Compression,
+			CompressionMinBytes: c.CompressionMinBytes,
+			BodyFile:            c.BodyFile,
+			BodyURL:             c.BodyURL,
+			BodyURLTTL:          c.BodyURLTTL,
+
+			Delay:         c.Delay,
+			DelayJitter:   c.DelayJitter,
+			TimeoutChance: c.TimeoutChance,
+			SequenceLoop:  c.SequenceLoop,
+
+			HeaderMatch: convertMatchConditions(c.HeaderMatch),
+			QueryMatch:  convertMatchConditions(c.QueryMatch),
+			BodyMatch:   convertMatchConditions(c.BodyMatch),
 		}
 		if rule.Name == "" {
 			rule.Name = fmt.Sprintf("rule-%d", len(rules)+1)
@@ -139,6 +414,19 @@ func convertImmediateResponseConfigs(cfgs []config.ImmediateResponseConfig) []Im
 		if rule.Headers == nil {
 			rule.Headers = map[string]string{}
 		}
+		rule.Sequence = convertResponseSteps(rule.Name, c.Sequence, c.Template)
+		if c.Template {
+			// Templates are validated with the config, so a parse error here leaves the literal text in place
+			rule.bodyTemplate, _ = mocktemplate.Parse(rule.Name, rule.Body)
+			for key, value := range rule.Headers {
+				if tmpl, _ := mocktemplate.Parse(rule.Name+" "+key, value); tmpl != nil {
+					if rule.headerTemplates == nil {
+						rule.headerTemplates = make(map[string]*mocktemplate.Template)
+					}
+					rule.headerTemplates[key] = tmpl
+				}
+			}
+		}
 		rules = append(rules, rule)
 	}
 	if len(rules) == 0 {
@@ -154,6 +442,135 @@ func convertImmediateResponseConfigs(cfgs []config.ImmediateResponseConfig) []Im
 	return rules
 }
 
+func convertForwardTargets(cfgs []config.ForwardTargetConfig) []forwarder.Target {
+	targets := make([]forwarder.Target, 0, len(cfgs))
+	for _, c := range cfgs {
+		target := forwarder.Target{
+			URL:           c.URL,
+			LatencyBudget: c.LatencyBudget,
+			Format:        strings.ToLower(c.Format),
+			SamplePercent: c.SamplePercent,
+			Weight:        c.Weight,
+			Sign:          forwarder.NewSigner(c.Sign),
+			MaxConcurrent: c.MaxConcurrent,
+			RateLimit:     c.RateLimit,
+		}
+		if c.Retry.MaxRetries != nil || c.Retry.Backoff > 0 || c.Retry.MaxBackoff > 0 {
+			target.Retry = &forwarder.RetryPolicy{
+				MaxRetries: -1,
+				Backoff:    c.Retry.Backoff,
+				MaxBackoff: c.Retry.MaxBackoff,
+			}
+			if c.Retry.MaxRetries != nil {
+				target.Retry.MaxRetries = *c.Retry.MaxRetries
+			}
+		}
+		if !c.Expect.Empty() {
+			expect := &forwarder.Expectation{
+				Status:       append([]int(nil), c.Expect.Status...),
+				MaxLatency:   c.Expect.MaxLatency,
+				BodyContains: append([]string(nil), c.Expect.BodyContains...),
+			}
+			for _, assertion := range c.Expect.JSON {
+				expect.JSON = append(expect.JSON, forwarder.JSONAssertion{
+					Path:   assertion.Path,
+					Equals: assertion.Equals,
+				})
+			}
+			target.Expect = expect
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func targetURLs(targets []forwarder.Target) []string {
+	urls := make([]string, 0, len(targets))
+	for _, target := range targets {
+		urls = append(urls, target.URL)
+	}
+	return urls
+}
+
+func buildCircuitBreakerOptions(cfg config.ForwardCircuitBreakerConfig) forwarder.CircuitBreakerOptions {
+	if !cfg.Enable {
+		return forwarder.CircuitBreakerOptions{}
+	}
+	return forwarder.CircuitBreakerOptions{FailureThreshold: cfg.FailureThreshold, Cooldown: cfg.Cooldown}
+}
+
+func buildHealthCheckOptions(cfg config.ForwardHealthCheckConfig) forwarder.HealthCheckOptions {
+	if !cfg.Enable {
+		return forwarder.HealthCheckOptions{}
+	}
+	return forwarder.HealthCheckOptions{Path: cfg.Path, Interval: cfg.Interval, Timeout: cfg.Timeout}
+}
+
+// convertForwardFilters compiles the filter patterns, which were already checked by config validation
+func convertForwardFilters(cfgs []config.ForwardFilterConfig) []forwarder.Filter {
+	filters := make([]forwarder.Filter, 0, len(cfgs))
+	for _, c := range cfgs {
+		filter := forwarder.Filter{
+			Name:         c.Name,
+			Deny:         strings.EqualFold(c.Action, "deny"),
+			Targets:      append([]string(nil), c.Targets...),
+			Methods:      normalizeMethods(c.Methods),
+			BodyContains: []byte(c.BodyContains),
+		}
+		if c.PathRegex != "" {
+			filter.Path = regexp.MustCompile(c.PathRegex)
+		}
+		for name, pattern := range c.Headers {
+			if filter.Headers == nil {
+				filter.Headers = make(map[string]*regexp.Regexp, len(c.Headers))
+			}
+			filter.Headers[name] = regexp.MustCompile(pattern)
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// convertForwardTransforms parses the transform templates, which were already checked by config validation
+func convertForwardTransforms(cfgs []config.ForwardTransformConfig) []forwarder.Transform {
+	transforms := make([]forwarder.Transform, 0, len(cfgs))
+	for _, c := range cfgs {
+		transform := forwarder.Transform{
+			Name:          c.Name,
+			Targets:       append([]string(nil), c.Targets...),
+			JSONRemove:    append([]string(nil), c.JSON.Remove...),
+			RemoveHeaders: append([]string(nil), c.Headers.Remove...),
+		}
+		for _, rename := range c.JSON.Rename {
+			transform.JSONRename = append(transform.JSONRename, forwarder.JSONRename{From: rename.From, To: rename.To})
+		}
+		for _, op := range c.JSON.Set {
+			transform.JSONSet = append(transform.JSONSet, forwarder.JSONSet{
+				Path:  op.Path,
+				Value: transformValue(op.Path, op.Value),
+				Raw:   op.Raw,
+			})
+		}
+		if c.Body != "" {
+			body := transformValue("body", c.Body)
+			transform.Body = &body
+		}
+		for name, value := range c.Headers.Set {
+			if transform.SetHeaders == nil {
+				transform.SetHeaders = make(map[string]forwarder.Value, len(c.Headers.Set))
+			}
+			transform.SetHeaders[name] = transformValue(name, value)
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms
+}
+
+func transformValue(name, text string) forwarder.Value {
+	tmpl, _ := mocktemplate.Parse(name, text)
+	return forwarder.Value{Text: text, Template: tmpl}
+}
+
 func normalizeMethods(methods []string) []string {
 	if len(methods) == 0 {
 		return nil
@@ -184,7 +601,8 @@ func buildForwardPathStrategyOptions(cfg *config.Config) forwarder.PathStrategyO
 	if mode == "" {
 		return options
 	}
-	if mode == "strip_prefix" && options.StripPrefix == "" {
+	// With server.paths every target strips its own route prefix, see buildCaptureRoutes
+	if mode == "strip_prefix" && options.StripPrefix == "" && len(cfg.Server.Paths) == 0 {
 		options.StripPrefix = cfg.Server.Path
 	}
 	return options
@@ -203,13 +621,63 @@ func convertForwardRewriteRules(cfgRules []config.ForwardRewriteRuleConfig) []fo
 	return rules
 }
 
-// Start starts the server
+// OnReady registers fn to run once Start has bound the listening port.
+func (s *Server) OnReady(fn func()) {
+	s.onReady = append(s.onReady, fn)
+}
+
+// LoginLink issues a one-time web console login token, see web.Service.LoginLink.
+func (s *Server) LoginLink() (string, time.Time, error) {
+	return s.web.LoginLink()
+}
+
+// listenerProtocols accepts HTTP/2 when server.http2 is enabled, and h2c for gRPC clients, which
+// speak cleartext HTTP/2 with prior knowledge; with TLS, HTTP/2 is negotiated through ALPN
+func listenerProtocols(cfg config.ServerConfig) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if cfg.HTTP2.Enable || cfg.GRPC.Enable {
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	return protocols
+}
+
+// Start serves on the configured port and blocks until a shutdown signal arrives or the
+// terminal UI is closed.
 func (s *Server) Start() error {
+	s.startedAt = time.Now()
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	var tuiDone <-chan struct{}
+	if s.tui != nil {
+		tuiDone = s.tui.Start()
+	}
+	s.startStatusLine()
+	s.watchPauseKey()
+
+	// Wait for shutdown signal, or for the terminal UI to be closed
+	s.waitForShutdown(tuiDone)
+
+	return nil
+}
+
+// Listen binds the configured port (a free one when it is 0) and serves in the background
+// until Stop is called.
+func (s *Server) Listen() error {
 	// Create router
 	router := mux.NewRouter()
+	if s.health != nil {
+		s.health.register(router, s.config.Health)
+	}
 	if s.web != nil {
 		s.web.RegisterRoutes(router)
 	}
+	if s.tunnel != nil {
+		router.Handle(s.config.Tunnel.Path, s.tunnel).Methods(http.MethodGet)
+	}
 	router.PathPrefix("/").HandlerFunc(s.handleRequest)
 
 	// Create HTTP server
@@ -220,94 +688,332 @@ func (s *Server) Start() error {
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	s.httpSrv.Protocols = listenerProtocols(s.config.Server)
+
+	listener, activated, err := listen(s.config.Server.Listener, s.httpSrv.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	if s.health != nil {
+		s.health.serving.Store(true)
+	}
+	if activated {
+		s.logger.Info("Using the socket passed by systemd, server.port is ignored")
+	}
 
 	// Start server
 	s.logger.Info("Starting HTTP server",
-		"addr", s.httpSrv.Addr,
-		"path", s.config.Server.Path,
+		"addr", listener.Addr().String(),
+		"paths", s.config.Server.CapturePrefixes(),
+		"tls", s.config.Server.TLS.Enabled(),
+		"http2", s.httpSrv.Protocols.HTTP2() || s.httpSrv.Protocols.UnencryptedHTTP2(),
 	)
 
 	// Start server in goroutine
 	go func() {
-		if err := s.httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsCfg := s.config.Server.TLS; tlsCfg.Enabled() {
+			err = s.httpSrv.ServeTLS(listener, tlsCfg.CertFile, tlsCfg.KeyFile)
+		} else {
+			err = s.httpSrv.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Fatal("Server failed to start", "error", err)
 		}
 	}()
+	for _, fn := range s.onReady {
+		fn()
+	}
+	return nil
+}
 
-	// Wait for shutdown signal
-	s.waitForShutdown()
+// Addr returns the address the listener is bound to, or nil before Listen.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
 
-	return nil
+// Store exposes the storage backend captured requests are persisted to.
+func (s *Server) Store() storage.Store {
+	return s.store
 }
 
 // handleRequest handles HTTP request
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
-	// Check path prefix
-	if s.config.Server.Path != "/" && !s.handler.shouldHandlePath(r.URL.Path) {
-		http.NotFound(w, r)
-		return
+	s.handler.ServeHTTP(w, r)
+}
+
+// Pipeline exposes the request processing chain for extensions.
+func (s *Server) Pipeline() *Pipeline {
+	return s.handler.Pipeline()
+}
+
+// AddHook registers a hook run at point for every captured request, see HookPoint.
+func (s *Server) AddHook(point HookPoint, name string, fn Hook) error {
+	return s.handler.AddHook(point, name, fn)
+}
+
+// SetConfigLoader enables hot reload; without a loader Reload returns an error.
+func (s *Server) SetConfigLoader(loader ConfigLoader) {
+	s.mu.Lock()
+	s.loader = loader
+	s.mu.Unlock()
+}
+
+// Reload re-reads the configuration and applies mock response rules, forward targets,
+// path strategy and output settings in place. Mock rules added through the API stay in front of
+// the reloaded server.responses. It returns the changed settings that
+// only take effect after a restart.
+func (s *Server) Reload() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loader == nil {
+		return nil, fmt.Errorf("config reload is not available")
+	}
+	next, err := s.loader()
+	if err != nil {
+		s.logger.Error("Config reload failed", "error", err)
+		return nil, err
 	}
 
-	// Call handler
-	s.handler.ServeHTTP(w, r)
+	restartRequired := restartRequiredChanges(s.config, next)
+	// The listener stays bound to the original port until restart.
+	next.Server.Port = s.config.Server.Port
+
+	serverConfig := buildServerConfig(next)
+	serverConfig.Responses = mockRuleConfigs(s.mockRules, next.Server.ResponseRules())
+	s.handler.UpdateConfig(serverConfig)
+	if setter, ok := s.forwarder.(interface {
+		SetPathStrategy(forwarder.PathStrategyOptions)
+	}); ok {
+		setter.SetPathStrategy(buildForwardPathStrategyOptions(next))
+	}
+	if setter, ok := s.forwarder.(interface {
+		SetHealthCheckTargets([]string)
+	}); ok {
+		setter.SetHealthCheckTargets(serverConfig.healthCheckURLs())
+	}
+	s.web.SetJWTView(next.Output.BodyView.JWT.Enable)
+	if s.health != nil {
+		s.health.setTargets(next)
+	}
+	if s.tui == nil {
+		s.printer = buildPrinter(next, s.logger, s.translator, s.outputFile)
+		s.handler.SetPrinter(s.printer)
+	}
+	s.config = next
+
+	s.logger.Info("Configuration reloaded",
+		"paths", next.Server.CapturePrefixes(),
+		"mock_rules", len(serverConfig.Responses),
+		"forward_targets", len(next.Forward.ResolvedTargets()),
+		"output_mode", next.Output.Mode,
+	)
+	if len(restartRequired) > 0 {
+		s.logger.Warn("Some configuration changes require a restart", "settings", restartRequired)
+	}
+	return restartRequired, nil
 }
 
-// waitForShutdown waits for shutdown signal
-func (s *Server) waitForShutdown() {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+// restartRequiredChanges lists sections that changed but cannot be applied to a running server.
+func restartRequiredChanges(prev, next *config.Config) []string {
+	var changed []string
+	if prev.Server.Port != next.Server.Port {
+		changed = append(changed, "server.port")
+	}
+	if prev.Server.HTTP2 != next.Server.HTTP2 {
+		changed = append(changed, "server.http2")
+	}
+	if prev.Server.TLS != next.Server.TLS {
+		changed = append(changed, "server.tls")
+	}
+	if prev.Server.Listener != next.Server.Listener {
+		changed = append(changed, "server.listener")
+	}
+	if !reflect.DeepEqual(prev.Server.GRPC, next.Server.GRPC) {
+		changed = append(changed, "server.grpc")
+	}
+	if !reflect.DeepEqual(prev.Output.BodyView.Protobuf, next.Output.BodyView.Protobuf) {
+		changed = append(changed, "output.body_view.protobuf")
+	}
+	if usesTUI(prev) != usesTUI(next) {
+		changed = append(changed, "output.mode")
+	}
+	if prev.Output.File != next.Output.File || prev.Output.FileRotation != next.Output.FileRotation {
+		changed = append(changed, "output.file")
+	}
+	if usesStatusLine(prev) != usesStatusLine(next) {
+		changed = append(changed, "output.status_line")
+	}
+	if !reflect.DeepEqual(prev.Forward.HostOverrides, next.Forward.HostOverrides) {
+		changed = append(changed, "forward.host_overrides")
+	}
+	if !reflect.DeepEqual(prev.Log, next.Log) {
+		changed = append(changed, "log")
+	}
+	if !reflect.DeepEqual(prev.Storage, next.Storage) {
+		changed = append(changed, "storage")
+	}
+	if !reflect.DeepEqual(prev.Web, next.Web) {
+		changed = append(changed, "web")
+	}
+	if prev.Tunnel != next.Tunnel {
+		changed = append(changed, "tunnel")
+	}
+	if prev.Health != next.Health {
+		changed = append(changed, "health")
+	}
+	if !reflect.DeepEqual(prev.Plugins, next.Plugins) {
+		changed = append(changed, "plugins")
+	}
+	if !reflect.DeepEqual(prev.WasmTransforms, next.WasmTransforms) {
+		changed = append(changed, "wasm_transforms")
+	}
+	if !reflect.DeepEqual(prev.Scripts, next.Scripts) {
+		changed = append(changed, "scripts")
+	}
+	if !reflect.DeepEqual(prev.Anomaly, next.Anomaly) {
+		changed = append(changed, "anomaly")
+	}
+	if prev.Dedup != next.Dedup {
+		changed = append(changed, "dedup")
+	}
+	if !reflect.DeepEqual(prev.Cluster, next.Cluster) {
+		changed = append(changed, "cluster")
+	}
+	if !reflect.DeepEqual(prev.Telemetry, next.Telemetry) {
+		changed = append(changed, "telemetry")
+	}
+	if prev.Debug != next.Debug {
+		changed = append(changed, "debug")
+	}
+	prevForward, nextForward := prev.Forward, next.Forward
+	prevForward.URLs, nextForward.URLs = nil, nil
+	prevForward.Targets, nextForward.Targets = nil, nil
+	prevForward.Timeout, nextForward.Timeout = 0, 0
+	prevForward.LatencyBudget, nextForward.LatencyBudget = 0, 0
+	prevForward.Filters, nextForward.Filters = nil, nil
+	prevForward.Transforms, nextForward.Transforms = nil, nil
+	prevForward.PathStrategy, nextForward.PathStrategy = config.ForwardPathStrategyConfig{}, config.ForwardPathStrategyConfig{}
+	if !reflect.DeepEqual(prevForward, nextForward) {
+		changed = append(changed, "forward")
+	}
+	return changed
+}
 
-	<-quit
+// waitForShutdown waits for shutdown signal, reloading the configuration on SIGHUP
+func (s *Server) waitForShutdown(tuiDone <-chan struct{}) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+wait:
+	for {
+		select {
+		case sig := <-quit:
+			if sig != syscall.SIGHUP {
+				break wait
+			}
+			s.logger.Info("Received SIGHUP, reloading configuration")
+			s.Reload()
+		case <-tuiDone:
+			break wait
+		}
+	}
 	s.logger.Info("Shutting down server...")
+	if s.tui != nil {
+		if err := s.tui.Stop(); err != nil {
+			s.logger.Error("Terminal UI failed", "error", err)
+		}
+	}
+
+	// Graceful shutdown
+	if err := s.Stop(); err != nil {
+		s.logger.Error("Server forced to shutdown", "error", err)
+	}
+
+	s.logger.Info("Server exited")
+}
+
+// printSessionSummary recaps the requests captured since Start: as text in console mode, as a log
+// entry otherwise
+func (s *Server) printSessionSummary() {
+	if s.startedAt.IsZero() {
+		return
+	}
+	summary, err := storage.Summarize(s.store, s.startedAt, time.Time{}, sessionSummaryTopPaths)
+	if err != nil {
+		s.logger.Error("Failed to summarize the session", "error", err)
+		return
+	}
+	if strings.ToLower(s.config.Output.Mode) == "console" {
+		if out := printer.Writer(s.outputFile, s.config.Output.Silence); out != nil {
+			if err := printer.WriteSummary(out, summary); err != nil {
+				s.logger.Error("Failed to print the session summary", "error", err)
+			}
+		}
+		return
+	}
+	s.logger.Info("Session summary",
+		"requests", summary.Total,
+		"body_size_p50", summary.BodySizeP50,
+		"body_size_p95", summary.BodySizeP95,
+		"forwards", summary.Forwards,
+		"forward_success_rate", summary.ForwardSuccessRate,
+		"forward_latency_p50_ms", summary.ForwardLatencyP50Ms,
+		"forward_latency_p95_ms", summary.ForwardLatencyP95Ms,
+	)
+}
+
+// Stop shuts the listener down, if it was started, and releases every resource; calls after the
+// first are no-ops.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil
+	}
+	s.stopped = true
+	s.statusLine.Stop()
+	if s.health != nil {
+		// Readiness fails while the listener drains
+		s.health.serving.Store(false)
+	}
 
-	// Create shutdown context
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if s.cancel != nil {
 		s.cancel()
 	}
-
-	// Graceful shutdown
-	if err := s.httpSrv.Shutdown(ctx); err != nil {
-		s.logger.Error("Server forced to shutdown", "error", err)
+	var err error
+	if s.httpSrv != nil {
+		err = s.httpSrv.Shutdown(ctx)
+	}
+	if s.tunnel != nil {
+		// Hijacked tunnel connections are not closed by Shutdown
+		s.tunnel.Close()
 	}
-
 	if s.processingWG != nil {
 		s.processingWG.Wait()
 	}
-
-	// Close forwarder
 	s.forwarder.Close()
 	if s.web != nil {
 		s.web.Close()
 	}
 	if s.store != nil {
+		s.printSessionSummary()
 		s.store.Close()
 	}
-
-	s.logger.Info("Server exited")
-}
-
-// Stop stops the server
-func (s *Server) Stop() error {
-	if s.httpSrv != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		if s.cancel != nil {
-			s.cancel()
-		}
-		err := s.httpSrv.Shutdown(ctx)
-		if s.processingWG != nil {
-			s.processingWG.Wait()
-		}
-		s.forwarder.Close()
-		if s.web != nil {
-			s.web.Close()
-		}
-		if s.store != nil {
-			s.store.Close()
-		}
-		return err
+	s.plugins.Close()
+	closeWasmTransforms(s.transforms)
+	if s.outputFile != nil {
+		s.outputFile.Close()
 	}
-	return nil
+	if terr := s.telemetry(ctx); terr != nil {
+		s.logger.Error("Failed to flush traces", "error", terr)
+	}
+	return err
 }
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/web"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfig("", viper.New())
+	if err != nil {
+		t.Fatalf("load config failed: %v", err)
+	}
+	return cfg
+}
+
+func TestServerReload(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Output.Silence = true
+	cfg.Web.Enable = false
+	cfg.Storage.Path = filepath.Join(t.TempDir(), "reqtap.db")
+
+	srv, err := New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	defer srv.Stop()
+
+	if _, err := srv.Reload(); err == nil {
+		t.Fatal("expected error without config loader")
+	}
+
+	next := *cfg
+	next.Server.Port = cfg.Server.Port + 1
+	next.Server.Responses = []config.ImmediateResponseConfig{{Name: "teapot", Status: http.StatusTeapot, Body: "short and stout"}}
+	next.Forward.URLs = []string{"http://127.0.0.1:9/hook"}
+	srv.SetConfigLoader(func() (*config.Config, error) {
+		copied := next
+		return &copied, nil
+	})
+
+	restartRequired, err := srv.Reload()
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reflect.DeepEqual(restartRequired, []string{"server.port"}) {
+		t.Fatalf("unexpected restart-required settings: %v", restartRequired)
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/brew", nil)
+	if rule := srv.handler.sendImmediateResponse(rr, req, nil); rule == nil || rule.Name != "teapot" {
+		t.Fatalf("expected reloaded mock rule, got %#v", rule)
+	}
+	if rr.Code != http.StatusTeapot {
+		t.Fatalf("expected 418 after reload, got %d", rr.Code)
+	}
+	if got := srv.handler.currentConfig(); got.Port != cfg.Server.Port || len(got.ForwardTargets) != 1 {
+		t.Fatalf("unexpected runtime config after reload: %#v", got)
+	}
+
+	srv.SetConfigLoader(func() (*config.Config, error) {
+		return nil, errors.New("broken yaml")
+	})
+	if _, err := srv.Reload(); err == nil {
+		t.Fatal("expected loader error to be returned")
+	}
+	if rule := srv.handler.selectResponseRule(req, nil); rule == nil || rule.Name != "teapot" {
+		t.Fatal("failed reload must keep the previous configuration")
+	}
+}
+
+func TestServerMockRules(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Output.Silence = true
+	cfg.Web.Enable = false
+	cfg.Storage.Path = filepath.Join(t.TempDir(), "reqtap.db")
+
+	srv, err := New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/reqtap/pay", nil)
+
+	outage := config.ImmediateResponseConfig{Name: "outage", PathPrefix: "/reqtap/pay", Status: http.StatusServiceUnavailable}
+	if err := srv.SaveMockRule(outage, "alice", true); !errors.Is(err, web.ErrMockRuleNotFound) {
+		t.Fatalf("expected replacing an unknown rule to fail, got %v", err)
+	}
+	if err := srv.SaveMockRule(outage, "alice", false); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := srv.SaveMockRule(outage, "alice", false); !errors.Is(err, web.ErrMockRuleExists) {
+		t.Fatalf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if rule := srv.handler.selectResponseRule(req, nil); rule == nil || rule.Status != http.StatusServiceUnavailable {
+		t.Fatalf("expected the API rule to match before the config rules, got %#v", rule)
+	}
+
+	// Replacing a config rule shadows it until the API rule is deleted
+	teapot := config.ImmediateResponseConfig{Name: "default-ok", Status: http.StatusTeapot}
+	if err := srv.SaveMockRule(teapot, "alice", true); err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	rules := srv.MockRules()
+	if len(rules) != 2 || rules[1].Rule.Name != "default-ok" || rules[1].Source != web.MockRuleSourceAPI {
+		t.Fatalf("expected the config rule to be replaced, got %#v", rules)
+	}
+	srv.Stop()
+
+	// Rules added through the API survive a restart
+	srv, err = New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	defer srv.Stop()
+	if rules := srv.MockRules(); len(rules) != 2 || rules[0].Rule.Name != "outage" || rules[0].UpdatedBy != "alice" {
+		t.Fatalf("expected stored rules to be restored, got %#v", rules)
+	}
+	if err := srv.DeleteMockRule("default-ok"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := srv.DeleteMockRule("default-ok"); !errors.Is(err, web.ErrMockRuleConfigured) {
+		t.Fatalf("expected config rules to be kept, got %v", err)
+	}
+	other := httptest.NewRequest(http.MethodGet, "http://localhost/reqtap/other", nil)
+	if rule := srv.handler.selectResponseRule(other, nil); rule == nil || rule.Status != http.StatusOK {
+		t.Fatalf("expected the config rule to apply again, got %#v", rule)
+	}
+}
+
+func TestServerMockPresets(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Output.Silence = true
+	cfg.Web.Enable = false
+	cfg.Storage.Path = filepath.Join(t.TempDir(), "reqtap.db")
+	cfg.Server.MockPresets = []string{"slack"}
+
+	srv, err := New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	defer srv.Stop()
+
+	body := []byte(`{"token":"x","challenge":"3eZbrw1aBm2rZgRN","type":"url_verification"}`)
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/reqtap", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	rule := srv.handler.sendImmediateResponse(rr, req, &request.RequestData{Method: http.MethodPost, Path: "/reqtap", Body: body})
+	if rule == nil || rule.Name != "preset-slack-url-verification" {
+		t.Fatalf("expected the slack preset to answer, got %#v", rule)
+	}
+	if rr.Body.String() != "3eZbrw1aBm2rZgRN" {
+		t.Fatalf("expected the challenge to be echoed, got %q", rr.Body.String())
+	}
+
+	// Requests of other providers still reach server.responses
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/reqtap", nil)
+	if rule := srv.handler.selectResponseRule(req, nil); rule == nil || rule.Name != "default-ok" {
+		t.Fatalf("expected the default rule, got %#v", rule)
+	}
+}
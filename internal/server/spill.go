@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+// SpillOptions streams bodies above Threshold bytes to files in Dir; 0 buffers every body in memory
+type SpillOptions struct {
+	Threshold int64
+	Dir       string
+}
+
+// buildSpillOptions defaults the spill directory to a "bodies" folder next to the database. Bodies
+// are not spilled while redaction rules mask bodies, as only the in-memory body can be masked.
+func buildSpillOptions(cfg *config.Config) SpillOptions {
+	dir := cfg.Server.Spill.Dir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(cfg.Storage.Path), "bodies")
+	}
+	threshold := cfg.Server.Spill.ThresholdBytes
+	if redactsBodies(cfg) {
+		threshold = 0
+	}
+	return SpillOptions{Threshold: threshold, Dir: dir}
+}
+
+// redactsBodies reports whether privacy.redact or a redact hook masks request bodies
+func redactsBodies(cfg *config.Config) bool {
+	if len(cfg.Privacy.Redact.Fields) > 0 || len(cfg.Privacy.Redact.Patterns) > 0 {
+		return true
+	}
+	for _, hook := range cfg.Hooks {
+		if hook.Action == "redact" && len(hook.RedactFields) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// capturedBody is a request body as read by readRequestBody; File is set when the body was
+// spilled to disk, Data then holds its first Threshold bytes and Size the full length
+type capturedBody struct {
+	Data []byte
+	File string
+	Size int64
+}
+
+// spillBody writes prefix and the rest of r to a new file in dir. A body over the size limit
+// removes the file again and returns errRequestBodyTooLarge.
+func spillBody(dir string, prefix []byte, r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", 0, fmt.Errorf("create spill directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "body-*.bin")
+	if err != nil {
+		return "", 0, fmt.Errorf("create spill file: %w", err)
+	}
+	size, err := f.Write(prefix)
+	var rest int64
+	if err == nil {
+		rest, err = io.Copy(f, r)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "", 0, errRequestBodyTooLarge
+		}
+		return "", 0, err
+	}
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		path = f.Name()
+	}
+	return path, int64(size) + rest, nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestHandlerSpillsLargeBodies(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(out)
+	dir := t.TempDir()
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := &ServerConfig{
+		Path:         "/",
+		MaxBodyBytes: 64,
+		Spill:        SpillOptions{Threshold: 8, Dir: dir},
+		Responses:    []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(p, stubForwarder{}, noopLogger{}, cfg, store, nil, context.Background(), &sync.WaitGroup{})
+
+	decode := func() request.RequestData {
+		t.Helper()
+		var env struct {
+			Request request.RequestData `json:"request"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+			t.Fatalf("invalid json: %v (%q)", err, out.String())
+		}
+		return env.Request
+	}
+
+	body := strings.Repeat("0123456789", 3)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "http://localhost/upload", strings.NewReader(body)))
+	h.procWG.Wait()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	record := decode()
+	if record.BodyFile == "" || string(record.Body) != body[:8] || record.Size != int64(len(body)) {
+		t.Fatalf("expected an 8 byte preview of a spilled body, got %+v", record)
+	}
+	if data, err := os.ReadFile(record.BodyFile); err != nil || string(data) != body {
+		t.Fatalf("expected the full body on disk, got %q (%v)", data, err)
+	}
+
+	out.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/small", strings.NewReader("tiny")))
+	h.procWG.Wait()
+	if record := decode(); record.BodyFile != "" || string(record.Body) != "tiny" {
+		t.Fatalf("expected a small body kept in memory, got %+v", record)
+	}
+
+	// Bodies over max_body_bytes are refused and leave no file behind
+	out.Reset()
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "http://localhost/upload", strings.NewReader(strings.Repeat("x", 100))))
+	h.procWG.Wait()
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rr.Code)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected only the first spilled body on disk, got %d files", len(entries))
+	}
+}
+
+func TestHandlerRemovesSpilledBodiesOfUnstoredRequests(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &ServerConfig{
+		Path:         "/in",
+		MaxBodyBytes: 1 << 16,
+		Spill:        SpillOptions{Threshold: 16, Dir: dir},
+		Responses:    []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	h := NewHandler(nil, stubForwarder{}, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+	drop := func(context.Context, *HookEvent) error { return ErrDropRequest }
+	if err := h.AddHook(HookOnReceive, "drop-receive", func(ctx context.Context, ev *HookEvent) error {
+		if ev.Record.Path == "/in/receive" {
+			return drop(ctx, ev)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddHook(HookBeforeStore, "drop-store", drop); err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Repeat("x", 4096)
+	for _, path := range []string{"/outside", "/in/receive", "/in/store"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost"+path, strings.NewReader(body)))
+		h.procWG.Wait()
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Fatalf("expected no spilled body left behind for %s, got %d files", path, len(entries))
+		}
+	}
+}
+
+func TestBuildSpillOptionsDisabledByBodyRedaction(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.Path = filepath.Join("data", "reqtap.db")
+	cfg.Server.Spill.ThresholdBytes = 1024
+	cfg.Privacy.Redact.Headers = []string{"authorization"}
+	if opts := buildSpillOptions(cfg); opts.Threshold != 1024 || opts.Dir != filepath.Join("data", "bodies") {
+		t.Fatalf("expected header redaction to keep spilling, got %+v", opts)
+	}
+
+	cfg.Privacy.Redact.Fields = []string{"password"}
+	if opts := buildSpillOptions(cfg); opts.Threshold != 0 {
+		t.Fatalf("expected no spilling while privacy.redact masks fields, got %+v", opts)
+	}
+
+	cfg.Privacy.Redact.Fields = nil
+	cfg.Hooks = []config.HookConfig{{Action: "redact", RedactFields: []string{"card.number"}}}
+	if opts := buildSpillOptions(cfg); opts.Threshold != 0 {
+		t.Fatalf("expected no spilling while a redact hook masks fields, got %+v", opts)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/funnyzak/reqtap/internal/forwarder"
+)
+
+func TestHandlerTracesReceiveAndForward(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	traceparents := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("Traceparent")
+	}))
+	defer upstream.Close()
+
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: upstream.URL}},
+		ForwardOpts:    ForwardOptions{Timeout: 5},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	fwd := forwarder.NewForwarder(noopLogger{}, forwarder.Options{})
+	defer fwd.Close()
+	h := NewHandler(nil, fwd, noopLogger{}, cfg, nil, nil, context.Background(), &sync.WaitGroup{})
+
+	const callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader("{}"))
+	req.Header.Set("Traceparent", "00-"+callerTrace+"-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.procWG.Wait()
+
+	if got := <-traceparents; !strings.HasPrefix(got, "00-"+callerTrace+"-") {
+		t.Fatalf("expected the caller's trace to be propagated, got %q", got)
+	}
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	receive, forward := spans["reqtap.receive"], spans["reqtap.forward"]
+	if receive == nil || forward == nil {
+		t.Fatalf("expected receive and forward spans, got %v", spans)
+	}
+	if receive.SpanContext().TraceID().String() != callerTrace {
+		t.Fatalf("receive span did not join the caller's trace: %s", receive.SpanContext().TraceID())
+	}
+	if forward.Parent().SpanID() != receive.SpanContext().SpanID() {
+		t.Fatal("expected the forward span to be a child of the receive span")
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/web"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// maxTimelines is how many recent requests keep their processing timeline in memory
+const maxTimelines = 1000
+
+// timelineRecorder keeps the processing timelines of the most recent requests for
+// /api/requests/{id}/timeline; older ones are rebuilt from storage by the web console
+type timelineRecorder struct {
+	mu        sync.Mutex
+	timelines map[string]*web.ProcessingTimeline
+	// order is a ring of the request IDs in arrival order; next is the oldest
+	order [maxTimelines]string
+	next  int
+}
+
+func newTimelineRecorder() *timelineRecorder {
+	return &timelineRecorder{timelines: make(map[string]*web.ProcessingTimeline)}
+}
+
+// received starts the timeline of record, evicting the oldest one
+func (r *timelineRecorder) received(record *request.RequestData) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.timelines[record.ID]; ok {
+		return
+	}
+	if oldest := r.order[r.next]; oldest != "" {
+		delete(r.timelines, oldest)
+	}
+	r.order[r.next] = record.ID
+	r.next = (r.next + 1) % len(r.order)
+	timeline := &web.ProcessingTimeline{RequestID: record.ID, ReceivedAt: record.Timestamp}
+	timeline.AddStep(web.ProcessingStep{Step: web.StepReceived, At: record.Timestamp}, 0)
+	r.timelines[record.ID] = timeline
+}
+
+// stored adds the write of the request to storage, which took took
+func (r *timelineRecorder) stored(requestID string, took time.Duration) {
+	r.step(requestID, web.StepStored, took)
+}
+
+// printed adds the output of the request to the console or log, which took took
+func (r *timelineRecorder) printed(requestID string, took time.Duration) {
+	r.step(requestID, web.StepPrinted, took)
+}
+
+// step adds a step that ended now and took took; requests without a timeline are ignored
+func (r *timelineRecorder) step(requestID, step string, took time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if timeline := r.timelines[requestID]; timeline != nil {
+		timeline.AddStep(web.ProcessingStep{Step: step, At: time.Now()}, took)
+	}
+}
+
+// forwarded adds the start and outcome of each delivery in results; started is when forwarding
+// began, so the start steps show how long each target waited for its turn, limits and a worker
+func (r *timelineRecorder) forwarded(requestID string, started time.Time, results []forwarder.Result) {
+	if r == nil || len(results) == 0 {
+		return
+	}
+	done := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timeline := r.timelines[requestID]
+	if timeline == nil {
+		return
+	}
+	for _, res := range results {
+		success := res.Success
+		completed := web.ProcessingStep{
+			Step:       web.StepForwardCompleted,
+			Target:     res.URL,
+			At:         done,
+			StatusCode: res.StatusCode,
+			Attempts:   res.Attempts,
+			Success:    &success,
+			Error:      res.Error,
+		}
+		if res.StartedAt.IsZero() {
+			// Skipped before the first attempt, e.g. by an open circuit
+			timeline.AddStep(completed, done.Sub(started))
+			continue
+		}
+		timeline.AddStep(web.ProcessingStep{Step: web.StepForwardStarted, Target: res.URL, At: res.StartedAt}, res.StartedAt.Sub(started))
+		completed.At = res.StartedAt.Add(res.Duration)
+		timeline.AddStep(completed, res.Duration)
+	}
+}
+
+// get returns a copy of the timeline of requestID with its steps in the order they ended
+func (r *timelineRecorder) get(requestID string) (web.ProcessingTimeline, bool) {
+	if r == nil {
+		return web.ProcessingTimeline{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timeline := r.timelines[requestID]
+	if timeline == nil {
+		return web.ProcessingTimeline{}, false
+	}
+	copied := *timeline
+	copied.Steps = append([]web.ProcessingStep(nil), timeline.Steps...)
+	sort.SliceStable(copied.Steps, func(i, j int) bool {
+		return copied.Steps[i].At.Before(copied.Steps[j].At)
+	})
+	return copied, true
+}
+
+// ProcessingTimeline returns the recorded processing timeline of a recent request
+func (h *Handler) ProcessingTimeline(requestID string) (web.ProcessingTimeline, bool) {
+	return h.timelines.get(requestID)
+}
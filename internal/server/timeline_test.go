@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/printer"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/internal/web"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// slowForwarder waits before each delivery like a target that takes its time to answer
+type slowForwarder struct {
+	stubForwarder
+	delay time.Duration
+}
+
+func (f slowForwarder) Forward(ctx context.Context, data *request.RequestData, targets []forwarder.Target) ([]forwarder.Result, error) {
+	started := time.Now()
+	time.Sleep(f.delay)
+	results, err := f.stubForwarder.Forward(ctx, data, targets)
+	for i := range results {
+		results[i].StartedAt = started
+		results[i].Duration = time.Since(started)
+	}
+	return results, err
+}
+
+func TestProcessingTimeline(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	p := printer.NewJSONPrinter(noopLogger{})
+	p.SetOutput(io.Discard)
+	cfg := &ServerConfig{
+		Path:           "/",
+		ForwardTargets: []forwarder.Target{{URL: "http://slow.test/hook"}},
+		ForwardOpts:    ForwardOptions{Timeout: 5},
+		Responses:      []ImmediateResponseRule{{Name: "ok", Status: http.StatusOK}},
+	}
+	var wg sync.WaitGroup
+	h := NewHandler(p, slowForwarder{delay: 50 * time.Millisecond}, noopLogger{}, cfg, store, nil, context.Background(), &wg)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/hook", strings.NewReader("{}")))
+	wg.Wait()
+	stored, _, err := store.List(storage.ListOptions{Limit: 1})
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("expected the stored request, got %v (%v)", stored, err)
+	}
+
+	timeline, ok := h.ProcessingTimeline(stored[0].ID)
+	if !ok {
+		t.Fatal("expected a timeline for the request")
+	}
+	var steps []string
+	for _, step := range timeline.Steps {
+		steps = append(steps, step.Step)
+	}
+	want := []string{web.StepReceived, web.StepStored, web.StepForwardStarted, web.StepForwardCompleted, web.StepPrinted}
+	if strings.Join(steps, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected steps %v, got %v", want, steps)
+	}
+	completed := timeline.Steps[3]
+	if completed.Target != "http://slow.test/hook" || completed.DurationMs < 50 || completed.Success == nil || !*completed.Success {
+		t.Fatalf("expected the slow delivery to be reported, got %+v", completed)
+	}
+	if completed.OffsetMs < completed.DurationMs {
+		t.Fatalf("expected the offset to include the delivery, got %+v", completed)
+	}
+	if _, ok := h.ProcessingTimeline("missing"); ok {
+		t.Fatal("expected no timeline for an unknown request")
+	}
+}
+
+func TestTimelineRecorderEvictsOldest(t *testing.T) {
+	r := newTimelineRecorder()
+	for i := 0; i <= maxTimelines; i++ {
+		r.received(&request.RequestData{ID: fmt.Sprintf("REQ-%d", i), Timestamp: time.Now()})
+	}
+	if _, ok := r.get("REQ-0"); ok {
+		t.Fatal("expected the oldest timeline to be evicted")
+	}
+	if _, ok := r.get("REQ-1"); !ok {
+		t.Fatal("expected the second timeline to be kept")
+	}
+	if len(r.timelines) != maxTimelines {
+		t.Fatalf("expected %d timelines, got %d", maxTimelines, len(r.timelines))
+	}
+}
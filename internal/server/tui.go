@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/tui"
+	"github.com/funnyzak/reqtap/pkg/i18n"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// outputModeTUI replaces the console printer with the interactive terminal UI.
+const outputModeTUI = "tui"
+
+func usesTUI(cfg *config.Config) bool {
+	return !cfg.Output.Silence && strings.EqualFold(cfg.Output.Mode, outputModeTUI)
+}
+
+func newTUI(cfg *config.Config, translator *i18n.Translator, togglePause func()) *tui.UI {
+	return tui.New(tui.Options{
+		Translator:  translator,
+		Locale:      cfg.Output.Locale,
+		Replay:      replayToListener(cfg.Server),
+		TogglePause: togglePause,
+	})
+}
+
+// replayToListener sends a captured request back to this instance, so the replay is captured,
+// answered and forwarded like the original.
+func replayToListener(cfg config.ServerConfig) tui.Replayer {
+	client := &http.Client{}
+	if cfg.TLS.Enabled() {
+		// The certificate is issued for the public name, not the loopback address dialed here
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return func(ctx context.Context, data *request.RequestData) (int, error) {
+		target := fmt.Sprintf("%s://127.0.0.1:%d%s", cfg.Scheme(), cfg.Port, data.Path)
+		if data.Query != "" {
+			target += "?" + data.Query
+		}
+		body, size, err := data.OpenBody()
+		if err != nil {
+			return 0, err
+		}
+		req, err := http.NewRequestWithContext(ctx, data.Method, target, body)
+		if err != nil {
+			body.Close()
+			return 0, err
+		}
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+			body.Close()
+		}
+		for key, values := range data.Headers {
+			if strings.EqualFold(key, "Content-Length") || strings.EqualFold(key, "Host") {
+				continue
+			}
+			req.Header[key] = append([]string(nil), values...)
+		}
+		if host := data.Headers.Get("Host"); host != "" {
+			req.Host = host
+		}
+		req.Header.Set("X-ReqTap-Replay", "true")
+		req.Header.Set("X-ReqTap-Original-ID", data.ID)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"io"
+
+	"github.com/funnyzak/reqtap/internal/tunnel"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// tunnelRule is the mock rule name recorded for requests answered through the tunnel.
+const tunnelRule = "tunnel"
+
+// hopHeaders describe the local connection and are not copied onto the sender's response.
+var hopHeaders = []string{"Connection", "Content-Length", "Keep-Alive", "Transfer-Encoding", "Upgrade"}
+
+// SetTunnel relays captured requests to the tunnel client connected to hub, if any.
+func (h *Handler) SetTunnel(hub *tunnel.Hub) {
+	h.mu.Lock()
+	h.tunnel = hub
+	h.mu.Unlock()
+}
+
+// tunnelHub returns the hub when a tunnel client is connected.
+func (h *Handler) tunnelHub() *tunnel.Hub {
+	h.mu.RLock()
+	hub := h.tunnel
+	h.mu.RUnlock()
+	if hub == nil || !hub.Connected() {
+		return nil
+	}
+	return hub
+}
+
+// relayTunnel answers the request with the response of the local instance behind the tunnel.
+// It reports false when the relay failed and the mock rules should answer instead.
+func (h *Handler) relayTunnel(hub *tunnel.Hub, ex *Exchange) bool {
+	record := ex.Record
+	body, _, err := record.OpenBody()
+	if err != nil {
+		h.logger.Warn("Failed to read body for tunnel", "error", err, "request_id", record.ID)
+		return false
+	}
+	payload, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		h.logger.Warn("Failed to read body for tunnel", "error", err, "request_id", record.ID)
+		return false
+	}
+
+	headers := ex.Request.Header.Clone()
+	headers.Set("Host", ex.Request.Host)
+	resp, err := hub.RoundTrip(ex.Request.Context(), &tunnel.Request{
+		Method:  record.Method,
+		Path:    record.Path,
+		Query:   record.Query,
+		Headers: headers,
+		Body:    payload,
+	})
+	if err != nil {
+		h.logger.Warn("Tunnel relay failed, answering with mock rules", "error", err, "request_id", record.ID)
+		return false
+	}
+
+	for key, values := range resp.Headers {
+		for _, value := range values {
+			ex.Writer.Header().Add(key, value)
+		}
+	}
+	for _, key := range hopHeaders {
+		ex.Writer.Header().Del(key)
+	}
+	ex.Writer.WriteHeader(resp.Status)
+	if _, err := ex.Writer.Write(resp.Body); err != nil {
+		h.logger.Debug("Failed to write tunnel response", "error", err, "request_id", record.ID)
+	}
+	record.MockResponse = request.MockResponse{Rule: tunnelRule, Status: resp.Status}
+	return true
+}
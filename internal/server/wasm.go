@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+
+	"github.com/funnyzak/reqtap/internal/wasm"
+)
+
+// StageWasmTransform runs the configured WebAssembly transforms.
+const StageWasmTransform = "wasm-transform"
+
+// installWasmStage runs WebAssembly transforms in order before the request is persisted.
+func (h *Handler) installWasmStage(transformers []*wasm.Transformer) error {
+	if len(transformers) == 0 {
+		return nil
+	}
+	return h.pipeline.InsertBefore(StageStore, Stage{Name: StageWasmTransform, Phase: PhaseAsync, Run: func(ctx context.Context, ex *Exchange) error {
+		for _, t := range transformers {
+			result, drop, err := t.Transform(ctx, ex.Record)
+			if err != nil {
+				h.logger.Error("WASM transform failed", "transform", t.Name(), "error", err, "request_id", ex.Record.ID)
+				continue
+			}
+			if drop {
+				h.logger.Debug("Request dropped by WASM transform", "transform", t.Name(), "request_id", ex.Record.ID)
+				return ErrStopPipeline
+			}
+			applyTransformResult(ex, result)
+		}
+		return nil
+	}})
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// webSocketRule is the mock rule name recorded for upgraded requests.
+const webSocketRule = "websocket"
+
+// controlWriteWait bounds how long relaying a control frame may block.
+const controlWriteWait = 5 * time.Second
+
+// WebSocketOptions controls WebSocket capture on the capture path
+type WebSocketOptions struct {
+	Enable       bool
+	ProxyURL     string
+	PreviewBytes int
+}
+
+// FrameNotifier receives every captured WebSocket frame, e.g. to stream it to live consoles.
+type FrameNotifier interface {
+	NotifyFrame(frame *request.WebSocketFrame)
+}
+
+var captureUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Captured clients may come from any origin; enforcing it is the upstream's job
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// handshakeHeaders are managed by the dialer and must not be copied to the upstream handshake.
+var handshakeHeaders = map[string]struct{}{
+	"Host":                     {},
+	"Upgrade":                  {},
+	"Connection":               {},
+	"Content-Length":           {},
+	"Sec-Websocket-Key":        {},
+	"Sec-Websocket-Version":    {},
+	"Sec-Websocket-Extensions": {},
+}
+
+func (h *Handler) acceptsWebSocket(r *http.Request) bool {
+	return h.currentConfig().WebSocket.Enable && websocket.IsWebSocketUpgrade(r)
+}
+
+// upgradeWebSocket completes the handshake (after dialing the upstream when proxying)
+// and relays frames in the background; the handshake request itself is processed as usual.
+func (h *Handler) upgradeWebSocket(ex *Exchange) error {
+	opts := h.currentConfig().WebSocket
+	record := ex.Record
+	record.MockResponse = request.MockResponse{Rule: webSocketRule, Status: http.StatusSwitchingProtocols}
+
+	responseHeader := http.Header{}
+	var upstream *websocket.Conn
+	if opts.ProxyURL != "" {
+		conn, err := dialWebSocketUpstream(ex.Request, opts.ProxyURL)
+		if err != nil {
+			h.logger.Error("Failed to connect WebSocket upstream", "error", err, "url", opts.ProxyURL, "request_id", record.ID)
+			h.writeError(ex.Writer, http.StatusBadGateway)
+			record.MockResponse.Status = http.StatusBadGateway
+			return nil
+		}
+		upstream = conn
+		if protocol := conn.Subprotocol(); protocol != "" {
+			responseHeader.Set("Sec-WebSocket-Protocol", protocol)
+		}
+	} else if protocols := websocket.Subprotocols(ex.Request); len(protocols) > 0 {
+		// Without an upstream, accept the client's preferred subprotocol so strict clients connect
+		responseHeader.Set("Sec-WebSocket-Protocol", protocols[0])
+	}
+	h.setServerHeader(responseHeader)
+
+	client, err := captureUpgrader.Upgrade(ex.Writer, ex.Request, responseHeader)
+	if err != nil {
+		// The upgrader has already answered the client
+		h.logger.Warn("Failed to upgrade WebSocket request", "error", err, "request_id", record.ID)
+		record.MockResponse.Status = http.StatusBadRequest
+		if upstream != nil {
+			upstream.Close()
+		}
+		return nil
+	}
+
+	h.procWG.Add(1)
+	go func() {
+		defer h.procWG.Done()
+		h.relayWebSocket(record.ID, client, upstream, opts.PreviewBytes)
+	}()
+	return nil
+}
+
+func dialWebSocketUpstream(r *http.Request, target string) (*websocket.Conn, error) {
+	header := http.Header{}
+	for key, values := range r.Header {
+		if _, skip := handshakeHeaders[http.CanonicalHeaderKey(key)]; skip {
+			continue
+		}
+		header[key] = append([]string(nil), values...)
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	conn, resp, err := dialer.DialContext(r.Context(), target, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (status %d)", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// relayWebSocket logs frames until either side closes; without an upstream the client is only observed.
+func (h *Handler) relayWebSocket(requestID string, client, upstream *websocket.Conn, previewBytes int) {
+	closeAll := func() {
+		client.Close()
+		if upstream != nil {
+			upstream.Close()
+		}
+	}
+	defer closeAll()
+	// Shutdown cancels baseCtx; hijacked connections are not closed by http.Server
+	stop := context.AfterFunc(h.baseCtx, closeAll)
+	defer stop()
+
+	h.logger.Info("WebSocket session opened", "request_id", requestID, "proxied", upstream != nil)
+
+	inbound, outbound := 0, 0
+	if upstream == nil {
+		inbound = h.pumpWebSocket(requestID, request.FrameInbound, client, nil, previewBytes)
+	} else {
+		done := make(chan int, 1)
+		go func() {
+			frames := h.pumpWebSocket(requestID, request.FrameOutbound, upstream, client, previewBytes)
+			closeAll()
+			done <- frames
+		}()
+		inbound = h.pumpWebSocket(requestID, request.FrameInbound, client, upstream, previewBytes)
+		closeAll()
+		outbound = <-done
+	}
+
+	h.logger.Info("WebSocket session closed", "request_id", requestID, "inbound_frames", inbound, "outbound_frames", outbound)
+}
+
+// pumpWebSocket reads frames from src, reports them and relays them to dst when set.
+// It returns the number of frames read.
+func (h *Handler) pumpWebSocket(requestID, direction string, src, dst *websocket.Conn, previewBytes int) int {
+	frames := 0
+	observe := func(opcode string, payload []byte) {
+		frames++
+		h.observeFrame(request.NewWebSocketFrame(requestID, direction, opcode, payload, previewBytes))
+	}
+
+	src.SetPingHandler(func(data string) error {
+		observe("ping", []byte(data))
+		deadline := time.Now().Add(controlWriteWait)
+		if dst != nil {
+			return dst.WriteControl(websocket.PingMessage, []byte(data), deadline)
+		}
+		err := src.WriteControl(websocket.PongMessage, []byte(data), deadline)
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+	src.SetPongHandler(func(data string) error {
+		observe("pong", []byte(data))
+		if dst != nil {
+			return dst.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(controlWriteWait))
+		}
+		return nil
+	})
+
+	for {
+		messageType, payload, err := src.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				observe("close", []byte(fmt.Sprintf("%d %s", closeErr.Code, closeErr.Text)))
+				if dst != nil {
+					_ = dst.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(closeErr.Code, closeErr.Text),
+						time.Now().Add(controlWriteWait))
+				}
+			}
+			return frames
+		}
+		observe(opcodeName(messageType), payload)
+		if dst != nil {
+			if err := dst.WriteMessage(messageType, payload); err != nil {
+				h.logger.Warn("Failed to relay WebSocket frame", "error", err, "request_id", requestID, "direction", direction)
+				return frames
+			}
+		}
+	}
+}
+
+func (h *Handler) observeFrame(frame *request.WebSocketFrame) {
+	if h.paused.Load() {
+		return
+	}
+	log := h.logger.Info
+	if frame.Opcode == "ping" || frame.Opcode == "pong" {
+		log = h.logger.Debug
+	}
+	log("WebSocket frame",
+		"request_id", frame.RequestID,
+		"direction", frame.Direction,
+		"opcode", frame.Opcode,
+		"size", frame.Size,
+		"preview", frame.Preview,
+	)
+	if notifier, ok := h.web.(FrameNotifier); ok {
+		notifier.NotifyFrame(frame)
+	}
+}
+
+func opcodeName(messageType int) string {
+	switch messageType {
+	case websocket.TextMessage:
+		return "text"
+	case websocket.BinaryMessage:
+		return "binary"
+	case websocket.CloseMessage:
+		return "close"
+	case websocket.PingMessage:
+		return "ping"
+	case websocket.PongMessage:
+		return "pong"
+	default:
+		return fmt.Sprintf("opcode-%d", messageType)
+	}
+}
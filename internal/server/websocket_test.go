@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// frameRecorder collects broadcast records and frames
+type frameRecorder struct {
+	mu      sync.Mutex
+	records []*storage.StoredRequest
+	frames  []*request.WebSocketFrame
+}
+
+func (r *frameRecorder) Record(s *storage.StoredRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, s)
+}
+
+func (r *frameRecorder) Close() {}
+
+func (r *frameRecorder) NotifyFrame(frame *request.WebSocketFrame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, frame)
+}
+
+func (r *frameRecorder) snapshot() ([]*storage.StoredRequest, []*request.WebSocketFrame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*storage.StoredRequest(nil), r.records...), append([]*request.WebSocketFrame(nil), r.frames...)
+}
+
+func newWebSocketHandler(t *testing.T, opts WebSocketOptions) (*Handler, *frameRecorder, string) {
+	t.Helper()
+	recorder := &frameRecorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	h := NewHandler(nil, nil, noopLogger{}, &ServerConfig{Path: "/", WebSocket: opts}, nil, recorder, ctx, wg)
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		wg.Wait()
+	})
+	return h, recorder, "ws" + strings.TrimPrefix(srv.URL, "http") + "/socket"
+}
+
+func waitForFrames(t *testing.T, recorder *frameRecorder, n int) []*request.WebSocketFrame {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, frames := recorder.snapshot(); len(frames) >= n {
+			return frames
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, frames := recorder.snapshot()
+	t.Fatalf("expected %d frames, got %d", n, len(frames))
+	return nil
+}
+
+func TestWebSocketCapture(t *testing.T) {
+	_, recorder, url := newWebSocketHandler(t, WebSocketOptions{Enable: true, PreviewBytes: 4})
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Sec-WebSocket-Protocol": {"chat"}})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || conn.Subprotocol() != "chat" {
+		t.Fatalf("unexpected handshake: status %d protocol %q", resp.StatusCode, conn.Subprotocol())
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello world")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte{0xff, 0x00}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	conn.Close()
+
+	frames := waitForFrames(t, recorder, 3)
+	text, binary, closing := frames[0], frames[1], frames[2]
+	if text.Direction != request.FrameInbound || text.Opcode != "text" || text.Size != 11 || text.Preview != "hell" || !text.Truncated {
+		t.Fatalf("unexpected text frame: %#v", text)
+	}
+	if binary.Opcode != "binary" || !binary.PreviewHex || binary.Preview != "ff00" {
+		t.Fatalf("unexpected binary frame: %#v", binary)
+	}
+	if closing.Opcode != "close" || !strings.HasPrefix(closing.Preview, "1000") {
+		t.Fatalf("unexpected close frame: %#v", closing)
+	}
+
+	records, _ := recorder.snapshot()
+	if len(records) != 1 || records[0].MockResponse.Status != http.StatusSwitchingProtocols || records[0].ID != text.RequestID {
+		t.Fatalf("expected the handshake to be recorded, got %#v", records)
+	}
+}
+
+func TestWebSocketProxy(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, append([]byte("echo:"), payload...))
+		}
+	}))
+	defer upstream.Close()
+
+	_, recorder, url := newWebSocketHandler(t, WebSocketOptions{
+		Enable:       true,
+		ProxyURL:     "ws" + strings.TrimPrefix(upstream.URL, "http"),
+		PreviewBytes: 256,
+	})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, payload, err := conn.ReadMessage()
+	if err != nil || string(payload) != "echo:ping" {
+		t.Fatalf("expected relayed echo, got %q (%v)", payload, err)
+	}
+
+	frames := waitForFrames(t, recorder, 2)
+	if frames[0].Direction != request.FrameInbound || frames[0].Preview != "ping" {
+		t.Fatalf("unexpected inbound frame: %#v", frames[0])
+	}
+	if frames[1].Direction != request.FrameOutbound || frames[1].Preview != "echo:ping" {
+		t.Fatalf("unexpected outbound frame: %#v", frames[1])
+	}
+}
+
+func TestWebSocketProxyUnavailable(t *testing.T) {
+	_, recorder, url := newWebSocketHandler(t, WebSocketOptions{Enable: true, ProxyURL: "ws://127.0.0.1:1"})
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("expected handshake to fail when the upstream is down")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %#v", resp)
+	}
+	if _, frames := recorder.snapshot(); len(frames) != 0 {
+		t.Fatalf("expected no frames, got %d", len(frames))
+	}
+}
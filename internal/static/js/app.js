@@ -3,14 +3,18 @@ import { createI18n } from './i18n.js';
 const CONFIG = window.__REQTAP__ || {};
 const API_BASE = CONFIG.apiBase || '/api';
 const WS_PATH = CONFIG.wsEndpoint || `${API_BASE}/ws`;
+const EVENTS_PATH = CONFIG.eventsEndpoint || `${API_BASE}/events`;
 const AUTH_ENABLED = CONFIG.authEnabled !== false;
 const MAX_REQUESTS = CONFIG.maxRequests || 500;
+const WS_HISTORY = CONFIG.wsHistory || 0;
 const EXPORT_ENABLED = CONFIG.exportEnabled !== false;
+const JWT_VIEW = CONFIG.jwtView !== false;
 const WEB_BASE = CONFIG.webBase || '/web';
 const ROLE_ADMIN = CONFIG.roleAdmin || 'admin';
 const ROLE_VIEWER = CONFIG.roleViewer || 'viewer';
 const THEME_STORAGE_KEY = 'reqtap-theme';
 const DEFAULT_THEME = 'dark';
+const HEX_VIEW_BYTES = 4096;
 const i18n = createI18n({
   defaultLocale: CONFIG.defaultLocale || 'en',
   supportedLocales: CONFIG.supportedLocales || ['en'],
@@ -22,45 +26,98 @@ const state = {
   filters: {
     search: '',
     method: '',
+    claim: '',
+    tag: '',
+    pinned: false,
   },
+  username: '',
   userRole: '',
   locale: CONFIG.defaultLocale || 'en',
   activeRequest: null,
+  activeComments: [],
+  activeTimeline: null,
   activeRequestBody: '',
   theme: DEFAULT_THEME,
   detailBodyRaw: '',
   detailBodyPretty: '',
   detailBodyMode: 'raw',
   wsStatus: 'connecting',
+  timelineBucket: 'hour',
+  timeline: null,
+  anomaly: null,
+  capture: { paused: false },
+  diffBase: null,
 };
 
 let ws;
+let eventSource;
 let reconnectTimer;
+// Set once a WebSocket fails before it opens, e.g. when a proxy blocks the upgrade
+let useEventSource = false;
 let actionStatusTimer;
+let timelineTimer;
 
 const els = {
   body: document.getElementById('requests-body'),
   empty: document.getElementById('empty-state'),
   search: document.getElementById('search-input'),
   method: document.getElementById('method-filter'),
+  claimFilter: document.getElementById('claim-filter'),
+  tagFilter: document.getElementById('tag-filter'),
+  pinnedFilter: document.getElementById('pinned-filter'),
   refresh: document.getElementById('refresh-btn'),
   logout: document.getElementById('logout-btn'),
   total: document.getElementById('total-counter'),
   filtered: document.getElementById('filtered-counter'),
   exportBtns: document.querySelectorAll('.export-btn'),
   exportSection: document.getElementById('export-section'),
+  importBtn: document.getElementById('import-btn'),
+  importFile: document.getElementById('import-file'),
   wsStatus: document.getElementById('ws-status'),
   user: document.getElementById('current-user'),
   role: document.getElementById('current-role'),
   modal: document.getElementById('detail-modal'),
   modalClose: document.getElementById('detail-close'),
   detailMeta: document.getElementById('detail-meta'),
+  claimStatus: document.getElementById('detail-claim-status'),
+  claimBtn: document.getElementById('claim-btn'),
+  claimBtnLabel: document.getElementById('claim-btn-label'),
+  pinBtn: document.getElementById('pin-btn'),
+  pinBtnLabel: document.getElementById('pin-btn-label'),
+  diffBtn: document.getElementById('diff-btn'),
+  diffBtnLabel: document.getElementById('diff-btn-label'),
+  diffModal: document.getElementById('diff-modal'),
+  diffClose: document.getElementById('diff-close'),
+  diffSubtitle: document.getElementById('diff-subtitle'),
+  diffContent: document.getElementById('diff-content'),
+  groupsBtn: document.getElementById('groups-btn'),
+  groupsModal: document.getElementById('groups-modal'),
+  groupsClose: document.getElementById('groups-close'),
+  groupsSubtitle: document.getElementById('groups-subtitle'),
+  groupsContent: document.getElementById('groups-content'),
+  statsBtn: document.getElementById('stats-btn'),
+  statsModal: document.getElementById('stats-modal'),
+  statsClose: document.getElementById('stats-close'),
+  statsSubtitle: document.getElementById('stats-subtitle'),
+  statsContent: document.getElementById('stats-content'),
+  detailComments: document.getElementById('detail-comments'),
+  commentForm: document.getElementById('comment-form'),
+  commentInput: document.getElementById('comment-input'),
+  exportComments: document.getElementById('export-comments'),
+  annotationForm: document.getElementById('annotation-form'),
+  annotationTags: document.getElementById('annotation-tags'),
+  annotationNote: document.getElementById('annotation-note'),
   detailHeaders: document.getElementById('detail-headers'),
   detailBody: document.getElementById('detail-body'),
+  detailJwtSection: document.getElementById('detail-jwt-section'),
+  detailJwt: document.getElementById('detail-jwt'),
+  detailProcessing: document.getElementById('detail-processing'),
+  detailProcessingPartial: document.getElementById('detail-processing-partial'),
   requestDownload: document.getElementById('request-download-btn'),
   requestCopy: document.getElementById('request-copy-btn'),
   curlCopy: document.getElementById('curl-copy-btn'),
   replayBtn: document.getElementById('replay-btn'),
+  reforwardBtn: document.getElementById('reforward-btn'),
   replayModal: document.getElementById('replay-modal'),
   replayClose: document.getElementById('replay-close'),
   replayCancel: document.getElementById('replay-cancel'),
@@ -83,6 +140,17 @@ const els = {
   bodyCopyBtn: document.getElementById('body-copy-btn'),
   bodyWrapBtn: document.getElementById('body-wrap-btn'),
   bodyFormatToggle: document.getElementById('body-format-toggle'),
+  timelineGrid: document.getElementById('timeline-grid'),
+  timelineSummary: document.getElementById('timeline-summary'),
+  timelineBuckets: document.querySelectorAll('[data-timeline-bucket]'),
+  anomalyBanner: document.getElementById('anomaly-banner'),
+  anomalyMessage: document.getElementById('anomaly-message'),
+  anomalyDismiss: document.getElementById('anomaly-dismiss'),
+  captureToggle: document.getElementById('capture-toggle'),
+  captureToggleIcon: document.getElementById('capture-toggle-icon'),
+  captureToggleLabel: document.getElementById('capture-toggle-label'),
+  captureBanner: document.getElementById('capture-banner'),
+  captureMessage: document.getElementById('capture-message'),
 };
 
 function getStoredTheme() {
@@ -159,6 +227,43 @@ function buildDetailMeta(item, fullPath, bodySize) {
     { label: i18n.t('detail.meta.full_path'), value: fullPath, full: true, code: true },
     { label: i18n.t('detail.meta.user_agent'), value: item.user_agent || '-', full: true, mono: true },
   ];
+  if (item.content_encoding) {
+    entries.splice(4, 0, {
+      label: i18n.t('detail.meta.wire_size'),
+      value: `${formatSize(item.wire_size || 0)} · ${item.content_encoding}`,
+      pill: 'metric',
+    });
+  }
+  if (item.body_file) {
+    entries.splice(4, 0, {
+      label: i18n.t('detail.meta.full_body'),
+      value: i18n.t('detail.meta.full_body_download'),
+      href: `${API_BASE}/requests/${encodeURIComponent(item.id)}/body`,
+    });
+  }
+  if (item.is_binary && (item.size || 0) > 0) {
+    entries.splice(4, 0, {
+      label: i18n.t('detail.meta.hex_view'),
+      value: i18n.t('detail.meta.hex_view_open'),
+      href: `${API_BASE}/requests/${encodeURIComponent(item.id)}/body?view=hex&range=0-${HEX_VIEW_BYTES}`,
+      inline: true,
+    });
+  }
+  if (item.instance) {
+    entries.splice(6, 0, { label: i18n.t('detail.meta.instance'), value: item.instance, mono: true });
+  }
+  if (item.credential) {
+    entries.splice(6, 0, { label: i18n.t('detail.meta.credential'), value: item.credential, mono: true });
+  }
+  if (item.grpc) {
+    const status = item.grpc.status ? ` · grpc-status ${item.grpc.status}` : '';
+    entries.splice(6, 0, {
+      label: i18n.t('detail.meta.grpc_method'),
+      value: `${item.grpc.service}/${item.grpc.method}${status}`,
+      full: true,
+      code: true,
+    });
+  }
 
   const markup = entries
     .map((entry) => {
@@ -174,6 +279,10 @@ function buildDetailMeta(item, fullPath, bodySize) {
       } else if (entry.mono) {
         valueMarkup = `<span class="detail-meta__mono">${safeValue}</span>`;
       }
+      if (entry.href) {
+        const target = entry.inline ? 'target="_blank" rel="noopener"' : 'download';
+        valueMarkup = `<a href="${escapeHtml(entry.href)}" ${target}>${valueMarkup}</a>`;
+      }
       if (entry.pill) {
         valueMarkup = `<span class="detail-pill detail-pill--${entry.pill}">${valueMarkup}</span>`;
       }
@@ -263,6 +372,37 @@ function updateBodyFormatToggle() {
   }
 }
 
+// formatGrpcCall lists the decoded messages of both directions for the pretty body view.
+function formatGrpcCall(call) {
+  const section = (title, messages) => {
+    if (!messages || messages.length === 0) {
+      return '';
+    }
+    const blocks = messages.map((message, index) => {
+      const heading = `# ${title} ${index + 1} (${message.size} B${message.raw ? `, ${i18n.t('grpc.raw')}` : ''})`;
+      if (message.error) {
+        return `${heading}\n${i18n.t('grpc.decode_error', { error: message.error })}`;
+      }
+      return `${heading}\n${JSON.stringify(message.json, null, 2)}`;
+    });
+    return blocks.join('\n\n');
+  };
+  const parts = [section(i18n.t('grpc.request'), call.requests), section(i18n.t('grpc.response'), call.responses)];
+  if (call.status_message) {
+    parts.push(`# grpc-message: ${call.status_message}`);
+  }
+  return parts.filter(Boolean).join('\n\n') || null;
+}
+
+// formatProtobufBody shows a body decoded through output.body_view.protobuf for the pretty body view.
+function formatProtobufBody(body) {
+  const heading = `# ${body.message || i18n.t('protobuf.raw')}`;
+  if (body.error) {
+    return `${heading}\n${i18n.t('grpc.decode_error', { error: body.error })}`;
+  }
+  return `${heading}\n${JSON.stringify(body.json, null, 2)}`;
+}
+
 function renderDetailBody() {
   if (!els.detailBody) {
     return;
@@ -308,6 +448,7 @@ async function loadUser() {
     const authEnabled = data.auth !== false;
     els.user.textContent = username;
     els.role.textContent = role;
+    state.username = username;
     state.userRole = role;
     updateUIForRole(role, authEnabled);
   } catch (error) {
@@ -384,6 +525,9 @@ async function loadRequests() {
     const payload = await resp.json();
     state.requests = payload.data || [];
     render();
+    if (state.filters.pinned) {
+      loadPinned();
+    }
   } catch (error) {
     console.error('Failed to load requests', error);
   }
@@ -404,7 +548,47 @@ function render() {
     cells[0].textContent = formatTime(item.timestamp);
     cells[1].innerHTML = `<span class="method-badge">${item.method}</span>`;
     cells[2].textContent = `${item.path}${item.query ? `?${item.query}` : ''}`;
+    if (item.pinned) {
+      const pin = document.createElement('i');
+      pin.className = 'fa-solid fa-thumbtack pin-badge';
+      pin.title = i18n.t('pin.pinned');
+      cells[2].prepend(pin);
+    }
+    if (item.forward_violations) {
+      const warning = document.createElement('i');
+      warning.className = 'fa-solid fa-triangle-exclamation violation-badge';
+      warning.title = i18n.t('expectations.violated');
+      cells[2].prepend(warning);
+    }
+    if (item.duplicate_of) {
+      const copy = document.createElement('i');
+      copy.className = 'fa-solid fa-clone duplicate-badge';
+      copy.title = i18n.t('dedup.duplicate_of', { id: item.duplicate_of });
+      cells[2].prepend(copy);
+    }
+    if (item.claim) {
+      const badge = document.createElement('span');
+      badge.className = 'claim-badge';
+      badge.textContent = item.claim.user;
+      badge.title = i18n.t('claim.claimed_by', { user: item.claim.user });
+      cells[2].appendChild(badge);
+    }
+    (item.tags || []).forEach((tag) => {
+      const badge = document.createElement('span');
+      badge.className = 'tag-badge';
+      badge.textContent = tag;
+      cells[2].appendChild(badge);
+    });
+    if (item.note) {
+      cells[2].title = item.note;
+    }
     cells[3].textContent = item.remote_addr;
+    if (item.instance) {
+      const badge = document.createElement('span');
+      badge.className = 'instance-badge';
+      badge.textContent = item.instance;
+      cells[3].appendChild(badge);
+    }
     cells[4].textContent = item.user_agent || '-';
     cells[5].textContent = formatSize(item.size || item.content_length || 0);
     clone.addEventListener('click', () => openDetail(item));
@@ -415,16 +599,29 @@ function render() {
 function applyFilters() {
   const search = state.filters.search.toLowerCase();
   const method = state.filters.method.toUpperCase();
+  const claim = state.filters.claim;
+  const tags = parseTags(state.filters.tag);
   return state.requests.filter((req) => {
+    if (state.filters.pinned && !req.pinned) {
+      return false;
+    }
+    if (tags.length && !tags.every((tag) => (req.tags || []).includes(tag))) {
+      return false;
+    }
     if (method && req.method !== method) {
       return false;
     }
+    if (claim && !matchesClaim(req, claim)) {
+      return false;
+    }
     if (search) {
       const target = [
         req.path,
         req.query,
         req.remote_addr,
         req.user_agent,
+        req.instance,
+        req.note,
       ].join(' ').toLowerCase();
       return target.includes(search);
     }
@@ -432,14 +629,177 @@ function applyFilters() {
   });
 }
 
+function parseTags(value) {
+  return (value || '')
+    .split(',')
+    .map((tag) => tag.trim().toLowerCase())
+    .filter(Boolean);
+}
+
+function matchesClaim(req, claim) {
+  const owner = req.claim ? req.claim.user : '';
+  switch (claim) {
+    case 'none':
+      return !owner;
+    case 'any':
+      return Boolean(owner);
+    case 'mine':
+      return Boolean(owner) && owner.toLowerCase() === state.username.toLowerCase();
+    default:
+      return true;
+  }
+}
+
 function pushRequest(data) {
-  state.requests.unshift(data);
-  if (state.requests.length > MAX_REQUESTS) {
-    state.requests.length = MAX_REQUESTS;
+  // A request broadcast while the history backfill was built may arrive twice
+  if (state.requests.some((item) => item.id === data.id)) {
+    return;
   }
+  state.requests.unshift(data);
+  state.requests = trimRequests(state.requests);
+  render();
+  scheduleTimelineRefresh();
+}
+
+// applyHistory merges the backfill replayed on websocket connect, newest first.
+function applyHistory(items) {
+  const known = new Set(items.map((item) => item.id));
+  const live = state.requests.filter((item) => !known.has(item.id));
+  state.requests = trimRequests(
+    live.concat(items).sort((a, b) => new Date(b.timestamp) - new Date(a.timestamp))
+  );
   render();
 }
 
+// trimRequests keeps the newest MAX_REQUESTS requests, newest first; pinned requests are never
+// dropped and do not count toward the limit.
+function trimRequests(items) {
+  let unpinned = 0;
+  return items.filter((item) => item.pinned || (unpinned += 1) <= MAX_REQUESTS);
+}
+
+// loadPinned merges the stored pinned requests, which may be older than the loaded ones.
+async function loadPinned() {
+  try {
+    const resp = await apiFetch('/requests?pinned=true&limit=500');
+    const payload = await resp.json();
+    applyHistory(payload.data || []);
+  } catch (error) {
+    console.error('Failed to load pinned requests', error);
+  }
+}
+
+function resolveTimeZone() {
+  try {
+    return Intl.DateTimeFormat().resolvedOptions().timeZone || '';
+  } catch (error) {
+    return '';
+  }
+}
+
+async function loadTimeline() {
+  if (!els.timelineGrid) return;
+  try {
+    const params = new URLSearchParams({ bucket: state.timelineBucket });
+    const tz = resolveTimeZone();
+    if (tz) params.set('tz', tz);
+    if (state.filters.method) params.set('method', state.filters.method);
+    const resp = await apiFetch(`/timeline?${params.toString()}`);
+    state.timeline = await resp.json();
+    renderTimeline();
+  } catch (error) {
+    console.error('Failed to load timeline', error);
+  }
+}
+
+function scheduleTimelineRefresh() {
+  if (timelineTimer) return;
+  timelineTimer = setTimeout(() => {
+    timelineTimer = null;
+    loadTimeline();
+  }, 5000);
+}
+
+function heatLevel(count, max) {
+  if (!count || !max) return 0;
+  return Math.min(4, Math.ceil((count / max) * 4));
+}
+
+function timelineCell(bucket, max, label) {
+  const cell = document.createElement('div');
+  cell.className = 'timeline-cell';
+  cell.dataset.level = String(heatLevel(bucket.count, max));
+  cell.title = i18n.t('timeline.cell', { time: label, count: bucket.count });
+  return cell;
+}
+
+function renderTimeline() {
+  const data = state.timeline;
+  if (!els.timelineGrid || !data) return;
+  const buckets = data.buckets || [];
+  const grid = els.timelineGrid;
+  grid.innerHTML = '';
+  grid.className = `timeline-grid timeline-grid--${data.bucket}`;
+
+  els.timelineBuckets.forEach((btn) => {
+    btn.setAttribute('aria-pressed', String(btn.dataset.timelineBucket === data.bucket));
+  });
+  if (els.timelineSummary) {
+    els.timelineSummary.textContent = i18n.t('timeline.summary', {
+      total: data.total || 0,
+      max: data.max || 0,
+      bucket: i18n.t(`timeline.unit_${data.bucket}`),
+    });
+  }
+
+  if (data.bucket === 'day') {
+    // Calendar layout: one column per week, one row per weekday
+    const first = buckets.length ? new Date(buckets[0].start) : null;
+    for (let i = 0; first && i < first.getDay(); i += 1) {
+      const blank = document.createElement('div');
+      blank.className = 'timeline-cell timeline-cell--blank';
+      grid.appendChild(blank);
+    }
+    buckets.forEach((bucket) => {
+      const label = new Date(bucket.start).toLocaleDateString(state.locale);
+      grid.appendChild(timelineCell(bucket, data.max, label));
+    });
+    return;
+  }
+
+  // Hourly layout: one row per day, one column per hour of the day
+  const corner = document.createElement('div');
+  grid.appendChild(corner);
+  for (let hour = 0; hour < 24; hour += 1) {
+    const head = document.createElement('div');
+    head.className = 'timeline-grid__label';
+    head.textContent = hour % 3 === 0 ? String(hour).padStart(2, '0') : '';
+    grid.appendChild(head);
+  }
+  let currentDay = '';
+  let filled = 0;
+  buckets.forEach((bucket) => {
+    const start = new Date(bucket.start);
+    const day = start.toDateString();
+    if (day !== currentDay) {
+      for (; currentDay && filled < 24; filled += 1) {
+        const blank = document.createElement('div');
+        blank.className = 'timeline-cell timeline-cell--blank';
+        grid.appendChild(blank);
+      }
+      currentDay = day;
+      filled = 0;
+      const label = document.createElement('div');
+      label.className = 'timeline-grid__label';
+      label.textContent = start.toLocaleDateString(state.locale, { weekday: 'short', month: '2-digit', day: '2-digit' });
+      grid.appendChild(label);
+    }
+    if (filled >= 24) return;
+    grid.appendChild(timelineCell(bucket, data.max, start.toLocaleString(state.locale)));
+    filled += 1;
+  });
+}
+
 function formatTime(value) {
   if (!value) return '-';
   return new Date(value).toLocaleString();
@@ -461,6 +821,12 @@ function openDetail(item) {
   const fullPath = composeRequestPath(item);
   const bodySize = formatSize(item.size || item.content_length || 0);
   els.detailMeta.innerHTML = buildDetailMeta(item, fullPath, bodySize);
+  renderClaim(item);
+  renderPin(item);
+  renderDiffButton(item);
+  renderAnnotations(item);
+  loadComments(item);
+  loadProcessingTimeline(item);
 
   const headersText = formatHeaders(item.headers || {});
   if (els.detailHeaders) {
@@ -471,20 +837,636 @@ function openDetail(item) {
   state.activeRequest = item;
   state.activeRequestBody = decodedBody;
   state.detailBodyRaw = decodedBody;
-  state.detailBodyPretty = isBodyPlaceholder(decodedBody) ? null : tryFormatJson(decodedBody);
+  state.detailBodyPretty = item.grpc
+    ? formatGrpcCall(item.grpc)
+    : item.protobuf
+      ? formatProtobufBody(item.protobuf)
+      : isBodyPlaceholder(decodedBody)
+      ? null
+      : tryFormatJson(decodedBody);
   state.detailBodyMode = state.detailBodyPretty ? 'pretty' : 'raw';
   renderDetailBody();
   setWrapState(els.detailBody, els.bodyWrapBtn, true);
+  renderJwts(item);
   clearActionStatus();
   els.modal.classList.remove('hidden');
   els.modal.classList.add('flex');
 }
 
+const JWT_PATTERN = /^[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]*$/;
+
+// decodeJwt returns the header and claims of a compact JWT; the signature is neither shown nor verified.
+function decodeJwt(token) {
+  const value = (token || '').trim();
+  if (!JWT_PATTERN.test(value)) {
+    return null;
+  }
+  const decodeSegment = (segment) => {
+    const base64 = segment.replace(/-/g, '+').replace(/_/g, '/');
+    const binary = window.atob(base64.padEnd(Math.ceil(base64.length / 4) * 4, '='));
+    const bytes = Uint8Array.from(binary, (char) => char.charCodeAt(0));
+    return JSON.parse(new TextDecoder().decode(bytes));
+  };
+  try {
+    const [header, claims] = value.split('.').slice(0, 2).map(decodeSegment);
+    if (!header || typeof header !== 'object' || !('alg' in header) || !claims || typeof claims !== 'object') {
+      return null;
+    }
+    return { header, claims };
+  } catch {
+    return null;
+  }
+}
+
+// findJwts collects the tokens of the Authorization: Bearer header and of JSON or form body fields.
+function findJwts(item) {
+  const found = [];
+  const seen = new Set();
+  const add = (source, fromBody, token) => {
+    const value = (token || '').trim();
+    if (seen.has(value)) {
+      return;
+    }
+    const decoded = decodeJwt(value);
+    if (decoded) {
+      seen.add(value);
+      found.push({ source, fromBody, ...decoded });
+    }
+  };
+
+  Object.entries(item.headers || {}).forEach(([key, values]) => {
+    if (key.toLowerCase() !== 'authorization') return;
+    (Array.isArray(values) ? values : [values]).forEach((value) => {
+      const match = /^bearer\s+(.+)$/i.exec((value || '').trim());
+      if (match) add(key, false, match[1]);
+    });
+  });
+
+  const body = state.activeRequestBody;
+  if (item.is_binary || isBodyPlaceholder(body)) {
+    return found;
+  }
+  const trimmed = body.trim();
+  if (JWT_PATTERN.test(trimmed)) {
+    add('.', true, trimmed);
+    return found;
+  }
+  const walk = (path, node) => {
+    if (Array.isArray(node)) {
+      node.forEach((value, index) => walk(`${path}[${index}]`, value));
+    } else if (node && typeof node === 'object') {
+      Object.keys(node).sort().forEach((key) => walk(`${path}.${key}`, node[key]));
+    } else if (typeof node === 'string') {
+      add(path, true, node.replace(/^bearer\s+/i, ''));
+    }
+  };
+  if (trimmed.startsWith('{') || trimmed.startsWith('[')) {
+    try {
+      walk('', JSON.parse(trimmed));
+    } catch {
+      // not JSON
+    }
+    return found;
+  }
+  if ((item.content_type || '').toLowerCase().startsWith('application/x-www-form-urlencoded')) {
+    new URLSearchParams(trimmed).forEach((value, key) => add(key, true, value));
+  }
+  return found;
+}
+
+// renderJwts shows the decoded JWTs of the request with their expiry highlighted.
+function renderJwts(item) {
+  if (!els.detailJwtSection || !els.detailJwt) return;
+  const tokens = JWT_VIEW && item ? findJwts(item) : [];
+  els.detailJwtSection.classList.toggle('hidden', tokens.length === 0);
+  els.detailJwt.innerHTML = '';
+  tokens.forEach((token) => {
+    const wrapper = document.createElement('div');
+    wrapper.className = 'jwt-token';
+    const source = document.createElement('p');
+    source.className = 'jwt-token__source';
+    source.textContent = i18n.t(token.fromBody ? 'jwt.from_body' : 'jwt.from_header', { source: token.source });
+    const code = document.createElement('pre');
+    code.className = 'code-block code-block--wrap';
+    code.textContent = `${JSON.stringify(token.header)}\n${JSON.stringify(token.claims, null, 2)}`;
+    const expiry = document.createElement('p');
+    expiry.className = 'jwt-token__expiry';
+    if (typeof token.claims.exp === 'number') {
+      const expiresAt = new Date(token.claims.exp * 1000);
+      const expired = expiresAt.getTime() < Date.now();
+      expiry.classList.add(expired ? 'jwt-token__expiry--expired' : 'jwt-token__expiry--valid');
+      expiry.textContent = i18n.t(expired ? 'jwt.expired' : 'jwt.expires', { time: formatTime(expiresAt.toISOString()) });
+    } else {
+      expiry.textContent = i18n.t('jwt.no_expiry');
+    }
+    wrapper.append(source, code, expiry);
+    els.detailJwt.appendChild(wrapper);
+  });
+}
+
+function renderPin(item) {
+  if (!els.pinBtn || !els.pinBtnLabel) return;
+  const pinned = Boolean(item && item.pinned);
+  els.pinBtn.setAttribute('aria-pressed', String(pinned));
+  els.pinBtnLabel.textContent = i18n.t(pinned ? 'pin.unpin' : 'pin.pin');
+}
+
+// applyForward flags a request once a forward target broke its response expectations.
+function applyForward(requestId, results) {
+  if (state.activeRequest && state.activeRequest.id === requestId) {
+    loadProcessingTimeline(state.activeRequest);
+  }
+  if (!(results || []).some((res) => res.violations && res.violations.length)) {
+    return;
+  }
+  state.requests.forEach((req) => {
+    if (req.id === requestId) {
+      req.forward_violations = true;
+    }
+  });
+  render();
+}
+
+function applyPin(requestId, pinned) {
+  state.requests.forEach((req) => {
+    if (req.id === requestId) {
+      req.pinned = pinned || undefined;
+    }
+  });
+  render();
+  if (state.activeRequest && state.activeRequest.id === requestId) {
+    state.activeRequest.pinned = pinned || undefined;
+    renderPin(state.activeRequest);
+  }
+}
+
+async function handlePinToggle() {
+  const item = ensureActiveRequest();
+  if (!item) return;
+  const pinned = !item.pinned;
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/pin`, {
+      method: pinned ? 'POST' : 'DELETE',
+    });
+    const result = await resp.json();
+    applyPin(item.id, result.pinned);
+  } catch (error) {
+    alert(i18n.t('pin.failed', { error: error.message || i18n.t('alerts.unknown_error') }));
+  }
+}
+
+function renderClaim(item) {
+  if (!els.claimStatus || !els.claimBtn) return;
+  const owner = item && item.claim ? item.claim.user : '';
+  const mine = Boolean(owner) && owner === state.username;
+  els.claimStatus.textContent = owner
+    ? i18n.t('claim.claimed_by', { user: owner })
+    : i18n.t('claim.unclaimed');
+  els.claimStatus.classList.toggle('detail-claim__status--active', Boolean(owner));
+  els.claimBtn.dataset.action = mine ? 'release' : 'claim';
+  els.claimBtnLabel.textContent = i18n.t(mine ? 'claim.release' : 'claim.claim');
+  // Someone else's claim can only be taken over by an admin.
+  els.claimBtn.disabled = Boolean(owner) && !mine && !canUseAdminActions();
+}
+
+function renderDiffButton(item) {
+  if (!els.diffBtn || !els.diffBtnLabel) return;
+  const base = state.diffBase;
+  const marked = Boolean(base) && Boolean(item) && base.id === item.id;
+  els.diffBtn.setAttribute('aria-pressed', String(marked));
+  if (!base || marked) {
+    els.diffBtnLabel.textContent = i18n.t(marked ? 'diff.marked' : 'diff.mark');
+    return;
+  }
+  els.diffBtnLabel.textContent = i18n.t('diff.compare_with', { request: `${base.method} ${base.path}` });
+}
+
+// The first click marks the open request as A, a click on another request compares it as B.
+function handleDiffButton() {
+  const item = state.activeRequest;
+  if (!item) return;
+  const base = state.diffBase;
+  if (base && base.id !== item.id) {
+    openDiff(base.id, item.id);
+    return;
+  }
+  state.diffBase = base ? null : item;
+  renderDiffButton(item);
+}
+
+async function openDiff(idA, idB) {
+  try {
+    const params = new URLSearchParams({ a: idA, b: idB });
+    const resp = await apiFetch(`/requests/diff?${params.toString()}`);
+    renderDiff(await resp.json());
+  } catch (error) {
+    alert(i18n.t('diff.failed', { error: error.message }));
+    return;
+  }
+  closeDetail();
+  state.diffBase = null;
+  els.diffModal.classList.remove('hidden');
+  els.diffModal.classList.add('flex');
+}
+
+function closeDiff() {
+  if (!els.diffModal) return;
+  els.diffModal.classList.add('hidden');
+  els.diffModal.classList.remove('flex');
+}
+
+function formatDiffValue(value) {
+  if (value === undefined || value === null) return '';
+  return typeof value === 'string' ? value : JSON.stringify(value, null, 2);
+}
+
+function diffSection(title, entries, emptyLabel) {
+  const rows = entries.length
+    ? entries.map((entry) => `
+      <tr>
+        <td class="diff-table__key">${escapeHtml(entry.key)}</td>
+        <td><span class="diff-op diff-op--${entry.op}">${escapeHtml(i18n.t(`diff.ops.${entry.op}`))}</span></td>
+        <td>${escapeHtml(formatDiffValue(entry.a))}</td>
+        <td>${escapeHtml(formatDiffValue(entry.b))}</td>
+      </tr>`).join('')
+    : `<tr><td colspan="4" class="diff-empty">${escapeHtml(emptyLabel)}</td></tr>`;
+  return `
+    <div class="detail-section">
+      <div class="detail-section__bar">
+        <p class="detail-section__title">${escapeHtml(title)}</p>
+      </div>
+      <table class="diff-table"><tbody>${rows}</tbody></table>
+    </div>`;
+}
+
+function renderDiff(diff) {
+  if (!els.diffContent) return;
+  const side = (s) => `${s.method} ${s.path} · ${formatTime(s.timestamp)}`;
+  if (els.diffSubtitle) {
+    els.diffSubtitle.textContent = i18n.t('diff.subtitle', { a: side(diff.a), b: side(diff.b) });
+  }
+  const noChanges = i18n.t('diff.no_changes');
+  const bodyTitle = `${i18n.t('diff.sections.body')} (${diff.body.mode})`;
+  els.diffContent.innerHTML = [
+    diffSection(i18n.t('diff.sections.request'), diff.request || [], noChanges),
+    diffSection(i18n.t('diff.sections.headers'), diff.headers || [], noChanges),
+    diffSection(i18n.t('diff.sections.query'), diff.query || [], noChanges),
+    diffSection(bodyTitle, diff.body.changes || [], noChanges),
+  ].join('');
+}
+
+async function openGroups() {
+  const params = new URLSearchParams({
+    search: state.filters.search || '',
+    method: state.filters.method || '',
+    claim: state.filters.claim || '',
+  });
+  if (state.filters.tag) {
+    params.set('tag', parseTags(state.filters.tag).join(','));
+  }
+  if (state.filters.pinned) {
+    params.set('pinned', 'true');
+  }
+  try {
+    const resp = await apiFetch(`/requests/groups?${params.toString()}`);
+    renderGroups(await resp.json());
+  } catch (error) {
+    alert(i18n.t('groups.failed', { error: error.message }));
+    return;
+  }
+  els.groupsModal.classList.remove('hidden');
+  els.groupsModal.classList.add('flex');
+}
+
+function closeGroups() {
+  if (!els.groupsModal) return;
+  els.groupsModal.classList.add('hidden');
+  els.groupsModal.classList.remove('flex');
+}
+
+function renderGroups(result) {
+  if (!els.groupsContent) return;
+  const groups = result.groups || [];
+  if (els.groupsSubtitle) {
+    els.groupsSubtitle.textContent = i18n.t('groups.subtitle', { groups: groups.length, scanned: result.scanned });
+  }
+  if (!groups.length) {
+    els.groupsContent.innerHTML = `<p class="diff-empty">${escapeHtml(i18n.t('groups.empty'))}</p>`;
+    return;
+  }
+  els.groupsContent.innerHTML = groups.map((group) => {
+    const fields = group.fields.length
+      ? group.fields.map((field) => `<code class="group-field">${escapeHtml(field)}</code>`).join('')
+      : `<span class="diff-empty">${escapeHtml(i18n.t(`groups.kinds.${group.kind}`))}</span>`;
+    const samples = group.request_ids.map((id) => {
+      const loaded = state.requests.some((req) => req.id === id);
+      return `<button type="button" class="group-sample" data-group-request="${escapeHtml(id)}" ${loaded ? '' : 'disabled'}>${escapeHtml(id)}</button>`;
+    }).join('');
+    return `
+      <div class="detail-section">
+        <div class="detail-section__bar">
+          <p class="detail-section__title">${escapeHtml(`${group.method} ${group.path}`)}</p>
+          <span class="group-count">${escapeHtml(i18n.t('groups.count', { count: group.count }))}</span>
+        </div>
+        <p class="stat-card__hint">${escapeHtml(i18n.t('groups.seen', {
+          fingerprint: group.fingerprint,
+          first: formatTime(group.first_seen),
+          last: formatTime(group.last_seen),
+        }))}</p>
+        <div class="group-fields">${fields}</div>
+        <div class="group-samples">${samples}</div>
+      </div>`;
+  }).join('');
+  els.groupsContent.querySelectorAll('[data-group-request]').forEach((btn) => {
+    btn.addEventListener('click', () => {
+      const item = state.requests.find((req) => req.id === btn.dataset.groupRequest);
+      if (!item) return;
+      closeGroups();
+      openDetail(item);
+    });
+  });
+}
+
+async function openStats() {
+  try {
+    const resp = await apiFetch('/stats');
+    renderStats(await resp.json());
+  } catch (error) {
+    alert(i18n.t('statistics.failed', { error: error.message }));
+    return;
+  }
+  els.statsModal.classList.remove('hidden');
+  els.statsModal.classList.add('flex');
+}
+
+function closeStats() {
+  if (!els.statsModal) return;
+  els.statsModal.classList.add('hidden');
+  els.statsModal.classList.remove('flex');
+}
+
+function statsBars(buckets, format) {
+  const max = Math.max(1, ...buckets.map((bucket) => bucket.count));
+  return `<div class="stats-bars">${buckets.map((bucket) => {
+    const height = bucket.count ? Math.max(4, Math.round((bucket.count / max) * 100)) : 0;
+    const title = i18n.t('timeline.cell', { time: format(new Date(bucket.start)), count: bucket.count });
+    return `<span class="stats-bar" style="height:${height}%" title="${escapeHtml(title)}"></span>`;
+  }).join('')}</div>`;
+}
+
+function statsSection(title, body) {
+  return `
+    <div class="detail-section">
+      <div class="detail-section__bar">
+        <p class="detail-section__title">${escapeHtml(title)}</p>
+      </div>
+      ${body}
+    </div>`;
+}
+
+function statsRows(rows) {
+  if (!rows.length) {
+    return `<p class="diff-empty">${escapeHtml(i18n.t('statistics.empty'))}</p>`;
+  }
+  return `<table class="stats-table">${rows.map((cells) => `<tr>${cells.map((cell) => `<td>${escapeHtml(cell)}</td>`).join('')}</tr>`).join('')}</table>`;
+}
+
+function renderStats(stats) {
+  if (!els.statsContent) return;
+  if (els.statsSubtitle) {
+    els.statsSubtitle.textContent = i18n.t('statistics.subtitle', {
+      from: formatTime(stats.from),
+      to: formatTime(stats.to),
+    });
+  }
+  const percent = (value) => `${(value * 100).toFixed(1)}%`;
+  const summary = [
+    [i18n.t('statistics.total'), String(stats.total)],
+    [i18n.t('statistics.per_minute'), stats.requests_per_minute.toFixed(2)],
+    [i18n.t('statistics.per_hour'), stats.requests_per_hour.toFixed(1)],
+    [i18n.t('statistics.avg_body'), formatSize(Math.round(stats.avg_body_bytes))],
+  ];
+  const forwards = (stats.forwards || []).map((target) => [
+    target.target_url,
+    target.violations
+      ? `${i18n.t('statistics.forward_counts', { succeeded: target.succeeded, total: target.total })} · ${i18n.t('statistics.forward_violations', { count: target.violations })}`
+      : i18n.t('statistics.forward_counts', { succeeded: target.succeeded, total: target.total }),
+    percent(target.success_rate),
+    `${Math.round(target.avg_latency_ms)} ms`,
+  ]);
+  els.statsContent.innerHTML = [
+    statsSection(i18n.t('statistics.summary'), statsRows(summary)),
+    statsSection(i18n.t('statistics.last_hour'), statsBars(stats.minutes || [], (date) => date.toLocaleTimeString())),
+    statsSection(i18n.t('statistics.hourly'), statsBars(stats.hours || [], (date) => date.toLocaleString())),
+    statsSection(i18n.t('statistics.methods'), statsRows((stats.methods || []).map((entry) => [entry.method, String(entry.count)]))),
+    statsSection(i18n.t('statistics.top_paths'), statsRows((stats.top_paths || []).map((entry) => [entry.path, String(entry.count)]))),
+    statsSection(i18n.t('statistics.forwards'), statsRows(forwards)),
+  ].join('');
+}
+
+function applyClaim(requestId, claim) {
+  state.requests.forEach((req) => {
+    if (req.id === requestId) {
+      req.claim = claim || undefined;
+    }
+  });
+  render();
+  if (state.activeRequest && state.activeRequest.id === requestId) {
+    state.activeRequest.claim = claim || undefined;
+    renderClaim(state.activeRequest);
+  }
+}
+
+async function handleClaimToggle() {
+  const item = ensureActiveRequest();
+  if (!item) return;
+  const release = els.claimBtn.dataset.action === 'release';
+  const force = !release && Boolean(item.claim);
+  const params = force ? '?force=true' : '';
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/claim${params}`, {
+      method: release ? 'DELETE' : 'POST',
+    });
+    const result = await resp.json();
+    applyClaim(item.id, result.claim);
+  } catch (error) {
+    let holder = '';
+    try {
+      holder = JSON.parse(error.message).claim?.user || '';
+    } catch {
+      // plain-text error
+    }
+    const message = holder
+      ? i18n.t('claim.conflict', { user: holder })
+      : error.message || i18n.t('alerts.unknown_error');
+    alert(message);
+  }
+}
+
+function renderAnnotations(item) {
+  if (!els.annotationTags || !els.annotationNote) return;
+  els.annotationTags.value = (item.tags || []).join(', ');
+  els.annotationNote.value = item.note || '';
+}
+
+function applyAnnotation(annotation) {
+  const update = (req) => {
+    req.tags = annotation.tags || undefined;
+    req.note = annotation.note || undefined;
+  };
+  state.requests.filter((req) => req.id === annotation.request_id).forEach(update);
+  render();
+  if (state.activeRequest && state.activeRequest.id === annotation.request_id) {
+    update(state.activeRequest);
+    renderAnnotations(state.activeRequest);
+  }
+}
+
+async function handleAnnotationSubmit(event) {
+  event.preventDefault();
+  const item = ensureActiveRequest();
+  if (!item) return;
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}`, {
+      method: 'PATCH',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        tags: parseTags(els.annotationTags.value),
+        note: els.annotationNote.value,
+      }),
+    });
+    const stored = await resp.json();
+    applyAnnotation({ request_id: stored.id, tags: stored.tags, note: stored.note });
+  } catch (error) {
+    alert(i18n.t('annotations.failed', { error: error.message || i18n.t('alerts.unknown_error') }));
+  }
+}
+
+async function loadComments(item) {
+  state.activeComments = [];
+  renderComments();
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/comments`);
+    const payload = await resp.json();
+    if (state.activeRequest && state.activeRequest.id === item.id) {
+      state.activeComments = payload.data || [];
+      renderComments();
+    }
+  } catch (error) {
+    console.error('Failed to load comments', error);
+  }
+}
+
+async function loadProcessingTimeline(item) {
+  state.activeTimeline = null;
+  renderProcessingTimeline();
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/timeline`);
+    const payload = await resp.json();
+    if (state.activeRequest && state.activeRequest.id === item.id) {
+      state.activeTimeline = payload;
+      renderProcessingTimeline();
+    }
+  } catch (error) {
+    console.error('Failed to load processing timeline', error);
+  }
+}
+
+function formatMs(ms) {
+  if (ms >= 1000) return `${(ms / 1000).toFixed(2)} s`;
+  return `${ms.toFixed(ms < 10 ? 2 : 1)} ms`;
+}
+
+// renderProcessingTimeline draws each step as a bar from its start to its end, scaled to the last
+// step, so the slow stage of a request stands out.
+function renderProcessingTimeline() {
+  if (!els.detailProcessing) return;
+  const timeline = state.activeTimeline;
+  const steps = (timeline && timeline.steps) || [];
+  if (els.detailProcessingPartial) {
+    els.detailProcessingPartial.classList.toggle('hidden', !(timeline && timeline.partial));
+  }
+  if (steps.length === 0) {
+    els.detailProcessing.innerHTML = `<li class="processing-list__empty">${escapeHtml(i18n.t('processing.empty'))}</li>`;
+    return;
+  }
+  const total = Math.max(...steps.map((step) => step.offset_ms), 0.001);
+  els.detailProcessing.innerHTML = steps
+    .map((step) => {
+      const start = Math.max(step.offset_ms - step.duration_ms, 0);
+      const left = Math.min((start / total) * 100, 99.5);
+      const width = Math.max(((step.offset_ms - start) / total) * 100, 0.5);
+      const failed = step.success === false;
+      const outcome = [step.status_code || '', step.error || ''].filter(Boolean).join(' ');
+      return `
+        <li class="processing-step${failed ? ' processing-step--failed' : ''}">
+          <div class="processing-step__label">
+            <span class="processing-step__name">${escapeHtml(i18n.t(`processing.steps.${step.step}`))}</span>
+            ${step.target ? `<span class="processing-step__target">${escapeHtml(step.target)}</span>` : ''}
+            ${outcome ? `<span class="processing-step__outcome">${escapeHtml(outcome)}</span>` : ''}
+          </div>
+          <div class="processing-step__track">
+            <span class="processing-step__bar" style="left: ${left.toFixed(2)}%; width: ${Math.min(width, 100 - left).toFixed(2)}%"></span>
+          </div>
+          <div class="processing-step__time">
+            +${escapeHtml(formatMs(step.offset_ms))}${step.duration_ms > 0 ? ` · ${escapeHtml(i18n.t('processing.took', { duration: formatMs(step.duration_ms) }))}` : ''}
+          </div>
+        </li>`;
+    })
+    .join('');
+}
+
+function renderComments() {
+  if (!els.detailComments) return;
+  if (state.activeComments.length === 0) {
+    els.detailComments.innerHTML = `<li class="comment-list__empty">${escapeHtml(i18n.t('comments.empty'))}</li>`;
+    return;
+  }
+  els.detailComments.innerHTML = state.activeComments
+    .map(
+      (comment) => `
+        <li class="comment-item">
+          <p class="comment-item__meta">
+            <span class="comment-item__author">${escapeHtml(comment.author)}</span>${escapeHtml(formatTime(comment.created_at))}
+          </p>
+          <p class="comment-item__body">${escapeHtml(comment.body)}</p>
+        </li>`
+    )
+    .join('');
+}
+
+function appendComment(comment) {
+  if (!state.activeRequest || state.activeRequest.id !== comment.request_id) return;
+  if (state.activeComments.some((existing) => existing.id === comment.id)) return;
+  state.activeComments.push(comment);
+  renderComments();
+}
+
+async function handleCommentSubmit(event) {
+  event.preventDefault();
+  const item = ensureActiveRequest();
+  if (!item) return;
+  const body = els.commentInput.value.trim();
+  if (!body) return;
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/comments`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ body }),
+    });
+    appendComment(await resp.json());
+    els.commentInput.value = '';
+  } catch (error) {
+    alert(i18n.t('comments.failed', { error: error.message || i18n.t('alerts.unknown_error') }));
+  }
+}
+
 function closeDetail() {
   els.modal.classList.add('hidden');
   els.modal.classList.remove('flex');
   state.activeRequest = null;
   state.activeRequestBody = '';
+  state.activeComments = [];
   clearActionStatus();
 }
 
@@ -565,8 +1547,13 @@ function initWebsocket() {
   if (reconnectTimer) {
     clearTimeout(reconnectTimer);
   }
+  if (useEventSource) {
+    initEventSource();
+    return;
+  }
 
   updateWsStatus('connecting');
+  let opened = false;
   try {
     const protocol = window.location.protocol === 'https:' ? 'wss' : 'ws';
     const url = `${protocol}://${window.location.host}${WS_PATH}`;
@@ -577,22 +1564,121 @@ function initWebsocket() {
     return;
   }
 
-  ws.onopen = () => updateWsStatus('connected');
+  ws.onopen = () => {
+    opened = true;
+    updateWsStatus('connected');
+  };
   ws.onerror = () => updateWsStatus('error');
   ws.onclose = () => {
     updateWsStatus('disconnected');
+    if (!opened && typeof EventSource !== 'undefined') {
+      // The upgrade never went through; switch to Server-Sent Events for this page
+      useEventSource = true;
+      initEventSource();
+      return;
+    }
     scheduleReconnect();
   };
-  ws.onmessage = (event) => {
-    try {
-      const payload = JSON.parse(event.data);
-      if (payload.type === 'request' && payload.data) {
-        pushRequest(payload.data);
-      }
-    } catch (error) {
-      console.error('Failed to parse websocket payload', error);
+  ws.onmessage = (event) => handleLiveEvent(event.data);
+}
+
+// initEventSource receives the live events over Server-Sent Events; the browser reconnects by itself.
+function initEventSource() {
+  updateWsStatus('connecting');
+  eventSource = new EventSource(EVENTS_PATH);
+  eventSource.onopen = () => updateWsStatus('connected');
+  eventSource.onerror = () => {
+    if (eventSource.readyState === EventSource.CLOSED) {
+      updateWsStatus('disconnected');
+      scheduleReconnect();
+    } else {
+      updateWsStatus('connecting');
     }
   };
+  eventSource.onmessage = (event) => handleLiveEvent(event.data);
+}
+
+function handleLiveEvent(data) {
+  try {
+    const payload = JSON.parse(data);
+    if (payload.type === 'request' && payload.data) {
+      pushRequest(payload.data);
+    } else if (payload.type === 'history' && Array.isArray(payload.data)) {
+      applyHistory(payload.data);
+    } else if (payload.type === 'annotation' && payload.data) {
+      applyAnnotation(payload.data);
+    } else if (payload.type === 'comment' && payload.data) {
+      appendComment(payload.data);
+    } else if (payload.type === 'claim' && payload.data) {
+      applyClaim(payload.data.request_id, payload.data.claim);
+    } else if (payload.type === 'pin' && payload.data) {
+      applyPin(payload.data.request_id, payload.data.pinned);
+    } else if (payload.type === 'forward' && payload.data) {
+      applyForward(payload.data.request_id, payload.data.results);
+    } else if (payload.type === 'anomaly' && payload.data) {
+      state.anomaly = payload.data;
+      renderAnomaly();
+    } else if (payload.type === 'capture' && payload.data) {
+      state.capture = payload.data;
+      renderCapture();
+    }
+  } catch (error) {
+    console.error('Failed to parse live event', error);
+  }
+}
+
+function renderAnomaly() {
+  if (!els.anomalyBanner) return;
+  const event = state.anomaly;
+  els.anomalyBanner.classList.toggle('hidden', !event);
+  if (!event) return;
+  const percent = event.metric === 'error_rate';
+  const format = (value) => (percent ? `${(value * 100).toFixed(1)}%` : Math.round(value).toString());
+  els.anomalyMessage.textContent = i18n.t('anomaly.message', {
+    time: new Date(event.window_end).toLocaleTimeString(state.locale),
+    metric: i18n.t(`anomaly.metrics.${event.metric}`),
+    direction: i18n.t(`anomaly.${event.direction}`),
+    value: format(event.value),
+    baseline: format(event.baseline),
+  });
+}
+
+async function loadCapture() {
+  try {
+    const resp = await apiFetch('/capture');
+    state.capture = await resp.json();
+    renderCapture();
+  } catch (error) {
+    console.error('Failed to load capture state', error);
+  }
+}
+
+async function handleCaptureToggle() {
+  const action = state.capture.paused ? 'resume' : 'pause';
+  try {
+    const resp = await apiFetch(`/capture/${action}`, { method: 'POST' });
+    state.capture = await resp.json();
+    renderCapture();
+  } catch (error) {
+    alert(i18n.t('capture.failed', { error: error.message }));
+  }
+}
+
+function renderCapture() {
+  const paused = Boolean(state.capture && state.capture.paused);
+  if (els.captureToggleLabel) {
+    els.captureToggleLabel.textContent = i18n.t(paused ? 'capture.resume' : 'capture.pause');
+  }
+  if (els.captureToggleIcon) {
+    els.captureToggleIcon.className = paused ? 'fa-solid fa-play' : 'fa-solid fa-pause';
+  }
+  if (!els.captureBanner) return;
+  els.captureBanner.classList.toggle('hidden', !paused);
+  if (!paused) return;
+  els.captureMessage.textContent = i18n.t('capture.paused', {
+    time: state.capture.paused_at ? new Date(state.capture.paused_at).toLocaleTimeString(state.locale) : '-',
+    count: state.capture.skipped || 0,
+  });
 }
 
 function scheduleReconnect() {
@@ -617,7 +1703,17 @@ async function handleExport(format) {
     format,
     search: state.filters.search || '',
     method: state.filters.method || '',
+    claim: state.filters.claim || '',
   });
+  if (state.filters.tag) {
+    params.set('tag', parseTags(state.filters.tag).join(','));
+  }
+  if (state.filters.pinned) {
+    params.set('pinned', 'true');
+  }
+  if (els.exportComments && els.exportComments.checked) {
+    params.set('comments', 'true');
+  }
 
   try {
     const resp = await apiFetch(`/export?${params.toString()}`, {
@@ -651,6 +1747,27 @@ async function handleExport(format) {
   }
 }
 
+async function handleImport(file) {
+  if (!file) return;
+  const params = new URLSearchParams({ scenario: file.name.replace(/\.[^.]+$/, '') });
+  try {
+    const resp = await apiFetch(`/import?${params.toString()}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: file,
+    });
+    const result = await resp.json();
+    alert(i18n.t('alerts.import_done', { count: result.imported }));
+    loadRequests();
+    loadTimeline();
+  } catch (error) {
+    console.error('Import failed', error);
+    alert(i18n.t('alerts.import_failed', { error: error.message || i18n.t('alerts.unknown_error') }));
+  } finally {
+    els.importFile.value = '';
+  }
+}
+
 async function handleLogout() {
   try {
     await apiFetch('/auth/logout', { method: 'POST' });
@@ -670,9 +1787,59 @@ function bindEvents() {
   els.method.addEventListener('change', (event) => {
     state.filters.method = event.target.value;
     render();
+    loadTimeline();
   });
 
-  els.refresh.addEventListener('click', () => loadRequests());
+  if (els.claimFilter) {
+    els.claimFilter.addEventListener('change', (event) => {
+      state.filters.claim = event.target.value;
+      render();
+    });
+  }
+  if (els.claimBtn) {
+    els.claimBtn.addEventListener('click', handleClaimToggle);
+  }
+  if (els.pinnedFilter) {
+    els.pinnedFilter.addEventListener('change', (event) => {
+      state.filters.pinned = event.target.checked;
+      render();
+      if (state.filters.pinned) {
+        loadPinned();
+      }
+    });
+  }
+  if (els.pinBtn) {
+    els.pinBtn.addEventListener('click', handlePinToggle);
+  }
+  if (els.tagFilter) {
+    els.tagFilter.addEventListener('input', (event) => {
+      state.filters.tag = event.target.value;
+      render();
+    });
+  }
+  if (els.annotationForm) {
+    els.annotationForm.addEventListener('submit', handleAnnotationSubmit);
+  }
+  if (els.commentForm) {
+    els.commentForm.addEventListener('submit', handleCommentSubmit);
+  }
+
+  els.refresh.addEventListener('click', () => {
+    loadRequests();
+    loadTimeline();
+  });
+  els.timelineBuckets.forEach((btn) =>
+    btn.addEventListener('click', () => {
+      state.timelineBucket = btn.dataset.timelineBucket;
+      loadTimeline();
+    })
+  );
+  if (els.anomalyDismiss) {
+    els.anomalyDismiss.addEventListener('click', () => {
+      state.anomaly = null;
+      renderAnomaly();
+    });
+  }
   els.logout.addEventListener('click', handleLogout);
   els.modalClose.addEventListener('click', closeDetail);
   els.modal.addEventListener('click', (event) => {
@@ -681,16 +1848,64 @@ function bindEvents() {
     }
   });
 
-  els.exportBtns.forEach((btn) =>
-    btn.addEventListener('click', () => handleExport(btn.dataset.format))
-  );
+  els.exportBtns.forEach((btn) => {
+    if (btn.dataset.format) {
+      btn.addEventListener('click', () => handleExport(btn.dataset.format));
+    }
+  });
+  if (els.importBtn && els.importFile) {
+    els.importBtn.addEventListener('click', () => els.importFile.click());
+    els.importFile.addEventListener('change', () => handleImport(els.importFile.files[0]));
+  }
 
   document.addEventListener('keydown', (event) => {
     if (event.key === 'Escape') {
       closeDetail();
+      closeDiff();
+      closeGroups();
+      closeStats();
     }
   });
 
+  if (els.diffBtn) {
+    els.diffBtn.addEventListener('click', handleDiffButton);
+  }
+  if (els.diffClose && els.diffModal) {
+    els.diffClose.addEventListener('click', closeDiff);
+    els.diffModal.addEventListener('click', (event) => {
+      if (event.target === els.diffModal) {
+        closeDiff();
+      }
+    });
+  }
+
+  if (els.groupsBtn) {
+    els.groupsBtn.addEventListener('click', openGroups);
+  }
+  if (els.groupsClose && els.groupsModal) {
+    els.groupsClose.addEventListener('click', closeGroups);
+    els.groupsModal.addEventListener('click', (event) => {
+      if (event.target === els.groupsModal) {
+        closeGroups();
+      }
+    });
+  }
+
+  if (els.statsBtn) {
+    els.statsBtn.addEventListener('click', openStats);
+  }
+  if (els.captureToggle) {
+    els.captureToggle.addEventListener('click', handleCaptureToggle);
+  }
+  if (els.statsClose && els.statsModal) {
+    els.statsClose.addEventListener('click', closeStats);
+    els.statsModal.addEventListener('click', (event) => {
+      if (event.target === els.statsModal) {
+        closeStats();
+      }
+    });
+  }
+
   if (els.requestDownload) {
     els.requestDownload.addEventListener('click', handleRequestDownload);
   }
@@ -711,6 +1926,12 @@ function bindEvents() {
     });
   }
 
+  if (els.reforwardBtn) {
+    els.reforwardBtn.addEventListener('click', () => {
+      handleReforward();
+    });
+  }
+
   if (els.replayClose) {
     els.replayClose.addEventListener('click', () => {
       closeReplayModal();
@@ -974,6 +2195,31 @@ async function handleCurlCopy() {
   }
 }
 
+async function handleReforward() {
+  if (!ensureAdminAction()) return;
+  const item = ensureActiveRequest();
+  if (!item) return;
+  if (els.reforwardBtn) {
+    els.reforwardBtn.disabled = true;
+  }
+  try {
+    const resp = await apiFetch(`/requests/${encodeURIComponent(item.id)}/reforward`, { method: 'POST' });
+    const result = await resp.json();
+    const type = result.delivered === result.total ? 'info' : 'error';
+    setActionStatus(i18n.t('detail.actions.status.reforwarded', {
+      delivered: result.delivered,
+      total: result.total,
+    }), type);
+  } catch (error) {
+    console.error('Failed to re-forward request', error);
+    setActionStatus(i18n.t('detail.actions.status.reforward_failed', { error: error.message }), 'error');
+  } finally {
+    if (els.reforwardBtn) {
+      els.reforwardBtn.disabled = false;
+    }
+  }
+}
+
 async function handleHeadersCopy() {
   if (!els.detailHeaders) return;
   try {
@@ -1036,6 +2282,17 @@ function refreshLocaleUI() {
     setWrapState(els.detailBody, els.bodyWrapBtn, shouldWrapBody);
   }
   updateWsStatus(state.wsStatus || 'connecting');
+  renderTimeline();
+  renderAnomaly();
+  renderCapture();
+  if (state.activeRequest) {
+    renderClaim(state.activeRequest);
+    renderPin(state.activeRequest);
+    renderDiffButton(state.activeRequest);
+    renderJwts(state.activeRequest);
+    renderProcessingTimeline();
+    renderComments();
+  }
   if (els.localeSelect) {
     Array.from(els.localeSelect.options).forEach((option) => {
       option.textContent = i18n.t(`header.locale_label.${option.value}`) || option.value;
@@ -1205,7 +2462,12 @@ async function bootstrap() {
   initTheme();
   i18n.applyTranslations();
   await loadUser();
-  await loadRequests();
+  // The websocket backfill replaces the initial list call when enabled
+  if (!WS_HISTORY) {
+    await loadRequests();
+  }
+  loadTimeline();
+  loadCapture();
   bindEvents();
   initWebsocket();
 }
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordAudit appends an entry to the audit log
+func (s *sqliteStore) RecordAudit(entry *AuditEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+	var details sql.NullString
+	if len(entry.Details) > 0 {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			return fmt.Errorf("marshal audit details: %w", err)
+		}
+		details = sql.NullString{String: string(encoded), Valid: true}
+	}
+	res, err := s.db.ExecContext(context.Background(),
+		`INSERT INTO audit_log (timestamp_ns, action, actor, remote_addr, target, success, details) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Timestamp.UnixNano(), entry.Action, entry.Actor, entry.RemoteAddr, entry.Target, boolToInt(entry.Success), details)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	entry.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// AuditEntries lists the audit log newest first
+func (s *sqliteStore) AuditEntries(opts AuditOptions) ([]*AuditEntry, int, error) {
+	ctx := context.Background()
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	if opts.Action != "" {
+		clauses = append(clauses, "action = ?")
+		args = append(args, opts.Action)
+	}
+	if opts.Actor != "" {
+		clauses = append(clauses, "actor = ?")
+		args = append(args, opts.Actor)
+	}
+	if !opts.Since.IsZero() {
+		clauses = append(clauses, "timestamp_ns >= ?")
+		args = append(args, opts.Since.UnixNano())
+	}
+	if !opts.Until.IsZero() {
+		clauses = append(clauses, "timestamp_ns < ?")
+		args = append(args, opts.Until.UnixNano())
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM audit_log "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT id, timestamp_ns, action, actor, COALESCE(remote_addr, ''), COALESCE(target, ''), success, COALESCE(details, '')
+		FROM audit_log ` + where + ` ORDER BY id DESC`
+	if opts.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, opts.Limit, max(opts.Offset, 0))
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []*AuditEntry
+	for rows.Next() {
+		var (
+			entry   AuditEntry
+			ts      int64
+			success int
+			details string
+		)
+		if err := rows.Scan(&entry.ID, &ts, &entry.Action, &entry.Actor, &entry.RemoteAddr, &entry.Target, &success, &details); err != nil {
+			return nil, 0, err
+		}
+		entry.Timestamp = time.Unix(0, ts).UTC()
+		entry.Success = success != 0
+		if details != "" {
+			if err := json.Unmarshal([]byte(details), &entry.Details); err != nil {
+				return nil, 0, fmt.Errorf("decode audit details: %w", err)
+			}
+		}
+		result = append(result, &entry)
+	}
+	return result, total, rows.Err()
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestAuditStores(t *testing.T) {
+	stores := map[string]Store{
+		"sqlite": newTestStore(t, 1),
+		"bolt":   newTestBoltStore(t, config.StorageConfig{MaxRecords: 1}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			audit := store.(AuditStore)
+			base := time.Now().Add(-time.Hour).UTC()
+			entries := []*AuditEntry{
+				{Timestamp: base, Action: "login_failed", Actor: "alice", RemoteAddr: "10.0.0.1"},
+				{Timestamp: base.Add(time.Minute), Action: "login", Actor: "alice", Success: true},
+				{Timestamp: base.Add(2 * time.Minute), Action: "export", Actor: "alice", Success: true, Details: map[string]string{"format": "har"}},
+				{Timestamp: base.Add(3 * time.Minute), Action: "export", Actor: "token:ci", Success: true},
+			}
+			for _, entry := range entries {
+				if err := audit.RecordAudit(entry); err != nil {
+					t.Fatalf("record failed: %v", err)
+				}
+				if entry.ID == 0 {
+					t.Fatal("expected an ID to be assigned")
+				}
+			}
+			// Pruning requests leaves the audit log alone
+			for i := 0; i < 3; i++ {
+				if _, err := store.Record(fakeRequest("", "POST", "/hook")); err != nil {
+					t.Fatalf("record request failed: %v", err)
+				}
+			}
+
+			all, total, err := audit.AuditEntries(AuditOptions{})
+			if err != nil || total != 4 || len(all) != 4 {
+				t.Fatalf("expected 4 entries, got %d/%d (%v)", len(all), total, err)
+			}
+			if all[0].Actor != "token:ci" || all[3].Action != "login_failed" || all[3].RemoteAddr != "10.0.0.1" {
+				t.Fatalf("expected newest first, got %#v", all)
+			}
+
+			exports, total, err := audit.AuditEntries(AuditOptions{Action: "export", Actor: "alice"})
+			if err != nil || total != 1 || exports[0].Details["format"] != "har" || !exports[0].Success {
+				t.Fatalf("unexpected filtered entries: %#v (%d, %v)", exports, total, err)
+			}
+			page, total, err := audit.AuditEntries(AuditOptions{Since: base.Add(time.Minute), Limit: 1, Offset: 1})
+			if err != nil || total != 3 || len(page) != 1 || page[0].Action != "export" || page[0].Actor != "alice" {
+				t.Fatalf("unexpected page: %#v (%d, %v)", page, total, err)
+			}
+		})
+	}
+
+	sqlite := stores["sqlite"].(*sqliteStore)
+	if _, err := sqlite.db.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Fatal("expected the audit log to reject deletes")
+	}
+}
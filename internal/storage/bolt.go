@@ -0,0 +1,1313 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// boltOpenTimeout bounds the wait for the file lock held by another process using the database.
+const boltOpenTimeout = 2 * time.Second
+
+// boltScanPage is how many requests Iterate decodes per read transaction; the callback runs
+// outside of it so that it may use the store.
+const boltScanPage = 256
+
+// Buckets of the bolt database. Keys of the per-request buckets start with the request ID and a
+// zero byte so that the entries of one request can be found with a prefix scan.
+var (
+	boltRequests = []byte("requests")
+	// boltTimeline keys are the big-endian capture time followed by the request ID
+	boltTimeline = []byte("timeline")
+	boltPinned   = []byte("pinned")
+	boltReplays  = []byte("replays")
+	boltForwards = []byte("forwards")
+	boltComments = []byte("comments")
+	boltQueue    = []byte("forward_queue")
+	boltTokens   = []byte("api_tokens")
+	boltMock     = []byte("mock_rules")
+	// boltAudit keys are the big-endian entry IDs, so the log reads in insertion order
+	boltAudit = []byte("audit_log")
+	// boltMeta holds boltUnpinned, the number of unpinned requests that max_records is checked against
+	boltMeta     = []byte("meta")
+	boltUnpinned = []byte("unpinned")
+)
+
+// boltMark is the value of the timeline and pinned keys, which carry no data of their own.
+var boltMark = []byte{1}
+
+// boltStore keeps requests in a single bbolt file, a pure-Go alternative to the sqlite store.
+// Filters are evaluated while walking the requests in time order instead of through indexes.
+type boltStore struct {
+	db   *bolt.DB
+	cfg  *config.StorageConfig
+	log  logger.Logger
+	path string
+
+	stopMaintenance chan struct{}
+	maintenanceDone chan struct{}
+}
+
+// boltRequest is the stored form of a request along with its triage state.
+type boltRequest struct {
+	Data *request.RequestData `json:"data"`
+	// WireBody is not part of the JSON form of RequestData
+	WireBody          []byte   `json:"wire_body,omitempty"`
+	ClaimedBy         string   `json:"claimed_by,omitempty"`
+	ClaimedAt         int64    `json:"claimed_at_ns,omitempty"`
+	Note              string   `json:"note,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	Pinned            bool     `json:"pinned,omitempty"`
+	ForwardViolations bool     `json:"forward_violations,omitempty"`
+}
+
+// boltToken is the stored form of an API token, whose hash is left out of its JSON form.
+type boltToken struct {
+	*APIToken
+	Hash string `json:"hash"`
+}
+
+func newBoltStore(cfg *config.StorageConfig, log logger.Logger) (Store, error) {
+	path := cfg.Path
+	if path == "" {
+		return nil, fmt.Errorf("bolt path cannot be empty")
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve bolt path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return nil, fmt.Errorf("prepare bolt directory: %w", err)
+	}
+	db, err := bolt.Open(absPath, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("open bolt database %s: the file is in use by another process", absPath)
+		}
+		return nil, fmt.Errorf("open bolt database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltRequests, boltTimeline, boltPinned, boltReplays, boltForwards, boltComments, boltQueue, boltTokens, boltMock, boltAudit, boltMeta} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("create bucket %s: %w", name, err)
+			}
+		}
+		if tx.Bucket(boltMeta).Get(boltUnpinned) != nil {
+			return nil
+		}
+		unpinned := 0
+		err := tx.Bucket(boltRequests).ForEach(func(k, _ []byte) error {
+			if tx.Bucket(boltPinned).Get(k) == nil {
+				unpinned++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return addUnpinned(tx, unpinned)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &boltStore{db: db, cfg: cfg, log: log, path: absPath}
+	if cfg.MaintenanceInterval > 0 {
+		store.startMaintenance(cfg.MaintenanceInterval)
+	}
+	return store, nil
+}
+
+// startMaintenance prunes every interval so that retention applies while no requests arrive.
+// bbolt reuses freed pages but never shrinks the file.
+func (s *boltStore) startMaintenance(interval time.Duration) {
+	s.stopMaintenance = make(chan struct{})
+	s.maintenanceDone = make(chan struct{})
+	go func() {
+		defer close(s.maintenanceDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopMaintenance:
+				return
+			case <-ticker.C:
+				var (
+					pruned    int64
+					bodyFiles []string
+				)
+				err := s.db.Update(func(tx *bolt.Tx) (err error) {
+					pruned, bodyFiles, err = s.prune(tx)
+					return err
+				})
+				if err != nil {
+					s.log.Warn("Storage maintenance failed", "error", err)
+					continue
+				}
+				removeBodyFiles(bodyFiles)
+				s.log.Info("Storage maintenance completed",
+					"pruned", pruned,
+					"size", humanize.Bytes(uint64(s.diskUsage())),
+				)
+			}
+		}
+	}()
+}
+
+// diskUsage is the size of the database file.
+func (s *boltStore) diskUsage() int64 {
+	if info, err := os.Stat(s.path); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (s *boltStore) Record(data *request.RequestData) (*StoredRequest, error) {
+	if data == nil {
+		return nil, fmt.Errorf("request data is nil")
+	}
+	if strings.TrimSpace(data.ID) == "" {
+		data.ID = fmt.Sprintf("REQ-%d", time.Now().UnixNano())
+	}
+	data.Timestamp = data.Timestamp.UTC()
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now().UTC()
+	}
+	if data.Size == 0 {
+		data.Size = int64(len(data.Body))
+	}
+	if data.Headers == nil {
+		data.Headers = http.Header{}
+	}
+	value, err := json.Marshal(&boltRequest{Data: data, WireBody: data.WireBody})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	var bodyFiles []string
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		requests := tx.Bucket(boltRequests)
+		if requests.Get([]byte(data.ID)) != nil {
+			return fmt.Errorf("insert request: request %s already exists", data.ID)
+		}
+		if err := requests.Put([]byte(data.ID), value); err != nil {
+			return fmt.Errorf("insert request: %w", err)
+		}
+		if err := tx.Bucket(boltTimeline).Put(timelineKey(data.Timestamp, data.ID), boltMark); err != nil {
+			return fmt.Errorf("insert request: %w", err)
+		}
+		if err := addUnpinned(tx, 1); err != nil {
+			return err
+		}
+		var err error
+		_, bodyFiles, err = s.prune(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	removeBodyFiles(bodyFiles)
+	return &StoredRequest{ID: data.ID, RequestData: data}, nil
+}
+
+// prune applies retention and max_records inside tx, like the sqlite store; pinned requests are
+// never pruned and do not count toward max_records.
+func (s *boltStore) prune(tx *bolt.Tx) (int64, []string, error) {
+	timeline := tx.Bucket(boltTimeline)
+	pinned := tx.Bucket(boltPinned)
+
+	var expired [][]byte
+	if s.cfg.Retention > 0 {
+		cutoff := time.Now().Add(-s.cfg.Retention).UTC().UnixNano()
+		c := timeline.Cursor()
+		for k, _ := c.First(); k != nil && int64(binary.BigEndian.Uint64(k)) < cutoff; k, _ = c.Next() {
+			if pinned.Get(k[8:]) == nil {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+		}
+	}
+	if s.cfg.MaxRecords > 0 {
+		count := unpinnedCount(tx) - len(expired)
+		if excess := count - s.cfg.MaxRecords; excess > 0 {
+			c := timeline.Cursor()
+			k, _ := c.First()
+			// Skip the requests already expired by retention
+			for i := 0; i < len(expired) && k != nil; k, _ = c.Next() {
+				if bytes.Equal(k, expired[i]) {
+					i++
+				}
+			}
+			for ; k != nil && excess > 0; k, _ = c.Next() {
+				if pinned.Get(k[8:]) == nil {
+					expired = append(expired, append([]byte(nil), k...))
+					excess--
+				}
+			}
+		}
+	}
+
+	var bodyFiles []string
+	for _, key := range expired {
+		bodyFile, err := deleteBoltRequest(tx, string(key[8:]), key)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bodyFile != "" {
+			bodyFiles = append(bodyFiles, bodyFile)
+		}
+	}
+	return int64(len(expired)), bodyFiles, nil
+}
+
+// deleteBoltRequest removes a request with its replays, forwards, comments and queued deliveries
+// and returns its spilled body file, if any.
+func deleteBoltRequest(tx *bolt.Tx, id string, timeline []byte) (string, error) {
+	record, err := getBoltRequest(tx, id)
+	if err != nil || record == nil {
+		return "", err
+	}
+	if err := tx.Bucket(boltRequests).Delete([]byte(id)); err != nil {
+		return "", fmt.Errorf("prune request: %w", err)
+	}
+	if err := tx.Bucket(boltTimeline).Delete(timeline); err != nil {
+		return "", fmt.Errorf("prune request: %w", err)
+	}
+	if err := tx.Bucket(boltPinned).Delete([]byte(id)); err != nil {
+		return "", fmt.Errorf("prune request: %w", err)
+	}
+	if !record.Pinned {
+		if err := addUnpinned(tx, -1); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range [][]byte{boltReplays, boltForwards, boltComments} {
+		if err := deletePrefix(tx.Bucket(name), requestPrefix(id)); err != nil {
+			return "", fmt.Errorf("prune %s: %w", name, err)
+		}
+	}
+	queue := tx.Bucket(boltQueue)
+	var queued [][]byte
+	err = queue.ForEach(func(k, v []byte) error {
+		var item QueuedForward
+		if err := json.Unmarshal(v, &item); err == nil && item.RequestID == id {
+			queued = append(queued, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, k := range queued {
+		if err := queue.Delete(k); err != nil {
+			return "", fmt.Errorf("prune forward queue: %w", err)
+		}
+	}
+	return record.Data.BodyFile, nil
+}
+
+func unpinnedCount(tx *bolt.Tx) int {
+	value := tx.Bucket(boltMeta).Get(boltUnpinned)
+	if len(value) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(value))
+}
+
+func addUnpinned(tx *bolt.Tx, delta int) error {
+	count := unpinnedCount(tx) + delta
+	if count < 0 {
+		count = 0
+	}
+	return tx.Bucket(boltMeta).Put(boltUnpinned, binary.BigEndian.AppendUint64(nil, uint64(count)))
+}
+
+func deletePrefix(bucket *bolt.Bucket, prefix []byte) error {
+	var keys [][]byte
+	c := bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timelineKey(ts time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(ts.UnixNano()))
+	return append(key, id...)
+}
+
+func requestPrefix(id string) []byte {
+	return append([]byte(id), 0)
+}
+
+// sequenceKey appends the big-endian n to prefix, so that keys sort in insertion order.
+func sequenceKey(prefix []byte, n uint64) []byte {
+	key := make([]byte, len(prefix), len(prefix)+8)
+	copy(key, prefix)
+	return binary.BigEndian.AppendUint64(key, n)
+}
+
+func getBoltRequest(tx *bolt.Tx, id string) (*boltRequest, error) {
+	value := tx.Bucket(boltRequests).Get([]byte(id))
+	if value == nil {
+		return nil, nil
+	}
+	return decodeBoltRequest(value)
+}
+
+func decodeBoltRequest(value []byte) (*boltRequest, error) {
+	var record boltRequest
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+	if record.Data == nil {
+		return nil, fmt.Errorf("decode request: no request data")
+	}
+	record.Data.WireBody = record.WireBody
+	if record.Data.Headers == nil {
+		record.Data.Headers = http.Header{}
+	}
+	return &record, nil
+}
+
+// putBoltRequest stores the triage state of a request that is known to exist.
+func putBoltRequest(tx *bolt.Tx, record *boltRequest) error {
+	record.WireBody = record.Data.WireBody
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	return tx.Bucket(boltRequests).Put([]byte(record.Data.ID), value)
+}
+
+func (r *boltRequest) stored() *StoredRequest {
+	stored := &StoredRequest{
+		ID:                r.Data.ID,
+		RequestData:       r.Data,
+		Note:              r.Note,
+		Pinned:            r.Pinned,
+		ForwardViolations: r.ForwardViolations,
+	}
+	if len(r.Tags) > 0 {
+		stored.Tags = append([]string(nil), r.Tags...)
+		sort.Strings(stored.Tags)
+	}
+	if r.ClaimedBy != "" {
+		stored.Claim = &Claim{User: r.ClaimedBy, ClaimedAt: time.Unix(0, r.ClaimedAt).UTC()}
+	}
+	return stored
+}
+
+// matches applies the filters of opts the way buildFilters does for the sqlite store.
+func (r *boltRequest) matches(opts ListOptions) bool {
+	data := r.Data
+	if method := strings.TrimSpace(opts.Method); method != "" && !strings.EqualFold(data.Method, method) {
+		return false
+	}
+	if search := strings.TrimSpace(strings.ToLower(opts.Search)); search != "" {
+		headers, _ := json.Marshal(data.Headers)
+		found := false
+		for _, field := range []string{data.Path, data.Query, data.RemoteAddr, data.UserAgent, string(headers), data.Instance, r.Note} {
+			if strings.Contains(strings.ToLower(field), search) {
+				found = true
+				break
+			}
+		}
+		if !found && !data.IsBinary {
+			found = strings.Contains(strings.ToLower(string(data.Body)), search)
+		}
+		if !found {
+			return false
+		}
+	}
+	switch claim := strings.TrimSpace(opts.Claim); claim {
+	case "":
+	case ClaimNone:
+		if r.ClaimedBy != "" {
+			return false
+		}
+	case ClaimAny:
+		if r.ClaimedBy == "" {
+			return false
+		}
+	default:
+		if !strings.EqualFold(r.ClaimedBy, claim) {
+			return false
+		}
+	}
+	for _, tag := range opts.Tags {
+		found := false
+		for _, have := range r.Tags {
+			if have == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if opts.Pinned && !r.Pinned {
+		return false
+	}
+	if opts.ForwardViolations && !r.ForwardViolations {
+		return false
+	}
+	if opts.Duplicates && data.DuplicateOf == "" {
+		return false
+	}
+	if contentType := strings.TrimSpace(strings.ToLower(opts.ContentType)); contentType != "" &&
+		!strings.HasPrefix(strings.ToLower(data.ContentType), contentType) {
+		return false
+	}
+	if opts.PathPrefix != "" && !strings.HasPrefix(data.Path, opts.PathPrefix) {
+		return false
+	}
+	if opts.MinSize > 0 && data.Size < opts.MinSize {
+		return false
+	}
+	if opts.MaxSize > 0 && data.Size > opts.MaxSize {
+		return false
+	}
+	if opts.IsBinary != nil && data.IsBinary != *opts.IsBinary {
+		return false
+	}
+	return true
+}
+
+// seekTimeline positions c at the newest request captured before until (all when zero).
+func seekTimeline(c *bolt.Cursor, until time.Time) ([]byte, []byte) {
+	if until.IsZero() {
+		return c.Last()
+	}
+	bound := make([]byte, 8)
+	binary.BigEndian.PutUint64(bound, uint64(until.UnixNano()))
+	if k, _ := c.Seek(bound); k != nil {
+		return c.Prev()
+	}
+	return c.Last()
+}
+
+// walkBoltRequests calls fn with the requests matching opts, newest first, until fn returns false;
+// Limit and Offset are ignored. A non-nil after resumes right after the timeline key of a previous walk.
+func walkBoltRequests(tx *bolt.Tx, opts ListOptions, after []byte, fn func(key []byte, record *boltRequest) (bool, error)) error {
+	requests := tx.Bucket(boltRequests)
+	c := tx.Bucket(boltTimeline).Cursor()
+	var k []byte
+	if after != nil {
+		c.Seek(after)
+		k, _ = c.Prev()
+	} else {
+		k, _ = seekTimeline(c, opts.Until)
+	}
+	since := opts.Since.UnixNano()
+	for ; k != nil; k, _ = c.Prev() {
+		if !opts.Since.IsZero() && int64(binary.BigEndian.Uint64(k)) < since {
+			break
+		}
+		value := requests.Get(k[8:])
+		if value == nil {
+			continue
+		}
+		record, err := decodeBoltRequest(value)
+		if err != nil {
+			return err
+		}
+		if !record.matches(opts) {
+			continue
+		}
+		more, err := fn(k, record)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *boltStore) List(opts ListOptions) ([]*StoredRequest, int, error) {
+	var (
+		result []*StoredRequest
+		total  int
+	)
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return walkBoltRequests(tx, opts, nil, func(_ []byte, record *boltRequest) (bool, error) {
+			if total >= offset && (opts.Limit <= 0 || len(result) < opts.Limit) {
+				result = append(result, record.stored())
+			}
+			total++
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	annotateSearchMatches(result, opts.Search)
+	return result, total, nil
+}
+
+func (s *boltStore) Iterate(opts ListOptions, fn func(*StoredRequest) bool) error {
+	var after []byte
+	for {
+		var page []*StoredRequest
+		err := s.db.View(func(tx *bolt.Tx) error {
+			return walkBoltRequests(tx, opts, after, func(key []byte, record *boltRequest) (bool, error) {
+				page = append(page, record.stored())
+				after = append(after[:0], key...)
+				return len(page) < boltScanPage, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range page {
+			if !fn(item) {
+				return nil
+			}
+		}
+		if len(page) < boltScanPage {
+			return nil
+		}
+	}
+}
+
+func (s *boltStore) Snapshot() ([]*StoredRequest, error) {
+	var records []*StoredRequest
+	err := s.Iterate(ListOptions{}, func(item *StoredRequest) bool {
+		records = append(records, item)
+		return true
+	})
+	return records, err
+}
+
+func (s *boltStore) Get(id string) (*StoredRequest, error) {
+	var record *boltRequest
+	err := s.db.View(func(tx *bolt.Tx) (err error) {
+		record, err = getBoltRequest(tx, id)
+		return err
+	})
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return record.stored(), nil
+}
+
+// updateBoltRequest runs fn on the stored request id and saves it unless fn fails; it returns
+// ErrNotFound for unknown requests.
+func (s *boltStore) updateBoltRequest(id string, fn func(*boltRequest) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		record, err := getBoltRequest(tx, id)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrNotFound
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		return putBoltRequest(tx, record)
+	})
+}
+
+func (s *boltStore) Claim(requestID, user string, force bool) (*Claim, error) {
+	claim := &Claim{User: user, ClaimedAt: time.Now().UTC()}
+	var current *Claim
+	err := s.updateBoltRequest(requestID, func(record *boltRequest) error {
+		if !force && record.ClaimedBy != "" && record.ClaimedBy != user {
+			current = record.stored().Claim
+			return ErrClaimed
+		}
+		record.ClaimedBy = claim.User
+		record.ClaimedAt = claim.ClaimedAt.UnixNano()
+		return nil
+	})
+	if err != nil {
+		return current, err
+	}
+	return claim, nil
+}
+
+func (s *boltStore) ReleaseClaim(requestID, user string, force bool) error {
+	return s.updateBoltRequest(requestID, func(record *boltRequest) error {
+		if !force && record.ClaimedBy != "" && record.ClaimedBy != user {
+			return ErrClaimed
+		}
+		record.ClaimedBy = ""
+		record.ClaimedAt = 0
+		return nil
+	})
+}
+
+// Annotate replaces the tags (when tags is non-nil) and the note (when note is non-nil) of a request
+func (s *boltStore) Annotate(requestID string, tags []string, note *string) error {
+	return s.updateBoltRequest(requestID, func(record *boltRequest) error {
+		if note != nil {
+			record.Note = *note
+		}
+		if tags != nil {
+			record.Tags = nil
+			for _, tag := range tags {
+				if !containsString(record.Tags, tag) {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Pin sets or clears the pinned flag of a request
+func (s *boltStore) Pin(requestID string, pinned bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		record, err := getBoltRequest(tx, requestID)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrNotFound
+		}
+		if record.Pinned != pinned {
+			delta := 1
+			if pinned {
+				delta = -1
+			}
+			if err := addUnpinned(tx, delta); err != nil {
+				return err
+			}
+		}
+		record.Pinned = pinned
+		if pinned {
+			err = tx.Bucket(boltPinned).Put([]byte(requestID), boltMark)
+		} else {
+			err = tx.Bucket(boltPinned).Delete([]byte(requestID))
+		}
+		if err != nil {
+			return fmt.Errorf("pin request: %w", err)
+		}
+		return putBoltRequest(tx, record)
+	})
+}
+
+// Delete removes requests and everything stored for them
+func (s *boltStore) Delete(ids []string) (int64, error) {
+	var deleted int64
+	var bodyFiles []string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			record, err := getBoltRequest(tx, id)
+			if err != nil {
+				return err
+			}
+			if record == nil {
+				continue
+			}
+			bodyFile, err := deleteBoltRequest(tx, id, timelineKey(record.Data.Timestamp, id))
+			if err != nil {
+				return err
+			}
+			if bodyFile != "" {
+				bodyFiles = append(bodyFiles, bodyFile)
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	removeBodyFiles(bodyFiles)
+	return deleted, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// errWriteCheck rolls back the transaction of CheckWritable
+var errWriteCheck = errors.New("write check")
+
+// CheckWritable opens and rolls back a write transaction. The context is not honored while
+// waiting for another writer.
+func (s *boltStore) CheckWritable(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := s.db.Update(func(*bolt.Tx) error {
+		return errWriteCheck
+	})
+	if errors.Is(err, errWriteCheck) {
+		return nil
+	}
+	return err
+}
+
+func (s *boltStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	if s.stopMaintenance != nil {
+		close(s.stopMaintenance)
+		<-s.maintenanceDone
+	}
+	return s.db.Close()
+}
+
+// RecordReplay stores a replay record
+func (s *boltStore) RecordReplay(data *request.ReplayData) (*StoredReplay, error) {
+	if data == nil {
+		return nil, fmt.Errorf("replay data is nil")
+	}
+	if strings.TrimSpace(data.ID) == "" {
+		data.ID = fmt.Sprintf("RPL-%d", time.Now().UnixNano())
+	}
+	data.Timestamp = data.Timestamp.UTC()
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now().UTC()
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal replay: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		key := append(sequenceKey(requestPrefix(data.OriginalRequestID), uint64(data.Timestamp.UnixNano())), data.ID...)
+		return tx.Bucket(boltReplays).Put(key, value)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("insert replay: %w", err)
+	}
+	return &StoredReplay{ReplayData: data}, nil
+}
+
+// GetReplays retrieves all replays for a specific request, newest first
+func (s *boltStore) GetReplays(originalRequestID string) ([]*StoredReplay, error) {
+	var result []*StoredReplay
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return eachPrefix(tx.Bucket(boltReplays), requestPrefix(originalRequestID), func(value []byte) error {
+			var data request.ReplayData
+			if err := json.Unmarshal(value, &data); err != nil {
+				return fmt.Errorf("decode replay: %w", err)
+			}
+			result = append(result, &StoredReplay{ReplayData: &data})
+			return nil
+		})
+	})
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, err
+}
+
+// eachPrefix calls fn with the values of the keys starting with prefix, in key order.
+func eachPrefix(bucket *bolt.Bucket, prefix []byte, fn func(value []byte) error) error {
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordForwards stores the target responses of a forwarded request
+func (s *boltStore) RecordForwards(requestID string, records []*ForwardRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		forwards := tx.Bucket(boltForwards)
+		violated := false
+		for _, record := range records {
+			if record == nil {
+				continue
+			}
+			record.RequestID = requestID
+			record.Timestamp = record.Timestamp.UTC()
+			if record.Timestamp.IsZero() {
+				record.Timestamp = time.Now().UTC()
+			}
+			if record.Headers == nil {
+				record.Headers = http.Header{}
+			}
+			id, err := forwards.NextSequence()
+			if err != nil {
+				return fmt.Errorf("insert forward: %w", err)
+			}
+			record.ID = int64(id)
+			value, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("marshal forward: %w", err)
+			}
+			if err := forwards.Put(sequenceKey(requestPrefix(requestID), id), value); err != nil {
+				return fmt.Errorf("insert forward: %w", err)
+			}
+			violated = violated || len(record.Violations) > 0
+		}
+		if !violated {
+			return nil
+		}
+		stored, err := getBoltRequest(tx, requestID)
+		if err != nil || stored == nil || stored.ForwardViolations {
+			return err
+		}
+		stored.ForwardViolations = true
+		return putBoltRequest(tx, stored)
+	})
+}
+
+// GetForwards retrieves the target responses recorded for a request
+func (s *boltStore) GetForwards(requestID string) ([]*ForwardRecord, error) {
+	var result []*ForwardRecord
+	err := s.db.View(func(tx *bolt.Tx) (err error) {
+		result, err = boltForwardsOf(tx, requestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result, nil
+}
+
+func boltForwardsOf(tx *bolt.Tx, requestID string) ([]*ForwardRecord, error) {
+	var result []*ForwardRecord
+	err := eachPrefix(tx.Bucket(boltForwards), requestPrefix(requestID), func(value []byte) error {
+		var record ForwardRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("decode forward: %w", err)
+		}
+		result = append(result, &record)
+		return nil
+	})
+	return result, err
+}
+
+// AddComment stores a comment on an existing request
+func (s *boltStore) AddComment(comment *Comment) error {
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+	comment.CreatedAt = comment.CreatedAt.UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltRequests).Get([]byte(comment.RequestID)) == nil {
+			return ErrNotFound
+		}
+		comments := tx.Bucket(boltComments)
+		id, err := comments.NextSequence()
+		if err != nil {
+			return fmt.Errorf("insert comment: %w", err)
+		}
+		comment.ID = int64(id)
+		value, err := json.Marshal(comment)
+		if err != nil {
+			return fmt.Errorf("marshal comment: %w", err)
+		}
+		return comments.Put(sequenceKey(requestPrefix(comment.RequestID), id), value)
+	})
+}
+
+// GetComments retrieves the comments left on a request, oldest first
+func (s *boltStore) GetComments(requestID string) ([]*Comment, error) {
+	var result []*Comment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return eachPrefix(tx.Bucket(boltComments), requestPrefix(requestID), func(value []byte) error {
+			var comment Comment
+			if err := json.Unmarshal(value, &comment); err != nil {
+				return fmt.Errorf("decode comment: %w", err)
+			}
+			result = append(result, &comment)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// EnqueueForward adds a failed delivery to the forward queue
+func (s *boltStore) EnqueueForward(item *QueuedForward) error {
+	now := time.Now().UTC()
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = now
+	}
+	if item.NextAttempt.IsZero() {
+		item.NextAttempt = now
+	}
+	item.CreatedAt = item.CreatedAt.UTC()
+	item.NextAttempt = item.NextAttempt.UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltRequests).Get([]byte(item.RequestID)) == nil {
+			return ErrNotFound
+		}
+		queue := tx.Bucket(boltQueue)
+		existing, err := boltQueued(queue)
+		if err != nil {
+			return err
+		}
+		for _, queued := range existing {
+			if queued.RequestID == item.RequestID && queued.TargetURL == item.TargetURL {
+				item.ID = queued.ID
+				return nil
+			}
+		}
+		id, err := queue.NextSequence()
+		if err != nil {
+			return fmt.Errorf("enqueue forward: %w", err)
+		}
+		item.ID = int64(id)
+		return putQueued(queue, item)
+	})
+}
+
+func boltQueued(queue *bolt.Bucket) ([]*QueuedForward, error) {
+	var result []*QueuedForward
+	err := queue.ForEach(func(_, value []byte) error {
+		var item QueuedForward
+		if err := json.Unmarshal(value, &item); err != nil {
+			return fmt.Errorf("decode queued forward: %w", err)
+		}
+		result = append(result, &item)
+		return nil
+	})
+	return result, err
+}
+
+func putQueued(queue *bolt.Bucket, item *QueuedForward) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("marshal queued forward: %w", err)
+	}
+	return queue.Put(sequenceKey(nil, uint64(item.ID)), value)
+}
+
+// QueuedForwards lists queued deliveries, soonest first
+func (s *boltStore) QueuedForwards(due time.Time, limit int) ([]*QueuedForward, error) {
+	var items []*QueuedForward
+	err := s.db.View(func(tx *bolt.Tx) (err error) {
+		items, err = boltQueued(tx.Bucket(boltQueue))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := items[:0]
+	for _, item := range items {
+		if due.IsZero() || !item.NextAttempt.After(due) {
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextAttempt.Equal(result[j].NextAttempt) {
+			return result[i].NextAttempt.Before(result[j].NextAttempt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// RescheduleForward stores the outcome of a failed queue retry
+func (s *boltStore) RescheduleForward(item *QueuedForward) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		queue := tx.Bucket(boltQueue)
+		value := queue.Get(sequenceKey(nil, uint64(item.ID)))
+		if value == nil {
+			return ErrNotFound
+		}
+		var stored QueuedForward
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return fmt.Errorf("decode queued forward: %w", err)
+		}
+		stored.Attempts = item.Attempts
+		stored.LastError = item.LastError
+		stored.NextAttempt = item.NextAttempt.UTC()
+		return putQueued(queue, &stored)
+	})
+}
+
+// DequeueForward removes a delivery from the forward queue
+func (s *boltStore) DequeueForward(id int64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltQueue).Delete(sequenceKey(nil, uint64(id)))
+	})
+	if err != nil {
+		return fmt.Errorf("dequeue forward: %w", err)
+	}
+	return nil
+}
+
+// SaveToken stores an API token created through the admin API
+func (s *boltStore) SaveToken(token *APIToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	token.CreatedAt = token.CreatedAt.UTC()
+	value, err := json.Marshal(&boltToken{APIToken: token, Hash: token.Hash})
+	if err != nil {
+		return fmt.Errorf("marshal token: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(boltTokens)
+		if tokens.Get([]byte(token.Name)) != nil {
+			return fmt.Errorf("insert token: token %s already exists", token.Name)
+		}
+		return tokens.Put([]byte(token.Name), value)
+	})
+}
+
+// Tokens lists the stored API tokens by name
+func (s *boltStore) Tokens() ([]*APIToken, error) {
+	var result []*APIToken
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTokens).ForEach(func(_, value []byte) error {
+			stored := boltToken{APIToken: &APIToken{}}
+			if err := json.Unmarshal(value, &stored); err != nil {
+				return fmt.Errorf("decode token: %w", err)
+			}
+			stored.APIToken.Hash = stored.Hash
+			result = append(result, stored.APIToken)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// DeleteToken removes a stored API token
+func (s *boltStore) DeleteToken(name string) error {
+	return deleteBoltKey(s.db, boltTokens, name)
+}
+
+// SaveMockRule stores a mock rule managed through the admin API, replacing the one with the same name
+func (s *boltStore) SaveMockRule(rule *MockRule) error {
+	now := time.Now().UTC()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.CreatedAt = rule.CreatedAt.UTC()
+	rule.UpdatedAt = now
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rules := tx.Bucket(boltMock)
+		stored := *rule
+		// A replaced rule keeps its position
+		if value := rules.Get([]byte(rule.Name)); value != nil {
+			var existing MockRule
+			if err := json.Unmarshal(value, &existing); err == nil {
+				stored.CreatedAt = existing.CreatedAt
+			}
+		}
+		value, err := json.Marshal(&stored)
+		if err != nil {
+			return fmt.Errorf("save mock rule: %w", err)
+		}
+		return rules.Put([]byte(rule.Name), value)
+	})
+}
+
+// MockRules lists the stored mock rules in the order they were added
+func (s *boltStore) MockRules() ([]*MockRule, error) {
+	var result []*MockRule
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltMock).ForEach(func(_, value []byte) error {
+			var rule MockRule
+			if err := json.Unmarshal(value, &rule); err != nil {
+				return fmt.Errorf("decode mock rule: %w", err)
+			}
+			result = append(result, &rule)
+			return nil
+		})
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, err
+}
+
+// DeleteMockRule removes a stored mock rule
+func (s *boltStore) DeleteMockRule(name string) error {
+	return deleteBoltKey(s.db, boltMock, name)
+}
+
+// RecordAudit appends an entry to the audit log
+func (s *boltStore) RecordAudit(entry *AuditEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		audit := tx.Bucket(boltAudit)
+		id, err := audit.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.ID = int64(id)
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshal audit entry: %w", err)
+		}
+		return audit.Put(sequenceKey(nil, id), value)
+	})
+}
+
+// AuditEntries lists the audit log newest first
+func (s *boltStore) AuditEntries(opts AuditOptions) ([]*AuditEntry, int, error) {
+	var (
+		result []*AuditEntry
+		total  int
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltAudit).Cursor()
+		for k, value := c.Last(); k != nil; k, value = c.Prev() {
+			var entry AuditEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return fmt.Errorf("decode audit entry: %w", err)
+			}
+			if (opts.Action != "" && entry.Action != opts.Action) ||
+				(opts.Actor != "" && entry.Actor != opts.Actor) ||
+				(!opts.Since.IsZero() && entry.Timestamp.Before(opts.Since)) ||
+				(!opts.Until.IsZero() && !entry.Timestamp.Before(opts.Until)) {
+				continue
+			}
+			total++
+			if total > opts.Offset && (opts.Limit <= 0 || len(result) < opts.Limit) {
+				result = append(result, &entry)
+			}
+		}
+		return nil
+	})
+	return result, total, err
+}
+
+func deleteBoltKey(db *bolt.DB, bucket []byte, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b.Get([]byte(key)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// ForwardStats aggregates the recorded deliveries per target URL
+func (s *boltStore) ForwardStats(since, until time.Time) ([]*TargetForwardStats, error) {
+	byTarget := make(map[string]*TargetForwardStats)
+	latency := make(map[string]int64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltForwards).ForEach(func(_, value []byte) error {
+			var record ForwardRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("decode forward: %w", err)
+			}
+			if (!since.IsZero() && record.Timestamp.Before(since)) || (!until.IsZero() && !record.Timestamp.Before(until)) {
+				return nil
+			}
+			stats := byTarget[record.TargetURL]
+			if stats == nil {
+				stats = &TargetForwardStats{TargetURL: record.TargetURL}
+				byTarget[record.TargetURL] = stats
+			}
+			stats.Total++
+			if record.Success {
+				stats.Succeeded++
+			}
+			if len(record.Violations) > 0 {
+				stats.Violations++
+			}
+			latency[record.TargetURL] += record.LatencyMs
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []*TargetForwardStats
+	for target, stats := range byTarget {
+		stats.Failed = stats.Total - stats.Succeeded
+		stats.SuccessRate = float64(stats.Succeeded) / float64(stats.Total)
+		stats.AvgLatencyMs = float64(latency[target]) / float64(stats.Total)
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].TargetURL < result[j].TargetURL })
+	return result, nil
+}
+
+// Aggregate groups the matching requests per interval; forward outcomes are counted towards the
+// interval of their request.
+func (s *boltStore) Aggregate(opts ListOptions, interval time.Duration) ([]*IntervalAggregate, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	type bucket struct {
+		agg       IntervalAggregate
+		size      int64
+		latencyMs int64
+	}
+	buckets := make(map[int64]*bucket)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return walkBoltRequests(tx, opts, nil, func(_ []byte, record *boltRequest) (bool, error) {
+			forwards, err := boltForwardsOf(tx, record.Data.ID)
+			if err != nil {
+				return false, err
+			}
+			n := record.Data.Timestamp.UnixNano() / interval.Nanoseconds()
+			b := buckets[n]
+			if b == nil {
+				b = &bucket{agg: IntervalAggregate{Start: time.Unix(0, n*interval.Nanoseconds()).UTC()}}
+				buckets[n] = b
+			}
+			b.agg.Requests++
+			b.size += record.Data.Size
+			failed := record.Data.MockResponse.Status >= 400
+			for _, forward := range forwards {
+				failed = failed || !forward.Success
+				b.latencyMs += forward.LatencyMs
+			}
+			if failed {
+				b.agg.Errors++
+			}
+			b.agg.Forwards += len(forwards)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*IntervalAggregate, 0, len(buckets))
+	for _, b := range buckets {
+		b.agg.AvgSizeBytes = float64(b.size) / float64(b.agg.Requests)
+		if b.agg.Forwards > 0 {
+			b.agg.AvgForwardLatencyMs = float64(b.latencyMs) / float64(b.agg.Forwards)
+		}
+		agg := b.agg
+		result = append(result, &agg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, nil
+}
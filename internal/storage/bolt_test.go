@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func newTestBoltStore(t *testing.T, cfg config.StorageConfig) Store {
+	t.Helper()
+	cfg.Driver = "bolt"
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(t.TempDir(), "reqtap.bolt")
+	}
+	store, err := New(&cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store
+}
+
+func TestBoltStore_RecordAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reqtap.bolt")
+	store, err := New(&config.StorageConfig{Driver: "bolt", Path: path}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	data := fakeRequest("rec-1", "POST", "/hook")
+	data.WireBody = []byte("gzipped")
+	data.ContentEncoding = "gzip"
+	if _, err := store.Record(data); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if _, err := store.Record(fakeRequest("rec-1", "POST", "/hook")); err == nil {
+		t.Fatalf("expected a duplicate ID to be rejected")
+	}
+	if err := store.Annotate("rec-1", []string{"stripe", "billing"}, nil); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	store.Close()
+
+	store = newTestBoltStore(t, config.StorageConfig{Path: path})
+	got, err := store.Get("rec-1")
+	if err != nil || got == nil {
+		t.Fatalf("expected the request to survive a reopen, got %v (%v)", got, err)
+	}
+	if string(got.Body) != "body" || string(got.WireBody) != "gzipped" || got.Headers.Get("User-Agent") != "reqtap" {
+		t.Fatalf("unexpected stored request: %+v", got.RequestData)
+	}
+	if fmt.Sprint(got.Tags) != "[billing stripe]" {
+		t.Fatalf("expected sorted tags, got %v", got.Tags)
+	}
+	if missing, err := store.Get("missing"); missing != nil || err != nil {
+		t.Fatalf("expected nil for unknown requests, got %v (%v)", missing, err)
+	}
+}
+
+func TestBoltStore_ListFilters(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		method := "POST"
+		if i%2 == 0 {
+			method = "GET"
+		}
+		data := fakeRequest(fmt.Sprintf("req-%d", i), method, fmt.Sprintf("/hook/%d", i))
+		data.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	items, total, err := store.List(ListOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 5 || len(items) != 2 || items[0].ID != "req-3" || items[1].ID != "req-2" {
+		t.Fatalf("expected req-3 and req-2 of 5, got %d items of %d", len(items), total)
+	}
+	if _, total, _ = store.List(ListOptions{Method: "get"}); total != 3 {
+		t.Fatalf("expected 3 GET requests, got %d", total)
+	}
+	if _, total, _ = store.List(ListOptions{Search: "HOOK/4"}); total != 1 {
+		t.Fatalf("expected the search to match one request, got %d", total)
+	}
+	items, total, _ = store.List(ListOptions{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)})
+	if total != 2 || items[0].ID != "req-2" || items[1].ID != "req-1" {
+		t.Fatalf("expected req-2 and req-1 in the time range, got %d", total)
+	}
+
+	if err := store.Pin("req-0", true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	if items, _, _ = store.List(ListOptions{Pinned: true}); len(items) != 1 || !items[0].Pinned {
+		t.Fatalf("expected the pinned request, got %d", len(items))
+	}
+	if err := store.Pin("missing", true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBoltStore_IterateAcrossPages(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	base := time.Now()
+	count := boltScanPage + 10
+	for i := 0; i < count; i++ {
+		data := fakeRequest(fmt.Sprintf("req-%04d", i), "POST", "/hook")
+		data.Timestamp = base.Add(time.Duration(i) * time.Millisecond)
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	seen := 0
+	previous := ""
+	err := store.Iterate(ListOptions{}, func(item *StoredRequest) bool {
+		if previous != "" && item.ID >= previous {
+			t.Fatalf("expected newest first, got %s after %s", item.ID, previous)
+		}
+		previous = item.ID
+		seen++
+		// The callback may use the store
+		_, err := store.GetForwards(item.ID)
+		return err == nil
+	})
+	if err != nil || seen != count {
+		t.Fatalf("expected %d requests, got %d (%v)", count, seen, err)
+	}
+}
+
+func TestBoltStore_PruneKeepsPinned(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{MaxRecords: 2})
+	base := time.Now()
+	for i := 0; i < 2; i++ {
+		data := fakeRequest(fmt.Sprintf("req-%d", i), "POST", "/hook")
+		data.Timestamp = base.Add(time.Duration(i) * time.Second)
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := store.Pin("req-0", true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	if err := store.RecordForwards("req-1", []*ForwardRecord{{TargetURL: "http://a", Success: true}}); err != nil {
+		t.Fatalf("record forwards: %v", err)
+	}
+	for i := 2; i < 4; i++ {
+		data := fakeRequest(fmt.Sprintf("req-%d", i), "POST", "/hook")
+		data.Timestamp = base.Add(time.Duration(i) * time.Second)
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	items, total, _ := store.List(ListOptions{})
+	if total != 3 || items[0].ID != "req-3" || items[1].ID != "req-2" || items[2].ID != "req-0" {
+		t.Fatalf("expected req-3, req-2 and the pinned req-0, got %d", total)
+	}
+	if forwards, _ := store.GetForwards("req-1"); len(forwards) != 0 {
+		t.Fatalf("expected the forwards of a pruned request to be removed, got %d", len(forwards))
+	}
+}
+
+func TestBoltStore_ForwardsAndStats(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	if _, err := store.Record(fakeRequest("req-1", "POST", "/hook")); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	records := []*ForwardRecord{
+		{TargetURL: "http://a", StatusCode: 200, Success: true, LatencyMs: 10},
+		{TargetURL: "http://b", StatusCode: 500, LatencyMs: 30, Violations: []string{"status 500"}},
+	}
+	if err := store.RecordForwards("req-1", records); err != nil {
+		t.Fatalf("record forwards: %v", err)
+	}
+	forwards, err := store.GetForwards("req-1")
+	if err != nil || len(forwards) != 2 || forwards[0].ID == 0 || forwards[1].TargetURL != "http://b" {
+		t.Fatalf("unexpected forwards: %+v (%v)", forwards, err)
+	}
+	if items, _, _ := store.List(ListOptions{ForwardViolations: true}); len(items) != 1 || !items[0].ForwardViolations {
+		t.Fatalf("expected the request with a violated contract, got %d", len(items))
+	}
+
+	stats, err := store.(ForwardStatsStore).ForwardStats(time.Time{}, time.Time{})
+	if err != nil || len(stats) != 2 || stats[0].Succeeded != 1 || stats[1].Failed != 1 || stats[1].Violations != 1 {
+		t.Fatalf("unexpected forward stats: %+v (%v)", stats, err)
+	}
+	aggregates, err := store.(AggregateStore).Aggregate(ListOptions{}, time.Hour)
+	if err != nil || len(aggregates) != 1 || aggregates[0].Errors != 1 || aggregates[0].Forwards != 2 || aggregates[0].AvgForwardLatencyMs != 20 {
+		t.Fatalf("unexpected aggregates: %+v (%v)", aggregates, err)
+	}
+}
+
+func TestBoltStore_ClaimsAndComments(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	if _, err := store.Record(fakeRequest("req-1", "POST", "/hook")); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if _, err := store.Claim("req-1", "alice", false); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	current, err := store.Claim("req-1", "bob", false)
+	if !errors.Is(err, ErrClaimed) || current == nil || current.User != "alice" {
+		t.Fatalf("expected alice's claim to hold, got %v (%v)", current, err)
+	}
+	if items, _, _ := store.List(ListOptions{Claim: "ALICE"}); len(items) != 1 {
+		t.Fatalf("expected alice's request, got %d", len(items))
+	}
+	if err := store.ReleaseClaim("req-1", "bob", true); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if items, _, _ := store.List(ListOptions{Claim: ClaimNone}); len(items) != 1 {
+		t.Fatalf("expected the request to be unclaimed, got %d", len(items))
+	}
+
+	if err := store.AddComment(&Comment{RequestID: "missing", Author: "alice", Body: "?"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	for _, body := range []string{"first", "second"} {
+		if err := store.AddComment(&Comment{RequestID: "req-1", Author: "alice", Body: body}); err != nil {
+			t.Fatalf("add comment: %v", err)
+		}
+	}
+	comments, err := store.GetComments("req-1")
+	if err != nil || len(comments) != 2 || comments[0].Body != "first" || comments[1].ID <= comments[0].ID {
+		t.Fatalf("unexpected comments: %+v (%v)", comments, err)
+	}
+}
+
+func TestBoltStore_ForwardQueue(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	if _, err := store.Record(fakeRequest("req-1", "POST", "/hook")); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := store.EnqueueForward(&QueuedForward{RequestID: "missing", TargetURL: "http://a"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	now := time.Now()
+	first := &QueuedForward{RequestID: "req-1", TargetURL: "http://a", NextAttempt: now.Add(time.Minute)}
+	second := &QueuedForward{RequestID: "req-1", TargetURL: "http://b", NextAttempt: now.Add(-time.Minute)}
+	for _, item := range []*QueuedForward{first, second} {
+		if err := store.EnqueueForward(item); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	again := &QueuedForward{RequestID: "req-1", TargetURL: "http://a"}
+	if err := store.EnqueueForward(again); err != nil || again.ID != first.ID {
+		t.Fatalf("expected the queued delivery to be kept, got %d (%v)", again.ID, err)
+	}
+
+	due, err := store.QueuedForwards(now, 0)
+	if err != nil || len(due) != 1 || due[0].TargetURL != "http://b" {
+		t.Fatalf("expected one due delivery, got %+v (%v)", due, err)
+	}
+	second.Attempts, second.LastError, second.NextAttempt = 1, "refused", now.Add(time.Hour)
+	if err := store.RescheduleForward(second); err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+	all, _ := store.QueuedForwards(time.Time{}, 0)
+	if len(all) != 2 || all[0].ID != first.ID || all[1].LastError != "refused" {
+		t.Fatalf("unexpected queue: %+v", all)
+	}
+	if err := store.DequeueForward(first.ID); err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if err := store.RescheduleForward(first); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBoltStore_TokensAndMockRules(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	tokens := store.(TokenStore)
+	if err := tokens.SaveToken(&APIToken{Name: "ci", Hash: "abc", Scopes: []string{"read"}}); err != nil {
+		t.Fatalf("save token: %v", err)
+	}
+	if err := tokens.SaveToken(&APIToken{Name: "ci", Hash: "def"}); err == nil {
+		t.Fatalf("expected a duplicate token name to be rejected")
+	}
+	list, err := tokens.Tokens()
+	if err != nil || len(list) != 1 || list[0].Hash != "abc" || list[0].Scopes[0] != "read" {
+		t.Fatalf("unexpected tokens: %+v (%v)", list, err)
+	}
+	if err := tokens.DeleteToken("ci"); err != nil {
+		t.Fatalf("delete token: %v", err)
+	}
+	if err := tokens.DeleteToken("ci"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	rules := store.(MockRuleStore)
+	for _, name := range []string{"b", "a"} {
+		if err := rules.SaveMockRule(&MockRule{Name: name, Definition: "status: 200"}); err != nil {
+			t.Fatalf("save rule: %v", err)
+		}
+	}
+	if err := rules.SaveMockRule(&MockRule{Name: "b", Definition: "status: 404"}); err != nil {
+		t.Fatalf("replace rule: %v", err)
+	}
+	saved, err := rules.MockRules()
+	if err != nil || len(saved) != 2 || saved[0].Name != "b" || saved[0].Definition != "status: 404" {
+		t.Fatalf("expected the replaced rule to keep its position, got %+v (%v)", saved, err)
+	}
+}
+
+func TestBoltStore_Delete(t *testing.T) {
+	store := newTestBoltStore(t, config.StorageConfig{})
+	for _, id := range []string{"old-0", "old-1", "new"} {
+		if _, err := store.Record(fakeRequest(id, "POST", "/hook")); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := store.Pin("old-1", true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	if err := store.RecordForwards("old-0", []*ForwardRecord{{TargetURL: "http://a", Success: true}}); err != nil {
+		t.Fatalf("record forwards: %v", err)
+	}
+
+	deleted, err := store.Delete([]string{"old-0", "old-1", "missing"})
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 requests deleted, got %d (%v)", deleted, err)
+	}
+	items, total, _ := store.List(ListOptions{})
+	if total != 1 || items[0].ID != "new" {
+		t.Fatalf("expected only the new request to remain, got %d", total)
+	}
+	if forwards, _ := store.GetForwards("old-0"); len(forwards) != 0 {
+		t.Fatalf("expected the forwards to be removed, got %d", len(forwards))
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// sqliteAutoVacuumIncremental is the PRAGMA auto_vacuum value for INCREMENTAL mode.
+const sqliteAutoVacuumIncremental = 2
+
+// maintenanceReport summarizes one maintenance pass.
+type maintenanceReport struct {
+	Pruned    int64
+	Before    int64
+	After     int64
+	Converted bool
+}
+
+// Reclaimed is the number of bytes the pass returned to the filesystem.
+func (r maintenanceReport) Reclaimed() int64 {
+	if r.After >= r.Before {
+		return 0
+	}
+	return r.Before - r.After
+}
+
+// startMaintenance prunes, vacuums and checkpoints every interval so the database shrinks
+// even while no requests arrive to trigger prune-on-insert.
+func (s *sqliteStore) startMaintenance(interval time.Duration) {
+	s.stopMaintenance = make(chan struct{})
+	s.maintenanceDone = make(chan struct{})
+	go func() {
+		defer close(s.maintenanceDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopMaintenance:
+				return
+			case <-ticker.C:
+				report, err := s.maintain(context.Background())
+				if err != nil {
+					s.log.Warn("Storage maintenance failed", "error", err)
+					continue
+				}
+				s.log.Info("Storage maintenance completed",
+					"pruned", report.Pruned,
+					"reclaimed", humanize.Bytes(uint64(report.Reclaimed())),
+					"size", humanize.Bytes(uint64(report.After)),
+					"converted_to_incremental_vacuum", report.Converted,
+				)
+			}
+		}
+	}()
+}
+
+// maintain applies retention, releases free pages and truncates the WAL. A database created
+// before auto_vacuum was enabled is converted with one full VACUUM.
+func (s *sqliteStore) maintain(ctx context.Context) (maintenanceReport, error) {
+	report := maintenanceReport{Before: s.diskUsage()}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, err
+	}
+	pruned, bodyFiles, err := s.prune(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+	removeBodyFiles(bodyFiles)
+	report.Pruned = pruned
+
+	var mode int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return report, fmt.Errorf("read auto_vacuum: %w", err)
+	}
+	if mode != sqliteAutoVacuumIncremental {
+		if _, err := s.db.ExecContext(ctx, "PRAGMA auto_vacuum=INCREMENTAL"); err != nil {
+			return report, fmt.Errorf("enable incremental vacuum: %w", err)
+		}
+		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return report, fmt.Errorf("vacuum: %w", err)
+		}
+		if err := s.rebuildSearchIndex(ctx); err != nil {
+			return report, fmt.Errorf("rebuild search index: %w", err)
+		}
+		report.Converted = true
+	} else if _, err := s.db.ExecContext(ctx, "PRAGMA incremental_vacuum"); err != nil {
+		return report, fmt.Errorf("incremental vacuum: %w", err)
+	}
+
+	var busy, logFrames, checkpointed int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return report, fmt.Errorf("wal checkpoint: %w", err)
+	}
+
+	report.After = s.diskUsage()
+	return report, nil
+}
+
+// diskUsage is the combined size of the database file and its write-ahead log.
+func (s *sqliteStore) diskUsage() int64 {
+	var total int64
+	for _, path := range []string{s.path, s.path + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SaveMockRule stores a mock rule managed through the admin API, replacing the one with the same name
+func (s *sqliteStore) SaveMockRule(rule *MockRule) error {
+	now := time.Now().UTC()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.CreatedAt = rule.CreatedAt.UTC()
+	rule.UpdatedAt = now
+	_, err := s.db.ExecContext(context.Background(),
+		`INSERT INTO mock_rules (name, definition, updated_by, created_at_ns, updated_at_ns) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET definition = excluded.definition, updated_by = excluded.updated_by,
+		 updated_at_ns = excluded.updated_at_ns`,
+		rule.Name, rule.Definition, rule.UpdatedBy, rule.CreatedAt.UnixNano(), rule.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("save mock rule: %w", err)
+	}
+	return nil
+}
+
+// MockRules lists the stored mock rules in the order they were added
+func (s *sqliteStore) MockRules() ([]*MockRule, error) {
+	rows, err := s.db.QueryContext(context.Background(),
+		`SELECT name, definition, COALESCE(updated_by, ''), created_at_ns, updated_at_ns FROM mock_rules ORDER BY created_at_ns, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*MockRule
+	for rows.Next() {
+		var (
+			rule                 MockRule
+			createdAt, updatedAt int64
+		)
+		if err := rows.Scan(&rule.Name, &rule.Definition, &rule.UpdatedBy, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		rule.CreatedAt = time.Unix(0, createdAt).UTC()
+		rule.UpdatedAt = time.Unix(0, updatedAt).UTC()
+		result = append(result, &rule)
+	}
+	return result, rows.Err()
+}
+
+// DeleteMockRule removes a stored mock rule
+func (s *sqliteStore) DeleteMockRule(name string) error {
+	res, err := s.db.ExecContext(context.Background(), `DELETE FROM mock_rules WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete mock rule: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SearchMatch tells where ListOptions.Search matched the body of a listed request, so clients can
+// highlight it without downloading and scanning the body themselves.
+type SearchMatch struct {
+	// Snippet is an excerpt of the body around the first match
+	Snippet string `json:"snippet"`
+	// Highlights are the [start, end) byte offsets of the matches within Snippet
+	Highlights [][2]int `json:"highlights"`
+	// Matches counts the occurrences in the whole body, up to maxSearchMatches
+	Matches int `json:"matches"`
+}
+
+const (
+	// searchSnippetContext is how many bytes of the body a snippet shows before the first match
+	searchSnippetContext = 60
+	// searchSnippetBytes bounds the length of a snippet
+	searchSnippetBytes = 240
+	// maxSearchMatches bounds the matches counted per body
+	maxSearchMatches = 1000
+	// minIndexedSearch is the shortest search the trigram index can answer; shorter ones scan bodies
+	minIndexedSearch = 3
+)
+
+// searchIndexSchema indexes the text bodies of requests for substring search. The trigram index
+// reads the bodies through the requests_search_text view instead of keeping a copy, and the
+// triggers keep it in step with inserts and pruning. Binary bodies are indexed as empty.
+const searchIndexSchema = `
+CREATE VIEW IF NOT EXISTS requests_search_text AS
+    SELECT rowid AS doc_id, CASE WHEN COALESCE(is_binary, 0) = 0 THEN CAST(body AS TEXT) END AS body FROM requests;
+CREATE VIRTUAL TABLE IF NOT EXISTS requests_fts USING fts5(
+    body, content='requests_search_text', content_rowid='doc_id', tokenize='trigram'
+);
+CREATE TRIGGER IF NOT EXISTS requests_fts_insert AFTER INSERT ON requests BEGIN
+    INSERT INTO requests_fts(rowid, body)
+    VALUES (new.rowid, CASE WHEN COALESCE(new.is_binary, 0) = 0 THEN CAST(new.body AS TEXT) END);
+END;
+CREATE TRIGGER IF NOT EXISTS requests_fts_delete AFTER DELETE ON requests BEGIN
+    INSERT INTO requests_fts(requests_fts, rowid, body)
+    VALUES ('delete', old.rowid, CASE WHEN COALESCE(old.is_binary, 0) = 0 THEN CAST(old.body AS TEXT) END);
+END;
+`
+
+// initSearchIndex creates the body search index, indexing the requests of databases created
+// before it existed.
+func (s *sqliteStore) initSearchIndex() error {
+	var existing int
+	if err := s.db.QueryRow("SELECT COUNT(1) FROM sqlite_master WHERE name = 'requests_fts'").Scan(&existing); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(searchIndexSchema); err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+	return s.rebuildSearchIndex(context.Background())
+}
+
+// rebuildSearchIndex re-indexes every body. VACUUM may renumber the rowids the index refers to,
+// so it runs after one.
+func (s *sqliteStore) rebuildSearchIndex(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO requests_fts(requests_fts) VALUES ('rebuild')")
+	return err
+}
+
+// bodySearchClause matches the bodies containing search, ignoring case; search is lower case
+func bodySearchClause(search string) (string, interface{}) {
+	if utf8.RuneCountInString(search) >= minIndexedSearch {
+		// A quoted phrase of the trigram tokenizer is a plain substring match
+		phrase := `"` + strings.ReplaceAll(search, `"`, `""`) + `"`
+		return "requests.rowid IN (SELECT rowid FROM requests_fts WHERE requests_fts MATCH ?)", phrase
+	}
+	return `(COALESCE(is_binary, 0) = 0 AND LOWER(CAST(body AS TEXT)) LIKE ? ESCAPE '\')`, "%" + escapeLike(search) + "%"
+}
+
+// annotateSearchMatches sets BodyMatch on the records whose body contains search
+func annotateSearchMatches(records []*StoredRequest, search string) {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return
+	}
+	expr := regexp.MustCompile("(?i)" + regexp.QuoteMeta(search))
+	for _, record := range records {
+		if record.RequestData != nil && !record.IsBinary {
+			record.BodyMatch = searchMatch(record.Body, expr)
+		}
+	}
+}
+
+// searchMatch cuts a snippet around the first match of expr in body; nil when there is none
+func searchMatch(body []byte, expr *regexp.Regexp) *SearchMatch {
+	matches := expr.FindAllIndex(body, maxSearchMatches)
+	if len(matches) == 0 {
+		return nil
+	}
+	start := max(matches[0][0]-searchSnippetContext, 0)
+	end := min(start+searchSnippetBytes, len(body))
+	// Keep whole characters at both ends
+	for start > 0 && !utf8.RuneStart(body[start]) {
+		start--
+	}
+	for end < len(body) && !utf8.RuneStart(body[end]) {
+		end--
+	}
+
+	match := &SearchMatch{Snippet: string(body[start:end]), Highlights: [][2]int{}, Matches: len(matches)}
+	for _, m := range matches {
+		if m[0] >= end {
+			break
+		}
+		match.Highlights = append(match.Highlights, [2]int{m[0] - start, min(m[1], end) - start})
+	}
+	return match
+}
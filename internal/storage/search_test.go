@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestBodySearch(t *testing.T) {
+	stores := map[string]Store{
+		"sqlite": newTestStore(t, 3),
+		"bolt":   newTestBoltStore(t, config.StorageConfig{MaxRecords: 3}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			order := fakeRequest("", "POST", "/orders")
+			order.Body = []byte(`{"event":"order.paid","data":{"order_id=12345":true,"note":"café Order_ID=12345"}}`)
+			binary := fakeRequest("", "POST", "/upload")
+			binary.Body = []byte("order_id=12345")
+			binary.IsBinary = true
+			other := fakeRequest("", "POST", "/orders")
+			other.Body = []byte("order_id=99")
+			for _, data := range []*request.RequestData{order, binary, other} {
+				if _, err := store.Record(data); err != nil {
+					t.Fatalf("record failed: %v", err)
+				}
+			}
+
+			items, total, err := store.List(ListOptions{Search: "ORDER_ID=12345"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 1 || items[0].Path != "/orders" {
+				t.Fatalf("expected only the text body to match, got %d", total)
+			}
+			match := items[0].BodyMatch
+			if match == nil || match.Matches != 2 || len(match.Highlights) != 2 {
+				t.Fatalf("expected two highlighted matches, got %+v", match)
+			}
+			for _, span := range match.Highlights {
+				if got := match.Snippet[span[0]:span[1]]; !strings.EqualFold(got, "order_id=12345") {
+					t.Fatalf("expected the highlight to cover the match, got %q", got)
+				}
+			}
+
+			// Searches too short for the trigram index scan the bodies
+			if _, total, _ := store.List(ListOptions{Search: "99"}); total != 1 {
+				t.Fatalf("expected a short search to match one body, got %d", total)
+			}
+			// Metadata matches carry no body match
+			items, _, _ = store.List(ListOptions{Search: "/upload"})
+			if len(items) != 1 || items[0].BodyMatch != nil {
+				t.Fatalf("expected a path match without a body match, got %+v", items)
+			}
+
+			// Pruned requests leave the index
+			for i := 0; i < 3; i++ {
+				if _, err := store.Record(fakeRequest("", "GET", "/ping")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, total, err := store.List(ListOptions{Search: "order_id=12345"}); err != nil || total != 0 {
+				t.Fatalf("expected pruned requests to stop matching, got %d (%v)", total, err)
+			}
+		})
+	}
+}
+
+func TestSearchIndexBuiltForExistingDatabase(t *testing.T) {
+	cfg := &config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}
+	store, err := New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fakeRequest("", "POST", "/hook")
+	data.Body = []byte("customer=acme-corp")
+	if _, err := store.Record(data); err != nil {
+		t.Fatal(err)
+	}
+	// Drop the index as if the database came from a release without it
+	if _, err := store.(*sqliteStore).db.Exec("DROP TRIGGER requests_fts_insert; DROP TRIGGER requests_fts_delete; DROP TABLE requests_fts; DROP VIEW requests_search_text"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, total, err := store.List(ListOptions{Search: "ACME-CORP"}); err != nil || total != 1 {
+		t.Fatalf("expected existing bodies to be indexed on open, got %d (%v)", total, err)
+	}
+}
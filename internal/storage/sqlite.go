@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/funnyzak/reqtap/internal/logger"
@@ -23,9 +27,19 @@ const (
 )
 
 type sqliteStore struct {
-	db  *sql.DB
-	cfg *config.StorageConfig
-	log logger.Logger
+	db   *sql.DB
+	cfg  *config.StorageConfig
+	log  logger.Logger
+	path string
+
+	stopMaintenance chan struct{}
+	maintenanceDone chan struct{}
+
+	// writes feeds the batching writer; writeMu guards sends against Close.
+	writes       chan *pendingWrite
+	writerDone   chan struct{}
+	writeMu      sync.RWMutex
+	writesClosed bool
 }
 
 func newSQLiteStore(cfg *config.StorageConfig, log logger.Logger) (Store, error) {
@@ -51,6 +65,8 @@ func newSQLiteStore(cfg *config.StorageConfig, log logger.Logger) (Store, error)
 	db.SetConnMaxLifetime(0)
 
 	pragmas := []string{
+		// auto_vacuum only takes effect on a fresh database; existing files are converted by the first maintenance run.
+		"PRAGMA auto_vacuum=INCREMENTAL;",
 		"PRAGMA journal_mode=WAL;",
 		"PRAGMA synchronous=NORMAL;",
 		"PRAGMA temp_store=MEMORY;",
@@ -63,11 +79,15 @@ func newSQLiteStore(cfg *config.StorageConfig, log logger.Logger) (Store, error)
 		}
 	}
 
-	store := &sqliteStore{db: db, cfg: cfg, log: log}
+	store := &sqliteStore{db: db, cfg: cfg, log: log, path: absPath}
 	if err := store.initSchema(); err != nil {
 		db.Close()
 		return nil, err
 	}
+	store.startWriter(cfg.WriteBatch, cfg.WriteQueue)
+	if cfg.MaintenanceInterval > 0 {
+		store.startMaintenance(cfg.MaintenanceInterval)
+	}
 	return store, nil
 }
 
@@ -89,7 +109,18 @@ CREATE TABLE IF NOT EXISTS requests (
     is_binary INTEGER,
     size INTEGER,
     mock_rule TEXT,
-    mock_status INTEGER
+    mock_status INTEGER,
+    instance TEXT,
+    credential TEXT,
+    content_encoding TEXT,
+    wire_body BLOB,
+    wire_size INTEGER,
+    body_file TEXT,
+    claimed_by TEXT,
+    claimed_at_ns INTEGER,
+    pinned INTEGER,
+    fingerprint TEXT,
+    duplicate_of TEXT
 );
 CREATE INDEX IF NOT EXISTS idx_requests_ts ON requests(timestamp_ns DESC);
 CREATE INDEX IF NOT EXISTS idx_requests_method_ts ON requests(method, timestamp_ns DESC);
@@ -110,9 +141,160 @@ CREATE TABLE IF NOT EXISTS replays (
 );
 CREATE INDEX IF NOT EXISTS idx_replays_ts ON replays(timestamp_ns DESC);
 CREATE INDEX IF NOT EXISTS idx_replays_original ON replays(original_request_id);
+
+CREATE TABLE IF NOT EXISTS forwards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id TEXT NOT NULL,
+    target_url TEXT NOT NULL,
+    timestamp_ns INTEGER NOT NULL,
+    status_code INTEGER,
+    headers_json TEXT,
+    body BLOB,
+    body_truncated INTEGER,
+    latency_ms INTEGER,
+    attempts INTEGER,
+    success INTEGER,
+    error TEXT,
+    violations_json TEXT,
+    latency_budget_ms INTEGER,
+    over_budget INTEGER,
+    FOREIGN KEY (request_id) REFERENCES requests(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_forwards_request ON forwards(request_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id TEXT NOT NULL,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at_ns INTEGER NOT NULL,
+    FOREIGN KEY (request_id) REFERENCES requests(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_comments_request ON comments(request_id);
+
+CREATE TABLE IF NOT EXISTS request_tags (
+    request_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (request_id, tag),
+    FOREIGN KEY (request_id) REFERENCES requests(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_request_tags_tag ON request_tags(tag);
+
+CREATE TABLE IF NOT EXISTS forward_queue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id TEXT NOT NULL,
+    target_url TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_ns INTEGER NOT NULL,
+    created_at_ns INTEGER NOT NULL,
+    UNIQUE (request_id, target_url),
+    FOREIGN KEY (request_id) REFERENCES requests(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_forward_queue_next ON forward_queue(next_attempt_ns);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    name TEXT PRIMARY KEY,
+    hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by TEXT,
+    created_at_ns INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mock_rules (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    updated_by TEXT,
+    created_at_ns INTEGER NOT NULL,
+    updated_at_ns INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp_ns INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    remote_addr TEXT,
+    target TEXT,
+    success INTEGER NOT NULL,
+    details TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_ts ON audit_log(timestamp_ns DESC);
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
 `
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	// Databases created by older releases lack the columns added since
+	if err := s.addMissingColumns("requests", []columnDef{
+		{"instance", "TEXT"},
+		{"claimed_by", "TEXT"},
+		{"claimed_at_ns", "INTEGER"},
+		{"note", "TEXT"},
+		{"grpc_json", "TEXT"},
+		{"credential", "TEXT"},
+		{"content_encoding", "TEXT"},
+		{"wire_body", "BLOB"},
+		{"wire_size", "INTEGER"},
+		{"body_file", "TEXT"},
+		{"pinned", "INTEGER"},
+		{"protobuf_json", "TEXT"},
+		{"fingerprint", "TEXT"},
+		{"duplicate_of", "TEXT"},
+	}); err != nil {
+		return err
+	}
+	if err := s.addMissingColumns("forwards", []columnDef{
+		{"latency_budget_ms", "INTEGER"},
+		{"over_budget", "INTEGER"},
+	}); err != nil {
+		return err
+	}
+	return s.initSearchIndex()
+}
+
+type columnDef struct {
+	name string
+	kind string
+}
+
+func (s *sqliteStore) addMissingColumns(table string, columns []columnDef) error {
+	rows, err := s.db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]struct{})
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			kind      string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = struct{}{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if _, ok := existing[column.name]; ok {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column.name, column.kind)); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, column.name, err)
+		}
+	}
+	return nil
 }
 
 func (s *sqliteStore) Record(data *request.RequestData) (*StoredRequest, error) {
@@ -122,7 +304,6 @@ func (s *sqliteStore) Record(data *request.RequestData) (*StoredRequest, error)
 	if strings.TrimSpace(data.ID) == "" {
 		data.ID = fmt.Sprintf("REQ-%d", time.Now().UnixNano())
 	}
-	ctx := context.Background()
 	ts := data.Timestamp.UTC()
 	if ts.IsZero() {
 		ts = time.Now().UTC()
@@ -141,24 +322,24 @@ func (s *sqliteStore) Record(data *request.RequestData) (*StoredRequest, error)
 	if err != nil {
 		return nil, fmt.Errorf("marshal headers: %w", err)
 	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
+	var grpcJSON sql.NullString
+	if data.GRPC != nil {
+		encoded, err := json.Marshal(data.GRPC)
+		if err != nil {
+			return nil, fmt.Errorf("marshal grpc call: %w", err)
+		}
+		grpcJSON = sql.NullString{String: string(encoded), Valid: true}
 	}
-	defer func() {
+	var protobufJSON sql.NullString
+	if data.Protobuf != nil {
+		encoded, err := json.Marshal(data.Protobuf)
 		if err != nil {
-			_ = tx.Rollback()
+			return nil, fmt.Errorf("marshal protobuf body: %w", err)
 		}
-	}()
-
-	insertSQL := `INSERT INTO requests (
-        id, timestamp_ns, method, proto, path, query, remote_addr, user_agent,
-        headers_json, body, content_type, content_length, is_binary, size,
-        mock_rule, mock_status
-    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		protobufJSON = sql.NullString{String: string(encoded), Valid: true}
+	}
 
-	_, err = tx.ExecContext(ctx, insertSQL,
+	err = s.enqueue([]interface{}{
 		data.ID,
 		ts.UnixNano(),
 		data.Method,
@@ -175,33 +356,49 @@ func (s *sqliteStore) Record(data *request.RequestData) (*StoredRequest, error)
 		data.Size,
 		data.MockResponse.Rule,
 		data.MockResponse.Status,
-	)
+		data.Instance,
+		grpcJSON,
+		data.Credential,
+		data.ContentEncoding,
+		data.WireBody,
+		data.WireSize,
+		data.BodyFile,
+		protobufJSON,
+		data.Fingerprint,
+		data.DuplicateOf,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("insert request: %w", err)
-	}
-
-	if err = s.prune(ctx, tx); err != nil {
-		return nil, err
-	}
-
-	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return &StoredRequest{ID: data.ID, RequestData: data}, nil
 }
 
-func (s *sqliteStore) prune(ctx context.Context, tx *sql.Tx) error {
+// prune applies retention and max_records inside tx and reports how many requests were deleted,
+// along with the spilled body files of those requests; callers remove them once tx is committed.
+// Pinned requests are never pruned and do not count toward max_records.
+func (s *sqliteStore) prune(ctx context.Context, tx *sql.Tx) (int64, []string, error) {
+	var pruned int64
+	var bodyFiles []string
 	if s.cfg.Retention > 0 {
 		cutoff := time.Now().Add(-s.cfg.Retention).UTC().UnixNano()
-		if _, err := tx.ExecContext(ctx, "DELETE FROM requests WHERE timestamp_ns < ?", cutoff); err != nil {
-			return fmt.Errorf("prune by retention: %w", err)
+		files, err := selectBodyFiles(ctx, tx, "timestamp_ns < ? AND "+unpinnedClause, cutoff)
+		if err != nil {
+			return 0, nil, err
+		}
+		bodyFiles = append(bodyFiles, files...)
+		res, err := tx.ExecContext(ctx, "DELETE FROM requests WHERE timestamp_ns < ? AND "+unpinnedClause, cutoff)
+		if err != nil {
+			return 0, nil, fmt.Errorf("prune by retention: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			pruned += n
 		}
 	}
 	if s.cfg.MaxRecords > 0 {
 		var count int
-		if err := tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM requests").Scan(&count); err != nil {
-			return fmt.Errorf("count records: %w", err)
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM requests WHERE "+unpinnedClause).Scan(&count); err != nil {
+			return 0, nil, fmt.Errorf("count records: %w", err)
 		}
 		if count > s.cfg.MaxRecords {
 			excess := count - s.cfg.MaxRecords
@@ -209,15 +406,73 @@ func (s *sqliteStore) prune(ctx context.Context, tx *sql.Tx) error {
 				excess = 0
 			}
 			if excess > 0 {
-				if _, err := tx.ExecContext(ctx, "DELETE FROM requests WHERE id IN (SELECT id FROM requests ORDER BY timestamp_ns ASC LIMIT ?)", excess); err != nil {
-					return fmt.Errorf("prune max records: %w", err)
+				files, err := selectBodyFiles(ctx, tx, "id IN (SELECT id FROM requests WHERE "+unpinnedClause+" ORDER BY timestamp_ns ASC LIMIT ?)", excess)
+				if err != nil {
+					return 0, nil, err
+				}
+				bodyFiles = append(bodyFiles, files...)
+				res, err := tx.ExecContext(ctx, "DELETE FROM requests WHERE id IN (SELECT id FROM requests WHERE "+unpinnedClause+" ORDER BY timestamp_ns ASC LIMIT ?)", excess)
+				if err != nil {
+					return 0, nil, fmt.Errorf("prune max records: %w", err)
+				}
+				if n, err := res.RowsAffected(); err == nil {
+					pruned += n
 				}
 			}
 		}
 	}
-	return nil
+	if pruned > 0 {
+		// Foreign keys are not guaranteed to be enforced, so drop orphaned forward responses explicitly.
+		if _, err := tx.ExecContext(ctx, "DELETE FROM forwards WHERE request_id NOT IN (SELECT id FROM requests)"); err != nil {
+			return 0, nil, fmt.Errorf("prune forwards: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE request_id NOT IN (SELECT id FROM requests)"); err != nil {
+			return 0, nil, fmt.Errorf("prune comments: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM request_tags WHERE request_id NOT IN (SELECT id FROM requests)"); err != nil {
+			return 0, nil, fmt.Errorf("prune tags: %w", err)
+		}
+	}
+	return pruned, bodyFiles, nil
 }
 
+// unpinnedClause matches the requests pruning may delete.
+const unpinnedClause = "COALESCE(pinned, 0) = 0"
+
+// selectBodyFiles lists the spilled body files of the requests matching where.
+func selectBodyFiles(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT body_file FROM requests WHERE body_file IS NOT NULL AND body_file != '' AND "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list body files: %w", err)
+	}
+	defer rows.Close()
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// removeBodyFiles deletes spilled bodies; files already gone are ignored.
+func removeBodyFiles(files []string) {
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
+}
+
+// forwardViolated matches requests with a forward delivery that broke its response contract
+const forwardViolated = `EXISTS (SELECT 1 FROM forwards WHERE forwards.request_id = requests.id AND forwards.violations_json NOT IN ('', 'null', '[]'))`
+
+// requestColumns is the column list scanStoredRequest expects; tags are folded into one comma-separated value.
+const requestColumns = `id, timestamp_ns, method, proto, path, query, remote_addr, user_agent, headers_json, body,
+	content_type, content_length, is_binary, size, mock_rule, mock_status, instance, claimed_by, claimed_at_ns, note, grpc_json,
+	credential, content_encoding, wire_body, wire_size, body_file, pinned, protobuf_json, fingerprint, duplicate_of, ` + forwardViolated + `,
+	(SELECT GROUP_CONCAT(tag) FROM request_tags WHERE request_tags.request_id = requests.id)`
+
 func (s *sqliteStore) List(opts ListOptions) ([]*StoredRequest, int, error) {
 	ctx := context.Background()
 	where, args := buildFilters(opts)
@@ -229,7 +484,7 @@ func (s *sqliteStore) List(opts ListOptions) ([]*StoredRequest, int, error) {
 	}
 
 	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString("SELECT id, timestamp_ns, method, proto, path, query, remote_addr, user_agent, headers_json, body, content_type, content_length, is_binary, size, mock_rule, mock_status FROM requests ")
+	queryBuilder.WriteString("SELECT " + requestColumns + " FROM requests ")
 	queryBuilder.WriteString(where)
 	queryBuilder.WriteString(" ORDER BY timestamp_ns DESC")
 
@@ -263,6 +518,7 @@ func (s *sqliteStore) List(opts ListOptions) ([]*StoredRequest, int, error) {
 		return nil, 0, err
 	}
 
+	annotateSearchMatches(result, opts.Search)
 	return result, total, nil
 }
 
@@ -271,7 +527,7 @@ func (s *sqliteStore) Iterate(opts ListOptions, fn func(*StoredRequest) bool) er
 	where, args := buildFilters(opts)
 
 	query := strings.Builder{}
-	query.WriteString("SELECT id, timestamp_ns, method, proto, path, query, remote_addr, user_agent, headers_json, body, content_type, content_length, is_binary, size, mock_rule, mock_status FROM requests ")
+	query.WriteString("SELECT " + requestColumns + " FROM requests ")
 	query.WriteString(where)
 	query.WriteString(" ORDER BY timestamp_ns DESC")
 
@@ -304,7 +560,7 @@ func (s *sqliteStore) Snapshot() ([]*StoredRequest, error) {
 
 func (s *sqliteStore) Get(id string) (*StoredRequest, error) {
 	ctx := context.Background()
-	row := s.db.QueryRowContext(ctx, "SELECT id, timestamp_ns, method, proto, path, query, remote_addr, user_agent, headers_json, body, content_type, content_length, is_binary, size, mock_rule, mock_status FROM requests WHERE id = ?", id)
+	row := s.db.QueryRowContext(ctx, "SELECT "+requestColumns+" FROM requests WHERE id = ?", id)
 	record, err := scanStoredRequest(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -315,10 +571,165 @@ func (s *sqliteStore) Get(id string) (*StoredRequest, error) {
 	return record, nil
 }
 
+func (s *sqliteStore) Claim(requestID, user string, force bool) (*Claim, error) {
+	ctx := context.Background()
+	claim := &Claim{User: user, ClaimedAt: time.Now().UTC()}
+	res, err := s.db.ExecContext(ctx, `UPDATE requests SET claimed_by = ?, claimed_at_ns = ?
+		WHERE id = ? AND (? OR COALESCE(claimed_by, '') = '' OR claimed_by = ?)`,
+		claim.User, claim.ClaimedAt.UnixNano(), requestID, boolToInt(force), user)
+	if err != nil {
+		return nil, fmt.Errorf("claim request: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return claim, nil
+	}
+	current, err := s.Get(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	return current.Claim, ErrClaimed
+}
+
+func (s *sqliteStore) ReleaseClaim(requestID, user string, force bool) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, `UPDATE requests SET claimed_by = NULL, claimed_at_ns = NULL
+		WHERE id = ? AND (? OR COALESCE(claimed_by, '') = '' OR claimed_by = ?)`,
+		requestID, boolToInt(force), user)
+	if err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+	current, err := s.Get(requestID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrNotFound
+	}
+	return ErrClaimed
+}
+
+// Annotate replaces the tags (when tags is non-nil) and the note (when note is non-nil) of a request
+func (s *sqliteStore) Annotate(requestID string, tags []string, note *string) (err error) {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists int
+	if err = tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM requests WHERE id = ?", requestID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	if note != nil {
+		if _, err = tx.ExecContext(ctx, "UPDATE requests SET note = ? WHERE id = ?", *note, requestID); err != nil {
+			return fmt.Errorf("update note: %w", err)
+		}
+	}
+	if tags != nil {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM request_tags WHERE request_id = ?", requestID); err != nil {
+			return fmt.Errorf("clear tags: %w", err)
+		}
+		for _, tag := range tags {
+			if _, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO request_tags (request_id, tag) VALUES (?, ?)", requestID, tag); err != nil {
+				return fmt.Errorf("insert tag: %w", err)
+			}
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+// Pin sets or clears the pinned flag of a request
+func (s *sqliteStore) Pin(requestID string, pinned bool) error {
+	res, err := s.db.ExecContext(context.Background(), "UPDATE requests SET pinned = ? WHERE id = ?", boolToInt(pinned), requestID)
+	if err != nil {
+		return fmt.Errorf("pin request: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// deleteChunk keeps the IN lists of Delete below SQLite's bound parameter limit
+const deleteChunk = 500
+
+// Delete removes requests and everything stored for them
+func (s *sqliteStore) Delete(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var deleted int64
+	var bodyFiles []string
+	for start := 0; start < len(ids); start += deleteChunk {
+		chunk := ids[start:min(start+deleteChunk, len(ids))]
+		in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",") + ")"
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		files, err := selectBodyFiles(ctx, tx, "id IN "+in, args...)
+		if err != nil {
+			return 0, err
+		}
+		bodyFiles = append(bodyFiles, files...)
+		res, err := tx.ExecContext(ctx, "DELETE FROM requests WHERE id IN "+in, args...)
+		if err != nil {
+			return 0, fmt.Errorf("delete requests: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			deleted += n
+		}
+		// Foreign keys are not guaranteed to be enforced, so the dependent rows go explicitly
+		for _, stmt := range []string{
+			"DELETE FROM replays WHERE original_request_id IN " + in,
+			"DELETE FROM forwards WHERE request_id IN " + in,
+			"DELETE FROM comments WHERE request_id IN " + in,
+			"DELETE FROM request_tags WHERE request_id IN " + in,
+			"DELETE FROM forward_queue WHERE request_id IN " + in,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return 0, fmt.Errorf("delete requests: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	removeBodyFiles(bodyFiles)
+	return deleted, nil
+}
+
 func (s *sqliteStore) Close() error {
 	if s.db == nil {
 		return nil
 	}
+	s.stopWriter()
+	if s.stopMaintenance != nil {
+		close(s.stopMaintenance)
+		<-s.maintenanceDone
+	}
 	return s.db.Close()
 }
 
@@ -342,6 +753,22 @@ func scanStoredRequest(scanner interface {
 		size        sql.NullInt64
 		mockRule    sql.NullString
 		mockStatus  sql.NullInt64
+		instance    sql.NullString
+		claimedBy   sql.NullString
+		claimedAt   sql.NullInt64
+		note        sql.NullString
+		grpcJSON    sql.NullString
+		credential  sql.NullString
+		encoding    sql.NullString
+		wireBody    []byte
+		wireSize    sql.NullInt64
+		bodyFile    sql.NullString
+		pinned      sql.NullInt64
+		protobuf    sql.NullString
+		fingerprint sql.NullString
+		duplicateOf sql.NullString
+		violated    bool
+		tags        sql.NullString
 	)
 
 	if err := scanner.Scan(
@@ -361,6 +788,22 @@ func scanStoredRequest(scanner interface {
 		&size,
 		&mockRule,
 		&mockStatus,
+		&instance,
+		&claimedBy,
+		&claimedAt,
+		&note,
+		&grpcJSON,
+		&credential,
+		&encoding,
+		&wireBody,
+		&wireSize,
+		&bodyFile,
+		&pinned,
+		&protobuf,
+		&fingerprint,
+		&duplicateOf,
+		&violated,
+		&tags,
 	); err != nil {
 		return nil, err
 	}
@@ -391,11 +834,41 @@ func scanStoredRequest(scanner interface {
 			Rule:   mockRule.String,
 			Status: int(mockStatus.Int64),
 		},
+		Instance:        instance.String,
+		Credential:      credential.String,
+		ContentEncoding: encoding.String,
+		WireSize:        wireSize.Int64,
+		BodyFile:        bodyFile.String,
+		Fingerprint:     fingerprint.String,
+		DuplicateOf:     duplicateOf.String,
+	}
+	if wireBody != nil {
+		data.WireBody = append([]byte(nil), wireBody...)
 	}
 	if data.Size == 0 {
 		data.Size = int64(len(body))
 	}
-	return &StoredRequest{ID: id, RequestData: data}, nil
+	if grpcJSON.Valid && grpcJSON.String != "" {
+		var call request.GRPCCall
+		if err := json.Unmarshal([]byte(grpcJSON.String), &call); err == nil {
+			data.GRPC = &call
+		}
+	}
+	if protobuf.Valid && protobuf.String != "" {
+		var decoded request.ProtobufBody
+		if err := json.Unmarshal([]byte(protobuf.String), &decoded); err == nil {
+			data.Protobuf = &decoded
+		}
+	}
+	stored := &StoredRequest{ID: id, RequestData: data, Note: note.String, Pinned: pinned.Int64 == 1, ForwardViolations: violated}
+	if tags.String != "" {
+		stored.Tags = strings.Split(tags.String, ",")
+		sort.Strings(stored.Tags)
+	}
+	if claimedBy.String != "" {
+		stored.Claim = &Claim{User: claimedBy.String, ClaimedAt: time.Unix(0, claimedAt.Int64).UTC()}
+	}
+	return stored, nil
 }
 
 func buildFilters(opts ListOptions) (string, []interface{}) {
@@ -409,8 +882,68 @@ func buildFilters(opts ListOptions) (string, []interface{}) {
 
 	if search := strings.TrimSpace(strings.ToLower(opts.Search)); search != "" {
 		like := fmt.Sprintf("%%%s%%", search)
-		clauses = append(clauses, "(LOWER(path) LIKE ? OR LOWER(query) LIKE ? OR LOWER(remote_addr) LIKE ? OR LOWER(user_agent) LIKE ? OR LOWER(headers_json) LIKE ?)")
-		args = append(args, like, like, like, like, like)
+		bodyClause, bodyArg := bodySearchClause(search)
+		clauses = append(clauses, "(LOWER(path) LIKE ? OR LOWER(query) LIKE ? OR LOWER(remote_addr) LIKE ? OR LOWER(user_agent) LIKE ? OR LOWER(headers_json) LIKE ? OR LOWER(instance) LIKE ? OR LOWER(note) LIKE ? OR "+bodyClause+")")
+		args = append(args, like, like, like, like, like, like, like, bodyArg)
+	}
+
+	switch claim := strings.TrimSpace(opts.Claim); claim {
+	case "":
+	case ClaimNone:
+		clauses = append(clauses, "COALESCE(claimed_by, '') = ''")
+	case ClaimAny:
+		clauses = append(clauses, "COALESCE(claimed_by, '') <> ''")
+	default:
+		clauses = append(clauses, "LOWER(claimed_by) = LOWER(?)")
+		args = append(args, claim)
+	}
+
+	for _, tag := range opts.Tags {
+		clauses = append(clauses, "EXISTS (SELECT 1 FROM request_tags WHERE request_tags.request_id = requests.id AND request_tags.tag = ?)")
+		args = append(args, tag)
+	}
+
+	if opts.Pinned {
+		clauses = append(clauses, "pinned = 1")
+	}
+
+	if opts.ForwardViolations {
+		clauses = append(clauses, forwardViolated)
+	}
+
+	if opts.Duplicates {
+		clauses = append(clauses, "COALESCE(duplicate_of, '') <> ''")
+	}
+
+	if contentType := strings.TrimSpace(strings.ToLower(opts.ContentType)); contentType != "" {
+		clauses = append(clauses, `LOWER(content_type) LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(contentType)+"%")
+	}
+	if opts.PathPrefix != "" {
+		// LIKE ignores case, paths do not
+		clauses = append(clauses, "substr(path, 1, ?) = ?")
+		args = append(args, utf8.RuneCountInString(opts.PathPrefix), opts.PathPrefix)
+	}
+	if opts.MinSize > 0 {
+		clauses = append(clauses, "size >= ?")
+		args = append(args, opts.MinSize)
+	}
+	if opts.MaxSize > 0 {
+		clauses = append(clauses, "size <= ?")
+		args = append(args, opts.MaxSize)
+	}
+	if opts.IsBinary != nil {
+		clauses = append(clauses, "COALESCE(is_binary, 0) = ?")
+		args = append(args, boolToInt(*opts.IsBinary))
+	}
+
+	if !opts.Since.IsZero() {
+		clauses = append(clauses, "timestamp_ns >= ?")
+		args = append(args, opts.Since.UnixNano())
+	}
+	if !opts.Until.IsZero() {
+		clauses = append(clauses, "timestamp_ns < ?")
+		args = append(args, opts.Until.UnixNano())
 	}
 
 	if len(clauses) == 0 {
@@ -419,6 +952,11 @@ func buildFilters(opts ListOptions) (string, []interface{}) {
 	return "WHERE " + strings.Join(clauses, " AND "), args
 }
 
+// escapeLike escapes the LIKE wildcards of value for use with ESCAPE '\'
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -553,3 +1091,315 @@ func scanStoredReplay(scanner interface {
 
 	return &StoredReplay{ReplayData: data}, nil
 }
+
+// RecordForwards stores the target responses of a forwarded request
+func (s *sqliteStore) RecordForwards(requestID string, records []*ForwardRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	insertSQL := `INSERT INTO forwards (
+		request_id, target_url, timestamp_ns, status_code, headers_json, body,
+		body_truncated, latency_ms, attempts, success, error, violations_json,
+		latency_budget_ms, over_budget
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		record.RequestID = requestID
+		ts := record.Timestamp.UTC()
+		if ts.IsZero() {
+			ts = time.Now().UTC()
+		}
+		record.Timestamp = ts
+
+		headers := record.Headers
+		if headers == nil {
+			headers = http.Header{}
+		}
+		var headersJSON, violationsJSON []byte
+		if headersJSON, err = json.Marshal(headers); err != nil {
+			return fmt.Errorf("marshal headers: %w", err)
+		}
+		if violationsJSON, err = json.Marshal(record.Violations); err != nil {
+			return fmt.Errorf("marshal violations: %w", err)
+		}
+
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, insertSQL,
+			requestID,
+			record.TargetURL,
+			ts.UnixNano(),
+			record.StatusCode,
+			string(headersJSON),
+			record.Body,
+			boolToInt(record.BodyTruncated),
+			record.LatencyMs,
+			record.Attempts,
+			boolToInt(record.Success),
+			record.Error,
+			string(violationsJSON),
+			record.LatencyBudgetMs,
+			boolToInt(record.OverBudget),
+		)
+		if err != nil {
+			return fmt.Errorf("insert forward: %w", err)
+		}
+		if id, idErr := res.LastInsertId(); idErr == nil {
+			record.ID = id
+		}
+	}
+
+	err = tx.Commit()
+	return err
+}
+
+// GetForwards retrieves the target responses recorded for a request
+func (s *sqliteStore) GetForwards(requestID string) ([]*ForwardRecord, error) {
+	ctx := context.Background()
+	query := `SELECT id, request_id, target_url, timestamp_ns, status_code, headers_json, body,
+		body_truncated, latency_ms, attempts, success, error, violations_json,
+		latency_budget_ms, over_budget
+		FROM forwards WHERE request_id = ? ORDER BY timestamp_ns ASC, id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*ForwardRecord
+	for rows.Next() {
+		record, err := scanForwardRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+
+	return result, rows.Err()
+}
+
+func scanForwardRecord(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*ForwardRecord, error) {
+	var (
+		id             int64
+		requestID      string
+		targetURL      string
+		ts             int64
+		statusCode     sql.NullInt64
+		headersJSON    sql.NullString
+		body           []byte
+		truncated      sql.NullInt64
+		latencyMs      sql.NullInt64
+		attempts       sql.NullInt64
+		success        sql.NullInt64
+		errorMsg       sql.NullString
+		violationsJSON sql.NullString
+		budgetMs       sql.NullInt64
+		overBudget     sql.NullInt64
+	)
+
+	if err := scanner.Scan(
+		&id,
+		&requestID,
+		&targetURL,
+		&ts,
+		&statusCode,
+		&headersJSON,
+		&body,
+		&truncated,
+		&latencyMs,
+		&attempts,
+		&success,
+		&errorMsg,
+		&violationsJSON,
+		&budgetMs,
+		&overBudget,
+	); err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	if headersJSON.Valid && headersJSON.String != "" {
+		if err := json.Unmarshal([]byte(headersJSON.String), &headers); err != nil {
+			headers = http.Header{}
+		}
+	}
+	var violations []string
+	if violationsJSON.Valid && violationsJSON.String != "" {
+		_ = json.Unmarshal([]byte(violationsJSON.String), &violations)
+	}
+
+	return &ForwardRecord{
+		ID:              id,
+		RequestID:       requestID,
+		TargetURL:       targetURL,
+		Timestamp:       time.Unix(0, ts).UTC(),
+		StatusCode:      int(statusCode.Int64),
+		Headers:         headers,
+		Body:            append([]byte(nil), body...),
+		BodyTruncated:   truncated.Int64 == 1,
+		LatencyMs:       latencyMs.Int64,
+		Attempts:        int(attempts.Int64),
+		Success:         success.Int64 == 1,
+		Error:           errorMsg.String,
+		Violations:      violations,
+		LatencyBudgetMs: budgetMs.Int64,
+		OverBudget:      overBudget.Int64 == 1,
+	}, nil
+}
+
+// AddComment stores a comment on an existing request
+func (s *sqliteStore) AddComment(comment *Comment) error {
+	ctx := context.Background()
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+	comment.CreatedAt = comment.CreatedAt.UTC()
+	res, err := s.db.ExecContext(ctx, `INSERT INTO comments (request_id, author, body, created_at_ns)
+		SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM requests WHERE id = ?)`,
+		comment.RequestID, comment.Author, comment.Body, comment.CreatedAt.UnixNano(), comment.RequestID)
+	if err != nil {
+		return fmt.Errorf("insert comment: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	if id, idErr := res.LastInsertId(); idErr == nil {
+		comment.ID = id
+	}
+	return nil
+}
+
+// GetComments retrieves the comments left on a request
+func (s *sqliteStore) GetComments(requestID string) ([]*Comment, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, request_id, author, body, created_at_ns
+		FROM comments WHERE request_id = ? ORDER BY created_at_ns ASC, id ASC`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*Comment
+	for rows.Next() {
+		var (
+			comment   Comment
+			createdAt int64
+		)
+		if err := rows.Scan(&comment.ID, &comment.RequestID, &comment.Author, &comment.Body, &createdAt); err != nil {
+			return nil, err
+		}
+		comment.CreatedAt = time.Unix(0, createdAt).UTC()
+		result = append(result, &comment)
+	}
+	return result, rows.Err()
+}
+
+// EnqueueForward adds a failed delivery to the forward queue
+func (s *sqliteStore) EnqueueForward(item *QueuedForward) error {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = now
+	}
+	if item.NextAttempt.IsZero() {
+		item.NextAttempt = now
+	}
+	item.CreatedAt = item.CreatedAt.UTC()
+	item.NextAttempt = item.NextAttempt.UTC()
+	res, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO forward_queue (request_id, target_url, attempts, last_error, next_attempt_ns, created_at_ns)
+		SELECT ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM requests WHERE id = ?)`,
+		item.RequestID, item.TargetURL, item.Attempts, item.LastError,
+		item.NextAttempt.UnixNano(), item.CreatedAt.UnixNano(), item.RequestID)
+	if err != nil {
+		return fmt.Errorf("enqueue forward: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		if id, idErr := res.LastInsertId(); idErr == nil {
+			item.ID = id
+		}
+		return nil
+	}
+	// Either the request is unknown or the delivery is already queued
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM forward_queue WHERE request_id = ? AND target_url = ?`,
+		item.RequestID, item.TargetURL).Scan(&item.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// QueuedForwards lists queued deliveries, soonest first
+func (s *sqliteStore) QueuedForwards(due time.Time, limit int) ([]*QueuedForward, error) {
+	query := `SELECT id, request_id, target_url, attempts, last_error, next_attempt_ns, created_at_ns FROM forward_queue`
+	var args []interface{}
+	if !due.IsZero() {
+		query += ` WHERE next_attempt_ns <= ?`
+		args = append(args, due.UnixNano())
+	}
+	query += ` ORDER BY next_attempt_ns ASC, id ASC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*QueuedForward
+	for rows.Next() {
+		var (
+			item        QueuedForward
+			lastError   sql.NullString
+			nextAttempt int64
+			createdAt   int64
+		)
+		if err := rows.Scan(&item.ID, &item.RequestID, &item.TargetURL, &item.Attempts, &lastError, &nextAttempt, &createdAt); err != nil {
+			return nil, err
+		}
+		item.LastError = lastError.String
+		item.NextAttempt = time.Unix(0, nextAttempt).UTC()
+		item.CreatedAt = time.Unix(0, createdAt).UTC()
+		result = append(result, &item)
+	}
+	return result, rows.Err()
+}
+
+// RescheduleForward stores the outcome of a failed queue retry
+func (s *sqliteStore) RescheduleForward(item *QueuedForward) error {
+	res, err := s.db.ExecContext(context.Background(),
+		`UPDATE forward_queue SET attempts = ?, last_error = ?, next_attempt_ns = ? WHERE id = ?`,
+		item.Attempts, item.LastError, item.NextAttempt.UTC().UnixNano(), item.ID)
+	if err != nil {
+		return fmt.Errorf("reschedule forward: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DequeueForward removes a delivery from the forward queue
+func (s *sqliteStore) DequeueForward(id int64) error {
+	if _, err := s.db.ExecContext(context.Background(), `DELETE FROM forward_queue WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("dequeue forward: %w", err)
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -98,6 +104,55 @@ func TestSQLiteStore_ListFilters(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_Duplicates(t *testing.T) {
+	store := newTestStore(t, 100)
+	first := fakeRequest("dup-1", "POST", "/hook")
+	first.Fingerprint = "body:abc"
+	copied := fakeRequest("dup-2", "POST", "/hook")
+	copied.Fingerprint = "body:abc"
+	copied.DuplicateOf = "dup-1"
+	for _, req := range []*request.RequestData{first, copied} {
+		if _, err := store.Record(req); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	got, err := store.Get("dup-2")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Fingerprint != "body:abc" || got.DuplicateOf != "dup-1" {
+		t.Fatalf("expected the dedup fields to round-trip, got %q %q", got.Fingerprint, got.DuplicateOf)
+	}
+	items, total, err := store.List(ListOptions{Duplicates: true})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if total != 1 || items[0].ID != "dup-2" {
+		t.Fatalf("expected only the duplicate, got %d", total)
+	}
+}
+
+func TestSQLiteStore_ListTimeRange(t *testing.T) {
+	store := newTestStore(t, 100)
+	base := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		req := fakeRequest(fmt.Sprintf("rec-%d", i), "GET", "/p")
+		req.Timestamp = base.Add(time.Duration(i) * time.Hour)
+		if _, err := store.Record(req); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	items, total, err := store.List(ListOptions{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if total != 2 || len(items) != 2 || items[0].ID != "rec-2" || items[1].ID != "rec-1" {
+		t.Fatalf("expected rec-2 and rec-1 in range, got total=%d items=%v", total, items)
+	}
+}
+
 func TestSQLiteStore_IterateStops(t *testing.T) {
 	store := newTestStore(t, 100)
 	for i := 0; i < 5; i++ {
@@ -133,3 +188,633 @@ func TestSQLiteStore_PruneMaxRecords(t *testing.T) {
 		t.Fatalf("expected only 2 records retained, got total=%d len=%d", total, len(items))
 	}
 }
+
+func TestSQLiteStore_ListContentFilters(t *testing.T) {
+	store := newTestStore(t, 100)
+	base := time.Now().Add(-time.Hour)
+	for i, spec := range []struct {
+		path, contentType string
+		size              int64
+		binary            bool
+	}{
+		{"/hooks/github", "application/json; charset=utf-8", 120, false},
+		{"/hooks/stripe", "application/json", 4096, false},
+		{"/Hooks/upload", "image/png", 90000, true},
+		{"/other_path", "text/plain", 10, false},
+	} {
+		data := fakeRequest(fmt.Sprintf("req-%d", i), "POST", spec.path)
+		data.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		data.ContentType, data.Size, data.IsBinary = spec.contentType, spec.size, spec.binary
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	binary, text := true, false
+	cases := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"content type prefix", ListOptions{ContentType: "Application/JSON"}, []string{"req-1", "req-0"}},
+		{"media class", ListOptions{ContentType: "image/"}, []string{"req-2"}},
+		{"path prefix is case-sensitive", ListOptions{PathPrefix: "/hooks/"}, []string{"req-1", "req-0"}},
+		{"path prefix wildcards are literal", ListOptions{PathPrefix: "/other_"}, []string{"req-3"}},
+		{"size range", ListOptions{MinSize: 100, MaxSize: 4096}, []string{"req-1", "req-0"}},
+		{"binary", ListOptions{IsBinary: &binary}, []string{"req-2"}},
+		{"text in time range", ListOptions{IsBinary: &text, Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)}, []string{"req-1"}},
+	}
+	for _, tc := range cases {
+		items, total, err := store.List(tc.opts)
+		if err != nil {
+			t.Fatalf("%s: list failed: %v", tc.name, err)
+		}
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		if total != len(tc.want) || fmt.Sprint(ids) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: expected %v, got %v (total %d)", tc.name, tc.want, ids, total)
+		}
+	}
+}
+
+func TestSQLiteStore_PinnedSurvivesPruning(t *testing.T) {
+	store := newTestStore(t, 2)
+	old := fakeRequest("pinned", "POST", "/repro")
+	old.Timestamp = time.Now().Add(-2 * time.Hour)
+	if _, err := store.Record(old); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if err := store.Pin("pinned", true); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if err := store.Pin("missing", true); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := store.Record(fakeRequest(fmt.Sprintf("noise-%d", i), "GET", "/noise")); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	// Pinned requests do not count toward max_records
+	if _, total, err := store.List(ListOptions{}); err != nil || total != 3 {
+		t.Fatalf("expected the pinned request and 2 others, got total=%d (%v)", total, err)
+	}
+
+	sqlite := store.(*sqliteStore)
+	sqlite.cfg.Retention = time.Hour
+	if _, err := sqlite.maintain(context.Background()); err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	pinned, total, err := store.List(ListOptions{Pinned: true})
+	if err != nil || total != 1 || !pinned[0].Pinned || pinned[0].ID != "pinned" {
+		t.Fatalf("expected the expired pinned request to be kept, got %+v (%v)", pinned, err)
+	}
+
+	if err := store.Pin("pinned", false); err != nil {
+		t.Fatalf("unpin failed: %v", err)
+	}
+	if _, err := sqlite.maintain(context.Background()); err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if stored, err := store.Get("pinned"); err != nil || stored != nil {
+		t.Fatalf("expected the unpinned request to be pruned, got %+v (%v)", stored, err)
+	}
+}
+
+func TestSQLiteStore_BatchedWrites(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(dir, "reqtap.db"), WriteBatch: 16, WriteQueue: 4}, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("rec-%d", i)
+			if i%50 == 49 {
+				// A duplicate ID fails its own insert without failing the rest of its batch
+				id = "rec-0"
+			}
+			_, err := store.Record(fakeRequest(id, "POST", "/hook"))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	// rec-0 itself may lose the race to one of its duplicates, so exactly four of the five fail
+	if failed != 4 {
+		t.Fatalf("expected 4 duplicate inserts to fail, got %d", failed)
+	}
+	if _, total, err := store.List(ListOptions{}); err != nil || total != 196 {
+		t.Fatalf("expected every unique request to be stored, got %d (%v)", total, err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := store.Record(fakeRequest("late", "GET", "/")); err == nil {
+		t.Fatal("expected Record after Close to fail")
+	}
+}
+
+func TestSQLiteStore_PruneRemovesSpilledBodies(t *testing.T) {
+	store := newTestStore(t, 1)
+	bodyFile := filepath.Join(t.TempDir(), "body.bin")
+	if err := os.WriteFile(bodyFile, []byte("full body"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spilled := fakeRequest("spilled", "POST", "/upload")
+	spilled.BodyFile = bodyFile
+	if _, err := store.Record(spilled); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	stored, err := store.Get("spilled")
+	if err != nil || stored.BodyFile != bodyFile {
+		t.Fatalf("expected body file to round-trip, got %+v (%v)", stored, err)
+	}
+
+	if _, err := store.Record(fakeRequest("next", "GET", "/")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if _, err := os.Stat(bodyFile); !os.IsNotExist(err) {
+		t.Fatalf("expected the spilled body to be removed with its request, got %v", err)
+	}
+}
+
+func TestSQLiteStore_MaintenancePrunesAndVacuums(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reqtap.db")
+	// A database created before auto_vacuum was enabled must be converted by the first pass.
+	legacy, err := sql.Open(sqliteDriverName, "file:"+filepath.ToSlash(path))
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	if _, err := legacy.Exec("CREATE TABLE legacy (id INTEGER)"); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	legacy.Close()
+
+	cfg := &config.StorageConfig{Driver: "sqlite", Path: path}
+	store, err := New(cfg, noopLogger{})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	old := fakeRequest("old", "POST", "/old")
+	old.Timestamp = time.Now().Add(-2 * time.Hour)
+	old.Body = []byte(strings.Repeat("x", 1<<20))
+	if _, err := store.Record(old); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if _, err := store.Record(fakeRequest("new", "GET", "/new")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	cfg.Retention = time.Hour
+	sqlite := store.(*sqliteStore)
+	report, err := sqlite.maintain(context.Background())
+	if err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if report.Pruned != 1 || !report.Converted {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Reclaimed() <= 0 {
+		t.Fatalf("expected space to be reclaimed, before=%d after=%d", report.Before, report.After)
+	}
+	if stored, err := store.Get("old"); err != nil || stored != nil {
+		t.Fatalf("expected expired record to be pruned, got %+v (%v)", stored, err)
+	}
+
+	report, err = sqlite.maintain(context.Background())
+	if err != nil {
+		t.Fatalf("second maintain failed: %v", err)
+	}
+	if report.Converted {
+		t.Fatal("expected the database to stay in incremental vacuum mode")
+	}
+}
+
+func TestSQLiteStore_Forwards(t *testing.T) {
+	store := newTestStore(t, 2)
+	if _, err := store.Record(fakeRequest("rec-0", "POST", "/hook")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	err := store.RecordForwards("rec-0", []*ForwardRecord{
+		{
+			TargetURL:  "http://a.example",
+			StatusCode: http.StatusAccepted,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"ok":true}`),
+			LatencyMs:  12,
+			Attempts:   1,
+			Success:    true,
+			// Budget breaches are flagged without failing the delivery
+			LatencyBudgetMs: 10,
+			OverBudget:      true,
+		},
+		{
+			TargetURL:  "http://b.example",
+			StatusCode: http.StatusBadGateway,
+			Attempts:   3,
+			Error:      "target returned status 502",
+			Violations: []string{"status 502 not accepted"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("record forwards failed: %v", err)
+	}
+
+	forwards, err := store.GetForwards("rec-0")
+	if err != nil {
+		t.Fatalf("get forwards failed: %v", err)
+	}
+	if len(forwards) != 2 {
+		t.Fatalf("expected 2 forwards, got %d", len(forwards))
+	}
+	first := forwards[0]
+	if first.TargetURL != "http://a.example" || !first.Success || first.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected first forward: %#v", first)
+	}
+	if first.Headers.Get("Content-Type") != "application/json" || string(first.Body) != `{"ok":true}` {
+		t.Fatalf("response headers/body not persisted: %#v", first)
+	}
+	if !first.OverBudget || first.LatencyBudgetMs != 10 {
+		t.Fatalf("latency budget not persisted: %#v", first)
+	}
+	if second := forwards[1]; second.Success || second.Attempts != 3 || len(second.Violations) != 1 {
+		t.Fatalf("unexpected second forward: %#v", second)
+	}
+
+	// Pruning the request must drop its forward responses as well.
+	for i := 1; i <= 2; i++ {
+		if _, err := store.Record(fakeRequest(fmt.Sprintf("rec-%d", i), "GET", "/p")); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	forwards, err = store.GetForwards("rec-0")
+	if err != nil {
+		t.Fatalf("get forwards failed: %v", err)
+	}
+	if len(forwards) != 0 {
+		t.Fatalf("expected forwards pruned with request, got %d", len(forwards))
+	}
+}
+
+func TestSQLiteStore_ForwardStats(t *testing.T) {
+	store := newTestStore(t, 0)
+	if _, err := store.Record(fakeRequest("rec-0", "POST", "/hook")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	now := time.Now()
+	err := store.RecordForwards("rec-0", []*ForwardRecord{
+		{TargetURL: "http://a.example", Timestamp: now, LatencyMs: 10, Success: true},
+		{TargetURL: "http://a.example", Timestamp: now, LatencyMs: 30, Violations: []string{"latency 30ms exceeds 20ms"}},
+		{TargetURL: "http://b.example", Timestamp: now, LatencyMs: 5, Success: true},
+		{TargetURL: "http://b.example", Timestamp: now.Add(-2 * time.Hour), LatencyMs: 500},
+	})
+	if err != nil {
+		t.Fatalf("record forwards failed: %v", err)
+	}
+
+	stats, err := store.(ForwardStatsStore).ForwardStats(now.Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("forward stats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 targets, got %d", len(stats))
+	}
+	if a := stats[0]; a.TargetURL != "http://a.example" || a.Total != 2 || a.Failed != 1 || a.SuccessRate != 0.5 || a.AvgLatencyMs != 20 || a.Violations != 1 {
+		t.Fatalf("unexpected stats for a: %#v", a)
+	}
+	if b := stats[1]; b.Total != 1 || b.SuccessRate != 1 {
+		t.Fatalf("expected the old delivery to be outside of the range: %#v", b)
+	}
+
+	if _, err := store.Record(fakeRequest("rec-1", "POST", "/hook")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	flagged, total, err := store.List(ListOptions{ForwardViolations: true})
+	if err != nil || total != 1 || len(flagged) != 1 || flagged[0].ID != "rec-0" || !flagged[0].ForwardViolations {
+		t.Fatalf("expected only rec-0 flagged with a contract violation, got %d (%v)", total, err)
+	}
+}
+
+func TestSQLiteStore_Aggregate(t *testing.T) {
+	store := newTestStore(t, 0)
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{5 * time.Minute, 20 * time.Minute, 75 * time.Minute} {
+		data := fakeRequest(fmt.Sprintf("agg-%d", i), "POST", "/hook")
+		data.Timestamp = base.Add(offset)
+		data.Body = bytes.Repeat([]byte("x"), 10*(i+1))
+		if i == 1 {
+			data.MockResponse.Status = http.StatusServiceUnavailable
+		}
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	err := store.RecordForwards("agg-0", []*ForwardRecord{
+		{TargetURL: "http://a.example", Timestamp: base, LatencyMs: 10, Success: true},
+		{TargetURL: "http://b.example", Timestamp: base, LatencyMs: 30},
+	})
+	if err != nil {
+		t.Fatalf("record forwards failed: %v", err)
+	}
+
+	aggregates, err := store.(AggregateStore).Aggregate(ListOptions{Method: "POST"}, time.Hour)
+	if err != nil {
+		t.Fatalf("aggregate failed: %v", err)
+	}
+	if len(aggregates) != 2 {
+		t.Fatalf("expected 2 intervals, got %d", len(aggregates))
+	}
+	first := aggregates[0]
+	if !first.Start.Equal(base) || first.Requests != 2 || first.Errors != 2 || first.AvgSizeBytes != 15 || first.Forwards != 2 || first.AvgForwardLatencyMs != 20 {
+		t.Fatalf("unexpected first interval: %#v", first)
+	}
+	if second := aggregates[1]; !second.Start.Equal(base.Add(time.Hour)) || second.Requests != 1 || second.Errors != 0 || second.Forwards != 0 {
+		t.Fatalf("unexpected second interval: %#v", second)
+	}
+}
+
+func TestSQLiteStore_MigratesForwardColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE forwards (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request_id TEXT NOT NULL,
+		target_url TEXT NOT NULL,
+		timestamp_ns INTEGER NOT NULL,
+		status_code INTEGER,
+		headers_json TEXT,
+		body BLOB,
+		body_truncated INTEGER,
+		latency_ms INTEGER,
+		attempts INTEGER,
+		success INTEGER,
+		error TEXT,
+		violations_json TEXT
+	)`)
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	store, err := New(&config.StorageConfig{Driver: "sqlite", Path: path, MaxRecords: 10}, noopLogger{})
+	if err != nil {
+		t.Fatalf("open store over legacy db: %v", err)
+	}
+	defer store.Close()
+	if err := store.RecordForwards("rec-0", []*ForwardRecord{{TargetURL: "http://a.example", OverBudget: true}}); err != nil {
+		t.Fatalf("record forwards after migration: %v", err)
+	}
+	forwards, err := store.GetForwards("rec-0")
+	if err != nil || len(forwards) != 1 || !forwards[0].OverBudget {
+		t.Fatalf("unexpected forwards after migration: %v %#v", err, forwards)
+	}
+}
+
+func TestSQLiteStore_Claims(t *testing.T) {
+	store := newTestStore(t, 100)
+	for i := 0; i < 3; i++ {
+		if _, err := store.Record(fakeRequest(fmt.Sprintf("claim-%d", i), "POST", "/hook")); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	claim, err := store.Claim("claim-0", "alice", false)
+	if err != nil || claim == nil || claim.User != "alice" {
+		t.Fatalf("expected alice to claim, got %+v %v", claim, err)
+	}
+	if current, err := store.Claim("claim-0", "bob", false); err != ErrClaimed || current == nil || current.User != "alice" {
+		t.Fatalf("expected ErrClaimed with alice's claim, got %+v %v", current, err)
+	}
+	if err := store.ReleaseClaim("claim-0", "bob", false); err != ErrClaimed {
+		t.Fatalf("expected bob to be unable to release alice's claim, got %v", err)
+	}
+	if _, err := store.Claim("claim-1", "bob", false); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, err := store.Claim("missing", "bob", false); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	got, err := store.Get("claim-0")
+	if err != nil || got.Claim == nil || got.Claim.User != "alice" || got.Claim.ClaimedAt.IsZero() {
+		t.Fatalf("expected the claim to be stored with the request, got %+v %v", got, err)
+	}
+
+	counts := map[string]int{ClaimNone: 1, ClaimAny: 2, "ALICE": 1, "carol": 0}
+	for filter, want := range counts {
+		_, total, err := store.List(ListOptions{Claim: filter})
+		if err != nil || total != want {
+			t.Fatalf("claim filter %q: expected %d, got %d (%v)", filter, want, total, err)
+		}
+	}
+
+	if err := store.ReleaseClaim("claim-0", "bob", true); err != nil {
+		t.Fatalf("forced release failed: %v", err)
+	}
+	if got, _ := store.Get("claim-0"); got.Claim != nil {
+		t.Fatalf("expected the claim to be released, got %+v", got.Claim)
+	}
+}
+
+func TestSQLiteStore_Comments(t *testing.T) {
+	store := newTestStore(t, 2)
+	if _, err := store.Record(fakeRequest("note-0", "POST", "/hook")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	first := &Comment{RequestID: "note-0", Author: "alice", Body: "signature mismatch"}
+	if err := store.AddComment(first); err != nil {
+		t.Fatalf("add comment failed: %v", err)
+	}
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Fatalf("expected id and timestamp to be filled in, got %+v", first)
+	}
+	if err := store.AddComment(&Comment{RequestID: "note-0", Author: "bob", Body: "clock skew, see NTP"}); err != nil {
+		t.Fatalf("add comment failed: %v", err)
+	}
+	if err := store.AddComment(&Comment{RequestID: "missing", Author: "bob", Body: "x"}); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	comments, err := store.GetComments("note-0")
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d (%v)", len(comments), err)
+	}
+	if comments[0].Author != "alice" || comments[1].Body != "clock skew, see NTP" {
+		t.Fatalf("unexpected comments order: %+v %+v", comments[0], comments[1])
+	}
+
+	// pruning the request drops its comments
+	for i := 1; i <= 2; i++ {
+		if _, err := store.Record(fakeRequest(fmt.Sprintf("note-%d", i), "GET", "/")); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	if comments, err := store.GetComments("note-0"); err != nil || len(comments) != 0 {
+		t.Fatalf("expected comments to be pruned with the request, got %d (%v)", len(comments), err)
+	}
+}
+
+func TestSQLiteStore_ForwardQueue(t *testing.T) {
+	store := newTestStore(t, 0)
+	if _, err := store.Record(fakeRequest("queue-0", "POST", "/hook")); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	now := time.Now()
+	later := &QueuedForward{RequestID: "queue-0", TargetURL: "http://b.test", NextAttempt: now.Add(time.Hour)}
+	first := &QueuedForward{RequestID: "queue-0", TargetURL: "http://a.test", LastError: "timeout", NextAttempt: now.Add(-time.Second)}
+	for _, item := range []*QueuedForward{later, first} {
+		if err := store.EnqueueForward(item); err != nil || item.ID == 0 {
+			t.Fatalf("enqueue failed: %v (%+v)", err, item)
+		}
+	}
+	dup := &QueuedForward{RequestID: "queue-0", TargetURL: "http://a.test"}
+	if err := store.EnqueueForward(dup); err != nil || dup.ID != first.ID {
+		t.Fatalf("expected the queued delivery to be kept, got %+v (%v)", dup, err)
+	}
+	if err := store.EnqueueForward(&QueuedForward{RequestID: "missing", TargetURL: "http://a.test"}); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	all, err := store.QueuedForwards(time.Time{}, 0)
+	if err != nil || len(all) != 2 || all[0].ID != first.ID || all[0].LastError != "timeout" {
+		t.Fatalf("expected both deliveries, soonest first, got %+v (%v)", all, err)
+	}
+	if due, _ := store.QueuedForwards(now, 0); len(due) != 1 || due[0].ID != first.ID {
+		t.Fatalf("expected only the due delivery, got %+v", due)
+	}
+
+	first.Attempts = 2
+	first.LastError = "status 503"
+	first.NextAttempt = now.Add(2 * time.Hour)
+	if err := store.RescheduleForward(first); err != nil {
+		t.Fatalf("reschedule failed: %v", err)
+	}
+	if due, _ := store.QueuedForwards(now, 0); len(due) != 0 {
+		t.Fatalf("expected nothing due after rescheduling, got %+v", due)
+	}
+	if err := store.DequeueForward(later.ID); err != nil {
+		t.Fatalf("dequeue failed: %v", err)
+	}
+	all, _ = store.QueuedForwards(time.Time{}, 0)
+	if len(all) != 1 || all[0].Attempts != 2 || all[0].LastError != "status 503" {
+		t.Fatalf("unexpected queue %+v", all)
+	}
+}
+
+func TestSQLiteStore_Annotate(t *testing.T) {
+	store := newTestStore(t, 100)
+	for i := 0; i < 3; i++ {
+		if _, err := store.Record(fakeRequest(fmt.Sprintf("tag-%d", i), "POST", "/hook")); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	note := "signature header missing"
+	if err := store.Annotate("tag-0", []string{"prod-incident", "bug-123"}, &note); err != nil {
+		t.Fatalf("annotate failed: %v", err)
+	}
+	if err := store.Annotate("tag-1", []string{"bug-123"}, nil); err != nil {
+		t.Fatalf("annotate failed: %v", err)
+	}
+	if err := store.Annotate("missing", []string{"bug-123"}, nil); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	got, err := store.Get("tag-0")
+	if err != nil || got.Note != note || strings.Join(got.Tags, ",") != "bug-123,prod-incident" {
+		t.Fatalf("unexpected annotations: %+v %v", got, err)
+	}
+
+	counts := map[string]int{"bug-123": 2, "prod-incident": 1, "bug-123,prod-incident": 1, "other": 0}
+	for filter, want := range counts {
+		items, total, err := store.List(ListOptions{Tags: strings.Split(filter, ",")})
+		if err != nil || total != want || len(items) != want {
+			t.Fatalf("tag filter %q: expected %d, got %d (%v)", filter, want, total, err)
+		}
+	}
+
+	// a nil note keeps the current one, an empty tag list clears the tags
+	if err := store.Annotate("tag-0", []string{}, nil); err != nil {
+		t.Fatalf("annotate failed: %v", err)
+	}
+	if got, _ := store.Get("tag-0"); len(got.Tags) != 0 || got.Note != note {
+		t.Fatalf("expected tags cleared and note kept, got %+v", got)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Bug-123 ", "prod-incident", "bug-123", ""})
+	if err != nil || strings.Join(tags, ",") != "bug-123,prod-incident" {
+		t.Fatalf("unexpected normalized tags %v (%v)", tags, err)
+	}
+	if _, err := NormalizeTags([]string{"has space"}); err == nil {
+		t.Fatal("expected tags with spaces to be rejected")
+	}
+	if _, err := NormalizeTags([]string{"a,b"}); err == nil {
+		t.Fatal("expected tags with commas to be rejected")
+	}
+}
+
+func TestSQLiteStore_Delete(t *testing.T) {
+	store := newTestStore(t, 0)
+	bodyFile := filepath.Join(t.TempDir(), "body.bin")
+	if err := os.WriteFile(bodyFile, []byte("full body"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spilled := fakeRequest("old-0", "POST", "/upload")
+	spilled.BodyFile = bodyFile
+	for _, data := range []*request.RequestData{spilled, fakeRequest("old-1", "POST", "/hook"), fakeRequest("new", "GET", "/")} {
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	if err := store.Pin("old-1", true); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if err := store.RecordForwards("old-0", []*ForwardRecord{{TargetURL: "http://a", Success: true}}); err != nil {
+		t.Fatalf("record forwards failed: %v", err)
+	}
+	if err := store.AddComment(&Comment{RequestID: "old-0", Author: "bob", Body: "archived"}); err != nil {
+		t.Fatalf("add comment failed: %v", err)
+	}
+
+	deleted, err := store.Delete([]string{"old-0", "old-1", "missing"})
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 requests deleted, got %d (%v)", deleted, err)
+	}
+	items, total, _ := store.List(ListOptions{})
+	if total != 1 || items[0].ID != "new" {
+		t.Fatalf("expected only the new request to remain, got %d", total)
+	}
+	if forwards, _ := store.GetForwards("old-0"); len(forwards) != 0 {
+		t.Fatalf("expected the forwards to be removed, got %d", len(forwards))
+	}
+	if comments, _ := store.GetComments("old-0"); len(comments) != 0 {
+		t.Fatalf("expected the comments to be removed, got %d", len(comments))
+	}
+	if _, err := os.Stat(bodyFile); !os.IsNotExist(err) {
+		t.Fatalf("expected the spilled body to be removed, got %v", err)
+	}
+}
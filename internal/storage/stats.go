@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ForwardStats aggregates the recorded deliveries per target URL
+func (s *sqliteStore) ForwardStats(since, until time.Time) ([]*TargetForwardStats, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if !since.IsZero() {
+		conditions = append(conditions, "timestamp_ns >= ?")
+		args = append(args, since.UnixNano())
+	}
+	if !until.IsZero() {
+		conditions = append(conditions, "timestamp_ns < ?")
+		args = append(args, until.UnixNano())
+	}
+	query := `SELECT target_url, COUNT(*), COALESCE(SUM(success), 0), COALESCE(AVG(latency_ms), 0),
+		COALESCE(SUM(violations_json NOT IN ('', 'null', '[]')), 0) FROM forwards`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " GROUP BY target_url ORDER BY target_url"
+
+	rows, err := s.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*TargetForwardStats
+	for rows.Next() {
+		var stats TargetForwardStats
+		if err := rows.Scan(&stats.TargetURL, &stats.Total, &stats.Succeeded, &stats.AvgLatencyMs, &stats.Violations); err != nil {
+			return nil, err
+		}
+		stats.Failed = stats.Total - stats.Succeeded
+		if stats.Total > 0 {
+			stats.SuccessRate = float64(stats.Succeeded) / float64(stats.Total)
+		}
+		result = append(result, &stats)
+	}
+	return result, rows.Err()
+}
+
+// Aggregate groups the matching requests per interval in a single query; forward outcomes are
+// counted towards the interval of their request.
+func (s *sqliteStore) Aggregate(opts ListOptions, interval time.Duration) ([]*IntervalAggregate, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	where, args := buildFilters(opts)
+	query := fmt.Sprintf(`SELECT bucket, COUNT(*), COALESCE(SUM(failed), 0), COALESCE(AVG(size), 0), COALESCE(SUM(forwards), 0), COALESCE(SUM(latency), 0)
+FROM (
+    SELECT timestamp_ns / ? AS bucket,
+        COALESCE(size, 0) AS size,
+        (COALESCE(mock_status, 0) >= 400 OR EXISTS (SELECT 1 FROM forwards WHERE forwards.request_id = requests.id AND COALESCE(forwards.success, 0) = 0)) AS failed,
+        (SELECT COUNT(*) FROM forwards WHERE forwards.request_id = requests.id) AS forwards,
+        (SELECT COALESCE(SUM(latency_ms), 0) FROM forwards WHERE forwards.request_id = requests.id) AS latency
+    FROM requests %s
+)
+GROUP BY bucket ORDER BY bucket`, where)
+
+	rows, err := s.db.QueryContext(context.Background(), query, append([]interface{}{interval.Nanoseconds()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*IntervalAggregate
+	for rows.Next() {
+		var (
+			bucket    int64
+			latencyMs int64
+			agg       IntervalAggregate
+		)
+		if err := rows.Scan(&bucket, &agg.Requests, &agg.Errors, &agg.AvgSizeBytes, &agg.Forwards, &latencyMs); err != nil {
+			return nil, err
+		}
+		agg.Start = time.Unix(0, bucket*interval.Nanoseconds()).UTC()
+		if agg.Forwards > 0 {
+			agg.AvgForwardLatencyMs = float64(latencyMs) / float64(agg.Forwards)
+		}
+		result = append(result, &agg)
+	}
+	return result, rows.Err()
+}
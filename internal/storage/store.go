@@ -1,7 +1,13 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/funnyzak/reqtap/internal/config"
 	"github.com/funnyzak/reqtap/internal/logger"
@@ -11,18 +17,83 @@ import (
 // ErrUnsupportedDriver indicates the configured driver is not available.
 var ErrUnsupportedDriver = errors.New("unsupported storage driver")
 
+// ErrUnsupported indicates the backend cannot perform the operation.
+var ErrUnsupported = errors.New("operation not supported by the storage backend")
+
+// ErrNotFound indicates the request does not exist.
+var ErrNotFound = errors.New("request not found")
+
+// ErrClaimed indicates another user holds the claim on a request.
+var ErrClaimed = errors.New("request claimed by another user")
+
+// Claim filter values for ListOptions.Claim; any other value filters by username.
+const (
+	ClaimNone = "none"
+	ClaimAny  = "any"
+)
+
 // ListOptions controls filtering and pagination when fetching requests.
 type ListOptions struct {
 	Search string
 	Method string
-	Limit  int
-	Offset int
+	// Since and Until bound the capture time (inclusive/exclusive); zero values leave the range open.
+	Since time.Time
+	Until time.Time
+	// Claim keeps unclaimed (ClaimNone), claimed (ClaimAny) or one user's requests; empty disables the filter.
+	Claim string
+	// Tags keeps requests carrying every listed tag.
+	Tags []string
+	// Pinned keeps only pinned requests.
+	Pinned bool
+	// ForwardViolations keeps requests with a forward delivery that broke its response contract.
+	ForwardViolations bool
+	// Duplicates keeps requests flagged as re-deliveries by dedup.
+	Duplicates bool
+	// ContentType keeps requests whose Content-Type starts with it, case-insensitively, so
+	// "application/json" also matches a charset parameter and "image/" every image.
+	ContentType string
+	// PathPrefix keeps requests whose path starts with it.
+	PathPrefix string
+	// MinSize and MaxSize bound the body size in bytes (inclusive); 0 leaves a bound open.
+	MinSize int64
+	MaxSize int64
+	// IsBinary keeps binary (true) or text (false) bodies; nil disables the filter.
+	IsBinary *bool
+	Limit    int
+	Offset   int
 }
 
 // StoredRequest wraps RequestData with its persisted identifier.
 type StoredRequest struct {
 	ID string `json:"id"`
 	*request.RequestData
+	Claim *Claim `json:"claim,omitempty"`
+	// Tags and Note are the triage annotations set through Annotate.
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+	// Pinned requests are exempt from retention and max_records pruning.
+	Pinned bool `json:"pinned,omitempty"`
+	// ForwardViolations flags a request with a forward delivery that broke its response contract.
+	ForwardViolations bool `json:"forward_violations,omitempty"`
+	// Comments is only filled in by exports that ask for them.
+	Comments []*Comment `json:"comments,omitempty"`
+	// BodyMatch locates ListOptions.Search in the body; only List fills it in.
+	BodyMatch *SearchMatch `json:"body_match,omitempty"`
+}
+
+// Claim records the console user investigating a request.
+type Claim struct {
+	User      string    `json:"user"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
+
+// Comment is a note a console user left on a request.
+type Comment struct {
+	ID        int64     `json:"id"`
+	RequestID string    `json:"request_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // StoredReplay wraps ReplayData with storage metadata
@@ -30,6 +101,167 @@ type StoredReplay struct {
 	*request.ReplayData
 }
 
+// ForwardRecord captures what a forward target answered for a captured request.
+type ForwardRecord struct {
+	ID            int64       `json:"id"`
+	RequestID     string      `json:"request_id"`
+	TargetURL     string      `json:"target_url"`
+	Timestamp     time.Time   `json:"timestamp"`
+	StatusCode    int         `json:"status_code"`
+	Headers       http.Header `json:"headers"`
+	Body          []byte      `json:"body"`
+	BodyTruncated bool        `json:"body_truncated"`
+	LatencyMs     int64       `json:"latency_ms"`
+	Attempts      int         `json:"attempts"`
+	Success       bool        `json:"success"`
+	Error         string      `json:"error,omitempty"`
+	Violations    []string    `json:"violations,omitempty"`
+	// LatencyBudgetMs is the provider timeout the delivery was measured against; OverBudget marks a breach.
+	LatencyBudgetMs int64 `json:"latency_budget_ms,omitempty"`
+	OverBudget      bool  `json:"over_budget,omitempty"`
+}
+
+// QueuedForward is a delivery that failed every attempt and waits in the forward queue for a retry.
+type QueuedForward struct {
+	ID        int64  `json:"id"`
+	RequestID string `json:"request_id"`
+	TargetURL string `json:"target_url"`
+	// Attempts counts the queue retries so far, not the forwarder's own retries.
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// APIToken is an admin API token created through the API; only the SHA-256 hash of the token is kept.
+type APIToken struct {
+	Name      string    `json:"name"`
+	Hash      string    `json:"-"`
+	Scopes    []string  `json:"scopes"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenStore persists API tokens across restarts. It is optional: with a store that does not
+// implement it, tokens created through the API last until the process exits.
+type TokenStore interface {
+	// SaveToken stores a new token and fills in its creation time.
+	SaveToken(*APIToken) error
+	// Tokens lists the stored tokens by name.
+	Tokens() ([]*APIToken, error)
+	// DeleteToken removes a token; it returns ErrNotFound for unknown names.
+	DeleteToken(name string) error
+}
+
+// MockRule is a mock response rule managed through the admin API; Definition holds the rule in the
+// YAML form of a server.responses entry.
+type MockRule struct {
+	Name       string    `json:"name"`
+	Definition string    `json:"definition"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// MockRuleStore persists the mock rules managed through the API across restarts. It is optional:
+// with a store that does not implement it, rule changes last until the process exits.
+type MockRuleStore interface {
+	// SaveMockRule adds a rule or replaces the one with the same name, keeping its position.
+	SaveMockRule(*MockRule) error
+	// MockRules lists the stored rules in the order they were added.
+	MockRules() ([]*MockRule, error)
+	// DeleteMockRule removes a rule; it returns ErrNotFound for unknown names.
+	DeleteMockRule(name string) error
+}
+
+// AuditEntry is one web console action of the audit log, e.g. a login, an export or a change of
+// the mock rules.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`
+	// Actor is the console user, "token:<name>" for API tokens, "guest" without web auth or "unknown"
+	Actor      string            `json:"actor"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Success    bool              `json:"success"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// AuditOptions narrows the entries listed by AuditEntries; zero values do not filter.
+type AuditOptions struct {
+	Action string
+	Actor  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditStore keeps the audit log. Entries can only be added: nothing updates or deletes them and
+// pruning leaves them alone. It is optional: with a store that does not implement it, only
+// web.audit.file records the actions.
+type AuditStore interface {
+	// RecordAudit appends an entry and fills in its ID and, when zero, its timestamp.
+	RecordAudit(*AuditEntry) error
+	// AuditEntries lists the matching entries newest first with the number of matches.
+	AuditEntries(AuditOptions) ([]*AuditEntry, int, error)
+}
+
+// TargetForwardStats aggregates the deliveries to one forward target.
+type TargetForwardStats struct {
+	TargetURL    string  `json:"target_url"`
+	Total        int     `json:"total"`
+	Succeeded    int     `json:"succeeded"`
+	Failed       int     `json:"failed"`
+	SuccessRate  float64 `json:"success_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	// Violations counts deliveries that broke the response contract of the target
+	Violations int `json:"violations"`
+}
+
+// ForwardStatsStore aggregates recorded forward outcomes. It is optional: stores that do not
+// implement it report no forward statistics.
+type ForwardStatsStore interface {
+	// ForwardStats aggregates the deliveries recorded in [since, until) per target URL; zero
+	// values leave the range open.
+	ForwardStats(since, until time.Time) ([]*TargetForwardStats, error)
+}
+
+// IntervalAggregate summarizes the requests captured in [Start, Start+interval).
+type IntervalAggregate struct {
+	Start    time.Time `json:"start"`
+	Requests int       `json:"requests"`
+	// Errors counts requests answered with a mock status of 400 or above or with a failed forward
+	Errors       int     `json:"errors"`
+	AvgSizeBytes float64 `json:"avg_size_bytes"`
+	// Forwards counts the recorded deliveries of the requests; AvgForwardLatencyMs averages them
+	Forwards            int     `json:"forwards"`
+	AvgForwardLatencyMs float64 `json:"avg_forward_latency_ms"`
+}
+
+// AggregateStore summarizes requests per time interval without loading them. It is optional:
+// stores that do not implement it cannot export aggregates.
+type AggregateStore interface {
+	// Aggregate groups the requests matching opts (Limit and Offset are ignored) into intervals
+	// aligned to the Unix epoch, in time order; intervals without requests are left out.
+	Aggregate(opts ListOptions, interval time.Duration) ([]*IntervalAggregate, error)
+}
+
+// WriteQueueReporter reports the backlog of a store that persists requests in the background. It
+// is optional: stores that write synchronously report no queue.
+type WriteQueueReporter interface {
+	// WriteQueue returns how many requests wait for the writer and how many may wait at most.
+	WriteQueue() (pending, capacity int)
+}
+
+// WriteChecker verifies that a store accepts writes, for readiness probes. It is optional: stores
+// that do not implement it are assumed writable.
+type WriteChecker interface {
+	// CheckWritable takes and releases the write lock without changing anything.
+	CheckWritable(ctx context.Context) error
+}
+
 // Store defines the persistence contract for captured requests.
 type Store interface {
 	Record(*request.RequestData) (*StoredRequest, error)
@@ -42,17 +274,79 @@ type Store interface {
 	RecordReplay(*request.ReplayData) (*StoredReplay, error)
 	GetReplays(originalRequestID string) ([]*StoredReplay, error)
 
+	// Forward response related methods
+	RecordForwards(requestID string, records []*ForwardRecord) error
+	GetForwards(requestID string) ([]*ForwardRecord, error)
+
+	// Claim assigns the request to user; it returns ErrClaimed with the current claim when another
+	// user holds it, unless force is set.
+	Claim(requestID, user string, force bool) (*Claim, error)
+	// ReleaseClaim clears the claim held by user, or any claim when force is set.
+	ReleaseClaim(requestID, user string, force bool) error
+
+	// Annotate replaces the tags of a request when tags is non-nil and its note when note is non-nil;
+	// it returns ErrNotFound for unknown requests.
+	Annotate(requestID string, tags []string, note *string) error
+
+	// Pin marks a request as pinned, or unpins it, so that pruning leaves it alone; it returns
+	// ErrNotFound for unknown requests.
+	Pin(requestID string, pinned bool) error
+
+	// Delete removes requests with their replays, forwards, comments, tags, queued deliveries and
+	// spilled bodies, pinned or not; unknown IDs are skipped. It returns how many were removed.
+	Delete(ids []string) (int64, error)
+
+	// AddComment stores a comment and fills in its ID and timestamp; it returns ErrNotFound for unknown requests.
+	AddComment(*Comment) error
+	// GetComments lists the comments of a request, oldest first.
+	GetComments(requestID string) ([]*Comment, error)
+
+	// EnqueueForward adds a failed delivery to the forward queue and fills in its ID; a delivery
+	// already queued for the same request and target is kept as is. It returns ErrNotFound for
+	// unknown requests.
+	EnqueueForward(*QueuedForward) error
+	// QueuedForwards lists queued deliveries due at or before due (all when zero), soonest first;
+	// limit 0 returns every match.
+	QueuedForwards(due time.Time, limit int) ([]*QueuedForward, error)
+	// RescheduleForward stores the attempts, last error and next attempt of a queued delivery.
+	RescheduleForward(*QueuedForward) error
+	// DequeueForward removes a delivery from the queue.
+	DequeueForward(id int64) error
+
 	Close() error
 }
 
+// tagPattern keeps tags short and free of the separators used by filters and exports.
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:/-]{0,63}$`)
+
+// NormalizeTags lowercases, validates and deduplicates tags, keeping their order.
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to 64 letters, digits, '.', '_', ':', '/' or '-'", tag)
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
 // New instantiates a Store based on configuration.
 func New(cfg *config.StorageConfig, log logger.Logger) (Store, error) {
 	if cfg == nil {
 		return nil, errors.New("storage config is nil")
 	}
-	switch driver := cfg.Driver; driver {
+	switch driver := strings.ToLower(strings.TrimSpace(cfg.Driver)); driver {
 	case "", "sqlite", "sqlite3":
 		return newSQLiteStore(cfg, log)
+	case "bolt", "bbolt":
+		return newBoltStore(cfg, log)
 	default:
 		return nil, ErrUnsupportedDriver
 	}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Summary recaps the requests captured in a time range, for the session summary printed on
+// shutdown and by reqtap stats.
+type Summary struct {
+	// From and To bound the range; an open start is the first capture, an open end the time of the summary
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Total int       `json:"total"`
+	// Methods and TopPaths are sorted by count, most used first
+	Methods  []SummaryCount `json:"methods"`
+	TopPaths []SummaryCount `json:"top_paths"`
+	// BodySizeP50 and BodySizeP95 are body size percentiles in bytes
+	BodySizeP50 int64 `json:"body_size_p50"`
+	BodySizeP95 int64 `json:"body_size_p95"`
+	// Forwards counts the recorded deliveries of the requests, across every target
+	Forwards           int     `json:"forwards"`
+	ForwardsSucceeded  int     `json:"forwards_succeeded"`
+	ForwardSuccessRate float64 `json:"forward_success_rate"`
+	// ForwardLatencyP50Ms and ForwardLatencyP95Ms are delivery latency percentiles in milliseconds
+	ForwardLatencyP50Ms int64 `json:"forward_latency_p50_ms"`
+	ForwardLatencyP95Ms int64 `json:"forward_latency_p95_ms"`
+}
+
+// SummaryCount is the number of requests sharing a method or a path.
+type SummaryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summarize recaps the requests captured in [since, until), zero values leaving the range open,
+// and keeps the top most requested paths.
+func Summarize(store Store, since, until time.Time, top int) (*Summary, error) {
+	summary := &Summary{From: since, To: until, Methods: []SummaryCount{}, TopPaths: []SummaryCount{}}
+	if until.IsZero() {
+		summary.To = time.Now()
+	}
+	methods := make(map[string]int)
+	paths := make(map[string]int)
+	var (
+		sizes []int64
+		ids   []string
+	)
+	err := store.Iterate(ListOptions{Since: since, Until: until}, func(item *StoredRequest) bool {
+		summary.Total++
+		if since.IsZero() && (summary.From.IsZero() || item.Timestamp.Before(summary.From)) {
+			summary.From = item.Timestamp
+		}
+		methods[item.Method]++
+		paths[item.Path]++
+		sizes = append(sizes, item.Size)
+		ids = append(ids, item.ID)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Forward records are read once the iteration is over, so the store is not queried from
+	// within its own iteration
+	var latencies []int64
+	for _, id := range ids {
+		records, err := store.GetForwards(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			summary.Forwards++
+			if record.Success {
+				summary.ForwardsSucceeded++
+			}
+			latencies = append(latencies, record.LatencyMs)
+		}
+	}
+	if summary.Forwards > 0 {
+		summary.ForwardSuccessRate = float64(summary.ForwardsSucceeded) / float64(summary.Forwards)
+	}
+
+	summary.Methods = sortedCounts(methods, 0)
+	summary.TopPaths = sortedCounts(paths, top)
+	summary.BodySizeP50, summary.BodySizeP95 = percentile(sizes, 0.5), percentile(sizes, 0.95)
+	summary.ForwardLatencyP50Ms, summary.ForwardLatencyP95Ms = percentile(latencies, 0.5), percentile(latencies, 0.95)
+	return summary, nil
+}
+
+// sortedCounts orders counts by count then name and keeps the first limit; 0 keeps them all
+func sortedCounts(counts map[string]int, limit int) []SummaryCount {
+	result := make([]SummaryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, SummaryCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile p of values, sorting them in place; 0 when empty
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	rank := int(math.Ceil(p*float64(len(values)))) - 1
+	return values[max(rank, 0)]
+}
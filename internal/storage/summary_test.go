@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestSummarize(t *testing.T) {
+	stores := map[string]Store{
+		"sqlite": newTestStore(t, 100),
+		"bolt":   newTestBoltStore(t, config.StorageConfig{MaxRecords: 100}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			old := fakeRequest("old", "GET", "/old")
+			old.Timestamp = time.Now().Add(-time.Hour)
+			if _, err := store.Record(old); err != nil {
+				t.Fatal(err)
+			}
+			since := time.Now().Add(-time.Minute)
+			for i := 1; i <= 20; i++ {
+				method, path := "POST", "/orders"
+				if i%4 == 0 {
+					method, path = "GET", "/health"
+				}
+				data := fakeRequest(fmt.Sprintf("req-%d", i), method, path)
+				data.Size = int64(i * 100)
+				if _, err := store.Record(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := store.RecordForwards("req-1", []*ForwardRecord{
+				{TargetURL: "http://a.example", Success: true, LatencyMs: 10},
+				{TargetURL: "http://b.example", Success: false, LatencyMs: 300},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.RecordForwards("req-2", []*ForwardRecord{{TargetURL: "http://a.example", Success: true, LatencyMs: 20}}); err != nil {
+				t.Fatal(err)
+			}
+
+			summary, err := Summarize(store, since, time.Time{}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Total != 20 {
+				t.Fatalf("expected the request before since to be left out, got %d", summary.Total)
+			}
+			if len(summary.Methods) != 2 || summary.Methods[0] != (SummaryCount{Name: "POST", Count: 15}) {
+				t.Fatalf("unexpected methods: %+v", summary.Methods)
+			}
+			if len(summary.TopPaths) != 1 || summary.TopPaths[0] != (SummaryCount{Name: "/orders", Count: 15}) {
+				t.Fatalf("expected only the top path, got %+v", summary.TopPaths)
+			}
+			if summary.BodySizeP50 != 1000 || summary.BodySizeP95 != 1900 {
+				t.Fatalf("unexpected body size percentiles: p50 %d, p95 %d", summary.BodySizeP50, summary.BodySizeP95)
+			}
+			if summary.Forwards != 3 || summary.ForwardsSucceeded != 2 {
+				t.Fatalf("unexpected forward counts: %+v", summary)
+			}
+			if summary.ForwardLatencyP50Ms != 20 || summary.ForwardLatencyP95Ms != 300 {
+				t.Fatalf("unexpected forward latency percentiles: p50 %d, p95 %d", summary.ForwardLatencyP50Ms, summary.ForwardLatencyP95Ms)
+			}
+		})
+	}
+}
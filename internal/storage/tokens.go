@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SaveToken stores an API token created through the admin API
+func (s *sqliteStore) SaveToken(token *APIToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	token.CreatedAt = token.CreatedAt.UTC()
+	_, err := s.db.ExecContext(context.Background(),
+		`INSERT INTO api_tokens (name, hash, scopes, created_by, created_at_ns) VALUES (?, ?, ?, ?, ?)`,
+		token.Name, token.Hash, strings.Join(token.Scopes, ","), token.CreatedBy, token.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("insert token: %w", err)
+	}
+	return nil
+}
+
+// Tokens lists the stored API tokens by name
+func (s *sqliteStore) Tokens() ([]*APIToken, error) {
+	rows, err := s.db.QueryContext(context.Background(),
+		`SELECT name, hash, scopes, COALESCE(created_by, ''), created_at_ns FROM api_tokens ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*APIToken
+	for rows.Next() {
+		var (
+			token     APIToken
+			scopes    string
+			createdAt int64
+		)
+		if err := rows.Scan(&token.Name, &token.Hash, &scopes, &token.CreatedBy, &createdAt); err != nil {
+			return nil, err
+		}
+		if scopes != "" {
+			token.Scopes = strings.Split(scopes, ",")
+		}
+		token.CreatedAt = time.Unix(0, createdAt).UTC()
+		result = append(result, &token)
+	}
+	return result, rows.Err()
+}
+
+// DeleteToken removes a stored API token
+func (s *sqliteStore) DeleteToken(name string) error {
+	res, err := s.db.ExecContext(context.Background(), `DELETE FROM api_tokens WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
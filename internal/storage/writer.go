@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultWriteBatch is the most requests the writer commits in one transaction.
+	DefaultWriteBatch = 256
+	// DefaultWriteQueue is how many requests may wait for the writer before Record blocks.
+	DefaultWriteQueue = 4096
+)
+
+// errStoreClosed is returned by Record once Close has been called.
+var errStoreClosed = errors.New("storage is closed")
+
+const insertRequestSQL = `INSERT INTO requests (
+        id, timestamp_ns, method, proto, path, query, remote_addr, user_agent,
+        headers_json, body, content_type, content_length, is_binary, size,
+        mock_rule, mock_status, instance, grpc_json, credential, content_encoding, wire_body, wire_size,
+        body_file, protobuf_json, fingerprint, duplicate_of
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// pendingWrite is a request waiting for the writer; done receives the outcome of its insert
+// once the batch holding it is committed.
+type pendingWrite struct {
+	args []interface{}
+	done chan error
+}
+
+// startWriter launches the goroutine that persists recorded requests. Requests queued while a
+// batch is being written are committed together, so under load many inserts share one
+// transaction and one prune instead of paying for a commit each.
+func (s *sqliteStore) startWriter(batch, queue int) {
+	if batch <= 0 {
+		batch = DefaultWriteBatch
+	}
+	if queue <= 0 {
+		queue = DefaultWriteQueue
+	}
+	s.writes = make(chan *pendingWrite, queue)
+	s.writerDone = make(chan struct{})
+	go func() {
+		defer close(s.writerDone)
+		pending := make([]*pendingWrite, 0, batch)
+		for first := range s.writes {
+			pending = append(pending[:0], first)
+		drain:
+			for len(pending) < batch {
+				select {
+				case next, ok := <-s.writes:
+					if !ok {
+						break drain
+					}
+					pending = append(pending, next)
+				default:
+					break drain
+				}
+			}
+			s.writeBatch(pending)
+		}
+	}()
+}
+
+// WriteQueue reports the requests waiting for the writer.
+func (s *sqliteStore) WriteQueue() (pending, capacity int) {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+	if s.writes == nil {
+		return 0, 0
+	}
+	return len(s.writes), cap(s.writes)
+}
+
+// CheckWritable opens a write transaction; a statement that deletes nothing still takes the write
+// lock, so read-only databases and files fail the check.
+func (s *sqliteStore) CheckWritable(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM requests WHERE 0")
+	return err
+}
+
+// enqueue hands a request to the writer and waits until its batch is committed. It blocks while
+// the queue is full, which pushes back on the capture pipeline instead of buffering without bound.
+func (s *sqliteStore) enqueue(args []interface{}) error {
+	write := &pendingWrite{args: args, done: make(chan error, 1)}
+
+	s.writeMu.RLock()
+	if s.writesClosed {
+		s.writeMu.RUnlock()
+		return errStoreClosed
+	}
+	select {
+	case s.writes <- write:
+	default:
+		s.log.Debug("Storage write queue is full, waiting for the writer", "queue", cap(s.writes))
+		s.writes <- write
+	}
+	s.writeMu.RUnlock()
+
+	return <-write.done
+}
+
+// writeBatch inserts every pending request and prunes once in a single transaction. A request
+// that fails to insert (e.g. a duplicate ID) only fails its own Record call; a failed
+// transaction fails the whole batch.
+func (s *sqliteStore) writeBatch(pending []*pendingWrite) {
+	errs := make([]error, len(pending))
+	finish := func(batchErr error) {
+		for i, write := range pending {
+			if batchErr != nil {
+				write.done <- batchErr
+				continue
+			}
+			write.done <- errs[i]
+		}
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		finish(err)
+		return
+	}
+	stmt, err := tx.PrepareContext(ctx, insertRequestSQL)
+	if err != nil {
+		_ = tx.Rollback()
+		finish(fmt.Errorf("prepare insert: %w", err))
+		return
+	}
+	for i, write := range pending {
+		if _, err := stmt.ExecContext(ctx, write.args...); err != nil {
+			errs[i] = fmt.Errorf("insert request: %w", err)
+		}
+	}
+	stmt.Close()
+
+	_, bodyFiles, err := s.prune(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		finish(err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		finish(err)
+		return
+	}
+	removeBodyFiles(bodyFiles)
+	finish(nil)
+}
+
+// stopWriter lets the writer flush the queued requests and waits for it to exit.
+func (s *sqliteStore) stopWriter() {
+	if s.writes == nil {
+		return
+	}
+	s.writeMu.Lock()
+	if s.writesClosed {
+		s.writeMu.Unlock()
+		return
+	}
+	s.writesClosed = true
+	close(s.writes)
+	s.writeMu.Unlock()
+	<-s.writerDone
+}
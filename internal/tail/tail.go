@@ -0,0 +1,176 @@
+// Package tail follows the live request stream of a remote ReqTap instance through the WebSocket
+// endpoint of its web console API.
+package tail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// maxReconnectDelay caps the backoff between reconnection attempts.
+const maxReconnectDelay = 30 * time.Second
+
+// ErrUnauthorized reports that the remote instance rejected the token; retrying cannot help.
+var ErrUnauthorized = errors.New("remote instance rejected the token")
+
+// Options configures a tail client.
+type Options struct {
+	// URL is the remote instance, e.g. http://remote:38888; without a path the WebSocket endpoint
+	// is APIPath + "/ws"
+	URL string
+	// Token is an API token or session token of the remote web console; empty when auth is off
+	Token string
+	// APIPath is the remote web.admin_path, "/api" by default
+	APIPath string
+	// History replays the latest stored requests on the first connection
+	History int
+}
+
+// Client follows a remote instance and hands every captured request to a callback.
+type Client struct {
+	opts     Options
+	endpoint string
+	log      logger.Logger
+}
+
+// NewClient validates opts and resolves the WebSocket endpoint; http(s) URLs become ws(s) URLs.
+func NewClient(opts Options, log logger.Logger) (*Client, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(opts.URL))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid remote URL: %s", opts.URL)
+	}
+	switch endpoint.Scheme {
+	case "http":
+		endpoint.Scheme = "ws"
+	case "https":
+		endpoint.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return nil, fmt.Errorf("remote URL must use http(s) or ws(s): %s", opts.URL)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		apiPath := strings.TrimRight(opts.APIPath, "/")
+		if apiPath == "" {
+			apiPath = "/api"
+		}
+		endpoint.Path = apiPath + "/ws"
+	}
+	return &Client{opts: opts, endpoint: endpoint.String(), log: log}, nil
+}
+
+// Endpoint is the WebSocket URL the client connects to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// Run follows the remote instance until ctx is cancelled, reconnecting with backoff. Requests are
+// passed to handle in the order they were captured; history is only requested on the first
+// connection so a reconnect does not repeat it.
+func (c *Client) Run(ctx context.Context, handle func(*request.RequestData)) error {
+	delay := time.Second
+	history := c.opts.History
+	for {
+		connected, err := c.connect(ctx, history, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+		if connected {
+			delay = time.Second
+			history = 0
+		}
+		c.log.Warn("Remote stream disconnected, reconnecting", "error", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// event is a message of the web console stream; only request events are followed.
+type event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// connect reads one stream connection; connected reports whether the handshake succeeded.
+func (c *Client) connect(ctx context.Context, history int, handle func(*request.RequestData)) (connected bool, err error) {
+	header := http.Header{}
+	if c.opts.Token != "" {
+		header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, c.streamURL(history), header)
+	if err != nil {
+		if resp != nil {
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				return false, ErrUnauthorized
+			}
+			return false, fmt.Errorf("%w (status %d)", err, resp.StatusCode)
+		}
+		return false, err
+	}
+	defer ws.Close()
+	c.log.Info("Following remote instance", "url", c.endpoint)
+
+	// Closing the socket unblocks ReadMessage once ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		var ev event
+		if err := json.Unmarshal(data, &ev); err != nil {
+			c.log.Warn("Ignoring malformed stream message", "error", err)
+			continue
+		}
+		switch ev.Type {
+		case "request":
+			var req request.RequestData
+			if err := json.Unmarshal(ev.Data, &req); err != nil {
+				c.log.Warn("Ignoring malformed request event", "error", err)
+				continue
+			}
+			handle(&req)
+		case "history":
+			var items []*request.RequestData
+			if err := json.Unmarshal(ev.Data, &items); err != nil {
+				c.log.Warn("Ignoring malformed history event", "error", err)
+				continue
+			}
+			// History arrives newest first
+			for i := len(items) - 1; i >= 0; i-- {
+				handle(items[i])
+			}
+		}
+	}
+}
+
+// streamURL asks for history requests; 0 also overrides the remote web.websocket.history default
+func (c *Client) streamURL(history int) string {
+	endpoint, _ := url.Parse(c.endpoint)
+	query := endpoint.Query()
+	query.Set("history", strconv.Itoa(history))
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String()
+}
@@ -0,0 +1,112 @@
+package tail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestNewClientResolvesEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"http://remote:38888":           "ws://remote:38888/console/ws",
+		"https://hooks.example.com/":    "wss://hooks.example.com/console/ws",
+		"ws://remote:38888/custom/ws":   "ws://remote:38888/custom/ws",
+		"https://hooks.example.com/api": "wss://hooks.example.com/api",
+	}
+	for input, want := range cases {
+		client, err := NewClient(Options{URL: input, APIPath: "/console/"}, noopLogger{})
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		if client.Endpoint() != want {
+			t.Errorf("%s: expected %s, got %s", input, want, client.Endpoint())
+		}
+	}
+	if _, err := NewClient(Options{URL: "ftp://remote"}, noopLogger{}); err == nil {
+		t.Error("expected an unsupported scheme to be rejected")
+	}
+}
+
+func TestClientFollowsRemoteStream(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	connects := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ws" || r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		connects++
+		if want := []string{"2", "0"}[min(connects, 2)-1]; r.URL.Query().Get("history") != want {
+			t.Errorf("connection %d: expected history=%s, got %q", connects, want, r.URL.Query().Get("history"))
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if connects == 1 {
+			conn.WriteJSON(map[string]interface{}{"type": "history", "data": []map[string]string{{"id": "B"}, {"id": "A"}}})
+			conn.WriteJSON(map[string]interface{}{"type": "claim", "data": map[string]string{"request_id": "A"}})
+			conn.WriteJSON(map[string]interface{}{"type": "request", "data": map[string]interface{}{"id": "C", "method": "POST", "body": []byte("hi")}})
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{"type": "request", "data": map[string]string{"id": "D"}})
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Options{URL: srv.URL, Token: "s3cret", History: 2}, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var seen []*request.RequestData
+	client.Run(ctx, func(data *request.RequestData) {
+		seen = append(seen, data)
+		if data.ID == "D" {
+			cancel()
+		}
+	})
+
+	var ids []string
+	for _, data := range seen {
+		ids = append(ids, data.ID)
+	}
+	if len(ids) != 4 || ids[0] != "A" || ids[1] != "B" || ids[2] != "C" || ids[3] != "D" {
+		t.Fatalf("expected history oldest first, then live requests across a reconnect, got %v", ids)
+	}
+	if seen[2].Method != http.MethodPost || string(seen[2].Body) != "hi" {
+		t.Fatalf("expected the request to be decoded, got %+v", seen[2])
+	}
+}
+
+func TestClientStopsOnRejectedToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Options{URL: srv.URL, Token: "wrong"}, noopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Run(context.Background(), func(*request.RequestData) {}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package tui
+
+const (
+	keyCount        = "cli.tui.count"
+	keyFilter       = "cli.tui.filter"
+	keyWaiting      = "cli.tui.waiting"
+	keyNoMatches    = "cli.tui.no_matches"
+	keyHeaders      = "cli.tui.headers"
+	keyBody         = "cli.tui.body"
+	keyBodyEmpty    = "cli.tui.body_empty"
+	keyBodyBinary   = "cli.tui.body_binary"
+	keySearchPrompt = "cli.tui.search_prompt"
+	keyHelpList     = "cli.tui.help_list"
+	keyHelpDetail   = "cli.tui.help_detail"
+	keyReplaying    = "cli.tui.replaying"
+	keyReplayDone   = "cli.tui.replay_done"
+	keyReplayFailed = "cli.tui.replay_failed"
+	keyPaused       = "cli.tui.paused"
+)
@@ -0,0 +1,441 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+	runewidth "github.com/mattn/go-runewidth"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// replayTimeout bounds a replay started with the r key.
+const replayTimeout = 30 * time.Second
+
+type requestMsg struct {
+	data *request.RequestData
+}
+
+type replayMsg struct {
+	id     string
+	status int
+	err    error
+}
+
+type pauseMsg struct {
+	paused bool
+}
+
+type focus int
+
+const (
+	focusList focus = iota
+	focusDetail
+	focusSearch
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	sectionStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	pausedStyle   = lipgloss.NewStyle().Bold(true).Reverse(true).Foreground(lipgloss.Color("11"))
+	methodStyles  = map[string]lipgloss.Style{
+		http.MethodGet:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("4")),
+		http.MethodPost:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2")),
+		http.MethodPut:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3")),
+		http.MethodDelete: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1")),
+		http.MethodPatch:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5")),
+	}
+)
+
+// model keeps requests newest first; visible holds the indexes matching the search query.
+type model struct {
+	opts    Options
+	items   []*request.RequestData
+	visible []int
+	cursor  int
+	scroll  int
+	focus   focus
+	query   string
+	draft   string
+	status  string
+	paused  bool
+	width   int
+	height  int
+}
+
+func newModel(opts Options) *model {
+	return &model{opts: opts, width: 100, height: 30}
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) t(key string) string {
+	if m.opts.Translator == nil {
+		return key
+	}
+	return m.opts.Translator.Text(m.opts.Locale, key)
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case requestMsg:
+		m.add(msg.data)
+	case replayMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf(m.t(keyReplayFailed), msg.err)
+		} else {
+			m.status = fmt.Sprintf(m.t(keyReplayDone), msg.id, msg.status)
+		}
+	case pauseMsg:
+		m.paused = msg.paused
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *model) add(data *request.RequestData) {
+	selected := m.selected()
+	m.items = append([]*request.RequestData{data}, m.items...)
+	if len(m.items) > m.opts.MaxItems {
+		m.items = m.items[:m.opts.MaxItems]
+	}
+	m.filter()
+	// Keep the selection on the same request while new ones arrive on top
+	if selected != nil {
+		for i, idx := range m.visible {
+			if m.items[idx] == selected {
+				m.cursor = i
+				return
+			}
+		}
+	}
+}
+
+func (m *model) filter() {
+	m.visible = m.visible[:0]
+	query := strings.ToLower(m.query)
+	for i, item := range m.items {
+		if query == "" || matches(item, query) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// matches searches method, path, query, client, headers and textual bodies.
+func matches(item *request.RequestData, query string) bool {
+	fields := []string{item.ID, item.Method, item.Path, item.Query, item.RemoteAddr, item.UserAgent, item.ContentType}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	for key, values := range item.Headers {
+		if strings.Contains(strings.ToLower(key+": "+strings.Join(values, ", ")), query) {
+			return true
+		}
+	}
+	return !item.IsBinary && strings.Contains(strings.ToLower(string(item.Body)), query)
+}
+
+func (m *model) selected() *request.RequestData {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return nil
+	}
+	return m.items[m.visible[m.cursor]]
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	if msg.Type == tea.KeyCtrlC {
+		return tea.Quit
+	}
+	if m.focus == focusSearch {
+		m.handleSearchKey(msg)
+		return nil
+	}
+
+	switch msg.String() {
+	case "q":
+		if m.focus == focusDetail {
+			m.focus = focusList
+			return nil
+		}
+		return tea.Quit
+	case "esc":
+		if m.focus == focusDetail {
+			m.focus = focusList
+		} else if m.query != "" {
+			m.query = ""
+			m.filter()
+		}
+	case "enter", "tab":
+		if m.focus == focusList && m.selected() != nil {
+			m.focus = focusDetail
+			m.scroll = 0
+		} else {
+			m.focus = focusList
+		}
+	case "/":
+		m.focus = focusSearch
+		m.draft = m.query
+	case "r":
+		return m.replay()
+	case "p":
+		if m.opts.TogglePause != nil {
+			m.opts.TogglePause()
+		}
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup":
+		m.move(-m.listHeight())
+	case "pgdown":
+		m.move(m.listHeight())
+	case "home", "g":
+		m.move(-(m.cursor + m.scroll))
+	case "end", "G":
+		m.move(1 << 30)
+	}
+	return nil
+}
+
+func (m *model) move(delta int) {
+	if m.focus == focusDetail {
+		m.scroll += delta
+		if m.scroll < 0 {
+			m.scroll = 0
+		}
+		return
+	}
+	m.cursor += delta
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.scroll = 0
+}
+
+func (m *model) handleSearchKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.query = strings.TrimSpace(m.draft)
+		m.focus = focusList
+		m.cursor = 0
+		m.filter()
+	case tea.KeyEsc:
+		m.focus = focusList
+	case tea.KeyBackspace:
+		if m.draft != "" {
+			_, size := utf8.DecodeLastRuneInString(m.draft)
+			m.draft = m.draft[:len(m.draft)-size]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.draft += string(msg.Runes)
+	}
+}
+
+func (m *model) replay() tea.Cmd {
+	item := m.selected()
+	if item == nil || m.opts.Replay == nil {
+		return nil
+	}
+	m.status = fmt.Sprintf(m.t(keyReplaying), item.ID)
+	replay := m.opts.Replay
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+		defer cancel()
+		status, err := replay(ctx, item)
+		return replayMsg{id: item.ID, status: status, err: err}
+	}
+}
+
+// listHeight is the number of rows given to the request list; the detail pane gets the rest.
+func (m *model) listHeight() int {
+	rows := (m.height - 4) * 2 / 5
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+	b.WriteString(m.headerLine())
+	b.WriteString("\n")
+
+	listRows := m.listHeight()
+	b.WriteString(m.listView(listRows))
+
+	detailRows := m.height - listRows - 4
+	if detailRows < 3 {
+		detailRows = 3
+	}
+	b.WriteString(dimStyle.Render(strings.Repeat("─", max(m.width, 1))))
+	b.WriteString("\n")
+	b.WriteString(m.detailView(detailRows))
+	b.WriteString(m.footerLine())
+	return b.String()
+}
+
+func (m *model) headerLine() string {
+	header := titleStyle.Render("ReqTap") + "  " + fmt.Sprintf(m.t(keyCount), len(m.items))
+	if m.paused {
+		header += "  " + pausedStyle.Render(m.t(keyPaused))
+	}
+	if m.query != "" {
+		header += "  " + fmt.Sprintf(m.t(keyFilter), m.query, len(m.visible), len(m.items))
+	}
+	return header
+}
+
+func (m *model) listView(rows int) string {
+	var b strings.Builder
+	if len(m.visible) == 0 {
+		message := m.t(keyWaiting)
+		if m.query != "" {
+			message = m.t(keyNoMatches)
+		}
+		b.WriteString(dimStyle.Render(message))
+		b.WriteString(strings.Repeat("\n", rows))
+		return b.String()
+	}
+
+	// Scroll the window so the cursor stays visible
+	start := 0
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	for row := 0; row < rows; row++ {
+		i := start + row
+		if i < len(m.visible) {
+			b.WriteString(m.listRow(m.items[m.visible[i]], i == m.cursor))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (m *model) listRow(item *request.RequestData, selected bool) string {
+	method := fmt.Sprintf("%-7s", item.Method)
+	target := item.Path
+	if item.Query != "" {
+		target += "?" + item.Query
+	}
+	size := humanize.Bytes(uint64(item.Size))
+	prefix := item.Timestamp.Local().Format("15:04:05") + "  "
+	suffix := fmt.Sprintf("  %8s  %s", size, item.RemoteAddr)
+	room := m.width - runewidth.StringWidth(prefix) - len(method) - 1 - runewidth.StringWidth(suffix)
+	if room < 10 {
+		room = 10
+	}
+	target = runewidth.FillRight(runewidth.Truncate(target, room, "…"), room)
+	if selected {
+		return selectedStyle.Render(prefix + method + " " + target + suffix)
+	}
+	style, ok := methodStyles[item.Method]
+	if !ok {
+		style = lipgloss.NewStyle().Bold(true)
+	}
+	return dimStyle.Render(prefix) + style.Render(method) + " " + target + dimStyle.Render(suffix)
+}
+
+func (m *model) detailView(rows int) string {
+	item := m.selected()
+	lines := []string{}
+	if item != nil {
+		lines = detailLines(item, m.t)
+	}
+	if m.scroll > len(lines)-rows {
+		m.scroll = max(len(lines)-rows, 0)
+	}
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		i := m.scroll + row
+		if i < len(lines) {
+			b.WriteString(runewidth.Truncate(lines[i], m.width, "…"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// detailLines renders the metadata, headers and formatted body of a request.
+func detailLines(item *request.RequestData, t func(string) string) []string {
+	target := item.Path
+	if item.Query != "" {
+		target += "?" + item.Query
+	}
+	lines := []string{
+		sectionStyle.Render(item.Method+" "+target) + "  " + dimStyle.Render(item.Proto),
+		fmt.Sprintf("%s  %s  ·  %s  ·  %s", item.ID, item.Timestamp.Local().Format(time.RFC3339), item.RemoteAddr, humanize.Bytes(uint64(item.Size))),
+		"",
+		sectionStyle.Render(t(keyHeaders)),
+	}
+	keys := make([]string, 0, len(item.Headers))
+	for key := range item.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, "  "+key+": "+strings.Join(item.Headers[key], ", "))
+	}
+	lines = append(lines, "", sectionStyle.Render(t(keyBody)))
+	lines = append(lines, strings.Split(formatBody(item, t), "\n")...)
+	return lines
+}
+
+func formatBody(item *request.RequestData, t func(string) string) string {
+	switch {
+	case len(item.Body) == 0:
+		return dimStyle.Render(t(keyBodyEmpty))
+	case item.IsBinary:
+		return dimStyle.Render(fmt.Sprintf(t(keyBodyBinary), humanize.Bytes(uint64(len(item.Body))), item.ContentType))
+	}
+	trimmed := bytes.TrimSpace(item.Body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var out bytes.Buffer
+		if err := json.Indent(&out, trimmed, "", "  "); err == nil {
+			return out.String()
+		}
+	}
+	return strings.ReplaceAll(string(item.Body), "\r\n", "\n")
+}
+
+func (m *model) footerLine() string {
+	if m.focus == focusSearch {
+		return m.t(keySearchPrompt) + m.draft + "█"
+	}
+	help := m.t(keyHelpList)
+	if m.focus == focusDetail {
+		help = m.t(keyHelpDetail)
+	}
+	if m.status != "" {
+		return m.status + dimStyle.Render("  ·  "+help)
+	}
+	return dimStyle.Render(help)
+}
@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/funnyzak/reqtap/pkg/i18n"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func testRequest(id, method, path, body string) *request.RequestData {
+	return &request.RequestData{
+		ID:        id,
+		Timestamp: time.Now(),
+		Method:    method,
+		Path:      path,
+		Headers:   http.Header{"Content-Type": {"application/json"}},
+		Body:      []byte(body),
+		Size:      int64(len(body)),
+	}
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestModelKeepsSelectionAsRequestsArrive(t *testing.T) {
+	m := newModel(Options{MaxItems: 2})
+	m.Update(requestMsg{data: testRequest("A", "GET", "/a", "")})
+	m.Update(requestMsg{data: testRequest("B", "POST", "/b", "")})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := m.selected().ID; got != "A" {
+		t.Fatalf("expected A selected, got %s", got)
+	}
+
+	m.Update(requestMsg{data: testRequest("C", "PUT", "/c", "")})
+	if len(m.items) != 2 || m.items[0].ID != "C" {
+		t.Fatalf("expected newest first and the oldest dropped, got %d items", len(m.items))
+	}
+	if m.selected().ID != "B" {
+		t.Fatalf("expected the cursor to clamp to the remaining requests, got %s", m.selected().ID)
+	}
+}
+
+func TestModelSearch(t *testing.T) {
+	m := newModel(Options{MaxItems: 10})
+	m.Update(requestMsg{data: testRequest("A", "POST", "/orders", `{"sku":"red-shoe"}`)})
+	m.Update(requestMsg{data: testRequest("B", "GET", "/health", "")})
+
+	m.Update(runes("/"))
+	m.Update(runes("shoe"))
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.visible) != 1 || m.selected().ID != "A" {
+		t.Fatalf("expected the body match only, got %v", m.visible)
+	}
+	if !strings.Contains(m.View(), "/orders") || strings.Contains(m.View(), "/health") {
+		t.Fatalf("unexpected view:\n%s", m.View())
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(m.visible) != 2 {
+		t.Fatalf("esc should clear the search, got %v", m.visible)
+	}
+}
+
+func TestModelDetailAndReplay(t *testing.T) {
+	translator, err := i18n.NewTranslator("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := ""
+	m := newModel(Options{Translator: translator, MaxItems: 10, Replay: func(_ context.Context, data *request.RequestData) (int, error) {
+		replayed = data.ID
+		return http.StatusAccepted, nil
+	}})
+	m.Update(requestMsg{data: testRequest("A", "POST", "/orders", `{"sku":"red-shoe"}`)})
+
+	if !strings.Contains(m.View(), `"sku": "red-shoe"`) {
+		t.Fatalf("expected the JSON body pretty-printed in the detail pane:\n%s", m.View())
+	}
+
+	_, cmd := m.Update(runes("r"))
+	if cmd == nil {
+		t.Fatal("expected a replay command")
+	}
+	m.Update(cmd())
+	if replayed != "A" || m.status != "Replayed A → 202" {
+		t.Fatalf("unexpected replay outcome %q / %q", replayed, m.status)
+	}
+}
+
+func TestModelPauseKey(t *testing.T) {
+	translator, err := i18n.NewTranslator("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	toggled := 0
+	m := newModel(Options{Translator: translator, MaxItems: 10, TogglePause: func() { toggled++ }})
+
+	m.Update(runes("p"))
+	if toggled != 1 {
+		t.Fatalf("expected p to toggle capture once, got %d", toggled)
+	}
+	m.Update(pauseMsg{paused: true})
+	if !strings.Contains(m.View(), "PAUSED") {
+		t.Fatalf("expected the paused indicator in the header:\n%s", m.View())
+	}
+	m.Update(pauseMsg{paused: false})
+	if strings.Contains(m.View(), "PAUSED") {
+		t.Fatalf("expected the paused indicator to disappear:\n%s", m.View())
+	}
+}
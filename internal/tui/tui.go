@@ -0,0 +1,80 @@
+// Package tui is the interactive terminal view of captured requests, used instead of the
+// scrolling console printer when the output mode is "tui".
+package tui
+
+import (
+	"context"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/funnyzak/reqtap/pkg/i18n"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// defaultMaxItems bounds how many requests the list keeps; the oldest are dropped first.
+const defaultMaxItems = 1000
+
+// Replayer sends a captured request again and reports the status it was answered with.
+type Replayer func(ctx context.Context, data *request.RequestData) (int, error)
+
+// Options configures the terminal UI.
+type Options struct {
+	Translator *i18n.Translator
+	Locale     string
+	MaxItems   int
+	Replay     Replayer
+	// TogglePause pauses or resumes capture when p is pressed; nil disables the key
+	TogglePause func()
+}
+
+// UI implements printer.Printer by feeding requests to the terminal program.
+type UI struct {
+	program *tea.Program
+	once    sync.Once
+	done    chan struct{}
+	err     error
+}
+
+// New prepares the UI; nothing is drawn until Start.
+func New(opts Options) *UI {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = defaultMaxItems
+	}
+	return &UI{
+		program: tea.NewProgram(newModel(opts), tea.WithAltScreen()),
+		done:    make(chan struct{}),
+	}
+}
+
+// PrintRequest adds a captured request to the list.
+func (u *UI) PrintRequest(data *request.RequestData) error {
+	if data != nil {
+		u.program.Send(requestMsg{data: data})
+	}
+	return nil
+}
+
+// SetPaused shows or hides the paused indicator.
+func (u *UI) SetPaused(paused bool, _ int64) {
+	u.program.Send(pauseMsg{paused: paused})
+}
+
+// Start runs the UI in the background; the returned channel is closed once the user quits.
+func (u *UI) Start() <-chan struct{} {
+	u.once.Do(func() {
+		go func() {
+			_, u.err = u.program.Run()
+			close(u.done)
+		}()
+	})
+	return u.done
+}
+
+// Stop closes the UI and restores the terminal.
+func (u *UI) Stop() error {
+	u.Start()
+	u.program.Quit()
+	<-u.done
+	return u.err
+}
@@ -0,0 +1,174 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+)
+
+// maxReconnectDelay caps the backoff between reconnection attempts.
+const maxReconnectDelay = 30 * time.Second
+
+// ClientOptions configures a tunnel client.
+type ClientOptions struct {
+	// ServerURL is the tunnel endpoint of the public instance, e.g. wss://hooks.example.com/reqtap-tunnel
+	ServerURL string
+	Secret    string
+	// TargetURL is the local instance relayed requests are sent to, e.g. http://127.0.0.1:38888
+	TargetURL string
+	// Timeout bounds each request to the local instance
+	Timeout time.Duration
+}
+
+// Client connects to a public instance and answers the requests it relays by sending them to
+// the local target.
+type Client struct {
+	opts   ClientOptions
+	target *url.URL
+	http   *http.Client
+	log    logger.Logger
+}
+
+// NewClient validates opts; http(s) server URLs are turned into ws(s) URLs.
+func NewClient(opts ClientOptions, log logger.Logger) (*Client, error) {
+	server, err := url.Parse(opts.ServerURL)
+	if err != nil || server.Host == "" {
+		return nil, fmt.Errorf("invalid tunnel server URL: %s", opts.ServerURL)
+	}
+	switch server.Scheme {
+	case "http":
+		server.Scheme = "ws"
+	case "https":
+		server.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return nil, fmt.Errorf("tunnel server URL must use http(s) or ws(s): %s", opts.ServerURL)
+	}
+	opts.ServerURL = server.String()
+
+	target, err := url.Parse(strings.TrimRight(opts.TargetURL, "/"))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("tunnel target must be an http(s) URL: %s", opts.TargetURL)
+	}
+	return &Client{
+		opts:   opts,
+		target: target,
+		http: &http.Client{
+			Timeout: opts.Timeout,
+			// Redirects are the local instance's answer and go back to the sender unchanged
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		log: log,
+	}, nil
+}
+
+// Run keeps the tunnel connected until ctx is cancelled, reconnecting with backoff.
+func (c *Client) Run(ctx context.Context) error {
+	delay := time.Second
+	for {
+		connected, err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			delay = time.Second
+		}
+		c.log.Warn("Tunnel disconnected, reconnecting", "error", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// connect serves one tunnel connection; connected reports whether the handshake succeeded.
+func (c *Client) connect(ctx context.Context) (connected bool, err error) {
+	header := http.Header{}
+	header.Set(SecretHeader, c.opts.Secret)
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, c.opts.ServerURL, header)
+	if err != nil {
+		if resp != nil {
+			return false, fmt.Errorf("%w (status %d)", err, resp.StatusCode)
+		}
+		return false, err
+	}
+	defer ws.Close()
+	c.log.Info("Tunnel connected", "server", c.opts.ServerURL, "target", c.target.String())
+
+	// Closing the socket unblocks ReadMessage once ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		var req Request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.log.Warn("Ignoring malformed tunnel frame", "error", err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := c.deliver(ctx, &req)
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := ws.WriteJSON(resp); err != nil {
+				c.log.Warn("Failed to answer tunnel request", "error", err, "path", req.Path)
+			}
+		}()
+	}
+}
+
+// deliver sends req to the local target and packs its answer.
+func (c *Client) deliver(ctx context.Context, req *Request) *Response {
+	target := *c.target
+	target.Path = strings.TrimRight(target.Path, "/") + req.Path
+	target.RawQuery = req.Query
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, target.String(), bytes.NewReader(req.Body))
+	if err != nil {
+		return &Response{ID: req.ID, Error: err.Error()}
+	}
+	for key, values := range req.Headers {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	if host := req.Headers.Get("Host"); host != "" {
+		httpReq.Host = host
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		c.log.Warn("Tunnel request to local target failed", "error", err, "method", req.Method, "path", req.Path)
+		return &Response{ID: req.ID, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Response{ID: req.ID, Error: err.Error()}
+	}
+	c.log.Info("Tunnel request relayed", "method", req.Method, "path", req.Path, "status", resp.StatusCode)
+	return &Response{ID: req.ID, Status: resp.StatusCode, Headers: resp.Header, Body: body}
+}
@@ -0,0 +1,253 @@
+// Package tunnel relays requests captured by a public ReqTap instance to a `reqtap tunnel`
+// client over a persistent WebSocket; the client sends them to a local ReqTap and returns its
+// answer, which the public instance passes on to the original sender.
+package tunnel
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/internal/logger"
+)
+
+// SecretHeader carries the tunnel secret when a client connects.
+const SecretHeader = "X-ReqTap-Tunnel-Secret"
+
+// pingInterval keeps idle tunnels open through proxies and load balancers.
+const pingInterval = 30 * time.Second
+
+// ErrNoClient is returned by Hub.RoundTrip while no tunnel client is connected.
+var ErrNoClient = errors.New("no tunnel client connected")
+
+// Request is a captured request sent to the tunnel client.
+type Request struct {
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+}
+
+// Response is the tunnel client's answer to a Request; Error is set when the local instance
+// could not be reached.
+type Response struct {
+	ID      string      `json:"id"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Hub accepts tunnel clients on the public instance. A newly connected client replaces the
+// previous one.
+type Hub struct {
+	secret  string
+	timeout time.Duration
+	log     logger.Logger
+	seq     atomic.Uint64
+
+	mu     sync.Mutex
+	client *conn
+}
+
+type conn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *Response
+	closed  bool
+}
+
+// NewHub returns a hub authenticating clients with secret; timeout bounds each RoundTrip.
+func NewHub(secret string, timeout time.Duration, log logger.Logger) *Hub {
+	return &Hub{secret: secret, timeout: timeout, log: log}
+}
+
+var upgrader = websocket.Upgrader{
+	// Clients are command line tools authenticated by the secret, not browsers
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// ServeHTTP upgrades an authenticated client connection and serves it until it is closed.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), []byte(h.secret)) != 1 {
+		http.Error(w, "invalid tunnel secret", http.StatusUnauthorized)
+		return
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.log.Warn("Tunnel client handshake failed", "error", err, "remote_addr", r.RemoteAddr)
+		return
+	}
+	c := &conn{ws: ws, pending: make(map[string]chan *Response)}
+
+	h.mu.Lock()
+	previous := h.client
+	h.client = c
+	h.mu.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+	h.log.Info("Tunnel client connected", "remote_addr", r.RemoteAddr)
+
+	done := make(chan struct{})
+	go c.keepAlive(done)
+	c.readResponses()
+	close(done)
+	c.close()
+
+	h.mu.Lock()
+	if h.client == c {
+		h.client = nil
+	}
+	h.mu.Unlock()
+	h.log.Info("Tunnel client disconnected", "remote_addr", r.RemoteAddr)
+}
+
+// Connected reports whether a tunnel client is connected.
+func (h *Hub) Connected() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.client != nil
+}
+
+// Close disconnects the current tunnel client.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	c := h.client
+	h.client = nil
+	h.mu.Unlock()
+	if c != nil {
+		c.close()
+	}
+}
+
+// RoundTrip sends req to the connected client and waits for its answer.
+func (h *Hub) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	h.mu.Lock()
+	c := h.client
+	h.mu.Unlock()
+	if c == nil {
+		return nil, ErrNoClient
+	}
+
+	// The ID matches the client's answer to this request
+	req.ID = strconv.FormatUint(h.seq.Add(1), 10)
+	wait := make(chan *Response, 1)
+	if !c.register(req.ID, wait) {
+		return nil, ErrNoClient
+	}
+	defer c.unregister(req.ID)
+	if err := c.write(req); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	select {
+	case resp, ok := <-wait:
+		if !ok {
+			return nil, ErrNoClient
+		}
+		if resp.Error != "" {
+			return nil, errors.New(resp.Error)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *conn) readResponses() {
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var resp Response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			continue
+		}
+		c.mu.Lock()
+		wait := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if wait != nil {
+			wait <- &resp
+		}
+	}
+}
+
+func (c *conn) keepAlive(done <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			c.writeMu.Unlock()
+			if err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) register(id string, wait chan *Response) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.pending[id] = wait
+	return true
+}
+
+func (c *conn) unregister(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *conn) write(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteJSON(v)
+}
+
+// close drops the connection and fails every request still waiting for an answer.
+func (c *conn) close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	c.ws.Close()
+	for _, wait := range pending {
+		close(wait)
+	}
+}
@@ -0,0 +1,91 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestTunnelRelaysToLocalTarget(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seen", r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("X-Signature"))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(append([]byte("local:"), body...))
+	}))
+	defer local.Close()
+
+	hub := NewHub("s3cret", 5*time.Second, noopLogger{})
+	public := httptest.NewServer(hub)
+	defer public.Close()
+	defer hub.Close()
+
+	if _, err := hub.RoundTrip(context.Background(), &Request{Method: http.MethodGet, Path: "/"}); err != ErrNoClient {
+		t.Fatalf("expected ErrNoClient without a client, got %v", err)
+	}
+
+	client, err := NewClient(ClientOptions{ServerURL: public.URL, Secret: "s3cret", TargetURL: local.URL, Timeout: time.Second}, noopLogger{})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("tunnel client did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := hub.RoundTrip(context.Background(), &Request{
+		Method:  http.MethodPost,
+		Path:    "/reqtap/hook",
+		Query:   "a=1",
+		Headers: http.Header{"X-Signature": {"sig"}},
+		Body:    []byte("payload"),
+	})
+	if err != nil {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if resp.Status != http.StatusAccepted || string(resp.Body) != "local:payload" {
+		t.Fatalf("unexpected response: %d %q", resp.Status, resp.Body)
+	}
+	if seen := resp.Headers.Get("X-Seen"); seen != "POST /reqtap/hook?a=1 sig" {
+		t.Fatalf("local target saw %q", seen)
+	}
+}
+
+func TestHubRejectsWrongSecret(t *testing.T) {
+	hub := NewHub("s3cret", time.Second, noopLogger{})
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/reqtap-tunnel", nil)
+	req.Header.Set(SecretHeader, "guess")
+	hub.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || hub.Connected() {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+}
+
+func TestNewClientValidatesURLs(t *testing.T) {
+	if _, err := NewClient(ClientOptions{ServerURL: "ftp://example.com", TargetURL: "http://127.0.0.1:1"}, noopLogger{}); err == nil || !strings.Contains(err.Error(), "ws(s)") {
+		t.Fatalf("expected scheme error, got %v", err)
+	}
+	if _, err := NewClient(ClientOptions{ServerURL: "https://example.com/t", TargetURL: "127.0.0.1"}, noopLogger{}); err == nil {
+		t.Fatal("expected target error")
+	}
+}
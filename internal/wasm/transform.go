@@ -0,0 +1,148 @@
+// Package wasm runs user-provided WebAssembly transforms over captured requests.
+//
+// A module implements the transform ABI by exporting:
+//
+//	memory                                   linear memory
+//	alloc(size i32) -> i32                   returns a buffer of size bytes for the input
+//	transform(ptr i32, len i32) -> i64       returns (out_ptr << 32 | out_len)
+//
+// The input is the request as JSON (the same shape as the web API); the output is
+// the rewritten request JSON, or an empty result (out_len == 0) to drop the request.
+// Modules get WASI without filesystem, network or environment access; memory is capped
+// by memory_limit_mb and each invocation is aborted once its timeout expires.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// wasmPagesPerMB is the number of 64 KiB pages in one MiB.
+const wasmPagesPerMB = 16
+
+// Transformer is a compiled transform module; every call runs in a fresh instance.
+type Transformer struct {
+	name    string
+	timeout time.Duration
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// Load compiles the module at cfg.Path.
+func Load(ctx context.Context, cfg config.WasmTransformConfig) (*Transformer, error) {
+	binary, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read wasm transform %s: %w", cfg.Name, err)
+	}
+	return Compile(ctx, cfg, binary)
+}
+
+// Compile builds a Transformer from an in-memory module.
+func Compile(ctx context.Context, cfg config.WasmTransformConfig, binary []byte) (*Transformer, error) {
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if cfg.MemoryLimitMB > 0 {
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(uint32(cfg.MemoryLimitMB * wasmPagesPerMB))
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("wasm transform %s: %w", cfg.Name, err)
+	}
+
+	module, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("compile wasm transform %s: %w", cfg.Name, err)
+	}
+	for _, export := range []string{"alloc", "transform"} {
+		if _, ok := module.ExportedFunctions()[export]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("wasm transform %s must export %q", cfg.Name, export)
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+	return &Transformer{name: cfg.Name, timeout: timeout, runtime: runtime, module: module}, nil
+}
+
+// Name returns the configured transform name.
+func (t *Transformer) Name() string {
+	return t.name
+}
+
+// Transform runs the module; drop reports that the module discarded the request.
+func (t *Transformer) Transform(ctx context.Context, data *request.RequestData) (result *request.RequestData, drop bool, err error) {
+	input, err := json.Marshal(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	// Anonymous instances allow concurrent calls and never share state between requests.
+	instance, err := t.runtime.InstantiateModule(ctx, t.module,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, false, t.wrap(ctx, err)
+	}
+	defer instance.Close(context.Background())
+
+	memory := instance.Memory()
+	if memory == nil {
+		return nil, false, fmt.Errorf("wasm transform %s does not export memory", t.name)
+	}
+
+	res, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, false, t.wrap(ctx, err)
+	}
+	inPtr := uint32(res[0])
+	if !memory.Write(inPtr, input) {
+		return nil, false, fmt.Errorf("wasm transform %s: alloc returned an out of range buffer", t.name)
+	}
+
+	res, err = instance.ExportedFunction("transform").Call(ctx, uint64(inPtr), uint64(len(input)))
+	if err != nil {
+		return nil, false, t.wrap(ctx, err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	if outLen == 0 {
+		return nil, true, nil
+	}
+	output, ok := memory.Read(outPtr, outLen)
+	if !ok {
+		return nil, false, fmt.Errorf("wasm transform %s: output is out of memory range", t.name)
+	}
+
+	result = &request.RequestData{}
+	if err := json.Unmarshal(output, result); err != nil {
+		return nil, false, fmt.Errorf("wasm transform %s returned invalid JSON: %w", t.name, err)
+	}
+	return result, false, nil
+}
+
+// Close releases the runtime.
+func (t *Transformer) Close(ctx context.Context) error {
+	return t.runtime.Close(ctx)
+}
+
+func (t *Transformer) wrap(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("wasm transform %s exceeded its %s time limit", t.name, t.timeout)
+	}
+	return fmt.Errorf("wasm transform %s: %w", t.name, err)
+}
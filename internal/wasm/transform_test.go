@@ -0,0 +1,144 @@
+package wasm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Bodies of the exported transform(ptr, len) -> i64 function used by the tests.
+var (
+	// identity returns the input buffer unchanged.
+	identityBody = []byte{0x00, 0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84, 0x0b}
+	// dropBody returns an empty result.
+	dropBody = []byte{0x00, 0x42, 0x00, 0x0b}
+	// spinBody never returns.
+	spinBody = []byte{0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x42, 0x00, 0x0b}
+)
+
+// rewrittenJSON is placed at offset 0 by the data section of constModule.
+const rewrittenJSON = `{"method":"PUT","path":"/rewritten","headers":{"X-Wasm":["yes"]}}`
+
+func leb(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n != 0 {
+			out = append(out, b|0x80)
+			continue
+		}
+		return append(out, b)
+	}
+}
+
+// sleb encodes a non-negative signed LEB128 immediate.
+func sleb(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 && b&0x40 == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func section(id byte, payload ...byte) []byte {
+	return append(append([]byte{id}, leb(len(payload))...), payload...)
+}
+
+func name(s string) []byte {
+	return append(leb(len(s)), s...)
+}
+
+func body(code []byte) []byte {
+	return append(leb(len(code)), code...)
+}
+
+// buildModule assembles a module exporting memory, a bump allocator and transform.
+func buildModule(transform []byte, data string) []byte {
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1,
+		0x02,
+		0x60, 0x01, 0x7f, 0x01, 0x7f, // (i32) -> i32
+		0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, // (i32, i32) -> i64
+	)...)
+	module = append(module, section(3, 0x02, 0x00, 0x01)...)
+	module = append(module, section(5, 0x01, 0x00, 0x01)...)
+	module = append(module, section(6, 0x01, 0x7f, 0x01, 0x41, 0x80, 0x08, 0x0b)...) // mut i32 heap = 1024
+
+	exports := []byte{0x03}
+	exports = append(append(exports, name("memory")...), 0x02, 0x00)
+	exports = append(append(exports, name("alloc")...), 0x00, 0x00)
+	exports = append(append(exports, name("transform")...), 0x00, 0x01)
+	module = append(module, section(7, exports...)...)
+
+	alloc := []byte{0x00, 0x23, 0x00, 0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, 0x0b}
+	code := append([]byte{0x02}, body(alloc)...)
+	code = append(code, body(transform)...)
+	module = append(module, section(10, code...)...)
+
+	if data != "" {
+		segment := append([]byte{0x01, 0x00, 0x41, 0x00, 0x0b}, name(data)...)
+		module = append(module, section(11, segment...)...)
+	}
+	return module
+}
+
+func compileTest(t *testing.T, transform []byte, data string, timeout time.Duration) *Transformer {
+	t.Helper()
+	tr, err := Compile(context.Background(), config.WasmTransformConfig{Name: "test", MemoryLimitMB: 1, Timeout: timeout}, buildModule(transform, data))
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	t.Cleanup(func() { tr.Close(context.Background()) })
+	return tr
+}
+
+func testRequest() *request.RequestData {
+	return &request.RequestData{ID: "REQ1", Method: http.MethodPost, Path: "/hook", Headers: http.Header{"X-Test": []string{"1"}}, Body: []byte("hi")}
+}
+
+func TestTransformIdentityAndRewrite(t *testing.T) {
+	identity := compileTest(t, identityBody, "", time.Second)
+	result, drop, err := identity.Transform(context.Background(), testRequest())
+	if err != nil || drop {
+		t.Fatalf("unexpected outcome: drop=%v err=%v", drop, err)
+	}
+	if result.Path != "/hook" || string(result.Body) != "hi" || result.Headers.Get("X-Test") != "1" {
+		t.Fatalf("identity transform changed the request: %#v", result)
+	}
+
+	rewrite := compileTest(t, append([]byte{0x00, 0x42}, append(sleb(len(rewrittenJSON)), 0x0b)...), rewrittenJSON, time.Second)
+	result, _, err = rewrite.Transform(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	if result.Method != http.MethodPut || result.Path != "/rewritten" || result.Headers.Get("X-Wasm") != "yes" {
+		t.Fatalf("unexpected rewritten request: %#v", result)
+	}
+}
+
+func TestTransformDropAndLimits(t *testing.T) {
+	drop := compileTest(t, dropBody, "", time.Second)
+	if _, dropped, err := drop.Transform(context.Background(), testRequest()); err != nil || !dropped {
+		t.Fatalf("expected drop, got dropped=%v err=%v", dropped, err)
+	}
+
+	spin := compileTest(t, spinBody, "", 50*time.Millisecond)
+	_, _, err := spin.Transform(context.Background(), testRequest())
+	if err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Fatalf("expected time limit error, got %v", err)
+	}
+
+	if _, err := Compile(context.Background(), config.WasmTransformConfig{Name: "bad"}, []byte("not wasm")); err == nil {
+		t.Fatal("expected compile error for invalid module")
+	}
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/parquet"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+const (
+	defaultAggregateInterval = time.Hour
+	minAggregateInterval     = time.Second
+	// maxAggregateIntervals bounds the rows of one export, empty intervals included
+	maxAggregateIntervals = 100000
+)
+
+// ErrTooManyIntervals reports an aggregate export that would exceed maxAggregateIntervals rows.
+var ErrTooManyIntervals = errors.New("too many intervals; use a longer interval or a shorter range")
+
+// AggregateColumns are the columns of an aggregate export, in order.
+var AggregateColumns = []string{"interval_start", "requests", "errors", "avg_size_bytes", "forwards", "avg_forward_latency_ms"}
+
+// CollectAggregates groups the requests matching opts per interval and fills the intervals without
+// requests in with zero rows, from opts.Since (or the first request) to opts.Until (or the last).
+func CollectAggregates(store storage.Store, opts ListOptions, interval time.Duration) ([]*storage.IntervalAggregate, error) {
+	aggStore, ok := store.(storage.AggregateStore)
+	if !ok {
+		return nil, fmt.Errorf("the storage backend cannot aggregate requests")
+	}
+	if interval < minAggregateInterval {
+		return nil, fmt.Errorf("interval must be at least %s", minAggregateInterval)
+	}
+	opts.Limit, opts.Offset = 0, 0
+	rows, err := aggStore.Aggregate(opts, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Intervals are aligned to the Unix epoch like the storage groups them
+	first := time.Unix(0, opts.Since.UnixNano()/int64(interval)*int64(interval)).UTC()
+	last := opts.Until.UTC()
+	if opts.Since.IsZero() {
+		if len(rows) == 0 {
+			return rows, nil
+		}
+		first = rows[0].Start
+	}
+	if opts.Until.IsZero() {
+		if len(rows) == 0 {
+			return rows, nil
+		}
+		last = rows[len(rows)-1].Start.Add(interval)
+	}
+	if last.Sub(first)/interval > maxAggregateIntervals {
+		return nil, fmt.Errorf("%w (more than %d of %s)", ErrTooManyIntervals, maxAggregateIntervals, interval)
+	}
+	filled := make([]*storage.IntervalAggregate, 0, int(last.Sub(first)/interval)+1)
+	next := 0
+	for start := first; start.Before(last); start = start.Add(interval) {
+		if next < len(rows) && rows[next].Start.Equal(start) {
+			filled = append(filled, rows[next])
+			next++
+			continue
+		}
+		filled = append(filled, &storage.IntervalAggregate{Start: start})
+	}
+	return filled, nil
+}
+
+// ExportAggregates writes aggregate rows as csv or parquet and returns the content type and file
+// extension of the format.
+func ExportAggregates(w io.Writer, rows []*storage.IntervalAggregate, format string) (string, string, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return "text/csv", "csv", writeAggregatesCSV(w, rows)
+	case "parquet":
+		return "application/vnd.apache.parquet", "parquet", writeAggregatesParquet(w, rows)
+	default:
+		return "", "", fmt.Errorf("unsupported aggregate export format: %s", format)
+	}
+}
+
+func writeAggregatesCSV(w io.Writer, rows []*storage.IntervalAggregate) error {
+	bw := bufio.NewWriter(w)
+	csvWriter := csv.NewWriter(bw)
+	if err := csvWriter.Write(AggregateColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err := csvWriter.Write([]string{
+			row.Start.Format(time.RFC3339),
+			strconv.Itoa(row.Requests),
+			strconv.Itoa(row.Errors),
+			strconv.FormatFloat(row.AvgSizeBytes, 'f', 2, 64),
+			strconv.Itoa(row.Forwards),
+			strconv.FormatFloat(row.AvgForwardLatencyMs, 'f', 2, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeAggregatesParquet(w io.Writer, rows []*storage.IntervalAggregate) error {
+	columns := []parquet.Column{
+		{Name: AggregateColumns[0], Kind: parquet.TimestampMillis},
+		{Name: AggregateColumns[1], Kind: parquet.Int64},
+		{Name: AggregateColumns[2], Kind: parquet.Int64},
+		{Name: AggregateColumns[3], Kind: parquet.Double},
+		{Name: AggregateColumns[4], Kind: parquet.Int64},
+		{Name: AggregateColumns[5], Kind: parquet.Double},
+	}
+	for _, row := range rows {
+		columns[0].Int64 = append(columns[0].Int64, row.Start.UnixMilli())
+		columns[1].Int64 = append(columns[1].Int64, int64(row.Requests))
+		columns[2].Int64 = append(columns[2].Int64, int64(row.Errors))
+		columns[3].Double = append(columns[3].Double, row.AvgSizeBytes)
+		columns[4].Int64 = append(columns[4].Int64, int64(row.Forwards))
+		columns[5].Double = append(columns[5].Double, row.AvgForwardLatencyMs)
+	}
+	return parquet.Write(w, columns)
+}
+
+// handleExportAggregates exports per-interval request statistics instead of raw requests. It takes
+// the filters of /requests plus interval (a Go duration, default 1h) and format (csv or parquet).
+func (s *Service) handleExportAggregates(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if _, ok := s.store.(storage.AggregateStore); !ok {
+		http.Error(w, "aggregates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !s.cfg.Export.Enable {
+		http.Error(w, "Export disabled", http.StatusForbidden)
+		return
+	}
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeExport) {
+			http.Error(w, "Forbidden: export requires admin role or the export scope", http.StatusForbidden)
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "parquet" {
+		http.Error(w, fmt.Sprintf("Unsupported aggregate export format: %s", format), http.StatusBadRequest)
+		return
+	}
+	interval := defaultAggregateInterval
+	if raw := strings.TrimSpace(query.Get("interval")); raw != "" {
+		var err error
+		if interval, err = time.ParseDuration(raw); err != nil || interval < minAggregateInterval {
+			http.Error(w, "interval must be a duration such as 5m or 1h", http.StatusBadRequest)
+			return
+		}
+	}
+	opts, err := s.listFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := CollectAggregates(s.store, opts, interval)
+	if errors.Is(err, ErrTooManyIntervals) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to aggregate requests", "error", err)
+		http.Error(w, "Failed to aggregate requests", http.StatusInternalServerError)
+		return
+	}
+	// Rendered up front so that a failure can still answer with an error status
+	var buf bytes.Buffer
+	contentType, ext, err := ExportAggregates(&buf, rows, format)
+	if err != nil {
+		s.logger.Error("Aggregate export failed", "error", err)
+		http.Error(w, "Failed to export aggregates", http.StatusInternalServerError)
+		return
+	}
+	filename := fmt.Sprintf("reqtap_aggregates_%d.%s", time.Now().Unix(), ext)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+	s.audit(r, storage.AuditEntry{Action: auditExport, Success: true, Details: map[string]string{
+		"format": format, "aggregates": interval.String(), "query": r.URL.RawQuery,
+	}})
+}
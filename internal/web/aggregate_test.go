@@ -0,0 +1,91 @@
+package web
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestExportAggregates(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{time.Minute, 2 * time.Minute, 3*time.Hour + time.Minute} {
+		_, err := store.Record(&request.RequestData{
+			ID:        fmt.Sprintf("agg-%d", i),
+			Timestamp: base.Add(offset),
+			Method:    http.MethodPost,
+			Path:      "/hook",
+			Body:      []byte("payload"),
+		})
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	rows, err := CollectAggregates(store, ListOptions{}, time.Hour)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(rows) != 4 || rows[0].Requests != 2 || rows[1].Requests != 0 || !rows[2].Start.Equal(base.Add(2*time.Hour)) || rows[3].Requests != 1 {
+		t.Fatalf("expected 4 hourly rows with the empty hours filled in, got %d", len(rows))
+	}
+	if _, err := CollectAggregates(store, ListOptions{Since: base.Add(-24 * 365 * time.Hour)}, time.Second); err == nil {
+		t.Fatal("expected a range of too many intervals to be rejected")
+	}
+
+	cfg := &config.WebConfig{
+		Enable:    true,
+		Path:      "/web",
+		AdminPath: "/api",
+		Export:    config.WebExportConfig{Enable: true, Formats: []string{"json"}},
+	}
+	svc := NewService(cfg, store, noopLogger{})
+	defer svc.Close()
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/export/aggregates?interval=30m&from=2026-03-01T10:00:00Z&to=2026-03-01T11:00:00Z", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("expected a csv export, got %d %s", rr.Code, rr.Body.String())
+	}
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := [][]string{
+		AggregateColumns,
+		{"2026-03-01T10:00:00Z", "2", "0", "7.00", "0", "0.00"},
+		{"2026-03-01T10:30:00Z", "0", "0", "0.00", "0", "0.00"},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Fatalf("unexpected csv export:\n%v", records)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/export/aggregates?format=parquet", nil))
+	if body := rr.Body.String(); rr.Code != http.StatusOK || !strings.HasPrefix(body, "PAR1") || !strings.HasSuffix(body, "PAR1") {
+		t.Fatalf("expected a parquet file, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/export/aggregates?interval=soon", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid interval to be rejected, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// maxNoteLength caps the free-text note of a request, counted in characters.
+const maxNoteLength = 4000
+
+// annotationPatch is the body of PATCH /requests/{id}; omitted fields are left unchanged.
+type annotationPatch struct {
+	Tags []string `json:"tags"`
+	Note *string  `json:"note"`
+}
+
+// requireWrite answers the request and returns false unless the caller may change tags, notes,
+// pins, claims and comments.
+func (s *Service) requireWrite(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeWrite) {
+			http.Error(w, "Forbidden: changing requests requires the write scope", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// handleAnnotate replaces the tags and/or note of a request.
+func (s *Service) handleAnnotate(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !s.requireWrite(w, r) {
+		return
+	}
+
+	var patch annotationPatch
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4*maxNoteLength+64*1024)).Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if patch.Tags == nil && patch.Note == nil {
+		http.Error(w, "tags or note is required", http.StatusBadRequest)
+		return
+	}
+	if patch.Tags != nil {
+		tags, err := storage.NormalizeTags(patch.Tags)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		patch.Tags = tags
+	}
+	if patch.Note != nil {
+		note := strings.TrimSpace(*patch.Note)
+		if utf8.RuneCountInString(note) > maxNoteLength {
+			http.Error(w, "note is too long", http.StatusBadRequest)
+			return
+		}
+		patch.Note = &note
+	}
+
+	requestID := mux.Vars(r)["id"]
+	err := s.store.Annotate(requestID, patch.Tags, patch.Note)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		s.logger.Error("Failed to annotate request", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to annotate request", http.StatusInternalServerError)
+		return
+	}
+
+	stored, err := s.store.Get(requestID)
+	if err != nil || stored == nil {
+		s.logger.Error("Failed to reload annotated request", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to annotate request", http.StatusInternalServerError)
+		return
+	}
+	s.NotifyAnnotation(stored)
+	s.respondJSON(w, http.StatusOK, stored)
+}
+
+// tagFilter reads the tag query parameter, repeated or comma-separated.
+func tagFilter(r *http.Request) ([]string, error) {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return storage.NormalizeTags(tags)
+}
+
+// NotifyAnnotation pushes the new tags and note of a request to websocket clients.
+func (s *Service) NotifyAnnotation(stored *StoredRequest) {
+	if s == nil || !s.cfg.Enable || stored == nil {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "annotation",
+		"data": map[string]interface{}{
+			"request_id": stored.ID,
+			"tags":       stored.Tags,
+			"note":       stored.Note,
+		},
+	})
+}
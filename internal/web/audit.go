@@ -0,0 +1,166 @@
+package web
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// Actions recorded in the audit log
+const (
+	auditLogin          = "login"
+	auditLoginFailed    = "login_failed"
+	auditLoginLink      = "login_link"
+	auditExport         = "export"
+	auditImport         = "import"
+	auditReplay         = "replay"
+	auditReforward      = "reforward"
+	auditConfigReload   = "config_reload"
+	auditMockRuleSave   = "mock_rule_save"
+	auditMockRuleDelete = "mock_rule_delete"
+	auditTokenCreate    = "token_create"
+	auditTokenRevoke    = "token_revoke"
+	auditCapturePause   = "capture_pause"
+	auditCaptureResume  = "capture_resume"
+)
+
+// auditFileMu serializes the appends to web.audit.file, so that concurrent entries stay one per line
+var auditFileMu sync.Mutex
+
+// auditStore returns the store keeping the audit log, or nil when web.audit is off or the storage
+// driver does not support it.
+func (s *Service) auditStore() storage.AuditStore {
+	if !s.cfg.Audit.Enable {
+		return nil
+	}
+	auditStore, _ := s.store.(storage.AuditStore)
+	return auditStore
+}
+
+// audit appends an action of the caller of r to the audit log and web.audit.file. The actor is
+// taken from the session unless set; failures are logged and never fail the action itself.
+func (s *Service) audit(r *http.Request, entry storage.AuditEntry) {
+	auditStore := s.auditStore()
+	if auditStore == nil && s.cfg.Audit.File == "" {
+		return
+	}
+	if entry.Actor == "" {
+		entry.Actor = auditActor(s.sessionFromContext(r.Context()))
+	}
+	entry.RemoteAddr = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		entry.RemoteAddr = host
+	}
+	entry.Timestamp = time.Now().UTC()
+
+	if auditStore != nil {
+		if err := auditStore.RecordAudit(&entry); err != nil {
+			s.logger.Error("Failed to record audit entry", "action", entry.Action, "actor", entry.Actor, "error", err)
+		}
+	}
+	if s.cfg.Audit.File != "" {
+		if err := appendAuditFile(s.cfg.Audit.File, &entry); err != nil {
+			s.logger.Error("Failed to write audit file", "path", s.cfg.Audit.File, "action", entry.Action, "error", err)
+		}
+	}
+}
+
+// auditActor names the user of a session; API tokens are reported as "token:<name>" and callers
+// without a session, e.g. of an unknown login link, as "unknown"
+func auditActor(session *Session) string {
+	if session == nil {
+		return "unknown"
+	}
+	if strings.HasPrefix(session.ID, "token:") {
+		return session.ID
+	}
+	return session.Username
+}
+
+// appendAuditFile appends entry as a JSON line. The file is opened for every entry, so it can be
+// rotated or shipped away without a restart.
+func appendAuditFile(path string, entry *storage.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditFileMu.Lock()
+	defer auditFileMu.Unlock()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// handleAudit lists the audit log newest first, narrowed by action, actor, since and until
+// (RFC 3339) and paged with limit and offset.
+func (s *Service) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: the audit log requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+	auditStore := s.auditStore()
+	if auditStore == nil {
+		http.Error(w, "audit log unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := storage.AuditOptions{
+		Action: strings.TrimSpace(query.Get("action")),
+		Actor:  strings.TrimSpace(query.Get("actor")),
+		Limit:  defaultListLimit,
+	}
+	for key, target := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if value := query.Get(key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid "+key+": use RFC 3339, e.g. 2024-05-01T00:00:00Z", http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	for key, target := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if value := query.Get(key); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "invalid "+key, http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	if opts.Limit == 0 || opts.Limit > maxListLimit {
+		opts.Limit = maxListLimit
+	}
+
+	entries, total, err := auditStore.AuditEntries(opts)
+	if err != nil {
+		s.logger.Error("Failed to list audit entries", "error", err)
+		http.Error(w, "Failed to list audit entries", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []*storage.AuditEntry{}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+	})
+}
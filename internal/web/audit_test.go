@@ -0,0 +1,127 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(dir, "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	auditFile := filepath.Join(dir, "audit.log")
+	cfg := &config.WebConfig{
+		Enable:    true,
+		Path:      "/web",
+		AdminPath: "/api",
+		Export:    config.WebExportConfig{Enable: true, Formats: []string{"json"}},
+		Audit:     config.WebAuditConfig{Enable: true, File: auditFile},
+		Auth: config.WebAuthConfig{
+			Enable:         true,
+			SessionTimeout: time.Hour,
+			Users:          []config.WebUserConfig{{Username: "ops", Password: "plain", Role: "admin"}},
+			Tokens: []config.WebTokenConfig{
+				{Name: "reader", Token: "read-token-0123456789", Scopes: []string{"read"}},
+				{Name: "exporter", Token: "export-token-0123456789", Scopes: []string{"export"}},
+			},
+		},
+	}
+	svc := NewService(cfg, store, noopLogger{})
+	defer svc.Close()
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+	serve := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/api/auth/login", "", `{"username":"OPS","password":"wrong"}`); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the login to fail, got %d", rr.Code)
+	}
+	rr := serve(http.MethodPost, "/api/auth/login", "", `{"username":"ops","password":"plain"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the login to succeed, got %d", rr.Code)
+	}
+	var session string
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			session = cookie.Value
+		}
+	}
+	if rr := serve(http.MethodGet, "/api/export?format=json&method=POST", "export-token-0123456789", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected the export to succeed, got %d", rr.Code)
+	}
+
+	if rr := serve(http.MethodGet, "/api/audit", "read-token-0123456789", ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected the audit log to require admin, got %d", rr.Code)
+	}
+	rr = serve(http.MethodGet, "/api/audit", session, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected admins to read the audit log, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result struct {
+		Entries []storage.AuditEntry `json:"entries"`
+		Total   int                  `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 {
+		t.Fatalf("expected 3 entries, got %#v", result.Entries)
+	}
+	export, login, failed := result.Entries[0], result.Entries[1], result.Entries[2]
+	if export.Action != auditExport || export.Actor != "token:exporter" || export.Details["format"] != "json" || export.Details["query"] != "format=json&method=POST" {
+		t.Fatalf("unexpected export entry %#v", export)
+	}
+	if login.Action != auditLogin || login.Actor != "ops" || !login.Success || login.RemoteAddr != "192.0.2.1" {
+		t.Fatalf("unexpected login entry %#v", login)
+	}
+	if failed.Action != auditLoginFailed || failed.Actor != "ops" || failed.Success {
+		t.Fatalf("unexpected failed login entry %#v", failed)
+	}
+
+	if rr := serve(http.MethodGet, "/api/audit?action=login_failed", session, ""); !strings.Contains(rr.Body.String(), `"total":1`) {
+		t.Fatalf("expected the action filter to apply, got %s", rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/api/audit?since=yesterday", session, ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid since to be rejected, got %d", rr.Code)
+	}
+
+	file, err := os.Open(auditFile)
+	if err != nil {
+		t.Fatalf("expected the audit file to be written: %v", err)
+	}
+	defer file.Close()
+	var lines []storage.AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry storage.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 3 || lines[0].Action != auditLoginFailed || lines[2].Action != auditExport {
+		t.Fatalf("unexpected audit file entries %#v", lines)
+	}
+}
@@ -2,13 +2,33 @@ package web
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/password"
+)
+
+// API token scopes. Every token can read; write covers tags, notes, pins, claims and comments,
+// and admin implies the other scopes.
+const (
+	scopeRead   = "read"
+	scopeWrite  = "write"
+	scopeExport = "export"
+	scopeReplay = "replay"
+	scopeAdmin  = "admin"
+)
+
+// Token sources reported by TokenInfo.
+const (
+	tokenSourceConfig = "config"
+	tokenSourceAPI    = "api"
 )
 
 // Session describes an authenticated user session.
@@ -17,24 +37,54 @@ type Session struct {
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
 	ExpiresAt time.Time `json:"expires_at"`
+	// Scopes is set for sessions authenticated with an API token
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TokenInfo describes an API token without its value.
+type TokenInfo struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Source    string     `json:"source"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
 }
 
 // AuthManager performs credential validation and session management.
 type AuthManager struct {
 	enable bool
 
-	timeout  time.Duration
-	users    map[string]config.WebUserConfig
+	timeout time.Duration
+	users   map[string]config.WebUserConfig
+	// order keeps the configured order of users
+	order    []string
 	sessions map[string]*Session
-	mu       sync.RWMutex
+	// loginTokens are single-use tokens from IssueLoginToken, keyed by token
+	loginTokens map[string]loginToken
+	// tokens are the long-lived API tokens, keyed by the SHA-256 hash of their value
+	tokens map[string]TokenInfo
+	mu     sync.RWMutex
+}
+
+type loginToken struct {
+	username  string
+	expiresAt time.Time
 }
 
 // ErrInvalidCredential indicates username/password mismatch.
 var ErrInvalidCredential = errors.New("invalid username or password")
 
+// Errors returned by the API token operations.
+var (
+	ErrTokenExists     = errors.New("a token with this name already exists")
+	ErrTokenNotFound   = errors.New("token not found")
+	ErrTokenConfigured = errors.New("token is defined in the config file")
+)
+
 // NewAuthManager creates a new AuthManager from configuration.
 func NewAuthManager(cfg config.WebAuthConfig) *AuthManager {
 	users := make(map[string]config.WebUserConfig, len(cfg.Users))
+	var order []string
 	for _, user := range cfg.Users {
 		username := strings.ToLower(strings.TrimSpace(user.Username))
 		if username == "" {
@@ -42,15 +92,25 @@ func NewAuthManager(cfg config.WebAuthConfig) *AuthManager {
 		}
 		sanitized := user
 		sanitized.Role = strings.ToLower(sanitized.Role)
+		if _, seen := users[username]; !seen {
+			order = append(order, username)
+		}
 		users[username] = sanitized
 	}
 
-	return &AuthManager{
-		enable:   cfg.Enable,
-		timeout:  cfg.SessionTimeout,
-		users:    users,
-		sessions: make(map[string]*Session),
+	a := &AuthManager{
+		enable:      cfg.Enable,
+		timeout:     cfg.SessionTimeout,
+		users:       users,
+		order:       order,
+		sessions:    make(map[string]*Session),
+		loginTokens: make(map[string]loginToken),
+		tokens:      make(map[string]TokenInfo),
+	}
+	for _, token := range cfg.Tokens {
+		_ = a.AddToken(TokenInfo{Name: token.Name, Scopes: token.Scopes, Source: tokenSourceConfig}, HashToken(token.Token))
 	}
+	return a
 }
 
 // Enabled indicates whether authentication is active.
@@ -72,10 +132,69 @@ func (a *AuthManager) Login(username, password string) (*Session, error) {
 
 	username = strings.ToLower(strings.TrimSpace(username))
 	user, ok := a.users[username]
-	if !ok || user.Password != password {
+	if !ok || !checkPassword(user, password) {
+		return nil, ErrInvalidCredential
+	}
+
+	return a.newSession(user), nil
+}
+
+// IssueLoginToken creates a token that signs in as username once, until ttl elapses. An empty
+// username picks the first admin user, or the first user when there is no admin.
+func (a *AuthManager) IssueLoginToken(username string, ttl time.Duration) (string, error) {
+	if !a.Enabled() {
+		return "", errors.New("authentication is disabled")
+	}
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		username = a.defaultLoginUser()
+	}
+	if _, ok := a.users[username]; !ok {
+		return "", ErrInvalidCredential
+	}
+
+	token := randomToken()
+	a.mu.Lock()
+	a.loginTokens[token] = loginToken{username: username, expiresAt: time.Now().Add(ttl)}
+	a.mu.Unlock()
+	return token, nil
+}
+
+// RedeemLoginToken exchanges a login token for a new session; every token works only once.
+func (a *AuthManager) RedeemLoginToken(token string) (*Session, error) {
+	if !a.Enabled() {
 		return nil, ErrInvalidCredential
 	}
+	token = strings.TrimSpace(token)
 
+	a.mu.Lock()
+	issued, ok := a.loginTokens[token]
+	delete(a.loginTokens, token)
+	a.mu.Unlock()
+
+	if !ok || time.Now().After(issued.expiresAt) {
+		return nil, ErrInvalidCredential
+	}
+	user, ok := a.users[issued.username]
+	if !ok {
+		return nil, ErrInvalidCredential
+	}
+	return a.newSession(user), nil
+}
+
+func (a *AuthManager) defaultLoginUser() string {
+	for _, name := range a.order {
+		if a.users[name].Role == roleAdmin {
+			return name
+		}
+	}
+	if len(a.order) > 0 {
+		return a.order[0]
+	}
+	return ""
+}
+
+func (a *AuthManager) newSession(user config.WebUserConfig) *Session {
 	session := &Session{
 		ID:        randomToken(),
 		Username:  user.Username,
@@ -87,7 +206,7 @@ func (a *AuthManager) Login(username, password string) (*Session, error) {
 	a.sessions[session.ID] = session
 	a.mu.Unlock()
 
-	return session, nil
+	return session
 }
 
 // Validate finds a session by token and ensures it's not expired.
@@ -111,7 +230,7 @@ func (a *AuthManager) Validate(token string) (*Session, error) {
 	a.mu.RUnlock()
 
 	if !ok {
-		return nil, ErrInvalidCredential
+		return a.tokenSession(token)
 	}
 
 	if time.Now().After(session.ExpiresAt) {
@@ -156,6 +275,115 @@ func (a *AuthManager) Cleanup() {
 			delete(a.sessions, token)
 		}
 	}
+	for token, issued := range a.loginTokens {
+		if now.After(issued.expiresAt) {
+			delete(a.loginTokens, token)
+		}
+	}
+}
+
+// HashToken returns the hex SHA-256 hash API tokens are stored and looked up by.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddToken registers an API token by the hash of its value.
+func (a *AuthManager) AddToken(info TokenInfo, hash string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, existing := range a.tokens {
+		if existing.Name == info.Name {
+			return ErrTokenExists
+		}
+	}
+	a.tokens[hash] = info
+	return nil
+}
+
+// CreateToken issues a new API token and returns its value, which is not kept anywhere.
+func (a *AuthManager) CreateToken(name string, scopes []string, createdBy string) (string, TokenInfo, error) {
+	value := "rqt_" + randomToken() + randomToken()
+	now := time.Now().UTC()
+	info := TokenInfo{Name: name, Scopes: scopes, Source: tokenSourceAPI, CreatedBy: createdBy, CreatedAt: &now}
+	if err := a.AddToken(info, HashToken(value)); err != nil {
+		return "", TokenInfo{}, err
+	}
+	return value, info, nil
+}
+
+// RevokeToken removes an API token created through the API.
+func (a *AuthManager) RevokeToken(name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for hash, token := range a.tokens {
+		if token.Name != name {
+			continue
+		}
+		if token.Source == tokenSourceConfig {
+			return ErrTokenConfigured
+		}
+		delete(a.tokens, hash)
+		return nil
+	}
+	return ErrTokenNotFound
+}
+
+// Tokens lists the API tokens by name.
+func (a *AuthManager) Tokens() []TokenInfo {
+	a.mu.RLock()
+	result := make([]TokenInfo, 0, len(a.tokens))
+	for _, token := range a.tokens {
+		result = append(result, token)
+	}
+	a.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// tokenSession authenticates an API token; token sessions are not stored and never expire.
+func (a *AuthManager) tokenSession(value string) (*Session, error) {
+	a.mu.RLock()
+	token, ok := a.tokens[HashToken(value)]
+	a.mu.RUnlock()
+	if !ok {
+		return nil, ErrInvalidCredential
+	}
+	role := roleViewer
+	if containsScope(token.Scopes, scopeAdmin) {
+		role = roleAdmin
+	}
+	return &Session{
+		ID:       "token:" + token.Name,
+		Username: token.Name,
+		Role:     role,
+		Scopes:   append([]string{}, token.Scopes...),
+	}, nil
+}
+
+// allows reports whether the session may use scope. Console users may read, write and replay
+// and admins may do everything; API tokens can read and use the scopes they were issued with.
+func (s *Session) allows(scope string) bool {
+	if s == nil {
+		return false
+	}
+	if s.Scopes == nil {
+		switch scope {
+		case scopeRead, scopeWrite, scopeReplay:
+			return true
+		}
+		return strings.EqualFold(s.Role, roleAdmin)
+	}
+	return scope == scopeRead || containsScope(s.Scopes, scope) || containsScope(s.Scopes, scopeAdmin)
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, candidate := range scopes {
+		if candidate == scope {
+			return true
+		}
+	}
+	return false
 }
 
 func randomToken() string {
@@ -165,3 +393,12 @@ func randomToken() string {
 	}
 	return hex.EncodeToString(buf)
 }
+
+// checkPassword prefers the configured hash and falls back to a constant-time plaintext comparison.
+func checkPassword(user config.WebUserConfig, candidate string) bool {
+	if user.PasswordHash != "" {
+		ok, err := password.Verify(user.PasswordHash, candidate)
+		return err == nil && ok
+	}
+	return subtle.ConstantTimeCompare([]byte(user.Password), []byte(candidate)) == 1
+}
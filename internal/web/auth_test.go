@@ -0,0 +1,185 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/password"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestLoginAcceptsPasswordHashes(t *testing.T) {
+	hash, err := password.Hash("s3cret!", password.Argon2id)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	auth := NewAuthManager(config.WebAuthConfig{
+		Enable:         true,
+		SessionTimeout: time.Hour,
+		Users: []config.WebUserConfig{
+			{Username: "admin", PasswordHash: hash, Role: "admin"},
+			{Username: "viewer", Password: "plain", Role: "viewer"},
+		},
+	})
+
+	if _, err := auth.Login("admin", "s3cret!"); err != nil {
+		t.Fatalf("expected the hashed password to log in, got %v", err)
+	}
+	if _, err := auth.Login("admin", hash); err != ErrInvalidCredential {
+		t.Fatalf("the hash itself must not be accepted as a password, got %v", err)
+	}
+	if _, err := auth.Login("viewer", "plain"); err != nil {
+		t.Fatalf("expected plaintext passwords to keep working, got %v", err)
+	}
+	if _, err := auth.Login("viewer", "plainx"); err != ErrInvalidCredential {
+		t.Fatalf("expected a mismatch, got %v", err)
+	}
+}
+
+func TestLoginLinkWorksOnce(t *testing.T) {
+	cfg := &config.WebConfig{
+		Enable: true,
+		Path:   "/web",
+		Auth: config.WebAuthConfig{
+			Enable:         true,
+			SessionTimeout: time.Hour,
+			Users: []config.WebUserConfig{
+				{Username: "viewer", Password: "plain", Role: "viewer"},
+				{Username: "ops", Password: "plain", Role: "admin"},
+			},
+			LoginLink: config.WebLoginLinkConfig{Enable: true, TTL: time.Minute},
+		},
+	}
+	svc := &Service{cfg: cfg, logger: noopLogger{}, auth: NewAuthManager(cfg.Auth)}
+
+	link, expires, err := svc.LoginLink()
+	if err != nil || !strings.HasPrefix(link, "/web/login/link?token=") || time.Until(expires) <= 0 {
+		t.Fatalf("unexpected login link %q (expires %s): %v", link, expires, err)
+	}
+
+	rec := httptest.NewRecorder()
+	svc.handleLoginLink(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/web/" {
+		t.Fatalf("expected a redirect into the console, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookieName {
+		t.Fatalf("expected a session cookie, got %v", cookies)
+	}
+	session, err := svc.auth.Validate(cookies[0].Value)
+	if err != nil || session.Username != "ops" || session.Role != roleAdmin {
+		t.Fatalf("expected an admin session for the first admin user, got %+v (%v)", session, err)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.handleLoginLink(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Header().Get("Location") != "/web/login" || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected a reused link to fall back to the login page, got %q", rec.Header().Get("Location"))
+	}
+}
+
+func TestAPITokensScopesAndPersistence(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	cfg := &config.WebConfig{
+		Enable:    true,
+		Path:      "/web",
+		AdminPath: "/api",
+		Export:    config.WebExportConfig{Enable: true, Formats: []string{"json"}},
+		Auth: config.WebAuthConfig{
+			Enable:         true,
+			SessionTimeout: time.Hour,
+			Users:          []config.WebUserConfig{{Username: "ops", Password: "plain", Role: "admin"}},
+			Tokens: []config.WebTokenConfig{
+				{Name: "ci", Token: "ci-token-0123456789", Scopes: []string{"read"}},
+				{Name: "triage", Token: "write-token-0123456789", Scopes: []string{"write"}},
+				{Name: "automation", Token: "admin-token-0123456789", Scopes: []string{"admin"}},
+			},
+		},
+	}
+	serve := func(svc *Service, method, target, token, body string) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		svc.RegisterRoutes(router)
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	svc := NewService(cfg, store, noopLogger{})
+	defer svc.Close()
+	if rr := serve(svc, http.MethodGet, "/api/requests", "ci-token-0123456789", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected a read token to list requests, got %d", rr.Code)
+	}
+	if rr := serve(svc, http.MethodGet, "/api/requests", "wrong-token", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown token to be rejected, got %d", rr.Code)
+	}
+	if rr := serve(svc, http.MethodGet, "/api/export?format=json", "ci-token-0123456789", ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected export to need the export scope, got %d", rr.Code)
+	}
+	if rr := serve(svc, http.MethodPost, "/api/tokens", "ci-token-0123456789", `{"name":"x","scopes":["read"]}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected token management to need admin, got %d", rr.Code)
+	}
+
+	// Tags, notes, pins, claims and comments need the write scope
+	if _, err := store.Record(&request.RequestData{ID: "req-1", Method: http.MethodPost, Path: "/hook"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	writes := []struct{ method, target, body string }{
+		{http.MethodPatch, "/api/requests/req-1", `{"tags":["bug"]}`},
+		{http.MethodPost, "/api/requests/req-1/pin", ""},
+		{http.MethodPost, "/api/requests/req-1/claim", ""},
+		{http.MethodPost, "/api/requests/req-1/comments", `{"body":"looks off"}`},
+	}
+	for _, write := range writes {
+		if rr := serve(svc, write.method, write.target, "ci-token-0123456789", write.body); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected %s %s to need the write scope, got %d", write.method, write.target, rr.Code)
+		}
+		if rr := serve(svc, write.method, write.target, "write-token-0123456789", write.body); rr.Code >= 300 {
+			t.Fatalf("expected a write token to %s %s, got %d: %s", write.method, write.target, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := serve(svc, http.MethodPost, "/api/tokens", "admin-token-0123456789", `{"name":"exporter","scopes":["export"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected token creation, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Token string    `json:"token"`
+		Info  TokenInfo `json:"info"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.Token == "" || created.Info.CreatedBy != "automation" {
+		t.Fatalf("unexpected creation response %s (%v)", rr.Body.String(), err)
+	}
+	if rr := serve(svc, http.MethodDelete, "/api/tokens/ci", "admin-token-0123456789", ""); rr.Code != http.StatusConflict {
+		t.Fatalf("expected configured tokens to be kept, got %d", rr.Code)
+	}
+
+	// A restarted service restores the token from storage
+	restarted := NewService(cfg, store, noopLogger{})
+	defer restarted.Close()
+	if rr := serve(restarted, http.MethodGet, "/api/export?format=json", created.Token, ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected the stored token to export after a restart, got %d", rr.Code)
+	}
+	if rr := serve(restarted, http.MethodDelete, "/api/tokens/exporter", "admin-token-0123456789", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the token to be revoked, got %d", rr.Code)
+	}
+	if rr := serve(restarted, http.MethodGet, "/api/requests", created.Token, ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked token to be rejected, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Body views of GET /requests/{id}/body
+const (
+	bodyViewRaw     = "raw"
+	bodyViewDecoded = "decoded"
+	bodyViewHex     = "hex"
+)
+
+// hexDumpWidth is the number of body bytes per line of the hex view
+const hexDumpWidth = 16
+
+// handleRequestBody streams a stored request body, so the console can load large or binary bodies
+// on demand. view=raw (the default) is the body exactly as it was received and the only way to get
+// the full body of a request spilled to disk, whose list entry carries a preview only;
+// view=decoded is the body after Content-Encoding decoding and view=hex a hex dump of the raw body.
+// range=start-end limits the response to the bytes from start up to, but not including, end.
+func (s *Service) handleRequestBody(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	view := strings.ToLower(query.Get("view"))
+	switch view {
+	case "", bodyViewRaw, bodyViewDecoded, bodyViewHex:
+	default:
+		http.Error(w, "view must be raw, decoded or hex", http.StatusBadRequest)
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+	item, err := s.store.Get(requestID)
+	if err != nil {
+		s.logger.Error("Failed to get request body", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to retrieve request", http.StatusInternalServerError)
+		return
+	}
+	if item == nil || item.RequestData == nil {
+		http.Error(w, "request not found", http.StatusNotFound)
+		return
+	}
+
+	var (
+		body io.ReadCloser
+		size int64
+	)
+	if view == bodyViewDecoded && item.BodyFile == "" {
+		// Spilled bodies are never decoded, so they are served as received
+		body, size = io.NopCloser(bytes.NewReader(item.Body)), int64(len(item.Body))
+	} else if body, size, err = item.OpenBody(); err != nil {
+		s.logger.Error("Failed to open request body", "request_id", requestID, "error", err)
+		http.Error(w, "request body is no longer available", http.StatusGone)
+		return
+	}
+	defer body.Close()
+
+	start, end, ranged, err := parseBodyRange(query.Get("range"), size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if err := skipBody(body, start); err != nil {
+		s.logger.Error("Failed to seek request body", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+		return
+	}
+	content := io.LimitReader(body, end-start)
+
+	status := http.StatusOK
+	if ranged {
+		status = http.StatusPartialContent
+		if end > start {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+		} else {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		}
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	if view == bodyViewHex {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		if err := writeHexDump(w, content, start); err != nil {
+			s.logger.Warn("Failed to stream request body", "request_id", requestID, "error", err)
+		}
+		return
+	}
+
+	contentType := item.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start, 10))
+	// Without a view the endpoint keeps serving downloads; views are loaded inline by the console
+	if view == "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", requestID+".body"))
+	} else {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	w.WriteHeader(status)
+	if _, err := io.Copy(w, content); err != nil {
+		s.logger.Warn("Failed to stream request body", "request_id", requestID, "error", err)
+	}
+}
+
+// parseBodyRange parses "start-end" or "start-" against a body of size bytes. The end is exclusive
+// and clamped to the body size; ranged is false when value is empty.
+func parseBodyRange(value string, size int64) (start, end int64, ranged bool, err error) {
+	if value == "" {
+		return 0, size, false, nil
+	}
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, false, fmt.Errorf("range must look like start-end")
+	}
+	if start, err = strconv.ParseInt(strings.TrimSpace(from), 10, 64); err != nil || start < 0 {
+		return 0, 0, false, fmt.Errorf("invalid range start %q", from)
+	}
+	end = size
+	if to = strings.TrimSpace(to); to != "" {
+		if end, err = strconv.ParseInt(to, 10, 64); err != nil || end < start {
+			return 0, 0, false, fmt.Errorf("invalid range end %q", to)
+		}
+	}
+	if start > size || (start == size && size > 0) {
+		return 0, 0, false, fmt.Errorf("range starts beyond the %d byte body", size)
+	}
+	if end > size {
+		end = size
+	}
+	return start, end, true, nil
+}
+
+// skipBody moves body forward by n bytes, seeking when it is a spilled file
+func skipBody(body io.Reader, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	if seeker, ok := body.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, body, n)
+	return err
+}
+
+// writeHexDump writes r in the layout of hexdump -C, numbering lines from offset
+func writeHexDump(w io.Writer, r io.Reader, offset int64) error {
+	line := make([]byte, hexDumpWidth)
+	var out strings.Builder
+	for {
+		n, err := io.ReadFull(r, line)
+		if n > 0 {
+			out.Reset()
+			fmt.Fprintf(&out, "%08x  ", offset)
+			for i := 0; i < hexDumpWidth; i++ {
+				if i < n {
+					fmt.Fprintf(&out, "%02x ", line[i])
+				} else {
+					out.WriteString("   ")
+				}
+				if i == hexDumpWidth/2-1 {
+					out.WriteByte(' ')
+				}
+			}
+			out.WriteString(" |")
+			for _, b := range line[:n] {
+				if b < 0x20 || b > 0x7e {
+					b = '.'
+				}
+				out.WriteByte(b)
+			}
+			out.WriteString("|\n")
+			if _, werr := io.WriteString(w, out.String()); werr != nil {
+				return werr
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
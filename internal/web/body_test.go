@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestRequestBodyViews(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	// The wire body stands in for an encoded payload; Body holds what it decoded to
+	_, err = store.Record(&request.RequestData{
+		ID:              "body-1",
+		Timestamp:       time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Method:          http.MethodPost,
+		Path:            "/hook",
+		ContentType:     "application/json",
+		ContentEncoding: "gzip",
+		Body:            []byte(`{"message":"hello"}`),
+		WireBody:        []byte("\x1f\x8b\x08\x00wire-bytes"),
+		WireSize:        14,
+	})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	svc := NewService(&config.WebConfig{Enable: true, Path: "/web", AdminPath: "/api"}, store, noopLogger{})
+	defer svc.Close()
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/requests/body-1/body"+query, nil))
+		return rr
+	}
+
+	rr := get("")
+	if rr.Code != http.StatusOK || rr.Body.String() != "\x1f\x8b\x08\x00wire-bytes" || rr.Header().Get("Content-Disposition") == "" {
+		t.Fatalf("expected the raw body as a download, got %d %q", rr.Code, rr.Body.String())
+	}
+	rr = get("?view=decoded&range=2-9")
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "message" || rr.Header().Get("Content-Range") != "bytes 2-8/19" {
+		t.Fatalf("expected a slice of the decoded body, got %d %q %s", rr.Code, rr.Body.String(), rr.Header().Get("Content-Range"))
+	}
+	if rr.Header().Get("Content-Disposition") != "" {
+		t.Fatal("expected views to be served inline")
+	}
+	rr = get("?view=hex&range=4-")
+	want := "00000004  77 69 72 65 2d 62 79 74  65 73                    |wire-bytes|\n"
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != want {
+		t.Fatalf("unexpected hex view %d:\n%q", rr.Code, rr.Body.String())
+	}
+
+	if rr = get("?range=20-"); rr.Code != http.StatusRequestedRangeNotSatisfiable || rr.Header().Get("Content-Range") != "bytes */14" {
+		t.Fatalf("expected a range past the body to be rejected, got %d", rr.Code)
+	}
+	if rr = get("?view=base64"); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "view") {
+		t.Fatalf("expected an unknown view to be rejected, got %d", rr.Code)
+	}
+}
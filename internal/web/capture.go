@@ -0,0 +1,86 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// CaptureState reports whether capture is paused. While paused, requests still get their mock
+// response but are not stored, printed or forwarded; Skipped counts them since the last pause.
+type CaptureState struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	Skipped  int64      `json:"skipped"`
+}
+
+// SetCaptureControl wires the pause switch exposed via /capture.
+func (s *Service) SetCaptureControl(state func() CaptureState, setPaused func(paused bool) CaptureState) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.captureState = state
+	s.setCapturePaused = setPaused
+	s.reloadMu.Unlock()
+}
+
+// NotifyCapture pushes pause and resume events to websocket clients.
+func (s *Service) NotifyCapture(state CaptureState) {
+	if s == nil || !s.cfg.Enable {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "capture",
+		"data": state,
+	})
+}
+
+// handleCaptureState reports whether capture is paused.
+func (s *Service) handleCaptureState(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.RLock()
+	captureState := s.captureState
+	s.reloadMu.RUnlock()
+
+	state := CaptureState{}
+	if captureState != nil {
+		state = captureState()
+	}
+	s.respondJSON(w, http.StatusOK, state)
+}
+
+func (s *Service) handlePauseCapture(w http.ResponseWriter, r *http.Request) {
+	s.switchCapture(w, r, true)
+}
+
+func (s *Service) handleResumeCapture(w http.ResponseWriter, r *http.Request) {
+	s.switchCapture(w, r, false)
+}
+
+// switchCapture pauses or resumes capture; pausing twice keeps the original pause.
+func (s *Service) switchCapture(w http.ResponseWriter, r *http.Request, paused bool) {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: pausing capture requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	s.reloadMu.RLock()
+	setPaused := s.setCapturePaused
+	s.reloadMu.RUnlock()
+	if setPaused == nil {
+		http.Error(w, "capture control unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	state := setPaused(paused)
+	action := auditCaptureResume
+	if paused {
+		action = auditCapturePause
+	}
+	s.audit(r, storage.AuditEntry{Action: action, Success: true})
+	s.respondJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// handleClaim assigns a request to the current user; admins may take over with force=true.
+func (s *Service) handleClaim(w http.ResponseWriter, r *http.Request) {
+	s.updateClaim(w, r, true)
+}
+
+// handleReleaseClaim clears the current user's claim; admins may clear anyone's with force=true.
+func (s *Service) handleReleaseClaim(w http.ResponseWriter, r *http.Request) {
+	s.updateClaim(w, r, false)
+}
+
+func (s *Service) updateClaim(w http.ResponseWriter, r *http.Request, claim bool) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	session := s.sessionFromContext(r.Context())
+	if session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.requireWrite(w, r) {
+		return
+	}
+	force := strings.EqualFold(r.URL.Query().Get("force"), "true")
+	if force && s.auth.Enabled() && !session.allows(scopeAdmin) {
+		http.Error(w, "Forbidden: taking over a claim requires admin role", http.StatusForbidden)
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+	var (
+		current *storage.Claim
+		err     error
+	)
+	if claim {
+		current, err = s.store.Claim(requestID, session.Username, force)
+	} else {
+		err = s.store.ReleaseClaim(requestID, session.Username, force)
+	}
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrClaimed):
+		if current == nil {
+			if stored, getErr := s.store.Get(requestID); getErr == nil && stored != nil {
+				current = stored.Claim
+			}
+		}
+		s.respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+			"claim": current,
+		})
+		return
+	case errors.Is(err, storage.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		s.logger.Error("Failed to update claim", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to update claim", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Request claim updated", "request_id", requestID, "user", session.Username, "claimed", claim)
+	s.NotifyClaim(requestID, current)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": requestID,
+		"claim":      current,
+	})
+}
+
+// claimFilter reads the claim query parameter, resolving "mine" to the current user.
+func (s *Service) claimFilter(r *http.Request) string {
+	claim := strings.TrimSpace(r.URL.Query().Get("claim"))
+	if strings.EqualFold(claim, "mine") {
+		if session := s.sessionFromContext(r.Context()); session != nil {
+			return session.Username
+		}
+	}
+	return claim
+}
+
+// NotifyClaim pushes a claim change to websocket clients; a nil claim means released.
+func (s *Service) NotifyClaim(requestID string, claim *storage.Claim) {
+	if s == nil || !s.cfg.Enable {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "claim",
+		"data": map[string]interface{}{
+			"request_id": requestID,
+			"claim":      claim,
+		},
+	})
+}
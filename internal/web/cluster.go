@@ -0,0 +1,59 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/funnyzak/reqtap/internal/cluster"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// SetClusterSecret enables the endpoint peers push their captured requests to.
+func (s *Service) SetClusterSecret(secret string) {
+	if s == nil {
+		return
+	}
+	s.clusterSecret = secret
+}
+
+// handleClusterRequest stores a request captured by a peer and streams it to the local consoles.
+// Bodies the peer spilled to disk arrive as their preview.
+func (s *Service) handleClusterRequest(w http.ResponseWriter, r *http.Request) {
+	if s.clusterSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !cluster.Authorized(r, s.clusterSecret) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var data request.RequestData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if data.ID == "" || data.Instance == "" {
+		http.Error(w, "id and instance are required", http.StatusBadRequest)
+		return
+	}
+	// A spill file path names a file on the peer; kept here it would be served and removed locally
+	data.BodyFile = ""
+	if existing, err := s.store.Get(data.ID); err == nil && existing != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	stored, err := s.store.Record(&data)
+	if err != nil {
+		s.logger.Error("Failed to store cluster request", "request_id", data.ID, "instance", data.Instance, "error", err)
+		http.Error(w, "Failed to store request", http.StatusInternalServerError)
+		return
+	}
+	s.Record(stored)
+	w.WriteHeader(http.StatusNoContent)
+}
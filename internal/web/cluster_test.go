@@ -0,0 +1,61 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/cluster"
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+func TestClusterRequestDropsPeerBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(dir, "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	secret := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(secret, []byte("local secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewService(&config.WebConfig{Enable: true, Path: "/web", AdminPath: "/api"}, store, noopLogger{})
+	defer svc.Close()
+	svc.SetClusterSecret("s3cret")
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+
+	payload := `{"id":"peer-1","instance":"edge-2","method":"POST","path":"/upload","body":"cHJldmlldw==","size":1048576,"body_file":` + strconv.Quote(secret) + `}`
+	req := httptest.NewRequest(http.MethodPost, "/api"+cluster.RequestsPath, strings.NewReader(payload))
+	req.Header.Set(cluster.SecretHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the request to be stored, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	item, err := store.Get("peer-1")
+	if err != nil || item == nil || item.BodyFile != "" || string(item.Body) != "preview" {
+		t.Fatalf("expected the peer's body file to be dropped, got %+v (%v)", item, err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/requests/peer-1/body", nil))
+	if rr.Body.String() != "preview" {
+		t.Fatalf("expected the preview to be served, got %q", rr.Body.String())
+	}
+	if _, err := store.Delete([]string{"peer-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(secret); err != nil || string(data) != "local secret" {
+		t.Fatalf("expected the local file to be left alone, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// maxCommentLength caps a single comment, counted in characters.
+const maxCommentLength = 4000
+
+// handleComments lists the comments left on a request.
+func (s *Service) handleComments(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	requestID := mux.Vars(r)["id"]
+	comments, err := s.store.GetComments(requestID)
+	if err != nil {
+		s.logger.Error("Failed to load comments", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to load comments", http.StatusInternalServerError)
+		return
+	}
+	if comments == nil {
+		comments = []*storage.Comment{}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": requestID,
+		"data":       comments,
+	})
+}
+
+// handleAddComment appends a comment by the current user; every console role may comment, API
+// tokens need the write scope.
+func (s *Service) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	session := s.sessionFromContext(r.Context())
+	if session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.requireWrite(w, r) {
+		return
+	}
+
+	var payload struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4*maxCommentLength+1024)).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		http.Error(w, "comment body is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		http.Error(w, "comment is too long", http.StatusBadRequest)
+		return
+	}
+
+	comment := &storage.Comment{
+		RequestID: mux.Vars(r)["id"],
+		Author:    session.Username,
+		Body:      body,
+	}
+	err := s.store.AddComment(comment)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		s.logger.Error("Failed to add comment", "request_id", comment.RequestID, "error", err)
+		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		return
+	}
+
+	s.NotifyComment(comment)
+	s.respondJSON(w, http.StatusCreated, comment)
+}
+
+// NotifyComment pushes a new comment to websocket clients.
+func (s *Service) NotifyComment(comment *storage.Comment) {
+	if s == nil || !s.cfg.Enable || comment == nil {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "comment",
+		"data": comment,
+	})
+}
+
+// withComments attaches each request's comments before it is exported.
+func (s *Service) withComments(iter RequestIterator) RequestIterator {
+	return func(yield func(*StoredRequest) bool) error {
+		var lookupErr error
+		err := iter(func(item *StoredRequest) bool {
+			comments, err := s.store.GetComments(item.ID)
+			if err != nil {
+				lookupErr = err
+				return false
+			}
+			item.Comments = comments
+			return yield(item)
+		})
+		if err != nil {
+			return err
+		}
+		return lookupErr
+	}
+}
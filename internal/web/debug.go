@@ -0,0 +1,141 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// SetDebug exposes the pprof profiles and runtime statistics under /debug (debug.pprof); without
+// it the endpoints answer 404.
+func (s *Service) SetDebug(enable bool) {
+	if s == nil {
+		return
+	}
+	s.debug = enable
+}
+
+// requireDebugAdmin answers the request and returns false unless debugging is on and the caller
+// is an admin.
+func (s *Service) requireDebugAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !s.debug {
+		http.NotFound(w, r)
+		return false
+	}
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: debug endpoints require admin role", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// handlePprof serves the net/http/pprof index and profiles below debug/pprof/.
+func (s *Service) handlePprof(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDebugAdmin(w, r) {
+		return
+	}
+	switch name := mux.Vars(r)["profile"]; name {
+	case "":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "profile", "trace":
+		seconds, err := strconv.ParseFloat(r.URL.Query().Get("seconds"), 64)
+		if err != nil || seconds <= 0 {
+			seconds = 30
+		}
+		// Sampling outlasts the server's write timeout, which pprof would refuse to run into
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Duration(seconds*float64(time.Second)) + 10*time.Second)); err != nil {
+			s.logger.Debug("Unable to extend write deadline for profile", "error", err)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, nil))
+		if name == "trace" {
+			pprof.Trace(w, r)
+		} else {
+			pprof.Profile(w, r)
+		}
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
+
+// RuntimeStats is the process snapshot reported by /debug/runtime.
+type RuntimeStats struct {
+	GoVersion  string       `json:"go_version"`
+	Goroutines int          `json:"goroutines"`
+	CPUs       int          `json:"cpus"`
+	Memory     MemoryStats  `json:"memory"`
+	Storage    StorageQueue `json:"storage"`
+	// LiveClients counts the connected /ws and /events listeners
+	LiveClients int `json:"live_clients"`
+}
+
+// MemoryStats is the subset of runtime.MemStats useful to spot memory growth; sizes are in bytes.
+type MemoryStats struct {
+	Alloc        uint64    `json:"alloc"`
+	TotalAlloc   uint64    `json:"total_alloc"`
+	Sys          uint64    `json:"sys"`
+	HeapAlloc    uint64    `json:"heap_alloc"`
+	HeapInuse    uint64    `json:"heap_inuse"`
+	HeapIdle     uint64    `json:"heap_idle"`
+	HeapReleased uint64    `json:"heap_released"`
+	HeapObjects  uint64    `json:"heap_objects"`
+	StackInuse   uint64    `json:"stack_inuse"`
+	NumGC        uint32    `json:"num_gc"`
+	PauseTotalNs uint64    `json:"pause_total_ns"`
+	LastGC       time.Time `json:"last_gc,omitempty"`
+}
+
+// StorageQueue reports the requests waiting to be written; Capacity is 0 for stores that write
+// synchronously.
+type StorageQueue struct {
+	Pending  int `json:"pending"`
+	Capacity int `json:"capacity"`
+}
+
+// handleRuntime reports goroutine, memory and storage queue statistics.
+func (s *Service) handleRuntime(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDebugAdmin(w, r) {
+		return
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := RuntimeStats{
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+		CPUs:       runtime.NumCPU(),
+		Memory: MemoryStats{
+			Alloc:        mem.Alloc,
+			TotalAlloc:   mem.TotalAlloc,
+			Sys:          mem.Sys,
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			HeapIdle:     mem.HeapIdle,
+			HeapReleased: mem.HeapReleased,
+			HeapObjects:  mem.HeapObjects,
+			StackInuse:   mem.StackInuse,
+			NumGC:        mem.NumGC,
+			PauseTotalNs: mem.PauseTotalNs,
+		},
+		LiveClients: s.hub.Clients(),
+	}
+	if mem.LastGC > 0 {
+		stats.Memory.LastGC = time.Unix(0, int64(mem.LastGC)).UTC()
+	}
+	if queue, ok := s.store.(storage.WriteQueueReporter); ok {
+		stats.Storage.Pending, stats.Storage.Capacity = queue.WriteQueue()
+	}
+	s.respondJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	svc := &Service{cfg: &config.WebConfig{Enable: true, AdminPath: "/api", Path: "/web"}, logger: noopLogger{}, hub: NewWebsocketHub(noopLogger{})}
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/api/debug/runtime"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while debug.pprof is off, got %d", rec.Code)
+	}
+
+	svc.SetDebug(true)
+	rec := get("/api/debug/runtime")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected runtime statistics, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats RuntimeStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Goroutines == 0 || stats.Memory.HeapAlloc == 0 || stats.GoVersion == "" {
+		t.Fatalf("expected populated statistics, got %+v", stats)
+	}
+
+	if rec := get("/api/debug/pprof/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Fatalf("expected the pprof index, got %d", rec.Code)
+	}
+	if rec := get("/api/debug/pprof/goroutine?debug=1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Fatalf("expected the goroutine profile, got %d: %.200s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+
+	diffBodyJSON   = "json"
+	diffBodyText   = "text"
+	diffBodyBinary = "binary"
+)
+
+// DiffEntry is one difference between request a and request b. Key is a header name, a query
+// parameter or a JSON path such as $.items[0].id; A and B are absent on the side missing the key.
+type DiffEntry struct {
+	Key string      `json:"key"`
+	Op  string      `json:"op"`
+	A   interface{} `json:"a,omitempty"`
+	B   interface{} `json:"b,omitempty"`
+}
+
+// DiffSide identifies one of the compared requests.
+type DiffSide struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// BodyDiff compares bodies structurally when both are JSON and as a whole otherwise.
+type BodyDiff struct {
+	Mode    string      `json:"mode"`
+	Equal   bool        `json:"equal"`
+	Changes []DiffEntry `json:"changes"`
+}
+
+// RequestDiff is the structured difference between two captured requests.
+type RequestDiff struct {
+	A       DiffSide    `json:"a"`
+	B       DiffSide    `json:"b"`
+	Request []DiffEntry `json:"request"`
+	Headers []DiffEntry `json:"headers"`
+	Query   []DiffEntry `json:"query"`
+	Body    BodyDiff    `json:"body"`
+}
+
+func (s *Service) handleRequestDiff(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	idA, idB := strings.TrimSpace(r.URL.Query().Get("a")), strings.TrimSpace(r.URL.Query().Get("b"))
+	if idA == "" || idB == "" {
+		http.Error(w, "a and b request ids are required", http.StatusBadRequest)
+		return
+	}
+	var items [2]*StoredRequest
+	for i, id := range []string{idA, idB} {
+		item, err := s.store.Get(id)
+		if err != nil {
+			s.logger.Error("Failed to load request for diff", "request_id", id, "error", err)
+			http.Error(w, "Failed to load request", http.StatusInternalServerError)
+			return
+		}
+		if item == nil {
+			http.Error(w, fmt.Sprintf("Request %s not found", id), http.StatusNotFound)
+			return
+		}
+		items[i] = item
+	}
+	s.respondJSON(w, http.StatusOK, diffRequests(items[0], items[1]))
+}
+
+func diffRequests(a, b *StoredRequest) *RequestDiff {
+	d := &RequestDiff{
+		A:       DiffSide{ID: a.ID, Method: a.Method, Path: a.Path, Timestamp: a.Timestamp},
+		B:       DiffSide{ID: b.ID, Method: b.Method, Path: b.Path, Timestamp: b.Timestamp},
+		Request: []DiffEntry{},
+		Headers: diffValues(a.Headers, b.Headers),
+		Query:   diffValues(parseQuery(a.Query), parseQuery(b.Query)),
+		Body:    diffBodies(a, b),
+	}
+	for _, field := range []struct{ key, a, b string }{
+		{"method", a.Method, b.Method},
+		{"path", a.Path, b.Path},
+		{"content_type", a.ContentType, b.ContentType},
+	} {
+		if field.a != field.b {
+			d.Request = append(d.Request, DiffEntry{Key: field.key, Op: diffChanged, A: field.a, B: field.b})
+		}
+	}
+	return d
+}
+
+func parseQuery(raw string) map[string][]string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return map[string][]string{}
+	}
+	return values
+}
+
+// diffValues compares multi-valued maps such as headers and query parameters; repeated values are joined with ", ".
+func diffValues(a, b map[string][]string) []DiffEntry {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	entries := []DiffEntry{}
+	for _, k := range sorted {
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inA:
+			entries = append(entries, DiffEntry{Key: k, Op: diffAdded, B: strings.Join(vb, ", ")})
+		case !inB:
+			entries = append(entries, DiffEntry{Key: k, Op: diffRemoved, A: strings.Join(va, ", ")})
+		case strings.Join(va, ", ") != strings.Join(vb, ", "):
+			entries = append(entries, DiffEntry{Key: k, Op: diffChanged, A: strings.Join(va, ", "), B: strings.Join(vb, ", ")})
+		}
+	}
+	return entries
+}
+
+func diffBodies(a, b *StoredRequest) BodyDiff {
+	if a.IsBinary || b.IsBinary {
+		equal := bytes.Equal(a.Body, b.Body)
+		body := BodyDiff{Mode: diffBodyBinary, Equal: equal, Changes: []DiffEntry{}}
+		if !equal {
+			body.Changes = append(body.Changes, DiffEntry{Key: "$", Op: diffChanged, A: len(a.Body), B: len(b.Body)})
+		}
+		return body
+	}
+	docA, okA := decodeJSONBody(a.Body)
+	docB, okB := decodeJSONBody(b.Body)
+	if okA && okB {
+		changes := diffJSON("$", docA, docB, []DiffEntry{})
+		return BodyDiff{Mode: diffBodyJSON, Equal: len(changes) == 0, Changes: changes}
+	}
+	equal := bytes.Equal(a.Body, b.Body)
+	body := BodyDiff{Mode: diffBodyText, Equal: equal, Changes: []DiffEntry{}}
+	if !equal {
+		body.Changes = append(body.Changes, DiffEntry{Key: "$", Op: diffChanged, A: string(a.Body), B: string(b.Body)})
+	}
+	return body
+}
+
+func decodeJSONBody(body []byte) (interface{}, bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// diffJSON walks both documents in lockstep; objects compare key by key and arrays index by index.
+func diffJSON(path string, a, b interface{}, entries []DiffEntry) []DiffEntry {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(va)+len(vb))
+		for k := range va {
+			keys = append(keys, k)
+		}
+		for k := range vb {
+			if _, seen := va[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childA, inA := va[k]
+			childB, inB := vb[k]
+			childPath := jsonPathKey(path, k)
+			switch {
+			case !inA:
+				entries = append(entries, DiffEntry{Key: childPath, Op: diffAdded, B: childB})
+			case !inB:
+				entries = append(entries, DiffEntry{Key: childPath, Op: diffRemoved, A: childA})
+			default:
+				entries = diffJSON(childPath, childA, childB, entries)
+			}
+		}
+		return entries
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(va):
+				entries = append(entries, DiffEntry{Key: childPath, Op: diffAdded, B: vb[i]})
+			case i >= len(vb):
+				entries = append(entries, DiffEntry{Key: childPath, Op: diffRemoved, A: va[i]})
+			default:
+				entries = diffJSON(childPath, va[i], vb[i], entries)
+			}
+		}
+		return entries
+	default:
+		if a == b {
+			return entries
+		}
+	}
+	return append(entries, DiffEntry{Key: path, Op: diffChanged, A: a, B: b})
+}
+
+var jsonIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func jsonPathKey(parent, key string) string {
+	if jsonIdentifier.MatchString(key) {
+		return parent + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return parent + "[" + string(quoted) + "]"
+}
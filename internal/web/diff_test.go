@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestDiffRequests(t *testing.T) {
+	a := &StoredRequest{ID: "a", RequestData: &request.RequestData{
+		Method:  http.MethodPost,
+		Path:    "/hook",
+		Query:   "v=1&source=github",
+		Headers: http.Header{"X-Signature": {"abc"}, "X-Retry": {"0"}},
+		Body:    []byte(`{"event":"push","commits":[{"id":1},{"id":2}],"repo":{"name":"reqtap"}}`),
+	}}
+	b := &StoredRequest{ID: "b", RequestData: &request.RequestData{
+		Method:  http.MethodPost,
+		Path:    "/hook",
+		Query:   "v=2&source=github",
+		Headers: http.Header{"X-Signature": {"def"}, "X-Delivery": {"42"}},
+		Body:    []byte(`{"event":"push","commits":[{"id":1}],"repo":{"name":"reqtap","full name":"funnyzak/reqtap"}}`),
+	}}
+
+	diff := diffRequests(a, b)
+	if len(diff.Request) != 0 {
+		t.Fatalf("expected identical request lines, got %+v", diff.Request)
+	}
+	assertDiff(t, "headers", diff.Headers, []DiffEntry{
+		{Key: "X-Delivery", Op: diffAdded, B: "42"},
+		{Key: "X-Retry", Op: diffRemoved, A: "0"},
+		{Key: "X-Signature", Op: diffChanged, A: "abc", B: "def"},
+	})
+	assertDiff(t, "query", diff.Query, []DiffEntry{{Key: "v", Op: diffChanged, A: "1", B: "2"}})
+	if diff.Body.Mode != diffBodyJSON || diff.Body.Equal {
+		t.Fatalf("unexpected body diff: %+v", diff.Body)
+	}
+	assertDiff(t, "body", diff.Body.Changes, []DiffEntry{
+		{Key: "$.commits[1]", Op: diffRemoved, A: map[string]interface{}{"id": json.Number("2")}},
+		{Key: `$.repo["full name"]`, Op: diffAdded, B: "funnyzak/reqtap"},
+	})
+}
+
+func TestDiffRequestsTextBodies(t *testing.T) {
+	a := &StoredRequest{ID: "a", RequestData: &request.RequestData{Method: http.MethodPut, Body: []byte("hello")}}
+	b := &StoredRequest{ID: "b", RequestData: &request.RequestData{Method: http.MethodPost, Body: []byte("hello")}}
+
+	diff := diffRequests(a, b)
+	if diff.Body.Mode != diffBodyText || !diff.Body.Equal {
+		t.Fatalf("expected equal text bodies, got %+v", diff.Body)
+	}
+	assertDiff(t, "request", diff.Request, []DiffEntry{{Key: "method", Op: diffChanged, A: http.MethodPut, B: http.MethodPost}})
+}
+
+func assertDiff(t *testing.T, section string, got, want []DiffEntry) {
+	t.Helper()
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("unexpected %s diff:\n got %s\nwant %s", section, gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// eventStreamKeepAlive is how often an idle event stream sends a comment, so that proxies do not
+// close it.
+const eventStreamKeepAlive = 25 * time.Second
+
+// handleEvents streams the live events of /ws as Server-Sent Events, for clients behind proxies
+// that block WebSocket upgrades. Every event is a data line holding the same JSON payload as the
+// WebSocket messages, and the history backfill is sent first as well.
+func (s *Service) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.auth.Enabled() {
+		if _, err := s.auth.Validate(s.extractToken(r)); err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	backfill, err := s.historyBackfill(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	// The server's write timeout would end the stream
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Debug("Unable to clear write deadline for event stream", "error", err)
+	}
+
+	// Subscribe before building the backfill so nothing broadcast in between is lost
+	events, cancel := s.hub.Subscribe()
+	defer cancel()
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// Keep nginx and similar proxies from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if backfill != nil {
+		if event := backfill(); event != nil {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				s.logger.Error("Failed to marshal event stream backfill", "error", err)
+			} else if writeEvent(w, payload) != nil {
+				return
+			}
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		s.logger.Error("Event stream is not supported by the response writer", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case payload, ok := <-events:
+			if !ok {
+				return
+			}
+			if writeEvent(w, payload) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if controller.Flush() != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one event; payloads are compact JSON and fit on a single data line.
+func writeEvent(w http.ResponseWriter, payload []byte) error {
+	_, err := fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
+}
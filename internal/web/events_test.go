@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestEventStreamRelaysBroadcasts(t *testing.T) {
+	hub := NewWebsocketHub(noopLogger{})
+	defer hub.Close()
+	svc := &Service{cfg: &config.WebConfig{Enable: true}, logger: noopLogger{}, hub: hub}
+	srv := httptest.NewServer(http.HandlerFunc(svc.handleEvents))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	// The subscription exists once the headers are sent
+	svc.Record(&StoredRequest{ID: "REQ-1", RequestData: &request.RequestData{Method: http.MethodPost, Path: "/hook"}})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended before the event arrived")
+			}
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			payload := strings.TrimPrefix(line, "data: ")
+			if !strings.Contains(payload, `"type":"request"`) || !strings.Contains(payload, `"id":"REQ-1"`) {
+				t.Fatalf("unexpected payload %s", payload)
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for the event")
+		}
+	}
+}
+
+func TestHubDropsStalledStreams(t *testing.T) {
+	hub := NewWebsocketHub(noopLogger{})
+	events, cancel := hub.Subscribe()
+	defer cancel()
+	for i := 0; i <= streamBuffer; i++ {
+		hub.Broadcast(map[string]interface{}{"type": "request", "data": i})
+	}
+	received := 0
+	for range events {
+		received++
+	}
+	if received != streamBuffer {
+		t.Fatalf("expected the queued %d events before the stream closed, got %d", streamBuffer, received)
+	}
+	// Closing the hub after the client was dropped must not close the channel twice
+	hub.Close()
+}
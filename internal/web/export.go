@@ -27,12 +27,12 @@ func ExportRequests(data []*StoredRequest, format string) ([]byte, string, strin
 		return nil
 	}
 	buf := &bytes.Buffer{}
-	contentType, ext, err := StreamExport(buf, iter, format)
+	contentType, ext, err := StreamExport(buf, iter, format, nil)
 	return buf.Bytes(), contentType, ext, err
 }
 
-// StreamExport 以流式方式导出，避免大数据加载内存
-func StreamExport(w io.Writer, iter RequestIterator, format string) (string, string, error) {
+// StreamExport 以流式方式导出，避免大数据加载内存；forwards 仅用于 HAR，为 nil 时不附带转发响应
+func StreamExport(w io.Writer, iter RequestIterator, format string, forwards ForwardLookup) (string, string, error) {
 	contentType, ext, err := describeFormat(format)
 	if err != nil {
 		return "", "", err
@@ -42,10 +42,14 @@ func StreamExport(w io.Writer, iter RequestIterator, format string) (string, str
 	switch strings.ToLower(format) {
 	case "json":
 		streamErr = streamJSON(w, iter)
+	case "ndjson", "jsonl":
+		streamErr = streamNDJSON(w, iter)
 	case "csv":
 		streamErr = streamCSV(w, iter)
 	case "text", "txt":
 		streamErr = streamText(w, iter)
+	case "har":
+		streamErr = streamHAR(w, iter, forwards)
 	}
 	return contentType, ext, streamErr
 }
@@ -54,10 +58,14 @@ func describeFormat(format string) (string, string, error) {
 	switch strings.ToLower(format) {
 	case "json":
 		return "application/json", "json", nil
+	case "ndjson", "jsonl":
+		return "application/x-ndjson", "ndjson", nil
 	case "csv":
 		return "text/csv", "csv", nil
 	case "text", "txt":
 		return "text/plain; charset=utf-8", "txt", nil
+	case "har":
+		return "application/json", "har", nil
 	default:
 		return "", "", fmt.Errorf("unsupported export format: %s", format)
 	}
@@ -101,6 +109,23 @@ func streamJSON(w io.Writer, iter RequestIterator) error {
 	return err
 }
 
+// streamNDJSON writes one JSON object per line, so the output can be piped into jq or log loaders
+func streamNDJSON(w io.Writer, iter RequestIterator) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	// json.Encoder terminates every value with a newline
+	enc := json.NewEncoder(bw)
+	var writeErr error
+	if err := iter(func(item *StoredRequest) bool {
+		writeErr = enc.Encode(item)
+		return writeErr == nil
+	}); err != nil {
+		return err
+	}
+	return writeErr
+}
+
 func streamCSV(w io.Writer, iter RequestIterator) error {
 	bw := bufio.NewWriter(w)
 	defer bw.Flush()
@@ -109,6 +134,7 @@ func streamCSV(w io.Writer, iter RequestIterator) error {
 	headers := []string{
 		"id", "timestamp", "method", "path", "query", "remote_addr",
 		"user_agent", "content_type", "content_length", "is_binary", "headers", "body_base64",
+		"tags", "note", "comments",
 	}
 	if err := csvWriter.Write(headers); err != nil {
 		return err
@@ -120,6 +146,11 @@ func streamCSV(w io.Writer, iter RequestIterator) error {
 			return false
 		}
 		headersJSON, _ := json.Marshal(item.Headers)
+		var comments string
+		if len(item.Comments) > 0 {
+			commentsJSON, _ := json.Marshal(item.Comments)
+			comments = string(commentsJSON)
+		}
 		line := []string{
 			item.ID,
 			item.Timestamp.Format(time.RFC3339),
@@ -133,6 +164,9 @@ func streamCSV(w io.Writer, iter RequestIterator) error {
 			fmt.Sprintf("%t", item.IsBinary),
 			string(headersJSON),
 			base64.StdEncoding.EncodeToString(item.Body),
+			strings.Join(item.Tags, ","),
+			item.Note,
+			comments,
 		}
 		writeErr = csvWriter.Write(line)
 		return writeErr == nil
@@ -183,6 +217,29 @@ func renderPlainRequest(item *StoredRequest) string {
 	if bodySize > 0 {
 		builder.WriteString(fmt.Sprintf("# Body-Size: %d bytes\n", bodySize))
 	}
+	if pb := item.Protobuf; pb != nil && len(pb.JSON) > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, pb.JSON); err == nil {
+			message := pb.Message
+			if message == "" {
+				message = "raw"
+			}
+			builder.WriteString(fmt.Sprintf("# Protobuf (%s): %s\n", message, compact.String()))
+		}
+	}
+	if item.DuplicateOf != "" {
+		builder.WriteString(fmt.Sprintf("# Duplicate-Of: %s\n", item.DuplicateOf))
+	}
+	if len(item.Tags) > 0 {
+		builder.WriteString(fmt.Sprintf("# Tags: %s\n", strings.Join(item.Tags, ", ")))
+	}
+	if item.Note != "" {
+		builder.WriteString(fmt.Sprintf("# Note: %s\n", strings.ReplaceAll(item.Note, "\n", "\n#   ")))
+	}
+	for _, comment := range item.Comments {
+		text := strings.ReplaceAll(comment.Body, "\n", "\n#   ")
+		builder.WriteString(fmt.Sprintf("# Comment (%s @ %s): %s\n", comment.Author, comment.CreatedAt.Format(time.RFC3339), text))
+	}
 	builder.WriteString("\n")
 	builder.WriteString(buildHTTPRequestMessage(item))
 	return builder.String()
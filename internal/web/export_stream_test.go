@@ -2,10 +2,13 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/funnyzak/reqtap/internal/storage"
 	"github.com/funnyzak/reqtap/pkg/request"
 )
 
@@ -21,7 +24,7 @@ func TestStreamExportJSON(t *testing.T) {
 		}
 		return nil
 	}
-	ct, ext, err := StreamExport(buf, iter, "json")
+	ct, ext, err := StreamExport(buf, iter, "json", nil)
 	if err != nil {
 		t.Fatalf("stream export failed: %v", err)
 	}
@@ -33,6 +36,37 @@ func TestStreamExportJSON(t *testing.T) {
 	}
 }
 
+func TestStreamExportNDJSON(t *testing.T) {
+	items := []*StoredRequest{
+		{ID: "1", RequestData: &RequestDataFixture},
+		{ID: "2", RequestData: &RequestDataFixture},
+	}
+	iter := func(yield func(*StoredRequest) bool) error {
+		for _, it := range items {
+			yield(it)
+		}
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	ct, ext, err := StreamExport(buf, iter, "ndjson", nil)
+	if err != nil {
+		t.Fatalf("ndjson export failed: %v", err)
+	}
+	if ct != "application/x-ndjson" || ext != "ndjson" {
+		t.Fatalf("unexpected metadata: %s %s", ct, ext)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per request, got %q", buf.String())
+	}
+	for i, line := range lines {
+		var item StoredRequest
+		if err := json.Unmarshal([]byte(line), &item); err != nil || item.Path != "/hook" {
+			t.Fatalf("line %d is not a request object: %q (%v)", i, line, err)
+		}
+	}
+}
+
 var RequestDataFixture = request.RequestData{
 	Method:        "POST",
 	Path:          "/hook",
@@ -52,7 +86,7 @@ func TestStreamExportCSV(t *testing.T) {
 		}
 		return nil
 	}
-	_, _, err := StreamExport(buf, iter, "csv")
+	_, _, err := StreamExport(buf, iter, "csv", nil)
 	if err != nil {
 		t.Fatalf("csv export failed: %v", err)
 	}
@@ -70,7 +104,7 @@ func TestStreamExportText(t *testing.T) {
 		}
 		return nil
 	}
-	_, _, err := StreamExport(buf, iter, "txt")
+	_, _, err := StreamExport(buf, iter, "txt", nil)
 	if err != nil {
 		t.Fatalf("txt export failed: %v", err)
 	}
@@ -80,7 +114,67 @@ func TestStreamExportText(t *testing.T) {
 }
 
 func TestDescribeFormatInvalid(t *testing.T) {
-	if _, _, err := StreamExport(&bytes.Buffer{}, func(func(*StoredRequest) bool) error { return nil }, "xml"); err == nil {
+	if _, _, err := StreamExport(&bytes.Buffer{}, func(func(*StoredRequest) bool) error { return nil }, "xml", nil); err == nil {
 		t.Fatalf("expected error for unsupported format")
 	}
 }
+
+func TestStreamExportHAR(t *testing.T) {
+	data := RequestDataFixture
+	data.Query = "a=1&b=2"
+	data.Headers = map[string][]string{"Host": {"hooks.example.com"}, "Cookie": {"session=abc"}}
+	data.MockResponse = request.MockResponse{Rule: "default", Status: 202}
+	items := []*StoredRequest{{ID: "1", RequestData: &data}}
+	iter := func(yield func(*StoredRequest) bool) error {
+		for _, it := range items {
+			yield(it)
+		}
+		return nil
+	}
+	forwards := func(id string) ([]*storage.ForwardRecord, error) {
+		return []*storage.ForwardRecord{{
+			RequestID:  id,
+			TargetURL:  "http://target.example/hook",
+			Timestamp:  time.Unix(1, 0),
+			StatusCode: 500,
+			Headers:    http.Header{"Content-Type": {"text/plain"}},
+			Body:       []byte("boom"),
+			LatencyMs:  250,
+		}}, nil
+	}
+
+	buf := &bytes.Buffer{}
+	ct, ext, err := StreamExport(buf, iter, "har", forwards)
+	if err != nil {
+		t.Fatalf("har export failed: %v", err)
+	}
+	if ct != "application/json" || ext != "har" {
+		t.Fatalf("unexpected metadata: %s %s", ct, ext)
+	}
+
+	var har struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("har output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("unexpected har log: %+v", har.Log)
+	}
+	capture, forward := har.Log.Entries[0], har.Log.Entries[1]
+	if capture.Request.URL != "http://hooks.example.com/hook?a=1&b=2" || capture.Response.Status != 202 {
+		t.Fatalf("unexpected capture entry: %+v", capture)
+	}
+	if len(capture.Request.QueryString) != 2 || len(capture.Request.Cookies) != 1 || capture.Request.PostData.Text != "demo" {
+		t.Fatalf("request details missing: %+v", capture.Request)
+	}
+	if forward.Request.URL != "http://target.example/hook" || forward.Response.Status != 500 || forward.Response.Content.Text != "boom" {
+		t.Fatalf("unexpected forward entry: %+v", forward)
+	}
+	if forward.Time != 250 || forward.StartedDateTime != time.Unix(1, 0).Add(-250*time.Millisecond).Format(time.RFC3339Nano) {
+		t.Fatalf("unexpected forward timing: %+v", forward)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/funnyzak/reqtap/internal/storage"
 	"github.com/funnyzak/reqtap/pkg/request"
 )
 
@@ -69,3 +70,60 @@ func TestExportRequestsText(t *testing.T) {
 		t.Fatalf("binary placeholder missing: %s", got)
 	}
 }
+
+func TestExportRequestsWithComments(t *testing.T) {
+	item := &StoredRequest{
+		ID:          "REQ1",
+		RequestData: &RequestDataFixture,
+		Comments: []*storage.Comment{{
+			RequestID: "REQ1",
+			Author:    "alice",
+			Body:      "signature mismatch\nretry after rotating keys",
+			CreatedAt: time.Date(2025, time.November, 7, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	buf, _, _, err := ExportRequests([]*StoredRequest{item}, "txt")
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if got := string(buf); !strings.Contains(got, "# Comment (alice @ 2025-11-07T12:00:00Z): signature mismatch\n#   retry after rotating keys") {
+		t.Fatalf("comment missing from text export: %s", got)
+	}
+
+	for _, format := range []string{"json", "csv", "har"} {
+		buf, _, _, err := ExportRequests([]*StoredRequest{item}, format)
+		if err != nil {
+			t.Fatalf("%s export failed: %v", format, err)
+		}
+		if !strings.Contains(string(buf), "signature mismatch") {
+			t.Fatalf("comment missing from %s export: %s", format, buf)
+		}
+	}
+}
+
+func TestExportRequestsWithAnnotations(t *testing.T) {
+	item := &StoredRequest{
+		ID:          "REQ1",
+		RequestData: &RequestDataFixture,
+		Tags:        []string{"bug-123", "prod-incident"},
+		Note:        "retried by the provider",
+	}
+
+	buf, _, _, err := ExportRequests([]*StoredRequest{item}, "txt")
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	got := string(buf)
+	if !strings.Contains(got, "# Tags: bug-123, prod-incident") || !strings.Contains(got, "# Note: retried by the provider") {
+		t.Fatalf("annotations missing from text export: %s", got)
+	}
+
+	buf, _, _, err = ExportRequests([]*StoredRequest{item}, "csv")
+	if err != nil {
+		t.Fatalf("csv export failed: %v", err)
+	}
+	if !strings.Contains(string(buf), "bug-123,prod-incident") {
+		t.Fatalf("tags missing from csv export: %s", buf)
+	}
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listFilters reads the request filters shared by /requests, /export, /requests/groups and the
+// websocket history: search, method, claim, tag, pinned, from/to, content_type, path_prefix,
+// min_size/max_size and is_binary. Limit and Offset are left to the caller.
+func (s *Service) listFilters(r *http.Request) (ListOptions, error) {
+	query := r.URL.Query()
+	tags, err := tagFilter(r)
+	if err != nil {
+		return ListOptions{}, err
+	}
+	opts := ListOptions{
+		Search:      query.Get("search"),
+		Method:      query.Get("method"),
+		Claim:       s.claimFilter(r),
+		Tags:        tags,
+		Pinned:      pinnedFilter(r),
+		ContentType: strings.TrimSpace(query.Get("content_type")),
+		PathPrefix:  strings.TrimSpace(query.Get("path_prefix")),
+	}
+	// violations=true keeps requests a forward target answered in breach of its expectations
+	opts.ForwardViolations = strings.EqualFold(strings.TrimSpace(query.Get("violations")), "true")
+	opts.Duplicates = strings.EqualFold(strings.TrimSpace(query.Get("duplicates")), "true")
+	for name, target := range map[string]*time.Time{"from": &opts.Since, "to": &opts.Until} {
+		if raw := strings.TrimSpace(query.Get(name)); raw != "" {
+			if *target, err = parseTimeParam(raw); err != nil {
+				return ListOptions{}, fmt.Errorf("%s must be an RFC 3339 time or unix milliseconds", name)
+			}
+		}
+	}
+	for name, target := range map[string]*int64{"min_size": &opts.MinSize, "max_size": &opts.MaxSize} {
+		if raw := strings.TrimSpace(query.Get(name)); raw != "" {
+			if *target, err = strconv.ParseInt(raw, 10, 64); err != nil || *target < 0 {
+				return ListOptions{}, fmt.Errorf("%s must be a non-negative number of bytes", name)
+			}
+		}
+	}
+	if raw := strings.TrimSpace(query.Get("is_binary")); raw != "" {
+		binary, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ListOptions{}, fmt.Errorf("is_binary must be true or false")
+		}
+		opts.IsBinary = &binary
+	}
+	return opts, nil
+}
+
+// parseTimeParam accepts an RFC 3339 time or unix milliseconds
+func parseTimeParam(raw string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339Nano, raw)
+}
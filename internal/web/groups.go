@@ -0,0 +1,227 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	payloadKindJSON   = "json"
+	payloadKindText   = "text"
+	payloadKindBinary = "binary"
+	payloadKindEmpty  = "empty"
+
+	defaultGroupScan   = 1000
+	maxGroupScan       = 10000
+	maxGroupSampleIDs  = 20
+	fingerprintHexSize = 16
+)
+
+// PayloadGroup collects requests to the same method and path whose bodies share one structure.
+type PayloadGroup struct {
+	Fingerprint string `json:"fingerprint"`
+	Kind        string `json:"kind"`
+	// Shape is the canonical structure, e.g. {"commits":[{"id":number}],"event":string}; values are ignored
+	Shape string `json:"shape"`
+	// Fields lists the leaf JSON paths of the shape, such as $.commits[].id
+	Fields    []string  `json:"fields"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// RequestIDs holds the most recent members, newest first
+	RequestIDs []string `json:"request_ids"`
+}
+
+// PayloadGroups is the response of the grouping endpoint.
+type PayloadGroups struct {
+	Scanned int             `json:"scanned"`
+	Groups  []*PayloadGroup `json:"groups"`
+}
+
+func (s *Service) handleRequestGroups(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	limit := parseIntDefault(query.Get("limit"), defaultGroupScan)
+	if limit <= 0 {
+		limit = defaultGroupScan
+	}
+	if limit > maxGroupScan {
+		limit = maxGroupScan
+	}
+	opts, err := s.listFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	path := strings.TrimSpace(query.Get("path"))
+
+	grouper := newPayloadGrouper()
+	err = s.store.Iterate(opts, func(item *StoredRequest) bool {
+		if path != "" && item.Path != path {
+			return true
+		}
+		grouper.add(item)
+		return grouper.scanned < limit
+	})
+	if err != nil {
+		s.logger.Error("Failed to group requests", "error", err)
+		http.Error(w, "Failed to group requests", http.StatusInternalServerError)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, grouper.result())
+}
+
+type payloadGrouper struct {
+	scanned int
+	groups  map[string]*PayloadGroup
+}
+
+func newPayloadGrouper() *payloadGrouper {
+	return &payloadGrouper{groups: make(map[string]*PayloadGroup)}
+}
+
+func (g *payloadGrouper) add(item *StoredRequest) {
+	g.scanned++
+	kind, shape, fields := payloadShape(item.Body, item.IsBinary)
+	fingerprint := payloadFingerprint(kind, shape)
+	key := item.Method + " " + item.Path + " " + fingerprint
+	group, ok := g.groups[key]
+	if !ok {
+		group = &PayloadGroup{
+			Fingerprint: fingerprint,
+			Kind:        kind,
+			Shape:       shape,
+			Fields:      fields,
+			Method:      item.Method,
+			Path:        item.Path,
+			FirstSeen:   item.Timestamp,
+			LastSeen:    item.Timestamp,
+		}
+		g.groups[key] = group
+	}
+	group.Count++
+	if item.Timestamp.Before(group.FirstSeen) {
+		group.FirstSeen = item.Timestamp
+	}
+	if item.Timestamp.After(group.LastSeen) {
+		group.LastSeen = item.Timestamp
+	}
+	if len(group.RequestIDs) < maxGroupSampleIDs {
+		group.RequestIDs = append(group.RequestIDs, item.ID)
+	}
+}
+
+// result orders groups by size, then by path and recency, so the dominant shapes come first.
+func (g *payloadGrouper) result() *PayloadGroups {
+	groups := make([]*PayloadGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+	return &PayloadGroups{Scanned: g.scanned, Groups: groups}
+}
+
+func payloadFingerprint(kind, shape string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + shape))
+	return hex.EncodeToString(sum[:])[:fingerprintHexSize]
+}
+
+// payloadShape reduces a body to its structure: JSON keys and value types, ignoring the values.
+// Bodies that are not JSON are grouped by kind alone.
+func payloadShape(body []byte, binary bool) (kind, shape string, fields []string) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return payloadKindEmpty, "", []string{}
+	}
+	if binary {
+		return payloadKindBinary, "", []string{}
+	}
+	doc, ok := decodeJSONBody(body)
+	if !ok {
+		return payloadKindText, "", []string{}
+	}
+	leaves := map[string]struct{}{}
+	shape = jsonShape("$", doc, leaves)
+	fields = make([]string, 0, len(leaves))
+	for field := range leaves {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return payloadKindJSON, shape, fields
+}
+
+// jsonShape renders the canonical shape of v and collects its leaf paths. Array elements are
+// merged, so [{"id":1},{"id":2}] and [{"id":3}] share the shape [{"id":number}].
+func jsonShape(path string, v interface{}, fields map[string]struct{}) string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			fields[path] = struct{}{}
+			return "{}"
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			quoted, _ := json.Marshal(k)
+			parts = append(parts, string(quoted)+":"+jsonShape(jsonPathKey(path, k), val[k], fields))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case []interface{}:
+		if len(val) == 0 {
+			fields[path+"[]"] = struct{}{}
+			return "[]"
+		}
+		seen := map[string]struct{}{}
+		var elements []string
+		for _, item := range val {
+			elem := jsonShape(path+"[]", item, fields)
+			if _, ok := seen[elem]; !ok {
+				seen[elem] = struct{}{}
+				elements = append(elements, elem)
+			}
+		}
+		sort.Strings(elements)
+		return "[" + strings.Join(elements, "|") + "]"
+	default:
+		fields[path] = struct{}{}
+		return jsonTypeName(val)
+	}
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
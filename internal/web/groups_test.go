@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestPayloadShapeIgnoresValues(t *testing.T) {
+	kindA, shapeA, fields := payloadShape([]byte(`{"event":"push","commits":[{"id":1},{"id":2}],"repo":{"name":"a"}}`), false)
+	kindB, shapeB, _ := payloadShape([]byte(`{"repo":{"name":"b"},"event":"ping","commits":[{"id":9}]}`), false)
+	if kindA != payloadKindJSON || kindB != payloadKindJSON {
+		t.Fatalf("expected json kinds, got %s and %s", kindA, kindB)
+	}
+	if shapeA != shapeB {
+		t.Fatalf("expected equal shapes, got %s and %s", shapeA, shapeB)
+	}
+	if want := `{"commits":[{"id":number}],"event":string,"repo":{"name":string}}`; shapeA != want {
+		t.Fatalf("unexpected shape %s", shapeA)
+	}
+	if want := []string{"$.commits[].id", "$.event", "$.repo.name"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	if _, shapeC, _ := payloadShape([]byte(`{"event":"push","commits":[]}`), false); shapeC == shapeA {
+		t.Fatal("expected a different key set to change the shape")
+	}
+	if kind, _, _ := payloadShape([]byte("a=1"), false); kind != payloadKindText {
+		t.Fatalf("expected text kind, got %s", kind)
+	}
+}
+
+func TestPayloadGrouperGroupsByPathAndShape(t *testing.T) {
+	now := time.Now()
+	item := func(id, path, body string, age time.Duration) *StoredRequest {
+		return &StoredRequest{ID: id, RequestData: &request.RequestData{
+			Method:    http.MethodPost,
+			Path:      path,
+			Body:      []byte(body),
+			Timestamp: now.Add(-age),
+		}}
+	}
+	grouper := newPayloadGrouper()
+	for _, it := range []*StoredRequest{
+		item("r4", "/webhook", `{"type":"b","data":{"id":4}}`, 0),
+		item("r3", "/webhook", `{"type":"a","amount":3}`, time.Minute),
+		item("r2", "/webhook", `{"type":"a","amount":2}`, 2*time.Minute),
+		item("r1", "/other", `{"type":"a","amount":1}`, 3*time.Minute),
+	} {
+		grouper.add(it)
+	}
+
+	result := grouper.result()
+	if result.Scanned != 4 || len(result.Groups) != 3 {
+		t.Fatalf("expected 3 groups from 4 requests, got %+v", result)
+	}
+	top := result.Groups[0]
+	if top.Path != "/webhook" || top.Count != 2 || !reflect.DeepEqual(top.RequestIDs, []string{"r3", "r2"}) {
+		t.Fatalf("unexpected top group %+v", top)
+	}
+	if !top.FirstSeen.Equal(now.Add(-2*time.Minute)) || !top.LastSeen.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("unexpected time range %s - %s", top.FirstSeen, top.LastSeen)
+	}
+	if result.Groups[1].Fingerprint != top.Fingerprint || result.Groups[1].Path != "/other" {
+		t.Fatalf("expected the same shape on another path to form its own group, got %+v", result.Groups[1])
+	}
+}
@@ -13,10 +13,14 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/funnyzak/reqtap/internal/anomaly"
+	"github.com/funnyzak/reqtap/internal/cluster"
 	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
 	"github.com/funnyzak/reqtap/internal/logger"
 	"github.com/funnyzak/reqtap/internal/static"
 	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
 )
 
 const (
@@ -29,6 +33,7 @@ const (
 	contextSessionKey = contextKey("web_session")
 	contentTypeJSON   = "application/json"
 	contentTypeHTML   = "text/html; charset=utf-8"
+	loginLinkPath     = "/login/link"
 	roleAdmin         = "admin"
 	roleViewer        = "viewer"
 )
@@ -47,8 +52,38 @@ type Service struct {
 	formats     []string
 	cleanupStop chan struct{}
 	cleanupWG   sync.WaitGroup
+	reloadMu    sync.RWMutex
+	reload      ReloadFunc
+	waits       waitRegistry
+	// targetStats reports forward target delivery, circuit and health state
+	targetStats func() []forwarder.TargetStats
+	reforward   ReforwardFunc
+	// timelines looks up the recorded processing timeline of a request
+	timelines func(requestID string) (ProcessingTimeline, bool)
+	// sequences and resetSequences expose the call counters of sequenced mock rules
+	sequences      func() []SequenceState
+	resetSequences func(rule string) []SequenceState
+	// accessStats reports the capture requests rejected by server.access_control
+	accessStats func() AccessStats
+	// captureState and setCapturePaused expose the capture pause switch
+	captureState     func() CaptureState
+	setCapturePaused func(paused bool) CaptureState
+	// clusterSecret authenticates peers pushing requests; empty disables the endpoint
+	clusterSecret string
+	// mockRules applies the server.responses rules changed through /mock-rules
+	mockRules MockRuleManager
+	// jwtView enables decoding JWTs in the request detail view (output.body_view.jwt.enable)
+	jwtView bool
+	// debug exposes pprof and runtime statistics (debug.pprof)
+	debug bool
 }
 
+// ReloadFunc re-applies the configuration and reports settings that still need a restart.
+type ReloadFunc func() ([]string, error)
+
+// ReforwardFunc delivers a stored request to the configured forward targets again.
+type ReforwardFunc func(ctx context.Context, record *request.RequestData) ([]forwarder.Result, error)
+
 // NewService builds a Service from configuration.
 func NewService(cfg *config.WebConfig, store storage.Store, log logger.Logger) *Service {
 	hub := NewWebsocketHub(log)
@@ -68,6 +103,7 @@ func NewService(cfg *config.WebConfig, store storage.Store, log logger.Logger) *
 	}
 
 	if svc.auth.Enabled() {
+		svc.loadStoredTokens()
 		svc.startSessionCleanup()
 	}
 
@@ -89,8 +125,48 @@ func (s *Service) RegisterRoutes(router *mux.Router) {
 	apiRouter.HandleFunc("/auth/logout", s.handleLogout).Methods(http.MethodPost)
 	apiRouter.Handle("/auth/me", s.authMiddleware(http.HandlerFunc(s.handleMe))).Methods(http.MethodGet)
 	apiRouter.Handle("/requests", s.authMiddleware(http.HandlerFunc(s.handleRequests))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/diff", s.authMiddleware(http.HandlerFunc(s.handleRequestDiff))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/groups", s.authMiddleware(http.HandlerFunc(s.handleRequestGroups))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/{id}", s.authMiddleware(http.HandlerFunc(s.handleAnnotate))).Methods(http.MethodPatch)
+	apiRouter.Handle("/requests/{id}/body", s.authMiddleware(http.HandlerFunc(s.handleRequestBody))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/{id}/claim", s.authMiddleware(http.HandlerFunc(s.handleClaim))).Methods(http.MethodPost)
+	apiRouter.Handle("/requests/{id}/claim", s.authMiddleware(http.HandlerFunc(s.handleReleaseClaim))).Methods(http.MethodDelete)
+	apiRouter.Handle("/requests/{id}/pin", s.authMiddleware(http.HandlerFunc(s.handlePin))).Methods(http.MethodPost)
+	apiRouter.Handle("/requests/{id}/pin", s.authMiddleware(http.HandlerFunc(s.handleUnpin))).Methods(http.MethodDelete)
+	apiRouter.Handle("/requests/{id}/comments", s.authMiddleware(http.HandlerFunc(s.handleComments))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/{id}/comments", s.authMiddleware(http.HandlerFunc(s.handleAddComment))).Methods(http.MethodPost)
+	apiRouter.Handle("/requests/{id}/forwards", s.authMiddleware(http.HandlerFunc(s.handleRequestForwards))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/{id}/timeline", s.authMiddleware(http.HandlerFunc(s.handleRequestTimeline))).Methods(http.MethodGet)
+	apiRouter.Handle("/requests/{id}/reforward", s.authMiddleware(http.HandlerFunc(s.handleReforward))).Methods(http.MethodPost)
+	apiRouter.Handle("/wait", s.authMiddleware(http.HandlerFunc(s.handleWait))).Methods(http.MethodGet)
+	apiRouter.Handle("/timeline", s.authMiddleware(http.HandlerFunc(s.handleTimeline))).Methods(http.MethodGet)
+	apiRouter.Handle("/stats", s.authMiddleware(http.HandlerFunc(s.handleStats))).Methods(http.MethodGet)
 	apiRouter.Handle("/export", s.authMiddleware(http.HandlerFunc(s.handleExport))).Methods(http.MethodGet)
+	apiRouter.Handle("/export/aggregates", s.authMiddleware(http.HandlerFunc(s.handleExportAggregates))).Methods(http.MethodGet)
+	apiRouter.Handle("/import", s.authMiddleware(http.HandlerFunc(s.handleImport))).Methods(http.MethodPost)
 	apiRouter.Handle("/ws", s.authMiddleware(http.HandlerFunc(s.handleWebsocket))).Methods(http.MethodGet)
+	apiRouter.Handle("/events", s.authMiddleware(http.HandlerFunc(s.handleEvents))).Methods(http.MethodGet)
+
+	apiRouter.HandleFunc(cluster.RequestsPath, s.handleClusterRequest).Methods(http.MethodPost)
+	apiRouter.Handle("/targets", s.authMiddleware(http.HandlerFunc(s.handleTargets))).Methods(http.MethodGet)
+	apiRouter.Handle("/access", s.authMiddleware(http.HandlerFunc(s.handleAccess))).Methods(http.MethodGet)
+	apiRouter.Handle("/capture", s.authMiddleware(http.HandlerFunc(s.handleCaptureState))).Methods(http.MethodGet)
+	apiRouter.Handle("/capture/pause", s.authMiddleware(http.HandlerFunc(s.handlePauseCapture))).Methods(http.MethodPost)
+	apiRouter.Handle("/capture/resume", s.authMiddleware(http.HandlerFunc(s.handleResumeCapture))).Methods(http.MethodPost)
+	apiRouter.Handle("/tokens", s.authMiddleware(http.HandlerFunc(s.handleTokens))).Methods(http.MethodGet)
+	apiRouter.Handle("/tokens", s.authMiddleware(http.HandlerFunc(s.handleCreateToken))).Methods(http.MethodPost)
+	apiRouter.Handle("/tokens/{name}", s.authMiddleware(http.HandlerFunc(s.handleRevokeToken))).Methods(http.MethodDelete)
+	apiRouter.Handle("/audit", s.authMiddleware(http.HandlerFunc(s.handleAudit))).Methods(http.MethodGet)
+	apiRouter.Handle("/admin/reload", s.authMiddleware(http.HandlerFunc(s.handleReload))).Methods(http.MethodPost)
+	apiRouter.Handle("/admin/sequences", s.authMiddleware(http.HandlerFunc(s.handleSequences))).Methods(http.MethodGet)
+	apiRouter.Handle("/admin/sequences/reset", s.authMiddleware(http.HandlerFunc(s.handleResetSequences))).Methods(http.MethodPost)
+	apiRouter.Handle("/mock-rules", s.authMiddleware(http.HandlerFunc(s.handleMockRules))).Methods(http.MethodGet)
+	apiRouter.Handle("/mock-rules", s.authMiddleware(http.HandlerFunc(s.handleCreateMockRule))).Methods(http.MethodPost)
+	apiRouter.Handle("/mock-rules/{name}", s.authMiddleware(http.HandlerFunc(s.handleUpdateMockRule))).Methods(http.MethodPut)
+	apiRouter.Handle("/mock-rules/{name}", s.authMiddleware(http.HandlerFunc(s.handleDeleteMockRule))).Methods(http.MethodDelete)
+	apiRouter.Handle("/debug/runtime", s.authMiddleware(http.HandlerFunc(s.handleRuntime))).Methods(http.MethodGet)
+	apiRouter.Handle("/debug/pprof/", s.authMiddleware(http.HandlerFunc(s.handlePprof))).Methods(http.MethodGet)
+	apiRouter.Handle("/debug/pprof/{profile}", s.authMiddleware(http.HandlerFunc(s.handlePprof))).Methods(http.MethodGet, http.MethodPost)
 
 	// Replay routes
 	apiRouter.Handle("/replay", s.authMiddleware(http.HandlerFunc(s.handleReplay))).Methods(http.MethodPost)
@@ -104,6 +180,7 @@ func (s *Service) RegisterRoutes(router *mux.Router) {
 		router.HandleFunc(webBase+"/", s.wrapPage(indexPageName, true)).Methods(http.MethodGet)
 	}
 	router.HandleFunc(fmt.Sprintf("%s/login", webBase), s.wrapPage(loginPageName, true)).Methods(http.MethodGet)
+	router.HandleFunc(joinPath(webBase, loginLinkPath), s.handleLoginLink).Methods(http.MethodGet)
 
 	staticPrefix := webBase
 	if staticPrefix == "/" {
@@ -122,12 +199,138 @@ func (s *Service) Record(data *StoredRequest) {
 		return
 	}
 
+	s.waits.notify(data)
 	s.hub.Broadcast(map[string]interface{}{
 		"type": "request",
 		"data": data,
 	})
 }
 
+// NotifyForward pushes forward outcomes to websocket clients.
+func (s *Service) NotifyForward(requestID string, results []forwarder.Result) {
+	if s == nil || !s.cfg.Enable || len(results) == 0 {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "forward",
+		"data": map[string]interface{}{
+			"request_id": requestID,
+			"results":    results,
+		},
+	})
+}
+
+// NotifyFrame pushes a captured WebSocket frame to websocket clients.
+func (s *Service) NotifyFrame(frame *request.WebSocketFrame) {
+	if s == nil || !s.cfg.Enable || frame == nil {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "ws_frame",
+		"data": frame,
+	})
+}
+
+// NotifyAnomaly pushes a traffic anomaly to websocket clients.
+func (s *Service) NotifyAnomaly(event anomaly.Event) {
+	if s == nil || !s.cfg.Enable {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "anomaly",
+		"data": event,
+	})
+}
+
+// SetReloadHandler wires the config reload action exposed via the admin API.
+func (s *Service) SetReloadHandler(fn ReloadFunc) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.reload = fn
+	s.reloadMu.Unlock()
+}
+
+// SetTargetStats wires the forward target report exposed via /targets.
+func (s *Service) SetTargetStats(fn func() []forwarder.TargetStats) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.targetStats = fn
+	s.reloadMu.Unlock()
+}
+
+// SetReforwardHandler wires the re-forward action exposed via /requests/{id}/reforward.
+func (s *Service) SetReforwardHandler(fn ReforwardFunc) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.reforward = fn
+	s.reloadMu.Unlock()
+}
+
+// SetJWTView toggles JWT decoding in the request detail view; pages loaded afterwards pick it up.
+func (s *Service) SetJWTView(enable bool) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.jwtView = enable
+	s.reloadMu.Unlock()
+}
+
+// handleTargets reports delivery counters, circuit state and health for every forward target.
+func (s *Service) handleTargets(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.RLock()
+	targetStats := s.targetStats
+	s.reloadMu.RUnlock()
+
+	targets := []forwarder.TargetStats{}
+	if targetStats != nil {
+		targets = append(targets, targetStats()...)
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"targets": targets,
+	})
+}
+
+// SetAccessStats wires the access control counters exposed via /access.
+func (s *Service) SetAccessStats(fn func() AccessStats) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.accessStats = fn
+	s.reloadMu.Unlock()
+}
+
+// AccessStats counts capture requests rejected by access control: Denied matched a deny range,
+// NotAllowed matched no allow range.
+type AccessStats struct {
+	Rejected   int64 `json:"rejected"`
+	Denied     int64 `json:"denied"`
+	NotAllowed int64 `json:"not_allowed"`
+}
+
+// handleAccess reports the access control rejection counters.
+func (s *Service) handleAccess(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.RLock()
+	accessStats := s.accessStats
+	s.reloadMu.RUnlock()
+
+	stats := AccessStats{}
+	if accessStats != nil {
+		stats = accessStats()
+	}
+	s.respondJSON(w, http.StatusOK, stats)
+}
+
 // Close releases resources.
 func (s *Service) Close() {
 	if s == nil {
@@ -188,14 +391,15 @@ func (s *Service) handleRequests(w http.ResponseWriter, r *http.Request) {
 	if limit > maxListLimit {
 		limit = maxListLimit
 	}
-	offset := parseIntDefault(query.Get("offset"), 0)
+	opts, err := s.listFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Limit = limit
+	opts.Offset = parseIntDefault(query.Get("offset"), 0)
 
-	items, total, err := s.store.List(ListOptions{
-		Search: query.Get("search"),
-		Method: query.Get("method"),
-		Limit:  limit,
-		Offset: offset,
-	})
+	items, total, err := s.store.List(opts)
 	if err != nil {
 		s.logger.Error("Failed to list requests", "error", err)
 		http.Error(w, "Failed to fetch requests", http.StatusInternalServerError)
@@ -206,11 +410,33 @@ func (s *Service) handleRequests(w http.ResponseWriter, r *http.Request) {
 		"data":   items,
 		"total":  total,
 		"limit":  limit,
-		"offset": offset,
+		"offset": opts.Offset,
 	}
 	s.respondJSON(w, http.StatusOK, resp)
 }
 
+func (s *Service) handleRequestForwards(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	requestID := mux.Vars(r)["id"]
+	forwards, err := s.store.GetForwards(requestID)
+	if err != nil {
+		s.logger.Error("Failed to get forward responses", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to retrieve forward responses", http.StatusInternalServerError)
+		return
+	}
+	if forwards == nil {
+		forwards = []*storage.ForwardRecord{}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": requestID,
+		"forwards":   forwards,
+		"total":      len(forwards),
+	})
+}
+
 func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
 	if s.store == nil {
 		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
@@ -224,8 +450,8 @@ func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
 
 	if s.auth.Enabled() {
 		session := s.sessionFromContext(r.Context())
-		if session != nil && !s.hasRole(session, roleAdmin) {
-			http.Error(w, "Forbidden: export requires admin role", http.StatusForbidden)
+		if session != nil && !session.allows(scopeExport) {
+			http.Error(w, "Forbidden: export requires admin role or the export scope", http.StatusForbidden)
 			return
 		}
 	}
@@ -241,11 +467,10 @@ func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	opts := ListOptions{
-		Search: r.URL.Query().Get("search"),
-		Method: r.URL.Query().Get("method"),
-		Limit:  0,
-		Offset: 0,
+	opts, err := s.listFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	contentType, ext, err := describeFormat(format)
 	if err != nil {
@@ -259,17 +484,66 @@ func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
 	// 提前写入状态码，后续流式写入响应体
 	w.WriteHeader(http.StatusOK)
 
-	_, _, err = StreamExport(w, func(yield func(*StoredRequest) bool) error {
+	var iter RequestIterator = func(yield func(*StoredRequest) bool) error {
 		return s.store.Iterate(opts, func(item *StoredRequest) bool {
 			return yield(item)
 		})
-	}, format)
+	}
+	if strings.EqualFold(r.URL.Query().Get("comments"), "true") {
+		iter = s.withComments(iter)
+	}
+	exported := 0
+	counted := func(yield func(*StoredRequest) bool) error {
+		return iter(func(item *StoredRequest) bool {
+			exported++
+			return yield(item)
+		})
+	}
+	_, _, err = StreamExport(w, counted, format, s.store.GetForwards)
+	s.audit(r, storage.AuditEntry{Action: auditExport, Success: err == nil, Details: map[string]string{
+		"format": format, "query": r.URL.RawQuery, "requests": strconv.Itoa(exported),
+	}})
 	if err != nil {
 		s.logger.Error("Export failed", "error", err)
 		return
 	}
 }
 
+func (s *Service) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: reload requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	s.reloadMu.RLock()
+	reload := s.reload
+	s.reloadMu.RUnlock()
+	if reload == nil {
+		http.Error(w, "reload unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	restartRequired, err := reload()
+	s.audit(r, storage.AuditEntry{Action: auditConfigReload, Success: err == nil})
+	if err != nil {
+		s.respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"reloaded": false,
+			"error":    err.Error(),
+		})
+		return
+	}
+	if restartRequired == nil {
+		restartRequired = []string{}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"reloaded":         true,
+		"restart_required": restartRequired,
+	})
+}
+
 func (s *Service) handleLogin(w http.ResponseWriter, r *http.Request) {
 	var creds struct {
 		Username string `json:"username"`
@@ -282,9 +556,13 @@ func (s *Service) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	session, err := s.auth.Login(creds.Username, creds.Password)
 	if err != nil {
+		s.audit(r, storage.AuditEntry{Action: auditLoginFailed, Actor: strings.ToLower(strings.TrimSpace(creds.Username))})
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
+	if s.auth.Enabled() {
+		s.audit(r, storage.AuditEntry{Action: auditLogin, Actor: session.Username, Success: true})
+	}
 
 	if s.auth.Enabled() {
 		http.SetCookie(w, &http.Cookie{
@@ -305,6 +583,46 @@ func (s *Service) handleLogin(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// LoginLink issues a one-time login token per web.auth.login_link and returns the console path
+// that redeems it. It returns an empty path when authentication or the login link is disabled.
+func (s *Service) LoginLink() (string, time.Time, error) {
+	if s == nil || !s.cfg.Enable || !s.auth.Enabled() || !s.cfg.Auth.LoginLink.Enable {
+		return "", time.Time{}, nil
+	}
+	link := s.cfg.Auth.LoginLink
+	token, err := s.auth.IssueLoginToken(link.User, link.TTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return joinPath(s.cfg.Path, loginLinkPath) + "?token=" + token, time.Now().Add(link.TTL), nil
+}
+
+// handleLoginLink redeems a one-time login token, sets the session cookie and opens the console.
+// Used, expired or unknown tokens lead to the regular login page.
+func (s *Service) handleLoginLink(w http.ResponseWriter, r *http.Request) {
+	webBase := normalizePath(s.cfg.Path)
+	// Keep the token out of Referer headers sent by the console
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	session, err := s.auth.RedeemLoginToken(r.URL.Query().Get("token"))
+	if err != nil {
+		s.audit(r, storage.AuditEntry{Action: auditLoginLink})
+		http.Redirect(w, r, fmt.Sprintf("%s/login", webBase), http.StatusFound)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    session.ID,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  session.ExpiresAt,
+		Secure:   r.TLS != nil,
+	})
+	s.logger.Info("Web console login link used", "username", session.Username, "remote_addr", r.RemoteAddr)
+	s.audit(r, storage.AuditEntry{Action: auditLoginLink, Actor: session.Username, Success: true})
+	http.Redirect(w, r, joinPath(webBase, "/"), http.StatusFound)
+}
+
 func (s *Service) handleLogout(w http.ResponseWriter, r *http.Request) {
 	if cookie, err := r.Cookie(sessionCookieName); err == nil {
 		s.auth.Logout(cookie.Value)
@@ -331,6 +649,7 @@ func (s *Service) handleMe(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"username": session.Username,
 		"role":     session.Role,
+		"scopes":   session.Scopes,
 		"auth":     s.auth.Enabled(),
 	})
 }
@@ -343,12 +662,59 @@ func (s *Service) handleWebsocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if _, err := s.hub.Upgrade(w, r); err != nil {
+	backfill, err := s.historyBackfill(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := s.hub.Upgrade(w, r, backfill); err != nil {
 		s.logger.Error("Failed to upgrade websocket", "error", err)
 		return
 	}
 }
 
+// historyBackfill builds the "history" event replayed to a new websocket client: the latest
+// web.websocket.history (or ?history=N) stored requests, narrowed by the /requests filters.
+// It returns nil when no backfill is wanted.
+func (s *Service) historyBackfill(r *http.Request) (func() interface{}, error) {
+	query := r.URL.Query()
+	limit := s.cfg.WebSocket.History
+	if value := query.Get("history"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid history %q", value)
+		}
+		limit = parsed
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	if limit == 0 || s.store == nil {
+		return nil, nil
+	}
+	opts, err := s.listFilters(r)
+	if err != nil {
+		return nil, err
+	}
+	opts.Limit = limit
+
+	return func() interface{} {
+		items, total, err := s.store.List(opts)
+		if err != nil {
+			s.logger.Error("Failed to load websocket history", "error", err)
+			return nil
+		}
+		if items == nil {
+			items = []*StoredRequest{}
+		}
+		return map[string]interface{}{
+			"type":  "history",
+			"data":  items,
+			"total": total,
+		}
+	}, nil
+}
+
 func (s *Service) redirectTo(target string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, target, http.StatusPermanentRedirect)
@@ -388,19 +754,26 @@ func (s *Service) wrapPage(page string, injectConfig bool) http.HandlerFunc {
 }
 
 func (s *Service) injectConfig(content []byte) []byte {
+	s.reloadMu.RLock()
+	jwtView := s.jwtView
+	s.reloadMu.RUnlock()
+
 	configScript := map[string]interface{}{
 		"apiBase":          normalizePath(s.cfg.AdminPath),
 		"wsEndpoint":       joinPath(s.cfg.AdminPath, "/ws"),
+		"eventsEndpoint":   joinPath(s.cfg.AdminPath, "/events"),
 		"exportFormats":    s.formats,
 		"authEnabled":      s.auth.Enabled(),
 		"webBase":          normalizePath(s.cfg.Path),
 		"maxRequests":      s.cfg.MaxRequests,
+		"wsHistory":        s.cfg.WebSocket.History,
 		"exportEnabled":    s.cfg.Export.Enable,
 		"sessionTimeout":   s.cfg.Auth.SessionTimeout.String(),
 		"roleAdmin":        roleAdmin,
 		"roleViewer":       roleViewer,
 		"defaultLocale":    s.cfg.DefaultLocale,
 		"supportedLocales": s.cfg.SupportedLocales,
+		"jwtView":          jwtView,
 	}
 
 	payload, _ := json.Marshal(configScript)
@@ -498,10 +871,3 @@ func containsFormat(formats []string, target string) bool {
 	}
 	return false
 }
-
-func (s *Service) hasRole(session *Session, role string) bool {
-	if session == nil {
-		return false
-	}
-	return strings.EqualFold(session.Role, role)
-}
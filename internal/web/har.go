@@ -0,0 +1,296 @@
+package web
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// harVersion is the HAR spec revision understood by Chrome DevTools, Insomnia and Fiddler.
+const harVersion = "1.2"
+
+// ForwardLookup returns the recorded forward responses of a request; nil skips them.
+type ForwardLookup func(requestID string) ([]*storage.ForwardRecord, error)
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []harNameValue `json:"params"`
+	Text     string         `json:"text"`
+	Encoding string         `json:"_encoding,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string             `json:"startedDateTime"`
+	Time            float64            `json:"time"`
+	Request         harRequest         `json:"request"`
+	Response        harResponse        `json:"response"`
+	Cache           struct{}           `json:"cache"`
+	Timings         harTimings         `json:"timings"`
+	Comment         string             `json:"comment,omitempty"`
+	RequestID       string             `json:"_reqtapId"`
+	Kind            string             `json:"_reqtapKind"`
+	OverBudget      bool               `json:"_overBudget,omitempty"`
+	Tags            []string           `json:"_reqtapTags,omitempty"`
+	Note            string             `json:"_reqtapNote,omitempty"`
+	Comments        []*storage.Comment `json:"_reqtapComments,omitempty"`
+}
+
+// streamHAR writes one entry per captured request, followed by one entry per forward target it reached.
+func streamHAR(w io.Writer, iter RequestIterator, forwards ForwardLookup) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	if _, err := bw.WriteString(`{"log":{"version":"` + harVersion + `","creator":{"name":"ReqTap","version":"1.0"},"pages":[],"entries":[`); err != nil {
+		return err
+	}
+	first := true
+	var writeErr error
+	write := func(entry harEntry) bool {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			writeErr = err
+			return false
+		}
+		if !first {
+			if _, writeErr = bw.WriteString(","); writeErr != nil {
+				return false
+			}
+		}
+		first = false
+		_, writeErr = bw.Write(b)
+		return writeErr == nil
+	}
+	if err := iter(func(item *StoredRequest) bool {
+		if writeErr != nil || item == nil || item.RequestData == nil {
+			return writeErr == nil
+		}
+		if !write(harCaptureEntry(item)) {
+			return false
+		}
+		if forwards == nil {
+			return true
+		}
+		records, err := forwards(item.ID)
+		if err != nil {
+			writeErr = err
+			return false
+		}
+		for _, record := range records {
+			if !write(harForwardEntry(item, record)) {
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	_, err := bw.WriteString("]}}")
+	return err
+}
+
+// harCaptureEntry pairs the captured request with the mock response ReqTap answered; the mock body is not stored.
+func harCaptureEntry(item *StoredRequest) harEntry {
+	status := item.MockResponse.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	entry := harEntry{
+		StartedDateTime: item.Timestamp.Format(time.RFC3339Nano),
+		Request:         harRequestOf(item, harCaptureURL(item)),
+		Response: harResponse{
+			Status:      status,
+			StatusText:  http.StatusText(status),
+			HTTPVersion: harHTTPVersion(item.Proto),
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			Content:     harContent{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		RequestID: item.ID,
+		Kind:      "capture",
+		Tags:      item.Tags,
+		Note:      item.Note,
+		Comments:  item.Comments,
+	}
+	if item.MockResponse.Rule != "" {
+		entry.Comment = "mock response rule: " + item.MockResponse.Rule
+	}
+	return entry
+}
+
+// harForwardEntry describes the request ReqTap relayed to a forward target and what the target answered.
+func harForwardEntry(item *StoredRequest, record *storage.ForwardRecord) harEntry {
+	started := record.Timestamp.Add(-time.Duration(record.LatencyMs) * time.Millisecond)
+	latency := float64(record.LatencyMs)
+	entry := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            latency,
+		Request:         harRequestOf(item, record.TargetURL),
+		Response: harResponse{
+			Status:      record.StatusCode,
+			StatusText:  http.StatusText(record.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(record.Headers),
+			Content:     harContentOf(record.Body, record.Headers.Get("Content-Type")),
+			RedirectURL: record.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(record.Body),
+		},
+		Timings:   harTimings{Wait: latency},
+		RequestID: item.ID,
+		Kind:      "forward",
+	}
+	var notes []string
+	if record.Error != "" {
+		notes = append(notes, "forward failed: "+record.Error)
+	} else if record.BodyTruncated {
+		notes = append(notes, "response body truncated")
+	}
+	if record.OverBudget {
+		notes = append(notes, fmt.Sprintf("exceeded the %dms provider latency budget", record.LatencyBudgetMs))
+	}
+	entry.Comment = strings.Join(notes, "; ")
+	entry.OverBudget = record.OverBudget
+	return entry
+}
+
+func harRequestOf(item *StoredRequest, target string) harRequest {
+	req := harRequest{
+		Method:      strings.ToUpper(item.Method),
+		URL:         target,
+		HTTPVersion: harHTTPVersion(item.Proto),
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(item.Headers),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(item.Body),
+	}
+	if values, err := url.ParseQuery(item.Query); err == nil {
+		for _, key := range sortedHeaderKeys(values) {
+			for _, value := range values[key] {
+				req.QueryString = append(req.QueryString, harNameValue{Name: key, Value: value})
+			}
+		}
+	}
+	for _, cookie := range (&http.Request{Header: item.Headers}).Cookies() {
+		req.Cookies = append(req.Cookies, harNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	if len(item.Body) > 0 {
+		post := &harPostData{MimeType: item.ContentType, Params: []harNameValue{}}
+		if item.IsBinary || !utf8.Valid(item.Body) {
+			// HAR 1.2 has no encoding for request bodies, so mark the base64 text explicitly
+			post.Text = base64.StdEncoding.EncodeToString(item.Body)
+			post.Encoding = "base64"
+		} else {
+			post.Text = string(item.Body)
+		}
+		req.PostData = post
+	}
+	return req
+}
+
+func harContentOf(body []byte, mimeType string) harContent {
+	content := harContent{Size: len(body), MimeType: mimeType}
+	if content.MimeType == "" {
+		content.MimeType = "x-unknown"
+	}
+	if len(body) == 0 {
+		return content
+	}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+// harCaptureURL rebuilds the absolute URL the client called from the forwarded/Host headers.
+func harCaptureURL(item *StoredRequest) string {
+	scheme := "http"
+	if proto := item.Headers.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	host := item.Headers.Get("X-Forwarded-Host")
+	if host == "" {
+		host = item.Headers.Get("Host")
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return scheme + "://" + host + composeFullPath(item)
+}
+
+func harHeaders(headers http.Header) []harNameValue {
+	result := []harNameValue{}
+	for _, key := range sortedHeaderKeys(headers) {
+		for _, value := range headers[key] {
+			result = append(result, harNameValue{Name: key, Value: value})
+		}
+	}
+	return result
+}
+
+func harHTTPVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/importer"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// maxImportBytes bounds an uploaded HAR/ngrok document.
+const maxImportBytes = 64 << 20
+
+// handleImport stores the requests of an uploaded HAR or ngrok export so they can be browsed and replayed.
+func (s *Service) handleImport(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: import requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Import exceeds %d bytes", maxImportBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read import", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	scenario := strings.TrimSpace(query.Get("scenario"))
+	items, format, err := importer.Parse(data, query.Get("format"), scenario)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid import: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		stored, err := s.store.Record(item)
+		if err != nil {
+			s.logger.Error("Failed to store imported request", "error", err)
+			http.Error(w, "Failed to store imported requests", http.StatusInternalServerError)
+			return
+		}
+		ids = append(ids, stored.ID)
+		s.Record(stored)
+	}
+
+	s.logger.Info("Requests imported", "format", format, "scenario", scenario, "count", len(ids))
+	s.audit(r, storage.AuditEntry{Action: auditImport, Success: true, Details: map[string]string{
+		"format": format, "scenario": scenario, "requests": strconv.Itoa(len(ids)),
+	}})
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"format":   format,
+		"scenario": scenario,
+		"imported": len(ids),
+		"ids":      ids,
+	})
+}
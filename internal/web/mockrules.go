@@ -0,0 +1,242 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// Sources of a MockRule
+const (
+	MockRuleSourceConfig = "config"
+	MockRuleSourceAPI    = "api"
+)
+
+var (
+	ErrMockRuleExists     = errors.New("a mock rule with this name already exists")
+	ErrMockRuleNotFound   = errors.New("mock rule not found")
+	ErrMockRuleConfigured = errors.New("mock rule is defined in the config file")
+)
+
+// MockRule is a server.responses rule as reported by /api/mock-rules. Rules added through the API
+// are matched before the rules of the config file and replace a config rule with the same name.
+type MockRule struct {
+	Rule config.ImmediateResponseConfig
+	// Source is "config" for rules of the config file and "api" for rules managed through the API
+	Source    string
+	UpdatedBy string
+	UpdatedAt *time.Time
+}
+
+// MarshalJSON reports the rule in the shape of a server.responses entry, e.g. "delay": "500ms".
+func (m MockRule) MarshalJSON() ([]byte, error) {
+	rule, err := mockRuleDocument(m.Rule)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Source    string                 `json:"source"`
+		Rule      map[string]interface{} `json:"rule"`
+		UpdatedBy string                 `json:"updated_by,omitempty"`
+		UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+	}{m.Source, rule, m.UpdatedBy, m.UpdatedAt})
+}
+
+// MockRuleManager applies and persists the mock rules changed through the API.
+type MockRuleManager interface {
+	// MockRules lists the effective server.responses rules in match order.
+	MockRules() []MockRule
+	// SaveMockRule adds a rule (ErrMockRuleExists when the name is taken) or, with replace, replaces
+	// the rule of that name (ErrMockRuleNotFound when there is none).
+	SaveMockRule(rule config.ImmediateResponseConfig, updatedBy string, replace bool) error
+	// DeleteMockRule removes a rule added through the API; config file rules report
+	// ErrMockRuleConfigured.
+	DeleteMockRule(name string) error
+}
+
+// SetMockRuleManager wires the /mock-rules endpoints; without a manager they answer 503.
+func (s *Service) SetMockRuleManager(manager MockRuleManager) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.mockRules = manager
+	s.reloadMu.Unlock()
+}
+
+func (s *Service) mockRuleManager(w http.ResponseWriter) MockRuleManager {
+	s.reloadMu.RLock()
+	manager := s.mockRules
+	s.reloadMu.RUnlock()
+	if manager == nil {
+		http.Error(w, "mock rules unavailable", http.StatusServiceUnavailable)
+	}
+	return manager
+}
+
+// requireMockRuleAdmin answers the request and returns false unless the caller may change mock rules.
+func (s *Service) requireMockRuleAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: changing mock rules requires admin role", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// handleMockRules lists the server.responses rules in match order.
+func (s *Service) handleMockRules(w http.ResponseWriter, r *http.Request) {
+	manager := s.mockRuleManager(w)
+	if manager == nil {
+		return
+	}
+	rules := []MockRule{}
+	rules = append(rules, manager.MockRules()...)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"rules": rules,
+	})
+}
+
+// handleCreateMockRule adds a rule that is matched before the rules of the config file.
+func (s *Service) handleCreateMockRule(w http.ResponseWriter, r *http.Request) {
+	s.saveMockRule(w, r, "", false)
+}
+
+// handleUpdateMockRule replaces a rule; replacing a config file rule shadows it until the API rule
+// is deleted again.
+func (s *Service) handleUpdateMockRule(w http.ResponseWriter, r *http.Request) {
+	s.saveMockRule(w, r, mux.Vars(r)["name"], true)
+}
+
+func (s *Service) saveMockRule(w http.ResponseWriter, r *http.Request, name string, replace bool) {
+	if !s.requireMockRuleAdmin(w, r) {
+		return
+	}
+	manager := s.mockRuleManager(w)
+	if manager == nil {
+		return
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule, err := decodeMockRule(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if replace {
+		if rule.Name != "" && rule.Name != name {
+			http.Error(w, "name cannot be changed; delete the rule and add it again", http.StatusBadRequest)
+			return
+		}
+		rule.Name = name
+	}
+	if rule.Status == 0 {
+		rule.Status = http.StatusOK
+	}
+	if err := config.ValidateMockRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedBy := ""
+	if session := s.sessionFromContext(r.Context()); session != nil {
+		updatedBy = session.Username
+	}
+	err = manager.SaveMockRule(rule, updatedBy, replace)
+	switch {
+	case errors.Is(err, ErrMockRuleExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, ErrMockRuleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		s.logger.Error("Failed to save mock rule", "rule", rule.Name, "error", err)
+		http.Error(w, "Failed to save mock rule", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Mock rule saved", "rule", rule.Name, "updated_by", updatedBy)
+	s.audit(r, storage.AuditEntry{Action: auditMockRuleSave, Target: rule.Name, Success: true, Details: map[string]string{
+		"replace": strconv.FormatBool(replace),
+	}})
+	status := http.StatusCreated
+	if replace {
+		status = http.StatusOK
+	}
+	now := time.Now().UTC()
+	s.respondJSON(w, status, MockRule{Rule: rule, Source: MockRuleSourceAPI, UpdatedBy: updatedBy, UpdatedAt: &now})
+}
+
+// handleDeleteMockRule removes a rule added through the API; a config file rule it replaced
+// applies again.
+func (s *Service) handleDeleteMockRule(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMockRuleAdmin(w, r) {
+		return
+	}
+	manager := s.mockRuleManager(w)
+	if manager == nil {
+		return
+	}
+	name := mux.Vars(r)["name"]
+	switch err := manager.DeleteMockRule(name); {
+	case errors.Is(err, ErrMockRuleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrMockRuleConfigured):
+		http.Error(w, "mock rule is defined in the config file; remove it there", http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Error("Failed to delete mock rule", "rule", name, "error", err)
+		http.Error(w, "Failed to delete mock rule", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Mock rule deleted", "rule", name)
+	s.audit(r, storage.AuditEntry{Action: auditMockRuleDelete, Target: name, Success: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeMockRule reads a rule keyed like a server.responses entry; unknown keys are rejected so
+// that typos do not silently drop a setting.
+func decodeMockRule(doc map[string]interface{}) (config.ImmediateResponseConfig, error) {
+	var rule config.ImmediateResponseConfig
+	// Going through YAML decodes durations such as "500ms" like the config file does
+	raw, err := yaml.Marshal(doc)
+	if err != nil {
+		return rule, fmt.Errorf("invalid mock rule: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rule); err != nil {
+		return rule, fmt.Errorf("invalid mock rule: %w", err)
+	}
+	return rule, nil
+}
+
+// mockRuleDocument converts a rule to a generic document keyed like a server.responses entry
+func mockRuleDocument(rule config.ImmediateResponseConfig) (map[string]interface{}, error) {
+	raw, err := yaml.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeMockRule(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"name":"slow","path_prefix":"/reqtap/pay","status":503,"delay":"1.5s","headers":{"retry-after":"30"}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := decodeMockRule(doc)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if rule.Status != 503 || rule.Delay != 1500*time.Millisecond || rule.PathPrefix != "/reqtap/pay" {
+		t.Fatalf("unexpected rule: %#v", rule)
+	}
+
+	encoded, err := json.Marshal(MockRule{Rule: rule, Source: MockRuleSourceAPI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"delay":"1.5s"`) || strings.Contains(string(encoded), `"body"`) {
+		t.Fatalf("expected the rule keyed like the config file, got %s", encoded)
+	}
+
+	if _, err := decodeMockRule(map[string]interface{}{"name": "typo", "staus": 500}); err == nil {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// handlePin keeps a request out of retention and max_records pruning.
+func (s *Service) handlePin(w http.ResponseWriter, r *http.Request) {
+	s.updatePin(w, r, true)
+}
+
+// handleUnpin lets pruning evict a request again.
+func (s *Service) handleUnpin(w http.ResponseWriter, r *http.Request) {
+	s.updatePin(w, r, false)
+}
+
+func (s *Service) updatePin(w http.ResponseWriter, r *http.Request, pinned bool) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !s.requireWrite(w, r) {
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+	err := s.store.Pin(requestID, pinned)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		s.logger.Error("Failed to update pin", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to update pin", http.StatusInternalServerError)
+		return
+	}
+
+	s.NotifyPin(requestID, pinned)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": requestID,
+		"pinned":     pinned,
+	})
+}
+
+// pinnedFilter reads the pinned query parameter; only "true" filters.
+func pinnedFilter(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.URL.Query().Get("pinned")), "true")
+}
+
+// NotifyPin pushes a pin change to websocket clients.
+func (s *Service) NotifyPin(requestID string, pinned bool) {
+	if s == nil || !s.cfg.Enable {
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "pin",
+		"data": map[string]interface{}{
+			"request_id": requestID,
+			"pinned":     pinned,
+		},
+	})
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Processing steps of a request timeline
+const (
+	StepReceived         = "received"
+	StepStored           = "stored"
+	StepPrinted          = "printed"
+	StepForwardStarted   = "forward_started"
+	StepForwardCompleted = "forward_completed"
+)
+
+// ProcessingTimeline lists what happened to a captured request, in order, so a slow stage stands
+// out.
+type ProcessingTimeline struct {
+	RequestID  string           `json:"request_id"`
+	ReceivedAt time.Time        `json:"received_at"`
+	Steps      []ProcessingStep `json:"steps"`
+	// Partial marks a timeline rebuilt from the stored request and forwards, because the recorded
+	// one was evicted or lost in a restart; only received and forward_completed steps are known
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ProcessingStep is one step of a ProcessingTimeline. At is when the step ended and DurationMs
+// how long it took; for forward_started it is the wait for the target's limits and a worker.
+type ProcessingStep struct {
+	Step       string    `json:"step"`
+	Target     string    `json:"target,omitempty"`
+	At         time.Time `json:"at"`
+	OffsetMs   float64   `json:"offset_ms"`
+	DurationMs float64   `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	Success    *bool     `json:"success,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SetProcessingTimelines wires the recorded timelines exposed via /requests/{id}/timeline.
+func (s *Service) SetProcessingTimelines(fn func(requestID string) (ProcessingTimeline, bool)) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.timelines = fn
+	s.reloadMu.Unlock()
+}
+
+// handleRequestTimeline reports the processing timeline of a request, rebuilt from storage when
+// it is no longer recorded.
+func (s *Service) handleRequestTimeline(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["id"]
+	s.reloadMu.RLock()
+	timelines := s.timelines
+	s.reloadMu.RUnlock()
+	if timelines != nil {
+		if timeline, ok := timelines(requestID); ok {
+			s.respondJSON(w, http.StatusOK, timeline)
+			return
+		}
+	}
+
+	if s.store == nil {
+		http.Error(w, "request not found", http.StatusNotFound)
+		return
+	}
+	item, err := s.store.Get(requestID)
+	if err != nil {
+		s.logger.Error("Failed to get request", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to retrieve request", http.StatusInternalServerError)
+		return
+	}
+	if item == nil || item.RequestData == nil {
+		http.Error(w, "request not found", http.StatusNotFound)
+		return
+	}
+	forwards, err := s.store.GetForwards(requestID)
+	if err != nil {
+		s.logger.Error("Failed to get forward responses", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to retrieve forward responses", http.StatusInternalServerError)
+		return
+	}
+
+	received := item.Timestamp
+	timeline := ProcessingTimeline{
+		RequestID:  requestID,
+		ReceivedAt: received,
+		Steps:      []ProcessingStep{{Step: StepReceived, At: received}},
+		Partial:    true,
+	}
+	for _, forward := range forwards {
+		success := forward.Success
+		timeline.AddStep(ProcessingStep{
+			Step:       StepForwardCompleted,
+			Target:     forward.TargetURL,
+			At:         forward.Timestamp,
+			StatusCode: forward.StatusCode,
+			Attempts:   forward.Attempts,
+			Success:    &success,
+			Error:      forward.Error,
+		}, time.Duration(forward.LatencyMs)*time.Millisecond)
+	}
+	sort.SliceStable(timeline.Steps, func(i, j int) bool {
+		return timeline.Steps[i].At.Before(timeline.Steps[j].At)
+	})
+	s.respondJSON(w, http.StatusOK, timeline)
+}
+
+// AddStep appends step, which ended at step.At and took took, with its offset from ReceivedAt
+func (t *ProcessingTimeline) AddStep(step ProcessingStep, took time.Duration) {
+	step.OffsetMs = milliseconds(step.At.Sub(t.ReceivedAt))
+	step.DurationMs = milliseconds(took)
+	t.Steps = append(t.Steps, step)
+}
+
+// milliseconds converts d to fractional milliseconds, the unit of timeline offsets and durations
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
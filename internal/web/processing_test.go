@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestRequestTimeline(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	received := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := store.Record(&request.RequestData{ID: "REQ", Timestamp: received, Method: http.MethodPost, Path: "/hook"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	forwards := []*storage.ForwardRecord{{TargetURL: "http://upstream.test", Timestamp: received.Add(300 * time.Millisecond), StatusCode: http.StatusOK, LatencyMs: 250, Attempts: 1, Success: true}}
+	if err := store.RecordForwards("REQ", forwards); err != nil {
+		t.Fatalf("record forwards: %v", err)
+	}
+
+	svc := NewService(&config.WebConfig{Enable: true, Path: "/web", AdminPath: "/api"}, store, noopLogger{})
+	defer svc.Close()
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+	get := func(id string) (int, ProcessingTimeline) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/requests/"+id+"/timeline", nil))
+		var timeline ProcessingTimeline
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &timeline); err != nil {
+				t.Fatalf("invalid timeline %q: %v", rr.Body.String(), err)
+			}
+		}
+		return rr.Code, timeline
+	}
+
+	// Without a recorded timeline it is rebuilt from the stored forwards
+	code, timeline := get("REQ")
+	if code != http.StatusOK || !timeline.Partial || len(timeline.Steps) != 2 {
+		t.Fatalf("expected a partial timeline, got %d %+v", code, timeline)
+	}
+	if step := timeline.Steps[1]; step.Step != StepForwardCompleted || step.OffsetMs != 300 || step.DurationMs != 250 || step.StatusCode != http.StatusOK {
+		t.Fatalf("expected the forward from storage, got %+v", step)
+	}
+	if code, _ := get("missing"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown request, got %d", code)
+	}
+
+	svc.SetProcessingTimelines(func(id string) (ProcessingTimeline, bool) {
+		recorded := ProcessingTimeline{RequestID: id, ReceivedAt: received}
+		recorded.AddStep(ProcessingStep{Step: StepStored, At: received.Add(2 * time.Millisecond)}, 1500*time.Microsecond)
+		return recorded, id == "REQ"
+	})
+	code, timeline = get("REQ")
+	if code != http.StatusOK || timeline.Partial || len(timeline.Steps) != 1 || timeline.Steps[0].OffsetMs != 2 || timeline.Steps[0].DurationMs != 1.5 {
+		t.Fatalf("expected the recorded timeline, got %d %+v", code, timeline)
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+// handleReforward pushes a stored request through the configured forward targets again. Unlike
+// replay, which sends to an arbitrary URL, this uses the production path: filters, path strategy,
+// header rules, retries and the circuit breaker all apply, and the outcomes join the request's
+// forward history.
+func (s *Service) handleReforward(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: re-forward requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	s.reloadMu.RLock()
+	reforward := s.reforward
+	s.reloadMu.RUnlock()
+	if reforward == nil {
+		http.Error(w, "forwarding unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+	item, err := s.store.Get(requestID)
+	if err != nil {
+		s.logger.Error("Failed to get request for re-forward", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to retrieve request", http.StatusInternalServerError)
+		return
+	}
+	if item == nil || item.RequestData == nil {
+		http.Error(w, "request not found", http.StatusNotFound)
+		return
+	}
+
+	results, err := reforward(r.Context(), item.RequestData)
+	s.audit(r, storage.AuditEntry{Action: auditReforward, Target: requestID, Success: err == nil})
+	if errors.Is(err, forwarder.ErrNoTargets) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to re-forward request", "request_id", requestID, "error", err)
+		http.Error(w, "Failed to re-forward request", http.StatusBadGateway)
+		return
+	}
+	if results == nil {
+		results = []forwarder.Result{}
+	}
+	delivered := 0
+	for _, res := range results {
+		if res.Success {
+			delivered++
+		}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": requestID,
+		"results":    results,
+		"delivered":  delivered,
+		"total":      len(results),
+	})
+}
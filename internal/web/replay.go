@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/funnyzak/reqtap/internal/storage"
 	"github.com/funnyzak/reqtap/pkg/request"
 )
 
@@ -21,6 +22,13 @@ func (s *Service) handleReplay(w http.ResponseWriter, r *http.Request) {
 		s.logger.Error("Storage not configured for web service")
 		return
 	}
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeReplay) {
+			http.Error(w, "Forbidden: replay requires the replay scope", http.StatusForbidden)
+			return
+		}
+	}
 
 	// Parse replay request
 	var req request.ReplayRequest
@@ -52,15 +60,24 @@ func (s *Service) handleReplay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Vars of a chained replay are resolved before anything references them
+	vars, err := s.resolveReplayVars(req.RequestID, req.Vars)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Use provided values or fallback to original
 	method := req.Method
 	if method == "" {
 		method = originalReq.Method
 	}
 
-	headers := req.Headers
-	if headers == nil {
-		headers = make(map[string]string)
+	headers := make(map[string]string)
+	for k, v := range req.Headers {
+		headers[k] = vars.expand(v)
+	}
+	if req.Headers == nil {
 		for k, v := range originalReq.Headers {
 			if len(v) > 0 {
 				headers[k] = v[0]
@@ -68,23 +85,46 @@ func (s *Service) handleReplay(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	body := []byte(req.Body)
-	if len(body) == 0 {
+	body := []byte(vars.expand(req.Body))
+	if len(body) == 0 && originalReq.BodyFile != "" {
+		// Only a preview of a spilled body is kept in memory; re-forward streams the full file
+		http.Error(w, "request body was spilled to disk; use re-forward to resend it in full", http.StatusConflict)
+		return
+	}
+	if len(body) == 0 && req.Headers == nil {
+		// The original headers still carry Content-Encoding, so send the body as it was received
+		body = originalReq.ForwardBody()
+	} else if len(body) == 0 {
 		body = originalReq.Body
 	}
+	if len(req.BodySet) > 0 {
+		if len(req.Body) == 0 {
+			// Fields are set on the decoded body, which is sent without its original encoding
+			body = originalReq.Body
+			deleteHeader(headers, "Content-Encoding")
+			deleteHeader(headers, "Content-Length")
+		}
+		if body, err = vars.applyBodySet(body, req.BodySet); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Build target URL with query
-	targetURL := req.TargetURL
-	if req.Query != "" {
+	targetURL := vars.expand(req.TargetURL)
+	if query := vars.expand(req.Query); query != "" {
 		if strings.Contains(targetURL, "?") {
-			targetURL += "&" + req.Query
+			targetURL += "&" + query
 		} else {
-			targetURL += "?" + req.Query
+			targetURL += "?" + query
 		}
 	}
 
 	// Perform replay
 	replayData, err := s.performReplay(r.Context(), method, targetURL, headers, body, req.RequestID)
+	s.audit(r, storage.AuditEntry{Action: auditReplay, Target: req.RequestID, Success: err == nil, Details: map[string]string{
+		"method": method, "target_url": targetURL,
+	}})
 	if err != nil {
 		s.logger.Error("Failed to perform replay", "error", err)
 		http.Error(w, "Failed to replay request", http.StatusInternalServerError)
@@ -106,6 +146,7 @@ func (s *Service) handleReplay(w http.ResponseWriter, r *http.Request) {
 		ResponseBody: string(replayData.ResponseBody),
 		ResponseTime: replayData.ResponseTimeMs,
 		Error:        replayData.Error,
+		Vars:         vars.strings(),
 	}
 
 	w.Header().Set("Content-Type", contentTypeJSON)
@@ -221,6 +262,15 @@ func (s *Service) performReplay(ctx context.Context, method, targetURL string, h
 	return replayData, nil
 }
 
+// deleteHeader removes a header from a replay header map regardless of its case
+func deleteHeader(headers map[string]string, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
 // parseURL safely parses a URL
 func parseURL(rawURL string) (*url.URL, error) {
 	u, err := url.Parse(rawURL)
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/jsonpath"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Sources of a replay var
+const (
+	replayVarFromRequest = "request"
+	replayVarFromReplay  = "replay"
+)
+
+// replayVars holds the resolved vars of a chained replay by name
+type replayVars map[string]interface{}
+
+// resolveReplayVars extracts every var; replayedID is the request read by vars without a request_id.
+func (s *Service) resolveReplayVars(replayedID string, vars []request.ReplayVar) (replayVars, error) {
+	values := make(replayVars, len(vars))
+	for i, v := range vars {
+		if v.Name == "" {
+			return nil, fmt.Errorf("var %d needs a name", i+1)
+		}
+		if _, ok := values[v.Name]; ok {
+			return nil, fmt.Errorf("var %q is defined twice", v.Name)
+		}
+		value, err := s.resolveReplayVar(replayedID, v)
+		if err != nil {
+			return nil, fmt.Errorf("var %q: %w", v.Name, err)
+		}
+		values[v.Name] = value
+	}
+	return values, nil
+}
+
+func (s *Service) resolveReplayVar(replayedID string, v request.ReplayVar) (interface{}, error) {
+	requestID := v.RequestID
+	if requestID == "" {
+		requestID = replayedID
+	}
+	if v.JSONPath != "" && v.Header != "" {
+		return nil, fmt.Errorf("set json_path or header, not both")
+	}
+
+	var body []byte
+	switch v.From {
+	case "", replayVarFromRequest:
+		stored, err := s.store.Get(requestID)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && stored == nil) {
+			return nil, fmt.Errorf("request %s not found", requestID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v.Header != "" {
+			values := stored.Headers.Values(v.Header)
+			if len(values) == 0 {
+				return nil, fmt.Errorf("header %s not found in request %s", v.Header, requestID)
+			}
+			return strings.Join(values, ", "), nil
+		}
+		body = stored.Body
+	case replayVarFromReplay:
+		if v.Header != "" {
+			return nil, fmt.Errorf("replay responses keep no headers; use json_path")
+		}
+		replay, err := s.findReplay(requestID, v.ReplayID)
+		if err != nil {
+			return nil, err
+		}
+		body = replay.ResponseBody
+	default:
+		return nil, fmt.Errorf("from must be %q or %q", replayVarFromRequest, replayVarFromReplay)
+	}
+
+	if v.JSONPath == "" {
+		return string(body), nil
+	}
+	value, ok := jsonpath.LookupBytes(body, v.JSONPath)
+	if !ok {
+		return nil, fmt.Errorf("json_path %s not found", v.JSONPath)
+	}
+	return value, nil
+}
+
+// findReplay returns replay replayID of a request, or its latest replay when replayID is empty
+func (s *Service) findReplay(requestID, replayID string) (*storage.StoredReplay, error) {
+	replays, err := s.store.GetReplays(requestID)
+	if err != nil {
+		return nil, err
+	}
+	for _, replay := range replays {
+		if replay.ReplayData == nil {
+			continue
+		}
+		// Replays are listed newest first
+		if replayID == "" || replay.ID == replayID {
+			return replay, nil
+		}
+	}
+	if replayID == "" {
+		return nil, fmt.Errorf("request %s has not been replayed", requestID)
+	}
+	return nil, fmt.Errorf("replay %s of request %s not found", replayID, requestID)
+}
+
+// expand replaces every {{name}} of a defined var in text
+func (values replayVars) expand(text string) string {
+	if len(values) == 0 || !strings.Contains(text, "{{") {
+		return text
+	}
+	for name, value := range values {
+		text = strings.ReplaceAll(text, "{{"+name+"}}", jsonpath.Stringify(value))
+	}
+	return text
+}
+
+// strings returns the vars as text, for the replay response
+func (values replayVars) strings() map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for name, value := range values {
+		result[name] = jsonpath.Stringify(value)
+	}
+	return result
+}
+
+// applyBodySet sets the fields of a JSON body; a value that is exactly one var placeholder keeps
+// the var's JSON type
+func (values replayVars) applyBodySet(body []byte, set map[string]string) ([]byte, error) {
+	var doc interface{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		var err error
+		if doc, err = jsonpath.Decode(body); err != nil {
+			return nil, fmt.Errorf("body_set needs a JSON body: %w", err)
+		}
+	}
+	for path, raw := range set {
+		var value interface{} = values.expand(raw)
+		if name, ok := strings.CutPrefix(raw, "{{"); ok {
+			if name, ok = strings.CutSuffix(name, "}}"); ok {
+				if typed, defined := values[name]; defined {
+					value = typed
+				}
+			}
+		}
+		var ok bool
+		if doc, ok = jsonpath.Set(doc, path, value); !ok {
+			return nil, fmt.Errorf("body_set cannot set %s", path)
+		}
+	}
+	return json.Marshal(doc)
+}
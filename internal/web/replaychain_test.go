@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestReplayChaining(t *testing.T) {
+	store, err := storage.New(&config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "reqtap.db")}, noopLogger{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	for _, data := range []*request.RequestData{
+		{ID: "order-created", Timestamp: time.Now(), Method: http.MethodPost, Path: "/hook", Headers: http.Header{"X-Account": {"acme"}}, Body: []byte(`{"data":{"id":42,"status":"created"}}`)},
+		{ID: "order-paid", Timestamp: time.Now(), Method: http.MethodPost, Path: "/hook", Headers: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"order_id":1,"status":"paid"}`)},
+	} {
+		if _, err := store.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	type received struct {
+		path, account, body string
+	}
+	var got []received
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{path: r.URL.RequestURI(), account: r.Header.Get("X-Account"), body: string(body)})
+		w.Write([]byte(`{"token":"tok-7"}`))
+	}))
+	defer upstream.Close()
+
+	svc := NewService(&config.WebConfig{Enable: true, Path: "/web", AdminPath: "/api"}, store, noopLogger{})
+	defer svc.Close()
+	router := mux.NewRouter()
+	svc.RegisterRoutes(router)
+	replay := func(payload string) (*httptest.ResponseRecorder, request.ReplayResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/replay", strings.NewReader(payload)))
+		var resp request.ReplayResponse
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid replay response %q: %v", rr.Body.String(), err)
+			}
+		}
+		return rr, resp
+	}
+
+	// Take the id from the first webhook and replay the second one with it
+	rr, resp := replay(`{"request_id":"order-paid","target_url":"` + upstream.URL + `/orders/{{id}}",
+		"vars":[{"name":"id","request_id":"order-created","json_path":"data.id"},{"name":"account","request_id":"order-created","header":"X-Account"}],
+		"headers":{"Content-Type":"application/json","X-Account":"{{account}}"},
+		"body_set":{"order_id":"{{id}}","note":"order {{id}}"}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("replay failed: %d %s", rr.Code, rr.Body.String())
+	}
+	if len(got) != 1 || got[0].path != "/orders/42" || got[0].account != "acme" {
+		t.Fatalf("expected the vars in the URL and headers, got %+v", got)
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal([]byte(got[0].body), &sent); err != nil || sent["order_id"] != float64(42) || sent["note"] != "order 42" || sent["status"] != "paid" {
+		t.Fatalf("expected body_set on the original body, got %s", got[0].body)
+	}
+	if resp.Vars["id"] != "42" || resp.Vars["account"] != "acme" {
+		t.Fatalf("expected the resolved vars in the response, got %+v", resp.Vars)
+	}
+
+	// The previous replay's response feeds the next step
+	rr, _ = replay(`{"request_id":"order-created","target_url":"` + upstream.URL + `/confirm",
+		"vars":[{"name":"token","request_id":"order-paid","from":"replay","json_path":"token"}],
+		"body":"token={{token}}"}`)
+	if rr.Code != http.StatusOK || len(got) != 2 || got[1].body != "token=tok-7" {
+		t.Fatalf("expected the previous response's token, got %d %+v", rr.Code, got)
+	}
+
+	rr, _ = replay(`{"request_id":"order-paid","target_url":"` + upstream.URL + `","vars":[{"name":"x","json_path":"missing"}]}`)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "json_path missing not found") {
+		t.Fatalf("expected a missing field to be rejected, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(got) != 2 {
+		t.Fatal("a replay with unresolved vars must not be sent")
+	}
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SequenceState reports how far a sequenced mock response rule has advanced.
+type SequenceState struct {
+	Rule string `json:"rule"`
+	// Path is the server.paths prefix the rule belongs to; empty for server.responses
+	Path  string `json:"path,omitempty"`
+	Calls int64  `json:"calls"`
+	// Step is the 1-based step the next matching call is answered with
+	Step  int  `json:"step"`
+	Steps int  `json:"steps"`
+	Loop  bool `json:"loop"`
+}
+
+// SetSequenceHandlers wires the sequence report and reset exposed via /admin/sequences.
+func (s *Service) SetSequenceHandlers(list func() []SequenceState, reset func(rule string) []SequenceState) {
+	if s == nil {
+		return
+	}
+	s.reloadMu.Lock()
+	s.sequences = list
+	s.resetSequences = reset
+	s.reloadMu.Unlock()
+}
+
+// handleSequences lists the call counters of sequenced mock rules.
+func (s *Service) handleSequences(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.RLock()
+	list := s.sequences
+	s.reloadMu.RUnlock()
+
+	sequences := []SequenceState{}
+	if list != nil {
+		sequences = append(sequences, list()...)
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sequences": sequences,
+	})
+}
+
+// handleResetSequences restarts sequenced rules from their first step; ?rule=name limits the reset
+// to one rule.
+func (s *Service) handleResetSequences(w http.ResponseWriter, r *http.Request) {
+	if s.auth.Enabled() {
+		session := s.sessionFromContext(r.Context())
+		if session != nil && !session.allows(scopeAdmin) {
+			http.Error(w, "Forbidden: resetting sequences requires admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	s.reloadMu.RLock()
+	reset := s.resetSequences
+	s.reloadMu.RUnlock()
+	if reset == nil {
+		http.Error(w, "sequences unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	sequences := []SequenceState{}
+	sequences = append(sequences, reset(strings.TrimSpace(r.URL.Query().Get("rule")))...)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sequences": sequences,
+	})
+}
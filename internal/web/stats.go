@@ -0,0 +1,184 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+const (
+	defaultStatsHours = 24
+	maxStatsHours     = 31 * 24
+	defaultStatsTop   = 10
+	maxStatsTop       = 100
+	statsMinutes      = 60
+)
+
+// RequestStats aggregates the requests captured in [From, To) for the dashboard.
+type RequestStats struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Total int       `json:"total"`
+	// RequestsPerMinute and RequestsPerHour average Total over the window
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	RequestsPerHour   float64 `json:"requests_per_hour"`
+	// Minutes counts the last 60 minutes, Hours every hour of the window
+	Minutes      []TimelineBucket `json:"minutes"`
+	Hours        []TimelineBucket `json:"hours"`
+	Methods      []MethodCount    `json:"methods"`
+	TopPaths     []PathCount      `json:"top_paths"`
+	AvgBodyBytes float64          `json:"avg_body_bytes"`
+	// Forwards is empty when the storage backend cannot aggregate forward outcomes
+	Forwards []*storage.TargetForwardStats `json:"forwards"`
+}
+
+// MethodCount is the number of requests using one HTTP method.
+type MethodCount struct {
+	Method string `json:"method"`
+	Count  int    `json:"count"`
+}
+
+// PathCount is the number of requests sent to one path.
+type PathCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+func (s *Service) handleStats(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	hours := parseIntDefault(query.Get("hours"), defaultStatsHours)
+	if hours <= 0 {
+		hours = defaultStatsHours
+	}
+	if hours > maxStatsHours {
+		hours = maxStatsHours
+	}
+	top := parseIntDefault(query.Get("top"), defaultStatsTop)
+	if top <= 0 {
+		top = defaultStatsTop
+	}
+	if top > maxStatsTop {
+		top = maxStatsTop
+	}
+
+	agg := newStatsAggregator(hours, time.Now().UTC())
+	err := s.store.Iterate(ListOptions{Since: agg.since()}, func(item *StoredRequest) bool {
+		agg.add(item)
+		return true
+	})
+	if err != nil {
+		s.logger.Error("Failed to compute request statistics", "error", err)
+		http.Error(w, "Failed to compute statistics", http.StatusInternalServerError)
+		return
+	}
+	stats := agg.result(top)
+
+	if forwardStats, ok := s.store.(storage.ForwardStatsStore); ok {
+		targets, err := forwardStats.ForwardStats(stats.From, time.Time{})
+		if err != nil {
+			s.logger.Error("Failed to compute forward statistics", "error", err)
+			http.Error(w, "Failed to compute statistics", http.StatusInternalServerError)
+			return
+		}
+		stats.Forwards = append(stats.Forwards, targets...)
+	}
+	s.respondJSON(w, http.StatusOK, stats)
+}
+
+type statsAggregator struct {
+	stats     *RequestStats
+	methods   map[string]int
+	paths     map[string]int
+	bodyBytes int64
+}
+
+// newStatsAggregator lays out empty buckets for the last hours hours, including the current one,
+// and the last 60 minutes up to now.
+func newStatsAggregator(hours int, now time.Time) *statsAggregator {
+	stats := &RequestStats{
+		To:       now,
+		Minutes:  make([]TimelineBucket, 0, statsMinutes),
+		Hours:    make([]TimelineBucket, 0, hours),
+		Methods:  []MethodCount{},
+		TopPaths: []PathCount{},
+		Forwards: []*storage.TargetForwardStats{},
+	}
+	hour := now.Truncate(time.Hour)
+	stats.From = hour.Add(-time.Duration(hours-1) * time.Hour)
+	for i := 0; i < hours; i++ {
+		stats.Hours = append(stats.Hours, TimelineBucket{Start: stats.From.Add(time.Duration(i) * time.Hour)})
+	}
+	minute := now.Truncate(time.Minute)
+	for i := statsMinutes - 1; i >= 0; i-- {
+		stats.Minutes = append(stats.Minutes, TimelineBucket{Start: minute.Add(-time.Duration(i) * time.Minute)})
+	}
+	return &statsAggregator{stats: stats, methods: make(map[string]int), paths: make(map[string]int)}
+}
+
+// since is the earliest capture time any bucket covers
+func (a *statsAggregator) since() time.Time {
+	if first := a.stats.Minutes[0].Start; first.Before(a.stats.From) {
+		return first
+	}
+	return a.stats.From
+}
+
+func (a *statsAggregator) add(item *StoredRequest) {
+	ts := item.Timestamp.UTC()
+	if ts.After(a.stats.To) {
+		return
+	}
+	if i := int(ts.Sub(a.stats.Minutes[0].Start) / time.Minute); i >= 0 && i < len(a.stats.Minutes) {
+		a.stats.Minutes[i].Count++
+	}
+	if ts.Before(a.stats.From) {
+		return
+	}
+	if i := int(ts.Sub(a.stats.From) / time.Hour); i < len(a.stats.Hours) {
+		a.stats.Hours[i].Count++
+	}
+	a.stats.Total++
+	a.methods[item.Method]++
+	a.paths[item.Path]++
+	a.bodyBytes += item.Size
+}
+
+// result sorts methods and paths by count and keeps the top paths.
+func (a *statsAggregator) result(top int) *RequestStats {
+	stats := a.stats
+	if elapsed := stats.To.Sub(stats.From); elapsed > 0 {
+		stats.RequestsPerMinute = float64(stats.Total) / elapsed.Minutes()
+		stats.RequestsPerHour = float64(stats.Total) / elapsed.Hours()
+	}
+	if stats.Total > 0 {
+		stats.AvgBodyBytes = float64(a.bodyBytes) / float64(stats.Total)
+	}
+	for method, count := range a.methods {
+		stats.Methods = append(stats.Methods, MethodCount{Method: method, Count: count})
+	}
+	sort.Slice(stats.Methods, func(i, j int) bool {
+		if stats.Methods[i].Count != stats.Methods[j].Count {
+			return stats.Methods[i].Count > stats.Methods[j].Count
+		}
+		return stats.Methods[i].Method < stats.Methods[j].Method
+	})
+	for path, count := range a.paths {
+		stats.TopPaths = append(stats.TopPaths, PathCount{Path: path, Count: count})
+	}
+	sort.Slice(stats.TopPaths, func(i, j int) bool {
+		if stats.TopPaths[i].Count != stats.TopPaths[j].Count {
+			return stats.TopPaths[i].Count > stats.TopPaths[j].Count
+		}
+		return stats.TopPaths[i].Path < stats.TopPaths[j].Path
+	})
+	if len(stats.TopPaths) > top {
+		stats.TopPaths = stats.TopPaths[:top]
+	}
+	return stats
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestStatsAggregator(t *testing.T) {
+	now := time.Date(2025, time.March, 10, 15, 30, 0, 0, time.UTC)
+	agg := newStatsAggregator(2, now)
+
+	if len(agg.stats.Hours) != 2 || len(agg.stats.Minutes) != 60 {
+		t.Fatalf("unexpected bucket layout: %d hours, %d minutes", len(agg.stats.Hours), len(agg.stats.Minutes))
+	}
+	if want := time.Date(2025, time.March, 10, 14, 0, 0, 0, time.UTC); !agg.stats.From.Equal(want) {
+		t.Fatalf("unexpected from %s", agg.stats.From)
+	}
+	if want := time.Date(2025, time.March, 10, 14, 31, 0, 0, time.UTC); !agg.since().Equal(agg.stats.From) || !agg.stats.Minutes[0].Start.Equal(want) {
+		t.Fatalf("unexpected minute window %s", agg.stats.Minutes[0].Start)
+	}
+
+	add := func(method, path string, size int64, ts time.Time) {
+		agg.add(&StoredRequest{ID: path, RequestData: &request.RequestData{Method: method, Path: path, Size: size, Timestamp: ts}})
+	}
+	add("POST", "/hook", 100, time.Date(2025, time.March, 10, 14, 10, 0, 0, time.UTC))
+	add("POST", "/hook", 300, time.Date(2025, time.March, 10, 15, 29, 30, 0, time.UTC))
+	add("GET", "/health", 0, time.Date(2025, time.March, 10, 15, 29, 59, 0, time.UTC))
+	add("POST", "/old", 50, time.Date(2025, time.March, 10, 13, 59, 0, 0, time.UTC))
+
+	stats := agg.result(1)
+	if stats.Total != 3 || stats.AvgBodyBytes != 400.0/3 {
+		t.Fatalf("unexpected totals: total=%d avg=%f", stats.Total, stats.AvgBodyBytes)
+	}
+	if stats.Hours[0].Count != 1 || stats.Hours[1].Count != 2 {
+		t.Fatalf("unexpected hour counts %+v", stats.Hours)
+	}
+	if stats.Minutes[58].Count != 2 {
+		t.Fatalf("expected two requests in the previous minute, got %+v", stats.Minutes[58])
+	}
+	if stats.RequestsPerHour != 2 || stats.RequestsPerMinute != 3.0/90 {
+		t.Fatalf("unexpected rates: %f/h %f/min", stats.RequestsPerHour, stats.RequestsPerMinute)
+	}
+	if len(stats.Methods) != 2 || stats.Methods[0] != (MethodCount{Method: "POST", Count: 2}) {
+		t.Fatalf("unexpected methods %+v", stats.Methods)
+	}
+	if len(stats.TopPaths) != 1 || stats.TopPaths[0] != (PathCount{Path: "/hook", Count: 2}) {
+		t.Fatalf("unexpected top paths %+v", stats.TopPaths)
+	}
+}
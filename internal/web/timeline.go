@@ -0,0 +1,159 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	// Embedded zone database so ?tz= works on hosts without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	timelineBucketHour = "hour"
+	timelineBucketDay  = "day"
+
+	defaultTimelineHourDays = 7
+	defaultTimelineDayDays  = 91
+	maxTimelineHourDays     = 31
+	maxTimelineDayDays      = 366
+)
+
+// TimelineBucket is the number of requests captured in [Start, Start+bucket).
+type TimelineBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// Timeline aggregates request counts per hour or day for the heatmap view.
+type Timeline struct {
+	Bucket   string           `json:"bucket"`
+	Timezone string           `json:"timezone"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Total    int              `json:"total"`
+	Max      int              `json:"max"`
+	Buckets  []TimelineBucket `json:"buckets"`
+}
+
+func (s *Service) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+
+	bucket := strings.ToLower(strings.TrimSpace(query.Get("bucket")))
+	if bucket == "" {
+		bucket = timelineBucketHour
+	}
+	defaultDays, maxDays := defaultTimelineHourDays, maxTimelineHourDays
+	switch bucket {
+	case timelineBucketHour:
+	case timelineBucketDay:
+		defaultDays, maxDays = defaultTimelineDayDays, maxTimelineDayDays
+	default:
+		http.Error(w, "bucket must be hour or day", http.StatusBadRequest)
+		return
+	}
+	days := parseIntDefault(query.Get("days"), defaultDays)
+	if days <= 0 {
+		days = defaultDays
+	}
+	if days > maxDays {
+		days = maxDays
+	}
+
+	loc := time.UTC
+	if tz := strings.TrimSpace(query.Get("tz")); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "unknown time zone", http.StatusBadRequest)
+			return
+		}
+	}
+
+	timeline := newTimeline(bucket, days, time.Now().In(loc))
+	opts := ListOptions{
+		Search: query.Get("search"),
+		Method: query.Get("method"),
+		Since:  timeline.From,
+		Until:  timeline.To,
+	}
+	err := s.store.Iterate(opts, func(item *StoredRequest) bool {
+		timeline.add(item.Timestamp)
+		return true
+	})
+	if err != nil {
+		s.logger.Error("Failed to build request timeline", "error", err)
+		http.Error(w, "Failed to build timeline", http.StatusInternalServerError)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, timeline)
+}
+
+// newTimeline lays out empty buckets covering the last days calendar days up to and including now's day.
+func newTimeline(bucket string, days int, now time.Time) *Timeline {
+	loc := now.Location()
+	y, m, d := now.Date()
+	t := &Timeline{
+		Bucket:   bucket,
+		Timezone: loc.String(),
+		From:     time.Date(y, m, d-days+1, 0, 0, 0, 0, loc),
+		To:       time.Date(y, m, d+1, 0, 0, 0, 0, loc),
+	}
+	for day := 0; day < days; day++ {
+		if bucket == timelineBucketDay {
+			t.Buckets = append(t.Buckets, TimelineBucket{Start: time.Date(y, m, d-days+1+day, 0, 0, 0, 0, loc)})
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			start := time.Date(y, m, d-days+1+day, hour, 0, 0, 0, loc)
+			// An hour skipped by a DST change normalizes onto the next one
+			if n := len(t.Buckets); n > 0 && t.Buckets[n-1].Start.Equal(start) {
+				continue
+			}
+			t.Buckets = append(t.Buckets, TimelineBucket{Start: start})
+		}
+	}
+	return t
+}
+
+func (t *Timeline) add(ts time.Time) {
+	if ts.Before(t.From) || !ts.Before(t.To) {
+		return
+	}
+	ts = ts.In(t.From.Location())
+	y, m, d := ts.Date()
+	hour := ts.Hour()
+	if t.Bucket == timelineBucketDay {
+		hour = 0
+	}
+	start := time.Date(y, m, d, hour, 0, 0, 0, ts.Location())
+	idx := t.indexOf(start)
+	if idx < 0 {
+		return
+	}
+	t.Buckets[idx].Count++
+	t.Total++
+	if t.Buckets[idx].Count > t.Max {
+		t.Max = t.Buckets[idx].Count
+	}
+}
+
+// indexOf finds the bucket starting at start; buckets are sorted, so a binary search suffices.
+func (t *Timeline) indexOf(start time.Time) int {
+	lo, hi := 0, len(t.Buckets)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch b := t.Buckets[mid].Start; {
+		case b.Equal(start):
+			return mid
+		case b.Before(start):
+			lo = mid + 1
+		default:
+			hi = mid - 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimelineHourBuckets(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2025, time.March, 10, 15, 30, 0, 0, loc)
+	timeline := newTimeline(timelineBucketHour, 2, now)
+
+	if len(timeline.Buckets) != 48 {
+		t.Fatalf("expected 48 hourly buckets, got %d", len(timeline.Buckets))
+	}
+	if want := time.Date(2025, time.March, 9, 0, 0, 0, 0, loc); !timeline.From.Equal(want) {
+		t.Fatalf("unexpected from %s", timeline.From)
+	}
+
+	// 02:xx local time on both days, one request outside of the window
+	timeline.add(time.Date(2025, time.March, 8, 18, 10, 0, 0, time.UTC)) // 03-09 02:10 local
+	timeline.add(time.Date(2025, time.March, 9, 18, 59, 0, 0, time.UTC)) // 03-10 02:59 local
+	timeline.add(time.Date(2025, time.March, 9, 18, 1, 0, 0, time.UTC))  // 03-10 02:01 local
+	timeline.add(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+
+	if timeline.Total != 3 || timeline.Max != 2 {
+		t.Fatalf("unexpected totals: total=%d max=%d", timeline.Total, timeline.Max)
+	}
+	if timeline.Buckets[2].Count != 1 || timeline.Buckets[26].Count != 2 {
+		t.Fatalf("requests landed in the wrong buckets: %+v", timeline.Buckets[:27])
+	}
+}
+
+func TestTimelineDayBuckets(t *testing.T) {
+	now := time.Date(2025, time.January, 2, 8, 0, 0, 0, time.UTC)
+	timeline := newTimeline(timelineBucketDay, 3, now)
+
+	if len(timeline.Buckets) != 3 {
+		t.Fatalf("expected 3 daily buckets, got %d", len(timeline.Buckets))
+	}
+	timeline.add(time.Date(2024, time.December, 31, 23, 59, 0, 0, time.UTC))
+	timeline.add(time.Date(2025, time.January, 2, 7, 0, 0, 0, time.UTC))
+	timeline.add(time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC))
+
+	counts := []int{timeline.Buckets[0].Count, timeline.Buckets[1].Count, timeline.Buckets[2].Count}
+	if counts[0] != 1 || counts[1] != 0 || counts[2] != 1 {
+		t.Fatalf("unexpected day counts %v", counts)
+	}
+}
+
+func TestTimelineSkipsDSTGap(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	// 2025-03-09 has no 02:00 hour in New York
+	now := time.Date(2025, time.March, 9, 12, 0, 0, 0, loc)
+	timeline := newTimeline(timelineBucketHour, 1, now)
+	if len(timeline.Buckets) != 23 {
+		t.Fatalf("expected 23 buckets on the DST day, got %d", len(timeline.Buckets))
+	}
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/storage"
+)
+
+type createTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// loadStoredTokens restores the API tokens created through the API before the last restart.
+func (s *Service) loadStoredTokens() {
+	tokenStore, ok := s.store.(storage.TokenStore)
+	if !ok {
+		return
+	}
+	tokens, err := tokenStore.Tokens()
+	if err != nil {
+		s.logger.Error("Failed to load API tokens", "error", err)
+		return
+	}
+	for _, token := range tokens {
+		createdAt := token.CreatedAt
+		info := TokenInfo{Name: token.Name, Scopes: token.Scopes, Source: tokenSourceAPI, CreatedBy: token.CreatedBy, CreatedAt: &createdAt}
+		if err := s.auth.AddToken(info, token.Hash); err != nil {
+			// A token defined in the config file takes precedence over a stored one with the same name
+			s.logger.Warn("Skipping stored API token", "token", token.Name, "error", err)
+		}
+	}
+}
+
+// requireTokenAdmin answers the request and returns false unless the caller may manage API tokens.
+func (s *Service) requireTokenAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !s.auth.Enabled() {
+		http.Error(w, "API tokens require web.auth.enable", http.StatusConflict)
+		return false
+	}
+	if !s.sessionFromContext(r.Context()).allows(scopeAdmin) {
+		http.Error(w, "Forbidden: managing API tokens requires admin role", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *Service) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTokenAdmin(w, r) {
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{"tokens": s.auth.Tokens()})
+}
+
+// handleCreateToken issues a token; its value is only ever returned in this response.
+func (s *Service) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTokenAdmin(w, r) {
+		return
+	}
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		http.Error(w, "at least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for i, scope := range req.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !config.ValidTokenScope(scope) {
+			http.Error(w, "scopes must be read, write, export, replay or admin", http.StatusBadRequest)
+			return
+		}
+		req.Scopes[i] = scope
+	}
+
+	createdBy := s.sessionFromContext(r.Context()).Username
+	value, info, err := s.auth.CreateToken(req.Name, req.Scopes, createdBy)
+	if errors.Is(err, ErrTokenExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	if tokenStore, ok := s.store.(storage.TokenStore); ok {
+		stored := &storage.APIToken{Name: info.Name, Hash: HashToken(value), Scopes: info.Scopes, CreatedBy: createdBy, CreatedAt: *info.CreatedAt}
+		if err := tokenStore.SaveToken(stored); err != nil {
+			s.auth.RevokeToken(info.Name)
+			s.logger.Error("Failed to store API token", "token", info.Name, "error", err)
+			http.Error(w, "Failed to store token", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.logger.Info("API token created", "token", info.Name, "scopes", info.Scopes, "created_by", createdBy)
+	s.audit(r, storage.AuditEntry{Action: auditTokenCreate, Target: info.Name, Success: true, Details: map[string]string{
+		"scopes": strings.Join(info.Scopes, ","),
+	}})
+	s.respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"token": value,
+		"info":  info,
+	})
+}
+
+func (s *Service) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTokenAdmin(w, r) {
+		return
+	}
+	name := mux.Vars(r)["name"]
+	switch err := s.auth.RevokeToken(name); {
+	case errors.Is(err, ErrTokenNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrTokenConfigured):
+		http.Error(w, "token is defined in the config file; remove it there", http.StatusConflict)
+		return
+	}
+	if tokenStore, ok := s.store.(storage.TokenStore); ok {
+		if err := tokenStore.DeleteToken(name); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.logger.Error("Failed to delete API token", "token", name, "error", err)
+			http.Error(w, "Failed to delete token", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.logger.Info("API token revoked", "token", name)
+	s.audit(r, storage.AuditEntry{Action: auditTokenRevoke, Target: name, Success: true})
+	w.WriteHeader(http.StatusNoContent)
+}
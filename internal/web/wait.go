@@ -0,0 +1,164 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+)
+
+var errInvalidWaitTimeout = errors.New("timeout must be a positive duration such as 30s")
+
+// requestMatcher selects the requests a long-poll waits for.
+type requestMatcher struct {
+	method string
+	path   string
+	prefix bool
+}
+
+func (m requestMatcher) match(item *StoredRequest) bool {
+	if m.method != "" && !strings.EqualFold(item.Method, m.method) {
+		return false
+	}
+	if m.path == "" {
+		return true
+	}
+	if m.prefix {
+		return strings.HasPrefix(item.Path, m.path)
+	}
+	return item.Path == m.path
+}
+
+type waiter struct {
+	matcher requestMatcher
+	ch      chan *StoredRequest
+}
+
+// waitRegistry hands newly recorded requests to pending long-polls.
+type waitRegistry struct {
+	mu      sync.Mutex
+	waiters map[*waiter]struct{}
+}
+
+func (r *waitRegistry) add(m requestMatcher) *waiter {
+	w := &waiter{matcher: m, ch: make(chan *StoredRequest, 1)}
+	r.mu.Lock()
+	if r.waiters == nil {
+		r.waiters = make(map[*waiter]struct{})
+	}
+	r.waiters[w] = struct{}{}
+	r.mu.Unlock()
+	return w
+}
+
+func (r *waitRegistry) remove(w *waiter) {
+	r.mu.Lock()
+	delete(r.waiters, w)
+	r.mu.Unlock()
+}
+
+// notify completes every waiter matching item; each waiter receives at most one request.
+func (r *waitRegistry) notify(item *StoredRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for w := range r.waiters {
+		if !w.matcher.match(item) {
+			continue
+		}
+		select {
+		case w.ch <- item:
+		default:
+		}
+		delete(r.waiters, w)
+	}
+}
+
+// handleWait blocks until a request matching method and path arrives and returns it, or answers
+// 408 once timeout elapses. With since, a matching request captured after that time that is
+// already stored is returned immediately, so scripts can send first and wait afterwards.
+func (s *Service) handleWait(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	timeout, err := parseWaitTimeout(query.Get("timeout"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var since time.Time
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		if since, err = parseTimeParam(raw); err != nil {
+			http.Error(w, "since must be an RFC 3339 time or unix milliseconds", http.StatusBadRequest)
+			return
+		}
+	}
+	matcher := requestMatcher{method: strings.TrimSpace(query.Get("method")), path: strings.TrimSpace(query.Get("path"))}
+	if strings.HasSuffix(matcher.path, "*") {
+		matcher.path, matcher.prefix = strings.TrimSuffix(matcher.path, "*"), true
+	}
+
+	// Register before looking at history so a request arriving in between is not missed
+	pending := s.waits.add(matcher)
+	defer s.waits.remove(pending)
+
+	if !since.IsZero() && s.store != nil {
+		var found *StoredRequest
+		err := s.store.Iterate(ListOptions{Method: matcher.method, Since: since}, func(item *StoredRequest) bool {
+			if matcher.match(item) {
+				found = item
+			}
+			return true
+		})
+		if err != nil {
+			s.logger.Error("Failed to look up requests for wait", "error", err)
+			http.Error(w, "Failed to fetch requests", http.StatusInternalServerError)
+			return
+		}
+		if found != nil {
+			s.respondJSON(w, http.StatusOK, found)
+			return
+		}
+	}
+
+	// The server's write timeout would cut long polls short
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second)); err != nil {
+		s.logger.Debug("Unable to extend write deadline for wait", "error", err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case item := <-pending.ch:
+		s.respondJSON(w, http.StatusOK, item)
+	case <-timer.C:
+		http.Error(w, "no matching request arrived before the timeout", http.StatusRequestTimeout)
+	case <-r.Context().Done():
+	}
+}
+
+// parseWaitTimeout accepts a Go duration such as 30s or a number of seconds.
+func parseWaitTimeout(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, errInvalidWaitTimeout
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, errInvalidWaitTimeout
+	}
+	if timeout > maxWaitTimeout {
+		timeout = maxWaitTimeout
+	}
+	return timeout, nil
+}
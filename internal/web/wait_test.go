@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Fatal(string, ...interface{}) {}
+
+func TestHandleWaitReturnsNextMatchingRequest(t *testing.T) {
+	svc := &Service{logger: noopLogger{}}
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.handleWait(rec, httptest.NewRequest(http.MethodGet, "/api/wait?method=post&path=/hooks/*&timeout=5s", nil))
+	}()
+
+	stored := func(id, method, path string) *StoredRequest {
+		return &StoredRequest{ID: id, RequestData: &request.RequestData{Method: method, Path: path}}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		svc.waits.mu.Lock()
+		registered := len(svc.waits.waiters)
+		svc.waits.mu.Unlock()
+		if registered == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("wait did not register")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	svc.waits.notify(stored("skip-method", http.MethodGet, "/hooks/a"))
+	svc.waits.notify(stored("skip-path", http.MethodPost, "/other"))
+	svc.waits.notify(stored("match", http.MethodPost, "/hooks/github"))
+	<-done
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got StoredRequest
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.ID != "match" {
+		t.Fatalf("expected the matching request, got %s (%v)", rec.Body.String(), err)
+	}
+	if len(svc.waits.waiters) != 0 {
+		t.Fatal("expected the waiter to be removed")
+	}
+}
+
+func TestHandleWaitTimesOut(t *testing.T) {
+	svc := &Service{logger: noopLogger{}}
+	rec := httptest.NewRecorder()
+	svc.handleWait(rec, httptest.NewRequest(http.MethodGet, "/api/wait?timeout=10ms", nil))
+	if rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("expected 408, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.handleWait(rec, httptest.NewRequest(http.MethodGet, "/api/wait?timeout=soon", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid timeout, got %d", rec.Code)
+	}
+}
@@ -11,10 +11,16 @@ import (
 	"github.com/funnyzak/reqtap/internal/logger"
 )
 
-// WebsocketHub manages live connections for request broadcasts.
+// streamBuffer is how many broadcasts a Server-Sent Events listener may fall behind before it is
+// dropped.
+const streamBuffer = 64
+
+// WebsocketHub manages live connections for request broadcasts, over WebSocket and Server-Sent
+// Events alike.
 type WebsocketHub struct {
 	logger  logger.Logger
-	clients map[*websocket.Conn]struct{}
+	clients map[*websocket.Conn]*wsClient
+	streams map[chan []byte]struct{}
 	mu      sync.RWMutex
 
 	upgrader websocket.Upgrader
@@ -24,32 +30,59 @@ type WebsocketHub struct {
 func NewWebsocketHub(log logger.Logger) *WebsocketHub {
 	return &WebsocketHub{
 		logger:  log,
-		clients: make(map[*websocket.Conn]struct{}),
+		clients: make(map[*websocket.Conn]*wsClient),
+		streams: make(map[chan []byte]struct{}),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
 	}
 }
 
+// wsClient serializes writes to one connection; gorilla allows a single concurrent writer.
+type wsClient struct {
+	mu sync.Mutex
+}
+
 // Upgrade upgrades the HTTP connection to WebSocket.
-func (h *WebsocketHub) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+// A non-nil backfill event is sent before any broadcast reaches the new client; it is
+// built after registration so nothing broadcast in between is lost.
+func (h *WebsocketHub) Upgrade(w http.ResponseWriter, r *http.Request, backfill func() interface{}) (*websocket.Conn, error) {
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	h.register(conn)
+	client := &wsClient{}
+	client.mu.Lock()
+	h.register(conn, client)
+	if backfill != nil {
+		if event := backfill(); event != nil {
+			if err := h.write(conn, event); err != nil {
+				h.logger.Warn("Failed to write websocket backfill", "error", err)
+			}
+		}
+	}
+	client.mu.Unlock()
 	return conn, nil
 }
 
-func (h *WebsocketHub) register(conn *websocket.Conn) {
+func (h *WebsocketHub) register(conn *websocket.Conn, client *wsClient) {
 	h.mu.Lock()
-	h.clients[conn] = struct{}{}
+	h.clients[conn] = client
 	h.mu.Unlock()
 
 	go h.readLoop(conn)
 }
 
+func (h *WebsocketHub) write(conn *websocket.Conn, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return conn.WriteMessage(websocket.TextMessage, payload)
+}
+
 func (h *WebsocketHub) readLoop(conn *websocket.Conn) {
 	defer h.unregister(conn)
 
@@ -75,16 +108,37 @@ func (h *WebsocketHub) unregister(conn *websocket.Conn) {
 	conn.Close()
 }
 
+// Subscribe registers a Server-Sent Events listener. Broadcast payloads are queued on the
+// returned channel, which is closed when the listener falls behind by more than streamBuffer
+// events or the hub closes; cancel unregisters the listener.
+func (h *WebsocketHub) Subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, streamBuffer)
+	h.mu.Lock()
+	h.streams[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() { h.unsubscribe(ch) }
+}
+
+func (h *WebsocketHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.streams[ch]; ok {
+		delete(h.streams, ch)
+		close(ch)
+	}
+}
+
 // Broadcast sends payload to all active connections.
 func (h *WebsocketHub) Broadcast(event interface{}) {
 	h.mu.RLock()
-	conns := make([]*websocket.Conn, 0, len(h.clients))
-	for conn := range h.clients {
-		conns = append(conns, conn)
+	conns := make(map[*websocket.Conn]*wsClient, len(h.clients))
+	for conn, client := range h.clients {
+		conns[conn] = client
 	}
+	streams := len(h.streams)
 	h.mu.RUnlock()
 
-	if len(conns) == 0 {
+	if len(conns) == 0 && streams == 0 {
 		return
 	}
 
@@ -94,15 +148,39 @@ func (h *WebsocketHub) Broadcast(event interface{}) {
 		return
 	}
 
-	for _, conn := range conns {
+	if streams > 0 {
+		h.mu.Lock()
+		for ch := range h.streams {
+			select {
+			case ch <- payload:
+			default:
+				h.logger.Warn("Dropping event stream client that fell behind")
+				delete(h.streams, ch)
+				close(ch)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	for conn, client := range conns {
+		client.mu.Lock()
 		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-		if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		err := conn.WriteMessage(websocket.TextMessage, payload)
+		client.mu.Unlock()
+		if err != nil {
 			h.logger.Warn("Failed to write to websocket client", "error", err)
 			h.unregister(conn)
 		}
 	}
 }
 
+// Clients counts the connected WebSocket and Server-Sent Events listeners.
+func (h *WebsocketHub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients) + len(h.streams)
+}
+
 // Close terminates all connections.
 func (h *WebsocketHub) Close() {
 	h.mu.Lock()
@@ -110,7 +188,11 @@ func (h *WebsocketHub) Close() {
 	for conn := range h.clients {
 		conns = append(conns, conn)
 	}
-	h.clients = make(map[*websocket.Conn]struct{})
+	h.clients = make(map[*websocket.Conn]*wsClient)
+	for ch := range h.streams {
+		close(ch)
+	}
+	h.streams = make(map[chan []byte]struct{})
 	h.mu.Unlock()
 
 	for _, conn := range conns {
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/funnyzak/reqtap/internal/config"
+)
+
+func TestWebsocketHubSendsBackfillBeforeBroadcasts(t *testing.T) {
+	hub := NewWebsocketHub(noopLogger{})
+	defer hub.Close()
+
+	registered := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := hub.Upgrade(w, r, func() interface{} {
+			// A broadcast racing the backfill must queue behind it
+			close(registered)
+			<-release
+			return map[string]interface{}{"type": "history", "data": []string{"old"}}
+		})
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	<-registered
+	broadcastDone := make(chan struct{})
+	go func() {
+		defer close(broadcastDone)
+		hub.Broadcast(map[string]interface{}{"type": "request", "data": "new"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-broadcastDone
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var types []string
+	for i := 0; i < 2; i++ {
+		var event struct {
+			Type string `json:"type"`
+		}
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			t.Fatalf("invalid payload %s: %v", payload, err)
+		}
+		types = append(types, event.Type)
+	}
+	if types[0] != "history" || types[1] != "request" {
+		t.Fatalf("expected history before request, got %v", types)
+	}
+}
+
+func TestHistoryBackfillLimit(t *testing.T) {
+	svc := &Service{cfg: &config.WebConfig{}, logger: noopLogger{}}
+	if fn, err := svc.historyBackfill(httptest.NewRequest(http.MethodGet, "/api/ws", nil)); err != nil || fn != nil {
+		t.Fatalf("expected no backfill without history or storage, got %v", err)
+	}
+	if _, err := svc.historyBackfill(httptest.NewRequest(http.MethodGet, "/api/ws?history=-1", nil)); err == nil {
+		t.Fatal("expected an error for a negative history")
+	}
+}
@@ -0,0 +1,142 @@
+// Package plugin defines the protocol spoken between ReqTap and external plugin
+// processes, and provides helpers for writing plugins in Go.
+//
+// A plugin is an executable started by ReqTap. Both sides exchange JSON-RPC 1.0
+// messages over the plugin's stdin/stdout, so plugins can be written in any
+// language; stderr is passed through to ReqTap's stderr. Methods:
+//
+//	Plugin.Handshake       HandshakeArgs  -> HandshakeReply
+//	Plugin.Transform       TransformArgs  -> TransformReply   (hook "transform")
+//	Plugin.Export          ExportArgs     -> Empty            (hook "export")
+//	Storage.Record         RecordArgs     -> Empty            (hook "storage")
+//	Storage.List           ListArgs       -> ListReply
+//	Storage.Get            GetArgs        -> GetReply
+//	Storage.RecordReplay   ReplayArgs     -> Empty
+//	Storage.GetReplays     GetArgs        -> ReplaysReply
+//	Storage.RecordForwards ForwardsArgs   -> Empty
+//	Storage.GetForwards    GetArgs        -> ForwardsReply
+package plugin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// ProtocolVersion is bumped on incompatible protocol changes.
+const ProtocolVersion = 1
+
+// Hook names a plugin can implement.
+const (
+	HookTransform = "transform"
+	HookExport    = "export"
+	HookStorage   = "storage"
+)
+
+// Hooks lists every supported hook.
+var Hooks = []string{HookTransform, HookExport, HookStorage}
+
+// Empty is used for calls without arguments or results.
+type Empty struct{}
+
+// HandshakeArgs is sent once right after the plugin starts.
+type HandshakeArgs struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// HandshakeReply describes the plugin.
+type HandshakeReply struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Name            string   `json:"name"`
+	Hooks           []string `json:"hooks"`
+}
+
+// TransformArgs carries a captured request before it is stored and forwarded.
+type TransformArgs struct {
+	Request *request.RequestData `json:"request"`
+}
+
+// TransformReply returns the (possibly modified) request; Drop discards it.
+type TransformReply struct {
+	Request *request.RequestData `json:"request"`
+	Drop    bool                 `json:"drop"`
+}
+
+// ExportArgs carries a stored request.
+type ExportArgs struct {
+	Request *request.RequestData `json:"request"`
+}
+
+// RecordArgs asks a storage plugin to persist a request.
+type RecordArgs struct {
+	Request *request.RequestData `json:"request"`
+}
+
+// ListArgs filters and paginates stored requests, newest first.
+type ListArgs struct {
+	Search string `json:"search"`
+	Method string `json:"method"`
+	// Since and Until bound the capture time (inclusive/exclusive); zero values leave the range open.
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+// ListReply returns a page of stored requests.
+type ListReply struct {
+	Items []*request.RequestData `json:"items"`
+	Total int                    `json:"total"`
+}
+
+// GetArgs identifies a stored request.
+type GetArgs struct {
+	ID string `json:"id"`
+}
+
+// GetReply returns a stored request; Request is nil when not found.
+type GetReply struct {
+	Request *request.RequestData `json:"request"`
+}
+
+// ReplayArgs asks a storage plugin to persist a replay.
+type ReplayArgs struct {
+	Replay *request.ReplayData `json:"replay"`
+}
+
+// ReplaysReply returns the replays of a request.
+type ReplaysReply struct {
+	Replays []*request.ReplayData `json:"replays"`
+}
+
+// ForwardRecord is the wire form of a forward target response.
+type ForwardRecord struct {
+	ID            int64       `json:"id"`
+	RequestID     string      `json:"request_id"`
+	TargetURL     string      `json:"target_url"`
+	Timestamp     time.Time   `json:"timestamp"`
+	StatusCode    int         `json:"status_code"`
+	Headers       http.Header `json:"headers"`
+	Body          []byte      `json:"body"`
+	BodyTruncated bool        `json:"body_truncated"`
+	LatencyMs     int64       `json:"latency_ms"`
+	Attempts      int         `json:"attempts"`
+	Success       bool        `json:"success"`
+	Error         string      `json:"error,omitempty"`
+	Violations    []string    `json:"violations,omitempty"`
+	// LatencyBudgetMs is the provider timeout the delivery was measured against; OverBudget marks a breach.
+	LatencyBudgetMs int64 `json:"latency_budget_ms,omitempty"`
+	OverBudget      bool  `json:"over_budget,omitempty"`
+}
+
+// ForwardsArgs asks a storage plugin to persist forward responses.
+type ForwardsArgs struct {
+	RequestID string           `json:"request_id"`
+	Records   []*ForwardRecord `json:"records"`
+}
+
+// ForwardsReply returns the forward responses of a request.
+type ForwardsReply struct {
+	Records []*ForwardRecord `json:"records"`
+}
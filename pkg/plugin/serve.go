@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Transformer rewrites captured requests; returning nil drops the request.
+type Transformer interface {
+	Transform(*request.RequestData) (*request.RequestData, error)
+}
+
+// Exporter receives every stored request, e.g. to ship it to another system.
+type Exporter interface {
+	Export(*request.RequestData) error
+}
+
+// Storage is a custom persistence backend.
+type Storage interface {
+	Record(*request.RequestData) error
+	List(ListArgs) ([]*request.RequestData, int, error)
+	// Get returns nil without error when the request does not exist.
+	Get(id string) (*request.RequestData, error)
+	RecordReplay(*request.ReplayData) error
+	GetReplays(requestID string) ([]*request.ReplayData, error)
+	RecordForwards(requestID string, records []*ForwardRecord) error
+	GetForwards(requestID string) ([]*ForwardRecord, error)
+}
+
+// Serve runs impl as a plugin over stdin/stdout until ReqTap closes the pipe.
+// impl must implement at least one of Transformer, Exporter or Storage.
+func Serve(name string, impl interface{}) error {
+	return ServeConn(name, impl, stdioConn{})
+}
+
+// ServeConn runs impl as a plugin over an arbitrary connection.
+func ServeConn(name string, impl interface{}, conn io.ReadWriteCloser) error {
+	hooks := implementedHooks(impl)
+	if len(hooks) == 0 {
+		return errors.New("plugin implements no hooks")
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &pluginService{name: name, impl: impl, hooks: hooks}); err != nil {
+		return err
+	}
+	if store, ok := impl.(Storage); ok {
+		if err := server.RegisterName("Storage", &storageService{impl: store}); err != nil {
+			return err
+		}
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+func implementedHooks(impl interface{}) []string {
+	var hooks []string
+	if _, ok := impl.(Transformer); ok {
+		hooks = append(hooks, HookTransform)
+	}
+	if _, ok := impl.(Exporter); ok {
+		hooks = append(hooks, HookExport)
+	}
+	if _, ok := impl.(Storage); ok {
+		hooks = append(hooks, HookStorage)
+	}
+	return hooks
+}
+
+type stdioConn struct{}
+
+func (stdioConn) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdioConn) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdioConn) Close() error                { return os.Stdout.Close() }
+
+type pluginService struct {
+	name  string
+	impl  interface{}
+	hooks []string
+}
+
+func (s *pluginService) Handshake(args *HandshakeArgs, reply *HandshakeReply) error {
+	if args.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d (plugin speaks %d)", args.ProtocolVersion, ProtocolVersion)
+	}
+	reply.ProtocolVersion = ProtocolVersion
+	reply.Name = s.name
+	reply.Hooks = s.hooks
+	return nil
+}
+
+func (s *pluginService) Transform(args *TransformArgs, reply *TransformReply) error {
+	transformer, ok := s.impl.(Transformer)
+	if !ok {
+		return errors.New("transform hook not implemented")
+	}
+	result, err := transformer.Transform(args.Request)
+	if err != nil {
+		return err
+	}
+	reply.Request = result
+	reply.Drop = result == nil
+	return nil
+}
+
+func (s *pluginService) Export(args *ExportArgs, _ *Empty) error {
+	exporter, ok := s.impl.(Exporter)
+	if !ok {
+		return errors.New("export hook not implemented")
+	}
+	return exporter.Export(args.Request)
+}
+
+type storageService struct {
+	impl Storage
+}
+
+func (s *storageService) Record(args *RecordArgs, _ *Empty) error {
+	return s.impl.Record(args.Request)
+}
+
+func (s *storageService) List(args *ListArgs, reply *ListReply) error {
+	items, total, err := s.impl.List(*args)
+	if err != nil {
+		return err
+	}
+	reply.Items = items
+	reply.Total = total
+	return nil
+}
+
+func (s *storageService) Get(args *GetArgs, reply *GetReply) error {
+	item, err := s.impl.Get(args.ID)
+	if err != nil {
+		return err
+	}
+	reply.Request = item
+	return nil
+}
+
+func (s *storageService) RecordReplay(args *ReplayArgs, _ *Empty) error {
+	return s.impl.RecordReplay(args.Replay)
+}
+
+func (s *storageService) GetReplays(args *GetArgs, reply *ReplaysReply) error {
+	replays, err := s.impl.GetReplays(args.ID)
+	if err != nil {
+		return err
+	}
+	reply.Replays = replays
+	return nil
+}
+
+func (s *storageService) RecordForwards(args *ForwardsArgs, _ *Empty) error {
+	return s.impl.RecordForwards(args.RequestID, args.Records)
+}
+
+func (s *storageService) GetForwards(args *GetArgs, reply *ForwardsReply) error {
+	records, err := s.impl.GetForwards(args.ID)
+	if err != nil {
+		return err
+	}
+	reply.Records = records
+	return nil
+}
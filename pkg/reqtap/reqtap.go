@@ -0,0 +1,229 @@
+// Package reqtap embeds the ReqTap capture engine in other Go programs, e.g. to receive and
+// inspect the webhooks a service sends during an integration test:
+//
+//	tap, err := reqtap.New(reqtap.Options{})
+//	if err != nil { ... }
+//	if err := tap.Start(); err != nil { ... }
+//	defer tap.Stop()
+//	callbackURL := tap.URL() + "/hooks/github"
+package reqtap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/funnyzak/reqtap/internal/config"
+	"github.com/funnyzak/reqtap/internal/forwarder"
+	"github.com/funnyzak/reqtap/internal/logger"
+	"github.com/funnyzak/reqtap/internal/server"
+	"github.com/funnyzak/reqtap/internal/storage"
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+// Config is the full ReqTap configuration, as read from config.yaml by the binary.
+type Config = config.Config
+
+// Store gives access to the captured requests.
+type Store = storage.Store
+
+// StoredRequest is a captured request with its triage annotations.
+type StoredRequest = storage.StoredRequest
+
+// ListOptions filters and paginates Store.List.
+type ListOptions = storage.ListOptions
+
+// Logger receives ReqTap's structured logs; fields are alternating keys and values.
+type Logger = logger.Logger
+
+// Hook observes or changes a captured request at a hook point; return ErrDropRequest to drop it.
+type Hook = server.Hook
+
+// HookEvent is the request a hook runs for, plus the forward results at HookAfterForward.
+type HookEvent = server.HookEvent
+
+// HookPoint names the place in the pipeline where a hook runs.
+type HookPoint = server.HookPoint
+
+// ForwardResult is the outcome of delivering a request to one forward target.
+type ForwardResult = forwarder.Result
+
+// Hook points, in the order a request reaches them; see the server package for what dropping
+// does at each of them.
+const (
+	HookOnReceive     = server.HookOnReceive
+	HookBeforeStore   = server.HookBeforeStore
+	HookBeforeForward = server.HookBeforeForward
+	HookAfterForward  = server.HookAfterForward
+)
+
+// ErrDropRequest is returned by a hook to drop the request.
+var ErrDropRequest = server.ErrDropRequest
+
+// onRequestStage is the pipeline stage running Options.OnRequest.
+const onRequestStage = "on_request"
+
+// Options configures an embedded instance. The zero value captures every path on a free port
+// and answers 200 "ok".
+type Options struct {
+	// Port to listen on; 0 picks a free port, see Server.URL
+	Port int
+	// Path is the capture path prefix; empty captures every path
+	Path string
+	// ForwardURLs relays every captured request to these targets
+	ForwardURLs []string
+	// StoragePath is the sqlite database file; empty uses a temporary file removed by Stop
+	StoragePath string
+	// OnRequest is called in the background for every captured request once it has been answered
+	// and stored
+	OnRequest func(*request.RequestData)
+	// Hooks run at their hook point for every captured request, after the hooks of the config
+	Hooks map[HookPoint][]Hook
+	// Logger receives ReqTap's logs; nil discards them
+	Logger Logger
+	// Configure adjusts any other setting before the server is created. The configuration starts
+	// from ReqTap's defaults with console output and the web console turned off.
+	Configure func(*Config)
+}
+
+// Server is an embedded ReqTap instance.
+type Server struct {
+	srv     *server.Server
+	cfg     *Config
+	tempDir string
+}
+
+// New creates an instance from opts; call Start to begin accepting requests.
+func New(opts Options) (*Server, error) {
+	path := "/"
+	if opts.Path != "" {
+		path = opts.Path
+	}
+	cfg, err := config.New(
+		config.WithPort(opts.Port),
+		config.WithPath(path),
+		config.WithForwardURLs(opts.ForwardURLs...),
+		config.WithSilence(true),
+		config.WithWeb(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var tempDir string
+	if opts.StoragePath != "" {
+		cfg.Storage.Path = opts.StoragePath
+	} else {
+		if tempDir, err = os.MkdirTemp("", "reqtap-"); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+		cfg.Storage.Path = filepath.Join(tempDir, "reqtap.db")
+	}
+	if opts.Configure != nil {
+		opts.Configure(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		removeTempDir(tempDir)
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	log := opts.Logger
+	if log == nil {
+		log = discardLogger{}
+	}
+	srv, err := server.New(cfg, log)
+	if err != nil {
+		removeTempDir(tempDir)
+		return nil, err
+	}
+	if opts.OnRequest != nil {
+		onRequest := opts.OnRequest
+		err = srv.Pipeline().InsertAfter(server.StageStore, server.Stage{
+			Name:  onRequestStage,
+			Phase: server.PhaseAsync,
+			Run: func(_ context.Context, ex *server.Exchange) error {
+				onRequest(ex.Record)
+				return nil
+			},
+		})
+		if err != nil {
+			srv.Stop()
+			removeTempDir(tempDir)
+			return nil, err
+		}
+	}
+	for _, point := range []HookPoint{HookOnReceive, HookBeforeStore, HookBeforeForward, HookAfterForward} {
+		for i, hook := range opts.Hooks[point] {
+			if err := srv.AddHook(point, fmt.Sprintf("%s-%d", point, i+1), hook); err != nil {
+				srv.Stop()
+				removeTempDir(tempDir)
+				return nil, err
+			}
+		}
+	}
+	return &Server{srv: srv, cfg: cfg, tempDir: tempDir}, nil
+}
+
+// AddHook registers a hook run at point for every captured request; call it before Start.
+func (s *Server) AddHook(point HookPoint, name string, fn Hook) error {
+	return s.srv.AddHook(point, name, fn)
+}
+
+// Start binds the listener and serves in the background; it returns once requests are accepted.
+func (s *Server) Start() error {
+	return s.srv.Listen()
+}
+
+// Stop shuts the listener down, waits for in-flight requests and forwards, and releases the
+// store; the temporary database, if any, is removed.
+func (s *Server) Stop() error {
+	err := s.srv.Stop()
+	removeTempDir(s.tempDir)
+	return err
+}
+
+// Addr returns the host:port the listener is bound to, or "" before Start.
+func (s *Server) Addr() string {
+	addr := s.srv.Addr()
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// URL returns the capture URL on the loopback interface, e.g. http://127.0.0.1:41234/reqtap,
+// or "" before Start. With server.paths configured it has no path; append one of the prefixes.
+func (s *Server) URL() string {
+	addr := s.srv.Addr()
+	if addr == nil {
+		return ""
+	}
+	_, port, _ := net.SplitHostPort(addr.String())
+	path := strings.TrimSuffix(s.cfg.Server.Path, "/")
+	if len(s.cfg.Server.Paths) > 0 {
+		path = ""
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%s%s", s.cfg.Server.Scheme(), port, path)
+}
+
+// Store gives access to the captured requests, e.g. to list them after the code under test ran.
+func (s *Server) Store() Store {
+	return s.srv.Store()
+}
+
+func removeTempDir(dir string) {
+	if dir != "" {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+func (discardLogger) Fatal(string, ...interface{}) {}
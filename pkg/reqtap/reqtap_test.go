@@ -0,0 +1,109 @@
+package reqtap
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funnyzak/reqtap/pkg/request"
+)
+
+func TestEmbeddedServerCapturesRequests(t *testing.T) {
+	received := make(chan *request.RequestData, 1)
+	tap, err := New(Options{
+		Path:      "/hooks",
+		OnRequest: func(data *request.RequestData) { received <- data },
+		Configure: func(cfg *Config) {
+			cfg.Server.Responses[0].Status = http.StatusAccepted
+		},
+	})
+	if err != nil {
+		t.Fatalf("new failed: %v", err)
+	}
+	if tap.URL() != "" {
+		t.Fatalf("expected no URL before Start, got %q", tap.URL())
+	}
+	if err := tap.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	dbDir := tap.tempDir
+
+	resp, err := http.Post(tap.URL()+"/github", "application/json", strings.NewReader(`{"ref":"main"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the configured 202, got %d", resp.StatusCode)
+	}
+
+	select {
+	case data := <-received:
+		if data.Path != "/hooks/github" || string(data.Body) != `{"ref":"main"}` {
+			t.Fatalf("unexpected captured request: %s %q", data.Path, data.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnRequest was not called")
+	}
+	items, total, err := tap.Store().List(ListOptions{})
+	if err != nil || total != 1 || items[0].Path != "/hooks/github" {
+		t.Fatalf("expected the request in the store, got %d (%v)", total, err)
+	}
+
+	if err := tap.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	if _, err := os.Stat(dbDir); !os.IsNotExist(err) {
+		t.Fatalf("expected the temporary storage to be removed, got %v", err)
+	}
+}
+
+func TestEmbeddedServerHooks(t *testing.T) {
+	tap, err := New(Options{
+		Hooks: map[HookPoint][]Hook{
+			HookBeforeStore: {func(_ context.Context, ev *HookEvent) error {
+				if ev.Record.Path == "/ping" {
+					return ErrDropRequest
+				}
+				ev.Record.Headers.Del("X-Api-Key")
+				ev.Tag("seen")
+				return nil
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new failed: %v", err)
+	}
+	if err := tap.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer tap.Stop()
+
+	for _, path := range []string{"/ping", "/event"} {
+		req, _ := http.NewRequest(http.MethodPost, tap.URL()+path, strings.NewReader("{}"))
+		req.Header.Set("X-Api-Key", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		items, total, err := tap.Store().List(ListOptions{})
+		if err == nil && total == 1 && len(items[0].Tags) == 1 {
+			if items[0].Path != "/event" || items[0].Headers.Get("X-Api-Key") != "" || items[0].Tags[0] != "seen" {
+				t.Fatalf("unexpected stored request: %s %v %v", items[0].Path, items[0].Headers, items[0].Tags)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected only the hooked request to be stored, got %d (%v)", total, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
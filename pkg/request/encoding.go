@@ -0,0 +1,127 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// ErrDecodedBodyTooLarge reports a compressed body that expands beyond the decoding limit
+var ErrDecodedBodyTooLarge = errors.New("decoded body exceeds limit")
+
+// DecodeContentEncoding replaces a gzip, deflate or brotli encoded body with its decoded bytes, keeping
+// the received bytes in WireBody for forwarding. Stacked encodings ("gzip, br") are undone in reverse
+// order. maxBytes caps the decoded size (0 = unlimited). On error the record is left unchanged.
+func (d *RequestData) DecodeContentEncoding(maxBytes int64) error {
+	if d == nil || len(d.Body) == 0 || d.WireBody != nil || d.BodyFile != "" {
+		return nil
+	}
+	encodings := contentEncodings(d.Headers.Get("Content-Encoding"))
+	if len(encodings) == 0 {
+		return nil
+	}
+	decoded := d.Body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		if decoded, err = decodeBody(encodings[i], decoded, maxBytes); err != nil {
+			return fmt.Errorf("%s: %w", encodings[i], err)
+		}
+	}
+	d.WireBody = d.Body
+	d.WireSize = int64(len(d.Body))
+	d.ContentEncoding = strings.Join(encodings, ", ")
+	d.Body = decoded
+	d.Size = int64(len(decoded))
+	d.IsBinary = isBinaryContent(d.ContentType, decoded)
+	return nil
+}
+
+// ForwardBody returns the body as it was received, still encoded when it was decoded for display
+func (d *RequestData) ForwardBody() []byte {
+	if d.WireBody != nil {
+		return d.WireBody
+	}
+	return d.Body
+}
+
+// OpenBody streams the body as it was received, from BodyFile when it was spilled to disk; size is
+// its length in bytes
+func (d *RequestData) OpenBody() (body io.ReadCloser, size int64, err error) {
+	if d.BodyFile == "" {
+		wire := d.ForwardBody()
+		return io.NopCloser(bytes.NewReader(wire)), int64(len(wire)), nil
+	}
+	f, err := os.Open(d.BodyFile)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open spilled body: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("open spilled body: %w", err)
+	}
+	return f, info.Size(), nil
+}
+
+// contentEncodings lists the codings of a Content-Encoding header; nil when one of them cannot be
+// decoded, or when the body is not encoded at all
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, part := range strings.Split(header, ",") {
+		coding := strings.ToLower(strings.TrimSpace(part))
+		switch coding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip", "deflate", "br":
+			encodings = append(encodings, coding)
+		default:
+			return nil
+		}
+	}
+	return encodings
+}
+
+func decodeBody(coding string, body []byte, maxBytes int64) ([]byte, error) {
+	var reader io.Reader
+	switch coding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		reader = zr
+	case "deflate":
+		// Content-Encoding: deflate means zlib-wrapped data, but raw deflate streams are common too
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(body))
+			defer fr.Close()
+			reader = fr
+		} else {
+			defer zr.Close()
+			reader = zr
+		}
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	}
+	if maxBytes > 0 {
+		reader = io.LimitReader(reader, maxBytes+1)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && int64(len(decoded)) > maxBytes {
+		return nil, ErrDecodedBodyTooLarge
+	}
+	return decoded, nil
+}
@@ -0,0 +1,76 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("%s: %v", coding, err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeContentEncoding(t *testing.T) {
+	plain := []byte(`{"event":"push","repository":{"name":"reqtap"}}`)
+	cases := []struct {
+		name   string
+		header string
+		body   []byte
+	}{
+		{"gzip", "gzip", compress(t, "gzip", plain)},
+		{"zlib deflate", "deflate", compress(t, "deflate", plain)},
+		{"raw deflate", "Deflate", compress(t, "raw-deflate", plain)},
+		{"brotli", "br", compress(t, "br", plain)},
+		{"stacked", "gzip, br", compress(t, "br", compress(t, "gzip", plain))},
+	}
+	for _, tc := range cases {
+		data := &RequestData{Headers: http.Header{"Content-Encoding": {tc.header}}, ContentType: "application/json", Body: tc.body, IsBinary: true}
+		if err := data.DecodeContentEncoding(0); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !bytes.Equal(data.Body, plain) || data.IsBinary || data.Size != int64(len(plain)) {
+			t.Errorf("%s: expected the decoded JSON body, got %q (binary=%v)", tc.name, data.Body, data.IsBinary)
+		}
+		if !bytes.Equal(data.ForwardBody(), tc.body) || data.WireSize != int64(len(tc.body)) {
+			t.Errorf("%s: expected the received bytes to be forwarded", tc.name)
+		}
+	}
+
+	gz := compress(t, "gzip", plain)
+	data := &RequestData{Headers: http.Header{"Content-Encoding": {"gzip"}}, Body: gz}
+	if err := data.DecodeContentEncoding(10); !errors.Is(err, ErrDecodedBodyTooLarge) {
+		t.Fatalf("expected the decoding limit to apply, got %v", err)
+	}
+	if !bytes.Equal(data.Body, gz) || data.ContentEncoding != "" {
+		t.Fatalf("expected a failed decode to keep the body as received")
+	}
+
+	data = &RequestData{Headers: http.Header{"Content-Encoding": {"zstd"}}, Body: plain}
+	if err := data.DecodeContentEncoding(0); err != nil || data.WireBody != nil {
+		t.Fatalf("expected unsupported codings to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package request
+
+import "encoding/json"
+
+// GRPCCall describes a captured gRPC call
+type GRPCCall struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	// Requests holds the messages sent by the client, Responses those answered by the gRPC backend
+	Requests  []GRPCMessage `json:"requests,omitempty"`
+	Responses []GRPCMessage `json:"responses,omitempty"`
+	// Status and StatusMessage come from the grpc-status and grpc-message trailers
+	Status        string `json:"status,omitempty"`
+	StatusMessage string `json:"status_message,omitempty"`
+}
+
+// GRPCMessage is one length-prefixed message of a gRPC stream
+type GRPCMessage struct {
+	Size       int  `json:"size"`
+	Compressed bool `json:"compressed,omitempty"`
+	// JSON is the decoded message; Raw reports it was decoded without a schema, keyed by field number
+	JSON  json.RawMessage `json:"json,omitempty"`
+	Raw   bool            `json:"raw,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
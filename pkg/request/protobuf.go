@@ -0,0 +1,13 @@
+package request
+
+import "encoding/json"
+
+// ProtobufBody is a protobuf request body decoded into JSON, see output.body_view.protobuf
+type ProtobufBody struct {
+	// Message is the fully qualified message type; empty when the body was decoded without a schema
+	Message string `json:"message,omitempty"`
+	// JSON is the decoded body; Raw reports it was decoded without a schema, keyed by field number
+	JSON  json.RawMessage `json:"json,omitempty"`
+	Raw   bool            `json:"raw,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
@@ -27,6 +27,27 @@ type ReplayRequest struct {
 	Body      string            `json:"body"`
 	Query     string            `json:"query"`
 	TargetURL string            `json:"target_url"`
+	// Vars are values taken from other captures or replays; {{name}} in TargetURL, Query, Headers,
+	// Body and BodySet is replaced with them
+	Vars []ReplayVar `json:"vars,omitempty"`
+	// BodySet sets fields of the JSON body by JSON path; a value that is exactly "{{name}}" keeps
+	// the type of the extracted value
+	BodySet map[string]string `json:"body_set,omitempty"`
+}
+
+// ReplayVar extracts one value for a chained replay
+type ReplayVar struct {
+	Name string `json:"name"`
+	// RequestID is the stored request to read; empty reads the replayed request
+	RequestID string `json:"request_id,omitempty"`
+	// From is "request" to read the request (default) or "replay" to read the response body of
+	// one of its replays: ReplayID, or the latest when empty
+	From     string `json:"from,omitempty"`
+	ReplayID string `json:"replay_id,omitempty"`
+	// JSONPath picks a field of the JSON body and Header a request header; with neither the whole
+	// body is used
+	JSONPath string `json:"json_path,omitempty"`
+	Header   string `json:"header,omitempty"`
 }
 
 // ReplayResponse represents a replay response to API
@@ -37,4 +58,6 @@ type ReplayResponse struct {
 	ResponseBody string `json:"response_body"`
 	ResponseTime int64  `json:"response_time_ms"`
 	Error        string `json:"error,omitempty"`
+	// Vars are the values the replay's vars resolved to
+	Vars map[string]string `json:"vars,omitempty"`
 }
@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 )
@@ -26,6 +28,26 @@ type RequestData struct {
 	IsBinary      bool         `json:"is_binary"`
 	Size          int64        `json:"size"`
 	MockResponse  MockResponse `json:"mock_response"`
+	// Instance names the ReqTap instance that captured the request when clustering is enabled
+	Instance string `json:"instance,omitempty"`
+	// Credential names the server.auth credential the request was captured with
+	Credential string `json:"credential,omitempty"`
+	// GRPC describes the call when the request was captured in gRPC mode
+	GRPC *GRPCCall `json:"grpc,omitempty"`
+	// Protobuf is the decoded body of a protobuf request, see output.body_view.protobuf
+	Protobuf *ProtobufBody `json:"protobuf,omitempty"`
+	// ContentEncoding lists the codings Body was decoded from; WireBody and WireSize hold the body as
+	// it was received and forwarded, see DecodeContentEncoding
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	WireSize        int64  `json:"wire_size,omitempty"`
+	WireBody        []byte `json:"-"`
+	// BodyFile holds the complete body when it was spilled to disk; Body then only keeps its first
+	// bytes as a preview and Size is the full length, see OpenBody
+	BodyFile string `json:"body_file,omitempty"`
+	// Fingerprint identifies the request for de-duplication; DuplicateOf is the ID of the first
+	// request with the same fingerprint when this one is a re-delivery, see dedup in config.yaml
+	Fingerprint string `json:"fingerprint,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
 }
 
 // MockResponse summarizes inline response meta
@@ -34,7 +56,8 @@ type MockResponse struct {
 	Status int    `json:"status"`
 }
 
-// NewRequestData creates new request data record
+// NewRequestData creates new request data record; RemoteAddr is the connecting peer, see ClientIP
+// to honor forwarding headers from trusted proxies
 func NewRequestData(r *http.Request, body []byte) *RequestData {
 	id := generateRequestID()
 	contentType := r.Header.Get("Content-Type")
@@ -46,7 +69,7 @@ func NewRequestData(r *http.Request, body []byte) *RequestData {
 		Proto:         r.Proto,
 		Path:          r.URL.Path,
 		Query:         r.URL.RawQuery,
-		RemoteAddr:    getClientIP(r),
+		RemoteAddr:    ClientIP(r, nil),
 		UserAgent:     r.UserAgent(),
 		Headers:       r.Header.Clone(),
 		Body:          body,
@@ -57,37 +80,67 @@ func NewRequestData(r *http.Request, body []byte) *RequestData {
 	}
 }
 
-// getClientIP gets client real IP address
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP (original client IP)
-		for idx := 0; idx < len(xff); idx++ {
-			if xff[idx] == ',' {
-				return xff[:idx]
-			}
-		}
-		return xff
+// ClientAddr returns the address of the client that sent r. The connecting peer is the client
+// unless it is one of trustedProxies; then X-Forwarded-For is walked from the right and the first
+// address that is not a trusted proxy is used, so a client cannot spoof its address by prepending
+// entries. X-Real-IP is used when a trusted proxy sent no X-Forwarded-For. ok is false when the
+// peer address is not an IP.
+func ClientAddr(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
 	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	client, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	client = client.Unmap()
+	if !trusted(trustedProxies, client) {
+		return client, true
 	}
 
-	// Use RemoteAddr
-	if idx := len(r.RemoteAddr) - 1; idx >= 0 && r.RemoteAddr[idx] >= '0' && r.RemoteAddr[idx] <= '9' {
-		// Find colon from back to front
-		for i := idx; i >= 0; i-- {
-			if r.RemoteAddr[i] == ':' {
-				return r.RemoteAddr[:i]
-			}
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return realIP.Unmap(), true
 		}
 	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !trusted(trustedProxies, client) {
+			break
+		}
+	}
+	return client, true
+}
 
+// ClientIP is ClientAddr as recorded in RequestData.RemoteAddr; a peer that is not an IP is kept as is.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	if client, ok := ClientAddr(r, trustedProxies); ok {
+		return client.String()
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
 	return r.RemoteAddr
 }
 
+func trusted(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // isBinaryContent detects if it's binary content
 func isBinaryContent(contentType string, body []byte) bool {
 	// Check Content-Type
@@ -2,6 +2,7 @@ package request
 
 import (
 	"net/http"
+	"net/netip"
 	"strings"
 	"testing"
 	"time"
@@ -62,13 +63,14 @@ func TestNewRequestData(t *testing.T) {
 		t.Errorf("Expected size 16, got %d", data.Size)
 	}
 
-	// Verify IP getting logic (should prioritize X-Forwarded-For)
-	if data.RemoteAddr != "192.168.1.100" {
-		t.Errorf("Expected remote addr 192.168.1.100, got %s", data.RemoteAddr)
+	// Forwarding headers are ignored unless the peer is a trusted proxy
+	if data.RemoteAddr != "10.0.0.1" {
+		t.Errorf("Expected remote addr 10.0.0.1, got %s", data.RemoteAddr)
 	}
 }
 
-func TestGetClientIP(t *testing.T) {
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
 	tests := []struct {
 		name       string
 		remoteAddr string
@@ -76,7 +78,7 @@ func TestGetClientIP(t *testing.T) {
 		expectedIP string
 	}{
 		{
-			name:       "X-Forwarded-For single IP",
+			name:       "X-Forwarded-For from trusted proxy",
 			remoteAddr: "10.0.0.1:12345",
 			headers: map[string]string{
 				"X-Forwarded-For": "192.168.1.100",
@@ -84,21 +86,37 @@ func TestGetClientIP(t *testing.T) {
 			expectedIP: "192.168.1.100",
 		},
 		{
-			name:       "X-Forwarded-For multiple IPs",
+			name:       "X-Forwarded-For through several trusted proxies",
 			remoteAddr: "10.0.0.1:12345",
 			headers: map[string]string{
-				"X-Forwarded-For": "192.168.1.100, 10.0.0.2, 172.16.0.1",
+				"X-Forwarded-For": "203.0.113.9, 192.168.1.100, 10.0.0.2",
 			},
 			expectedIP: "192.168.1.100",
 		},
 		{
-			name:       "X-Real-IP",
+			name:       "X-Forwarded-For from untrusted peer is ignored",
+			remoteAddr: "198.51.100.7:12345",
+			headers: map[string]string{
+				"X-Forwarded-For": "192.168.1.100",
+			},
+			expectedIP: "198.51.100.7",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
 			remoteAddr: "10.0.0.1:12345",
 			headers: map[string]string{
 				"X-Real-IP": "192.168.1.200",
 			},
 			expectedIP: "192.168.1.200",
 		},
+		{
+			name:       "X-Real-IP from untrusted peer is ignored",
+			remoteAddr: "198.51.100.7:12345",
+			headers: map[string]string{
+				"X-Real-IP": "192.168.1.200",
+			},
+			expectedIP: "198.51.100.7",
+		},
 		{
 			name:       "RemoteAddr only",
 			remoteAddr: "10.0.0.1:12345",
@@ -111,6 +129,12 @@ func TestGetClientIP(t *testing.T) {
 			headers:    map[string]string{},
 			expectedIP: "10.0.0.1",
 		},
+		{
+			name:       "RemoteAddr that is not an IP",
+			remoteAddr: "@:0",
+			headers:    map[string]string{},
+			expectedIP: "@",
+		},
 	}
 
 	for _, tt := range tests {
@@ -124,7 +148,7 @@ func TestGetClientIP(t *testing.T) {
 				req.Header.Set(key, value)
 			}
 
-			ip := getClientIP(req)
+			ip := ClientIP(req, proxies)
 			if ip != tt.expectedIP {
 				t.Errorf("Expected IP %s, got %s", tt.expectedIP, ip)
 			}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket frame directions
+const (
+	// FrameInbound is a frame sent by the captured client
+	FrameInbound = "inbound"
+	// FrameOutbound is a frame sent to the captured client (by the proxied upstream)
+	FrameOutbound = "outbound"
+)
+
+// WebSocketFrame describes a single frame seen on a captured WebSocket connection
+type WebSocketFrame struct {
+	RequestID string    `json:"request_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Direction string    `json:"direction"`
+	Opcode    string    `json:"opcode"`
+	Size      int       `json:"size"`
+	Preview   string    `json:"preview"`
+	// PreviewHex reports that Preview is hex-encoded because the payload is not valid UTF-8
+	PreviewHex bool `json:"preview_hex,omitempty"`
+	Truncated  bool `json:"truncated,omitempty"`
+}
+
+// NewWebSocketFrame builds a frame summary with a preview of at most previewBytes bytes
+func NewWebSocketFrame(requestID, direction, opcode string, payload []byte, previewBytes int) *WebSocketFrame {
+	frame := &WebSocketFrame{
+		RequestID: requestID,
+		Timestamp: time.Now(),
+		Direction: direction,
+		Opcode:    opcode,
+		Size:      len(payload),
+	}
+
+	preview := payload
+	if previewBytes > 0 && len(preview) > previewBytes {
+		preview = preview[:previewBytes]
+		frame.Truncated = true
+		// Do not split a multi-byte rune at the cut
+		if utf8.Valid(payload) {
+			for len(preview) > 0 && !utf8.Valid(preview) {
+				preview = preview[:len(preview)-1]
+			}
+		}
+	}
+	if utf8.Valid(preview) {
+		frame.Preview = string(preview)
+	} else {
+		frame.Preview = hex.EncodeToString(preview)
+		frame.PreviewHex = true
+	}
+	return frame
+}